@@ -19,6 +19,7 @@ package extension
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -38,6 +39,25 @@ const (
 	AnnotationGPUPartitionSpec = SchedulingDomainPrefix + "/gpu-partition-spec"
 	// AnnotationGPUPartitions represents the GPU partitions supported on the node
 	AnnotationGPUPartitions = SchedulingDomainPrefix + "/gpu-partitions"
+	// AnnotationDeviceResync is set on the node to force koordlet to rebuild and update the Device object.
+	// The value is an arbitrary token, and a new token triggers a new resync.
+	AnnotationDeviceResync = NodeDomainPrefix + "/device-resync"
+	// AnnotationReservedGPUs is set on the node to reserve the GPUs, e.g. for maintenance, which are reported as
+	// unhealthy so that the scheduler avoids them. The value is a comma-separated list of the GPU UUIDs or minors,
+	// e.g. "GPU-8c25ea37-2909-6e62-b7bf-e2fcadebea8d,3".
+	AnnotationReservedGPUs = NodeDomainPrefix + "/reserved-gpus"
+	// AnnotationDeviceReportPaused is set on the node to pause koordlet reporting the Device and marking the GPUs
+	// unhealthy, e.g. "true" during the driver maintenance. The Device keeps the last reported state until the
+	// annotation is removed.
+	AnnotationDeviceReportPaused = NodeDomainPrefix + "/device-report-paused"
+	// AnnotationNamespaceGPUCoreLimit is set on the namespace to limit the total koordinator.sh/gpu-core requested by
+	// the running and pending pods in the namespace, e.g. "400" for 4 whole GPUs.
+	AnnotationNamespaceGPUCoreLimit = SchedulingDomainPrefix + "/gpu-core-limit"
+	// AnnotationGPUExclusive indicates the pod must get whole GPUs without sharing them with other pods, e.g. "true"
+	AnnotationGPUExclusive = SchedulingDomainPrefix + "/gpu-exclusive"
+	// AnnotationGPUFabricSpread asks the webhook to spread the multi-GPU pods of the same group across the nodes with
+	// GPU fabric partitions. The value is the key of the pod label identifying the group, e.g. "job-name".
+	AnnotationGPUFabricSpread = SchedulingDomainPrefix + "/gpu-fabric-spread"
 )
 
 const (
@@ -51,6 +71,9 @@ const (
 	ResourceGPUCore        corev1.ResourceName = DomainPrefix + "gpu-core"
 	ResourceGPUMemory      corev1.ResourceName = DomainPrefix + "gpu-memory"
 	ResourceGPUMemoryRatio corev1.ResourceName = DomainPrefix + "gpu-memory-ratio"
+	ResourceGPUEncoder     corev1.ResourceName = DomainPrefix + "gpu-encoder"
+	ResourceGPUDecoder     corev1.ResourceName = DomainPrefix + "gpu-decoder"
+	ResourceGPUBAR1Memory  corev1.ResourceName = DomainPrefix + "gpu-bar1-memory"
 )
 
 const (
@@ -59,9 +82,88 @@ const (
 	LabelGPUDriverVersion           string = NodeDomainPrefix + "/gpu-driver-version"
 	LabelSecondaryDeviceWellPlanned string = NodeDomainPrefix + "/secondary-device-well-planned"
 
+	// LabelGPUCUDADriverVersion represents the highest CUDA version supported by the GPU driver, e.g. "12.2"
+	LabelGPUCUDADriverVersion string = NodeDomainPrefix + "/gpu-cuda-driver-version"
+
+	// LabelGPUComputeCapability represents the CUDA compute capability of the GPU, e.g. "8.0"
+	LabelGPUComputeCapability string = NodeDomainPrefix + "/gpu-compute-capability"
+	// LabelGPUProductName represents the product name of the GPU, e.g. "A100-SXM4-80GB"
+	LabelGPUProductName string = NodeDomainPrefix + "/gpu-product-name"
+	// LabelGPUCoreGranularity represents the gpu-core quantity of a whole GPU reported in the Device, e.g. "1000"
+	LabelGPUCoreGranularity string = NodeDomainPrefix + "/gpu-core-granularity"
+	// LabelGPUMemoryGranularity represents the bytes which the gpu-memory reported in the Device is rounded down to,
+	// e.g. "1073741824" for the device plugin allocating the GPU memory in whole GiB
+	LabelGPUMemoryGranularity string = NodeDomainPrefix + "/gpu-memory-granularity"
+	// LabelGPUFabricPartitionCount represents the count of the GPU fabric partitions of the node, e.g. "2", which is
+	// reported in the Device and synced to the node, so that the pods can be placed on the nodes with NVSwitches
+	LabelGPUFabricPartitionCount string = NodeDomainPrefix + "/gpu-fabric-partition-count"
+	// LabelGPUPhysicalUUID represents the UUID of the physical GPU which a time-sliced GPU replica belongs to
+	LabelGPUPhysicalUUID string = NodeDomainPrefix + "/gpu-physical-uuid"
+	// LabelGPUPhysicalMinor represents the minor of the physical GPU which a time-sliced GPU replica belongs to
+	LabelGPUPhysicalMinor string = NodeDomainPrefix + "/gpu-physical-minor"
+	// LabelGPUReplicaIndex represents the index of a time-sliced GPU replica in its physical GPU, starting from 0
+	LabelGPUReplicaIndex string = NodeDomainPrefix + "/gpu-replica-index"
+	// LabelGPUVGPUProfile represents the vGPU profile of a vGPU instance reported as a GPU on the virtualized host,
+	// e.g. "GRID A100-20C". The physical GPU of the vGPU is represented by LabelGPUPhysicalUUID.
+	LabelGPUVGPUProfile string = NodeDomainPrefix + "/gpu-vgpu-profile"
+	// LabelGPUMigCapable represents the GPU supports the Multi-Instance GPU (MIG), e.g. "true"
+	LabelGPUMigCapable string = NodeDomainPrefix + "/gpu-mig-capable"
+	// LabelGPUMigEnabled represents whether the MIG mode of the MIG-capable GPU is currently enabled, e.g. "false"
+	LabelGPUMigEnabled string = NodeDomainPrefix + "/gpu-mig-enabled"
+	// LabelGPUMigPendingEnabled represents whether the MIG mode of the MIG-capable GPU is enabled after the GPU reset
+	LabelGPUMigPendingEnabled string = NodeDomainPrefix + "/gpu-mig-pending-enabled"
+	// LabelGPUExpectedCount is the node label which represents the expected count of the physical GPUs on the node,
+	// e.g. "8", which defers the creation of the Device until the GPUs are all discovered
+	LabelGPUExpectedCount string = NodeDomainPrefix + "/gpu-expected-count"
+	// LabelGPUReserved represents the GPU is reserved by the node annotation and reported as unhealthy, e.g. "true"
+	LabelGPUReserved string = NodeDomainPrefix + "/gpu-reserved"
+	// LabelGPUFabricPartition represents the fabric partition of the GPUs connected by the same NVSwitches, e.g. "0",
+	// a multi-GPU job should be placed within one fabric partition to use the NVLink between GPUs. It is also reported
+	// in the Device and synced to the node if all the GPUs of the node are in one partition.
+	LabelGPUFabricPartition string = NodeDomainPrefix + "/gpu-fabric-partition"
+	// LabelGPUPersistenceModeEnabled represents whether the persistence mode of the GPU is enabled, e.g. "false",
+	// the GPU without persistence mode is initialized by each process and may respond slowly
+	LabelGPUPersistenceModeEnabled string = NodeDomainPrefix + "/gpu-persistence-mode-enabled"
+	// LabelGPUSerialNumber represents the serial number of the GPU board, which identifies the physical card to replace
+	LabelGPUSerialNumber string = NodeDomainPrefix + "/gpu-serial-number"
+	// LabelGPUInforomVersion represents the version of the OEM object of the GPU inforom, e.g. "1.1"
+	LabelGPUInforomVersion string = NodeDomainPrefix + "/gpu-inforom-version"
+	// LabelGPUCoolingDegraded represents the fan of the GPU stops while the GPU is thermally loaded, e.g. "true"
+	LabelGPUCoolingDegraded string = NodeDomainPrefix + "/gpu-cooling-degraded"
+	// LabelGPUMemoryDegraded represents the memory pages of the GPU are retired over the threshold or pending
+	// retirement, which indicates a failing GPU, e.g. "true"
+	LabelGPUMemoryDegraded string = NodeDomainPrefix + "/gpu-memory-degraded"
+	// LabelGPUP2PGroup represents the peer-access group of the GPU, where the GPUs of the same group can access the
+	// memory of each other directly, e.g. "0". The GPU without any peer has no group.
+	LabelGPUP2PGroup string = NodeDomainPrefix + "/gpu-p2p-group"
+	// LabelGPUPCIeLinkGeneration represents the current PCIe link generation of the GPU, e.g. "4", which may be
+	// lowered by the GPU itself to save power when idle
+	LabelGPUPCIeLinkGeneration string = NodeDomainPrefix + "/gpu-pcie-link-generation"
+	// LabelGPUPCIeLinkWidth represents the current PCIe link width of the GPU in lanes, e.g. "16"
+	LabelGPUPCIeLinkWidth string = NodeDomainPrefix + "/gpu-pcie-link-width"
+	// LabelGPUPCIeLinkMaxGeneration represents the max PCIe link generation supported by the GPU and the system
+	LabelGPUPCIeLinkMaxGeneration string = NodeDomainPrefix + "/gpu-pcie-link-max-generation"
+	// LabelGPUPCIeLinkMaxWidth represents the max PCIe link width supported by the GPU and the system in lanes
+	LabelGPUPCIeLinkMaxWidth string = NodeDomainPrefix + "/gpu-pcie-link-max-width"
+	// LabelGPUPCIeLinkDegraded represents the GPU runs at a narrower PCIe link width than the max, which usually
+	// indicates a poorly seated card or a hardware issue, e.g. "true"
+	LabelGPUPCIeLinkDegraded string = NodeDomainPrefix + "/gpu-pcie-link-degraded"
+
 	LabelGPUIsolationProvider = DomainPrefix + "gpu-isolation-provider"
 )
 
+const (
+	// NodeConditionGPUDiscoveryReady is the node condition reported by koordlet which represents whether the GPUs
+	// of the node are discovered, so that the nodes failing to discover the present GPUs can be alerted instead of
+	// silently reporting no GPU.
+	NodeConditionGPUDiscoveryReady corev1.NodeConditionType = "GPUDiscoveryReady"
+)
+
+const (
+	// DefaultGPUCoreGranularity is the gpu-core quantity of a whole GPU, i.e. the gpu-core is in percentage by default.
+	DefaultGPUCoreGranularity int64 = 100
+)
+
 // DeviceAllocations would be injected into Pod as form of annotation during Pre-bind stage.
 /*
 {
@@ -365,3 +467,43 @@ func IsSecondaryDeviceWellPlanned(device *schedulingv1alpha1.Device) bool {
 	}
 	return device.Labels[LabelSecondaryDeviceWellPlanned] == "true"
 }
+
+// GetGPUCoreGranularity returns the gpu-core quantity of a whole GPU on the node of the Device.
+// It returns DefaultGPUCoreGranularity if the Device does not declare a valid granularity.
+func GetGPUCoreGranularity(device *schedulingv1alpha1.Device) int64 {
+	if device == nil {
+		return DefaultGPUCoreGranularity
+	}
+	granularity, err := strconv.ParseInt(device.Labels[LabelGPUCoreGranularity], 10, 64)
+	if err != nil || granularity <= 0 {
+		return DefaultGPUCoreGranularity
+	}
+	return granularity
+}
+
+// GetGPUMemoryGranularity returns the bytes which the gpu-memory reported in the Device is rounded down to.
+// It returns 0 if the Device does not declare a valid granularity, i.e. the gpu-memory is not rounded.
+func GetGPUMemoryGranularity(device *schedulingv1alpha1.Device) int64 {
+	if device == nil {
+		return 0
+	}
+	granularity, err := strconv.ParseInt(device.Labels[LabelGPUMemoryGranularity], 10, 64)
+	if err != nil || granularity <= 0 {
+		return 0
+	}
+	return granularity
+}
+
+// IsDeviceSchedulable returns whether the device accepts the new pods, i.e. the device is healthy and not draining.
+func IsDeviceSchedulable(deviceInfo *schedulingv1alpha1.DeviceInfo) bool {
+	return deviceInfo.Health && deviceInfo.HealthState != schedulingv1alpha1.DeviceHealthStateDraining
+}
+
+// RoundDownGPUMemory rounds the gpu-memory down to the multiple of the granularity, the gpu-memory is returned as is
+// if the granularity is not positive.
+func RoundDownGPUMemory(memory resource.Quantity, granularity int64) resource.Quantity {
+	if granularity <= 0 {
+		return memory
+	}
+	return *resource.NewQuantity(memory.Value()/granularity*granularity, memory.Format)
+}
@@ -357,3 +357,111 @@ func TestGetNodeLevelGPUAllocatePolicy(t *testing.T) {
 		})
 	}
 }
+
+func TestGetGPUCoreGranularity(t *testing.T) {
+	tests := []struct {
+		name     string
+		device   *schedulingv1alpha1.Device
+		expected int64
+	}{
+		{
+			name:     "nil device",
+			expected: DefaultGPUCoreGranularity,
+		},
+		{
+			name: "device without granularity label",
+			device: &schedulingv1alpha1.Device{
+				ObjectMeta: metav1.ObjectMeta{},
+			},
+			expected: DefaultGPUCoreGranularity,
+		},
+		{
+			name: "device with granularity label",
+			device: &schedulingv1alpha1.Device{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						LabelGPUCoreGranularity: "1000",
+					},
+				},
+			},
+			expected: 1000,
+		},
+		{
+			name: "device with invalid granularity label",
+			device: &schedulingv1alpha1.Device{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						LabelGPUCoreGranularity: "-1",
+					},
+				},
+			},
+			expected: DefaultGPUCoreGranularity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, GetGPUCoreGranularity(tt.device))
+		})
+	}
+}
+
+func TestGetGPUMemoryGranularity(t *testing.T) {
+	tests := []struct {
+		name     string
+		device   *schedulingv1alpha1.Device
+		expected int64
+	}{
+		{
+			name:     "nil device",
+			expected: 0,
+		},
+		{
+			name:     "device without granularity label",
+			device:   &schedulingv1alpha1.Device{},
+			expected: 0,
+		},
+		{
+			name: "device with granularity label",
+			device: &schedulingv1alpha1.Device{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						LabelGPUMemoryGranularity: "1073741824",
+					},
+				},
+			},
+			expected: 1073741824,
+		},
+		{
+			name: "device with invalid granularity label",
+			device: &schedulingv1alpha1.Device{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						LabelGPUMemoryGranularity: "1Gi",
+					},
+				},
+			},
+			expected: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, GetGPUMemoryGranularity(tt.device))
+		})
+	}
+}
+
+func TestRoundDownGPUMemory(t *testing.T) {
+	memory := resource.MustParse("80994Mi")
+	expected := resource.MustParse("79Gi")
+	rounded := RoundDownGPUMemory(memory, 1<<30)
+	assert.Equal(t, expected.Value(), rounded.Value())
+	unchanged := RoundDownGPUMemory(memory, 0)
+	assert.Equal(t, memory.Value(), unchanged.Value())
+}
+
+func TestIsDeviceSchedulable(t *testing.T) {
+	assert.True(t, IsDeviceSchedulable(&schedulingv1alpha1.DeviceInfo{Health: true}))
+	assert.True(t, IsDeviceSchedulable(&schedulingv1alpha1.DeviceInfo{Health: true, HealthState: schedulingv1alpha1.DeviceHealthStateHealthy}))
+	assert.False(t, IsDeviceSchedulable(&schedulingv1alpha1.DeviceInfo{Health: true, HealthState: schedulingv1alpha1.DeviceHealthStateDraining}))
+	assert.False(t, IsDeviceSchedulable(&schedulingv1alpha1.DeviceInfo{Health: false}))
+}
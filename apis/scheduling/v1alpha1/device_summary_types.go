@@ -0,0 +1,76 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type DeviceSummarySpec struct {
+	// NodeName is the name of the node whose devices are summarized
+	NodeName string `json:"nodeName,omitempty"`
+}
+
+type DeviceSummaryStatus struct {
+	// Devices are the summaries of the devices of the node by the type
+	Devices []DeviceTypeSummary `json:"devices,omitempty"`
+	// LastUpdateTime is the last time the summary was updated by koordlet
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+}
+
+// DeviceTypeSummary summarizes the devices of a type on the node.
+type DeviceTypeSummary struct {
+	// Type represents the type of device
+	Type DeviceType `json:"type,omitempty"`
+	// Count is the number of the devices
+	Count int32 `json:"count"`
+	// HealthyCount is the number of the healthy devices
+	HealthyCount int32 `json:"healthyCount"`
+	// Resources is the sum of the resources of the devices
+	Resources corev1.ResourceList `json:"resources,omitempty"`
+	// Allocated is the sum of the resources of the devices allocated to the running pods, which is only reported
+	// for the gpus
+	Allocated corev1.ResourceList `json:"allocated,omitempty"`
+}
+
+// +genclient
+// +kubebuilder:object:root=true
+
+// DeviceSummary is the tenant-scoped summary of the devices of a node, which is reported by koordlet in the
+// namespace of the tenant the node belongs to, and named after the node. It is a read-only view for the tenants,
+// while the cluster-scoped Device is still the source of the scheduling.
+type DeviceSummary struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DeviceSummarySpec   `json:"spec,omitempty"`
+	Status DeviceSummaryStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+type DeviceSummaryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []DeviceSummary `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DeviceSummary{}, &DeviceSummaryList{})
+}
@@ -47,14 +47,31 @@ type DeviceInfo struct {
 	// Health indicates whether the device is normal
 	// +kubebuilder:default=false
 	Health bool `json:"health"`
+	// HealthState indicates the health state of the device in more detail than Health. A Draining device is
+	// healthy for the running pods but does not accept the new ones. Empty means Healthy if Health is true,
+	// otherwise Unhealthy.
+	// +optional
+	HealthState DeviceHealthState `json:"healthState,omitempty"`
 	// Resources is a set of (resource name, quantity) pairs
 	Resources corev1.ResourceList `json:"resources,omitempty"`
 	// Topology represents the topology information about the device
 	Topology *DeviceTopology `json:"topology,omitempty"`
 	// VFGroups represents the virtual function devices
 	VFGroups []VirtualFunctionGroup `json:"vfGroups,omitempty"`
+	// LastReportTime is the last time the device was reported by koordlet, which helps to tell whether the device
+	// is stale. It is ignored when checking whether the device is changed.
+	LastReportTime *metav1.Time `json:"lastReportTime,omitempty"`
 }
 
+// +kubebuilder:validation:Enum=Healthy;Draining;Unhealthy
+type DeviceHealthState string
+
+const (
+	DeviceHealthStateHealthy   DeviceHealthState = "Healthy"
+	DeviceHealthStateDraining  DeviceHealthState = "Draining"
+	DeviceHealthStateUnhealthy DeviceHealthState = "Unhealthy"
+)
+
 type DeviceTopology struct {
 	// SocketID is the ID of CPU Socket to which the device belongs
 	SocketID int32 `json:"socketID"`
@@ -82,13 +99,45 @@ type VirtualFunction struct {
 
 type DeviceStatus struct {
 	Allocations []DeviceAllocation `json:"allocations,omitempty"`
+	// Resources represents the resources of each device allocated to the running pods and free for the new ones,
+	// which is reported by koordlet from the device allocations of the pods on the node
+	Resources []DeviceResourceStatus `json:"resources,omitempty"`
+	// Conditions represents the node-level status of the devices, e.g. the GPUMonitoringReady reported by koordlet
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+const (
+	// DeviceConditionGPUMonitoringReady represents whether the GPUs of the node are monitored, i.e. NVML is
+	// initialized and the GPU subsystem is not degraded. The message summarizes the healthy and unhealthy GPUs,
+	// or the last error to build the GPU devices.
+	DeviceConditionGPUMonitoringReady = "GPUMonitoringReady"
+	// DeviceConditionGPUCoolingDegraded represents whether the fan of any GPU stops while the GPU is thermally
+	// loaded, which may cause the thermal throttling. The message lists the GPUs whose cooling is degraded.
+	DeviceConditionGPUCoolingDegraded = "GPUCoolingDegraded"
+	// DeviceConditionGPUMemoryDegraded represents whether the memory pages of any GPU are retired over the threshold
+	// or pending retirement, which indicates a failing GPU. The message lists the GPUs whose memory is degraded.
+	DeviceConditionGPUMemoryDegraded = "GPUMemoryDegraded"
+)
+
 type DeviceAllocation struct {
 	Type    DeviceType             `json:"type,omitempty"`
 	Entries []DeviceAllocationItem `json:"entries,omitempty"`
 }
 
+// DeviceResourceStatus represents the allocated and free resources of a device.
+type DeviceResourceStatus struct {
+	// Type represents the type of device
+	Type DeviceType `json:"type,omitempty"`
+	// UUID represents the UUID of device
+	UUID string `json:"id,omitempty"`
+	// Minor represents the Minor number of Device, starting from 0
+	Minor *int32 `json:"minor,omitempty"`
+	// Allocated is the sum of the resources of the device allocated to the running pods
+	Allocated corev1.ResourceList `json:"allocated,omitempty"`
+	// Free is the resources of the device not allocated, which is never negative
+	Free corev1.ResourceList `json:"free,omitempty"`
+}
+
 type DeviceAllocationItem struct {
 	Name      string  `json:"name,omitempty"`
 	Namespace string  `json:"namespace,omitempty"`
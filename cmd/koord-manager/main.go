@@ -81,6 +81,7 @@ func main() {
 	opts := options.NewOptions()
 	opts.InitFlags(flag.CommandLine)
 	sloconfig.InitFlags(flag.CommandLine)
+	webhook.InitFlags(flag.CommandLine)
 	utilfeature.DefaultMutableFeatureGate.AddFlag(pflag.CommandLine)
 	klog.InitFlags(nil)
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
@@ -88,6 +89,10 @@ func main() {
 	rand.Seed(time.Now().UnixNano())
 	ctrl.SetLogger(klogr.New())
 	features.SetDefaultFeatureGates()
+	if err := webhook.ValidateFlags(); err != nil {
+		setupLog.Error(err, "invalid webhook flags")
+		os.Exit(1)
+	}
 
 	if enablePprof {
 		go func() {
@@ -22,6 +22,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	"github.com/koordinator-sh/koordinator/pkg/quota-controller/profile"
+	"github.com/koordinator-sh/koordinator/pkg/slo-controller/devicegc"
 	"github.com/koordinator-sh/koordinator/pkg/slo-controller/nodemetric"
 	"github.com/koordinator-sh/koordinator/pkg/slo-controller/noderesource"
 	"github.com/koordinator-sh/koordinator/pkg/slo-controller/nodeslo"
@@ -29,9 +30,11 @@ import (
 
 var controllerInitFlags = map[string]func(*flag.FlagSet){
 	noderesource.Name: noderesource.InitFlags,
+	devicegc.Name:     devicegc.InitFlags,
 }
 
 var controllerAddFuncs = map[string]func(manager.Manager) error{
+	devicegc.Name:     devicegc.Add,
 	nodemetric.Name:   nodemetric.Add,
 	noderesource.Name: noderesource.Add,
 	nodeslo.Name:      nodeslo.Add,
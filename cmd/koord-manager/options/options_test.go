@@ -17,6 +17,7 @@ limitations under the License.
 package options
 
 import (
+	"flag"
 	"fmt"
 	"sort"
 	"testing"
@@ -55,7 +56,9 @@ func TestOptions(t *testing.T) {
 			"",
 			"--controllers=noderesource,nodemetric",
 		}
-		opt.InitFlags(nil)
+		fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+		opt.InitFlags(fs)
+		assert.NotNil(t, fs.Lookup("device-gc-ttl"), "controller flags should be registered on the given FlagSet")
 		pflag.NewFlagSet(args[0], pflag.ExitOnError)
 		err := pflag.CommandLine.Parse(args[1:])
 		assert.NoError(t, err)
@@ -33,6 +33,7 @@ import (
 	"github.com/koordinator-sh/koordinator/pkg/koordlet/audit"
 	"github.com/koordinator-sh/koordinator/pkg/koordlet/config"
 	"github.com/koordinator-sh/koordinator/pkg/koordlet/metrics"
+	statesinformerimpl "github.com/koordinator-sh/koordinator/pkg/koordlet/statesinformer/impl"
 	metricsutil "github.com/koordinator-sh/koordinator/pkg/util/metrics"
 )
 
@@ -96,6 +97,9 @@ func installHTTPHandler() {
 	if features.DefaultKoordletFeatureGate.Enabled(features.AuditEventsHTTPHandler) {
 		mux.HandleFunc("/events", audit.HttpHandler())
 	}
+	if features.DefaultKoordletFeatureGate.Enabled(features.DeviceDebugHTTPHandler) {
+		mux.HandleFunc(statesinformerimpl.DeviceDebugHTTPPath, statesinformerimpl.DeviceDebugHttpHandler())
+	}
 	// install extended HTTP handlers
 	options.InstallExtendedHTTPHandler(mux)
 	// http.HandleFunc("/healthz", d.HealthzHandler())
@@ -29,6 +29,7 @@ import (
 type SchedulingV1alpha1Interface interface {
 	RESTClient() rest.Interface
 	DevicesGetter
+	DeviceSummariesGetter
 	PodMigrationJobsGetter
 	ReservationsGetter
 }
@@ -42,6 +43,10 @@ func (c *SchedulingV1alpha1Client) Devices() DeviceInterface {
 	return newDevices(c)
 }
 
+func (c *SchedulingV1alpha1Client) DeviceSummaries(namespace string) DeviceSummaryInterface {
+	return newDeviceSummaries(c, namespace)
+}
+
 func (c *SchedulingV1alpha1Client) PodMigrationJobs() PodMigrationJobInterface {
 	return newPodMigrationJobs(c)
 }
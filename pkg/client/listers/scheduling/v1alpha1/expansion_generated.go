@@ -22,6 +22,14 @@ package v1alpha1
 // DeviceLister.
 type DeviceListerExpansion interface{}
 
+// DeviceSummaryListerExpansion allows custom methods to be added to
+// DeviceSummaryLister.
+type DeviceSummaryListerExpansion interface{}
+
+// DeviceSummaryNamespaceListerExpansion allows custom methods to be added to
+// DeviceSummaryNamespaceLister.
+type DeviceSummaryNamespaceListerExpansion interface{}
+
 // PodMigrationJobListerExpansion allows custom methods to be added to
 // PodMigrationJobLister.
 type PodMigrationJobListerExpansion interface{}
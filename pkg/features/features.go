@@ -84,6 +84,27 @@ const (
 
 	// Enable sync GPU shared resource from Device CRD
 	EnableSyncGPUSharedResource featuregate.Feature = "EnableSyncGPUSharedResource"
+
+	// EnableStrictGPUResourceSync enables syncing the node GPU resources as soon as they differ from the Device
+	// regardless of the resource diff threshold, so that the node resources keep consistent with the Device.
+	// It also makes koord-scheduler allocate the GPUs of the Device only after the node GPU resources are synced.
+	EnableStrictGPUResourceSync featuregate.Feature = "EnableStrictGPUResourceSync"
+
+	// EnablePodValidationRules enables validating pods with the additional rules loaded from a ConfigMap.
+	EnablePodValidationRules featuregate.Feature = "EnablePodValidationRules"
+
+	// EnablePodValidationDecisionCache enables reusing the allowed decision of the colocation and quota meta checks
+	// for the identical pods created in a short time, e.g. the replicas of a ReplicaSet.
+	EnablePodValidationDecisionCache featuregate.Feature = "EnablePodValidationDecisionCache"
+
+	// EnableGPUFabricSpreadInjection enables injecting the topology spread constraint and the node affinity into the
+	// multi-GPU pods annotated with AnnotationGPUFabricSpread, which spread the pods of the same group across the
+	// nodes with GPU fabric partitions.
+	EnableGPUFabricSpreadInjection featuregate.Feature = "EnableGPUFabricSpreadInjection"
+
+	// EnableGPUPodValidation enables validating the created GPU pods by the GPU pod validations, e.g. the Device of
+	// the node which the pod is assigned to, which are selected by the webhook flag gpu-pod-validations.
+	EnableGPUPodValidation featuregate.Feature = "EnableGPUPodValidation"
 )
 
 var defaultFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
@@ -104,6 +125,11 @@ var defaultFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
 	SupportParentQuotaSubmitPod:            {Default: false, PreRelease: featuregate.Alpha},
 	EnableQuotaAdmission:                   {Default: false, PreRelease: featuregate.Alpha},
 	EnableSyncGPUSharedResource:            {Default: true, PreRelease: featuregate.Alpha},
+	EnableStrictGPUResourceSync:            {Default: false, PreRelease: featuregate.Alpha},
+	EnablePodValidationRules:               {Default: false, PreRelease: featuregate.Alpha},
+	EnablePodValidationDecisionCache:       {Default: false, PreRelease: featuregate.Alpha},
+	EnableGPUFabricSpreadInjection:         {Default: false, PreRelease: featuregate.Alpha},
+	EnableGPUPodValidation:                 {Default: false, PreRelease: featuregate.Alpha},
 }
 
 const (
@@ -110,6 +110,11 @@ const (
 	// NetDevices enables RDMA related feature in koordlet.
 	RDMADevices featuregate.Feature = "RDMADevices"
 
+	// alpha: v1.6
+	//
+	// FPGADevices enables FPGA related feature in koordlet. Only Xilinx FPGAs supported.
+	FPGADevices featuregate.Feature = "FPGADevices"
+
 	// owner: @songtao98 @zwzhang0107
 	// alpha: v1.0
 	//
@@ -160,6 +165,9 @@ const (
 	// PodResourcesProxy enabled hooked podResources of kubelet provided by koordlet.
 	// It provides a grpc service to enable discovery of pod resources allocated by koordinator system.
 	PodResourcesProxy featuregate.Feature = "PodResourcesProxy"
+
+	// DeviceDebugHTTPHandler is used to get the gpu devices last built for the Device from koordlet port.
+	DeviceDebugHTTPHandler featuregate.Feature = "DeviceDebugHTTPHandler"
 )
 
 func init() {
@@ -185,6 +193,7 @@ var (
 		NodeTopologyReport:     {Default: true, PreRelease: featuregate.Beta},
 		Accelerators:           {Default: false, PreRelease: featuregate.Alpha},
 		RDMADevices:            {Default: false, PreRelease: featuregate.Alpha},
+		FPGADevices:            {Default: false, PreRelease: featuregate.Alpha},
 		CPICollector:           {Default: false, PreRelease: featuregate.Alpha},
 		Libpfm4:                {Default: false, PreRelease: featuregate.Alpha},
 		PSICollector:           {Default: false, PreRelease: featuregate.Alpha},
@@ -192,6 +201,7 @@ var (
 		ColdPageCollector:      {Default: false, PreRelease: featuregate.Alpha},
 		HugePageReport:         {Default: false, PreRelease: featuregate.Alpha},
 		PodResourcesProxy:      {Default: false, PreRelease: featuregate.Alpha},
+		DeviceDebugHTTPHandler: {Default: false, PreRelease: featuregate.Alpha},
 	}
 )
 
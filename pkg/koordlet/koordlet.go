@@ -73,6 +73,9 @@ func NewDaemon(config *config.Configuration) (Daemon, error) {
 	if len(nodeName) == 0 {
 		return nil, fmt.Errorf("failed to new daemon: NODE_NAME env is empty")
 	}
+	if err := config.StatesInformerConf.Validate(); err != nil {
+		return nil, fmt.Errorf("failed to new daemon: %w", err)
+	}
 	klog.Infof("NODE_NAME is %v, start time %v", nodeName, float64(time.Now().Unix()))
 	metrics.RecordKoordletStartTime(nodeName, float64(time.Now().Unix()))
 
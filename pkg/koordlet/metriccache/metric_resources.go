@@ -26,6 +26,13 @@ var (
 	NodeGPUCoreUsageMetric             = defaultMetricFactory.New(NodeMetricGPUCoreUsage).withPropertySchema(MetricPropertyGPUMinor, MetricPropertyGPUDeviceUUID)
 	NodeGPUMemUsageMetric              = defaultMetricFactory.New(NodeMetricGPUMemUsage).withPropertySchema(MetricPropertyGPUMinor, MetricPropertyGPUDeviceUUID)
 	NodeGPUMemTotalMetric              = defaultMetricFactory.New(NodeMetricGPUMemTotal).withPropertySchema(MetricPropertyGPUMinor, MetricPropertyGPUDeviceUUID)
+	NodeGPUEncoderUsageMetric          = defaultMetricFactory.New(NodeMetricGPUEncoderUsage).withPropertySchema(MetricPropertyGPUMinor, MetricPropertyGPUDeviceUUID)
+	NodeGPUDecoderUsageMetric          = defaultMetricFactory.New(NodeMetricGPUDecoderUsage).withPropertySchema(MetricPropertyGPUMinor, MetricPropertyGPUDeviceUUID)
+	NodeGPUMemBandwidthUsageMetric     = defaultMetricFactory.New(NodeMetricGPUMemBandwidthUsage).withPropertySchema(MetricPropertyGPUMinor, MetricPropertyGPUDeviceUUID)
+	NodeGPUFanSpeedMetric              = defaultMetricFactory.New(NodeMetricGPUFanSpeed).withPropertySchema(MetricPropertyGPUMinor, MetricPropertyGPUDeviceUUID)
+	NodeGPURetiredPagesMetric          = defaultMetricFactory.New(NodeMetricGPURetiredPages).withPropertySchema(MetricPropertyGPUMinor, MetricPropertyGPUDeviceUUID)
+	NodeGPUBAR1MemTotalMetric          = defaultMetricFactory.New(NodeMetricGPUBAR1MemTotal).withPropertySchema(MetricPropertyGPUMinor, MetricPropertyGPUDeviceUUID)
+	NodeGPUBAR1MemUsageMetric          = defaultMetricFactory.New(NodeMetricGPUBAR1MemUsage).withPropertySchema(MetricPropertyGPUMinor, MetricPropertyGPUDeviceUUID)
 
 	// define system resource usage as independent metric, although this can be calculate by node-sum(pod), but the time series are
 	// unaligned across different type of metric, which makes it hard to aggregate.
@@ -17,7 +17,9 @@ limitations under the License.
 package metriccache
 
 import (
+	"fmt"
 	"math"
+	"strings"
 	"time"
 
 	promstorage "github.com/prometheus/prometheus/storage"
@@ -76,6 +78,7 @@ type AggregationType string
 
 const (
 	AggregationTypeAVG   AggregationType = "avg"
+	AggregationTypeMax   AggregationType = "max"
 	AggregationTypeP99   AggregationType = "p99"
 	AggregationTypeP95   AggregationType = "P95"
 	AggregationTypeP90   AggregationType = "P90"
@@ -84,6 +87,18 @@ const (
 	AggregationTypeCount AggregationType = "count"
 )
 
+// ParseAggregationType returns the AggregationType of the name case-insensitively, e.g. "p95" for AggregationTypeP95.
+// Only the aggregations summarizing the values over a window are parsed, i.e. last and count are rejected.
+func ParseAggregationType(name string) (AggregationType, error) {
+	for _, t := range []AggregationType{AggregationTypeAVG, AggregationTypeMax, AggregationTypeP99, AggregationTypeP95,
+		AggregationTypeP90, AggregationTypeP50} {
+		if strings.EqualFold(string(t), name) {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported aggregation type %q, must be one of avg, max, p50, p90, p95, p99", name)
+}
+
 // AggregateParam defines the field name of value and time in series struct
 type AggregateParam struct {
 	ValueFieldName string
@@ -157,6 +172,8 @@ func getAggregateFunc(aggregationType AggregationType) AggregationFunc {
 	switch aggregationType {
 	case AggregationTypeAVG:
 		return fieldAvgOfMetricList
+	case AggregationTypeMax:
+		return fieldMaxOfMetricList
 	case AggregationTypeP99:
 		return percentileFuncOfMetricList(0.99)
 	case AggregationTypeP95:
@@ -0,0 +1,48 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metriccache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAggregationType(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    AggregationType
+		wantErr bool
+	}{
+		{name: "avg", want: AggregationTypeAVG},
+		{name: "MAX", want: AggregationTypeMax},
+		{name: "p95", want: AggregationTypeP95},
+		{name: "P99", want: AggregationTypeP99},
+		{name: "p50", want: AggregationTypeP50},
+		{name: "p90", want: AggregationTypeP90},
+		{name: "last", wantErr: true},
+		{name: "count", wantErr: true},
+		{name: "median", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAggregationType(tt.name)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -43,6 +43,21 @@ const (
 	NodeMetricGPUCoreUsage       MetricKind = "node_gpu_core_usage"
 	NodeMetricGPUMemUsage        MetricKind = "node_gpu_memory_usage"
 	NodeMetricGPUMemTotal        MetricKind = "node_gpu_memory_total"
+	NodeMetricGPUEncoderUsage    MetricKind = "node_gpu_encoder_usage"
+	NodeMetricGPUDecoderUsage    MetricKind = "node_gpu_decoder_usage"
+	// NodeMetricGPUMemBandwidthUsage is the percent of time the device memory is read or written, which tells the
+	// memory-bound load from the compute-bound load together with the core usage
+	NodeMetricGPUMemBandwidthUsage MetricKind = "node_gpu_memory_bandwidth_usage"
+	// NodeMetricGPUFanSpeed is the fan speed of the device in percentage of the max speed, which is not collected
+	// for the passively-cooled devices
+	NodeMetricGPUFanSpeed MetricKind = "node_gpu_fan_speed"
+	// NodeMetricGPURetiredPages is the count of the memory pages retired by the ECC errors of the device, which is
+	// not collected for the devices using the row remapping instead, e.g. Ampere and newer
+	NodeMetricGPURetiredPages MetricKind = "node_gpu_retired_pages"
+	// NodeMetricGPUBAR1MemTotal and NodeMetricGPUBAR1MemUsage are the total and used BAR1 memory of the device in
+	// bytes, which maps the device memory for the peer-to-peer accesses, e.g. the CUDA IPC and the GPUDirect RDMA
+	NodeMetricGPUBAR1MemTotal MetricKind = "node_gpu_bar1_memory_total"
+	NodeMetricGPUBAR1MemUsage MetricKind = "node_gpu_bar1_memory_usage"
 
 	SysMetricCPUUsage    MetricKind = "sys_cpu_usage"
 	SysMetricMemoryUsage MetricKind = "sys_memory_usage"
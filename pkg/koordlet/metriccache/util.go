@@ -52,6 +52,40 @@ func fieldAvgOfMetricList(metricsList interface{}, aggregateParam AggregateParam
 	return sum / float64(metrics.Len()), nil
 }
 
+func fieldMaxOfMetricList(metricsList interface{}, aggregateParam AggregateParam) (float64, error) {
+	maxValue := 0.0
+
+	inputType := reflect.TypeOf(metricsList).Kind()
+	if inputType != reflect.Slice && inputType != reflect.Array {
+		return 0, fmt.Errorf("metrics input type must be slice or array, %v is illegal", inputType.String())
+	}
+
+	metrics := reflect.ValueOf(metricsList)
+	if metrics.Len() == 0 {
+		return 0, fmt.Errorf("metric input is empty")
+	}
+
+	for i := 0; i < metrics.Len(); i++ {
+		metricStruct := metrics.Index(i)
+		if metricStruct.Kind() == reflect.Ptr {
+			// convert to struct for list with ptr
+			metricStruct = metricStruct.Elem()
+		}
+		fieldValue := metricStruct.FieldByName(aggregateParam.ValueFieldName)
+		if !fieldValue.IsValid() {
+			return 0, fmt.Errorf("fieldValue not Valid, metricStruct: %v ", metricStruct)
+		}
+		fieldType := fieldValue.Type().Kind()
+		if fieldType != reflect.Float32 && fieldType != reflect.Float64 {
+			return 0, fmt.Errorf("field type must be float32 or float64, %v is illegal", fieldType.String())
+		}
+		if i == 0 || fieldValue.Float() > maxValue {
+			maxValue = fieldValue.Float()
+		}
+	}
+	return maxValue, nil
+}
+
 func fieldPercentileOfMetricList(metricsList interface{}, aggregateParam AggregateParam, percentile float32) (float64, error) {
 	if percentile <= 0 || percentile > 1 || float32(int32(percentile*1000))/1000 != percentile {
 		return 0, fmt.Errorf("metrics percentile must be a fixed-point number between 0.001 to 1.000, %v is illegal",
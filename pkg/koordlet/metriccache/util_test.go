@@ -151,6 +151,71 @@ func Test_fieldPercentileOfMetricList(t *testing.T) {
 	}
 }
 
+func Test_fieldMaxOfMetricList(t *testing.T) {
+	tests := []struct {
+		name        string
+		metricsList interface{}
+		want        float64
+		wantErr     bool
+	}{
+		{
+			name:        "do not panic",
+			metricsList: 1,
+			want:        0,
+			wantErr:     true,
+		},
+		{
+			name: "trow error for illegal list length",
+			metricsList: []struct {
+				v float64
+			}{},
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name: "trow error for illegal element type",
+			metricsList: []struct {
+				v int
+			}{
+				{v: 1000},
+			},
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name: "calculate multi-element list",
+			metricsList: []struct {
+				v float64
+			}{
+				{v: 20},
+				{v: 95},
+				{v: 0},
+				{v: 60},
+			},
+			want:    95,
+			wantErr: false,
+		},
+		{
+			name: "calculate ptr list",
+			metricsList: []*struct {
+				v float32
+			}{
+				{v: 10},
+				{v: 5},
+			},
+			want:    10,
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fieldMaxOfMetricList(tt.metricsList, AggregateParam{ValueFieldName: "v"})
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
 func Test_fieldLastOfMetricList(t *testing.T) {
 	type args struct {
 		metricsList interface{}
@@ -17,6 +17,8 @@ limitations under the License.
 package metrics
 
 import (
+	"strconv"
+
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/koordinator-sh/koordinator/pkg/util/metrics"
@@ -71,6 +73,30 @@ var (
 		Help:      "Memory used by node in realtime",
 	}, []string{NodeKey})
 
+	GPUSubsystemDegraded = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: KoordletSubsystem,
+		Name:      "gpu_subsystem_degraded",
+		Help:      "Whether the calls to the gpu library are suspended after repeated failures, 1 for degraded and 0 for healthy",
+	}, []string{NodeKey})
+
+	GPUHealthCheckRegistrationFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: KoordletSubsystem,
+		Name:      "gpu_health_check_registration_failures",
+		Help:      "Number of gpus failed to register the health events, which are excluded from the gpu health check",
+	}, []string{NodeKey, GPUHealthCheckFailureReasonKey})
+
+	GPUUnhealthy = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: KoordletSubsystem,
+		Name:      "gpu_unhealthy",
+		Help:      "The gpus marked unhealthy by the gpu health check, which is 1 for each unhealthy gpu present on the node",
+	}, []string{NodeKey, GPUUUIDKey})
+
+	NodeGPUUtilization = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: KoordletSubsystem,
+		Name:      "node_gpu_utilization",
+		Help:      "The utilization percentage of the gpu resources aggregated over the node metric window, e.g. the avg and max of gpu-core",
+	}, []string{NodeKey, GPUMinorKey, GPUUUIDKey, ResourceKey, AggregationTypeKey})
+
 	CommonCollectors = []prometheus.Collector{
 		KoordletStartTime,
 		CollectNodeCPUInfoStatus,
@@ -80,6 +106,10 @@ var (
 		PodEvictionDetail.GetCounterVec(),
 		NodeUsedCPU,
 		NodeUsedMemory,
+		GPUSubsystemDegraded,
+		GPUHealthCheckRegistrationFailures,
+		GPUUnhealthy,
+		NodeGPUUtilization,
 	}
 )
 
@@ -156,6 +186,65 @@ func RecordNodeUsedMemory(value float64) {
 	NodeUsedMemory.With(labels).Set(value)
 }
 
+func RecordGPUSubsystemDegraded(degraded bool) {
+	labels := genNodeLabels()
+	if labels == nil {
+		return
+	}
+	value := 0.0
+	if degraded {
+		value = 1.0
+	}
+	GPUSubsystemDegraded.With(labels).Set(value)
+}
+
+const (
+	// GPUHealthCheckNotSupported means the gpu is too old to support the health events, which is marked unhealthy
+	GPUHealthCheckNotSupported = "not_supported"
+	// GPUHealthCheckGetDeviceFailed means the gpu cannot be found by the uuid, e.g. fallen off the bus
+	GPUHealthCheckGetDeviceFailed = "get_device_failed"
+	// GPUHealthCheckRegisterFailed means the registration of the health events fails for other errors
+	GPUHealthCheckRegisterFailed = "register_failed"
+)
+
+func RecordGPUHealthCheckRegistrationFailure(reason string) {
+	labels := genNodeLabels()
+	if labels == nil {
+		return
+	}
+	labels[GPUHealthCheckFailureReasonKey] = reason
+	GPUHealthCheckRegistrationFailures.With(labels).Inc()
+}
+
+func RecordGPUUnhealthy(uuid string) {
+	labels := genNodeLabels()
+	if labels == nil {
+		return
+	}
+	labels[GPUUUIDKey] = uuid
+	GPUUnhealthy.With(labels).Set(1)
+}
+
+func ResetGPUUnhealthy() {
+	GPUUnhealthy.Reset()
+}
+
+func RecordNodeGPUUtilization(minor int32, uuid string, resourceName string, aggregationType string, value float64) {
+	labels := genNodeLabels()
+	if labels == nil {
+		return
+	}
+	labels[GPUMinorKey] = strconv.Itoa(int(minor))
+	labels[GPUUUIDKey] = uuid
+	labels[ResourceKey] = resourceName
+	labels[AggregationTypeKey] = aggregationType
+	NodeGPUUtilization.With(labels).Set(value)
+}
+
+func ResetNodeGPUUtilization() {
+	NodeGPUUtilization.Reset()
+}
+
 func labelsClone(labels prometheus.Labels) prometheus.Labels {
 	copyLabels := prometheus.Labels{}
 	for key, value := range labels {
@@ -0,0 +1,116 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const (
+	// DeviceTypeKey is the type of the reported devices, e.g. gpu.
+	DeviceTypeKey = "device_type"
+	// DeviceReportOperationKey is the operation applied to the Device, i.e. create or update.
+	DeviceReportOperationKey = "operation"
+	// DeviceReportStageKey is the stage of the reporting cycle which fails.
+	DeviceReportStageKey = "stage"
+)
+
+const (
+	DeviceReportOperationCreate = "create"
+	DeviceReportOperationUpdate = "update"
+
+	// DeviceReportStageGetNode means the node is not synced yet
+	DeviceReportStageGetNode = "get_node"
+	// DeviceReportStageBuildGPU means the gpus fail to be built, while the other devices are still reported
+	DeviceReportStageBuildGPU = "build_gpu"
+	// DeviceReportStageUpdate means the Device fails to be patched
+	DeviceReportStageUpdate = "update"
+	// DeviceReportStageCreate means the Device fails to be created, including being deferred for the unstable gpus
+	DeviceReportStageCreate = "create"
+)
+
+var (
+	deviceReportDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Subsystem: KoordletSubsystem,
+		Name:      "device_report_duration_seconds",
+		Help:      "time duration of the Device reporting cycles in seconds",
+		// 10ms ~ 20.48s, the collection of the devices and the requests to apiserver
+		Buckets: prometheus.ExponentialBuckets(0.01, 2, 12),
+	}, []string{NodeKey, StatusKey})
+
+	deviceReportedDevices = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: KoordletSubsystem,
+		Name:      "device_reported_devices",
+		Help:      "Number of the devices of each type in the Device reported by the last successful cycle",
+	}, []string{NodeKey, DeviceTypeKey})
+
+	deviceReportOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: KoordletSubsystem,
+		Name:      "device_report_operations",
+		Help:      "Number of the Device creations and updates sent to apiserver by the reporting cycles",
+	}, []string{NodeKey, DeviceReportOperationKey})
+
+	deviceReportErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: KoordletSubsystem,
+		Name:      "device_report_errors",
+		Help:      "Number of the Device reporting cycles with errors by the stage which fails",
+	}, []string{NodeKey, DeviceReportStageKey})
+
+	DeviceReportCollectors = []prometheus.Collector{
+		deviceReportDurationSeconds,
+		deviceReportedDevices,
+		deviceReportOperations,
+		deviceReportErrors,
+	}
+)
+
+func RecordDeviceReportDuration(err error, seconds float64) {
+	labels := genNodeLabels()
+	if labels == nil {
+		return
+	}
+	labels[StatusKey] = StatusSucceed
+	if err != nil {
+		labels[StatusKey] = StatusFailed
+	}
+	deviceReportDurationSeconds.With(labels).Observe(seconds)
+}
+
+func RecordDeviceReportedDevices(deviceType string, count int) {
+	labels := genNodeLabels()
+	if labels == nil {
+		return
+	}
+	labels[DeviceTypeKey] = deviceType
+	deviceReportedDevices.With(labels).Set(float64(count))
+}
+
+func RecordDeviceReportOperation(operation string) {
+	labels := genNodeLabels()
+	if labels == nil {
+		return
+	}
+	labels[DeviceReportOperationKey] = operation
+	deviceReportOperations.With(labels).Inc()
+}
+
+func RecordDeviceReportError(stage string) {
+	labels := genNodeLabels()
+	if labels == nil {
+		return
+	}
+	labels[DeviceReportStageKey] = stage
+	deviceReportErrors.With(labels).Inc()
+}
@@ -45,4 +45,5 @@ func init() {
 	internalMustRegister(KubeletStubCollector...)
 	internalMustRegister(RuntimeHookCollectors...)
 	internalMustRegister(HostApplicationCollectors...)
+	internalMustRegister(DeviceReportCollectors...)
 }
@@ -39,6 +39,12 @@ const (
 	EvictionReasonKey = "reason"
 	BESuppressTypeKey = "type"
 
+	GPUHealthCheckFailureReasonKey = "reason"
+
+	GPUMinorKey        = "minor"
+	GPUUUIDKey         = "uuid"
+	AggregationTypeKey = "aggregation"
+
 	ContainerID   = "container_id"
 	ContainerName = "container_name"
 
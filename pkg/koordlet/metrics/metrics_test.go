@@ -141,6 +141,13 @@ func TestCommonCollectors(t *testing.T) {
 		RecordBESuppressBEUsedCPU(1.0)
 		RecordNodeUsedCPU(2.0)
 		RecordNodeUsedMemory(float64(1024))
+		RecordGPUSubsystemDegraded(true)
+		RecordGPUSubsystemDegraded(false)
+		RecordGPUHealthCheckRegistrationFailure(GPUHealthCheckNotSupported)
+		ResetGPUUnhealthy()
+		RecordGPUUnhealthy("GPU-xxx")
+		ResetNodeGPUUtilization()
+		RecordNodeGPUUtilization(0, "GPU-xxx", "gpu-core", "max", 95)
 		RecordContainerScaledCFSBurstUS(testingPod.Namespace, testingPod.Name, testingContainer.ContainerID, testingContainer.Name, 1000000)
 		RecordContainerScaledCFSQuotaUS(testingPod.Namespace, testingPod.Name, testingContainer.ContainerID, testingContainer.Name, 1000000)
 		RecordPodEviction(testingPod.Namespace, testingPod.Name, "evictByCPU")
@@ -446,3 +453,23 @@ func TestHostApplicationCollectors(t *testing.T) {
 		ResetHostApplicationResourceUsage()
 	})
 }
+
+func TestDeviceReportCollectors(t *testing.T) {
+	testingNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "test-node",
+			Labels: map[string]string{},
+		},
+	}
+	testErr := fmt.Errorf("expected error")
+	t.Run("test", func(t *testing.T) {
+		Register(testingNode)
+		defer Register(nil)
+		RecordDeviceReportDuration(nil, 0.1)
+		RecordDeviceReportDuration(testErr, 1)
+		RecordDeviceReportedDevices("gpu", 8)
+		RecordDeviceReportOperation(DeviceReportOperationCreate)
+		RecordDeviceReportOperation(DeviceReportOperationUpdate)
+		RecordDeviceReportError(DeviceReportStageBuildGPU)
+	})
+}
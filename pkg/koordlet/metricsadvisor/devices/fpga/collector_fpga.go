@@ -0,0 +1,79 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fpga
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/koordinator-sh/koordinator/pkg/features"
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/metriccache"
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/metricsadvisor/framework"
+)
+
+const (
+	DeviceCollectorName = "FPGA"
+)
+
+type fpgaCollector struct {
+	enabled bool
+}
+
+func New(opt *framework.Options) framework.DeviceCollector {
+	return &fpgaCollector{
+		enabled: features.DefaultKoordletFeatureGate.Enabled(features.FPGADevices),
+	}
+}
+
+func (f *fpgaCollector) Shutdown() {
+}
+
+func (f *fpgaCollector) Enabled() bool {
+	return f.enabled
+}
+
+func (f *fpgaCollector) Setup(fra *framework.Context) {
+}
+
+func (f *fpgaCollector) Run(stopCh <-chan struct{}) {
+}
+
+func (f *fpgaCollector) Started() bool {
+	return true
+}
+
+func (f *fpgaCollector) Infos() metriccache.Devices {
+	fpgaDevices, err := GetFPGADevices()
+	if err != nil {
+		// do not report the devices if the discovery failed, so that the reported devices are kept unchanged
+		klog.Errorf("failed to get fpga devices: %v", err)
+		return nil
+	}
+	return fpgaDevices
+}
+
+func (f *fpgaCollector) GetNodeMetric() ([]metriccache.MetricSample, error) {
+	return nil, nil
+}
+
+func (f *fpgaCollector) GetPodMetric(uid, podParentDir string, cs []corev1.ContainerStatus) ([]metriccache.MetricSample, error) {
+	return nil, nil
+}
+
+func (f *fpgaCollector) GetContainerMetric(containerID, podParentDir string, c *corev1.ContainerStatus) ([]metriccache.MetricSample, error) {
+	return nil, nil
+}
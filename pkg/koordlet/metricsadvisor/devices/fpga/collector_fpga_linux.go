@@ -0,0 +1,106 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fpga
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/metriccache"
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/metricsadvisor/devices/helper"
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/util"
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+const (
+	xilinxVendorID = "0x10ee"
+	// xilinxMgmtDriver is the XRT driver of the management physical function, which is not allocatable to pods.
+	xilinxMgmtDriver = "xclmgmt"
+)
+
+// GetFPGADevices discovers the Xilinx FPGA devices from the PCI devices in sysfs.
+// Each Alveo card exposes a management function and a user function, only the user function is reported.
+// A device is regarded as healthy if it is present and bound to a driver, e.g. the XRT user driver "xocl".
+func GetFPGADevices() (metriccache.Devices, error) {
+	pciDeviceDir := system.GetPCIDeviceDir()
+	entries, err := os.ReadDir(pciDeviceDir)
+	if err != nil {
+		return nil, fmt.Errorf("getFPGADevices(): read pci devices error, %v", err)
+	}
+	fpgaDevices := util.FPGADevices{}
+	for _, entry := range entries {
+		address := entry.Name()
+		vendor, err := readPCIDeviceFile(address, "vendor")
+		if err != nil || vendor != xilinxVendorID {
+			continue
+		}
+		if system.IsSriovVF(address) {
+			continue
+		}
+		driver := getPCIDeviceDriver(address)
+		if driver == xilinxMgmtDriver {
+			continue
+		}
+		deviceCode, _ := readPCIDeviceFile(address, "device")
+		fpgaDevice := util.FPGADeviceInfo{
+			ID:         address,
+			VendorCode: vendor,
+			DeviceCode: deviceCode,
+			Driver:     driver,
+			BusID:      address,
+			Unhealthy:  driver == "",
+		}
+		nodeID, pcie, _, err := helper.ParsePCIInfo(address)
+		if err != nil {
+			klog.Errorf("getFPGADevices(): parse pci device %s error, %v", address, err)
+			return nil, err
+		}
+		fpgaDevice.NodeID = nodeID
+		fpgaDevice.PCIE = pcie
+		fpgaDevices = append(fpgaDevices, fpgaDevice)
+	}
+	sort.Slice(fpgaDevices, func(i, j int) bool {
+		return fpgaDevices[i].BusID < fpgaDevices[j].BusID
+	})
+	for i := range fpgaDevices {
+		fpgaDevices[i].Minor = int32(i)
+	}
+	klog.V(5).Infof("fpga devices: %+v", fpgaDevices)
+	return fpgaDevices, nil
+}
+
+func readPCIDeviceFile(address, file string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(system.GetPCIDeviceDir(), address, file))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// getPCIDeviceDriver returns the name of the driver bound to the device, or empty if no driver is bound.
+func getPCIDeviceDriver(address string) string {
+	driverPath, err := filepath.EvalSymlinks(filepath.Join(system.GetPCIDeviceDir(), address, "driver"))
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(driverPath)
+}
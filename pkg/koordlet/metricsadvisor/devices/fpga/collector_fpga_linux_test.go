@@ -0,0 +1,80 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fpga
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/util"
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
+)
+
+func Test_GetFPGADevices(t *testing.T) {
+	helper := system.NewFileTestUtil(t)
+	defer helper.Cleanup()
+
+	pciDeviceDir := system.GetPCIDeviceDir()
+	driverDir := filepath.Join(helper.TempDir, "drivers")
+	addDevice := func(busID, vendor, device, driver string) {
+		deviceDir := filepath.Join(pciDeviceDir, "pci0000:00", busID)
+		assert.NoError(t, os.MkdirAll(deviceDir, 0700))
+		assert.NoError(t, os.WriteFile(filepath.Join(deviceDir, "vendor"), []byte(vendor+"\n"), 0600))
+		assert.NoError(t, os.WriteFile(filepath.Join(deviceDir, "device"), []byte(device+"\n"), 0600))
+		assert.NoError(t, os.WriteFile(filepath.Join(deviceDir, "numa_node"), []byte("1\n"), 0600))
+		if driver != "" {
+			assert.NoError(t, os.MkdirAll(filepath.Join(driverDir, driver), 0700))
+			assert.NoError(t, os.Symlink(filepath.Join(driverDir, driver), filepath.Join(deviceDir, "driver")))
+		}
+		assert.NoError(t, os.Symlink(deviceDir, filepath.Join(pciDeviceDir, busID)))
+	}
+	// the management and user functions of an Alveo card, and a user function without driver
+	addDevice("0000:3b:00.0", "0x10ee", "0x5004", "xclmgmt")
+	addDevice("0000:3b:00.1", "0x10ee", "0x5005", "xocl")
+	addDevice("0000:d8:00.1", "0x10ee", "0x5005", "")
+	// a non-fpga device
+	addDevice("0000:00:08.0", "0x10de", "0x20b0", "nvidia")
+
+	got, err := GetFPGADevices()
+	assert.NoError(t, err)
+	expected := util.FPGADevices{
+		{
+			ID:         "0000:3b:00.1",
+			Minor:      0,
+			VendorCode: "0x10ee",
+			DeviceCode: "0x5005",
+			Driver:     "xocl",
+			NodeID:     1,
+			PCIE:       "pci0000:00",
+			BusID:      "0000:3b:00.1",
+		},
+		{
+			ID:         "0000:d8:00.1",
+			Minor:      1,
+			VendorCode: "0x10ee",
+			DeviceCode: "0x5005",
+			NodeID:     1,
+			PCIE:       "pci0000:00",
+			BusID:      "0000:d8:00.1",
+			Unhealthy:  true,
+		},
+	}
+	assert.Equal(t, expected, got)
+}
@@ -35,6 +35,7 @@ const (
 type gpuCollector struct {
 	enabled          bool
 	collectInterval  time.Duration
+	pciDeviceIDs     framework.PCIDeviceIDs
 	gpuDeviceManager GPUDeviceManager
 }
 
@@ -42,6 +43,7 @@ func New(opt *framework.Options) framework.DeviceCollector {
 	return &gpuCollector{
 		enabled:         features.DefaultKoordletFeatureGate.Enabled(features.Accelerators),
 		collectInterval: opt.Config.CollectResUsedInterval,
+		pciDeviceIDs:    opt.Config.GPUPCIDeviceIDs,
 	}
 }
 
@@ -56,7 +58,7 @@ func (g *gpuCollector) Enabled() bool {
 }
 
 func (g *gpuCollector) Setup(fra *framework.Context) {
-	g.gpuDeviceManager = initGPUDeviceManager()
+	g.gpuDeviceManager = initGPUDeviceManager(g.pciDeviceIDs)
 }
 
 func (g *gpuCollector) Run(stopCh <-chan struct{}) {
@@ -17,9 +17,11 @@ limitations under the License.
 package gpu
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -32,6 +34,7 @@ import (
 	"github.com/koordinator-sh/koordinator/pkg/features"
 	"github.com/koordinator-sh/koordinator/pkg/koordlet/metriccache"
 	"github.com/koordinator-sh/koordinator/pkg/koordlet/metricsadvisor/devices/helper"
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/metricsadvisor/framework"
 	"github.com/koordinator-sh/koordinator/pkg/koordlet/util"
 )
 
@@ -42,6 +45,32 @@ type gpuDeviceManager struct {
 	collectTime      time.Time
 	start            *atomic.Bool
 	processesMetrics map[uint32][]*rawGPUMetric
+	// codecMetrics is the encoder and decoder utilization of each device, indexed as the devices
+	codecMetrics []*rawGPUCodecMetric
+	// memBandwidthMetrics is the memory controller utilization of each device in percentage, indexed as the devices
+	memBandwidthMetrics []*uint32
+	// migModes is the MIG mode of each device, indexed as the devices, nil if the device is not MIG-capable
+	migModes []*gpuMigMode
+	// persistenceModes is whether the persistence mode of each device is enabled, indexed as the devices, nil if the
+	// device does not support the persistence mode
+	persistenceModes []*bool
+	// fanSpeeds is the fan speed of each device in percentage, indexed as the devices, nil if the device does not
+	// report the fan speed, e.g. the passively-cooled datacenter gpus
+	fanSpeeds []*uint32
+	// coolingDegraded is whether the fan of each device stops while the device is thermally loaded, indexed as the
+	// devices
+	coolingDegraded []bool
+	// retiredPages is the memory pages retired of each device, indexed as the devices, nil if the device does not
+	// support the page retirement, e.g. the devices using the row remapping instead
+	retiredPages []*gpuRetiredPages
+	// bar1MemoryUsed is the used BAR1 memory of each device in bytes, indexed as the devices, nil if not supported
+	bar1MemoryUsed []*uint64
+	// fabricPartitions is the fabric partition id of each device indexed by the uuid
+	fabricPartitions map[string]string
+	// fabricPartitionGPUs is the gpu set which the fabricPartitions is computed with
+	fabricPartitionGPUs string
+	// nvmlBreaker suspends the collection after repeated nvml failures, which is shared with the statesinformer
+	nvmlBreaker *util.CircuitBreaker
 }
 
 type rawGPUMetric struct {
@@ -49,31 +78,61 @@ type rawGPUMetric struct {
 	MemoryUsed uint64
 }
 
+// rawGPUCodecMetric is the utilization of the video encoder and decoder engines in percentage.
+type rawGPUCodecMetric struct {
+	EncoderUtil *uint32
+	DecoderUtil *uint32
+}
+
+// gpuMigMode is the current and pending MIG mode of a MIG-capable device.
+// The pending mode takes effect after the gpu is reset.
+type gpuMigMode struct {
+	Enabled        bool
+	PendingEnabled bool
+}
+
 type device struct {
 	Minor       int32 // index starting from 0
 	DeviceUUID  string
 	MemoryTotal uint64
-	NodeID      int32
-	PCIE        string
-	BusID       string
-	Device      nvml.Device
+	// BAR1MemoryTotal is the total BAR1 memory in bytes, 0 if not supported
+	BAR1MemoryTotal   uint64
+	NodeID            int32
+	PCIE              string
+	BusID             string
+	ComputeCapability string
+	ProductName       string
+	EncoderSupported  bool
+	DecoderSupported  bool
+	// NvLinkRemoteBusIDs are the bus ids of the devices connected by the active NVLinks, e.g. the NVSwitches
+	NvLinkRemoteBusIDs []string
+	// FieldValuesUnsupported is whether the driver does not support the batch queries of the field values, in which
+	// case the metrics are queried by the individual calls. It is only accessed by the collection.
+	FieldValuesUnsupported bool
+	Device                 nvml.Device
 }
 
 // initGPUDeviceManager will not retry if init fails,
-func initGPUDeviceManager() GPUDeviceManager {
+// Only the gpus of the pciDeviceIDs are discovered if it is not empty.
+func initGPUDeviceManager(pciDeviceIDs framework.PCIDeviceIDs) GPUDeviceManager {
 	if !features.DefaultKoordletFeatureGate.Enabled(features.Accelerators) {
 		return &dummyDeviceManager{}
 	}
 	if ret := nvml.Init(); ret != nvml.SUCCESS {
 		if ret == nvml.ERROR_LIBRARY_NOT_FOUND {
 			klog.Warning("nvml init failed, library not found")
+			// try the Intel GPUs when there is no NVIDIA driver
+			if manager := initXPUDeviceManager(); manager != nil {
+				klog.Info("use xpu-smi to discover Intel gpus")
+				return manager
+			}
 			return &dummyDeviceManager{}
 		}
 		klog.Warningf("nvml init failed, return %s", nvml.ErrorString(ret))
 		return &dummyDeviceManager{}
 	}
-	manager := &gpuDeviceManager{start: atomic.NewBool(false)}
-	if err := manager.initGPUData(); err != nil {
+	manager := &gpuDeviceManager{start: atomic.NewBool(false), nvmlBreaker: util.NVMLCircuitBreaker}
+	if err := manager.initGPUData(pciDeviceIDs); err != nil {
 		klog.Warningf("nvml init gpu data, error %s", err)
 		manager.shutdown()
 		return &dummyDeviceManager{}
@@ -90,7 +149,7 @@ func (g *gpuDeviceManager) shutdown() error {
 	return nil
 }
 
-func (g *gpuDeviceManager) initGPUData() error {
+func (g *gpuDeviceManager) initGPUData(pciDeviceIDs framework.PCIDeviceIDs) error {
 	count, ret := nvml.DeviceGetCount()
 	if ret != nvml.SUCCESS {
 		return fmt.Errorf("unable to get device count: %v", nvml.ErrorString(ret))
@@ -98,13 +157,22 @@ func (g *gpuDeviceManager) initGPUData() error {
 	if count == 0 {
 		return errors.New("no gpu device found")
 	}
-	devices := make([]*device, count)
+	devices := make([]*device, 0, count)
 	for deviceIndex := 0; deviceIndex < count; deviceIndex++ {
 		gpudevice, ret := nvml.DeviceGetHandleByIndex(deviceIndex)
 		if ret != nvml.SUCCESS {
 			return fmt.Errorf("unable to get device at index %d: %v", deviceIndex, nvml.ErrorString(ret))
 		}
 
+		pciInfo, ret := gpudevice.GetPciInfo()
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("unable to get pci info: %v", nvml.ErrorString(ret))
+		}
+		if pciDeviceID := getPCIDeviceID(pciInfo); !pciDeviceIDs.Allows(pciDeviceID) {
+			klog.V(4).Infof("skip the device at index %d whose pci id %s is not allowed", deviceIndex, pciDeviceID)
+			continue
+		}
+
 		uuid, ret := gpudevice.GetUUID()
 		if ret != nvml.SUCCESS {
 			return fmt.Errorf("unable to get device uuid: %v", nvml.ErrorString(ret))
@@ -119,52 +187,220 @@ func (g *gpuDeviceManager) initGPUData() error {
 		if ret != nvml.SUCCESS {
 			return fmt.Errorf("unable to get device memory info: %v", nvml.ErrorString(ret))
 		}
-		pciInfo, ret := gpudevice.GetPciInfo()
-		if ret != nvml.SUCCESS {
-			return fmt.Errorf("unable to get pci info: %v", nvml.ErrorString(ret))
-		}
-		busIDBuilder := &strings.Builder{}
-		for _, v := range pciInfo.BusIdLegacy {
-			if v != 0 {
-				busIDBuilder.WriteByte(byte(v))
-			}
-		}
-		busID := strings.ToLower(busIDBuilder.String())
+		busID := formatPCIBusID(pciInfo)
 		nodeID, pcie, busID, err := helper.ParsePCIInfo(busID)
 		if err != nil {
 			return err
 		}
-		devices[deviceIndex] = &device{
-			DeviceUUID:  uuid,
-			Minor:       int32(minor),
-			MemoryTotal: memory.Total,
-			NodeID:      nodeID,
-			PCIE:        pcie,
-			BusID:       busID,
-			Device:      gpudevice,
+		// compute capability and product name are only used for scheduling constraints, do not fail the init
+		var computeCapability string
+		if ccMajor, ccMinor, ret := gpudevice.GetCudaComputeCapability(); ret == nvml.SUCCESS {
+			computeCapability = fmt.Sprintf("%d.%d", ccMajor, ccMinor)
+		} else {
+			klog.Warningf("unable to get device compute capability at index %d: %v", deviceIndex, nvml.ErrorString(ret))
 		}
+		var productName string
+		if name, ret := gpudevice.GetName(); ret == nvml.SUCCESS {
+			productName = util.FormatGPUProductName(name)
+		} else {
+			klog.Warningf("unable to get device name at index %d: %v", deviceIndex, nvml.ErrorString(ret))
+		}
+		// the BAR1 memory is only reported as a metric and an optional resource, do not fail the init
+		var bar1MemoryTotal uint64
+		if bar1Memory, ret := gpudevice.GetBAR1MemoryInfo(); ret == nvml.SUCCESS {
+			bar1MemoryTotal = bar1Memory.Bar1Total
+		} else {
+			klog.Warningf("unable to get device bar1 memory info at index %d: %v", deviceIndex, nvml.ErrorString(ret))
+		}
+		// the devices without the video engines return NOT_SUPPORTED, e.g. A100
+		_, _, encoderRet := gpudevice.GetEncoderUtilization()
+		_, _, decoderRet := gpudevice.GetDecoderUtilization()
+		devices = append(devices, &device{
+			DeviceUUID:         uuid,
+			Minor:              int32(minor),
+			MemoryTotal:        memory.Total,
+			BAR1MemoryTotal:    bar1MemoryTotal,
+			NodeID:             nodeID,
+			PCIE:               pcie,
+			BusID:              busID,
+			ComputeCapability:  computeCapability,
+			ProductName:        productName,
+			EncoderSupported:   encoderRet == nvml.SUCCESS,
+			DecoderSupported:   decoderRet == nvml.SUCCESS,
+			NvLinkRemoteBusIDs: getNvLinkRemoteBusIDs(gpudevice),
+			Device:             gpudevice,
+		})
+	}
+	if len(devices) == 0 {
+		return fmt.Errorf("no gpu device of the pci ids %s found", pciDeviceIDs.String())
 	}
 
 	g.Lock()
 	defer g.Unlock()
-	g.deviceCount = count
+	g.deviceCount = len(devices)
 	g.devices = devices
+	g.updateFabricPartitions()
 	return nil
 }
 
+// getPCIDeviceID returns the PCI vendor and device id of the gpu, whose combined id has the device id in the upper
+// 16 bits and the vendor id in the lower 16 bits, e.g. 0x20b010de for the NVIDIA A100.
+func getPCIDeviceID(pciInfo nvml.PciInfo) framework.PCIDeviceID {
+	return framework.PCIDeviceID{
+		VendorID: uint16(pciInfo.PciDeviceId & 0xffff),
+		DeviceID: uint16(pciInfo.PciDeviceId >> 16),
+	}
+}
+
+// formatPCIBusID returns the bus id in the form of "domain:bus:device.function", e.g. "0000:3b:00.0".
+func formatPCIBusID(pciInfo nvml.PciInfo) string {
+	busIDBuilder := &strings.Builder{}
+	for _, v := range pciInfo.BusIdLegacy {
+		if v != 0 {
+			busIDBuilder.WriteByte(byte(v))
+		}
+	}
+	return strings.ToLower(busIDBuilder.String())
+}
+
+// getNvLinkRemoteBusIDs returns the bus ids of the remote devices of the active NVLinks.
+// The devices without NVLink return NOT_SUPPORTED and have no remote device.
+func getNvLinkRemoteBusIDs(gpudevice nvml.Device) []string {
+	var remoteBusIDs []string
+	for link := 0; link < nvml.NVLINK_MAX_LINKS; link++ {
+		state, ret := gpudevice.GetNvLinkState(link)
+		if ret != nvml.SUCCESS || state != nvml.FEATURE_ENABLED {
+			continue
+		}
+		pciInfo, ret := gpudevice.GetNvLinkRemotePciInfo(link)
+		if ret != nvml.SUCCESS {
+			klog.V(4).Infof("unable to get the remote pci info of nvlink %d: %v", link, nvml.ErrorString(ret))
+			continue
+		}
+		remoteBusIDs = append(remoteBusIDs, formatPCIBusID(pciInfo))
+	}
+	return remoteBusIDs
+}
+
+// updateFabricPartitions recomputes the fabric partitions only when the gpu set changes, the lock should be held
+// by the caller.
+func (g *gpuDeviceManager) updateFabricPartitions() {
+	uuids := make([]string, 0, len(g.devices))
+	for _, d := range g.devices {
+		uuids = append(uuids, d.DeviceUUID)
+	}
+	sort.Strings(uuids)
+	gpuSet := strings.Join(uuids, ",")
+	if g.fabricPartitions != nil && gpuSet == g.fabricPartitionGPUs {
+		return
+	}
+	g.fabricPartitions = groupFabricPartitions(g.devices)
+	g.fabricPartitionGPUs = gpuSet
+	klog.V(4).Infof("gpu fabric partitions: %v", g.fabricPartitions)
+}
+
+// groupFabricPartitions groups the gpus connected to the same NVSwitches into one fabric partition, and returns the
+// partition id of each gpu indexed by the uuid. The NVLinks between gpus, e.g. the NVLink bridges, are ignored since
+// they are not NVSwitches. The partitions are numbered from 0 in the order of their lowest minors.
+func groupFabricPartitions(devices []*device) map[string]string {
+	gpuBusIDs := map[string]bool{}
+	for _, d := range devices {
+		gpuBusIDs[d.BusID] = true
+	}
+	// union the gpus connected to a same switch
+	parents := make([]int, len(devices))
+	for i := range parents {
+		parents[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parents[i] != i {
+			parents[i] = find(parents[i])
+		}
+		return parents[i]
+	}
+	switchOwners := map[string]int{}
+	connected := make([]bool, len(devices))
+	for i, d := range devices {
+		for _, remote := range d.NvLinkRemoteBusIDs {
+			if gpuBusIDs[remote] {
+				continue
+			}
+			connected[i] = true
+			if owner, ok := switchOwners[remote]; ok {
+				parents[find(i)] = find(owner)
+			} else {
+				switchOwners[remote] = i
+			}
+		}
+	}
+
+	lowestMinors := map[int]int32{}
+	for i, d := range devices {
+		if !connected[i] {
+			continue
+		}
+		root := find(i)
+		if minor, ok := lowestMinors[root]; !ok || d.Minor < minor {
+			lowestMinors[root] = d.Minor
+		}
+	}
+	roots := make([]int, 0, len(lowestMinors))
+	for root := range lowestMinors {
+		roots = append(roots, root)
+	}
+	sort.Slice(roots, func(i, j int) bool {
+		return lowestMinors[roots[i]] < lowestMinors[roots[j]]
+	})
+	partitionIDs := make(map[int]string, len(roots))
+	for i, root := range roots {
+		partitionIDs[root] = strconv.Itoa(i)
+	}
+
+	partitions := map[string]string{}
+	for i, d := range devices {
+		if connected[i] {
+			partitions[d.DeviceUUID] = partitionIDs[find(i)]
+		}
+	}
+	return partitions
+}
+
 func (g *gpuDeviceManager) deviceInfos() metriccache.Devices {
 	g.RLock()
 	defer g.RUnlock()
 	gpuDevices := util.GPUDevices{}
-	for _, device := range g.devices {
-		gpuDevices = append(gpuDevices, util.GPUDeviceInfo{
-			UUID:        device.DeviceUUID,
-			Minor:       device.Minor,
-			MemoryTotal: device.MemoryTotal,
-			NodeID:      device.NodeID,
-			PCIE:        device.PCIE,
-			BusID:       device.BusID,
-		})
+	for idx, device := range g.devices {
+		info := util.GPUDeviceInfo{
+			UUID:              device.DeviceUUID,
+			Minor:             device.Minor,
+			MemoryTotal:       device.MemoryTotal,
+			BAR1MemoryTotal:   device.BAR1MemoryTotal,
+			NodeID:            device.NodeID,
+			PCIE:              device.PCIE,
+			BusID:             device.BusID,
+			ComputeCapability: device.ComputeCapability,
+			ProductName:       device.ProductName,
+			EncoderSupported:  device.EncoderSupported,
+			DecoderSupported:  device.DecoderSupported,
+			FabricPartitionID: g.fabricPartitions[device.DeviceUUID],
+		}
+		if idx < len(g.migModes) && g.migModes[idx] != nil {
+			info.MigCapable = true
+			info.MigEnabled = g.migModes[idx].Enabled
+			info.MigPendingEnabled = g.migModes[idx].PendingEnabled
+		}
+		if idx < len(g.persistenceModes) {
+			info.PersistenceModeEnabled = g.persistenceModes[idx]
+		}
+		if idx < len(g.coolingDegraded) {
+			info.CoolingDegraded = g.coolingDegraded[idx]
+		}
+		if idx < len(g.retiredPages) && g.retiredPages[idx] != nil {
+			info.RetiredPages = g.retiredPages[idx].Count
+			info.RetiredPagesPending = g.retiredPages[idx].Pending
+		}
+		gpuDevices = append(gpuDevices, info)
 	}
 
 	return gpuDevices
@@ -204,11 +440,63 @@ func (g *gpuDeviceManager) getNodeGPUUsage() []metriccache.MetricSample {
 		if gpuMemUsedMetric != nil {
 			gpuMetrics = append(gpuMetrics, gpuMemUsedMetric)
 		}
+		gpuMetrics = append(gpuMetrics, g.getDeviceCodecUsage(idx, properties)...)
+		if idx < len(g.memBandwidthMetrics) && g.memBandwidthMetrics[idx] != nil {
+			if sample := buildMetricSample(metriccache.NodeGPUMemBandwidthUsageMetric, properties, g.collectTime, float64(*g.memBandwidthMetrics[idx])); sample != nil {
+				gpuMetrics = append(gpuMetrics, sample)
+			}
+		}
+		if idx < len(g.fanSpeeds) && g.fanSpeeds[idx] != nil {
+			if sample := buildMetricSample(metriccache.NodeGPUFanSpeedMetric, properties, g.collectTime, float64(*g.fanSpeeds[idx])); sample != nil {
+				gpuMetrics = append(gpuMetrics, sample)
+			}
+		}
+		if idx < len(g.retiredPages) && g.retiredPages[idx] != nil {
+			if sample := buildMetricSample(metriccache.NodeGPURetiredPagesMetric, properties, g.collectTime, float64(g.retiredPages[idx].Count)); sample != nil {
+				gpuMetrics = append(gpuMetrics, sample)
+			}
+		}
+		gpuMetrics = append(gpuMetrics, g.getDeviceBAR1MemoryUsage(idx, properties)...)
 	}
 
 	return gpuMetrics
 }
 
+// getDeviceBAR1MemoryUsage returns the total and used BAR1 memory of the device if supported, the lock should be
+// held by the caller.
+func (g *gpuDeviceManager) getDeviceBAR1MemoryUsage(idx int, properties map[metriccache.MetricProperty]string) []metriccache.MetricSample {
+	if idx >= len(g.bar1MemoryUsed) || g.bar1MemoryUsed[idx] == nil || g.devices[idx].BAR1MemoryTotal == 0 {
+		return nil
+	}
+	var samples []metriccache.MetricSample
+	if sample := buildMetricSample(metriccache.NodeGPUBAR1MemTotalMetric, properties, g.collectTime, float64(g.devices[idx].BAR1MemoryTotal)); sample != nil {
+		samples = append(samples, sample)
+	}
+	if sample := buildMetricSample(metriccache.NodeGPUBAR1MemUsageMetric, properties, g.collectTime, float64(*g.bar1MemoryUsed[idx])); sample != nil {
+		samples = append(samples, sample)
+	}
+	return samples
+}
+
+// getDeviceCodecUsage returns the encoder and decoder usages of the device, the lock should be held by the caller.
+func (g *gpuDeviceManager) getDeviceCodecUsage(idx int, properties map[metriccache.MetricProperty]string) []metriccache.MetricSample {
+	if idx >= len(g.codecMetrics) || g.codecMetrics[idx] == nil {
+		return nil
+	}
+	var samples []metriccache.MetricSample
+	if usage := g.codecMetrics[idx].EncoderUtil; usage != nil {
+		if sample := buildMetricSample(metriccache.NodeGPUEncoderUsageMetric, properties, g.collectTime, float64(*usage)); sample != nil {
+			samples = append(samples, sample)
+		}
+	}
+	if usage := g.codecMetrics[idx].DecoderUtil; usage != nil {
+		if sample := buildMetricSample(metriccache.NodeGPUDecoderUsageMetric, properties, g.collectTime, float64(*usage)); sample != nil {
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
+
 func (g *gpuDeviceManager) getPodOrContainerTotalGPUUsageOfPIDs(id string, isPodID bool, pids []uint32) []metriccache.MetricSample {
 	if id == "" {
 		klog.Warning("id is empty")
@@ -307,55 +595,357 @@ func (g *gpuDeviceManager) getContainerGPUUsage(containerID, podParentDir string
 }
 
 func (g *gpuDeviceManager) collectGPUUsage() {
+	if !g.nvmlBreaker.Allow() {
+		klog.V(5).Infof("skip collecting gpu usage since the GPU subsystem is degraded")
+		return
+	}
+	// nvml is considered broken only if it fails for all devices
+	var nvmlErr error
+	nvmlSucceeded := len(g.devices) == 0
 	processesGPUUsages := make(map[uint32][]*rawGPUMetric)
+	codecUsages := make([]*rawGPUCodecMetric, len(g.devices))
+	memBandwidthUsages := make([]*uint32, len(g.devices))
+	migModes := make([]*gpuMigMode, len(g.devices))
+	persistenceModes := make([]*bool, len(g.devices))
+	fanSpeeds := make([]*uint32, len(g.devices))
+	coolingDegraded := make([]bool, len(g.devices))
+	retiredPages := make([]*gpuRetiredPages, len(g.devices))
+	bar1MemoryUsed := make([]*uint64, len(g.devices))
 	for deviceIndex, gpuDevice := range g.devices {
+		codecUsages[deviceIndex] = collectCodecUsage(gpuDevice)
+		memBandwidthUsages[deviceIndex] = collectMemBandwidthUsage(gpuDevice)
+		migModes[deviceIndex] = collectMigMode(gpuDevice)
+		persistenceModes[deviceIndex] = collectPersistenceMode(gpuDevice)
+		// the persistence modes are only written by the collection, so they can be read without the lock
+		if isPersistenceModeDisabled(persistenceModes[deviceIndex], g.persistenceModes, deviceIndex) {
+			klog.Warningf("Persistence mode of device %s is disabled, the device may initialize slowly and fail the health check",
+				gpuDevice.DeviceUUID)
+		}
+		fanSpeeds[deviceIndex] = collectFanSpeed(gpuDevice)
+		coolingDegraded[deviceIndex] = isCoolingDegraded(gpuDevice, fanSpeeds[deviceIndex])
+		// the cooling states are only written by the collection, so they can be read without the lock
+		if coolingDegraded[deviceIndex] && (deviceIndex >= len(g.coolingDegraded) || !g.coolingDegraded[deviceIndex]) {
+			klog.Warningf("Fan of device %s stops while the device is thermally loaded, the cooling may fail", gpuDevice.DeviceUUID)
+		}
+		retiredPages[deviceIndex] = collectRetiredPages(gpuDevice)
+		// the retired pages are only written by the collection, so they can be read without the lock
+		if isRetiredPagesIncreased(retiredPages[deviceIndex], g.retiredPages, deviceIndex) {
+			klog.Warningf("Memory pages of device %s are retired, count %d, pending %v, the device may be failing",
+				gpuDevice.DeviceUUID, retiredPages[deviceIndex].Count, retiredPages[deviceIndex].Pending)
+		}
+		bar1MemoryUsed[deviceIndex] = collectBAR1MemoryUsed(gpuDevice.Device, gpuDevice.DeviceUUID)
 		processesInfos, ret := gpuDevice.Device.GetComputeRunningProcesses()
 		if ret != nvml.SUCCESS {
 			klog.Warningf("Unable to get process info for device at index %d: %v", deviceIndex, nvml.ErrorString(ret))
+			nvmlErr = fmt.Errorf("unable to get process info for device at index %d: %v", deviceIndex, nvml.ErrorString(ret))
 			continue
 		}
+		nvmlSucceeded = true
 		processUtilizations, ret := gpuDevice.Device.GetProcessUtilization(1024)
 		if ret != nvml.SUCCESS {
-			klog.Warningf("Unable to get process utilization for device at index %d: %v", deviceIndex, nvml.ErrorString(ret))
-			continue
+			// the memory usage of processes is still accounted without the utilization samples,
+			// e.g. nvml returns NOT_FOUND when no process is active since the last sample
+			klog.V(5).Infof("Unable to get process utilization for device at index %d: %v", deviceIndex, nvml.ErrorString(ret))
+			processUtilizations = nil
 		}
 
-		// Sort by pid.
-		sort.Slice(processesInfos, func(i, j int) bool {
-			return processesInfos[i].Pid < processesInfos[j].Pid
-		})
-		sort.Slice(processUtilizations, func(i, j int) bool {
-			return processUtilizations[i].Pid < processUtilizations[j].Pid
-		})
-
 		klog.V(3).Infof("Found %d processes on device %d\n", len(processesInfos), deviceIndex)
-		for _, info := range processesInfos {
-			var utilization *nvml.ProcessUtilizationSample
-			for i := range processUtilizations {
-				if processUtilizations[i].Pid == info.Pid {
-					utilization = &processUtilizations[i]
-					break
-				}
-			}
-			if utilization == nil {
-				continue
-			}
-			if _, ok := processesGPUUsages[info.Pid]; !ok {
+		for pid, metric := range buildProcessGPUMetrics(processesInfos, processUtilizations) {
+			if _, ok := processesGPUUsages[pid]; !ok {
 				// pid not exist.
 				// init processes gpu metric array.
-				processesGPUUsages[info.Pid] = make([]*rawGPUMetric, g.deviceCount)
-			}
-			processesGPUUsages[info.Pid][deviceIndex] = &rawGPUMetric{
-				SMUtil:     utilization.SmUtil,
-				MemoryUsed: info.UsedGpuMemory,
+				processesGPUUsages[pid] = make([]*rawGPUMetric, g.deviceCount)
 			}
+			processesGPUUsages[pid][deviceIndex] = metric
 		}
 	}
 	g.Lock()
 	g.processesMetrics = processesGPUUsages
+	g.codecMetrics = codecUsages
+	g.memBandwidthMetrics = memBandwidthUsages
+	g.migModes = migModes
+	g.persistenceModes = persistenceModes
+	g.fanSpeeds = fanSpeeds
+	g.coolingDegraded = coolingDegraded
+	g.retiredPages = retiredPages
+	g.bar1MemoryUsed = bar1MemoryUsed
 	g.collectTime = time.Now()
 	g.start.Store(true)
 	g.Unlock()
+	if nvmlSucceeded {
+		g.nvmlBreaker.RecordSuccess()
+	} else {
+		g.nvmlBreaker.RecordFailure(nvmlErr)
+	}
+}
+
+// buildProcessGPUMetrics returns the gpu metrics of the running processes on a device by pid.
+// An idle process without a utilization sample still holds the gpu memory, so it is accounted with zero utilization.
+func buildProcessGPUMetrics(processesInfos []nvml.ProcessInfo, processUtilizations []nvml.ProcessUtilizationSample) map[uint32]*rawGPUMetric {
+	smUtils := make(map[uint32]uint32, len(processUtilizations))
+	for _, utilization := range processUtilizations {
+		smUtils[utilization.Pid] = utilization.SmUtil
+	}
+	metrics := make(map[uint32]*rawGPUMetric, len(processesInfos))
+	for _, info := range processesInfos {
+		metrics[info.Pid] = &rawGPUMetric{
+			SMUtil:     smUtils[info.Pid],
+			MemoryUsed: info.UsedGpuMemory,
+		}
+	}
+	return metrics
+}
+
+// collectCodecUsage returns the encoder and decoder utilization of the device if supported.
+func collectCodecUsage(gpuDevice *device) *rawGPUCodecMetric {
+	metric := &rawGPUCodecMetric{}
+	if gpuDevice.EncoderSupported {
+		if utilization, _, ret := gpuDevice.Device.GetEncoderUtilization(); ret == nvml.SUCCESS {
+			metric.EncoderUtil = &utilization
+		} else {
+			klog.V(5).Infof("Unable to get encoder utilization for device %s: %v", gpuDevice.DeviceUUID, nvml.ErrorString(ret))
+		}
+	}
+	if gpuDevice.DecoderSupported {
+		if utilization, _, ret := gpuDevice.Device.GetDecoderUtilization(); ret == nvml.SUCCESS {
+			metric.DecoderUtil = &utilization
+		} else {
+			klog.V(5).Infof("Unable to get decoder utilization for device %s: %v", gpuDevice.DeviceUUID, nvml.ErrorString(ret))
+		}
+	}
+	return metric
+}
+
+// collectMemBandwidthUsage returns the memory controller utilization of the device, i.e. the percent of time over
+// the past sample period during which the device memory was being read or written.
+func collectMemBandwidthUsage(gpuDevice *device) *uint32 {
+	utilization, ret := gpuDevice.Device.GetUtilizationRates()
+	if ret != nvml.SUCCESS {
+		klog.V(5).Infof("Unable to get utilization rates for device %s: %v", gpuDevice.DeviceUUID, nvml.ErrorString(ret))
+		return nil
+	}
+	return &utilization.Memory
+}
+
+// collectMigMode returns the MIG mode of the device, or nil if the device is not MIG-capable.
+// The mode is collected periodically since it can be changed at runtime, e.g. by nvidia-smi.
+func collectMigMode(gpuDevice *device) *gpuMigMode {
+	current, pending, ret := gpuDevice.Device.GetMigMode()
+	if ret != nvml.SUCCESS {
+		if ret != nvml.ERROR_NOT_SUPPORTED {
+			klog.V(5).Infof("Unable to get mig mode for device %s: %v", gpuDevice.DeviceUUID, nvml.ErrorString(ret))
+		}
+		return nil
+	}
+	return &gpuMigMode{
+		Enabled:        current == nvml.DEVICE_MIG_ENABLE,
+		PendingEnabled: pending == nvml.DEVICE_MIG_ENABLE,
+	}
+}
+
+// collectPersistenceMode returns whether the persistence mode of the device is enabled, or nil if not supported.
+// The mode is collected periodically since it can be changed at runtime, e.g. by nvidia-smi -pm.
+func collectPersistenceMode(gpuDevice *device) *bool {
+	mode, ret := gpuDevice.Device.GetPersistenceMode()
+	if ret != nvml.SUCCESS {
+		if ret != nvml.ERROR_NOT_SUPPORTED {
+			klog.V(5).Infof("Unable to get persistence mode for device %s: %v", gpuDevice.DeviceUUID, nvml.ErrorString(ret))
+		}
+		return nil
+	}
+	enabled := mode == nvml.FEATURE_ENABLED
+	return &enabled
+}
+
+// gpuCoolingLoadedTemperature is the temperature in Celsius above which a device is regarded as thermally loaded,
+// i.e. its fan is expected to spin.
+var gpuCoolingLoadedTemperature uint32 = 60
+
+// collectFanSpeed returns the fan speed of the device in percentage of the max speed, or nil if not supported, e.g.
+// the passively-cooled datacenter gpus cooled by the chassis fans.
+func collectFanSpeed(gpuDevice *device) *uint32 {
+	speed, ret := gpuDevice.Device.GetFanSpeed()
+	if ret != nvml.SUCCESS {
+		if ret != nvml.ERROR_NOT_SUPPORTED {
+			klog.V(5).Infof("Unable to get fan speed for device %s: %v", gpuDevice.DeviceUUID, nvml.ErrorString(ret))
+		}
+		return nil
+	}
+	return &speed
+}
+
+// isCoolingDegraded returns whether the fan of the device reads 0 while the device is thermally loaded, which
+// indicates a cooling failure before the device is thermally throttled. The temperature is queried only when the fan
+// reads 0, and is not batched into the field values since nvml has no field id of the gpu temperature, but only of
+// the memory temperature which is a different sensor.
+func isCoolingDegraded(gpuDevice *device, fanSpeed *uint32) bool {
+	if fanSpeed == nil || *fanSpeed > 0 {
+		return false
+	}
+	temperature, ret := gpuDevice.Device.GetTemperature(nvml.TEMPERATURE_GPU)
+	if ret != nvml.SUCCESS {
+		klog.V(5).Infof("Unable to get temperature for device %s: %v", gpuDevice.DeviceUUID, nvml.ErrorString(ret))
+		return false
+	}
+	return temperature >= gpuCoolingLoadedTemperature
+}
+
+// gpuRetiredPages is the memory pages retired by the ECC errors of a device.
+type gpuRetiredPages struct {
+	// Count is the count of the pages retired by both the multiple single-bit and the double-bit ECC errors
+	Count uint32
+	// Pending is whether some pages are pending retirement, which takes effect after the device is reset
+	Pending bool
+}
+
+// nvmlErrorString returns the description of the nvml return, which is replaced in the tests without the library.
+var nvmlErrorString = nvml.ErrorString
+
+// retiredPagesDevice is the nvml device methods to query the retired pages, which is faked in the tests.
+type retiredPagesDevice interface {
+	GetFieldValues(values []nvml.FieldValue) nvml.Return
+	GetRetiredPages(cause nvml.PageRetirementCause) ([]uint64, nvml.Return)
+	GetRetiredPagesPendingStatus() (nvml.EnableState, nvml.Return)
+}
+
+// collectRetiredPages returns the memory pages retired of the device, or nil if the page retirement is not supported,
+// e.g. the devices using the row remapping instead.
+func collectRetiredPages(gpuDevice *device) *gpuRetiredPages {
+	return queryRetiredPages(gpuDevice.Device, gpuDevice.DeviceUUID, &gpuDevice.FieldValuesUnsupported)
+}
+
+// queryRetiredPages queries the retired pages in one batch of the field values, which takes one nvml call instead of
+// three. It falls back to the individual calls if the batch fails, and keeps using them once the driver is found not
+// supporting the field values.
+func queryRetiredPages(d retiredPagesDevice, uuid string, fieldValuesUnsupported *bool) *gpuRetiredPages {
+	if !*fieldValuesUnsupported {
+		pages, ret, fieldRet := getRetiredPagesByFieldValues(d)
+		switch {
+		case ret == nvml.SUCCESS && fieldRet == nvml.SUCCESS:
+			return pages
+		case ret == nvml.ERROR_NOT_SUPPORTED || ret == nvml.ERROR_FUNCTION_NOT_FOUND:
+			klog.V(4).Infof("Field values are not supported for device %s, query the metrics individually: %v",
+				uuid, nvmlErrorString(ret))
+			*fieldValuesUnsupported = true
+		case ret == nvml.SUCCESS && fieldRet == nvml.ERROR_NOT_SUPPORTED:
+			return nil
+		default:
+			klog.V(5).Infof("Unable to get retired pages field values for device %s: %v, %v, query them individually",
+				uuid, nvmlErrorString(ret), nvmlErrorString(fieldRet))
+		}
+	}
+	return getRetiredPages(d, uuid)
+}
+
+// retiredPagesFieldIDs are the fields of the retired pages queried in one batch.
+var retiredPagesFieldIDs = []uint32{nvml.FI_DEV_RETIRED_SBE, nvml.FI_DEV_RETIRED_DBE, nvml.FI_DEV_RETIRED_PENDING}
+
+// getRetiredPagesByFieldValues returns the retired pages queried by the field values, the return of the batch call
+// and the first failed return of the fields.
+func getRetiredPagesByFieldValues(d retiredPagesDevice) (*gpuRetiredPages, nvml.Return, nvml.Return) {
+	values := make([]nvml.FieldValue, len(retiredPagesFieldIDs))
+	for i, id := range retiredPagesFieldIDs {
+		values[i].FieldId = id
+	}
+	if ret := d.GetFieldValues(values); ret != nvml.SUCCESS {
+		return nil, ret, nvml.SUCCESS
+	}
+	pages := &gpuRetiredPages{}
+	for i := range values {
+		if fieldRet := nvml.Return(values[i].NvmlReturn); fieldRet != nvml.SUCCESS {
+			return nil, nvml.SUCCESS, fieldRet
+		}
+		value, ok := getFieldValueUint64(&values[i])
+		if !ok {
+			return nil, nvml.SUCCESS, nvml.ERROR_UNKNOWN
+		}
+		switch values[i].FieldId {
+		case nvml.FI_DEV_RETIRED_PENDING:
+			pages.Pending = value != 0
+		default:
+			pages.Count += uint32(value)
+		}
+	}
+	return pages, nvml.SUCCESS, nvml.SUCCESS
+}
+
+// getFieldValueUint64 returns the unsigned value of the field, and false if the value is not unsigned.
+// The value is a C union in the native byte order, which is little-endian on the platforms supported by nvml.
+func getFieldValueUint64(value *nvml.FieldValue) (uint64, bool) {
+	switch nvml.ValueType(value.ValueType) {
+	case nvml.VALUE_TYPE_UNSIGNED_INT:
+		return uint64(binary.LittleEndian.Uint32(value.Value[:4])), true
+	case nvml.VALUE_TYPE_UNSIGNED_LONG, nvml.VALUE_TYPE_UNSIGNED_LONG_LONG:
+		return binary.LittleEndian.Uint64(value.Value[:]), true
+	}
+	return 0, false
+}
+
+// getRetiredPages queries the retired pages by the individual calls of each retirement cause and the pending status.
+func getRetiredPages(d retiredPagesDevice, uuid string) *gpuRetiredPages {
+	pages := &gpuRetiredPages{}
+	for _, cause := range []nvml.PageRetirementCause{
+		nvml.PAGE_RETIREMENT_CAUSE_MULTIPLE_SINGLE_BIT_ECC_ERRORS,
+		nvml.PAGE_RETIREMENT_CAUSE_DOUBLE_BIT_ECC_ERROR,
+	} {
+		addresses, ret := d.GetRetiredPages(cause)
+		if ret != nvml.SUCCESS {
+			if ret != nvml.ERROR_NOT_SUPPORTED {
+				klog.V(5).Infof("Unable to get retired pages for device %s: %v", uuid, nvmlErrorString(ret))
+			}
+			return nil
+		}
+		pages.Count += uint32(len(addresses))
+	}
+	pending, ret := d.GetRetiredPagesPendingStatus()
+	if ret != nvml.SUCCESS {
+		klog.V(5).Infof("Unable to get retired pages pending status for device %s: %v", uuid, nvmlErrorString(ret))
+		return pages
+	}
+	pages.Pending = pending == nvml.FEATURE_ENABLED
+	return pages
+}
+
+// bar1MemoryDevice is the nvml device method to query the BAR1 memory, which is faked in the tests.
+type bar1MemoryDevice interface {
+	GetBAR1MemoryInfo() (nvml.BAR1Memory, nvml.Return)
+}
+
+// collectBAR1MemoryUsed returns the used BAR1 memory of the device in bytes, or nil if not supported. The BAR1
+// memory can be exhausted by the peer-to-peer mappings before the framebuffer, e.g. by the CUDA IPC.
+func collectBAR1MemoryUsed(d bar1MemoryDevice, uuid string) *uint64 {
+	bar1Memory, ret := d.GetBAR1MemoryInfo()
+	if ret != nvml.SUCCESS {
+		if ret != nvml.ERROR_NOT_SUPPORTED {
+			klog.V(5).Infof("Unable to get bar1 memory info for device %s: %v", uuid, nvmlErrorString(ret))
+		}
+		return nil
+	}
+	return &bar1Memory.Bar1Used
+}
+
+// isRetiredPagesIncreased returns whether more pages of the device at the index are newly found retired or pending
+// retirement, so that it is warned once instead of on each collection.
+func isRetiredPagesIncreased(pages *gpuRetiredPages, lastPages []*gpuRetiredPages, index int) bool {
+	if pages == nil || (pages.Count == 0 && !pages.Pending) {
+		return false
+	}
+	if index >= len(lastPages) || lastPages[index] == nil {
+		return true
+	}
+	return pages.Count > lastPages[index].Count || (pages.Pending && !lastPages[index].Pending)
+}
+
+// isPersistenceModeDisabled returns whether the persistence mode of the device at the index is newly found disabled,
+// so that it is warned once instead of on each collection.
+func isPersistenceModeDisabled(mode *bool, lastModes []*bool, index int) bool {
+	if mode == nil || *mode {
+		return false
+	}
+	if index < len(lastModes) && lastModes[index] != nil && !*lastModes[index] {
+		return false
+	}
+	return true
 }
 
 func (g *gpuDeviceManager) started() bool {
@@ -17,6 +17,8 @@ limitations under the License.
 package gpu
 
 import (
+	"encoding/binary"
+	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -24,23 +26,33 @@ import (
 	"testing"
 	"time"
 
-	"github.com/koordinator-sh/koordinator/pkg/koordlet/util"
-
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/util"
+
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
 
 	"github.com/koordinator-sh/koordinator/pkg/koordlet/metriccache"
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/metricsadvisor/framework"
 	"github.com/koordinator-sh/koordinator/pkg/koordlet/util/system"
 )
 
 func Test_gpuUsageDetailRecord_GetNodeGPUUsage(t *testing.T) {
 	collectTime := time.Now()
+	encoderUtil, decoderUtil, memBandwidthUtil, fanSpeed := uint32(30), uint32(10), uint32(85), uint32(40)
+	bar1MemoryUsed := uint64(64)
 	type fields struct {
-		deviceCount      int
-		devices          []*device
-		processesMetrics map[uint32][]*rawGPUMetric
+		deviceCount         int
+		devices             []*device
+		processesMetrics    map[uint32][]*rawGPUMetric
+		codecMetrics        []*rawGPUCodecMetric
+		memBandwidthMetrics []*uint32
+		fanSpeeds           []*uint32
+		retiredPages        []*gpuRetiredPages
+		bar1MemoryUsed      []*uint64
 	}
 	tests := []struct {
 		name   string
@@ -153,14 +165,258 @@ func Test_gpuUsageDetailRecord_GetNodeGPUUsage(t *testing.T) {
 				),
 			},
 		},
+		{
+			name: "device with encoder and decoder",
+			fields: fields{
+				deviceCount: 2,
+				devices: []*device{
+					{Minor: 0, DeviceUUID: "test-device1", MemoryTotal: 8000, EncoderSupported: true, DecoderSupported: true},
+					{Minor: 1, DeviceUUID: "test-device2", MemoryTotal: 9000},
+				},
+				processesMetrics: map[uint32][]*rawGPUMetric{
+					122: {{SMUtil: 70, MemoryUsed: 1500}, nil},
+				},
+				codecMetrics: []*rawGPUCodecMetric{
+					{EncoderUtil: &encoderUtil, DecoderUtil: &decoderUtil},
+					{},
+				},
+			},
+			want: []metriccache.MetricSample{
+				buildMetricSample(
+					metriccache.NodeGPUCoreUsageMetric,
+					metriccache.MetricPropertiesFunc.GPU("0", "test-device1"),
+					collectTime,
+					70,
+				),
+				buildMetricSample(
+					metriccache.NodeGPUMemUsageMetric,
+					metriccache.MetricPropertiesFunc.GPU("0", "test-device1"),
+					collectTime,
+					1500,
+				),
+				buildMetricSample(
+					metriccache.NodeGPUEncoderUsageMetric,
+					metriccache.MetricPropertiesFunc.GPU("0", "test-device1"),
+					collectTime,
+					30,
+				),
+				buildMetricSample(
+					metriccache.NodeGPUDecoderUsageMetric,
+					metriccache.MetricPropertiesFunc.GPU("0", "test-device1"),
+					collectTime,
+					10,
+				),
+				buildMetricSample(
+					metriccache.NodeGPUCoreUsageMetric,
+					metriccache.MetricPropertiesFunc.GPU("1", "test-device2"),
+					collectTime,
+					0,
+				),
+				buildMetricSample(
+					metriccache.NodeGPUMemUsageMetric,
+					metriccache.MetricPropertiesFunc.GPU("1", "test-device2"),
+					collectTime,
+					0,
+				),
+			},
+		},
+		{
+			name: "device with memory bandwidth usage",
+			fields: fields{
+				deviceCount: 2,
+				devices: []*device{
+					{Minor: 0, DeviceUUID: "test-device1", MemoryTotal: 8000},
+					{Minor: 1, DeviceUUID: "test-device2", MemoryTotal: 9000},
+				},
+				processesMetrics: map[uint32][]*rawGPUMetric{
+					122: {{SMUtil: 20, MemoryUsed: 1500}, nil},
+				},
+				memBandwidthMetrics: []*uint32{&memBandwidthUtil, nil},
+			},
+			want: []metriccache.MetricSample{
+				buildMetricSample(
+					metriccache.NodeGPUCoreUsageMetric,
+					metriccache.MetricPropertiesFunc.GPU("0", "test-device1"),
+					collectTime,
+					20,
+				),
+				buildMetricSample(
+					metriccache.NodeGPUMemUsageMetric,
+					metriccache.MetricPropertiesFunc.GPU("0", "test-device1"),
+					collectTime,
+					1500,
+				),
+				buildMetricSample(
+					metriccache.NodeGPUMemBandwidthUsageMetric,
+					metriccache.MetricPropertiesFunc.GPU("0", "test-device1"),
+					collectTime,
+					85,
+				),
+				buildMetricSample(
+					metriccache.NodeGPUCoreUsageMetric,
+					metriccache.MetricPropertiesFunc.GPU("1", "test-device2"),
+					collectTime,
+					0,
+				),
+				buildMetricSample(
+					metriccache.NodeGPUMemUsageMetric,
+					metriccache.MetricPropertiesFunc.GPU("1", "test-device2"),
+					collectTime,
+					0,
+				),
+			},
+		},
+		{
+			name: "device with fan speed",
+			fields: fields{
+				deviceCount: 2,
+				devices: []*device{
+					{Minor: 0, DeviceUUID: "test-device1", MemoryTotal: 8000},
+					{Minor: 1, DeviceUUID: "test-device2", MemoryTotal: 9000},
+				},
+				// the passively-cooled device reports no fan speed
+				fanSpeeds: []*uint32{&fanSpeed, nil},
+			},
+			want: []metriccache.MetricSample{
+				buildMetricSample(
+					metriccache.NodeGPUCoreUsageMetric,
+					metriccache.MetricPropertiesFunc.GPU("0", "test-device1"),
+					collectTime,
+					0,
+				),
+				buildMetricSample(
+					metriccache.NodeGPUMemUsageMetric,
+					metriccache.MetricPropertiesFunc.GPU("0", "test-device1"),
+					collectTime,
+					0,
+				),
+				buildMetricSample(
+					metriccache.NodeGPUFanSpeedMetric,
+					metriccache.MetricPropertiesFunc.GPU("0", "test-device1"),
+					collectTime,
+					40,
+				),
+				buildMetricSample(
+					metriccache.NodeGPUCoreUsageMetric,
+					metriccache.MetricPropertiesFunc.GPU("1", "test-device2"),
+					collectTime,
+					0,
+				),
+				buildMetricSample(
+					metriccache.NodeGPUMemUsageMetric,
+					metriccache.MetricPropertiesFunc.GPU("1", "test-device2"),
+					collectTime,
+					0,
+				),
+			},
+		},
+		{
+			name: "device with retired pages",
+			fields: fields{
+				deviceCount: 2,
+				devices: []*device{
+					{Minor: 0, DeviceUUID: "test-device1", MemoryTotal: 8000},
+					{Minor: 1, DeviceUUID: "test-device2", MemoryTotal: 9000},
+				},
+				// the device using the row remapping reports no retired pages
+				retiredPages: []*gpuRetiredPages{{Count: 3, Pending: true}, nil},
+			},
+			want: []metriccache.MetricSample{
+				buildMetricSample(
+					metriccache.NodeGPUCoreUsageMetric,
+					metriccache.MetricPropertiesFunc.GPU("0", "test-device1"),
+					collectTime,
+					0,
+				),
+				buildMetricSample(
+					metriccache.NodeGPUMemUsageMetric,
+					metriccache.MetricPropertiesFunc.GPU("0", "test-device1"),
+					collectTime,
+					0,
+				),
+				buildMetricSample(
+					metriccache.NodeGPURetiredPagesMetric,
+					metriccache.MetricPropertiesFunc.GPU("0", "test-device1"),
+					collectTime,
+					3,
+				),
+				buildMetricSample(
+					metriccache.NodeGPUCoreUsageMetric,
+					metriccache.MetricPropertiesFunc.GPU("1", "test-device2"),
+					collectTime,
+					0,
+				),
+				buildMetricSample(
+					metriccache.NodeGPUMemUsageMetric,
+					metriccache.MetricPropertiesFunc.GPU("1", "test-device2"),
+					collectTime,
+					0,
+				),
+			},
+		},
+		{
+			name: "device with bar1 memory",
+			fields: fields{
+				deviceCount: 2,
+				devices: []*device{
+					{Minor: 0, DeviceUUID: "test-device1", MemoryTotal: 8000, BAR1MemoryTotal: 256},
+					{Minor: 1, DeviceUUID: "test-device2", MemoryTotal: 9000},
+				},
+				// the device not supporting the bar1 memory reports no usage
+				bar1MemoryUsed: []*uint64{&bar1MemoryUsed, nil},
+			},
+			want: []metriccache.MetricSample{
+				buildMetricSample(
+					metriccache.NodeGPUCoreUsageMetric,
+					metriccache.MetricPropertiesFunc.GPU("0", "test-device1"),
+					collectTime,
+					0,
+				),
+				buildMetricSample(
+					metriccache.NodeGPUMemUsageMetric,
+					metriccache.MetricPropertiesFunc.GPU("0", "test-device1"),
+					collectTime,
+					0,
+				),
+				buildMetricSample(
+					metriccache.NodeGPUBAR1MemTotalMetric,
+					metriccache.MetricPropertiesFunc.GPU("0", "test-device1"),
+					collectTime,
+					256,
+				),
+				buildMetricSample(
+					metriccache.NodeGPUBAR1MemUsageMetric,
+					metriccache.MetricPropertiesFunc.GPU("0", "test-device1"),
+					collectTime,
+					64,
+				),
+				buildMetricSample(
+					metriccache.NodeGPUCoreUsageMetric,
+					metriccache.MetricPropertiesFunc.GPU("1", "test-device2"),
+					collectTime,
+					0,
+				),
+				buildMetricSample(
+					metriccache.NodeGPUMemUsageMetric,
+					metriccache.MetricPropertiesFunc.GPU("1", "test-device2"),
+					collectTime,
+					0,
+				),
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			g := &gpuDeviceManager{
-				collectTime:      collectTime,
-				deviceCount:      tt.fields.deviceCount,
-				devices:          tt.fields.devices,
-				processesMetrics: tt.fields.processesMetrics,
+				collectTime:         collectTime,
+				deviceCount:         tt.fields.deviceCount,
+				devices:             tt.fields.devices,
+				processesMetrics:    tt.fields.processesMetrics,
+				codecMetrics:        tt.fields.codecMetrics,
+				memBandwidthMetrics: tt.fields.memBandwidthMetrics,
+				fanSpeeds:           tt.fields.fanSpeeds,
+				retiredPages:        tt.fields.retiredPages,
+				bar1MemoryUsed:      tt.fields.bar1MemoryUsed,
 			}
 			got := g.getNodeGPUUsage()
 			assert.Equal(t, got, tt.want)
@@ -817,8 +1073,12 @@ func Test_buildMetricSample(t *testing.T) {
 
 func Test_gpuDeviceManager_deviceInfos(t *testing.T) {
 	type fields struct {
-		deviceCount int
-		devices     []*device
+		deviceCount      int
+		devices          []*device
+		migModes         []*gpuMigMode
+		persistenceModes []*bool
+		coolingDegraded  []bool
+		retiredPages     []*gpuRetiredPages
 	}
 	tests := []struct {
 		name   string
@@ -835,27 +1095,508 @@ func Test_gpuDeviceManager_deviceInfos(t *testing.T) {
 		},
 		{
 			name: "device",
+			fields: fields{
+				deviceCount: 2,
+				devices: []*device{
+					{DeviceUUID: "1", Minor: 1, MemoryTotal: 2000},
+					{DeviceUUID: "2", Minor: 2, MemoryTotal: 3000, BAR1MemoryTotal: 256, ComputeCapability: "8.0", ProductName: "A100-SXM4-80GB"},
+				},
+			},
+			want: util.GPUDevices{
+				util.GPUDeviceInfo{UUID: "1", Minor: 1, MemoryTotal: 2000},
+				util.GPUDeviceInfo{UUID: "2", Minor: 2, MemoryTotal: 3000, BAR1MemoryTotal: 256, ComputeCapability: "8.0", ProductName: "A100-SXM4-80GB"},
+			},
+		},
+		{
+			name: "mig-capable device",
+			fields: fields{
+				deviceCount: 3,
+				devices: []*device{
+					{DeviceUUID: "1", Minor: 1, MemoryTotal: 2000, ProductName: "Tesla-T4"},
+					{DeviceUUID: "2", Minor: 2, MemoryTotal: 3000, ProductName: "A100-SXM4-80GB"},
+					{DeviceUUID: "3", Minor: 3, MemoryTotal: 3000, ProductName: "A100-SXM4-80GB"},
+				},
+				migModes: []*gpuMigMode{
+					nil,
+					{Enabled: false, PendingEnabled: false},
+					{Enabled: false, PendingEnabled: true},
+				},
+			},
+			want: util.GPUDevices{
+				util.GPUDeviceInfo{UUID: "1", Minor: 1, MemoryTotal: 2000, ProductName: "Tesla-T4"},
+				util.GPUDeviceInfo{UUID: "2", Minor: 2, MemoryTotal: 3000, ProductName: "A100-SXM4-80GB", MigCapable: true},
+				util.GPUDeviceInfo{UUID: "3", Minor: 3, MemoryTotal: 3000, ProductName: "A100-SXM4-80GB", MigCapable: true, MigPendingEnabled: true},
+			},
+		},
+		{
+			name: "persistence mode",
+			fields: fields{
+				deviceCount: 3,
+				devices: []*device{
+					{DeviceUUID: "1", Minor: 1, MemoryTotal: 2000},
+					{DeviceUUID: "2", Minor: 2, MemoryTotal: 3000},
+					{DeviceUUID: "3", Minor: 3, MemoryTotal: 3000},
+				},
+				persistenceModes: []*bool{nil, pointer.Bool(true), pointer.Bool(false)},
+			},
+			want: util.GPUDevices{
+				util.GPUDeviceInfo{UUID: "1", Minor: 1, MemoryTotal: 2000},
+				util.GPUDeviceInfo{UUID: "2", Minor: 2, MemoryTotal: 3000, PersistenceModeEnabled: pointer.Bool(true)},
+				util.GPUDeviceInfo{UUID: "3", Minor: 3, MemoryTotal: 3000, PersistenceModeEnabled: pointer.Bool(false)},
+			},
+		},
+		{
+			name: "cooling degraded",
 			fields: fields{
 				deviceCount: 2,
 				devices: []*device{
 					{DeviceUUID: "1", Minor: 1, MemoryTotal: 2000},
 					{DeviceUUID: "2", Minor: 2, MemoryTotal: 3000},
 				},
+				coolingDegraded: []bool{false, true},
 			},
 			want: util.GPUDevices{
 				util.GPUDeviceInfo{UUID: "1", Minor: 1, MemoryTotal: 2000},
-				util.GPUDeviceInfo{UUID: "2", Minor: 2, MemoryTotal: 3000},
+				util.GPUDeviceInfo{UUID: "2", Minor: 2, MemoryTotal: 3000, CoolingDegraded: true},
+			},
+		},
+		{
+			name: "retired pages",
+			fields: fields{
+				deviceCount: 2,
+				devices: []*device{
+					{DeviceUUID: "1", Minor: 1, MemoryTotal: 2000},
+					{DeviceUUID: "2", Minor: 2, MemoryTotal: 3000},
+				},
+				retiredPages: []*gpuRetiredPages{nil, {Count: 2, Pending: true}},
+			},
+			want: util.GPUDevices{
+				util.GPUDeviceInfo{UUID: "1", Minor: 1, MemoryTotal: 2000},
+				util.GPUDeviceInfo{UUID: "2", Minor: 2, MemoryTotal: 3000, RetiredPages: 2, RetiredPagesPending: true},
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			g := &gpuDeviceManager{
-				RWMutex:     sync.RWMutex{},
-				deviceCount: tt.fields.deviceCount,
-				devices:     tt.fields.devices,
+				RWMutex:          sync.RWMutex{},
+				deviceCount:      tt.fields.deviceCount,
+				devices:          tt.fields.devices,
+				migModes:         tt.fields.migModes,
+				persistenceModes: tt.fields.persistenceModes,
+				coolingDegraded:  tt.fields.coolingDegraded,
+				retiredPages:     tt.fields.retiredPages,
 			}
 			assert.Equalf(t, tt.want, g.deviceInfos(), "deviceInfos()")
 		})
 	}
 }
+
+func Test_buildProcessGPUMetrics(t *testing.T) {
+	tests := []struct {
+		name                string
+		processesInfos      []nvml.ProcessInfo
+		processUtilizations []nvml.ProcessUtilizationSample
+		want                map[uint32]*rawGPUMetric
+	}{
+		{
+			name: "process utilization failed",
+			processesInfos: []nvml.ProcessInfo{
+				{Pid: 122, UsedGpuMemory: 1500},
+				{Pid: 222, UsedGpuMemory: 3000},
+			},
+			processUtilizations: nil,
+			want: map[uint32]*rawGPUMetric{
+				122: {SMUtil: 0, MemoryUsed: 1500},
+				222: {SMUtil: 0, MemoryUsed: 3000},
+			},
+		},
+		{
+			name: "idle process without utilization sample",
+			processesInfos: []nvml.ProcessInfo{
+				{Pid: 222, UsedGpuMemory: 3000},
+				{Pid: 122, UsedGpuMemory: 1500},
+			},
+			processUtilizations: []nvml.ProcessUtilizationSample{
+				{Pid: 122, SmUtil: 70},
+			},
+			want: map[uint32]*rawGPUMetric{
+				122: {SMUtil: 70, MemoryUsed: 1500},
+				222: {SMUtil: 0, MemoryUsed: 3000},
+			},
+		},
+		{
+			name: "utilization sample of exited process",
+			processesInfos: []nvml.ProcessInfo{
+				{Pid: 122, UsedGpuMemory: 1500},
+			},
+			processUtilizations: []nvml.ProcessUtilizationSample{
+				{Pid: 122, SmUtil: 70},
+				{Pid: 333, SmUtil: 20},
+			},
+			want: map[uint32]*rawGPUMetric{
+				122: {SMUtil: 70, MemoryUsed: 1500},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, buildProcessGPUMetrics(tt.processesInfos, tt.processUtilizations))
+		})
+	}
+}
+
+func Test_getPCIDeviceID(t *testing.T) {
+	assert.Equal(t, framework.PCIDeviceID{VendorID: 0x10de, DeviceID: 0x20b0}, getPCIDeviceID(nvml.PciInfo{PciDeviceId: 0x20b010de}))
+}
+
+func Test_isCoolingDegraded(t *testing.T) {
+	// the temperature is only queried when the fan stops
+	assert.False(t, isCoolingDegraded(&device{DeviceUUID: "1"}, nil))
+	assert.False(t, isCoolingDegraded(&device{DeviceUUID: "1"}, pointer.Uint32(30)))
+}
+
+func Test_isRetiredPagesIncreased(t *testing.T) {
+	tests := []struct {
+		name      string
+		pages     *gpuRetiredPages
+		lastPages []*gpuRetiredPages
+		want      bool
+	}{
+		{
+			name:  "not supported",
+			pages: nil,
+			want:  false,
+		},
+		{
+			name:  "no page retired",
+			pages: &gpuRetiredPages{},
+			want:  false,
+		},
+		{
+			name:  "retired at the first collection",
+			pages: &gpuRetiredPages{Count: 1},
+			want:  true,
+		},
+		{
+			name:      "more pages retired",
+			pages:     &gpuRetiredPages{Count: 2},
+			lastPages: []*gpuRetiredPages{{Count: 1}},
+			want:      true,
+		},
+		{
+			name:      "newly pending",
+			pages:     &gpuRetiredPages{Count: 1, Pending: true},
+			lastPages: []*gpuRetiredPages{{Count: 1}},
+			want:      true,
+		},
+		{
+			name:      "unchanged",
+			pages:     &gpuRetiredPages{Count: 1, Pending: true},
+			lastPages: []*gpuRetiredPages{{Count: 1, Pending: true}},
+			want:      false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isRetiredPagesIncreased(tt.pages, tt.lastPages, 0))
+		})
+	}
+}
+
+func Test_isPersistenceModeDisabled(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      *bool
+		lastModes []*bool
+		want      bool
+	}{
+		{
+			name: "not supported",
+			mode: nil,
+			want: false,
+		},
+		{
+			name: "enabled",
+			mode: pointer.Bool(true),
+			want: false,
+		},
+		{
+			name: "disabled at the first collection",
+			mode: pointer.Bool(false),
+			want: true,
+		},
+		{
+			name:      "newly disabled",
+			mode:      pointer.Bool(false),
+			lastModes: []*bool{pointer.Bool(true)},
+			want:      true,
+		},
+		{
+			name:      "still disabled",
+			mode:      pointer.Bool(false),
+			lastModes: []*bool{pointer.Bool(false)},
+			want:      false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isPersistenceModeDisabled(tt.mode, tt.lastModes, 0))
+		})
+	}
+}
+
+func Test_groupFabricPartitions(t *testing.T) {
+	tests := []struct {
+		name    string
+		devices []*device
+		want    map[string]string
+	}{
+		{
+			name: "no nvlink",
+			devices: []*device{
+				{DeviceUUID: "1", Minor: 0, BusID: "0000:3b:00.0"},
+				{DeviceUUID: "2", Minor: 1, BusID: "0000:5e:00.0"},
+			},
+			want: map[string]string{},
+		},
+		{
+			name: "nvlink bridges between gpus are not fabric partitions",
+			devices: []*device{
+				{DeviceUUID: "1", Minor: 0, BusID: "0000:3b:00.0", NvLinkRemoteBusIDs: []string{"0000:5e:00.0"}},
+				{DeviceUUID: "2", Minor: 1, BusID: "0000:5e:00.0", NvLinkRemoteBusIDs: []string{"0000:3b:00.0"}},
+			},
+			want: map[string]string{},
+		},
+		{
+			name: "all gpus connected to the same nvswitches",
+			devices: []*device{
+				{DeviceUUID: "1", Minor: 0, BusID: "0000:3b:00.0", NvLinkRemoteBusIDs: []string{"0000:c1:00.0", "0000:c2:00.0"}},
+				{DeviceUUID: "2", Minor: 1, BusID: "0000:5e:00.0", NvLinkRemoteBusIDs: []string{"0000:c1:00.0", "0000:c2:00.0"}},
+			},
+			want: map[string]string{"1": "0", "2": "0"},
+		},
+		{
+			name: "gpus in different nvswitch partitions",
+			devices: []*device{
+				{DeviceUUID: "1", Minor: 2, BusID: "0000:3b:00.0", NvLinkRemoteBusIDs: []string{"0000:c3:00.0"}},
+				{DeviceUUID: "2", Minor: 3, BusID: "0000:5e:00.0", NvLinkRemoteBusIDs: []string{"0000:c3:00.0", "0000:c4:00.0"}},
+				{DeviceUUID: "3", Minor: 0, BusID: "0000:86:00.0", NvLinkRemoteBusIDs: []string{"0000:c1:00.0"}},
+				{DeviceUUID: "4", Minor: 1, BusID: "0000:af:00.0", NvLinkRemoteBusIDs: []string{"0000:c1:00.0"}},
+				{DeviceUUID: "5", Minor: 4, BusID: "0000:d8:00.0", NvLinkRemoteBusIDs: []string{"0000:c4:00.0"}},
+				{DeviceUUID: "6", Minor: 5, BusID: "0000:db:00.0"},
+			},
+			want: map[string]string{"1": "1", "2": "1", "3": "0", "4": "0", "5": "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, groupFabricPartitions(tt.devices))
+		})
+	}
+}
+
+func Test_gpuDeviceManager_updateFabricPartitions(t *testing.T) {
+	g := &gpuDeviceManager{
+		devices: []*device{
+			{DeviceUUID: "1", Minor: 0, BusID: "0000:3b:00.0", NvLinkRemoteBusIDs: []string{"0000:c1:00.0"}},
+			{DeviceUUID: "2", Minor: 1, BusID: "0000:5e:00.0", NvLinkRemoteBusIDs: []string{"0000:c1:00.0"}},
+		},
+	}
+	g.updateFabricPartitions()
+	assert.Equal(t, map[string]string{"1": "0", "2": "0"}, g.fabricPartitions)
+
+	// not recomputed if the gpu set is unchanged
+	g.devices[1].NvLinkRemoteBusIDs = []string{"0000:c2:00.0"}
+	g.updateFabricPartitions()
+	assert.Equal(t, map[string]string{"1": "0", "2": "0"}, g.fabricPartitions)
+
+	// recomputed if the gpu set changes
+	g.devices = append(g.devices, &device{DeviceUUID: "3", Minor: 2, BusID: "0000:86:00.0", NvLinkRemoteBusIDs: []string{"0000:c2:00.0"}})
+	g.updateFabricPartitions()
+	assert.Equal(t, map[string]string{"1": "0", "2": "1", "3": "1"}, g.fabricPartitions)
+	assert.Equal(t, util.GPUDeviceInfo{UUID: "3", Minor: 2, BusID: "0000:86:00.0", FabricPartitionID: "1"}, g.deviceInfos().(util.GPUDevices)[2])
+}
+
+// fakeRetiredPagesDevice reports the retired pages and counts the nvml calls.
+type fakeRetiredPagesDevice struct {
+	fieldValuesRet  nvml.Return
+	fieldRets       map[uint32]nvml.Return
+	retiredPagesRet nvml.Return
+	sbePages        []uint64
+	dbePages        []uint64
+	pending         bool
+	calls           int
+}
+
+func (d *fakeRetiredPagesDevice) GetFieldValues(values []nvml.FieldValue) nvml.Return {
+	d.calls++
+	if d.fieldValuesRet != nvml.SUCCESS {
+		return d.fieldValuesRet
+	}
+	for i := range values {
+		if ret, ok := d.fieldRets[values[i].FieldId]; ok {
+			values[i].NvmlReturn = uint32(ret)
+			continue
+		}
+		var value uint64
+		switch values[i].FieldId {
+		case nvml.FI_DEV_RETIRED_SBE:
+			value = uint64(len(d.sbePages))
+		case nvml.FI_DEV_RETIRED_DBE:
+			value = uint64(len(d.dbePages))
+		case nvml.FI_DEV_RETIRED_PENDING:
+			if d.pending {
+				value = 1
+			}
+		}
+		values[i].ValueType = uint32(nvml.VALUE_TYPE_UNSIGNED_LONG_LONG)
+		binary.LittleEndian.PutUint64(values[i].Value[:], value)
+	}
+	return nvml.SUCCESS
+}
+
+func (d *fakeRetiredPagesDevice) GetRetiredPages(cause nvml.PageRetirementCause) ([]uint64, nvml.Return) {
+	d.calls++
+	if d.retiredPagesRet != nvml.SUCCESS {
+		return nil, d.retiredPagesRet
+	}
+	if cause == nvml.PAGE_RETIREMENT_CAUSE_DOUBLE_BIT_ECC_ERROR {
+		return d.dbePages, nvml.SUCCESS
+	}
+	return d.sbePages, nvml.SUCCESS
+}
+
+func (d *fakeRetiredPagesDevice) GetRetiredPagesPendingStatus() (nvml.EnableState, nvml.Return) {
+	d.calls++
+	if d.pending {
+		return nvml.FEATURE_ENABLED, nvml.SUCCESS
+	}
+	return nvml.FEATURE_DISABLED, nvml.SUCCESS
+}
+
+func fakeNVMLErrorString(ret nvml.Return) string {
+	return fmt.Sprintf("nvml return %d", ret)
+}
+
+func Test_queryRetiredPages(t *testing.T) {
+	defer func(fn func(nvml.Return) string) { nvmlErrorString = fn }(nvmlErrorString)
+	nvmlErrorString = fakeNVMLErrorString
+	tests := []struct {
+		name                       string
+		device                     *fakeRetiredPagesDevice
+		fieldValuesUnsupported     bool
+		want                       *gpuRetiredPages
+		wantCalls                  int
+		wantFieldValuesUnsupported bool
+	}{
+		{
+			name:      "batch query of field values",
+			device:    &fakeRetiredPagesDevice{sbePages: []uint64{1, 2}, dbePages: []uint64{3}, pending: true},
+			want:      &gpuRetiredPages{Count: 3, Pending: true},
+			wantCalls: 1,
+		},
+		{
+			name: "page retirement not supported",
+			device: &fakeRetiredPagesDevice{
+				fieldRets: map[uint32]nvml.Return{nvml.FI_DEV_RETIRED_SBE: nvml.ERROR_NOT_SUPPORTED},
+			},
+			wantCalls: 1,
+		},
+		{
+			name: "fall back to the individual calls on a field error",
+			device: &fakeRetiredPagesDevice{
+				fieldRets: map[uint32]nvml.Return{nvml.FI_DEV_RETIRED_PENDING: nvml.ERROR_UNKNOWN},
+				sbePages:  []uint64{1},
+			},
+			want:      &gpuRetiredPages{Count: 1},
+			wantCalls: 4,
+		},
+		{
+			name:                       "fall back to the individual calls if field values are not supported",
+			device:                     &fakeRetiredPagesDevice{fieldValuesRet: nvml.ERROR_FUNCTION_NOT_FOUND, dbePages: []uint64{1}},
+			want:                       &gpuRetiredPages{Count: 1},
+			wantCalls:                  4,
+			wantFieldValuesUnsupported: true,
+		},
+		{
+			name:                       "query individually once field values are found not supported",
+			device:                     &fakeRetiredPagesDevice{dbePages: []uint64{1}, pending: true},
+			fieldValuesUnsupported:     true,
+			want:                       &gpuRetiredPages{Count: 1, Pending: true},
+			wantCalls:                  3,
+			wantFieldValuesUnsupported: true,
+		},
+		{
+			name:                       "page retirement not supported by the individual calls",
+			device:                     &fakeRetiredPagesDevice{retiredPagesRet: nvml.ERROR_NOT_SUPPORTED},
+			fieldValuesUnsupported:     true,
+			wantCalls:                  1,
+			wantFieldValuesUnsupported: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fieldValuesUnsupported := tt.fieldValuesUnsupported
+			assert.Equal(t, tt.want, queryRetiredPages(tt.device, "1", &fieldValuesUnsupported))
+			assert.Equal(t, tt.wantCalls, tt.device.calls)
+			assert.Equal(t, tt.wantFieldValuesUnsupported, fieldValuesUnsupported)
+		})
+	}
+}
+
+type fakeBAR1MemoryDevice struct {
+	bar1Memory nvml.BAR1Memory
+	ret        nvml.Return
+}
+
+func (d *fakeBAR1MemoryDevice) GetBAR1MemoryInfo() (nvml.BAR1Memory, nvml.Return) {
+	return d.bar1Memory, d.ret
+}
+
+func Test_collectBAR1MemoryUsed(t *testing.T) {
+	defer func(fn func(nvml.Return) string) { nvmlErrorString = fn }(nvmlErrorString)
+	nvmlErrorString = fakeNVMLErrorString
+
+	got := collectBAR1MemoryUsed(&fakeBAR1MemoryDevice{bar1Memory: nvml.BAR1Memory{Bar1Total: 256, Bar1Free: 192, Bar1Used: 64}}, "1")
+	assert.Equal(t, pointer.Uint64(64), got)
+	assert.Nil(t, collectBAR1MemoryUsed(&fakeBAR1MemoryDevice{ret: nvml.ERROR_NOT_SUPPORTED}, "1"))
+	assert.Nil(t, collectBAR1MemoryUsed(&fakeBAR1MemoryDevice{ret: nvml.ERROR_UNKNOWN}, "1"))
+}
+
+func Test_getFieldValueUint64(t *testing.T) {
+	value := &nvml.FieldValue{ValueType: uint32(nvml.VALUE_TYPE_UNSIGNED_INT)}
+	binary.LittleEndian.PutUint32(value.Value[:], 7)
+	got, ok := getFieldValueUint64(value)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(7), got)
+
+	value = &nvml.FieldValue{ValueType: uint32(nvml.VALUE_TYPE_DOUBLE)}
+	_, ok = getFieldValueUint64(value)
+	assert.False(t, ok)
+}
+
+// Benchmark_queryRetiredPages measures the nvml calls of the retired pages, which are the only per-cycle queries
+// batched by the field values.
+func Benchmark_queryRetiredPages(b *testing.B) {
+	defer func(fn func(nvml.Return) string) { nvmlErrorString = fn }(nvmlErrorString)
+	nvmlErrorString = fakeNVMLErrorString
+	for _, bm := range []struct {
+		name                   string
+		fieldValuesUnsupported bool
+	}{
+		{name: "FieldValues"},
+		{name: "Individual", fieldValuesUnsupported: true},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			d := &fakeRetiredPagesDevice{sbePages: []uint64{1, 2}, dbePages: []uint64{3}, pending: true}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				fieldValuesUnsupported := bm.fieldValuesUnsupported
+				queryRetiredPages(d, "1", &fieldValuesUnsupported)
+			}
+			b.ReportMetric(float64(d.calls)/float64(b.N), "nvml-calls/op")
+		})
+	}
+}
@@ -19,7 +19,11 @@ limitations under the License.
 
 package gpu
 
+import (
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/metricsadvisor/framework"
+)
+
 // initGPUDeviceManager will not retry if init fails,
-func initGPUDeviceManager() GPUDeviceManager {
+func initGPUDeviceManager(pciDeviceIDs framework.PCIDeviceIDs) GPUDeviceManager {
 	return &dummyDeviceManager{}
 }
@@ -0,0 +1,234 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gpu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/metriccache"
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/metricsadvisor/devices/helper"
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/util"
+)
+
+const (
+	xpuSMICmd        = "xpu-smi"
+	xpuSMICmdTimeout = 10 * time.Second
+)
+
+// runXPUSMI executes xpu-smi with the args and returns the output, it can be replaced in tests.
+var runXPUSMI = func(args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), xpuSMICmdTimeout)
+	defer cancel()
+
+	executable, err := exec.LookPath(xpuSMICmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup %s path, err: %w", xpuSMICmd, err)
+	}
+	output, err := exec.CommandContext(ctx, executable, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to exec command %s %v, err: %v", executable, args, err)
+	}
+	return output, nil
+}
+
+type xpuDeviceList struct {
+	DeviceList []xpuDeviceDetail `json:"device_list"`
+}
+
+type xpuDeviceDetail struct {
+	DeviceID               int32       `json:"device_id"`
+	DeviceName             string      `json:"device_name"`
+	DeviceType             string      `json:"device_type"`
+	UUID                   string      `json:"uuid"`
+	PCIBDFAddress          string      `json:"pci_bdf_address"`
+	MemoryPhysicalSizeByte json.Number `json:"memory_physical_size_byte,omitempty"`
+}
+
+type xpuDevice struct {
+	Minor       int32
+	DeviceUUID  string
+	MemoryTotal uint64
+	NodeID      int32
+	PCIE        string
+	BusID       string
+	ProductName string
+}
+
+// xpuDeviceManager discovers the Intel GPUs by xpu-smi, which is based on Level Zero.
+// It does not collect the gpu usages yet, and it probes the liveness of gpus by the periodical discovery,
+// a gpu missing from a successful discovery is regarded as unhealthy.
+type xpuDeviceManager struct {
+	sync.RWMutex
+	devices   []*xpuDevice
+	unhealthy map[string]struct{}
+	start     *atomic.Bool
+}
+
+// initXPUDeviceManager returns nil if no Intel GPU is discovered.
+func initXPUDeviceManager() GPUDeviceManager {
+	manager := &xpuDeviceManager{
+		unhealthy: map[string]struct{}{},
+		start:     atomic.NewBool(false),
+	}
+	if err := manager.initGPUData(); err != nil {
+		klog.V(4).Infof("xpu-smi init gpu data, error %s", err)
+		return nil
+	}
+	return manager
+}
+
+func (x *xpuDeviceManager) initGPUData() error {
+	list, err := discoverXPUDevices()
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		return errors.New("no gpu device found")
+	}
+
+	devices := make([]*xpuDevice, 0, len(list))
+	for _, d := range list {
+		output, err := runXPUSMI("discovery", "-d", fmt.Sprintf("%d", d.DeviceID), "-j")
+		if err != nil {
+			return fmt.Errorf("unable to get device %d detail: %v", d.DeviceID, err)
+		}
+		detail := &xpuDeviceDetail{}
+		if err := json.Unmarshal(output, detail); err != nil {
+			return fmt.Errorf("unable to parse device %d detail: %v", d.DeviceID, err)
+		}
+		memory, err := detail.MemoryPhysicalSizeByte.Int64()
+		if err != nil {
+			return fmt.Errorf("unable to parse device %d memory size %q: %v", d.DeviceID, detail.MemoryPhysicalSizeByte, err)
+		}
+		busID := strings.ToLower(d.PCIBDFAddress)
+		nodeID, pcie, busID, err := helper.ParsePCIInfo(busID)
+		if err != nil {
+			return err
+		}
+		devices = append(devices, &xpuDevice{
+			Minor:       d.DeviceID,
+			DeviceUUID:  d.UUID,
+			MemoryTotal: util.ConvertMemoryToBytes(uint64(memory), util.MemoryUnitByte),
+			NodeID:      nodeID,
+			PCIE:        pcie,
+			BusID:       busID,
+			ProductName: util.FormatGPUProductName(d.DeviceName),
+		})
+	}
+
+	x.Lock()
+	defer x.Unlock()
+	x.devices = devices
+	return nil
+}
+
+// discoverXPUDevices returns the gpus listed by xpu-smi.
+func discoverXPUDevices() ([]xpuDeviceDetail, error) {
+	output, err := runXPUSMI("discovery", "-j")
+	if err != nil {
+		return nil, err
+	}
+	list := &xpuDeviceList{}
+	if err := json.Unmarshal(output, list); err != nil {
+		return nil, fmt.Errorf("unable to parse device list: %v", err)
+	}
+	var gpus []xpuDeviceDetail
+	for _, d := range list.DeviceList {
+		if d.DeviceType != "" && d.DeviceType != "GPU" {
+			continue
+		}
+		gpus = append(gpus, d)
+	}
+	return gpus, nil
+}
+
+func (x *xpuDeviceManager) started() bool {
+	return x.start.Load()
+}
+
+func (x *xpuDeviceManager) deviceInfos() metriccache.Devices {
+	x.RLock()
+	defer x.RUnlock()
+	gpuDevices := util.GPUDevices{}
+	for _, d := range x.devices {
+		_, unhealthy := x.unhealthy[d.DeviceUUID]
+		gpuDevices = append(gpuDevices, util.GPUDeviceInfo{
+			UUID:        d.DeviceUUID,
+			Minor:       d.Minor,
+			MemoryTotal: d.MemoryTotal,
+			NodeID:      d.NodeID,
+			PCIE:        d.PCIE,
+			BusID:       d.BusID,
+			ProductName: d.ProductName,
+			Unhealthy:   unhealthy,
+		})
+	}
+	return gpuDevices
+}
+
+// collectGPUUsage probes the liveness of the gpus since the usages are not supported yet.
+func (x *xpuDeviceManager) collectGPUUsage() {
+	list, err := discoverXPUDevices()
+	if err != nil {
+		// keep the last health states, a failed discovery does not mean the gpus are broken
+		klog.Warningf("failed to probe the liveness of xpu devices, err: %v", err)
+		return
+	}
+	alive := make(map[string]struct{}, len(list))
+	for _, d := range list {
+		alive[d.UUID] = struct{}{}
+	}
+
+	x.Lock()
+	defer x.Unlock()
+	unhealthy := map[string]struct{}{}
+	for _, d := range x.devices {
+		if _, ok := alive[d.DeviceUUID]; !ok {
+			klog.V(4).Infof("xpu device %s is not discovered, mark it unhealthy", d.DeviceUUID)
+			unhealthy[d.DeviceUUID] = struct{}{}
+		}
+	}
+	x.unhealthy = unhealthy
+	x.start.Store(true)
+}
+
+func (x *xpuDeviceManager) getNodeGPUUsage() []metriccache.MetricSample {
+	return nil
+}
+
+func (x *xpuDeviceManager) getPodGPUUsage(uid, podParentDir string, cs []corev1.ContainerStatus) ([]metriccache.MetricSample, error) {
+	return nil, nil
+}
+
+func (x *xpuDeviceManager) getContainerGPUUsage(containerID, podParentDir string, c *corev1.ContainerStatus) ([]metriccache.MetricSample, error) {
+	return nil, nil
+}
+
+func (x *xpuDeviceManager) shutdown() error {
+	return nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gpu
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
+
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/util"
+)
+
+const testXPUDeviceList = `{
+  "device_list": [
+    {
+      "device_function_type": "physical",
+      "device_id": 0,
+      "device_name": "Intel(R) Data Center GPU Max 1550",
+      "device_type": "GPU",
+      "pci_bdf_address": "0000:29:00.0",
+      "uuid": "01000000-0000-0000-0000-000000290000"
+    },
+    {
+      "device_function_type": "physical",
+      "device_id": 1,
+      "device_name": "Intel(R) Data Center GPU Max 1550",
+      "device_type": "GPU",
+      "pci_bdf_address": "0000:3a:00.0",
+      "uuid": "01000000-0000-0000-0000-0000003a0000"
+    }
+  ]
+}`
+
+func Test_discoverXPUDevices(t *testing.T) {
+	oldRunXPUSMI := runXPUSMI
+	defer func() {
+		runXPUSMI = oldRunXPUSMI
+	}()
+
+	runXPUSMI = func(args ...string) ([]byte, error) {
+		return []byte(testXPUDeviceList), nil
+	}
+	got, err := discoverXPUDevices()
+	assert.NoError(t, err)
+	assert.Len(t, got, 2)
+	assert.Equal(t, "01000000-0000-0000-0000-000000290000", got[0].UUID)
+	assert.Equal(t, int32(1), got[1].DeviceID)
+
+	runXPUSMI = func(args ...string) ([]byte, error) {
+		return nil, fmt.Errorf("exec failed")
+	}
+	_, err = discoverXPUDevices()
+	assert.Error(t, err)
+
+	runXPUSMI = func(args ...string) ([]byte, error) {
+		return []byte("invalid"), nil
+	}
+	_, err = discoverXPUDevices()
+	assert.Error(t, err)
+}
+
+func Test_xpuDeviceManager_collectGPUUsage(t *testing.T) {
+	oldRunXPUSMI := runXPUSMI
+	defer func() {
+		runXPUSMI = oldRunXPUSMI
+	}()
+
+	x := &xpuDeviceManager{
+		devices: []*xpuDevice{
+			{Minor: 0, DeviceUUID: "01000000-0000-0000-0000-000000290000", MemoryTotal: 1000},
+			{Minor: 1, DeviceUUID: "01000000-0000-0000-0000-0000003a0000", MemoryTotal: 1000},
+			{Minor: 2, DeviceUUID: "lost", MemoryTotal: 1000},
+		},
+		unhealthy: map[string]struct{}{},
+		start:     atomic.NewBool(false),
+	}
+
+	// a failed probe keeps the states
+	runXPUSMI = func(args ...string) ([]byte, error) {
+		return nil, fmt.Errorf("exec failed")
+	}
+	x.collectGPUUsage()
+	assert.False(t, x.started())
+	assert.Empty(t, x.unhealthy)
+
+	runXPUSMI = func(args ...string) ([]byte, error) {
+		return []byte(testXPUDeviceList), nil
+	}
+	x.collectGPUUsage()
+	assert.True(t, x.started())
+	want := util.GPUDevices{
+		{UUID: "01000000-0000-0000-0000-000000290000", Minor: 0, MemoryTotal: 1000},
+		{UUID: "01000000-0000-0000-0000-0000003a0000", Minor: 1, MemoryTotal: 1000},
+		{UUID: "lost", Minor: 2, MemoryTotal: 1000, Unhealthy: true},
+	}
+	assert.Equal(t, want, x.deviceInfos())
+}
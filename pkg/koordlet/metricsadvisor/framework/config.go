@@ -18,6 +18,9 @@ package framework
 
 import (
 	"flag"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -38,6 +41,66 @@ type Config struct {
 	ResctrlCollectorInterval         time.Duration
 	EnablePageCacheCollector         bool
 	EnableResctrlCollector           bool
+	GPUPCIDeviceIDs                  PCIDeviceIDs
+}
+
+// PCIDeviceID is the PCI vendor and device id of a device, e.g. 10de:20b0 for the NVIDIA A100.
+type PCIDeviceID struct {
+	VendorID uint16
+	DeviceID uint16
+}
+
+func (id PCIDeviceID) String() string {
+	return fmt.Sprintf("%04x:%04x", id.VendorID, id.DeviceID)
+}
+
+// PCIDeviceIDs is a comma-separated list of the PCI ids in the form of "vendor:device" in hex, e.g. 10de:20b0,10de:2330.
+type PCIDeviceIDs []PCIDeviceID
+
+func (ids *PCIDeviceIDs) String() string {
+	values := make([]string, 0, len(*ids))
+	for _, id := range *ids {
+		values = append(values, id.String())
+	}
+	return strings.Join(values, ",")
+}
+
+func (ids *PCIDeviceIDs) Set(value string) error {
+	var parsed PCIDeviceIDs
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		vendor, device, ok := strings.Cut(v, ":")
+		if !ok {
+			return fmt.Errorf("invalid pci device id %q, must be in the form of vendor:device", v)
+		}
+		vendorID, err := strconv.ParseUint(vendor, 16, 16)
+		if err != nil {
+			return fmt.Errorf("invalid pci vendor id %q, err: %w", vendor, err)
+		}
+		deviceID, err := strconv.ParseUint(device, 16, 16)
+		if err != nil {
+			return fmt.Errorf("invalid pci device id %q, err: %w", device, err)
+		}
+		parsed = append(parsed, PCIDeviceID{VendorID: uint16(vendorID), DeviceID: uint16(deviceID)})
+	}
+	*ids = parsed
+	return nil
+}
+
+// Allows returns whether the device of the PCI id is allowed, i.e. the list is empty or contains the id.
+func (ids PCIDeviceIDs) Allows(id PCIDeviceID) bool {
+	if len(ids) == 0 {
+		return true
+	}
+	for _, allowed := range ids {
+		if allowed == id {
+			return true
+		}
+	}
+	return false
 }
 
 func NewDefaultConfig() *Config {
@@ -67,5 +130,6 @@ func (c *Config) InitFlags(fs *flag.FlagSet) {
 	fs.DurationVar(&c.ColdPageCollectorInterval, "coldpage-collector-interval", c.ColdPageCollectorInterval, "Collect cold page interval. Non-zero values should contain a corresponding time unit (e.g. 1s, 2m, 3h).")
 	fs.BoolVar(&c.EnablePageCacheCollector, "enable-pagecache-collector", c.EnablePageCacheCollector, "Enable cache collector of node, pods and containers")
 	fs.BoolVar(&c.EnableResctrlCollector, "enable-resctrl-collector", c.EnableResctrlCollector, "Enable cache collector of node, pods and containers")
+	fs.Var(&c.GPUPCIDeviceIDs, "gpu-pci-device-ids", "The PCI ids of the gpus to discover in the form of vendor:device in hex, e.g. 10de:20b0,10de:2330 for the NVIDIA A100 and H100, so that the display-only or virtual devices enumerated by nvml are not reported. Empty means all gpus are discovered.")
 	fs.DurationVar(&c.ResctrlCollectorInterval, "resctrl-collector-interval", c.ResctrlCollectorInterval, "Collect cpi time window. Non-zero values should contain a corresponding time unit (e.g. 1s, 2m, 3h).")
 }
@@ -112,3 +112,20 @@ func Test_InitFlags(t *testing.T) {
 		})
 	}
 }
+
+func Test_PCIDeviceIDs(t *testing.T) {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	c := NewDefaultConfig()
+	c.InitFlags(fs)
+	assert.NoError(t, fs.Parse([]string{"--gpu-pci-device-ids=10de:20b0, 10DE:2330"}))
+	assert.Equal(t, PCIDeviceIDs{{VendorID: 0x10de, DeviceID: 0x20b0}, {VendorID: 0x10de, DeviceID: 0x2330}}, c.GPUPCIDeviceIDs)
+	assert.Equal(t, "10de:20b0,10de:2330", c.GPUPCIDeviceIDs.String())
+	assert.True(t, c.GPUPCIDeviceIDs.Allows(PCIDeviceID{VendorID: 0x10de, DeviceID: 0x2330}))
+	assert.False(t, c.GPUPCIDeviceIDs.Allows(PCIDeviceID{VendorID: 0x10de, DeviceID: 0x1eb8}))
+	assert.True(t, PCIDeviceIDs(nil).Allows(PCIDeviceID{VendorID: 0x1af4, DeviceID: 0x1050}), "all devices should be allowed by an empty list")
+
+	for _, invalid := range []string{"10de", "10de:xyz", "10de:20b00"} {
+		ids := PCIDeviceIDs{}
+		assert.Error(t, ids.Set(invalid), invalid)
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/koordinator-sh/koordinator/pkg/koordlet/metricsadvisor/collectors/podthrottled"
 	"github.com/koordinator-sh/koordinator/pkg/koordlet/metricsadvisor/collectors/resctrl"
 	"github.com/koordinator-sh/koordinator/pkg/koordlet/metricsadvisor/collectors/sysresource"
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/metricsadvisor/devices/fpga"
 	"github.com/koordinator-sh/koordinator/pkg/koordlet/metricsadvisor/devices/gpu"
 	"github.com/koordinator-sh/koordinator/pkg/koordlet/metricsadvisor/devices/rdma"
 	"github.com/koordinator-sh/koordinator/pkg/koordlet/metricsadvisor/framework"
@@ -40,6 +41,7 @@ var (
 	devicePlugins = map[string]framework.DeviceFactory{
 		gpu.DeviceCollectorName:  gpu.New,
 		rdma.DeviceCollectorName: rdma.New,
+		fpga.DeviceCollectorName: fpga.New,
 	}
 
 	collectorPlugins = map[string]framework.CollectorFactory{
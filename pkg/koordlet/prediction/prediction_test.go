@@ -68,6 +68,17 @@ func (m *mockStatesInformer) GetVolumeName(pvcNamespace, pvcName string) string
 func (m *mockStatesInformer) RegisterCallbacks(objType statesinformer.RegisterType, name, description string, callbackFn statesinformer.UpdateCbFn) {
 }
 
+func (m *mockStatesInformer) RegisterGPUHealthObserver(name string, observerFn statesinformer.GPUHealthObserverFn) {
+}
+
+func (m *mockStatesInformer) IsGPUHealthy(uuid string) bool {
+	return true
+}
+
+func (m *mockStatesInformer) ListUnhealthyGPUs() []string {
+	return nil
+}
+
 func TestInformer(t *testing.T) {
 	pod1 := &v1.Pod{ObjectMeta: metav1.ObjectMeta{UID: "pod1"}}
 	pod2 := &v1.Pod{ObjectMeta: metav1.ObjectMeta{UID: "pod2"}}
@@ -192,26 +192,25 @@ func CollectContainerThrottledMetric(metricCache metriccache.MetricCache, contai
 	return aggregateResult, nil
 }
 
-func GenerateQueryParamsAvg(windowDuration time.Duration) *metriccache.QueryParam {
+// GenerateQueryParams returns the query param of the last window aggregated by the aggregate, e.g. the max for the
+// peak usage and the avg for the steady usage.
+func GenerateQueryParams(windowDuration time.Duration, aggregate metriccache.AggregationType) *metriccache.QueryParam {
 	end := time.Now()
 	start := end.Add(-windowDuration)
 	queryParam := &metriccache.QueryParam{
-		Aggregate: metriccache.AggregationTypeAVG,
+		Aggregate: aggregate,
 		Start:     &start,
 		End:       &end,
 	}
 	return queryParam
 }
 
+func GenerateQueryParamsAvg(windowDuration time.Duration) *metriccache.QueryParam {
+	return GenerateQueryParams(windowDuration, metriccache.AggregationTypeAVG)
+}
+
 func GenerateQueryParamsLast(windowDuration time.Duration) *metriccache.QueryParam {
-	end := time.Now()
-	start := end.Add(-windowDuration)
-	queryParam := &metriccache.QueryParam{
-		Aggregate: metriccache.AggregationTypeLast,
-		Start:     &start,
-		End:       &end,
-	}
-	return queryParam
+	return GenerateQueryParams(windowDuration, metriccache.AggregationTypeLast)
 }
 
 func Query(querier metriccache.Querier, resource metriccache.MetricResource, properties map[metriccache.MetricProperty]string) (metriccache.AggregateResult, error) {
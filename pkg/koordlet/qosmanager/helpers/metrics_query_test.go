@@ -216,3 +216,13 @@ func Test_resmanager_collectorNodeMetricLast(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateQueryParams(t *testing.T) {
+	for _, aggregate := range []metriccache.AggregationType{metriccache.AggregationTypeAVG, metriccache.AggregationTypeMax, metriccache.AggregationTypeP95} {
+		queryParam := GenerateQueryParams(time.Minute, aggregate)
+		assert.Equal(t, aggregate, queryParam.Aggregate)
+		assert.Equal(t, time.Minute, queryParam.End.Sub(*queryParam.Start))
+	}
+	assert.Equal(t, metriccache.AggregationTypeAVG, GenerateQueryParamsAvg(time.Minute).Aggregate)
+	assert.Equal(t, metriccache.AggregationTypeLast, GenerateQueryParamsLast(time.Minute).Aggregate)
+}
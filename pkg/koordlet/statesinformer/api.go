@@ -114,6 +114,29 @@ func (t *CallbackTarget) String() string {
 
 type UpdateCbFn func(t RegisterType, obj interface{}, target *CallbackTarget)
 
+const (
+	// GPUHealthReasonXidError means the gpu is failed by a xid error.
+	GPUHealthReasonXidError = "XidError"
+	// GPUHealthReasonUnmonitored means the gpu is failed since it is too old to support the health check.
+	GPUHealthReasonUnmonitored = "Unmonitored"
+	// GPUHealthReasonNotDiscovered means the unhealthy gpu is dropped since it is no longer discovered.
+	GPUHealthReasonNotDiscovered = "NotDiscovered"
+)
+
+// GPUHealthTransition is a change of the health state of a gpu.
+type GPUHealthTransition struct {
+	DeviceUUID string
+	OldHealthy bool
+	NewHealthy bool
+	// Reason is the cause of the transition, e.g. GPUHealthReasonXidError.
+	Reason string
+	// Xid is the code of the xid error failing the gpu, or zero if the transition is not caused by a xid error.
+	Xid uint64
+}
+
+// GPUHealthObserverFn is called on each health transition of the gpus, it should return quickly without blocking.
+type GPUHealthObserverFn func(transition GPUHealthTransition)
+
 type StatesInformer interface {
 	Run(stopCh <-chan struct{}) error
 	HasSynced() bool
@@ -129,4 +152,9 @@ type StatesInformer interface {
 	GetVolumeName(pvcNamespace, pvcName string) string
 
 	RegisterCallbacks(objType RegisterType, name, description string, callbackFn UpdateCbFn)
+	RegisterGPUHealthObserver(name string, observerFn GPUHealthObserverFn)
+	// IsGPUHealthy returns whether the gpu of the uuid is not known unhealthy by the gpu health check.
+	IsGPUHealthy(uuid string) bool
+	// ListUnhealthyGPUs returns the uuids of the gpus known unhealthy by the gpu health check.
+	ListUnhealthyGPUs() []string
 }
@@ -18,35 +18,96 @@ package impl
 
 import (
 	"flag"
+	"fmt"
+	"strings"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	cliflag "k8s.io/component-base/cli/flag"
+
+	apiext "github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/metriccache"
 )
 
 type Config struct {
-	KubeletPreferredAddressType string
-	KubeletSyncInterval         time.Duration
-	KubeletSyncTimeout          time.Duration
-	InsecureKubeletTLS          bool
-	KubeletReadOnlyPort         uint
-	NodeTopologySyncInterval    time.Duration
-	DisableQueryKubeletConfig   bool
-	EnableNodeMetricReport      bool
-	MetricReportInterval        time.Duration // Deprecated
-	EnablePodTaskIds            bool
+	KubeletPreferredAddressType     string
+	KubeletSyncInterval             time.Duration
+	KubeletSyncTimeout              time.Duration
+	InsecureKubeletTLS              bool
+	KubeletReadOnlyPort             uint
+	NodeTopologySyncInterval        time.Duration
+	DisableQueryKubeletConfig       bool
+	EnableNodeMetricReport          bool
+	MetricReportInterval            time.Duration // Deprecated
+	EnablePodTaskIds                bool
+	GPUHealthCheckWaitTimeout       time.Duration
+	GPUCoreGranularity              int64
+	EnableGPUCodecResources         bool
+	GPUTimeSlicingReplicas          cliflag.ConfigurationMap
+	GPUResourceNameMapping          cliflag.ConfigurationMap
+	ExpectedGPUCount                int
+	GPUDeviceStabilizationPeriod    time.Duration
+	DeviceReportTimeRefreshInterval time.Duration
+	GPUHealthCheckStaleThreshold    time.Duration
+	DeviceReportMinInterval         time.Duration
+	MarkUnmonitoredGPUUnhealthy     bool
+	EnableGPUUtilizationWindow      bool
+	EnableVGPUReport                bool
+	DeviceReportJitterFactor        float64
+	GPUMemoryGranularity            int64
+	EnableGPUCoolingCondition       bool
+	MaxReportedDevices              int
+	CapReportedDevices              bool
+	EnableGPUMemoryCondition        bool
+	GPURetiredPagesThreshold        int
+	DrainGPUOnCoolingDegraded       bool
+	DrainGPUOnMemoryDegraded        bool
+	GPUMetricAggregationType        string
+	GPUXidStabilizations            cliflag.ConfigurationMap
+	EnableGPUBAR1MemoryResource     bool
+	DeviceSummaryTenantLabel        string
+	GPUHealthNodeConditions         string
 }
 
 func NewDefaultConfig() *Config {
 	return &Config{
-		KubeletPreferredAddressType: string(corev1.NodeInternalIP),
-		KubeletSyncInterval:         10 * time.Second,
-		KubeletSyncTimeout:          3 * time.Second,
-		InsecureKubeletTLS:          false,
-		KubeletReadOnlyPort:         10255,
-		NodeTopologySyncInterval:    3 * time.Second,
-		DisableQueryKubeletConfig:   false,
-		EnableNodeMetricReport:      true,
-		EnablePodTaskIds:            false,
+		KubeletPreferredAddressType:     string(corev1.NodeInternalIP),
+		KubeletSyncInterval:             10 * time.Second,
+		KubeletSyncTimeout:              3 * time.Second,
+		InsecureKubeletTLS:              false,
+		KubeletReadOnlyPort:             10255,
+		NodeTopologySyncInterval:        3 * time.Second,
+		DisableQueryKubeletConfig:       false,
+		EnableNodeMetricReport:          true,
+		EnablePodTaskIds:                false,
+		GPUHealthCheckWaitTimeout:       time.Second,
+		GPUCoreGranularity:              apiext.DefaultGPUCoreGranularity,
+		EnableGPUCodecResources:         false,
+		GPUTimeSlicingReplicas:          cliflag.ConfigurationMap{},
+		GPUResourceNameMapping:          cliflag.ConfigurationMap{},
+		ExpectedGPUCount:                0,
+		GPUDeviceStabilizationPeriod:    0,
+		DeviceReportTimeRefreshInterval: time.Minute,
+		GPUHealthCheckStaleThreshold:    30 * time.Second,
+		DeviceReportMinInterval:         time.Second,
+		MarkUnmonitoredGPUUnhealthy:     true,
+		EnableGPUUtilizationWindow:      false,
+		EnableVGPUReport:                false,
+		DeviceReportJitterFactor:        0,
+		GPUMemoryGranularity:            0,
+		EnableGPUCoolingCondition:       false,
+		MaxReportedDevices:              0,
+		CapReportedDevices:              false,
+		EnableGPUMemoryCondition:        false,
+		GPURetiredPagesThreshold:        60,
+		DrainGPUOnCoolingDegraded:       false,
+		DrainGPUOnMemoryDegraded:        false,
+		GPUMetricAggregationType:        "avg",
+		GPUXidStabilizations:            cliflag.ConfigurationMap{},
+		EnableGPUBAR1MemoryResource:     false,
+		DeviceSummaryTenantLabel:        "",
+		GPUHealthNodeConditions:         "",
 	}
 }
 
@@ -61,4 +122,48 @@ func (c *Config) InitFlags(fs *flag.FlagSet) {
 	fs.DurationVar(&c.MetricReportInterval, "report-interval", c.MetricReportInterval, "Deprecated since v1.1, use ColocationStrategy.MetricReportIntervalSeconds in config map of slo-controller")
 	fs.BoolVar(&c.EnableNodeMetricReport, "enable-node-metric-report", c.EnableNodeMetricReport, "Enable status update of node metric crd.")
 	fs.BoolVar(&c.EnablePodTaskIds, "enable-pod-taskids", c.EnablePodTaskIds, "Enable pod taskids in statesinformer.")
+	fs.DurationVar(&c.GPUHealthCheckWaitTimeout, "gpu-health-check-wait-timeout", c.GPUHealthCheckWaitTimeout, "The timeout of waiting for the gpu health events in each loop, which also bounds the delay to stop the gpu health check. Non-zero values should contain a corresponding time unit (e.g. 1s, 500ms).")
+	fs.Int64Var(&c.GPUCoreGranularity, "gpu-core-granularity", c.GPUCoreGranularity, "The gpu-core quantity of a whole GPU reported in the Device, e.g. 100 for the percentage granularity and 1000 for the milli granularity.")
+	fs.BoolVar(&c.EnableGPUCodecResources, "enable-gpu-codec-resources", c.EnableGPUCodecResources, "Enable reporting the gpu video encoder and decoder as the resources of the Device.")
+	fs.Var(&c.GPUTimeSlicingReplicas, "gpu-time-slicing-replicas", "The replicas of each GPU reported in the Device when the NVIDIA time-slicing is enabled, keyed by the GPU product name reported in the Device label, e.g. A100-SXM4-80GB=4,Tesla-T4=2. The GPU whose product name is not configured is reported as one device.")
+	fs.Var(&c.GPUResourceNameMapping, "gpu-resource-name-mapping", "The mapping from the default gpu resource names to the names reported in the Device, e.g. koordinator.sh/gpu-core=example.com/gpu-core,koordinator.sh/gpu-memory=example.com/gpu-memory. The unmapped resources are reported with the default names.")
+	fs.IntVar(&c.ExpectedGPUCount, "expected-gpu-count", c.ExpectedGPUCount, "The expected count of the physical gpus on the node, the Device is not created until the discovered gpus reach the count or the count is stable for the gpu-device-stabilization-period. The node label node.koordinator.sh/gpu-expected-count takes precedence. 0 means no expectation.")
+	fs.DurationVar(&c.GPUDeviceStabilizationPeriod, "gpu-device-stabilization-period", c.GPUDeviceStabilizationPeriod, "The period which the count of the discovered gpus must keep unchanged before the Device is created when the expected gpu count is not reached. 0 means waiting for the expected count, or no waiting if there is no expectation.")
+	fs.DurationVar(&c.DeviceReportTimeRefreshInterval, "device-report-time-refresh-interval", c.DeviceReportTimeRefreshInterval, "The interval to refresh the last report time of the devices in Device when the devices are unchanged. 0 means refreshing in each report cycle.")
+	fs.DurationVar(&c.GPUHealthCheckStaleThreshold, "gpu-health-check-stale-threshold", c.GPUHealthCheckStaleThreshold, "The threshold since the last return of waiting for the gpu health events, beyond which the gpu health check is regarded as stuck and restarted. Non-zero values should contain a corresponding time unit (e.g. 1s, 500ms).")
+	fs.DurationVar(&c.DeviceReportMinInterval, "device-report-min-interval", c.DeviceReportMinInterval, "The minimum interval between two Device reportings, the reportings triggered within the interval are merged into one after it. Zero means no limit. Non-zero values should contain a corresponding time unit (e.g. 1s, 500ms).")
+	fs.BoolVar(&c.MarkUnmonitoredGPUUnhealthy, "mark-unmonitored-gpu-unhealthy", c.MarkUnmonitoredGPUUnhealthy, "Mark the gpus too old to support the health events unhealthy. If false, they are reported healthy but not monitored by the gpu health check, e.g. the legacy gpus working fine for the workloads.")
+	fs.BoolVar(&c.EnableGPUUtilizationWindow, "enable-gpu-utilization-window", c.EnableGPUUtilizationWindow, "Enable reporting the avg and max utilization of each gpu over the node metric aggregate window as the koordlet metrics, so that a gpu just briefly idle can be told from a gpu idle over the window.")
+	fs.BoolVar(&c.EnableVGPUReport, "enable-vgpu-report", c.EnableVGPUReport, "Enable reporting the active vGPU instances of the physical gpus on the virtualized host as the gpus of the Device, whose gpu-memory is the framebuffer of the vGPU profile. The physical gpus without vGPU instances are reported as is.")
+	fs.Float64Var(&c.DeviceReportJitterFactor, "device-report-jitter-factor", c.DeviceReportJitterFactor, "The max factor of the random jitter added to the node-topology-sync-interval of the periodic Device reporting, e.g. 0.2 for up to 20% longer, which spreads the reportings of the nodes over time. The reportings triggered by the changes are not delayed. Zero means no jitter.")
+	fs.Int64Var(&c.GPUMemoryGranularity, "gpu-memory-granularity", c.GPUMemoryGranularity, "The bytes which the gpu-memory reported in the Device is rounded down to, e.g. 1073741824 when the device plugin allocates the gpu memory in whole GiB, so that the pods are admitted and scheduled with the gpu memory allocatable by the device plugin. Zero means no rounding.")
+	fs.BoolVar(&c.EnableGPUCoolingCondition, "enable-gpu-cooling-condition", c.EnableGPUCoolingCondition, "Enable reporting the GPUCoolingDegraded condition of the Device and labeling the gpus whose fan stops while thermally loaded. The fan speed is collected as the metric regardless of this flag.")
+	fs.IntVar(&c.MaxReportedDevices, "max-reported-devices", c.MaxReportedDevices, "The max number of the devices reported in the Device of the node, over which a warning is logged, so that a misconfigured device discovery does not produce an enormous Device failing to persist. Zero means no limit.")
+	fs.BoolVar(&c.CapReportedDevices, "cap-reported-devices", c.CapReportedDevices, "Cap the devices reported in the Device to the max-reported-devices, where the gpus are kept before the rdma and fpga devices. If false, all devices are reported with the warning.")
+	fs.BoolVar(&c.EnableGPUMemoryCondition, "enable-gpu-memory-condition", c.EnableGPUMemoryCondition, "Enable reporting the GPUMemoryDegraded condition of the Device and labeling the gpus whose memory pages are retired over the gpu-retired-pages-threshold or pending retirement. The retired pages are collected as the metric regardless of this flag.")
+	fs.IntVar(&c.GPURetiredPagesThreshold, "gpu-retired-pages-threshold", c.GPURetiredPagesThreshold, "The count of the retired memory pages at which the gpu memory is regarded as degraded. Zero means only the pages pending retirement degrade the gpu memory.")
+	fs.BoolVar(&c.DrainGPUOnCoolingDegraded, "drain-gpu-on-cooling-degraded", c.DrainGPUOnCoolingDegraded, "Report the healthy gpus whose fan stops while thermally loaded as Draining, which accept no new pods while the running ones keep running.")
+	fs.BoolVar(&c.DrainGPUOnMemoryDegraded, "drain-gpu-on-memory-degraded", c.DrainGPUOnMemoryDegraded, "Report the healthy gpus whose memory pages are retired over the gpu-retired-pages-threshold or pending retirement as Draining, which accept no new pods while the running ones keep running.")
+	fs.StringVar(&c.GPUMetricAggregationType, "gpu-metric-aggregation-type", c.GPUMetricAggregationType, "The aggregation of the gpu usage reported in the node usage of NodeMetric over the aggregate window, e.g. avg for the spreading policies and max or p95 for the bin-packing policies which care about the peak usage. The aggregated node usages keep their percentiles.")
+	fs.Var(&c.GPUXidStabilizations, "gpu-xid-stabilizations", "The stabilizations of the xid errors sometimes recoverable in the format of count/window keyed by the xid, e.g. 48=3/10m,63=2/1h, where the gpu is marked unhealthy only after the xid occurs the count times within the window. The xids not configured mark the gpu unhealthy on the first occurrence as is.")
+	fs.BoolVar(&c.EnableGPUBAR1MemoryResource, "enable-gpu-bar1-memory-resource", c.EnableGPUBAR1MemoryResource, "Enable reporting the total BAR1 memory of the gpus in bytes as the resource koordinator.sh/gpu-bar1-memory of the Device, which can be exhausted by the peer-to-peer mappings before the framebuffer, e.g. by the CUDA IPC. The BAR1 memory usage is collected as the metric regardless of this flag.")
+	fs.StringVar(&c.DeviceSummaryTenantLabel, "device-summary-tenant-label", c.DeviceSummaryTenantLabel, "The node label whose value is the namespace of the tenant the node belongs to, in which a DeviceSummary of the node is reported in addition to the cluster-scoped Device for the tenant dashboards. Empty means no DeviceSummary is reported.")
+	fs.StringVar(&c.GPUHealthNodeConditions, "gpu-health-node-conditions", c.GPUHealthNodeConditions, "The comma-separated types of the node conditions set by the node-problem-detector for the gpu faults, e.g. GPUProblem,XidError. While such a condition is true, the gpus whose uuids are named in its message are reported unhealthy in the Device, or all gpus if it names none. Empty means the node conditions are ignored.")
+}
+
+// Validate returns an error if the config is invalid, so that the koordlet fails to start instead of running with
+// a config different from the configured.
+func (c *Config) Validate() error {
+	if _, err := metriccache.ParseAggregationType(c.GPUMetricAggregationType); err != nil {
+		return fmt.Errorf("invalid gpu-metric-aggregation-type: %w", err)
+	}
+	if _, err := parseXidStabilizations(c.GPUXidStabilizations); err != nil {
+		return fmt.Errorf("invalid gpu-xid-stabilizations: %w", err)
+	}
+	if c.DeviceSummaryTenantLabel != "" {
+		if errs := validation.IsQualifiedName(c.DeviceSummaryTenantLabel); len(errs) > 0 {
+			return fmt.Errorf("invalid device-summary-tenant-label %q: %s", c.DeviceSummaryTenantLabel, strings.Join(errs, "; "))
+		}
+	}
+	return nil
 }
@@ -23,6 +23,9 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
+	cliflag "k8s.io/component-base/cli/flag"
+
+	apiext "github.com/koordinator-sh/koordinator/apis/extension"
 )
 
 func TestNewDefaultConfig(t *testing.T) {
@@ -33,16 +36,43 @@ func TestNewDefaultConfig(t *testing.T) {
 		{
 			name: "config",
 			want: &Config{
-				KubeletPreferredAddressType: string(corev1.NodeInternalIP),
-				KubeletSyncInterval:         10 * time.Second,
-				KubeletSyncTimeout:          3 * time.Second,
-				InsecureKubeletTLS:          false,
-				KubeletReadOnlyPort:         10255,
-				NodeTopologySyncInterval:    3 * time.Second,
-				DisableQueryKubeletConfig:   false,
-				EnableNodeMetricReport:      true,
-				MetricReportInterval:        0,
-				EnablePodTaskIds:            false,
+				KubeletPreferredAddressType:     string(corev1.NodeInternalIP),
+				KubeletSyncInterval:             10 * time.Second,
+				KubeletSyncTimeout:              3 * time.Second,
+				InsecureKubeletTLS:              false,
+				KubeletReadOnlyPort:             10255,
+				NodeTopologySyncInterval:        3 * time.Second,
+				DisableQueryKubeletConfig:       false,
+				EnableNodeMetricReport:          true,
+				MetricReportInterval:            0,
+				EnablePodTaskIds:                false,
+				GPUHealthCheckWaitTimeout:       time.Second,
+				GPUCoreGranularity:              apiext.DefaultGPUCoreGranularity,
+				EnableGPUCodecResources:         false,
+				GPUTimeSlicingReplicas:          cliflag.ConfigurationMap{},
+				GPUResourceNameMapping:          cliflag.ConfigurationMap{},
+				ExpectedGPUCount:                0,
+				GPUDeviceStabilizationPeriod:    0,
+				DeviceReportTimeRefreshInterval: time.Minute,
+				GPUHealthCheckStaleThreshold:    30 * time.Second,
+				DeviceReportMinInterval:         time.Second,
+				MarkUnmonitoredGPUUnhealthy:     true,
+				EnableGPUUtilizationWindow:      false,
+				EnableVGPUReport:                false,
+				DeviceReportJitterFactor:        0,
+				GPUMemoryGranularity:            0,
+				EnableGPUCoolingCondition:       false,
+				MaxReportedDevices:              0,
+				CapReportedDevices:              false,
+				EnableGPUMemoryCondition:        false,
+				GPURetiredPagesThreshold:        60,
+				DrainGPUOnCoolingDegraded:       false,
+				DrainGPUOnMemoryDegraded:        false,
+				GPUMetricAggregationType:        "avg",
+				GPUXidStabilizations:            cliflag.ConfigurationMap{},
+				EnableGPUBAR1MemoryResource:     false,
+				DeviceSummaryTenantLabel:        "",
+				GPUHealthNodeConditions:         "",
 			},
 		},
 	}
@@ -66,19 +96,73 @@ func TestConfig_InitFlags(t *testing.T) {
 		"--disable-query-kubelet-config=true",
 		"--enable-node-metric-report=false",
 		"--enable-pod-taskids=true",
+		"--gpu-health-check-wait-timeout=2s",
+		"--gpu-core-granularity=1000",
+		"--enable-gpu-codec-resources=true",
+		"--gpu-time-slicing-replicas=Tesla-T4=2",
+		"--gpu-resource-name-mapping=koordinator.sh/gpu-core=example.com/gpu-core",
+		"--expected-gpu-count=8",
+		"--gpu-device-stabilization-period=5m",
+		"--device-report-time-refresh-interval=2m",
+		"--gpu-health-check-stale-threshold=1m",
+		"--device-report-min-interval=5s",
+		"--mark-unmonitored-gpu-unhealthy=false",
+		"--enable-gpu-utilization-window=true",
+		"--enable-vgpu-report=true",
+		"--device-report-jitter-factor=0.2",
+		"--gpu-memory-granularity=1073741824",
+		"--enable-gpu-cooling-condition=true",
+		"--max-reported-devices=512",
+		"--cap-reported-devices=true",
+		"--enable-gpu-memory-condition=true",
+		"--gpu-retired-pages-threshold=30",
+		"--drain-gpu-on-cooling-degraded=true",
+		"--drain-gpu-on-memory-degraded=true",
+		"--gpu-metric-aggregation-type=max",
+		"--gpu-xid-stabilizations=48=3/10m",
+		"--enable-gpu-bar1-memory-resource=true",
+		"--device-summary-tenant-label=example.com/tenant",
+		"--gpu-health-node-conditions=GPUProblem,XidError",
 	}
 	fs := flag.NewFlagSet(cmdArgs[0], flag.ExitOnError)
 
 	type fields struct {
-		KubeletPreferredAddressType string
-		KubeletSyncInterval         time.Duration
-		KubeletSyncTimeout          time.Duration
-		InsecureKubeletTLS          bool
-		KubeletReadOnlyPort         uint
-		NodeTopologySyncInterval    time.Duration
-		DisableQueryKubeletConfig   bool
-		EnableNodeMetricReport      bool
-		EnablePodTaskIds            bool
+		KubeletPreferredAddressType     string
+		KubeletSyncInterval             time.Duration
+		KubeletSyncTimeout              time.Duration
+		InsecureKubeletTLS              bool
+		KubeletReadOnlyPort             uint
+		NodeTopologySyncInterval        time.Duration
+		DisableQueryKubeletConfig       bool
+		EnableNodeMetricReport          bool
+		EnablePodTaskIds                bool
+		GPUHealthCheckWaitTimeout       time.Duration
+		GPUCoreGranularity              int64
+		EnableGPUCodecResources         bool
+		GPUTimeSlicingReplicas          cliflag.ConfigurationMap
+		GPUResourceNameMapping          cliflag.ConfigurationMap
+		ExpectedGPUCount                int
+		GPUDeviceStabilizationPeriod    time.Duration
+		DeviceReportTimeRefreshInterval time.Duration
+		GPUHealthCheckStaleThreshold    time.Duration
+		DeviceReportMinInterval         time.Duration
+		MarkUnmonitoredGPUUnhealthy     bool
+		EnableGPUUtilizationWindow      bool
+		EnableVGPUReport                bool
+		DeviceReportJitterFactor        float64
+		GPUMemoryGranularity            int64
+		EnableGPUCoolingCondition       bool
+		MaxReportedDevices              int
+		CapReportedDevices              bool
+		EnableGPUMemoryCondition        bool
+		GPURetiredPagesThreshold        int
+		DrainGPUOnCoolingDegraded       bool
+		DrainGPUOnMemoryDegraded        bool
+		GPUMetricAggregationType        string
+		GPUXidStabilizations            cliflag.ConfigurationMap
+		EnableGPUBAR1MemoryResource     bool
+		DeviceSummaryTenantLabel        string
+		GPUHealthNodeConditions         string
 	}
 	type args struct {
 		fs *flag.FlagSet
@@ -91,15 +175,42 @@ func TestConfig_InitFlags(t *testing.T) {
 		{
 			name: "not default",
 			fields: fields{
-				KubeletPreferredAddressType: "Hostname",
-				KubeletSyncInterval:         30 * time.Second,
-				KubeletSyncTimeout:          10 * time.Second,
-				InsecureKubeletTLS:          true,
-				KubeletReadOnlyPort:         10258,
-				NodeTopologySyncInterval:    10 * time.Second,
-				DisableQueryKubeletConfig:   true,
-				EnableNodeMetricReport:      false,
-				EnablePodTaskIds:            true,
+				KubeletPreferredAddressType:     "Hostname",
+				KubeletSyncInterval:             30 * time.Second,
+				KubeletSyncTimeout:              10 * time.Second,
+				InsecureKubeletTLS:              true,
+				KubeletReadOnlyPort:             10258,
+				NodeTopologySyncInterval:        10 * time.Second,
+				DisableQueryKubeletConfig:       true,
+				EnableNodeMetricReport:          false,
+				EnablePodTaskIds:                true,
+				GPUHealthCheckWaitTimeout:       2 * time.Second,
+				GPUCoreGranularity:              1000,
+				EnableGPUCodecResources:         true,
+				GPUTimeSlicingReplicas:          cliflag.ConfigurationMap{"Tesla-T4": "2"},
+				GPUResourceNameMapping:          cliflag.ConfigurationMap{"koordinator.sh/gpu-core": "example.com/gpu-core"},
+				ExpectedGPUCount:                8,
+				GPUDeviceStabilizationPeriod:    5 * time.Minute,
+				DeviceReportTimeRefreshInterval: 2 * time.Minute,
+				GPUHealthCheckStaleThreshold:    time.Minute,
+				DeviceReportMinInterval:         5 * time.Second,
+				MarkUnmonitoredGPUUnhealthy:     false,
+				EnableGPUUtilizationWindow:      true,
+				EnableVGPUReport:                true,
+				DeviceReportJitterFactor:        0.2,
+				GPUMemoryGranularity:            1073741824,
+				EnableGPUCoolingCondition:       true,
+				MaxReportedDevices:              512,
+				CapReportedDevices:              true,
+				EnableGPUMemoryCondition:        true,
+				GPURetiredPagesThreshold:        30,
+				DrainGPUOnCoolingDegraded:       true,
+				DrainGPUOnMemoryDegraded:        true,
+				GPUMetricAggregationType:        "max",
+				GPUXidStabilizations:            cliflag.ConfigurationMap{"48": "3/10m"},
+				EnableGPUBAR1MemoryResource:     true,
+				DeviceSummaryTenantLabel:        "example.com/tenant",
+				GPUHealthNodeConditions:         "GPUProblem,XidError",
 			},
 			args: args{fs: fs},
 		},
@@ -107,15 +218,42 @@ func TestConfig_InitFlags(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			raw := &Config{
-				KubeletPreferredAddressType: tt.fields.KubeletPreferredAddressType,
-				KubeletSyncInterval:         tt.fields.KubeletSyncInterval,
-				KubeletSyncTimeout:          tt.fields.KubeletSyncTimeout,
-				InsecureKubeletTLS:          tt.fields.InsecureKubeletTLS,
-				KubeletReadOnlyPort:         tt.fields.KubeletReadOnlyPort,
-				NodeTopologySyncInterval:    tt.fields.NodeTopologySyncInterval,
-				DisableQueryKubeletConfig:   tt.fields.DisableQueryKubeletConfig,
-				EnableNodeMetricReport:      tt.fields.EnableNodeMetricReport,
-				EnablePodTaskIds:            tt.fields.EnablePodTaskIds,
+				KubeletPreferredAddressType:     tt.fields.KubeletPreferredAddressType,
+				KubeletSyncInterval:             tt.fields.KubeletSyncInterval,
+				KubeletSyncTimeout:              tt.fields.KubeletSyncTimeout,
+				InsecureKubeletTLS:              tt.fields.InsecureKubeletTLS,
+				KubeletReadOnlyPort:             tt.fields.KubeletReadOnlyPort,
+				NodeTopologySyncInterval:        tt.fields.NodeTopologySyncInterval,
+				DisableQueryKubeletConfig:       tt.fields.DisableQueryKubeletConfig,
+				EnableNodeMetricReport:          tt.fields.EnableNodeMetricReport,
+				EnablePodTaskIds:                tt.fields.EnablePodTaskIds,
+				GPUHealthCheckWaitTimeout:       tt.fields.GPUHealthCheckWaitTimeout,
+				GPUCoreGranularity:              tt.fields.GPUCoreGranularity,
+				EnableGPUCodecResources:         tt.fields.EnableGPUCodecResources,
+				GPUTimeSlicingReplicas:          tt.fields.GPUTimeSlicingReplicas,
+				GPUResourceNameMapping:          tt.fields.GPUResourceNameMapping,
+				ExpectedGPUCount:                tt.fields.ExpectedGPUCount,
+				GPUDeviceStabilizationPeriod:    tt.fields.GPUDeviceStabilizationPeriod,
+				DeviceReportTimeRefreshInterval: tt.fields.DeviceReportTimeRefreshInterval,
+				GPUHealthCheckStaleThreshold:    tt.fields.GPUHealthCheckStaleThreshold,
+				DeviceReportMinInterval:         tt.fields.DeviceReportMinInterval,
+				MarkUnmonitoredGPUUnhealthy:     tt.fields.MarkUnmonitoredGPUUnhealthy,
+				EnableGPUUtilizationWindow:      tt.fields.EnableGPUUtilizationWindow,
+				EnableVGPUReport:                tt.fields.EnableVGPUReport,
+				DeviceReportJitterFactor:        tt.fields.DeviceReportJitterFactor,
+				GPUMemoryGranularity:            tt.fields.GPUMemoryGranularity,
+				EnableGPUCoolingCondition:       tt.fields.EnableGPUCoolingCondition,
+				MaxReportedDevices:              tt.fields.MaxReportedDevices,
+				CapReportedDevices:              tt.fields.CapReportedDevices,
+				EnableGPUMemoryCondition:        tt.fields.EnableGPUMemoryCondition,
+				GPURetiredPagesThreshold:        tt.fields.GPURetiredPagesThreshold,
+				DrainGPUOnCoolingDegraded:       tt.fields.DrainGPUOnCoolingDegraded,
+				DrainGPUOnMemoryDegraded:        tt.fields.DrainGPUOnMemoryDegraded,
+				GPUMetricAggregationType:        tt.fields.GPUMetricAggregationType,
+				GPUXidStabilizations:            tt.fields.GPUXidStabilizations,
+				EnableGPUBAR1MemoryResource:     tt.fields.EnableGPUBAR1MemoryResource,
+				DeviceSummaryTenantLabel:        tt.fields.DeviceSummaryTenantLabel,
+				GPUHealthNodeConditions:         tt.fields.GPUHealthNodeConditions,
 			}
 			c := NewDefaultConfig()
 			c.InitFlags(tt.args.fs)
@@ -125,3 +263,26 @@ func TestConfig_InitFlags(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_Validate(t *testing.T) {
+	c := NewDefaultConfig()
+	assert.NoError(t, c.Validate())
+	c.GPUMetricAggregationType = "P95"
+	assert.NoError(t, c.Validate())
+	c.GPUMetricAggregationType = "last"
+	assert.Error(t, c.Validate())
+	c.GPUMetricAggregationType = "median"
+	assert.Error(t, c.Validate())
+
+	c = NewDefaultConfig()
+	c.DeviceSummaryTenantLabel = "example.com/tenant"
+	assert.NoError(t, c.Validate())
+	c.DeviceSummaryTenantLabel = "example.com/tenant/name"
+	assert.Error(t, c.Validate())
+
+	c = NewDefaultConfig()
+	c.GPUXidStabilizations = cliflag.ConfigurationMap{"48": "3/10m", "63": "2/1h"}
+	assert.NoError(t, c.Validate())
+	c.GPUXidStabilizations = cliflag.ConfigurationMap{"48": "3"}
+	assert.Error(t, c.Validate())
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impl
+
+import (
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+// nvmlInterface abstracts the NVML library used by the device reporting and the gpu health check,
+// so that they can be tested without real GPUs.
+type nvmlInterface interface {
+	Init() nvml.Return
+	Shutdown() nvml.Return
+	ErrorString(ret nvml.Return) string
+	SystemGetDriverVersion() (string, nvml.Return)
+	SystemGetCudaDriverVersion() (int, nvml.Return)
+	DeviceGetCount() (int, nvml.Return)
+	DeviceGetHandleByIndex(index int) (nvmlDevice, nvml.Return)
+	DeviceGetHandleByUUID(uuid string) (nvmlDevice, nvml.Return)
+	EventSetCreate() (nvmlEventSet, nvml.Return)
+}
+
+type nvmlDevice interface {
+	GetUUID() (string, nvml.Return)
+	GetName() (string, nvml.Return)
+	GetMinorNumber() (int, nvml.Return)
+	GetMemoryInfo() (nvml.Memory, nvml.Return)
+	GetSerial() (string, nvml.Return)
+	GetInforomVersion(object nvml.InforomObject) (string, nvml.Return)
+	GetActiveVgpus() ([]nvmlVgpuInstance, nvml.Return)
+	GetCurrPcieLinkGeneration() (int, nvml.Return)
+	GetCurrPcieLinkWidth() (int, nvml.Return)
+	GetMaxPcieLinkGeneration() (int, nvml.Return)
+	GetMaxPcieLinkWidth() (int, nvml.Return)
+	// GetP2PStatus returns the status of the peer-to-peer capability with the peer gpu
+	GetP2PStatus(peer nvmlDevice, index nvml.GpuP2PCapsIndex) (nvml.GpuP2PStatus, nvml.Return)
+	RegisterEvents(eventTypes uint64, set nvmlEventSet) nvml.Return
+}
+
+// nvmlVgpuInstance is a vGPU instance running on the physical gpu of a virtualized host.
+type nvmlVgpuInstance interface {
+	GetUUID() (string, nvml.Return)
+	// GetTypeName returns the name of the vGPU profile, e.g. "GRID A100-20C"
+	GetTypeName() (string, nvml.Return)
+	// GetFramebufferSize returns the framebuffer of the vGPU profile in bytes
+	GetFramebufferSize() (uint64, nvml.Return)
+}
+
+type nvmlEventSet interface {
+	Wait(timeoutMs uint32) (nvmlEventData, nvml.Return)
+	Free() nvml.Return
+}
+
+// nvmlEventData is the nvml.EventData whose device is abstracted.
+type nvmlEventData struct {
+	Device            nvmlDevice
+	EventType         uint64
+	EventData         uint64
+	GpuInstanceId     uint32
+	ComputeInstanceId uint32
+}
+
+func newNVMLInterface() nvmlInterface {
+	return &nvmlLib{}
+}
+
+// nvmlLib calls the real NVML library.
+type nvmlLib struct{}
+
+func (l *nvmlLib) Init() nvml.Return {
+	return nvml.Init()
+}
+
+func (l *nvmlLib) Shutdown() nvml.Return {
+	return nvml.Shutdown()
+}
+
+func (l *nvmlLib) ErrorString(ret nvml.Return) string {
+	return nvml.ErrorString(ret)
+}
+
+func (l *nvmlLib) SystemGetDriverVersion() (string, nvml.Return) {
+	return nvml.SystemGetDriverVersion()
+}
+
+func (l *nvmlLib) SystemGetCudaDriverVersion() (int, nvml.Return) {
+	return nvml.SystemGetCudaDriverVersion()
+}
+
+func (l *nvmlLib) DeviceGetCount() (int, nvml.Return) {
+	return nvml.DeviceGetCount()
+}
+
+func (l *nvmlLib) DeviceGetHandleByIndex(index int) (nvmlDevice, nvml.Return) {
+	device, ret := nvml.DeviceGetHandleByIndex(index)
+	return &nvmlLibDevice{device: device}, ret
+}
+
+func (l *nvmlLib) DeviceGetHandleByUUID(uuid string) (nvmlDevice, nvml.Return) {
+	device, ret := nvml.DeviceGetHandleByUUID(uuid)
+	return &nvmlLibDevice{device: device}, ret
+}
+
+func (l *nvmlLib) EventSetCreate() (nvmlEventSet, nvml.Return) {
+	set, ret := nvml.EventSetCreate()
+	return &nvmlLibEventSet{set: set}, ret
+}
+
+type nvmlLibDevice struct {
+	device nvml.Device
+}
+
+func (d *nvmlLibDevice) GetUUID() (string, nvml.Return) {
+	return d.device.GetUUID()
+}
+
+func (d *nvmlLibDevice) GetName() (string, nvml.Return) {
+	return d.device.GetName()
+}
+
+func (d *nvmlLibDevice) GetMinorNumber() (int, nvml.Return) {
+	return d.device.GetMinorNumber()
+}
+
+func (d *nvmlLibDevice) GetMemoryInfo() (nvml.Memory, nvml.Return) {
+	return d.device.GetMemoryInfo()
+}
+
+func (d *nvmlLibDevice) GetSerial() (string, nvml.Return) {
+	return d.device.GetSerial()
+}
+
+func (d *nvmlLibDevice) GetInforomVersion(object nvml.InforomObject) (string, nvml.Return) {
+	return d.device.GetInforomVersion(object)
+}
+
+func (d *nvmlLibDevice) GetActiveVgpus() ([]nvmlVgpuInstance, nvml.Return) {
+	instances, ret := d.device.GetActiveVgpus()
+	if ret != nvml.SUCCESS {
+		return nil, ret
+	}
+	vgpus := make([]nvmlVgpuInstance, 0, len(instances))
+	for _, instance := range instances {
+		vgpus = append(vgpus, &nvmlLibVgpuInstance{instance: instance})
+	}
+	return vgpus, nvml.SUCCESS
+}
+
+func (d *nvmlLibDevice) GetCurrPcieLinkGeneration() (int, nvml.Return) {
+	return d.device.GetCurrPcieLinkGeneration()
+}
+
+func (d *nvmlLibDevice) GetCurrPcieLinkWidth() (int, nvml.Return) {
+	return d.device.GetCurrPcieLinkWidth()
+}
+
+func (d *nvmlLibDevice) GetMaxPcieLinkGeneration() (int, nvml.Return) {
+	return d.device.GetMaxPcieLinkGeneration()
+}
+
+func (d *nvmlLibDevice) GetMaxPcieLinkWidth() (int, nvml.Return) {
+	return d.device.GetMaxPcieLinkWidth()
+}
+
+func (d *nvmlLibDevice) GetP2PStatus(peer nvmlDevice, index nvml.GpuP2PCapsIndex) (nvml.GpuP2PStatus, nvml.Return) {
+	libPeer, ok := peer.(*nvmlLibDevice)
+	if !ok {
+		return nvml.P2P_STATUS_UNKNOWN, nvml.ERROR_INVALID_ARGUMENT
+	}
+	return nvml.DeviceGetP2PStatus(d.device, libPeer.device, index)
+}
+
+func (d *nvmlLibDevice) RegisterEvents(eventTypes uint64, set nvmlEventSet) nvml.Return {
+	libSet, ok := set.(*nvmlLibEventSet)
+	if !ok {
+		return nvml.ERROR_INVALID_ARGUMENT
+	}
+	return nvml.DeviceRegisterEvents(d.device, eventTypes, libSet.set)
+}
+
+type nvmlLibVgpuInstance struct {
+	instance nvml.VgpuInstance
+}
+
+func (v *nvmlLibVgpuInstance) GetUUID() (string, nvml.Return) {
+	return v.instance.GetUUID()
+}
+
+func (v *nvmlLibVgpuInstance) GetTypeName() (string, nvml.Return) {
+	vgpuType, ret := v.instance.GetType()
+	if ret != nvml.SUCCESS {
+		return "", ret
+	}
+	return vgpuType.GetName()
+}
+
+func (v *nvmlLibVgpuInstance) GetFramebufferSize() (uint64, nvml.Return) {
+	vgpuType, ret := v.instance.GetType()
+	if ret != nvml.SUCCESS {
+		return 0, ret
+	}
+	return vgpuType.GetFramebufferSize()
+}
+
+type nvmlLibEventSet struct {
+	set nvml.EventSet
+}
+
+func (s *nvmlLibEventSet) Wait(timeoutMs uint32) (nvmlEventData, nvml.Return) {
+	e, ret := s.set.Wait(timeoutMs)
+	return newNVMLEventData(e), ret
+}
+
+// newNVMLEventData abstracts the device of the nvml event, which is nil if the event has no device handle, e.g. the
+// xid errors not attributed to a gpu, or the events returned on the timeout.
+func newNVMLEventData(e nvml.EventData) nvmlEventData {
+	data := nvmlEventData{
+		EventType:         e.EventType,
+		EventData:         e.EventData,
+		GpuInstanceId:     e.GpuInstanceId,
+		ComputeInstanceId: e.ComputeInstanceId,
+	}
+	if e.Device.Handle != nil {
+		data.Device = &nvmlLibDevice{device: e.Device}
+	}
+	return data
+}
+
+func (s *nvmlLibEventSet) Free() nvml.Return {
+	return s.set.Free()
+}
@@ -0,0 +1,299 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impl
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+var _ nvmlInterface = &fakeNVML{}
+
+// fakeNVML simulates the NVML library with the given devices, and the xid events sent to the events channel.
+type fakeNVML struct {
+	initRet       nvml.Return
+	driverVersion string
+	// shutdownCalls counts the calls to shut down nvml
+	shutdownCalls atomic.Int32
+	// cudaDriverVersion is the CUDA version of the driver as returned by nvml, e.g. 12020 for 12.2
+	cudaDriverVersion int
+	// cudaDriverVersionCalls counts the calls to get the CUDA driver version
+	cudaDriverVersionCalls atomic.Int32
+	devices                []*fakeNVMLDevice
+	events                 chan nvmlEventData
+	// countRet is returned when getting the device count, e.g. nvml.ERROR_GPU_IS_LOST when the driver crashes
+	countRet nvml.Return
+	// countCalls counts the calls to get the device count
+	countCalls atomic.Int32
+	// eventSetCreated counts the event sets created, i.e. the runs of the health check
+	eventSetCreated atomic.Int32
+	// eventSetFreed counts the event sets freed
+	eventSetFreed atomic.Int32
+	// waitHook is called before each wait of the event set with its creation order, e.g. to simulate a panic
+	waitHook func(eventSetIndex int32)
+	// handleLatency simulates the latency of getting a device handle, e.g. initializing a gpu without persistence mode
+	handleLatency time.Duration
+	// handleByUUIDCalls counts the calls to get a device handle by the uuid
+	handleByUUIDCalls atomic.Int32
+}
+
+func newFakeNVML(driverVersion string, devices ...*fakeNVMLDevice) *fakeNVML {
+	return &fakeNVML{
+		initRet:       nvml.SUCCESS,
+		driverVersion: driverVersion,
+		devices:       devices,
+		events:        make(chan nvmlEventData, 10),
+	}
+}
+
+// sendXid injects a xid critical error event of the device. An empty uuid injects the event without device handle
+// as nvml does for the errors not attributed to a gpu.
+func (f *fakeNVML) sendXid(uuid string, xid uint64) {
+	if uuid == "" {
+		f.sendEvent(newNVMLEventData(nvml.EventData{EventType: nvml.EventTypeXidCriticalError, EventData: xid}))
+		return
+	}
+	f.sendEvent(nvmlEventData{
+		Device:    &fakeNVMLDevice{uuid: uuid},
+		EventType: nvml.EventTypeXidCriticalError,
+		EventData: xid,
+	})
+}
+
+// sendEvent injects the event as is, e.g. an event of other types or without device.
+func (f *fakeNVML) sendEvent(e nvmlEventData) {
+	f.events <- e
+}
+
+func (f *fakeNVML) Init() nvml.Return {
+	return f.initRet
+}
+
+func (f *fakeNVML) Shutdown() nvml.Return {
+	f.shutdownCalls.Add(1)
+	return nvml.SUCCESS
+}
+
+func (f *fakeNVML) ErrorString(ret nvml.Return) string {
+	return fmt.Sprintf("nvml return %d", ret)
+}
+
+func (f *fakeNVML) SystemGetDriverVersion() (string, nvml.Return) {
+	return f.driverVersion, nvml.SUCCESS
+}
+
+func (f *fakeNVML) SystemGetCudaDriverVersion() (int, nvml.Return) {
+	f.cudaDriverVersionCalls.Add(1)
+	if f.cudaDriverVersion == 0 {
+		return 0, nvml.ERROR_NOT_SUPPORTED
+	}
+	return f.cudaDriverVersion, nvml.SUCCESS
+}
+
+func (f *fakeNVML) DeviceGetCount() (int, nvml.Return) {
+	f.countCalls.Add(1)
+	if f.countRet != nvml.SUCCESS {
+		return 0, f.countRet
+	}
+	return len(f.devices), nvml.SUCCESS
+}
+
+func (f *fakeNVML) DeviceGetHandleByIndex(index int) (nvmlDevice, nvml.Return) {
+	time.Sleep(f.handleLatency)
+	if index < 0 || index >= len(f.devices) {
+		return nil, nvml.ERROR_INVALID_ARGUMENT
+	}
+	if f.devices[index].handleRet != nvml.SUCCESS {
+		return nil, f.devices[index].handleRet
+	}
+	return f.devices[index], nvml.SUCCESS
+}
+
+func (f *fakeNVML) DeviceGetHandleByUUID(uuid string) (nvmlDevice, nvml.Return) {
+	f.handleByUUIDCalls.Add(1)
+	time.Sleep(f.handleLatency)
+	for _, d := range f.devices {
+		if d.uuid == uuid {
+			if d.lost {
+				return nil, nvml.ERROR_GPU_IS_LOST
+			}
+			return d, nvml.SUCCESS
+		}
+	}
+	return nil, nvml.ERROR_NOT_FOUND
+}
+
+func (f *fakeNVML) EventSetCreate() (nvmlEventSet, nvml.Return) {
+	index := f.eventSetCreated.Add(1)
+	return &fakeNVMLEventSet{events: f.events, index: index, waitHook: f.waitHook, freed: &f.eventSetFreed}, nvml.SUCCESS
+}
+
+type fakeNVMLDevice struct {
+	uuid        string
+	name        string
+	minor       int
+	memoryTotal uint64
+	// serial and inforomVersion are not supported if empty
+	serial         string
+	inforomVersion string
+	// vgpus are the active vGPU instances, nil means the host is not virtualized
+	vgpus []*fakeNVMLVgpuInstance
+	// registerRet is returned when registering events, e.g. nvml.ERROR_NOT_SUPPORTED for the old devices
+	registerRet nvml.Return
+	// lost means the device cannot be queried by uuid, e.g. fallen off the bus
+	lost bool
+	// handleRet is returned when getting the device handle by index, e.g. nvml.ERROR_UNKNOWN
+	handleRet nvml.Return
+	// minorRet is returned when getting the minor number, e.g. nvml.ERROR_UNKNOWN
+	minorRet nvml.Return
+	// p2pPeers are the uuids of the gpus which the device can access directly
+	p2pPeers []string
+	// p2pCalls counts the calls to get the p2p status
+	p2pCalls atomic.Int32
+	// pcieLink is the pcie link of the device, where the zero fields are not supported
+	pcieLink gpuPCIeLink
+}
+
+func (d *fakeNVMLDevice) GetUUID() (string, nvml.Return) {
+	return d.uuid, nvml.SUCCESS
+}
+
+func (d *fakeNVMLDevice) GetName() (string, nvml.Return) {
+	return d.name, nvml.SUCCESS
+}
+
+func (d *fakeNVMLDevice) GetMinorNumber() (int, nvml.Return) {
+	if d.minorRet != nvml.SUCCESS {
+		return 0, d.minorRet
+	}
+	return d.minor, nvml.SUCCESS
+}
+
+func (d *fakeNVMLDevice) GetMemoryInfo() (nvml.Memory, nvml.Return) {
+	return nvml.Memory{Total: d.memoryTotal}, nvml.SUCCESS
+}
+
+func (d *fakeNVMLDevice) GetSerial() (string, nvml.Return) {
+	if d.serial == "" {
+		return "", nvml.ERROR_NOT_SUPPORTED
+	}
+	return d.serial, nvml.SUCCESS
+}
+
+func (d *fakeNVMLDevice) GetInforomVersion(object nvml.InforomObject) (string, nvml.Return) {
+	if d.inforomVersion == "" || object != nvml.INFOROM_OEM {
+		return "", nvml.ERROR_NOT_SUPPORTED
+	}
+	return d.inforomVersion, nvml.SUCCESS
+}
+
+func (d *fakeNVMLDevice) GetActiveVgpus() ([]nvmlVgpuInstance, nvml.Return) {
+	if d.vgpus == nil {
+		return nil, nvml.ERROR_NOT_SUPPORTED
+	}
+	vgpus := make([]nvmlVgpuInstance, 0, len(d.vgpus))
+	for _, v := range d.vgpus {
+		vgpus = append(vgpus, v)
+	}
+	return vgpus, nvml.SUCCESS
+}
+
+type fakeNVMLVgpuInstance struct {
+	uuid            string
+	typeName        string
+	framebufferSize uint64
+}
+
+func (v *fakeNVMLVgpuInstance) GetUUID() (string, nvml.Return) {
+	return v.uuid, nvml.SUCCESS
+}
+
+func (v *fakeNVMLVgpuInstance) GetTypeName() (string, nvml.Return) {
+	return v.typeName, nvml.SUCCESS
+}
+
+func (v *fakeNVMLVgpuInstance) GetFramebufferSize() (uint64, nvml.Return) {
+	return v.framebufferSize, nvml.SUCCESS
+}
+
+func (d *fakeNVMLDevice) GetCurrPcieLinkGeneration() (int, nvml.Return) {
+	return fakePCIeLinkValue(d.pcieLink.Generation)
+}
+
+func (d *fakeNVMLDevice) GetCurrPcieLinkWidth() (int, nvml.Return) {
+	return fakePCIeLinkValue(d.pcieLink.Width)
+}
+
+func (d *fakeNVMLDevice) GetMaxPcieLinkGeneration() (int, nvml.Return) {
+	return fakePCIeLinkValue(d.pcieLink.MaxGeneration)
+}
+
+func (d *fakeNVMLDevice) GetMaxPcieLinkWidth() (int, nvml.Return) {
+	return fakePCIeLinkValue(d.pcieLink.MaxWidth)
+}
+
+func fakePCIeLinkValue(value int) (int, nvml.Return) {
+	if value == 0 {
+		return 0, nvml.ERROR_NOT_SUPPORTED
+	}
+	return value, nvml.SUCCESS
+}
+
+func (d *fakeNVMLDevice) GetP2PStatus(peer nvmlDevice, index nvml.GpuP2PCapsIndex) (nvml.GpuP2PStatus, nvml.Return) {
+	d.p2pCalls.Add(1)
+	fakePeer, ok := peer.(*fakeNVMLDevice)
+	if !ok {
+		return nvml.P2P_STATUS_UNKNOWN, nvml.ERROR_INVALID_ARGUMENT
+	}
+	for _, uuid := range d.p2pPeers {
+		if uuid == fakePeer.uuid {
+			return nvml.P2P_STATUS_OK, nvml.SUCCESS
+		}
+	}
+	return nvml.P2P_STATUS_NOT_SUPPORTED, nvml.SUCCESS
+}
+
+func (d *fakeNVMLDevice) RegisterEvents(eventTypes uint64, set nvmlEventSet) nvml.Return {
+	return d.registerRet
+}
+
+type fakeNVMLEventSet struct {
+	events   chan nvmlEventData
+	index    int32
+	waitHook func(eventSetIndex int32)
+	freed    *atomic.Int32
+}
+
+func (s *fakeNVMLEventSet) Wait(timeoutMs uint32) (nvmlEventData, nvml.Return) {
+	if s.waitHook != nil {
+		s.waitHook(s.index)
+	}
+	select {
+	case e := <-s.events:
+		return e, nvml.SUCCESS
+	case <-time.After(time.Duration(timeoutMs) * time.Millisecond):
+		return nvmlEventData{}, nvml.ERROR_TIMEOUT
+	}
+}
+
+func (s *fakeNVMLEventSet) Free() nvml.Return {
+	s.freed.Add(1)
+	return nvml.SUCCESS
+}
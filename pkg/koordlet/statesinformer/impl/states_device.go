@@ -0,0 +1,324 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impl
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	schedulingv1alpha1 "github.com/koordinator-sh/koordinator/apis/scheduling/v1alpha1"
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/metrics"
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/statesinformer"
+	koordletutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util"
+	"github.com/koordinator-sh/koordinator/pkg/util"
+)
+
+const (
+	// deviceReportKey is the only key of the device queue, since a node has only one Device.
+	deviceReportKey = "device"
+	// gpuDevicesCheckInterval is the interval to check the gpus updated in the metric cache, which has no notification.
+	gpuDevicesCheckInterval = time.Second
+)
+
+// runDeviceReporter reports the Device in a rate-limited loop, which is triggered by the gpu health changes,
+// the gpu updates in the metric cache, the node metadata changes and the periodic resyncs.
+func (s *statesInformer) runDeviceReporter(stopCh <-chan struct{}) {
+	defer s.deviceQueue.ShutDown()
+
+	// the periodic resyncs are jittered to spread the reportings of the nodes, while the triggers of the changes are
+	// only coalesced by the min interval
+	go wait.JitterUntil(s.enqueueDevice, s.config.NodeTopologySyncInterval, s.config.DeviceReportJitterFactor, true, stopCh)
+	go wait.Until(s.checkGPUDevicesUpdate, gpuDevicesCheckInterval, stopCh)
+	go wait.Until(func() {
+		for s.processNextDevice() {
+		}
+	}, time.Second, stopCh)
+
+	klog.V(4).InfoS("Start to report Device")
+	<-stopCh
+}
+
+// registerDeviceCallbacks enqueues the Device reporting when the node metadata changes, e.g. the resync annotation,
+// and when the pods allocated with gpus are added or removed. It must be called before the callback runner starts.
+func (s *statesInformer) registerDeviceCallbacks() {
+	s.states.callbackRunner.RegisterCallbacks(statesinformer.RegisterTypeNodeMetadata, "device-reporter",
+		"Report Device when the node metadata changes",
+		func(t statesinformer.RegisterType, obj interface{}, target *statesinformer.CallbackTarget) {
+			s.enqueueDevice()
+		})
+	s.states.callbackRunner.RegisterCallbacks(statesinformer.RegisterTypeAllPods, "device-allocation-reporter",
+		"Report Device when the pods allocated with gpus are added or removed",
+		func(t statesinformer.RegisterType, obj interface{}, target *statesinformer.CallbackTarget) {
+			if target == nil {
+				return
+			}
+			s.checkGPUAllocatedPodsUpdate(target.Pods)
+		})
+}
+
+// enqueueDevice triggers the Device reporting, the pending triggers are merged into one.
+func (s *statesInformer) enqueueDevice() {
+	if s.deviceQueue == nil {
+		return
+	}
+	s.deviceQueue.Add(deviceReportKey)
+}
+
+func (s *statesInformer) processNextDevice() bool {
+	key, quit := s.deviceQueue.Get()
+	if quit {
+		return false
+	}
+	defer s.deviceQueue.Done(key)
+
+	// the triggers within the min interval are coalesced into one reporting after the interval, so that the flapping
+	// changes do not update the Device in a hot loop, and the latest state is still reported
+	if wait := s.getDeviceReportWait(); wait > 0 {
+		klog.V(5).InfoS("Device is reported recently, delay the reporting", "wait", wait)
+		s.deviceQueue.AddAfter(key, wait)
+		return true
+	}
+	s.lastDeviceReportTime = time.Now()
+
+	// the reporting is skipped without retries until the CRD is installed, which is re-checked by the periodic resyncs
+	if !s.isDeviceCRDInstalled() {
+		s.deviceQueue.Forget(key)
+		return true
+	}
+	start := time.Now()
+	err := s.reportDevice()
+	metrics.RecordDeviceReportDuration(err, metrics.SinceInSeconds(start))
+	if err != nil {
+		klog.V(4).InfoS("Failed to report Device, retry later", "err", err, "retries", s.deviceQueue.NumRequeues(key))
+		s.deviceQueue.AddRateLimited(key)
+		return true
+	}
+	s.deviceQueue.Forget(key)
+	return true
+}
+
+// deviceCRDRecheckInterval is the min interval to re-check whether the Device CRD is installed.
+var deviceCRDRecheckInterval = 5 * time.Minute
+
+// isDeviceCRDInstalled returns whether the Device CRD is served by the apiserver, which is checked by the discovery
+// at most once per deviceCRDRecheckInterval. A missing CRD is warned once instead of failing every reporting, and
+// the CRD is regarded as installed if the discovery fails, e.g. the apiserver is temporarily unavailable.
+func (s *statesInformer) isDeviceCRDInstalled() bool {
+	if s.option == nil || s.option.KubeClient == nil {
+		return true
+	}
+	if !s.deviceCRDCheckTime.IsZero() && time.Since(s.deviceCRDCheckTime) < deviceCRDRecheckInterval {
+		return !s.deviceCRDMissing
+	}
+	missing, err := isDeviceCRDMissing(s.option.KubeClient.Discovery())
+	if err != nil {
+		klog.V(4).InfoS("Failed to check whether the Device CRD is installed, report Device anyway", "err", err)
+		return true
+	}
+	s.deviceCRDCheckTime = time.Now()
+	if missing && !s.deviceCRDMissing {
+		klog.Warningf("Device CRD %s is not installed, disable reporting Device and re-check every %v",
+			schedulingv1alpha1.SchemeGroupVersion.WithResource("devices").GroupResource(), deviceCRDRecheckInterval)
+	} else if !missing && s.deviceCRDMissing {
+		klog.Infof("Device CRD %s is installed, resume reporting Device",
+			schedulingv1alpha1.SchemeGroupVersion.WithResource("devices").GroupResource())
+	}
+	s.deviceCRDMissing = missing
+	return !missing
+}
+
+// isDeviceCRDMissing returns whether the resource devices is not served in the group version of the Device.
+func isDeviceCRDMissing(client discovery.DiscoveryInterface) (bool, error) {
+	resources, err := client.ServerResourcesForGroupVersion(schedulingv1alpha1.SchemeGroupVersion.String())
+	if errors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, r := range resources.APIResources {
+		if r.Name == "devices" {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// getDeviceReportWait returns the duration to wait before the next reporting to keep the min interval.
+func (s *statesInformer) getDeviceReportWait() time.Duration {
+	if s.config == nil || s.config.DeviceReportMinInterval <= 0 || s.lastDeviceReportTime.IsZero() {
+		return 0
+	}
+	return s.config.DeviceReportMinInterval - time.Since(s.lastDeviceReportTime)
+}
+
+// checkGPUDevicesUpdate triggers the Device reporting once the gpus collected in the metric cache are updated.
+func (s *statesInformer) checkGPUDevicesUpdate() {
+	gpus, _ := s.metricsCache.Get(koordletutil.GPUDeviceType)
+	if reflect.DeepEqual(gpus, s.lastGPUDevices) {
+		return
+	}
+	s.lastGPUDevices = gpus
+	klog.V(5).InfoS("Gpu devices are updated in the metric cache, enqueue Device")
+	s.enqueueDevice()
+}
+
+// checkGPUAllocatedPodsUpdate triggers the Device reporting once the pods allocated with gpus are added or removed,
+// so that the gpu resource status is updated. The pods are synced periodically, so the unchanged pods are ignored.
+func (s *statesInformer) checkGPUAllocatedPodsUpdate(pods []*statesinformer.PodMeta) {
+	key := getGPUAllocatedPodsKey(pods)
+	if key == s.lastGPUAllocatedPodsKey {
+		return
+	}
+	s.lastGPUAllocatedPodsKey = key
+	klog.V(5).InfoS("Pods allocated with gpus are updated, enqueue Device")
+	s.enqueueDevice()
+}
+
+// getGPUAllocatedPodsKey returns the sorted uids of the pods not terminated which are allocated with gpus.
+func getGPUAllocatedPodsKey(pods []*statesinformer.PodMeta) string {
+	var uids []string
+	for _, podMeta := range pods {
+		if podMeta == nil || podMeta.Pod == nil || util.IsPodTerminated(podMeta.Pod) {
+			continue
+		}
+		if _, ok := podMeta.Pod.Annotations[extension.AnnotationDeviceAllocated]; ok {
+			uids = append(uids, string(podMeta.Pod.UID))
+		}
+	}
+	sort.Strings(uids)
+	return strings.Join(uids, ",")
+}
+
+// buildGPUResourceStatus returns the allocated and free resources of the gpus, which are summed from the gpu
+// allocations of the pods not terminated. The allocations are matched to the gpus by the minor, and the ones of
+// the gpus not reported are ignored.
+func buildGPUResourceStatus(devices []schedulingv1alpha1.DeviceInfo, pods []*statesinformer.PodMeta) []schedulingv1alpha1.DeviceResourceStatus {
+	allocatedByMinor := map[int32]corev1.ResourceList{}
+	for _, podMeta := range pods {
+		if podMeta == nil || podMeta.Pod == nil || util.IsPodTerminated(podMeta.Pod) {
+			continue
+		}
+		allocations, err := extension.GetDeviceAllocations(podMeta.Pod.Annotations)
+		if err != nil {
+			klog.V(4).InfoS("Failed to parse the device allocations of pod, ignore it in the gpu resource status",
+				"pod", klog.KObj(podMeta.Pod), "err", err)
+			continue
+		}
+		for _, allocation := range allocations[schedulingv1alpha1.GPU] {
+			if allocation == nil {
+				continue
+			}
+			allocatedByMinor[allocation.Minor] = quotav1.Add(allocatedByMinor[allocation.Minor], allocation.Resources)
+		}
+	}
+
+	var statuses []schedulingv1alpha1.DeviceResourceStatus
+	for i := range devices {
+		d := &devices[i]
+		if d.Type != schedulingv1alpha1.GPU || d.Minor == nil {
+			continue
+		}
+		allocated := corev1.ResourceList{}
+		for name := range d.Resources {
+			allocated[name] = allocatedByMinor[*d.Minor][name].DeepCopy()
+		}
+		statuses = append(statuses, schedulingv1alpha1.DeviceResourceStatus{
+			Type:      d.Type,
+			UUID:      d.UUID,
+			Minor:     pointer.Int32(*d.Minor),
+			Allocated: allocated,
+			Free:      quotav1.SubtractWithNonNegativeResult(d.Resources, allocated),
+		})
+	}
+	// sorted to be compared with the reported ones regardless of the order of the gpus collected
+	sort.SliceStable(statuses, func(i, j int) bool {
+		if *statuses[i].Minor != *statuses[j].Minor {
+			return *statuses[i].Minor < *statuses[j].Minor
+		}
+		return statuses[i].UUID < statuses[j].UUID
+	})
+	return statuses
+}
+
+// IsGPUHealthy returns whether the gpu of the uuid is not known unhealthy by the health check.
+func (s *statesInformer) IsGPUHealthy(uuid string) bool {
+	s.gpuMutex.RLock()
+	defer s.gpuMutex.RUnlock()
+	_, unhealthy := s.unhealthyGPU[uuid]
+	return !unhealthy
+}
+
+// ListUnhealthyGPUs returns the sorted uuids of the gpus known unhealthy by the health check.
+func (s *statesInformer) ListUnhealthyGPUs() []string {
+	s.gpuMutex.RLock()
+	defer s.gpuMutex.RUnlock()
+	uuids := make([]string, 0, len(s.unhealthyGPU))
+	for uuid := range s.unhealthyGPU {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+	return uuids
+}
+
+type gpuHealthObserver struct {
+	name string
+	fn   statesinformer.GPUHealthObserverFn
+}
+
+// RegisterGPUHealthObserver registers an observer called on each health transition of the gpus.
+func (s *statesInformer) RegisterGPUHealthObserver(name string, observerFn statesinformer.GPUHealthObserverFn) {
+	s.gpuMutex.Lock()
+	defer s.gpuMutex.Unlock()
+	for _, o := range s.gpuHealthObservers {
+		if o.name == name {
+			klog.Fatalf("gpu health observer %s already registered", name)
+		}
+	}
+	s.gpuHealthObservers = append(s.gpuHealthObservers, gpuHealthObserver{name: name, fn: observerFn})
+	klog.V(1).Infof("gpu health observer %s has registered", name)
+}
+
+// notifyGPUHealthTransitions calls the observers on the transitions in order, it must be called without holding the
+// gpuMutex.
+func (s *statesInformer) notifyGPUHealthTransitions(transitions []statesinformer.GPUHealthTransition) {
+	if len(transitions) == 0 {
+		return
+	}
+	// the observers are only appended, so the registered ones are safe to call without the lock
+	s.gpuMutex.RLock()
+	observers := s.gpuHealthObservers
+	s.gpuMutex.RUnlock()
+	for _, t := range transitions {
+		for _, o := range observers {
+			klog.V(5).InfoS("Notify the gpu health transition", "observer", o.name, "deviceUUID", t.DeviceUUID,
+				"oldHealthy", t.OldHealthy, "newHealthy", t.NewHealthy, "reason", t.Reason, "xid", t.Xid)
+			o.fn(t)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impl
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	schedulingv1alpha1 "github.com/koordinator-sh/koordinator/apis/scheduling/v1alpha1"
+)
+
+// DeviceDebugHTTPPath is the path of the koordlet server to get the gpu devices last built for the Device.
+const DeviceDebugHTTPPath = "/debug/device"
+
+// GPUDeviceSnapshot is the gpu devices last built for the Device, which helps to diff what koordlet computes against
+// the Device in the apiserver.
+type GPUDeviceSnapshot struct {
+	// BuildTime is the time when the gpu devices are built, nil if they are never built
+	BuildTime *time.Time `json:"buildTime,omitempty"`
+	// BuildError is the error of the last build, the gpu devices are kept from the last successful build if failed
+	BuildError string `json:"buildError,omitempty"`
+	// GPUDevices are the gpu devices of the last successful build
+	GPUDevices []schedulingv1alpha1.DeviceInfo `json:"gpuDevices,omitempty"`
+	// UnhealthyGPUs are the uuids of the gpus known unhealthy by the health check at the last successful build
+	UnhealthyGPUs []string `json:"unhealthyGPUs,omitempty"`
+}
+
+// deviceDebugger keeps the snapshot of the gpu devices last built.
+type deviceDebugger struct {
+	lock     sync.RWMutex
+	snapshot GPUDeviceSnapshot
+}
+
+var defaultDeviceDebugger = &deviceDebugger{}
+
+// record records the result of building the gpu devices.
+func (d *deviceDebugger) record(gpuDevices []schedulingv1alpha1.DeviceInfo, unhealthyGPUs []string, buildErr error, now time.Time) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.snapshot.BuildTime = &now
+	if buildErr != nil {
+		d.snapshot.BuildError = buildErr.Error()
+		return
+	}
+	d.snapshot.BuildError = ""
+	d.snapshot.GPUDevices = make([]schedulingv1alpha1.DeviceInfo, len(gpuDevices))
+	for i := range gpuDevices {
+		gpuDevices[i].DeepCopyInto(&d.snapshot.GPUDevices[i])
+	}
+	d.snapshot.UnhealthyGPUs = append([]string{}, unhealthyGPUs...)
+	sort.Strings(d.snapshot.UnhealthyGPUs)
+}
+
+func (d *deviceDebugger) getSnapshot() GPUDeviceSnapshot {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return d.snapshot
+}
+
+func (d *deviceDebugger) HttpHandler() func(http.ResponseWriter, *http.Request) {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		data, err := json.Marshal(d.getSnapshot())
+		if err != nil {
+			klog.Errorf("failed to marshal the gpu device snapshot, err: %v", err)
+			http.Error(rw, "internal error", http.StatusInternalServerError)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		_, _ = rw.Write(data)
+	}
+}
+
+// DeviceDebugHttpHandler return the http handler to read the gpu devices last built for the Device.
+func DeviceDebugHttpHandler() func(http.ResponseWriter, *http.Request) {
+	return defaultDeviceDebugger.HttpHandler()
+}
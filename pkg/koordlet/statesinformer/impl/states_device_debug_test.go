@@ -0,0 +1,75 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impl
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/pointer"
+
+	schedulingv1alpha1 "github.com/koordinator-sh/koordinator/apis/scheduling/v1alpha1"
+)
+
+func Test_deviceDebugger(t *testing.T) {
+	d := &deviceDebugger{}
+	handler := d.HttpHandler()
+	getSnapshot := func() GPUDeviceSnapshot {
+		rw := httptest.NewRecorder()
+		handler(rw, httptest.NewRequest(http.MethodGet, DeviceDebugHTTPPath, nil))
+		assert.Equal(t, http.StatusOK, rw.Code)
+		var snapshot GPUDeviceSnapshot
+		assert.NoError(t, json.Unmarshal(rw.Body.Bytes(), &snapshot))
+		return snapshot
+	}
+
+	// never built
+	assert.Equal(t, GPUDeviceSnapshot{}, getSnapshot())
+
+	buildTime := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	gpuDevices := []schedulingv1alpha1.DeviceInfo{
+		{UUID: "GPU-0", Minor: pointer.Int32(0), Type: schedulingv1alpha1.GPU, Health: true},
+		{UUID: "GPU-1", Minor: pointer.Int32(1), Type: schedulingv1alpha1.GPU, Health: false},
+	}
+	d.record(gpuDevices, []string{"GPU-1"}, nil, buildTime)
+	// the snapshot is not changed with the devices built
+	gpuDevices[0].Health = false
+	snapshot := getSnapshot()
+	assert.True(t, buildTime.Equal(*snapshot.BuildTime))
+	assert.Empty(t, snapshot.BuildError)
+	assert.Equal(t, []string{"GPU-1"}, snapshot.UnhealthyGPUs)
+	assert.Equal(t, 2, len(snapshot.GPUDevices))
+	assert.True(t, snapshot.GPUDevices[0].Health)
+
+	// the devices of the last successful build are kept if the build fails
+	failTime := buildTime.Add(time.Minute)
+	d.record(nil, []string{"GPU-0", "GPU-1"}, fmt.Errorf("GPU subsystem degraded"), failTime)
+	snapshot = getSnapshot()
+	assert.True(t, failTime.Equal(*snapshot.BuildTime))
+	assert.Equal(t, "GPU subsystem degraded", snapshot.BuildError)
+	assert.Equal(t, []string{"GPU-1"}, snapshot.UnhealthyGPUs)
+	assert.Equal(t, 2, len(snapshot.GPUDevices))
+
+	rw := httptest.NewRecorder()
+	handler(rw, httptest.NewRequest(http.MethodPost, DeviceDebugHTTPPath, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rw.Code)
+}
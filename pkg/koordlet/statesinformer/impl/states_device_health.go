@@ -0,0 +1,247 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// GPUHealthDecision is the decision of a XidHealthPolicy on the health of the gpus.
+type GPUHealthDecision int
+
+const (
+	// GPUHealthy means the gpus are still healthy, e.g. the xid is caused by the application.
+	GPUHealthy GPUHealthDecision = iota
+	// GPUDegraded means the gpu of the event, or all gpus if the event is not attributed to a gpu, may be broken.
+	// The gpus are marked unhealthy only if they cannot be queried anymore.
+	GPUDegraded
+	// GPUFailed means the gpu of the event, or all gpus if the event is not attributed to a gpu, is unhealthy.
+	GPUFailed
+	// GPUNodeFailed means all gpus of the node are unhealthy, e.g. the xid indicates a failure of the NVSwitch.
+	GPUNodeFailed
+)
+
+func (d GPUHealthDecision) String() string {
+	switch d {
+	case GPUHealthy:
+		return "Healthy"
+	case GPUDegraded:
+		return "Degraded"
+	case GPUFailed:
+		return "Failed"
+	case GPUNodeFailed:
+		return "NodeFailed"
+	default:
+		return "Unknown"
+	}
+}
+
+// XidEvent is a xid critical error event received by the gpu health check.
+type XidEvent struct {
+	// Xid is the code of the xid error.
+	Xid uint64
+	// DeviceUUID is the uuid of the gpu which the event is attributed to, or empty if not attributed.
+	DeviceUUID string
+	// Timestamp is the time when the event is received.
+	Timestamp time.Time
+}
+
+// XidHealthPolicy decides the health of the gpus on a xid event.
+type XidHealthPolicy func(event XidEvent) GPUHealthDecision
+
+// GPUXidHealthPolicy is the XidHealthPolicy of the gpu health check, which can be replaced before the statesinformer
+// is created to classify the xid errors differently.
+var GPUXidHealthPolicy XidHealthPolicy = DefaultXidHealthPolicy
+
+// applicationXids are the xid errors caused by the applications, where the gpu should still be healthy.
+// http://docs.nvidia.com/deploy/xid-errors/index.html#topic_4
+var applicationXids = map[uint64]struct{}{
+	13: {},
+	31: {},
+	43: {},
+	45: {},
+	68: {},
+}
+
+// DefaultXidHealthPolicy ignores the application xid errors, and fails the gpu of any other xid error. The event
+// not attributed to a gpu may be a transient glitch, so all gpus are degraded instead of failed.
+func DefaultXidHealthPolicy(event XidEvent) GPUHealthDecision {
+	if _, ok := applicationXids[event.Xid]; ok {
+		return GPUHealthy
+	}
+	if event.DeviceUUID == "" {
+		return GPUDegraded
+	}
+	return GPUFailed
+}
+
+// xidStabilization requires a xid error to occur count times within the window before the gpus are marked unhealthy,
+// so that a one-off event of the xids sometimes recoverable does not remove the healthy gpus.
+type xidStabilization struct {
+	count  int
+	window time.Duration
+}
+
+// parseXidStabilizations parses the stabilizations keyed by the xid code in the format of count/window, e.g.
+// 48=3/10m means the xid 48 must occur 3 times within 10 minutes.
+func parseXidStabilizations(m map[string]string) (map[uint64]xidStabilization, error) {
+	stabilizations := map[uint64]xidStabilization{}
+	for key, value := range m {
+		xid, err := strconv.ParseUint(strings.TrimSpace(key), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid xid %q, %w", key, err)
+		}
+		countStr, windowStr, ok := strings.Cut(value, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid stabilization %q of xid %d, expect count/window", value, xid)
+		}
+		count, err := strconv.Atoi(strings.TrimSpace(countStr))
+		if err != nil || count <= 0 {
+			return nil, fmt.Errorf("invalid count %q of xid %d, expect a positive integer", countStr, xid)
+		}
+		window, err := time.ParseDuration(strings.TrimSpace(windowStr))
+		if err != nil || window <= 0 {
+			return nil, fmt.Errorf("invalid window %q of xid %d, expect a positive duration", windowStr, xid)
+		}
+		stabilizations[xid] = xidStabilization{count: count, window: window}
+	}
+	return stabilizations, nil
+}
+
+// newXidHealthPolicy returns the GPUXidHealthPolicy stabilized by the configured xid stabilizations.
+func newXidHealthPolicy(config *Config) XidHealthPolicy {
+	if config == nil {
+		return GPUXidHealthPolicy
+	}
+	stabilizations, err := parseXidStabilizations(config.GPUXidStabilizations)
+	if err != nil {
+		// unreachable since the config is validated at the start
+		klog.ErrorS(err, "Failed to parse the xid stabilizations, mark the gpus unhealthy on the first xid errors")
+		return GPUXidHealthPolicy
+	}
+	return stabilizeXidHealthPolicy(GPUXidHealthPolicy, stabilizations)
+}
+
+type xidOccurrenceKey struct {
+	deviceUUID string
+	xid        uint64
+}
+
+// stabilizeXidHealthPolicy returns the policy which defers the unhealthy decisions of the policy on the xids with
+// the stabilizations, until the xid of the same gpu occurs the count times within the window. The deferred events
+// are decided GPUHealthy. The occurrences are reset once the decision is committed, so a gpu recovered later needs
+// the xid to occur the count times again. The xids without the stabilizations are decided by the policy as is.
+func stabilizeXidHealthPolicy(policy XidHealthPolicy, stabilizations map[uint64]xidStabilization) XidHealthPolicy {
+	if len(stabilizations) == 0 {
+		return policy
+	}
+	var lock sync.Mutex
+	occurrences := map[xidOccurrenceKey][]time.Time{}
+	return func(event XidEvent) GPUHealthDecision {
+		decision := policy(event)
+		stabilization, ok := stabilizations[event.Xid]
+		if decision == GPUHealthy || !ok {
+			return decision
+		}
+
+		// the policy may be called by a health check being restarted, so the occurrences are locked
+		lock.Lock()
+		defer lock.Unlock()
+		key := xidOccurrenceKey{deviceUUID: event.DeviceUUID, xid: event.Xid}
+		recent := occurrences[key][:0]
+		for _, t := range occurrences[key] {
+			if event.Timestamp.Sub(t) < stabilization.window {
+				recent = append(recent, t)
+			}
+		}
+		recent = append(recent, event.Timestamp)
+		if len(recent) < stabilization.count {
+			occurrences[key] = recent
+			klog.V(4).InfoS("Defer the gpu health decision of the xid error until it is stable", "deviceUUID", event.DeviceUUID,
+				"xid", event.Xid, "decision", decision, "occurrences", len(recent), "count", stabilization.count, "window", stabilization.window)
+			return GPUHealthy
+		}
+		delete(occurrences, key)
+		return decision
+	}
+}
+
+// gpuXidLogInterval is the interval to log the xid errors of a device, the repeated ones in the interval are
+// summarized with a count instead of logged each.
+var gpuXidLogInterval = time.Minute
+
+// xidEventLogger deduplicates the logs of the xid errors per device. The first error of a device is logged
+// immediately, and the following ones in the interval are counted and logged as a summary once the interval passes.
+// It is not thread-safe since the xid errors are handled in one goroutine.
+type xidEventLogger struct {
+	interval time.Duration
+	devices  map[string]*xidLogState
+}
+
+type xidLogState struct {
+	lastLogTime time.Time
+	suppressed  int
+	lastXid     uint64
+}
+
+func newXidEventLogger(interval time.Duration) *xidEventLogger {
+	return &xidEventLogger{
+		interval: interval,
+		devices:  map[string]*xidLogState{},
+	}
+}
+
+// allow returns whether the xid error of the device should be logged at now, otherwise it is counted in the summary.
+func (l *xidEventLogger) allow(deviceUUID string, xid uint64, now time.Time) bool {
+	state, ok := l.devices[deviceUUID]
+	if !ok || now.Sub(state.lastLogTime) >= l.interval {
+		l.devices[deviceUUID] = &xidLogState{lastLogTime: now}
+		return true
+	}
+	state.suppressed++
+	state.lastXid = xid
+	return false
+}
+
+// xidLogSummary is the count of the xid errors of a device which are not logged in the last interval.
+type xidLogSummary struct {
+	DeviceUUID string
+	Count      int
+	LastXid    uint64
+}
+
+// flush returns the summaries of the devices whose interval passes at now, and resets their intervals.
+func (l *xidEventLogger) flush(now time.Time) []xidLogSummary {
+	var summaries []xidLogSummary
+	for deviceUUID, state := range l.devices {
+		if now.Sub(state.lastLogTime) < l.interval {
+			continue
+		}
+		if state.suppressed > 0 {
+			summaries = append(summaries, xidLogSummary{DeviceUUID: deviceUUID, Count: state.suppressed, LastXid: state.lastXid})
+		}
+		// the next error is logged immediately as the first one
+		delete(l.devices, deviceUUID)
+	}
+	return summaries
+}
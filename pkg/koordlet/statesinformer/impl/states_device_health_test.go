@@ -0,0 +1,165 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultXidHealthPolicy(t *testing.T) {
+	tests := []struct {
+		name  string
+		event XidEvent
+		want  GPUHealthDecision
+	}{
+		{
+			name:  "application xid",
+			event: XidEvent{Xid: 13, DeviceUUID: "1"},
+			want:  GPUHealthy,
+		},
+		{
+			name:  "application xid without device",
+			event: XidEvent{Xid: 43},
+			want:  GPUHealthy,
+		},
+		{
+			name:  "critical xid of device",
+			event: XidEvent{Xid: 79, DeviceUUID: "1"},
+			want:  GPUFailed,
+		},
+		{
+			name:  "critical xid without device",
+			event: XidEvent{Xid: 79},
+			want:  GPUDegraded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.event.Timestamp = time.Now()
+			assert.Equal(t, tt.want, DefaultXidHealthPolicy(tt.event))
+		})
+	}
+}
+
+func Test_parseXidStabilizations(t *testing.T) {
+	tests := []struct {
+		name    string
+		arg     map[string]string
+		want    map[uint64]xidStabilization
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			want: map[uint64]xidStabilization{},
+		},
+		{
+			name: "valid stabilizations",
+			arg:  map[string]string{"48": "3/10m", "63": " 2 / 1h "},
+			want: map[uint64]xidStabilization{
+				48: {count: 3, window: 10 * time.Minute},
+				63: {count: 2, window: time.Hour},
+			},
+		},
+		{
+			name:    "invalid xid",
+			arg:     map[string]string{"xid48": "3/10m"},
+			wantErr: true,
+		},
+		{
+			name:    "missing window",
+			arg:     map[string]string{"48": "3"},
+			wantErr: true,
+		},
+		{
+			name:    "non-positive count",
+			arg:     map[string]string{"48": "0/10m"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid window",
+			arg:     map[string]string{"48": "3/10"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseXidStabilizations(tt.arg)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			if !tt.wantErr {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_stabilizeXidHealthPolicy(t *testing.T) {
+	now := time.Now()
+	policy := stabilizeXidHealthPolicy(DefaultXidHealthPolicy, map[uint64]xidStabilization{
+		48: {count: 3, window: 10 * time.Minute},
+		13: {count: 2, window: time.Minute},
+	})
+
+	// the xids without the stabilizations are decided as is
+	assert.Equal(t, GPUFailed, policy(XidEvent{Xid: 79, DeviceUUID: "1", Timestamp: now}))
+	// the healthy decisions are not counted
+	assert.Equal(t, GPUHealthy, policy(XidEvent{Xid: 13, DeviceUUID: "1", Timestamp: now}))
+	assert.Equal(t, GPUHealthy, policy(XidEvent{Xid: 13, DeviceUUID: "1", Timestamp: now}))
+
+	// a one-off xid is deferred, and the occurrences out of the window expire
+	assert.Equal(t, GPUHealthy, policy(XidEvent{Xid: 48, DeviceUUID: "1", Timestamp: now}))
+	assert.Equal(t, GPUHealthy, policy(XidEvent{Xid: 48, DeviceUUID: "1", Timestamp: now.Add(5 * time.Minute)}))
+	assert.Equal(t, GPUHealthy, policy(XidEvent{Xid: 48, DeviceUUID: "1", Timestamp: now.Add(11 * time.Minute)}))
+	// the occurrences are counted per gpu
+	assert.Equal(t, GPUHealthy, policy(XidEvent{Xid: 48, DeviceUUID: "2", Timestamp: now.Add(12 * time.Minute)}))
+	// the recurring xid within the window is committed
+	assert.Equal(t, GPUFailed, policy(XidEvent{Xid: 48, DeviceUUID: "1", Timestamp: now.Add(12 * time.Minute)}))
+	// the occurrences are reset after committed
+	assert.Equal(t, GPUHealthy, policy(XidEvent{Xid: 48, DeviceUUID: "1", Timestamp: now.Add(13 * time.Minute)}))
+	// the events not attributed to a gpu are stabilized with their decisions
+	assert.Equal(t, GPUHealthy, policy(XidEvent{Xid: 48, Timestamp: now}))
+	assert.Equal(t, GPUHealthy, policy(XidEvent{Xid: 48, Timestamp: now}))
+	assert.Equal(t, GPUDegraded, policy(XidEvent{Xid: 48, Timestamp: now}))
+}
+
+func Test_xidEventLogger(t *testing.T) {
+	now := time.Now()
+	l := newXidEventLogger(time.Minute)
+
+	// the first errors of each device are logged
+	assert.True(t, l.allow("1", 79, now))
+	assert.True(t, l.allow("2", 48, now))
+	// the repeated errors in the interval are suppressed
+	for i := 0; i < 3; i++ {
+		assert.False(t, l.allow("1", 79, now.Add(time.Duration(i)*time.Second)))
+	}
+	assert.False(t, l.allow("1", 94, now.Add(10*time.Second)))
+	assert.Empty(t, l.flush(now.Add(30*time.Second)))
+
+	// the summary is flushed once the interval passes
+	summaries := l.flush(now.Add(time.Minute))
+	assert.Equal(t, []xidLogSummary{{DeviceUUID: "1", Count: 4, LastXid: 94}}, summaries)
+	assert.Empty(t, l.flush(now.Add(2*time.Minute)))
+
+	// the next error after the summary is logged immediately
+	assert.True(t, l.allow("1", 79, now.Add(2*time.Minute)))
+	assert.False(t, l.allow("1", 79, now.Add(2*time.Minute)))
+	// the error after the interval is logged even if not flushed
+	assert.True(t, l.allow("1", 79, now.Add(3*time.Minute)))
+}
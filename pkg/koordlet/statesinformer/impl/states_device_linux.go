@@ -18,183 +18,1566 @@ package impl
 
 import (
 	"context"
-	"os"
+	"encoding/json"
+	rawerrors "errors"
+	"fmt"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	jsonpatch "github.com/evanphx/json-patch"
 	corev1 "k8s.io/api/core/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/pointer"
 
 	"github.com/koordinator-sh/koordinator/apis/extension"
 	schedulingv1alpha1 "github.com/koordinator-sh/koordinator/apis/scheduling/v1alpha1"
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/metriccache"
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/metrics"
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/statesinformer"
 	koordletuti "github.com/koordinator-sh/koordinator/pkg/koordlet/util"
 	"github.com/koordinator-sh/koordinator/pkg/util"
 )
 
-func (s *statesInformer) reportDevice() {
+const (
+	defaultGPUHealthCheckWaitTimeout = time.Second
+	// defaultGPUHealthCheckStaleThreshold is the default threshold since the last return of waiting for the gpu
+	// health events, beyond which the event loop is regarded as stuck.
+	defaultGPUHealthCheckStaleThreshold = 30 * time.Second
+	// defaultDeviceReportTimeRefreshInterval is the default interval to refresh the last report time of the
+	// unchanged devices.
+	defaultDeviceReportTimeRefreshInterval = time.Minute
+)
+
+// reportDevice creates or updates the Device of the node, and returns an error if it needs to be retried.
+func (s *statesInformer) reportDevice() error {
 	node := s.GetNode()
 	if node == nil {
-		klog.Errorf("node is nil")
-		return
+		metrics.RecordDeviceReportError(metrics.DeviceReportStageGetNode)
+		return fmt.Errorf("failed to report Device, node is nil")
+	}
+	if isDeviceReportPaused(node) {
+		// keep the last reported Device, which is reported again once the node annotation is removed
+		klog.V(4).InfoS("Device reporting is paused, skip reporting Device", "node", node.Name)
+		return nil
 	}
 	device := s.buildBasicDevice(node)
-	func() {
-		gpuDevices := s.buildGPUDevice()
-		if len(gpuDevices) == 0 {
-			return
-		}
+	gpuDevices, err := s.buildGPUDevice(node)
+	defaultDeviceDebugger.record(gpuDevices, s.ListUnhealthyGPUs(), err, time.Now())
+	if updateErr := s.updateGPUDiscoveryCondition(node, s.buildGPUDiscoveryCondition(node, gpuDevices, err)); updateErr != nil {
+		klog.V(4).InfoS("Failed to update the gpu discovery condition of node", "node", node.Name, "err", updateErr)
+	}
+	if condition := s.buildGPUMonitoringCondition(gpuDevices, err); condition != nil {
+		meta.SetStatusCondition(&device.Status.Conditions, *condition)
+	}
+	if condition := s.buildGPUCoolingCondition(gpuDevices); condition != nil {
+		meta.SetStatusCondition(&device.Status.Conditions, *condition)
+	}
+	if condition := s.buildGPUMemoryCondition(gpuDevices); condition != nil {
+		meta.SetStatusCondition(&device.Status.Conditions, *condition)
+	}
+	// the gpus failed to be built are not reported, the reported gpus are kept to not remove them from the existing
+	// Device, while the other devices are still reported
+	buildGPUErr := err
+	if buildGPUErr != nil {
+		metrics.RecordDeviceReportError(metrics.DeviceReportStageBuildGPU)
+		klog.ErrorS(buildGPUErr, "Failed to build gpu devices, keep the reported gpus of Device", "node", node.Name)
+	} else if len(gpuDevices) != 0 {
 		gpuModel, gpuDriverVer := s.getGPUDriverAndModelFunc()
-		s.fillGPUDevice(device, gpuDevices, gpuModel, gpuDriverVer)
-	}()
+		s.fillGPUDevice(device, gpuDevices, gpuModel, gpuDriverVer, s.getCUDADriverVersion(gpuDriverVer))
+	}
 	func() {
 		rdmaDevices := s.buildRDMADevice()
 		if len(rdmaDevices) != 0 {
 			device.Spec.Devices = append(device.Spec.Devices, rdmaDevices...)
 		}
-	}()
-
-	err := s.updateDevice(device)
-	if err == nil {
-		klog.V(4).Infof("successfully update Device %s", node.Name)
-		return
+	}()
+	if fpgaDevices := s.buildFPGADevice(); len(fpgaDevices) != 0 {
+		device.Spec.Devices = append(device.Spec.Devices, fpgaDevices...)
+	}
+	s.limitReportedDevices(device)
+	device.Status.Resources = buildGPUResourceStatus(device.Spec.Devices, s.GetAllPods())
+	reportTime := metav1.Now()
+	for i := range device.Spec.Devices {
+		device.Spec.Devices[i].LastReportTime = &reportTime
+	}
+
+	resyncToken, forceResync := s.getDeviceResyncToken(node)
+	if forceResync {
+		klog.V(4).InfoS("Force to resync Device", "node", node.Name, "token", resyncToken)
+	}
+
+	err = s.updateDevice(device, forceResync, buildGPUErr != nil)
+	if err == nil {
+		klog.V(4).InfoS("Successfully updated Device", "node", node.Name)
+		s.deviceResyncToken = resyncToken
+		recordReportedDevices(device, buildGPUErr)
+		s.reportDeviceSummaryIfBuilt(node, device, buildGPUErr)
+		// retry to build the gpus
+		return buildGPUErr
+	}
+	if !errors.IsNotFound(err) {
+		metrics.RecordDeviceReportError(metrics.DeviceReportStageUpdate)
+		klog.ErrorS(err, "Failed to update Device", "node", node.Name)
+		return err
+	}
+
+	if err = s.checkGPUDeviceStable(node, gpuDevices); err != nil {
+		metrics.RecordDeviceReportError(metrics.DeviceReportStageCreate)
+		klog.V(4).InfoS("Defer creating Device until the gpus are stable", "node", node.Name, "reason", err)
+		return err
+	}
+	err = s.createDevice(device)
+	if err != nil {
+		metrics.RecordDeviceReportError(metrics.DeviceReportStageCreate)
+		klog.ErrorS(err, "Failed to create Device", "node", node.Name)
+		return err
+	}
+	klog.V(4).InfoS("Successfully created Device", "node", node.Name)
+	s.deviceResyncToken = resyncToken
+	recordReportedDevices(device, buildGPUErr)
+	s.reportDeviceSummaryIfBuilt(node, device, buildGPUErr)
+	return buildGPUErr
+}
+
+// recordReportedDevices records the number of the reported devices of each managed type. The gpus kept from the
+// reported Device are not counted, so the devices are not recorded until the gpus are built.
+func recordReportedDevices(device *schedulingv1alpha1.Device, buildGPUErr error) {
+	if buildGPUErr != nil {
+		return
+	}
+	counts := map[schedulingv1alpha1.DeviceType]int{}
+	for i := range device.Spec.Devices {
+		counts[device.Spec.Devices[i].Type]++
+	}
+	for deviceType := range managedDeviceTypes {
+		metrics.RecordDeviceReportedDevices(string(deviceType), counts[deviceType])
+	}
+}
+
+// reportDeviceSummaryIfBuilt reports the DeviceSummary of the reported Device unless the gpus failed to be built,
+// in which case the summary is kept until the gpus are built. The failures are retried in the next reporting.
+func (s *statesInformer) reportDeviceSummaryIfBuilt(node *corev1.Node, device *schedulingv1alpha1.Device, buildGPUErr error) {
+	if buildGPUErr != nil {
+		return
+	}
+	if err := s.reportDeviceSummary(node, device); err != nil {
+		klog.ErrorS(err, "Failed to report DeviceSummary", "node", node.Name)
+	}
+}
+
+// limitReportedDevices warns if the devices of the Device exceed the configured max, and caps them to the max if
+// configured. The devices are kept in the order built, i.e. the gpus before the rdma and fpga devices.
+func (s *statesInformer) limitReportedDevices(device *schedulingv1alpha1.Device) {
+	if s.config == nil || s.config.MaxReportedDevices <= 0 || len(device.Spec.Devices) <= s.config.MaxReportedDevices {
+		return
+	}
+	if !s.config.CapReportedDevices {
+		klog.Warningf("Device %s has %d devices over the max %d, the device discovery may be misconfigured",
+			device.Name, len(device.Spec.Devices), s.config.MaxReportedDevices)
+		return
+	}
+	klog.Warningf("Device %s has %d devices over the max %d, cap them to the max, the device discovery may be misconfigured",
+		device.Name, len(device.Spec.Devices), s.config.MaxReportedDevices)
+	device.Spec.Devices = device.Spec.Devices[:s.config.MaxReportedDevices]
+}
+
+func (s *statesInformer) buildBasicDevice(node *corev1.Node) *schedulingv1alpha1.Device {
+	blocker := true
+	device := &schedulingv1alpha1.Device{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: node.Name,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion:         "v1",
+					Kind:               "Node",
+					Name:               node.Name,
+					UID:                node.UID,
+					Controller:         &blocker,
+					BlockOwnerDeletion: &blocker,
+				},
+			},
+		},
+	}
+
+	return device
+}
+
+func (s *statesInformer) fillGPUDevice(device *schedulingv1alpha1.Device,
+	gpuDevices []schedulingv1alpha1.DeviceInfo, gpuModel string, gpuDriverVer string, cudaDriverVer string) {
+
+	device.Spec.Devices = append(device.Spec.Devices, gpuDevices...)
+	if device.Labels == nil {
+		device.Labels = make(map[string]string)
+	}
+	if gpuModel != "" {
+		device.Labels[extension.LabelGPUModel] = gpuModel
+	}
+	if gpuDriverVer != "" {
+		device.Labels[extension.LabelGPUDriverVersion] = gpuDriverVer
+	}
+	if cudaDriverVer != "" {
+		device.Labels[extension.LabelGPUCUDADriverVersion] = cudaDriverVer
+	}
+	device.Labels[extension.LabelGPUCoreGranularity] = strconv.FormatInt(s.getGPUCoreGranularity(), 10)
+	if granularity := s.getGPUMemoryGranularity(); granularity > 0 {
+		device.Labels[extension.LabelGPUMemoryGranularity] = strconv.FormatInt(granularity, 10)
+	}
+	partitions := sets.NewString()
+	for i := range gpuDevices {
+		if partition, ok := gpuDevices[i].Labels[extension.LabelGPUFabricPartition]; ok {
+			partitions.Insert(partition)
+		}
+	}
+	if partitions.Len() > 0 {
+		device.Labels[extension.LabelGPUFabricPartitionCount] = strconv.Itoa(partitions.Len())
+	}
+	// the node is a domain of the fabric partition only if all the gpus are in one partition
+	if partitions.Len() == 1 {
+		device.Labels[extension.LabelGPUFabricPartition] = partitions.List()[0]
+	}
+}
+
+const (
+	// gpuMonitoringReasonReady means NVML is initialized and the gpus are monitored by the health check.
+	gpuMonitoringReasonReady = "NVMLReady"
+	// gpuMonitoringReasonNVMLNotInitialized means the gpus are collected but NVML is not initialized, so that the
+	// health of the gpus is not monitored.
+	gpuMonitoringReasonNVMLNotInitialized = "NVMLNotInitialized"
+	// gpuMonitoringReasonGPUDevicesUnavailable means the gpus failed to be built, e.g. the GPU subsystem is degraded.
+	gpuMonitoringReasonGPUDevicesUnavailable = "GPUDevicesUnavailable"
+)
+
+// buildGPUMonitoringCondition returns the GPUMonitoringReady condition summarizing the gpus built with the buildErr,
+// or nil if the node has no gpu.
+func (s *statesInformer) buildGPUMonitoringCondition(gpuDevices []schedulingv1alpha1.DeviceInfo, buildErr error) *metav1.Condition {
+	if buildErr != nil {
+		return &metav1.Condition{
+			Type:    schedulingv1alpha1.DeviceConditionGPUMonitoringReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  gpuMonitoringReasonGPUDevicesUnavailable,
+			Message: buildErr.Error(),
+		}
+	}
+	if !s.gpuAvailable && len(gpuDevices) == 0 {
+		return nil
+	}
+	healthy, unhealthy := 0, 0
+	for i := range gpuDevices {
+		if gpuDevices[i].Health {
+			healthy++
+		} else {
+			unhealthy++
+		}
+	}
+	if !s.gpuAvailable {
+		return &metav1.Condition{
+			Type:    schedulingv1alpha1.DeviceConditionGPUMonitoringReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  gpuMonitoringReasonNVMLNotInitialized,
+			Message: fmt.Sprintf("NVML is not initialized, the health of %d gpus is not monitored", len(gpuDevices)),
+		}
+	}
+	return &metav1.Condition{
+		Type:    schedulingv1alpha1.DeviceConditionGPUMonitoringReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  gpuMonitoringReasonReady,
+		Message: fmt.Sprintf("%d healthy and %d unhealthy gpus", healthy, unhealthy),
+	}
+}
+
+const (
+	// gpuDiscoveryReasonDiscovered means the gpus are discovered as expected.
+	gpuDiscoveryReasonDiscovered = "GPUsDiscovered"
+	// gpuDiscoveryReasonNVMLInitFailed means nvml fails to be initialized, e.g. the driver is not loaded.
+	gpuDiscoveryReasonNVMLInitFailed = "NVMLInitFailed"
+	// gpuDiscoveryReasonDiscoveryFailed means the gpus fail to be queried, e.g. the device count fails.
+	gpuDiscoveryReasonDiscoveryFailed = "GPUDiscoveryFailed"
+	// gpuDiscoveryReasonGPUsMissing means less gpus are discovered than expected on the node.
+	gpuDiscoveryReasonGPUsMissing = "GPUsMissing"
+)
+
+// errNVMLLibraryNotFound is the error of initializing nvml on the node without the gpu driver installed.
+var errNVMLLibraryNotFound = rawerrors.New("nvml library not found")
+
+// buildGPUDiscoveryCondition returns the GPUDiscoveryReady node condition of the gpus discovered with the buildErr,
+// or nil if the node is not expected to have gpus, i.e. the gpu driver is not installed and no gpu is expected.
+func (s *statesInformer) buildGPUDiscoveryCondition(node *corev1.Node, gpuDevices []schedulingv1alpha1.DeviceInfo, buildErr error) *corev1.NodeCondition {
+	expected := s.getExpectedGPUCount(node)
+	discovered := countPhysicalGPUs(gpuDevices)
+	condition := &corev1.NodeCondition{
+		Type:   extension.NodeConditionGPUDiscoveryReady,
+		Status: corev1.ConditionFalse,
+	}
+	switch {
+	case s.gpuInitErr != nil && (s.gpuInitErr != errNVMLLibraryNotFound || expected > 0):
+		condition.Reason = gpuDiscoveryReasonNVMLInitFailed
+		condition.Message = s.gpuInitErr.Error()
+	case buildErr != nil:
+		condition.Reason = gpuDiscoveryReasonDiscoveryFailed
+		condition.Message = buildErr.Error()
+	case expected > 0 && discovered < expected:
+		condition.Reason = gpuDiscoveryReasonGPUsMissing
+		condition.Message = fmt.Sprintf("discovered %d gpus, expected %d", discovered, expected)
+	case s.gpuAvailable || discovered > 0:
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = gpuDiscoveryReasonDiscovered
+		condition.Message = fmt.Sprintf("discovered %d gpus", discovered)
+	default:
+		return nil
+	}
+	return condition
+}
+
+// updateGPUDiscoveryCondition patches the GPUDiscoveryReady condition of the node if it is changed. The condition
+// is kept as is if nil, and is set back to true when the discovery recovers.
+func (s *statesInformer) updateGPUDiscoveryCondition(node *corev1.Node, condition *corev1.NodeCondition) error {
+	if condition == nil || s.option == nil || s.option.KubeClient == nil {
+		return nil
+	}
+	var old *corev1.NodeCondition
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == condition.Type {
+			old = &node.Status.Conditions[i]
+			break
+		}
+	}
+	if old != nil && old.Status == condition.Status && old.Reason == condition.Reason && old.Message == condition.Message {
+		return nil
+	}
+	now := metav1.Now()
+	condition.LastHeartbeatTime = now
+	condition.LastTransitionTime = now
+	if old != nil && old.Status == condition.Status {
+		condition.LastTransitionTime = old.LastTransitionTime
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []corev1.NodeCondition{*condition},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err = s.option.KubeClient.CoreV1().Nodes().PatchStatus(context.TODO(), node.Name, patch); err != nil {
+		return err
+	}
+	klog.V(4).InfoS("Successfully updated the gpu discovery condition of node", "node", node.Name,
+		"status", condition.Status, "reason", condition.Reason)
+	return nil
+}
+
+const (
+	// gpuCoolingReasonFanStopped means the fan of any gpu stops while the gpu is thermally loaded.
+	gpuCoolingReasonFanStopped = "FanStopped"
+	// gpuCoolingReasonNormal means no gpu is found with the fan stopped while thermally loaded.
+	gpuCoolingReasonNormal = "CoolingNormal"
+)
+
+// buildGPUCoolingCondition returns the GPUCoolingDegraded condition of the gpus labeled cooling degraded, or nil if
+// the node has no gpu or the condition is not enabled.
+func (s *statesInformer) buildGPUCoolingCondition(gpuDevices []schedulingv1alpha1.DeviceInfo) *metav1.Condition {
+	if s.config == nil || !s.config.EnableGPUCoolingCondition || len(gpuDevices) == 0 {
+		return nil
+	}
+	var degraded []string
+	for i := range gpuDevices {
+		if gpuDevices[i].Labels[extension.LabelGPUCoolingDegraded] == "true" {
+			degraded = append(degraded, gpuDevices[i].UUID)
+		}
+	}
+	if len(degraded) == 0 {
+		return &metav1.Condition{
+			Type:    schedulingv1alpha1.DeviceConditionGPUCoolingDegraded,
+			Status:  metav1.ConditionFalse,
+			Reason:  gpuCoolingReasonNormal,
+			Message: "no gpu is found with the fan stopped while thermally loaded",
+		}
+	}
+	sort.Strings(degraded)
+	return &metav1.Condition{
+		Type:    schedulingv1alpha1.DeviceConditionGPUCoolingDegraded,
+		Status:  metav1.ConditionTrue,
+		Reason:  gpuCoolingReasonFanStopped,
+		Message: fmt.Sprintf("the fan stops while thermally loaded on gpus %s", strings.Join(degraded, ",")),
+	}
+}
+
+const (
+	// gpuMemoryReasonPagesRetired means the memory pages of any gpu are retired over the threshold or pending
+	// retirement.
+	gpuMemoryReasonPagesRetired = "PagesRetired"
+	// gpuMemoryReasonNormal means no gpu is found with the memory degraded.
+	gpuMemoryReasonNormal = "MemoryNormal"
+)
+
+// isGPUMemoryDegraded returns whether the memory pages of the gpu are retired over the threshold or pending retirement
+// if the condition is enabled.
+func (s *statesInformer) isGPUMemoryDegraded(gpu *koordletuti.GPUDeviceInfo) bool {
+	if s.config == nil || !s.config.EnableGPUMemoryCondition {
+		return false
+	}
+	return s.isGPURetiredPagesExceeded(gpu)
+}
+
+// isGPURetiredPagesExceeded returns whether the memory pages of the gpu are retired over the threshold or pending
+// retirement.
+func (s *statesInformer) isGPURetiredPagesExceeded(gpu *koordletuti.GPUDeviceInfo) bool {
+	if gpu.RetiredPagesPending {
+		return true
+	}
+	return s.config != nil && s.config.GPURetiredPagesThreshold > 0 && int(gpu.RetiredPages) >= s.config.GPURetiredPagesThreshold
+}
+
+// getGPUHealthState returns the health state of the gpu, which is Draining if the gpu is healthy but any configured
+// draining trigger fires, so that the gpu accepts no new pods while the running ones keep running.
+func (s *statesInformer) getGPUHealthState(gpu *koordletuti.GPUDeviceInfo, health bool) schedulingv1alpha1.DeviceHealthState {
+	if !health {
+		return schedulingv1alpha1.DeviceHealthStateUnhealthy
+	}
+	if s.config != nil {
+		if s.config.DrainGPUOnCoolingDegraded && gpu.CoolingDegraded {
+			return schedulingv1alpha1.DeviceHealthStateDraining
+		}
+		if s.config.DrainGPUOnMemoryDegraded && s.isGPURetiredPagesExceeded(gpu) {
+			return schedulingv1alpha1.DeviceHealthStateDraining
+		}
+	}
+	return schedulingv1alpha1.DeviceHealthStateHealthy
+}
+
+// buildGPUMemoryCondition returns the GPUMemoryDegraded condition of the gpus labeled memory degraded, or nil if the
+// node has no gpu or the condition is not enabled.
+func (s *statesInformer) buildGPUMemoryCondition(gpuDevices []schedulingv1alpha1.DeviceInfo) *metav1.Condition {
+	if s.config == nil || !s.config.EnableGPUMemoryCondition || len(gpuDevices) == 0 {
+		return nil
+	}
+	var degraded []string
+	for i := range gpuDevices {
+		if gpuDevices[i].Labels[extension.LabelGPUMemoryDegraded] == "true" {
+			degraded = append(degraded, gpuDevices[i].UUID)
+		}
+	}
+	if len(degraded) == 0 {
+		return &metav1.Condition{
+			Type:    schedulingv1alpha1.DeviceConditionGPUMemoryDegraded,
+			Status:  metav1.ConditionFalse,
+			Reason:  gpuMemoryReasonNormal,
+			Message: "no gpu is found with the memory pages retired over the threshold or pending retirement",
+		}
+	}
+	sort.Strings(degraded)
+	return &metav1.Condition{
+		Type:    schedulingv1alpha1.DeviceConditionGPUMemoryDegraded,
+		Status:  metav1.ConditionTrue,
+		Reason:  gpuMemoryReasonPagesRetired,
+		Message: fmt.Sprintf("the memory pages are retired over the threshold or pending retirement on gpus %s", strings.Join(degraded, ",")),
+	}
+}
+
+// checkGPUDeviceStable returns an error if the discovered gpus may be incomplete to create the Device, i.e. the
+// count of the physical gpus is less than the expected count, and has not been stable for the stabilization period.
+// It avoids publishing an incomplete device set when nvml is flaky during the node boot.
+func (s *statesInformer) checkGPUDeviceStable(node *corev1.Node, gpuDevices []schedulingv1alpha1.DeviceInfo) error {
+	if !s.gpuAvailable || s.config == nil {
+		return nil
+	}
+	expected := s.getExpectedGPUCount(node)
+	period := s.config.GPUDeviceStabilizationPeriod
+	if expected <= 0 && period <= 0 {
+		return nil
+	}
+
+	count := countPhysicalGPUs(gpuDevices)
+	if expected > 0 && count >= expected {
+		return nil
+	}
+	now := time.Now()
+	if count != s.discoveredGPUCount || s.discoveredGPUCountSince.IsZero() {
+		s.discoveredGPUCount = count
+		s.discoveredGPUCountSince = now
+	}
+	if period > 0 && now.Sub(s.discoveredGPUCountSince) >= period {
+		return nil
+	}
+	return fmt.Errorf("discovered %d gpus, expected %d, stable for %v", count, expected, now.Sub(s.discoveredGPUCountSince))
+}
+
+// getExpectedGPUCount returns the count of the physical gpus expected on the node, which is configured by the node
+// label LabelGPUExpectedCount or the config, or 0 if not expected.
+func (s *statesInformer) getExpectedGPUCount(node *corev1.Node) int {
+	var expected int
+	if s.config != nil {
+		expected = s.config.ExpectedGPUCount
+	}
+	if value, ok := node.Labels[extension.LabelGPUExpectedCount]; ok {
+		if count, err := strconv.Atoi(value); err == nil {
+			expected = count
+		} else {
+			klog.V(4).InfoS("Ignore the invalid expected gpu count of node", "node", node.Name, "value", value)
+		}
+	}
+	return expected
+}
+
+// countPhysicalGPUs returns the count of the physical gpus, where the time-sliced replicas are counted once.
+func countPhysicalGPUs(gpuDevices []schedulingv1alpha1.DeviceInfo) int {
+	physical := sets.NewString()
+	for _, d := range gpuDevices {
+		if uuid, ok := d.Labels[extension.LabelGPUPhysicalUUID]; ok {
+			physical.Insert(uuid)
+		} else {
+			physical.Insert(d.UUID)
+		}
+	}
+	return physical.Len()
+}
+
+// getGPUCoreGranularity returns the gpu-core quantity of a whole GPU.
+func (s *statesInformer) getGPUCoreGranularity() int64 {
+	if s.config == nil || s.config.GPUCoreGranularity <= 0 {
+		return extension.DefaultGPUCoreGranularity
+	}
+	return s.config.GPUCoreGranularity
+}
+
+// getGPUMemoryGranularity returns the bytes which the gpu-memory is rounded down to, or 0 if not rounded.
+func (s *statesInformer) getGPUMemoryGranularity() int64 {
+	if s.config == nil || s.config.GPUMemoryGranularity <= 0 {
+		return 0
+	}
+	return s.config.GPUMemoryGranularity
+}
+
+// gpuMemoryQuantity returns the gpu-memory of the bytes reported in the Device, which is rounded down to the
+// granularity allocatable by the device plugin.
+func (s *statesInformer) gpuMemoryQuantity(bytes uint64) resource.Quantity {
+	return extension.RoundDownGPUMemory(koordletuti.GPUMemoryQuantity(bytes, koordletuti.MemoryUnitByte), s.getGPUMemoryGranularity())
+}
+
+// getDeviceReportTimeRefreshInterval returns the interval to refresh the last report time of the unchanged devices.
+func (s *statesInformer) getDeviceReportTimeRefreshInterval() time.Duration {
+	if s.config == nil {
+		return defaultDeviceReportTimeRefreshInterval
+	}
+	return s.config.DeviceReportTimeRefreshInterval
+}
+
+func (s *statesInformer) createDevice(device *schedulingv1alpha1.Device) error {
+	_, err := s.deviceClient.Create(context.TODO(), device, metav1.CreateOptions{})
+	if err == nil {
+		metrics.RecordDeviceReportOperation(metrics.DeviceReportOperationCreate)
+	}
+	return err
+}
+
+// getDeviceResyncToken returns the resync token annotated on the node, and whether it is a new token
+// which has not been handled.
+func (s *statesInformer) getDeviceResyncToken(node *corev1.Node) (string, bool) {
+	token := node.Annotations[extension.AnnotationDeviceResync]
+	return token, token != "" && token != s.deviceResyncToken
+}
+
+// isDeviceReportPaused returns whether the Device reporting and the gpu health marking are paused by the node annotation.
+func isDeviceReportPaused(node *corev1.Node) bool {
+	return node != nil && node.Annotations[extension.AnnotationDeviceReportPaused] == "true"
+}
+
+var (
+	// managedDeviceTypes are the device types reported by koordlet, devices of other types are kept as is.
+	managedDeviceTypes = map[schedulingv1alpha1.DeviceType]struct{}{
+		schedulingv1alpha1.GPU:  {},
+		schedulingv1alpha1.RDMA: {},
+		schedulingv1alpha1.FPGA: {},
+	}
+	// managedDeviceConditionTypes are the types of the conditions reported by koordlet, other conditions are kept as is.
+	managedDeviceConditionTypes = []string{
+		schedulingv1alpha1.DeviceConditionGPUMonitoringReady,
+		schedulingv1alpha1.DeviceConditionGPUCoolingDegraded,
+		schedulingv1alpha1.DeviceConditionGPUMemoryDegraded,
+	}
+	// managedDeviceLabels are the labels reported by koordlet, other labels are kept as is.
+	managedDeviceLabels = []string{
+		extension.LabelGPUModel,
+		extension.LabelGPUDriverVersion,
+		extension.LabelGPUCUDADriverVersion,
+		extension.LabelGPUCoreGranularity,
+		extension.LabelGPUMemoryGranularity,
+		extension.LabelGPUFabricPartitionCount,
+		extension.LabelGPUFabricPartition,
+	}
+)
+
+// updateDevice patches the Device if the devices or labels are changed, or the last report time of the devices
+// is expired.
+// If force is true, it reads the latest Device from the apiserver instead of the cache, and always patches it.
+// Otherwise, the Device is read from the cache until the first conflict, after which the retries read the latest one
+// from the apiserver, since a stale cache can fail the patches repeatedly.
+// Only the devices and labels managed by koordlet are reconciled, the annotations, labels and devices added by
+// others are preserved.
+// If keepGPUs is true, e.g. the gpus failed to be built, the gpus reported in the latest Device are kept.
+func (s *statesInformer) updateDevice(device *schedulingv1alpha1.Device, force, keepGPUs bool) error {
+	sortDeviceInfos(device.Spec.Devices)
+
+	getOptions := metav1.GetOptions{ResourceVersion: "0"}
+	if force {
+		getOptions = metav1.GetOptions{}
+	}
+	return util.RetryOnConflictOrTooManyRequests(func() error {
+		latestDevice, err := s.deviceClient.Get(context.TODO(), device.Name, getOptions)
+		if err != nil {
+			return err
+		}
+		sortDeviceInfos(latestDevice.Spec.Devices)
+
+		desiredDevice := device
+		if keepGPUs {
+			desiredDevice = device.DeepCopy()
+			keepReportedGPUs(latestDevice, desiredDevice)
+		}
+		mergedDevice := mergeDevice(latestDevice, desiredDevice)
+		sortDeviceInfos(mergedDevice.Spec.Devices)
+		if !force && util.IsDeviceSpecEqual(&mergedDevice.Spec, &latestDevice.Spec) &&
+			apiequality.Semantic.DeepEqual(mergedDevice.Labels, latestDevice.Labels) &&
+			apiequality.Semantic.DeepEqual(mergedDevice.Status.Conditions, latestDevice.Status.Conditions) &&
+			apiequality.Semantic.DeepEqual(mergedDevice.Status.Resources, latestDevice.Status.Resources) &&
+			!s.isDeviceReportTimeExpired(latestDevice) {
+			klog.V(4).InfoS("Device has not changed and does not need to be updated", "node", device.Name)
+			return nil
+		}
+
+		patchBytes, err := generateDevicePatch(latestDevice, mergedDevice)
+		if err != nil {
+			return err
+		}
+		_, err = s.deviceClient.Patch(context.TODO(), device.Name, types.MergePatchType, patchBytes, metav1.PatchOptions{})
+		if errors.IsConflict(err) {
+			getOptions = metav1.GetOptions{}
+		}
+		if err == nil {
+			metrics.RecordDeviceReportOperation(metrics.DeviceReportOperationUpdate)
+		}
+		return err
+	})
+}
+
+// sortDeviceInfos sorts the devices in a total order of the type, minor, uuid and replica index, so that the devices
+// are compared in a deterministic order even if they are different in other fields, e.g. the gpus with different
+// memory, or the entries sharing a minor like the MIG instances and the time-sliced replicas.
+func sortDeviceInfos(devices []schedulingv1alpha1.DeviceInfo) {
+	minorOf := func(d *schedulingv1alpha1.DeviceInfo) int32 {
+		if d.Minor == nil {
+			return -1
+		}
+		return *d.Minor
+	}
+	replicaIndexOf := func(d *schedulingv1alpha1.DeviceInfo) int {
+		index, err := strconv.Atoi(d.Labels[extension.LabelGPUReplicaIndex])
+		if err != nil {
+			return -1
+		}
+		return index
+	}
+	sort.SliceStable(devices, func(i, j int) bool {
+		if devices[i].Type != devices[j].Type {
+			return devices[i].Type < devices[j].Type
+		}
+		if mi, mj := minorOf(&devices[i]), minorOf(&devices[j]); mi != mj {
+			return mi < mj
+		}
+		if devices[i].UUID != devices[j].UUID {
+			return devices[i].UUID < devices[j].UUID
+		}
+		return replicaIndexOf(&devices[i]) < replicaIndexOf(&devices[j])
+	})
+}
+
+// generateDevicePatch returns the merge patch from the latest Device to the merged one, which only contains the
+// devices and labels changed by koordlet, so that the other fields are not clobbered.
+// Since a merge patch replaces the whole device list, the patch carries the resourceVersion of the latest Device
+// to fail with a conflict instead of overwriting the devices added concurrently by others.
+func generateDevicePatch(latest, merged *schedulingv1alpha1.Device) ([]byte, error) {
+	original := latest.DeepCopy()
+	original.ResourceVersion = ""
+	oldData, err := json.Marshal(original)
+	if err != nil {
+		return nil, err
+	}
+	newData, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	return jsonpatch.CreateMergePatch(oldData, newData)
+}
+
+// isDeviceReportTimeExpired returns whether the last report time of any managed device needs to be refreshed,
+// so that the report time shows koordlet is still active even if the devices are unchanged.
+func (s *statesInformer) isDeviceReportTimeExpired(device *schedulingv1alpha1.Device) bool {
+	interval := s.getDeviceReportTimeRefreshInterval()
+	if interval <= 0 {
+		return true
+	}
+	for _, d := range device.Spec.Devices {
+		if _, ok := managedDeviceTypes[d.Type]; !ok {
+			continue
+		}
+		if d.LastReportTime == nil || time.Since(d.LastReportTime.Time) >= interval {
+			return true
+		}
+	}
+	return false
+}
+
+// keepReportedGPUs copies the gpus, the gpu resource status, the gpu labels and the conditions not built in the
+// desired Device from the latest one, so that the gpus failed to be built are reported as before. The last report
+// time of the gpus is not refreshed.
+func keepReportedGPUs(latest, desired *schedulingv1alpha1.Device) {
+	for _, d := range latest.Spec.Devices {
+		if d.Type == schedulingv1alpha1.GPU {
+			desired.Spec.Devices = append(desired.Spec.Devices, *d.DeepCopy())
+		}
+	}
+	for _, r := range latest.Status.Resources {
+		if r.Type == schedulingv1alpha1.GPU {
+			desired.Status.Resources = append(desired.Status.Resources, *r.DeepCopy())
+		}
+	}
+	for _, key := range managedDeviceLabels {
+		value, ok := latest.Labels[key]
+		if !ok {
+			continue
+		}
+		if _, ok = desired.Labels[key]; ok {
+			continue
+		}
+		if desired.Labels == nil {
+			desired.Labels = map[string]string{}
+		}
+		desired.Labels[key] = value
+	}
+	for _, conditionType := range managedDeviceConditionTypes {
+		if meta.FindStatusCondition(desired.Status.Conditions, conditionType) != nil {
+			continue
+		}
+		if condition := meta.FindStatusCondition(latest.Status.Conditions, conditionType); condition != nil {
+			meta.SetStatusCondition(&desired.Status.Conditions, *condition)
+		}
+	}
+}
+
+// mergeDevice returns a copy of the latest Device whose managed devices, labels, resource status and conditions are
+// replaced by the desired ones.
+func mergeDevice(latest, desired *schedulingv1alpha1.Device) *schedulingv1alpha1.Device {
+	merged := latest.DeepCopy()
+
+	var devices []schedulingv1alpha1.DeviceInfo
+	for _, d := range latest.Spec.Devices {
+		if _, ok := managedDeviceTypes[d.Type]; !ok {
+			devices = append(devices, d)
+		}
+	}
+	for _, d := range desired.Spec.Devices {
+		devices = append(devices, *d.DeepCopy())
+	}
+	merged.Spec.Devices = devices
+
+	var resources []schedulingv1alpha1.DeviceResourceStatus
+	for _, r := range latest.Status.Resources {
+		if _, ok := managedDeviceTypes[r.Type]; !ok {
+			resources = append(resources, r)
+		}
+	}
+	for _, r := range desired.Status.Resources {
+		resources = append(resources, *r.DeepCopy())
+	}
+	merged.Status.Resources = resources
+
+	for _, key := range managedDeviceLabels {
+		value, ok := desired.Labels[key]
+		if !ok {
+			delete(merged.Labels, key)
+			continue
+		}
+		if merged.Labels == nil {
+			merged.Labels = map[string]string{}
+		}
+		merged.Labels[key] = value
+	}
+	mergeDeviceConditions(merged, desired)
+	return merged
+}
+
+// mergeDeviceConditions sets the conditions managed by koordlet in the merged Device as the desired ones, the
+// transition time of a condition is kept unless its status changes.
+func mergeDeviceConditions(merged, desired *schedulingv1alpha1.Device) {
+	for _, conditionType := range managedDeviceConditionTypes {
+		condition := meta.FindStatusCondition(desired.Status.Conditions, conditionType)
+		if condition == nil {
+			meta.RemoveStatusCondition(&merged.Status.Conditions, conditionType)
+			continue
+		}
+		meta.SetStatusCondition(&merged.Status.Conditions, *condition)
+	}
+}
+
+// buildGPUDevice returns the gpu devices collected in the metric cache.
+// If the gpus are expected but not collected yet, e.g. the metric pipeline lags, it falls back to the minimal
+// gpu devices queried from nvml directly.
+// It returns an empty list without error if the node has no gpu, and returns an error if the gpus are
+// expected but failed to be collected, in which case the caller should keep the reported devices unchanged.
+// The gpus reserved by the node annotation are reported as unhealthy.
+func (s *statesInformer) buildGPUDevice(node *corev1.Node) ([]schedulingv1alpha1.DeviceInfo, error) {
+	if s.gpuAvailable && s.nvmlBreaker.IsOpen() {
+		// the collected gpus and their health may be stale
+		return nil, fmt.Errorf("GPU subsystem degraded until %v", s.nvmlBreaker.OpenUntil())
+	}
+	gpus, err := s.listGPUDevices()
+	if err != nil {
+		return nil, err
+	}
+	if len(gpus) == 0 {
+		klog.V(4).Infof("gpu device not exist")
+		return nil, nil
+	}
+	s.pruneUnhealthyGPUs(gpus)
+
+	// the vGPU instances are reported in place of their physical gpus
+	vgpus, err := s.getActiveVGPUs(gpus)
+	if err != nil {
+		return nil, err
+	}
+
+	// the minors of all gpus are scaled by the max replicas to keep them unique if any gpu is time-sliced or
+	// virtualized into vGPUs
+	maxReplicas := int32(1)
+	for idx := range gpus {
+		if replicas := s.getGPUTimeSlicingReplicas(gpus[idx].ProductName); replicas > maxReplicas {
+			maxReplicas = replicas
+		}
+		if count := int32(len(vgpus[gpus[idx].UUID])); count > maxReplicas {
+			maxReplicas = count
+		}
+	}
+
+	reservedGPUs := getReservedGPUs(node)
+	nodeProblemGPUs := s.getNodeProblemGPUs(node, gpus)
+	p2pGroups := s.getGPUP2PGroups(gpus)
+	var numaSockets map[int32]int32
+
+	var deviceInfos []schedulingv1alpha1.DeviceInfo
+	for idx := range gpus {
+		gpu := gpus[idx]
+		health := !gpu.Unhealthy
+		s.gpuMutex.RLock()
+		if _, ok := s.unhealthyGPU[gpu.UUID]; ok {
+			health = false
+		}
+		s.gpuMutex.RUnlock()
+		reserved := reservedGPUs.Has(gpu.UUID) || reservedGPUs.Has(strconv.Itoa(int(gpu.Minor)))
+		if reserved || nodeProblemGPUs.Has(gpu.UUID) {
+			health = false
+		}
+
+		var topology *schedulingv1alpha1.DeviceTopology
+		if gpu.NodeID >= 0 && gpu.PCIE != "" && gpu.BusID != "" {
+			if numaSockets == nil {
+				numaSockets = s.getNUMANodeSockets()
+			}
+			// the socket helps the scheduler to align the gpus with the cpus, -1 if unknown
+			socketID, ok := numaSockets[gpu.NodeID]
+			if !ok {
+				socketID = -1
+			}
+			topology = &schedulingv1alpha1.DeviceTopology{
+				SocketID: socketID,
+				NodeID:   gpu.NodeID,
+				PCIEID:   gpu.PCIE,
+				BusID:    gpu.BusID,
+			}
+		}
+
+		identity := s.getGPUIdentity(gpu.UUID, health)
+		coolingDegraded := s.config != nil && s.config.EnableGPUCoolingCondition && gpu.CoolingDegraded
+		p2pGroup := p2pGroups[gpu.UUID]
+		memoryDegraded := s.isGPUMemoryDegraded(&gpu)
+		pcieLink := s.getGPUPCIeLink(gpu.UUID)
+
+		var labels map[string]string
+		if gpu.ComputeCapability != "" || gpu.ProductName != "" || gpu.MigCapable || reserved || gpu.FabricPartitionID != "" ||
+			gpu.PersistenceModeEnabled != nil || identity.Serial != "" || identity.InforomVersion != "" || coolingDegraded ||
+			p2pGroup != "" || memoryDegraded || pcieLink != (gpuPCIeLink{}) {
+			labels = map[string]string{}
+			if gpu.ComputeCapability != "" {
+				labels[extension.LabelGPUComputeCapability] = gpu.ComputeCapability
+			}
+			if gpu.ProductName != "" {
+				labels[extension.LabelGPUProductName] = gpu.ProductName
+			}
+			// the mig partitions are not reported until the mig mode is enabled
+			if gpu.MigCapable {
+				labels[extension.LabelGPUMigCapable] = "true"
+				labels[extension.LabelGPUMigEnabled] = strconv.FormatBool(gpu.MigEnabled)
+				labels[extension.LabelGPUMigPendingEnabled] = strconv.FormatBool(gpu.MigPendingEnabled)
+			}
+			if reserved {
+				labels[extension.LabelGPUReserved] = "true"
+			}
+			if gpu.FabricPartitionID != "" {
+				labels[extension.LabelGPUFabricPartition] = gpu.FabricPartitionID
+			}
+			if gpu.PersistenceModeEnabled != nil {
+				labels[extension.LabelGPUPersistenceModeEnabled] = strconv.FormatBool(*gpu.PersistenceModeEnabled)
+			}
+			if identity.Serial != "" {
+				labels[extension.LabelGPUSerialNumber] = identity.Serial
+			}
+			if identity.InforomVersion != "" {
+				labels[extension.LabelGPUInforomVersion] = identity.InforomVersion
+			}
+			if coolingDegraded {
+				labels[extension.LabelGPUCoolingDegraded] = "true"
+			}
+			if p2pGroup != "" {
+				labels[extension.LabelGPUP2PGroup] = p2pGroup
+			}
+			if memoryDegraded {
+				labels[extension.LabelGPUMemoryDegraded] = "true"
+			}
+			if pcieLink.Generation > 0 {
+				labels[extension.LabelGPUPCIeLinkGeneration] = strconv.Itoa(pcieLink.Generation)
+			}
+			if pcieLink.Width > 0 {
+				labels[extension.LabelGPUPCIeLinkWidth] = strconv.Itoa(pcieLink.Width)
+			}
+			if pcieLink.MaxGeneration > 0 {
+				labels[extension.LabelGPUPCIeLinkMaxGeneration] = strconv.Itoa(pcieLink.MaxGeneration)
+			}
+			if pcieLink.MaxWidth > 0 {
+				labels[extension.LabelGPUPCIeLinkMaxWidth] = strconv.Itoa(pcieLink.MaxWidth)
+			}
+			if pcieLink.isDegraded() {
+				labels[extension.LabelGPUPCIeLinkDegraded] = "true"
+			}
+		}
+
+		resources := map[corev1.ResourceName]resource.Quantity{
+			extension.ResourceGPUCore:        *resource.NewQuantity(s.getGPUCoreGranularity(), resource.DecimalSI),
+			extension.ResourceGPUMemory:      s.gpuMemoryQuantity(gpu.MemoryTotal),
+			extension.ResourceGPUMemoryRatio: *resource.NewQuantity(100, resource.DecimalSI),
+		}
+		// the video engines are shared in percentage like the gpu memory ratio
+		if s.config != nil && s.config.EnableGPUCodecResources {
+			if gpu.EncoderSupported {
+				resources[extension.ResourceGPUEncoder] = *resource.NewQuantity(100, resource.DecimalSI)
+			}
+			if gpu.DecoderSupported {
+				resources[extension.ResourceGPUDecoder] = *resource.NewQuantity(100, resource.DecimalSI)
+			}
+		}
+		// the BAR1 memory is not rounded by the gpu-memory-granularity, since it is not allocated by the device plugin
+		if s.config != nil && s.config.EnableGPUBAR1MemoryResource && gpu.BAR1MemoryTotal > 0 {
+			resources[extension.ResourceGPUBAR1Memory] = *resource.NewQuantity(int64(gpu.BAR1MemoryTotal), resource.BinarySI)
+		}
+		resources = s.mapGPUResourceNames(resources)
+
+		deviceInfo := schedulingv1alpha1.DeviceInfo{
+			UUID:        gpu.UUID,
+			Minor:       &gpu.Minor,
+			Type:        schedulingv1alpha1.GPU,
+			Labels:      labels,
+			Health:      health,
+			HealthState: s.getGPUHealthState(&gpu, health),
+			Resources:   resources,
+			Topology:    topology,
+		}
+		if instances := vgpus[gpu.UUID]; len(instances) > 0 {
+			deviceInfos = append(deviceInfos, s.buildVGPUDevices(&deviceInfo, instances, maxReplicas)...)
+			continue
+		}
+		if maxReplicas <= 1 {
+			deviceInfos = append(deviceInfos, deviceInfo)
+			continue
+		}
+		replicas := s.getGPUTimeSlicingReplicas(gpu.ProductName)
+		deviceInfos = append(deviceInfos, buildGPUReplicas(&deviceInfo, replicas, maxReplicas)...)
+	}
+	return deviceInfos, nil
+}
+
+// listGPUDevices returns the gpus to report. If nvml is available, the gpus queried from nvml are authoritative
+// like the gpu health check, and the gpus collected in the metric cache are layered on top of them by the uuid to
+// add the topology, capabilities and codecs, so that the reporting does not depend on the readiness of the metric
+// cache. Otherwise, the gpus collected in the metric cache are returned as is.
+func (s *statesInformer) listGPUDevices() (koordletuti.GPUDevices, error) {
+	var collected koordletuti.GPUDevices
+	if gpuDeviceInfo, exist := s.metricsCache.Get(koordletuti.GPUDeviceType); exist {
+		var ok bool
+		collected, ok = gpuDeviceInfo.(koordletuti.GPUDevices)
+		if !ok {
+			return nil, fmt.Errorf("value type error, expect: %T, got %T", koordletuti.GPUDevices{}, gpuDeviceInfo)
+		}
+	}
+	if !s.gpuAvailable {
+		return collected, nil
+	}
+
+	gpus, unidentified, err := s.getGPUDevicesFromNVML()
+	if err != nil {
+		return nil, fmt.Errorf("nvml is available but failed to query gpus: %w", err)
+	}
+	return mergeCollectedGPUDevices(gpus, collected, unidentified), nil
+}
+
+// mergeCollectedGPUDevices returns the gpus queried from nvml, where each gpu is replaced by the one collected in the
+// metric cache with the same uuid. The collected gpus not found by nvml are dropped since they may be stale, unless
+// some gpus are unidentified by nvml, in which case they are kept unhealthy since they may be the unidentified ones.
+// The gpus failing to be queried from nvml are kept unhealthy, and dropped if their minors are unknown.
+func mergeCollectedGPUDevices(gpus, collected koordletuti.GPUDevices, unidentified int) koordletuti.GPUDevices {
+	collectedByUUID := make(map[string]int, len(collected))
+	for i := range collected {
+		collectedByUUID[collected[i].UUID] = i
+	}
+	merged := make(koordletuti.GPUDevices, 0, len(gpus)+unidentified)
+	found := make(map[string]struct{}, len(gpus))
+	missed := 0
+	for i := range gpus {
+		found[gpus[i].UUID] = struct{}{}
+		if idx, ok := collectedByUUID[gpus[i].UUID]; ok {
+			gpu := collected[idx]
+			gpu.Unhealthy = gpu.Unhealthy || gpus[i].Unhealthy
+			merged = append(merged, gpu)
+			continue
+		}
+		missed++
+		if gpus[i].Minor < 0 {
+			klog.InfoS("Skip reporting the gpu whose minor is unknown", "uuid", gpus[i].UUID)
+			continue
+		}
+		merged = append(merged, gpus[i])
+	}
+	if unidentified > 0 {
+		for i := range collected {
+			if _, ok := found[collected[i].UUID]; ok {
+				continue
+			}
+			gpu := collected[i]
+			gpu.Unhealthy = true
+			merged = append(merged, gpu)
+		}
+	}
+	if missed > 0 || unidentified > 0 || len(collected) != len(gpus) {
+		klog.V(4).InfoS("Gpu devices are not fully collected, report the gpus queried from nvml",
+			"count", len(gpus), "collected", len(collected), "notCollected", missed, "unidentified", unidentified)
+	}
+	return merged
+}
+
+// gpuIdentity is the identity of the gpu board, which helps the operators to replace the failing cards.
+type gpuIdentity struct {
+	Serial         string
+	InforomVersion string
+	// health is the gpu health when the identity is queried
+	health bool
+}
+
+// getGPUIdentity returns the board identity of the gpu queried from nvml. The identity is queried once and refreshed
+// when the gpu health changes, e.g. the card is marked unhealthy or replaced. It returns an empty identity if nvml is
+// not available, and the fields not supported by the gpu are left empty.
+func (s *statesInformer) getGPUIdentity(uuid string, health bool) gpuIdentity {
+	if !s.gpuAvailable {
+		return gpuIdentity{}
+	}
+	if identity, ok := s.gpuIdentities[uuid]; ok && identity.health == health {
+		return identity
+	}
+
+	if err := s.nvmlBreaker.Err(); err != nil {
+		klog.V(4).Infof("skip querying the identity of gpu %s, err: %v", uuid, err)
+		return s.gpuIdentities[uuid]
+	}
+	gpuDevice, ret := s.nvml.DeviceGetHandleByUUID(uuid)
+	if err := recordNVML(s.nvmlBreaker, s.nvml, ret); err != nil {
+		// retry in the next reporting
+		klog.V(4).Infof("failed to get gpu %s to query the identity, err: %v", uuid, err)
+		return s.gpuIdentities[uuid]
+	}
+	identity := gpuIdentity{health: health}
+	if serial, ret := gpuDevice.GetSerial(); ret == nvml.SUCCESS {
+		identity.Serial = serial
+	} else {
+		klog.V(4).Infof("failed to get the serial of gpu %s, err: %v", uuid, nvmlError(s.nvml, ret))
+	}
+	if version, ret := gpuDevice.GetInforomVersion(nvml.INFOROM_OEM); ret == nvml.SUCCESS {
+		identity.InforomVersion = version
+	} else {
+		klog.V(4).Infof("failed to get the inforom version of gpu %s, err: %v", uuid, nvmlError(s.nvml, ret))
+	}
+	if s.gpuIdentities == nil {
+		s.gpuIdentities = map[string]gpuIdentity{}
+	}
+	s.gpuIdentities[uuid] = identity
+	return identity
+}
+
+// gpuPCIeLink is the current and max pcie link of the gpu, where the fields not supported by the gpu are 0.
+type gpuPCIeLink struct {
+	Generation    int
+	Width         int
+	MaxGeneration int
+	MaxWidth      int
+}
+
+// isDegraded returns true if the gpu runs at a narrower link width than the max. The link generation is not
+// compared since the gpu lowers it to save power when idle.
+func (l gpuPCIeLink) isDegraded() bool {
+	return l.Width > 0 && l.MaxWidth > 0 && l.Width < l.MaxWidth
+}
+
+// getGPUPCIeLink returns the pcie link of the gpu queried from nvml, and warns once when the link becomes degraded,
+// which is a common sign of a poorly seated card or a hardware issue. The link is queried in each reporting since
+// it can change at runtime, and an empty link is returned if nvml is not available.
+func (s *statesInformer) getGPUPCIeLink(uuid string) gpuPCIeLink {
+	if !s.gpuAvailable {
+		return gpuPCIeLink{}
+	}
+	if err := s.nvmlBreaker.Err(); err != nil {
+		klog.V(4).Infof("skip querying the pcie link of gpu %s, err: %v", uuid, err)
+		return gpuPCIeLink{}
+	}
+	gpuDevice, ret := s.nvml.DeviceGetHandleByUUID(uuid)
+	if err := recordNVML(s.nvmlBreaker, s.nvml, ret); err != nil {
+		klog.V(4).Infof("failed to get gpu %s to query the pcie link, err: %v", uuid, err)
+		return gpuPCIeLink{}
+	}
+	var link gpuPCIeLink
+	if generation, ret := gpuDevice.GetCurrPcieLinkGeneration(); ret == nvml.SUCCESS {
+		link.Generation = generation
+	}
+	if width, ret := gpuDevice.GetCurrPcieLinkWidth(); ret == nvml.SUCCESS {
+		link.Width = width
+	}
+	if generation, ret := gpuDevice.GetMaxPcieLinkGeneration(); ret == nvml.SUCCESS {
+		link.MaxGeneration = generation
+	}
+	if width, ret := gpuDevice.GetMaxPcieLinkWidth(); ret == nvml.SUCCESS {
+		link.MaxWidth = width
+	}
+
+	if link.isDegraded() {
+		if !s.gpuPCIeLinkDegraded[uuid] {
+			klog.Warningf("gpu %s is running at degraded pcie link x%d (gen %d), max x%d (gen %d), the card may need reseating",
+				uuid, link.Width, link.Generation, link.MaxWidth, link.MaxGeneration)
+			if s.gpuPCIeLinkDegraded == nil {
+				s.gpuPCIeLinkDegraded = map[string]bool{}
+			}
+			s.gpuPCIeLinkDegraded[uuid] = true
+		}
+	} else if s.gpuPCIeLinkDegraded[uuid] {
+		klog.Infof("gpu %s pcie link recovered to x%d (gen %d)", uuid, link.Width, link.Generation)
+		delete(s.gpuPCIeLinkDegraded, uuid)
+	}
+	return link
+}
+
+// getGPUP2PGroups returns the peer-access group of each gpu by the uuid, where the gpus connected by the p2p read
+// capability directly or through the other gpus are grouped together, and the groups are numbered in the order of
+// their first gpus. The gpus without any peer are not grouped. The groups are queried from nvml once and recomputed
+// only if the gpus change, and nil is returned if nvml is not available or the groups fail to be queried.
+func (s *statesInformer) getGPUP2PGroups(gpus koordletuti.GPUDevices) map[string]string {
+	if !s.gpuAvailable || len(gpus) < 2 {
+		return nil
+	}
+	uuids := make([]string, 0, len(gpus))
+	for idx := range gpus {
+		uuids = append(uuids, gpus[idx].UUID)
+	}
+	sort.Strings(uuids)
+	key := strings.Join(uuids, ",")
+	if s.gpuP2PGroupsKey == key {
+		return s.gpuP2PGroups
+	}
+
+	if err := s.nvmlBreaker.Err(); err != nil {
+		klog.V(4).Infof("skip querying the p2p status of gpus, err: %v", err)
+		return nil
+	}
+	handles := make([]nvmlDevice, 0, len(gpus))
+	for idx := range gpus {
+		gpuDevice, ret := s.nvml.DeviceGetHandleByUUID(gpus[idx].UUID)
+		if err := recordNVML(s.nvmlBreaker, s.nvml, ret); err != nil {
+			// retry in the next reporting
+			klog.V(4).Infof("failed to get gpu %s to query the p2p status, err: %v", gpus[idx].UUID, err)
+			return nil
+		}
+		handles = append(handles, gpuDevice)
+	}
+	// union the gpus by the p2p capability, where each gpu points to the first gpu of its group
+	parents := make([]int, len(gpus))
+	for i := range parents {
+		parents[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parents[i] != i {
+			parents[i] = find(parents[i])
+		}
+		return parents[i]
+	}
+	for i := range handles {
+		for j := i + 1; j < len(handles); j++ {
+			status, ret := handles[i].GetP2PStatus(handles[j], nvml.P2P_CAPS_INDEX_READ)
+			if ret != nvml.SUCCESS || status != nvml.P2P_STATUS_OK {
+				continue
+			}
+			if ri, rj := find(i), find(j); ri < rj {
+				parents[rj] = ri
+			} else if rj < ri {
+				parents[ri] = rj
+			}
+		}
+	}
+	groupSizes := make([]int, len(gpus))
+	for i := range gpus {
+		groupSizes[find(i)]++
+	}
+	groups := map[string]string{}
+	groupIDs := map[int]string{}
+	for i := range gpus {
+		root := find(i)
+		if groupSizes[root] < 2 {
+			continue
+		}
+		groupID, ok := groupIDs[root]
+		if !ok {
+			groupID = strconv.Itoa(len(groupIDs))
+			groupIDs[root] = groupID
+		}
+		groups[gpus[i].UUID] = groupID
+	}
+	klog.V(4).InfoS("Gpu p2p groups are recomputed", "gpus", len(gpus), "groups", len(groupIDs))
+	s.gpuP2PGroupsKey = key
+	s.gpuP2PGroups = groups
+	return groups
+}
+
+// pruneUnhealthyGPUs drops the unhealthy gpus no longer discovered by nvml, e.g. the uuids changed by the MIG
+// reconfiguration, so that the unhealthy gpus are bounded by the present gpus. The unhealthy gpus are recorded as
+// the metrics.
+func (s *statesInformer) pruneUnhealthyGPUs(gpus koordletuti.GPUDevices) {
+	present := sets.NewString()
+	for idx := range gpus {
+		present.Insert(gpus[idx].UUID)
+	}
+	var transitions []statesinformer.GPUHealthTransition
+	s.gpuMutex.Lock()
+	// the gpus collected in the metric cache may be incomplete without nvml
+	if s.gpuAvailable {
+		for uuid := range s.unhealthyGPU {
+			if !present.Has(uuid) {
+				klog.V(4).InfoS("Prune the unhealthy gpu no longer discovered", "deviceUUID", uuid)
+				delete(s.unhealthyGPU, uuid)
+				transitions = append(transitions, statesinformer.GPUHealthTransition{
+					DeviceUUID: uuid,
+					OldHealthy: false,
+					NewHealthy: true,
+					Reason:     statesinformer.GPUHealthReasonNotDiscovered,
+				})
+			}
+		}
+	}
+	metrics.ResetGPUUnhealthy()
+	for uuid := range s.unhealthyGPU {
+		metrics.RecordGPUUnhealthy(uuid)
+	}
+	s.gpuMutex.Unlock()
+	sort.Slice(transitions, func(i, j int) bool {
+		return transitions[i].DeviceUUID < transitions[j].DeviceUUID
+	})
+	s.notifyGPUHealthTransitions(transitions)
+}
+
+// getNUMANodeSockets returns the socket of each NUMA node collected in the node cpu info, or an empty map if the
+// cpu info is not collected yet.
+func (s *statesInformer) getNUMANodeSockets() map[int32]int32 {
+	sockets := map[int32]int32{}
+	nodeCPUInfoRaw, exist := s.metricsCache.Get(metriccache.NodeCPUInfoKey)
+	if !exist {
+		klog.V(4).Infof("node cpu info not exist, the sockets of the gpus are unknown")
+		return sockets
+	}
+	nodeCPUInfo, ok := nodeCPUInfoRaw.(*metriccache.NodeCPUInfo)
+	if !ok {
+		klog.Warningf("value type error, expect: %T, got %T", &metriccache.NodeCPUInfo{}, nodeCPUInfoRaw)
+		return sockets
+	}
+	for _, p := range nodeCPUInfo.ProcessorInfos {
+		sockets[p.NodeID] = p.SocketID
+	}
+	return sockets
+}
+
+// getReservedGPUs returns the UUIDs and minors of the gpus reserved by the node annotation.
+func getReservedGPUs(node *corev1.Node) sets.String {
+	reserved := sets.NewString()
+	if node == nil {
+		return reserved
+	}
+	for _, v := range strings.Split(node.Annotations[extension.AnnotationReservedGPUs], ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			reserved.Insert(v)
+		}
+	}
+	return reserved
+}
+
+// getNodeProblemGPUs returns the UUIDs of the gpus faulted by the node conditions of the gpu-health-node-conditions,
+// which are set by the node-problem-detector. A true condition faults the gpus whose uuids are named in its message,
+// or all gpus if it names none of them, e.g. the driver failure.
+func (s *statesInformer) getNodeProblemGPUs(node *corev1.Node, gpus koordletuti.GPUDevices) sets.String {
+	faulted := sets.NewString()
+	if node == nil || s.config == nil || s.config.GPUHealthNodeConditions == "" {
+		return faulted
+	}
+	conditionTypes := sets.NewString()
+	for _, v := range strings.Split(s.config.GPUHealthNodeConditions, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			conditionTypes.Insert(v)
+		}
+	}
+	for _, condition := range node.Status.Conditions {
+		if !conditionTypes.Has(string(condition.Type)) || condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		named := sets.NewString()
+		for _, gpu := range gpus {
+			if gpu.UUID != "" && strings.Contains(condition.Message, gpu.UUID) {
+				named.Insert(gpu.UUID)
+			}
+		}
+		if named.Len() == 0 {
+			for _, gpu := range gpus {
+				named.Insert(gpu.UUID)
+			}
+		}
+		klog.V(4).InfoS("Gpus are faulted by the node condition", "condition", condition.Type,
+			"reason", condition.Reason, "gpus", named.List())
+		faulted = faulted.Union(named)
+	}
+	return faulted
+}
+
+// getGPUDevicesFromNVML returns the gpus with the minimal information queried from nvml, i.e. the uuid, minor,
+// memory and product name. The topology, capabilities and codecs are left to be reported once collected.
+// A gpu failing to be queried does not fail the others: the gpu whose uuid is known is returned unhealthy, and
+// the others are counted as unidentified. It returns an error only if no gpu is queried.
+func (s *statesInformer) getGPUDevicesFromNVML() (koordletuti.GPUDevices, int, error) {
+	if err := s.nvmlBreaker.Err(); err != nil {
+		return nil, 0, err
+	}
+	count, ret := s.nvml.DeviceGetCount()
+	if err := recordNVML(s.nvmlBreaker, s.nvml, ret); err != nil {
+		return nil, 0, fmt.Errorf("unable to get device count: %w", err)
+	}
+	if count == 0 {
+		return nil, 0, fmt.Errorf("no gpu device found")
+	}
+	gpus := make(koordletuti.GPUDevices, 0, count)
+	unidentified := 0
+	for deviceIndex := 0; deviceIndex < count; deviceIndex++ {
+		gpu, err := s.getGPUDeviceFromNVML(deviceIndex)
+		if err != nil {
+			klog.ErrorS(err, "Failed to identify gpu from nvml", "index", deviceIndex)
+			unidentified++
+			continue
+		}
+		gpus = append(gpus, *gpu)
 	}
-	if !errors.IsNotFound(err) {
-		klog.Errorf("Failed to updateDevice %s, err: %v", node.Name, err)
-		return
+	if len(gpus) == 0 {
+		return nil, 0, fmt.Errorf("unable to identify any of the %d gpus", count)
 	}
+	return gpus, unidentified, nil
+}
 
-	err = s.createDevice(device)
-	if err == nil {
-		klog.V(4).Infof("successfully create Device %s", node.Name)
+// getGPUDeviceFromNVML returns the gpu at the index queried from nvml. It returns an error if the gpu cannot be
+// identified by the uuid, and the gpu marked unhealthy if the others fail to be queried, where the unknown minor
+// is -1.
+func (s *statesInformer) getGPUDeviceFromNVML(deviceIndex int) (*koordletuti.GPUDeviceInfo, error) {
+	gpuDevice, ret := s.nvml.DeviceGetHandleByIndex(deviceIndex)
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("unable to get device at index %d: %w", deviceIndex, nvmlError(s.nvml, ret))
+	}
+	uuid, ret := gpuDevice.GetUUID()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("unable to get device uuid at index %d: %w", deviceIndex, nvmlError(s.nvml, ret))
+	}
+	gpu := &koordletuti.GPUDeviceInfo{
+		UUID:   uuid,
+		Minor:  -1,
+		NodeID: -1,
+	}
+	if minor, ret := gpuDevice.GetMinorNumber(); ret == nvml.SUCCESS {
+		gpu.Minor = int32(minor)
+	} else {
+		klog.ErrorS(nvmlError(s.nvml, ret), "Failed to get gpu minor number, mark it unhealthy", "uuid", uuid)
+		gpu.Unhealthy = true
+	}
+	if memory, ret := gpuDevice.GetMemoryInfo(); ret == nvml.SUCCESS {
+		gpu.MemoryTotal = memory.Total
 	} else {
-		klog.Errorf("Failed to create Device %s, err: %v", node.Name, err)
+		klog.ErrorS(nvmlError(s.nvml, ret), "Failed to get gpu memory info, mark it unhealthy", "uuid", uuid)
+		gpu.Unhealthy = true
 	}
+	// the product name keeps the time-slicing replicas stable
+	if name, ret := gpuDevice.GetName(); ret == nvml.SUCCESS {
+		gpu.ProductName = koordletuti.FormatGPUProductName(name)
+	}
+	return gpu, nil
 }
 
-func (s *statesInformer) buildBasicDevice(node *corev1.Node) *schedulingv1alpha1.Device {
-	blocker := true
-	device := &schedulingv1alpha1.Device{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: node.Name,
-			OwnerReferences: []metav1.OwnerReference{
-				{
-					APIVersion:         "v1",
-					Kind:               "Node",
-					Name:               node.Name,
-					UID:                node.UID,
-					Controller:         &blocker,
-					BlockOwnerDeletion: &blocker,
-				},
-			},
-		},
+// mapGPUResourceNames renames the gpu resources with the configured names, e.g. to co-exist with other device plugins.
+func (s *statesInformer) mapGPUResourceNames(resources map[corev1.ResourceName]resource.Quantity) map[corev1.ResourceName]resource.Quantity {
+	if s.config == nil || len(s.config.GPUResourceNameMapping) == 0 {
+		return resources
 	}
-
-	return device
+	mapped := make(map[corev1.ResourceName]resource.Quantity, len(resources))
+	for name, quantity := range resources {
+		if alias := s.config.GPUResourceNameMapping[string(name)]; alias != "" {
+			name = corev1.ResourceName(alias)
+		}
+		mapped[name] = quantity
+	}
+	return mapped
 }
 
-func (s *statesInformer) fillGPUDevice(device *schedulingv1alpha1.Device,
-	gpuDevices []schedulingv1alpha1.DeviceInfo, gpuModel string, gpuDriverVer string) {
-
-	device.Spec.Devices = append(device.Spec.Devices, gpuDevices...)
-	if device.Labels == nil {
-		device.Labels = make(map[string]string)
+// getGPUTimeSlicingReplicas returns the configured time-slicing replicas of the gpu model, and 1 if not configured.
+func (s *statesInformer) getGPUTimeSlicingReplicas(productName string) int32 {
+	if s.config == nil || productName == "" {
+		return 1
 	}
-	if gpuModel != "" {
-		device.Labels[extension.LabelGPUModel] = gpuModel
+	value, ok := s.config.GPUTimeSlicingReplicas[productName]
+	if !ok {
+		return 1
 	}
-	if gpuDriverVer != "" {
-		device.Labels[extension.LabelGPUDriverVersion] = gpuDriverVer
+	replicas, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || replicas < 1 {
+		klog.Warningf("invalid gpu time-slicing replicas %q of model %s, report it as one device", value, productName)
+		return 1
 	}
+	return int32(replicas)
 }
 
-func (s *statesInformer) createDevice(device *schedulingv1alpha1.Device) error {
-	_, err := s.deviceClient.Create(context.TODO(), device, metav1.CreateOptions{})
-	return err
-}
-
-func (s *statesInformer) updateDevice(device *schedulingv1alpha1.Device) error {
-	sorter := func(devices []schedulingv1alpha1.DeviceInfo) {
-		sort.Slice(devices, func(i, j int) bool {
-			if devices[i].Type != devices[j].Type {
-				return devices[i].Type < devices[j].Type
-			}
-			return *(devices[i].Minor) < *(devices[j].Minor)
-		})
-	}
-	sorter(device.Spec.Devices)
-
-	return util.RetryOnConflictOrTooManyRequests(func() error {
-		latestDevice, err := s.deviceClient.Get(context.TODO(), device.Name, metav1.GetOptions{ResourceVersion: "0"})
-		if err != nil {
-			return err
+// buildGPUReplicas splits a time-sliced gpu into the replicas which are scheduled as distinct devices.
+// The replicas share the physical gpu, so each of them reports the whole resources of the gpu, and records the
+// physical uuid, minor and its replica index in the labels. The minor of the replica is the physical minor scaled
+// by the max replicas on the node plus the replica index to keep it unique on the node.
+// The gpu which is not time-sliced is reported as one replica with the original uuid.
+func buildGPUReplicas(gpu *schedulingv1alpha1.DeviceInfo, replicas, maxReplicas int32) []schedulingv1alpha1.DeviceInfo {
+	deviceInfos := make([]schedulingv1alpha1.DeviceInfo, 0, replicas)
+	for i := int32(0); i < replicas; i++ {
+		replica := gpu.DeepCopy()
+		if replicas > 1 {
+			replica.UUID = fmt.Sprintf("%s::%d", gpu.UUID, i)
 		}
-		sorter(latestDevice.Spec.Devices)
-
-		if apiequality.Semantic.DeepEqual(device.Spec.Devices, latestDevice.Spec.Devices) &&
-			apiequality.Semantic.DeepEqual(device.Labels, latestDevice.Labels) {
-			klog.V(4).Infof("Device %s has not changed and does not need to be updated", device.Name)
-			return nil
+		replica.Minor = pointer.Int32(*gpu.Minor*maxReplicas + i)
+		if replica.Labels == nil {
+			replica.Labels = map[string]string{}
 		}
+		replica.Labels[extension.LabelGPUPhysicalUUID] = gpu.UUID
+		replica.Labels[extension.LabelGPUPhysicalMinor] = strconv.Itoa(int(*gpu.Minor))
+		replica.Labels[extension.LabelGPUReplicaIndex] = strconv.Itoa(int(i))
+		deviceInfos = append(deviceInfos, *replica)
+	}
+	return deviceInfos
+}
 
-		latestDevice.Spec.Devices = device.Spec.Devices
-		latestDevice.Labels = device.Labels
-
-		_, err = s.deviceClient.Update(context.TODO(), latestDevice, metav1.UpdateOptions{})
-		return err
-	})
+// gpuVGPU is a vGPU instance reported in place of its physical gpu.
+type gpuVGPU struct {
+	UUID    string
+	Profile string
+	// FramebufferSize is the framebuffer of the vGPU profile in bytes
+	FramebufferSize uint64
 }
 
-func (s *statesInformer) buildGPUDevice() []schedulingv1alpha1.DeviceInfo {
-	//queryParam := generateQueryParam()
-	gpuDeviceInfo, exist := s.metricsCache.Get(koordletuti.GPUDeviceType)
-	if !exist {
-		klog.V(4).Infof("gpu device not exist")
-		return nil
+// getActiveVGPUs returns the active vGPU instances of the gpus by the uuid if the vGPU reporting is enabled, where
+// the gpus of a host not virtualized have no vGPU instances. It returns an error if the vGPU instances fail to be
+// queried, since reporting the physical gpus instead would remove the vGPUs of the existing Device.
+func (s *statesInformer) getActiveVGPUs(gpus koordletuti.GPUDevices) (map[string][]gpuVGPU, error) {
+	if !s.gpuAvailable || s.config == nil || !s.config.EnableVGPUReport {
+		return nil, nil
 	}
-	gpus, ok := gpuDeviceInfo.(koordletuti.GPUDevices)
-	if !ok {
-		klog.Errorf("value type error, expect: %T, got %T", koordletuti.GPUDevices{}, gpuDeviceInfo)
-		return nil
+	if err := s.nvmlBreaker.Err(); err != nil {
+		return nil, err
 	}
-
-	var deviceInfos []schedulingv1alpha1.DeviceInfo
+	vgpus := map[string][]gpuVGPU{}
 	for idx := range gpus {
-		gpu := gpus[idx]
-		health := true
-		s.gpuMutex.RLock()
-		if _, ok := s.unhealthyGPU[gpu.UUID]; ok {
-			health = false
+		uuid := gpus[idx].UUID
+		gpuDevice, ret := s.nvml.DeviceGetHandleByUUID(uuid)
+		if err := recordNVML(s.nvmlBreaker, s.nvml, ret); err != nil {
+			return nil, fmt.Errorf("unable to get gpu %s to query the vGPUs: %w", uuid, err)
 		}
-		s.gpuMutex.RUnlock()
-
-		var topology *schedulingv1alpha1.DeviceTopology
-		if gpu.NodeID >= 0 && gpu.PCIE != "" && gpu.BusID != "" {
-			topology = &schedulingv1alpha1.DeviceTopology{
-				SocketID: -1,
-				NodeID:   gpu.NodeID,
-				PCIEID:   gpu.PCIE,
-				BusID:    gpu.BusID,
+		instances, ret := gpuDevice.GetActiveVgpus()
+		if ret == nvml.ERROR_NOT_SUPPORTED {
+			continue
+		}
+		if err := recordNVML(s.nvmlBreaker, s.nvml, ret); err != nil {
+			return nil, fmt.Errorf("unable to get the vGPUs of gpu %s: %w", uuid, err)
+		}
+		for _, instance := range instances {
+			vgpuUUID, ret := instance.GetUUID()
+			if ret != nvml.SUCCESS {
+				return nil, fmt.Errorf("unable to get the vGPU uuid of gpu %s: %w", uuid, nvmlError(s.nvml, ret))
+			}
+			profile, ret := instance.GetTypeName()
+			if ret != nvml.SUCCESS {
+				return nil, fmt.Errorf("unable to get the profile of vGPU %s: %w", vgpuUUID, nvmlError(s.nvml, ret))
 			}
+			framebufferSize, ret := instance.GetFramebufferSize()
+			if ret != nvml.SUCCESS {
+				return nil, fmt.Errorf("unable to get the framebuffer of vGPU %s: %w", vgpuUUID, nvmlError(s.nvml, ret))
+			}
+			vgpus[uuid] = append(vgpus[uuid], gpuVGPU{UUID: vgpuUUID, Profile: profile, FramebufferSize: framebufferSize})
 		}
+		// keep the minors of the vGPUs stable regardless of the order returned by nvml
+		sort.Slice(vgpus[uuid], func(i, j int) bool {
+			return vgpus[uuid][i].UUID < vgpus[uuid][j].UUID
+		})
+	}
+	return vgpus, nil
+}
 
-		deviceInfos = append(deviceInfos, schedulingv1alpha1.DeviceInfo{
-			UUID:   gpu.UUID,
-			Minor:  &gpu.Minor,
-			Type:   schedulingv1alpha1.GPU,
-			Health: health,
-			Resources: map[corev1.ResourceName]resource.Quantity{
-				extension.ResourceGPUCore:        *resource.NewQuantity(100, resource.DecimalSI),
-				extension.ResourceGPUMemory:      *resource.NewQuantity(int64(gpu.MemoryTotal), resource.BinarySI),
-				extension.ResourceGPUMemoryRatio: *resource.NewQuantity(100, resource.DecimalSI),
-			},
-			Topology: topology,
+// buildVGPUDevices returns the vGPU instances of the physical gpu as the gpus, whose minors are scaled like the
+// time-sliced replicas. Each vGPU is a whole gpu with the framebuffer of its profile, and the video engines are not
+// reported since they are shared by the vGPUs of the physical gpu.
+func (s *statesInformer) buildVGPUDevices(gpu *schedulingv1alpha1.DeviceInfo, vgpus []gpuVGPU, maxReplicas int32) []schedulingv1alpha1.DeviceInfo {
+	deviceInfos := make([]schedulingv1alpha1.DeviceInfo, 0, len(vgpus))
+	for i, vgpu := range vgpus {
+		deviceInfo := gpu.DeepCopy()
+		deviceInfo.UUID = vgpu.UUID
+		deviceInfo.Minor = pointer.Int32(*gpu.Minor*maxReplicas + int32(i))
+		if deviceInfo.Labels == nil {
+			deviceInfo.Labels = map[string]string{}
+		}
+		deviceInfo.Labels[extension.LabelGPUPhysicalUUID] = gpu.UUID
+		deviceInfo.Labels[extension.LabelGPUPhysicalMinor] = strconv.Itoa(int(*gpu.Minor))
+		deviceInfo.Labels[extension.LabelGPUVGPUProfile] = vgpu.Profile
+		deviceInfo.Resources = s.mapGPUResourceNames(map[corev1.ResourceName]resource.Quantity{
+			extension.ResourceGPUCore:        *resource.NewQuantity(s.getGPUCoreGranularity(), resource.DecimalSI),
+			extension.ResourceGPUMemory:      s.gpuMemoryQuantity(vgpu.FramebufferSize),
+			extension.ResourceGPUMemoryRatio: *resource.NewQuantity(100, resource.DecimalSI),
 		})
+		deviceInfos = append(deviceInfos, *deviceInfo)
 	}
 	return deviceInfos
 }
@@ -252,22 +1635,89 @@ func (s *statesInformer) buildRDMADevice() []schedulingv1alpha1.DeviceInfo {
 	return deviceInfos
 }
 
+// buildFPGADevice returns the fpga devices collected in the metric cache.
+func (s *statesInformer) buildFPGADevice() []schedulingv1alpha1.DeviceInfo {
+	rawFPGADevices, exist := s.metricsCache.Get(koordletuti.FPGADeviceType)
+	if !exist {
+		klog.V(4).Infof("fpga device not exist")
+		return nil
+	}
+	fpgaDevices, ok := rawFPGADevices.(koordletuti.FPGADevices)
+	if !ok {
+		klog.Errorf("value type error, expect: %T, got %T", koordletuti.FPGADevices{}, rawFPGADevices)
+		return nil
+	}
+	var deviceInfos []schedulingv1alpha1.DeviceInfo
+	for idx := range fpgaDevices {
+		fpga := fpgaDevices[idx]
+		deviceInfos = append(deviceInfos, schedulingv1alpha1.DeviceInfo{
+			UUID:   fpga.ID,
+			Minor:  pointer.Int32(fpga.Minor),
+			Type:   schedulingv1alpha1.FPGA,
+			Health: !fpga.Unhealthy,
+			Resources: map[corev1.ResourceName]resource.Quantity{
+				extension.ResourceFPGA: *resource.NewQuantity(100, resource.DecimalSI),
+			},
+			Topology: &schedulingv1alpha1.DeviceTopology{
+				SocketID: -1,
+				NodeID:   fpga.NodeID,
+				PCIEID:   fpga.PCIE,
+				BusID:    fpga.BusID,
+			},
+		})
+	}
+	return deviceInfos
+}
+
 func (s *statesInformer) initGPU() bool {
-	if ret := nvml.Init(); ret != nvml.SUCCESS {
+	s.gpuInitErr = nil
+	if ret := s.nvml.Init(); ret != nvml.SUCCESS {
 		if ret == nvml.ERROR_LIBRARY_NOT_FOUND {
 			klog.Warning("nvml init failed, library not found")
+			s.gpuInitErr = errNVMLLibraryNotFound
 			return false
 		}
-		klog.Warningf("nvml init failed, return %s", nvml.ErrorString(ret))
+		klog.Warningf("nvml init failed, return %s", s.nvml.ErrorString(ret))
+		s.gpuInitErr = fmt.Errorf("nvml init failed: %w", nvmlError(s.nvml, ret))
 		return false
 	}
 	return true
 }
 
+// gpuShutdownTimeout is the max time to wait for the gpu health check to exit before shutting down nvml.
+var gpuShutdownTimeout = time.Minute
+
+// shutdownGPU shuts down nvml initialized by the initGPU after the gpu health check exits, so that its event set is
+// freed before. nvml is not shut down if the health check does not exit in time, since a nvml call may still hang.
+func (s *statesInformer) shutdownGPU(healthCheckDone <-chan struct{}) {
+	if !s.gpuAvailable {
+		return
+	}
+	select {
+	case <-healthCheckDone:
+	case <-time.After(gpuShutdownTimeout):
+		klog.Warningf("gpu health check does not exit in %v, skip shutting down nvml", gpuShutdownTimeout)
+		return
+	}
+	if ret := s.nvml.Shutdown(); ret != nvml.SUCCESS {
+		klog.Warningf("nvml shutdown failed, return %s", s.nvml.ErrorString(ret))
+		return
+	}
+	klog.V(4).Infof("nvml shutdown successfully")
+}
+
 func (s *statesInformer) getGPUDriverAndModel() (string, string) {
-	count, ret := nvml.DeviceGetCount()
-	if ret != nvml.SUCCESS {
-		klog.Errorf("unable to get device count: %v", nvml.ErrorString(ret))
+	// the gpus may be discovered without nvml, e.g. Intel gpus
+	if !s.gpuAvailable {
+		return "", ""
+	}
+	if err := s.nvmlBreaker.Err(); err != nil {
+		klog.V(4).Infof("skip getting gpu driver and model: %v", err)
+		return "", ""
+	}
+	count, ret := s.nvml.DeviceGetCount()
+	if err := recordNVML(s.nvmlBreaker, s.nvml, ret); err != nil {
+		klog.Errorf("unable to get device count: %v", err)
 		return "", ""
 	}
 
@@ -278,9 +1728,9 @@ func (s *statesInformer) getGPUDriverAndModel() (string, string) {
 
 	var modelList []string
 	for deviceIndex := 0; deviceIndex < count; deviceIndex++ {
-		gpuDevice, ret := nvml.DeviceGetHandleByIndex(deviceIndex)
+		gpuDevice, ret := s.nvml.DeviceGetHandleByIndex(deviceIndex)
 		if ret != nvml.SUCCESS {
-			klog.Errorf("unable to get device model: %v", nvml.ErrorString(ret))
+			klog.Errorf("unable to get device model: %v", s.nvml.ErrorString(ret))
 			continue
 		}
 		deviceModel, _ := gpuDevice.GetName()
@@ -313,114 +1763,425 @@ func (s *statesInformer) getGPUDriverAndModel() (string, string) {
 	// GeForce GTX 1080 Ti -> GeForce-GTX-1080-Ti
 	transModel := strings.ReplaceAll(model, " ", "-")
 
-	driverVersion, ret := nvml.SystemGetDriverVersion()
+	driverVersion, ret := s.nvml.SystemGetDriverVersion()
 	if ret != nvml.SUCCESS {
-		klog.Errorf("unable to get device driver version: %v", nvml.ErrorString(ret))
+		klog.Errorf("unable to get device driver version: %v", s.nvml.ErrorString(ret))
 		return "", ""
 	}
 
 	return transModel, driverVersion
 }
 
+// getCUDADriverVersion returns the CUDA version supported by the gpu driver, e.g. "12.2". The version is queried
+// once and cached, and re-queried only if the gpu driver version changes, e.g. the driver is upgraded.
+// It returns an empty string if the driver version is unknown or the query fails.
+func (s *statesInformer) getCUDADriverVersion(driverVersion string) string {
+	if !s.gpuAvailable || driverVersion == "" {
+		return ""
+	}
+	if driverVersion == s.cudaDriverVersionOf {
+		return s.cudaDriverVersion
+	}
+	if err := s.nvmlBreaker.Err(); err != nil {
+		klog.V(4).Infof("skip getting cuda driver version: %v", err)
+		return ""
+	}
+	version, ret := s.nvml.SystemGetCudaDriverVersion()
+	if err := recordNVML(s.nvmlBreaker, s.nvml, ret); err != nil {
+		klog.Errorf("unable to get cuda driver version: %v", err)
+		return ""
+	}
+	if s.cudaDriverVersionOf != "" {
+		klog.InfoS("GPU driver changed, update the cuda driver version", "oldDriver", s.cudaDriverVersionOf,
+			"newDriver", driverVersion, "oldCUDA", s.cudaDriverVersion, "newCUDA", formatCUDADriverVersion(version))
+	}
+	s.cudaDriverVersion = formatCUDADriverVersion(version)
+	s.cudaDriverVersionOf = driverVersion
+	return s.cudaDriverVersion
+}
+
+// formatCUDADriverVersion formats the CUDA version returned by nvml, e.g. 12020 to "12.2".
+func formatCUDADriverVersion(version int) string {
+	return fmt.Sprintf("%d.%d", version/1000, version%1000/10)
+}
+
 func (s *statesInformer) gpuHealCheck(stopCh <-chan struct{}) {
-	count, ret := nvml.DeviceGetCount()
-	if ret != nvml.SUCCESS {
-		klog.Errorf("unable to get device count: %v", nvml.ErrorString(ret))
+	var nodeName string
+	if node := s.GetNode(); node != nil {
+		nodeName = node.Name
+	}
+	count, ret := s.nvml.DeviceGetCount()
+	if err := recordNVML(s.nvmlBreaker, s.nvml, ret); err != nil {
+		klog.ErrorS(err, "Unable to get device count", "node", nodeName)
 		return
 	}
 	if count == 0 {
-		klog.Errorf("no gpu device found")
+		klog.ErrorS(nil, "No gpu device found", "node", nodeName)
 		return
 	}
-	devices := []string{}
-	for deviceIndex := 0; deviceIndex < count; deviceIndex++ {
-		gpudevice, ret := nvml.DeviceGetHandleByIndex(deviceIndex)
+	devices := discoverGPUDevices(s.nvml, nodeName, count, gpuDiscoveryParallelism)
+	// the unhealthyChan is never closed since a stuck health check may still send to it after restarted
+	unhealthyChan := make(chan unhealthyGPUEvent)
+	superviseDone := make(chan struct{})
+	go func() {
+		defer close(superviseDone)
+		s.superviseGPUHealthCheck(stopCh, nodeName, devices, unhealthyChan)
+	}()
+	klog.InfoS("Start to do gpu health check", "node", nodeName)
+	for {
+		select {
+		case <-stopCh:
+			// wait for the event set to be freed before nvml is shut down
+			<-superviseDone
+			return
+		case e := <-unhealthyChan:
+			d := e.uuid
+			if isDeviceReportPaused(s.GetNode()) {
+				// the xid errors are expected when the driver is under maintenance
+				klog.InfoS("Ignore the unhealthy gpu since the device reporting is paused", "node", nodeName, "deviceUUID", d)
+				continue
+			}
+			// FIXME: there is no way to recover from the Unhealthy state.
+			s.gpuMutex.Lock()
+			_, exist := s.unhealthyGPU[d]
+			s.unhealthyGPU[d] = struct{}{}
+			s.gpuMutex.Unlock()
+			if exist {
+				// the gpu failing continuously is reported only once
+				continue
+			}
+			klog.InfoS("Get an unhealthy gpu", "node", nodeName, "deviceUUID", d, "reason", e.reason, "xid", e.xid)
+			// report the unhealthy gpu immediately instead of waiting for the next resync
+			s.enqueueDevice()
+			s.notifyGPUHealthTransitions([]statesinformer.GPUHealthTransition{{
+				DeviceUUID: d,
+				OldHealthy: true,
+				NewHealthy: false,
+				Reason:     e.reason,
+				Xid:        e.xid,
+			}})
+		}
+	}
+}
+
+// unhealthyGPUEvent is a gpu found unhealthy by the health check.
+type unhealthyGPUEvent struct {
+	uuid string
+	// reason is the cause of the failure, e.g. statesinformer.GPUHealthReasonXidError
+	reason string
+	// xid is the code of the xid error failing the gpu, or zero if not failed by a xid error
+	xid uint64
+}
+
+// gpuDiscoveryParallelism is the max number of the gpus queried concurrently when the gpu health check starts,
+// since getting the handle of a gpu without the persistence mode may take hundreds of milliseconds.
+var gpuDiscoveryParallelism = 8
+
+// discoverGPUDevices returns the uuids of the gpus in the order of their indexes, the gpus failed to query are
+// skipped. The gpus are queried by at most parallelism workers.
+func discoverGPUDevices(lib nvmlInterface, nodeName string, count, parallelism int) []string {
+	// each worker writes its own index, so the slice needs no lock
+	uuids := make([]string, count)
+	workqueue.ParallelizeUntil(context.TODO(), parallelism, count, func(deviceIndex int) {
+		gpudevice, ret := lib.DeviceGetHandleByIndex(deviceIndex)
 		if ret != nvml.SUCCESS {
-			klog.Errorf("unable to get device at index %d: %v", deviceIndex, nvml.ErrorString(ret))
-			continue
+			klog.ErrorS(nvmlError(lib, ret), "Unable to get device", "node", nodeName, "index", deviceIndex)
+			return
 		}
 		uuid, ret := gpudevice.GetUUID()
 		if ret != nvml.SUCCESS {
-			klog.Errorf("failed to get device uuid at index %d, err: %v", deviceIndex, nvml.ErrorString(ret))
+			klog.ErrorS(nvmlError(lib, ret), "Failed to get device uuid", "node", nodeName, "index", deviceIndex)
+			return
+		}
+		uuids[deviceIndex] = uuid
+	})
+	devices := make([]string, 0, count)
+	for _, uuid := range uuids {
+		if uuid != "" {
+			devices = append(devices, uuid)
 		}
-		devices = append(devices, uuid)
-	}
-	unhealthyChan := make(chan string)
-	go checkHealth(stopCh, devices, unhealthyChan)
-	klog.Info("start to do gpu health check")
-	for d := range unhealthyChan {
-		// FIXME: there is no way to recover from the Unhealthy state.
-		s.gpuMutex.Lock()
-		s.unhealthyGPU[d] = struct{}{}
-		s.gpuMutex.Unlock()
-		klog.Infof("get a unhealthy gpu %s", d)
 	}
+	return devices
 }
 
-// check status of gpus, and send unhealthy devices to the unhealthyDeviceChan channel
-func checkHealth(stopCh <-chan struct{}, devs []string, xids chan<- string) {
-	eventSet, ret := nvml.EventSetCreate()
-	if ret != nvml.SUCCESS {
-		klog.Errorf("failed to create event set, err: %v", nvml.ErrorString(ret))
-		os.Exit(1)
-	}
-	defer eventSet.Free()
-
-	for _, d := range devs {
-		device, ret := nvml.DeviceGetHandleByUUID(d)
+// registerGPUEvents registers the xid critical error events of the gpus to the event set, and returns the gpus too
+// old to support the events in the order of the devs. The gpus are looked up by at most parallelism workers, while
+// the registrations are serialized since they modify the same event set.
+func registerGPUEvents(lib nvmlInterface, eventSet nvmlEventSet, nodeName string, devs []string, parallelism int) []string {
+	var registerMutex sync.Mutex
+	unsupported := make([]bool, len(devs))
+	workqueue.ParallelizeUntil(context.TODO(), parallelism, len(devs), func(i int) {
+		d := devs[i]
+		device, ret := lib.DeviceGetHandleByUUID(d)
 		if ret != nvml.SUCCESS {
-			klog.Errorf("failed to get device %s, err: %v", d, nvml.ErrorString(ret))
-			continue
+			klog.ErrorS(nvmlError(lib, ret), "Failed to get device", "node", nodeName, "deviceUUID", d)
+			metrics.RecordGPUHealthCheckRegistrationFailure(metrics.GPUHealthCheckGetDeviceFailed)
+			return
 		}
-		ret = nvml.DeviceRegisterEvents(device, nvml.EventTypeXidCriticalError, eventSet)
+		registerMutex.Lock()
+		ret = device.RegisterEvents(nvml.EventTypeXidCriticalError, eventSet)
+		registerMutex.Unlock()
 		if ret == nvml.ERROR_NOT_SUPPORTED {
-			klog.Infof("Warning: %s is too old to support healthchecking: %v. Marking it unhealthy.", d, nvml.ErrorString(ret))
-			xids <- d
-			continue
+			klog.InfoS("Warning: device is too old to support healthchecking", "node", nodeName, "deviceUUID", d, "reason", lib.ErrorString(ret))
+			metrics.RecordGPUHealthCheckRegistrationFailure(metrics.GPUHealthCheckNotSupported)
+			unsupported[i] = true
+			return
 		}
-
 		if ret != nvml.SUCCESS {
-			klog.Infof("failed to register event for device %s, err: %v", d, nvml.ErrorString(ret))
-			continue
+			klog.ErrorS(nvmlError(lib, ret), "Failed to register event for device", "node", nodeName, "deviceUUID", d)
+			metrics.RecordGPUHealthCheckRegistrationFailure(metrics.GPUHealthCheckRegisterFailed)
+		}
+	})
+	var unsupportedDevs []string
+	for i, d := range devs {
+		if unsupported[i] {
+			unsupportedDevs = append(unsupportedDevs, d)
 		}
 	}
+	return unsupportedDevs
+}
+
+// gpuHealthCheckRestartBackoff is the delay before restarting the gpu health check after it fails.
+var gpuHealthCheckRestartBackoff = 5 * time.Second
 
+// superviseGPUHealthCheck keeps the gpu health check running until the stopCh is closed. The health check is
+// restarted with a new event set if it exits, panics or its event loop gets stale.
+func (s *statesInformer) superviseGPUHealthCheck(stopCh <-chan struct{}, nodeName string, devs []string, xids chan<- unhealthyGPUEvent) {
+	waitTimeout := s.config.GPUHealthCheckWaitTimeout
+	if waitTimeout <= 0 {
+		waitTimeout = defaultGPUHealthCheckWaitTimeout
+	}
+	staleThreshold := s.config.GPUHealthCheckStaleThreshold
+	if staleThreshold <= 0 {
+		staleThreshold = defaultGPUHealthCheckStaleThreshold
+	}
 	for {
+		// the health check is suspended while the GPU subsystem is degraded, and restarted as a probe after the cooldown
+		if s.nvmlBreaker.Allow() {
+			err := runGPUHealthCheck(stopCh, s.nvml, s.nvmlBreaker, s.xidHealthPolicy, nodeName, devs, s.config.MarkUnmonitoredGPUUnhealthy,
+				xids, waitTimeout, staleThreshold)
+			select {
+			case <-stopCh:
+				return
+			default:
+			}
+			klog.ErrorS(err, "GPU health check failed, restart it", "node", nodeName, "backoff", gpuHealthCheckRestartBackoff)
+		}
 		select {
 		case <-stopCh:
 			return
-		default:
+		case <-time.After(gpuHealthCheckRestartBackoff):
 		}
+	}
+}
 
-		e, ret := eventSet.Wait(5000)
-		if ret != nvml.SUCCESS && e.EventType != nvml.EventTypeXidCriticalError {
-			continue
+// runGPUHealthCheck runs the checkHealth in a new goroutine and watches its liveness. It returns nil when the
+// stopCh is closed and the checkHealth exits or gets stale, or an error when the checkHealth exits, panics or does
+// not return from the wait of events beyond the staleThreshold. The goroutine of a stuck checkHealth exits once the
+// wait returns.
+func runGPUHealthCheck(stopCh <-chan struct{}, lib nvmlInterface, breaker *koordletuti.CircuitBreaker, policy XidHealthPolicy,
+	nodeName string, devs []string, markUnmonitoredUnhealthy bool, xids chan<- unhealthyGPUEvent, waitTimeout, staleThreshold time.Duration) error {
+	runStopCh := make(chan struct{})
+	var stopRunOnce sync.Once
+	stopRun := func() {
+		stopRunOnce.Do(func() { close(runStopCh) })
+	}
+	defer stopRun()
+
+	heartbeat := &atomic.Int64{}
+	heartbeat.Store(time.Now().UnixNano())
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("gpu health check panics: %v", r)
+			}
+		}()
+		done <- checkHealth(runStopCh, lib, breaker, policy, nodeName, devs, markUnmonitoredUnhealthy, xids, waitTimeout, heartbeat)
+	}()
+
+	ticker := time.NewTicker(waitTimeout)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			// the checkHealth frees the event set after its wait of events returns
+			stopRun()
+			select {
+			case <-done:
+			case <-time.After(staleThreshold):
+				klog.Warningf("gpu health check does not exit in %v after stopped, node %s", staleThreshold, nodeName)
+			}
+			return nil
+		case err := <-done:
+			if err == nil {
+				err = fmt.Errorf("gpu health check exits unexpectedly")
+			}
+			return err
+		case <-ticker.C:
+			lastWait := time.Unix(0, heartbeat.Load())
+			if time.Since(lastWait) > staleThreshold {
+				return fmt.Errorf("gpu health check is stale, last wait of events returned at %v", lastWait)
+			}
 		}
+	}
+}
 
-		// http://docs.nvidia.com/deploy/xid-errors/index.html#topic_4
-		// Application errors: the GPU should still be healthy
-		if e.EventData == 13 || e.EventData == 31 || e.EventData == 43 || e.EventData == 45 || e.EventData == 68 {
-			continue
+// check status of gpus, and send unhealthy devices to the unhealthyDeviceChan channel until the stopCh is closed.
+// waitTimeout is the timeout of waiting for the events in each loop, which bounds the delay to notice the stopCh.
+// heartbeat records the time in nanoseconds of the last successful wait of events, including the timed out ones.
+// The results of the nvml calls are recorded to the breaker, and it returns an error once the breaker opens.
+// The health of the gpus on each xid error is decided by the policy, which is the DefaultXidHealthPolicy if nil.
+// The gpus too old to support the health events are sent as unhealthy if markUnmonitoredUnhealthy, otherwise they
+// are left healthy without monitoring.
+func checkHealth(stopCh <-chan struct{}, lib nvmlInterface, breaker *koordletuti.CircuitBreaker, policy XidHealthPolicy,
+	nodeName string, devs []string, markUnmonitoredUnhealthy bool, xids chan<- unhealthyGPUEvent, waitTimeout time.Duration, heartbeat *atomic.Int64) error {
+	if waitTimeout <= 0 {
+		waitTimeout = defaultGPUHealthCheckWaitTimeout
+	}
+	if policy == nil {
+		policy = DefaultXidHealthPolicy
+	}
+	xidLogger := newXidEventLogger(gpuXidLogInterval)
+	sendUnhealthy := func(e unhealthyGPUEvent) bool {
+		select {
+		case xids <- e:
+			return true
+		case <-stopCh:
+			return false
 		}
+	}
 
-		uuid, ret := e.Device.GetUUID()
-		if ret != nvml.SUCCESS {
-			klog.Errorf("failed to get uuid of device %s, err: %v", e.ComputeInstanceId, nvml.ErrorString(ret))
+	eventSet, ret := lib.EventSetCreate()
+	if err := recordNVML(breaker, lib, ret); err != nil {
+		return fmt.Errorf("failed to create event set, %w", err)
+	}
+	defer eventSet.Free()
+
+	for _, d := range registerGPUEvents(lib, eventSet, nodeName, devs, gpuDiscoveryParallelism) {
+		if !markUnmonitoredUnhealthy {
+			klog.InfoS("Device is too old to support healthchecking, keep it healthy without monitoring", "node", nodeName, "deviceUUID", d)
 			continue
 		}
+		if !sendUnhealthy(unhealthyGPUEvent{uuid: d, reason: statesinformer.GPUHealthReasonUnmonitored}) {
+			return nil
+		}
+	}
+
+	for {
+		select {
+		case <-stopCh:
+			return nil
+		default:
+		}
 
-		if len(uuid) == 0 {
-			// All devices are unhealthy
-			for _, d := range devs {
-				xids <- d
+		e, ret := eventSet.Wait(uint32(waitTimeout.Milliseconds()))
+		if ret == nvml.SUCCESS || ret == nvml.ERROR_TIMEOUT {
+			heartbeat.Store(time.Now().UnixNano())
+			breaker.RecordSuccess()
+		} else {
+			breaker.RecordFailure(nvmlError(lib, ret))
+			if breaker.IsOpen() {
+				return fmt.Errorf("failed to wait for events, %w", nvmlError(lib, ret))
 			}
+		}
+		// the summaries are flushed at the same time as the event is handled, so that no count is dropped
+		now := time.Now()
+		for _, summary := range xidLogger.flush(now) {
+			klog.InfoS("Get repeated xid errors of device in the last interval", "node", nodeName, "deviceUUID", summary.DeviceUUID,
+				"count", summary.Count, "lastXid", summary.LastXid, "interval", gpuXidLogInterval)
+		}
+		if ret != nvml.SUCCESS {
+			// no event is returned on the timeout or the failure
+			continue
+		}
+		if e.EventType != nvml.EventTypeXidCriticalError {
+			// only the xid critical errors are registered, the other events are not handled by the health check
+			klog.V(4).InfoS("Ignore the gpu event not handled by the health check", "node", nodeName,
+				"eventType", e.EventType, "eventData", e.EventData)
 			continue
 		}
+		// the xid error without device handle is not attributed to a gpu, e.g. affecting the whole node, which is
+		// passed to the policy with an empty uuid
+		var uuid string
+		if e.Device != nil {
+			uuid, ret = e.Device.GetUUID()
+			if ret != nvml.SUCCESS {
+				klog.ErrorS(nvmlError(lib, ret), "Failed to get uuid of device", "node", nodeName, "computeInstanceID", e.ComputeInstanceId, "xid", e.EventData)
+				continue
+			}
+		}
 
-		for _, d := range devs {
-			if d == uuid {
-				xids <- d
+		event := XidEvent{Xid: e.EventData, DeviceUUID: uuid, Timestamp: now}
+		decision := policy(event)
+		var unhealthy []string
+		switch decision {
+		case GPUHealthy:
+			continue
+		case GPUDegraded:
+			// the degraded gpus are failed only if they are confirmed unreachable by a direct query
+			unhealthy = unreachableDevices(lib, eventDevices(devs, uuid))
+		case GPUFailed:
+			unhealthy = eventDevices(devs, uuid)
+		case GPUNodeFailed:
+			unhealthy = devs
+		default:
+			klog.InfoS("Ignore the unknown gpu health decision", "node", nodeName, "deviceUUID", uuid, "xid", e.EventData, "decision", decision)
+			continue
+		}
+		// a failing gpu may throw xid errors continuously, so only the first one in the interval is logged
+		if xidLogger.allow(uuid, e.EventData, now) {
+			klog.InfoS("Get a xid error of device", "node", nodeName, "deviceUUID", uuid, "xid", e.EventData,
+				"decision", decision, "unhealthyDevices", unhealthy)
+		}
+		for _, d := range unhealthy {
+			if !sendUnhealthy(unhealthyGPUEvent{uuid: d, reason: statesinformer.GPUHealthReasonXidError, xid: e.EventData}) {
+				return nil
 			}
 		}
 	}
 }
+
+// eventDevices returns the devices which the event of the uuid is attributed to, i.e. all devices if the uuid is
+// empty, or the device of the uuid if it is found.
+func eventDevices(devs []string, uuid string) []string {
+	if uuid == "" {
+		return devs
+	}
+	for _, d := range devs {
+		if d == uuid {
+			return []string{d}
+		}
+	}
+	return nil
+}
+
+// unreachableDevices returns the devices which cannot be queried by nvml.
+func unreachableDevices(lib nvmlInterface, devs []string) []string {
+	var unreachable []string
+	for _, d := range devs {
+		device, ret := lib.DeviceGetHandleByUUID(d)
+		if ret == nvml.SUCCESS {
+			_, ret = device.GetUUID()
+		}
+		if ret != nvml.SUCCESS {
+			unreachable = append(unreachable, d)
+		}
+	}
+	return unreachable
+}
+
+func nvmlError(lib nvmlInterface, ret nvml.Return) error {
+	return fmt.Errorf("%s", lib.ErrorString(ret))
+}
+
+// recordNVML records the result of a nvml call to the breaker, and returns the error if the call fails.
+func recordNVML(breaker *koordletuti.CircuitBreaker, lib nvmlInterface, ret nvml.Return) error {
+	if ret != nvml.SUCCESS {
+		err := nvmlError(lib, ret)
+		breaker.RecordFailure(err)
+		return err
+	}
+	breaker.RecordSuccess()
+	return nil
+}
@@ -18,21 +18,38 @@ package impl
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	koordletutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util"
 
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/utils/pointer"
 
 	"github.com/koordinator-sh/koordinator/apis/extension"
 	schedulingv1alpha1 "github.com/koordinator-sh/koordinator/apis/scheduling/v1alpha1"
 	schedulingfake "github.com/koordinator-sh/koordinator/pkg/client/clientset/versioned/fake"
+	schedv1alpha1 "github.com/koordinator-sh/koordinator/pkg/client/clientset/versioned/typed/scheduling/v1alpha1"
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/metriccache"
 	mock_metriccache "github.com/koordinator-sh/koordinator/pkg/koordlet/metriccache/mockmetriccache"
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/statesinformer"
 )
 
 func Test_reportGPUDevice(t *testing.T) {
@@ -48,10 +65,17 @@ func Test_reportGPUDevice(t *testing.T) {
 	gpuDeviceInfo = []koordletutil.GPUDeviceInfo{
 		{UUID: "1", Minor: 1, MemoryTotal: 8000},
 		{UUID: "2", Minor: 2, MemoryTotal: 10000},
-		{UUID: "3", Minor: 3, MemoryTotal: 8000, BusID: "0000:00:08.0", NodeID: 0, PCIE: "pci0000:00"},
+		{UUID: "3", Minor: 3, MemoryTotal: 8000, BusID: "0000:00:08.0", NodeID: 0, PCIE: "pci0000:00", ComputeCapability: "8.0", ProductName: "A100-SXM4-80GB"},
 	}
 	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(gpuDeviceInfo, true)
 	mockMetricCache.EXPECT().Get(koordletutil.RDMADeviceType).Return(nil, false)
+	mockMetricCache.EXPECT().Get(koordletutil.FPGADeviceType).Return(nil, false)
+	mockMetricCache.EXPECT().Get(metriccache.NodeCPUInfoKey).Return(&metriccache.NodeCPUInfo{
+		ProcessorInfos: []koordletutil.ProcessorInfo{
+			{CPUID: 0, CoreID: 0, SocketID: 0, NodeID: 0},
+			{CPUID: 1, CoreID: 1, SocketID: 1, NodeID: 1},
+		},
+	}, true).AnyTimes()
 	r := &statesInformer{
 		deviceClient: fakeClient,
 		metricsCache: mockMetricCache,
@@ -69,10 +93,11 @@ func Test_reportGPUDevice(t *testing.T) {
 	r.reportDevice()
 	expectedDevices := []schedulingv1alpha1.DeviceInfo{
 		{
-			UUID:   "1",
-			Minor:  pointer.Int32(1),
-			Type:   schedulingv1alpha1.GPU,
-			Health: true,
+			UUID:        "1",
+			Minor:       pointer.Int32(1),
+			Type:        schedulingv1alpha1.GPU,
+			Health:      true,
+			HealthState: schedulingv1alpha1.DeviceHealthStateHealthy,
 			Resources: map[corev1.ResourceName]resource.Quantity{
 				extension.ResourceGPUCore:        *resource.NewQuantity(100, resource.DecimalSI),
 				extension.ResourceGPUMemory:      *resource.NewQuantity(8000, resource.BinarySI),
@@ -80,10 +105,11 @@ func Test_reportGPUDevice(t *testing.T) {
 			},
 		},
 		{
-			UUID:   "2",
-			Minor:  pointer.Int32(2),
-			Type:   schedulingv1alpha1.GPU,
-			Health: true,
+			UUID:        "2",
+			Minor:       pointer.Int32(2),
+			Type:        schedulingv1alpha1.GPU,
+			Health:      true,
+			HealthState: schedulingv1alpha1.DeviceHealthStateHealthy,
 			Resources: map[corev1.ResourceName]resource.Quantity{
 				extension.ResourceGPUCore:        *resource.NewQuantity(100, resource.DecimalSI),
 				extension.ResourceGPUMemory:      *resource.NewQuantity(10000, resource.BinarySI),
@@ -91,17 +117,22 @@ func Test_reportGPUDevice(t *testing.T) {
 			},
 		},
 		{
-			UUID:   "3",
-			Minor:  pointer.Int32(3),
-			Type:   schedulingv1alpha1.GPU,
-			Health: true,
+			UUID:  "3",
+			Minor: pointer.Int32(3),
+			Type:  schedulingv1alpha1.GPU,
+			Labels: map[string]string{
+				extension.LabelGPUComputeCapability: "8.0",
+				extension.LabelGPUProductName:       "A100-SXM4-80GB",
+			},
+			Health:      true,
+			HealthState: schedulingv1alpha1.DeviceHealthStateHealthy,
 			Resources: map[corev1.ResourceName]resource.Quantity{
 				extension.ResourceGPUCore:        *resource.NewQuantity(100, resource.DecimalSI),
 				extension.ResourceGPUMemory:      *resource.NewQuantity(8000, resource.BinarySI),
 				extension.ResourceGPUMemoryRatio: *resource.NewQuantity(100, resource.DecimalSI),
 			},
 			Topology: &schedulingv1alpha1.DeviceTopology{
-				SocketID: -1,
+				SocketID: 0,
 				NodeID:   0,
 				PCIEID:   "pci0000:00",
 				BusID:    "0000:00:08.0",
@@ -110,7 +141,7 @@ func Test_reportGPUDevice(t *testing.T) {
 	}
 	device, err := fakeClient.Get(context.TODO(), "test", metav1.GetOptions{})
 	assert.Equal(t, nil, err)
-	assert.Equal(t, device.Spec.Devices, expectedDevices)
+	assertDevicesEqual(t, expectedDevices, device.Spec.Devices)
 
 	gpuDeviceInfo = append(gpuDeviceInfo, koordletutil.GPUDeviceInfo{
 		UUID:        "4",
@@ -130,13 +161,15 @@ func Test_reportGPUDevice(t *testing.T) {
 	}
 	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(gpuDeviceInfo, true)
 	mockMetricCache.EXPECT().Get(koordletutil.RDMADeviceType).Return(rdmaDeviceInfo, true)
+	mockMetricCache.EXPECT().Get(koordletutil.FPGADeviceType).Return(nil, false)
 	r.reportDevice()
 
 	expectedDevices = append(expectedDevices, schedulingv1alpha1.DeviceInfo{
-		UUID:   "4",
-		Minor:  pointer.Int32(4),
-		Type:   schedulingv1alpha1.GPU,
-		Health: true,
+		UUID:        "4",
+		Minor:       pointer.Int32(4),
+		Type:        schedulingv1alpha1.GPU,
+		Health:      true,
+		HealthState: schedulingv1alpha1.DeviceHealthStateHealthy,
 		Resources: map[corev1.ResourceName]resource.Quantity{
 			extension.ResourceGPUCore:        *resource.NewQuantity(100, resource.DecimalSI),
 			extension.ResourceGPUMemory:      *resource.NewQuantity(10000, resource.BinarySI),
@@ -160,7 +193,3159 @@ func Test_reportGPUDevice(t *testing.T) {
 	})
 	device, err = fakeClient.Get(context.TODO(), "test", metav1.GetOptions{})
 	assert.Equal(t, nil, err)
-	assert.Equal(t, device.Spec.Devices, expectedDevices)
+	assertDevicesEqual(t, expectedDevices, device.Spec.Devices)
 	assert.Equal(t, device.Labels[extension.LabelGPUModel], "A100")
 	assert.Equal(t, device.Labels[extension.LabelGPUDriverVersion], "470")
 }
+
+// assertDevicesEqual asserts the devices are semantically equal regardless of the last report time, since the
+// quantities of the patched Device are decoded from json in a format different from the built ones.
+func assertDevicesEqual(t *testing.T, expected, actual []schedulingv1alpha1.DeviceInfo) {
+	actual = clearDeviceReportTime(actual)
+	assert.True(t, apiequality.Semantic.DeepEqual(expected, actual), "expected devices %+v, got %+v", expected, actual)
+}
+
+// clearDeviceReportTime clears the last report time of the devices, which is the current time of the report.
+func clearDeviceReportTime(devices []schedulingv1alpha1.DeviceInfo) []schedulingv1alpha1.DeviceInfo {
+	for i := range devices {
+		devices[i].LastReportTime = nil
+	}
+	return devices
+}
+
+func Test_reportDeviceKeepGPUsWhenGPUCollectFailed(t *testing.T) {
+	testNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	reportedGPU := schedulingv1alpha1.DeviceInfo{
+		UUID:   "1",
+		Minor:  pointer.Int32(1),
+		Type:   schedulingv1alpha1.GPU,
+		Health: true,
+	}
+	rdmaDeviceInfo := koordletutil.RDMADevices{
+		{ID: "0000:00:09.0", BusID: "0000:00:09.0", Minor: 0, NodeID: 0},
+	}
+	tests := []struct {
+		name           string
+		existingDevice *schedulingv1alpha1.Device
+		wantDevices    []schedulingv1alpha1.DeviceInfo
+	}{
+		{
+			name: "keep the reported gpus and report the rdma",
+			existingDevice: &schedulingv1alpha1.Device{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "test",
+					Labels: map[string]string{extension.LabelGPUModel: "A100"},
+				},
+				Spec: schedulingv1alpha1.DeviceSpec{
+					Devices: []schedulingv1alpha1.DeviceInfo{reportedGPU},
+				},
+			},
+			wantDevices: []schedulingv1alpha1.DeviceInfo{reportedGPU, {Type: schedulingv1alpha1.RDMA, UUID: "0000:00:09.0"}},
+		},
+		{
+			name:        "create Device with the rdma",
+			wantDevices: []schedulingv1alpha1.DeviceInfo{{Type: schedulingv1alpha1.RDMA, UUID: "0000:00:09.0"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := schedulingfake.NewSimpleClientset()
+			if tt.existingDevice != nil {
+				clientset = schedulingfake.NewSimpleClientset(tt.existingDevice)
+			}
+			fakeClient := clientset.SchedulingV1alpha1().Devices()
+			ctl := gomock.NewController(t)
+			mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+			mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(nil, false).AnyTimes()
+			mockMetricCache.EXPECT().Get(koordletutil.RDMADeviceType).Return(rdmaDeviceInfo, true).AnyTimes()
+			mockMetricCache.EXPECT().Get(koordletutil.FPGADeviceType).Return(nil, false).AnyTimes()
+			r := &statesInformer{
+				deviceClient: fakeClient,
+				metricsCache: mockMetricCache,
+				gpuAvailable: true,
+				// nvml is initialized but reports no gpu, e.g. all gpus fell off the bus
+				nvml: newFakeNVML("470.82.01"),
+				states: &PluginState{
+					informerPlugins: map[PluginName]informerPlugin{
+						nodeInformerName: &nodeInformer{
+							node: testNode,
+						},
+					},
+				},
+				getGPUDriverAndModelFunc: func() (string, string) {
+					return "A100", "470"
+				},
+			}
+			// the error is returned to retry building the gpus
+			assert.Error(t, r.reportDevice())
+
+			device, err := fakeClient.Get(context.TODO(), "test", metav1.GetOptions{})
+			assert.NoError(t, err)
+			var got []schedulingv1alpha1.DeviceInfo
+			for _, d := range device.Spec.Devices {
+				if d.Type == schedulingv1alpha1.RDMA {
+					assert.NotNil(t, d.LastReportTime, "the report time of the rdma should be refreshed")
+					got = append(got, schedulingv1alpha1.DeviceInfo{Type: d.Type, UUID: d.UUID})
+					continue
+				}
+				got = append(got, d)
+			}
+			assert.ElementsMatch(t, tt.wantDevices, got)
+			if tt.existingDevice != nil {
+				assert.Equal(t, "A100", device.Labels[extension.LabelGPUModel], "the gpu labels should be kept")
+			}
+			// the condition is updated even though the gpus are kept
+			condition := meta.FindStatusCondition(device.Status.Conditions, schedulingv1alpha1.DeviceConditionGPUMonitoringReady)
+			assert.NotNil(t, condition)
+			assert.Equal(t, metav1.ConditionFalse, condition.Status)
+			assert.Equal(t, gpuMonitoringReasonGPUDevicesUnavailable, condition.Reason)
+		})
+	}
+}
+
+func Test_keepReportedGPUs(t *testing.T) {
+	latest := &schedulingv1alpha1.Device{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{
+				extension.LabelGPUModel:         "A100",
+				extension.LabelGPUDriverVersion: "470",
+				"other":                         "value",
+			},
+		},
+		Spec: schedulingv1alpha1.DeviceSpec{
+			Devices: []schedulingv1alpha1.DeviceInfo{
+				{UUID: "gpu-1", Type: schedulingv1alpha1.GPU},
+				{UUID: "rdma-1", Type: schedulingv1alpha1.RDMA},
+			},
+		},
+		Status: schedulingv1alpha1.DeviceStatus{
+			Resources: []schedulingv1alpha1.DeviceResourceStatus{
+				{UUID: "gpu-1", Type: schedulingv1alpha1.GPU, Minor: pointer.Int32(0)},
+			},
+			Conditions: []metav1.Condition{
+				{Type: schedulingv1alpha1.DeviceConditionGPUCoolingDegraded, Status: metav1.ConditionTrue},
+				{Type: schedulingv1alpha1.DeviceConditionGPUMonitoringReady, Status: metav1.ConditionTrue},
+			},
+		},
+	}
+	desired := &schedulingv1alpha1.Device{
+		Spec: schedulingv1alpha1.DeviceSpec{
+			Devices: []schedulingv1alpha1.DeviceInfo{
+				{UUID: "rdma-2", Type: schedulingv1alpha1.RDMA},
+			},
+		},
+		Status: schedulingv1alpha1.DeviceStatus{
+			Conditions: []metav1.Condition{
+				{Type: schedulingv1alpha1.DeviceConditionGPUMonitoringReady, Status: metav1.ConditionFalse},
+			},
+		},
+	}
+	keepReportedGPUs(latest, desired)
+	assert.Equal(t, []schedulingv1alpha1.DeviceInfo{
+		{UUID: "rdma-2", Type: schedulingv1alpha1.RDMA},
+		{UUID: "gpu-1", Type: schedulingv1alpha1.GPU},
+	}, desired.Spec.Devices)
+	assert.Equal(t, map[string]string{
+		extension.LabelGPUModel:         "A100",
+		extension.LabelGPUDriverVersion: "470",
+	}, desired.Labels)
+	assert.Equal(t, latest.Status.Resources, desired.Status.Resources)
+	assert.Equal(t, metav1.ConditionFalse,
+		meta.FindStatusCondition(desired.Status.Conditions, schedulingv1alpha1.DeviceConditionGPUMonitoringReady).Status)
+	assert.Equal(t, metav1.ConditionTrue,
+		meta.FindStatusCondition(desired.Status.Conditions, schedulingv1alpha1.DeviceConditionGPUCoolingDegraded).Status)
+}
+
+func Test_buildGPUResourceStatus(t *testing.T) {
+	gpuResources := corev1.ResourceList{
+		extension.ResourceGPUCore:        resource.MustParse("100"),
+		extension.ResourceGPUMemory:      resource.MustParse("16Gi"),
+		extension.ResourceGPUMemoryRatio: resource.MustParse("100"),
+	}
+	devices := []schedulingv1alpha1.DeviceInfo{
+		{UUID: "gpu-0", Type: schedulingv1alpha1.GPU, Minor: pointer.Int32(0), Resources: gpuResources},
+		{UUID: "gpu-1", Type: schedulingv1alpha1.GPU, Minor: pointer.Int32(1), Resources: gpuResources},
+		{UUID: "rdma-0", Type: schedulingv1alpha1.RDMA, Minor: pointer.Int32(0)},
+	}
+	newPod := func(name string, phase corev1.PodPhase, allocations extension.DeviceAllocations) *statesinformer.PodMeta {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, UID: types.UID(name)},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+		if allocations != nil {
+			assert.NoError(t, extension.SetDeviceAllocations(pod, allocations))
+		}
+		return &statesinformer.PodMeta{Pod: pod}
+	}
+	gpuAllocations := func(minor int32, core, memory string) extension.DeviceAllocations {
+		return extension.DeviceAllocations{
+			schedulingv1alpha1.GPU: {
+				{
+					Minor: minor,
+					Resources: corev1.ResourceList{
+						extension.ResourceGPUCore:        resource.MustParse(core),
+						extension.ResourceGPUMemory:      resource.MustParse(memory),
+						extension.ResourceGPUMemoryRatio: resource.MustParse(core),
+					},
+				},
+			},
+		}
+	}
+	brokenPod := newPod("broken", corev1.PodRunning, nil)
+	brokenPod.Pod.Annotations = map[string]string{extension.AnnotationDeviceAllocated: "invalid"}
+	pods := []*statesinformer.PodMeta{
+		newPod("pod-1", corev1.PodRunning, gpuAllocations(0, "30", "4Gi")),
+		newPod("pod-2", corev1.PodPending, gpuAllocations(0, "50", "8Gi")),
+		// the terminated pods and the pods whose allocations are invalid are ignored
+		newPod("pod-3", corev1.PodSucceeded, gpuAllocations(0, "20", "4Gi")),
+		brokenPod,
+		// the allocations of the gpus not reported are ignored
+		newPod("pod-4", corev1.PodRunning, gpuAllocations(3, "100", "16Gi")),
+		newPod("pod-5", corev1.PodRunning, nil),
+	}
+
+	got := buildGPUResourceStatus(devices, pods)
+	want := []schedulingv1alpha1.DeviceResourceStatus{
+		{
+			Type:  schedulingv1alpha1.GPU,
+			UUID:  "gpu-0",
+			Minor: pointer.Int32(0),
+			Allocated: corev1.ResourceList{
+				extension.ResourceGPUCore:        resource.MustParse("80"),
+				extension.ResourceGPUMemory:      resource.MustParse("12Gi"),
+				extension.ResourceGPUMemoryRatio: resource.MustParse("80"),
+			},
+			Free: corev1.ResourceList{
+				extension.ResourceGPUCore:        resource.MustParse("20"),
+				extension.ResourceGPUMemory:      resource.MustParse("4Gi"),
+				extension.ResourceGPUMemoryRatio: resource.MustParse("20"),
+			},
+		},
+		{
+			Type:  schedulingv1alpha1.GPU,
+			UUID:  "gpu-1",
+			Minor: pointer.Int32(1),
+			Allocated: corev1.ResourceList{
+				extension.ResourceGPUCore:        resource.MustParse("0"),
+				extension.ResourceGPUMemory:      resource.MustParse("0"),
+				extension.ResourceGPUMemoryRatio: resource.MustParse("0"),
+			},
+			Free: gpuResources,
+		},
+	}
+	assert.True(t, apiequality.Semantic.DeepEqual(want, got), "want %+v, got %+v", want, got)
+
+	// the free resources are not negative if the gpu is overcommitted
+	pods = append(pods, newPod("pod-6", corev1.PodRunning, gpuAllocations(0, "50", "8Gi")))
+	got = buildGPUResourceStatus(devices, pods)
+	assert.True(t, apiequality.Semantic.DeepEqual(corev1.ResourceList{
+		extension.ResourceGPUCore:        resource.MustParse("0"),
+		extension.ResourceGPUMemory:      resource.MustParse("0"),
+		extension.ResourceGPUMemoryRatio: resource.MustParse("0"),
+	}, got[0].Free), "got %+v", got[0].Free)
+
+	assert.Nil(t, buildGPUResourceStatus(nil, pods))
+}
+
+func Test_checkGPUAllocatedPodsUpdate(t *testing.T) {
+	newPod := func(name string, phase corev1.PodPhase, allocated bool) *statesinformer.PodMeta {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, UID: types.UID(name)},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+		if allocated {
+			pod.Annotations = map[string]string{extension.AnnotationDeviceAllocated: `{"gpu":[{"minor":0}]}`}
+		}
+		return &statesinformer.PodMeta{Pod: pod}
+	}
+	s := &statesInformer{
+		deviceQueue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "device"),
+	}
+	defer s.deviceQueue.ShutDown()
+	drain := func() int {
+		n := s.deviceQueue.Len()
+		for i := 0; i < n; i++ {
+			key, _ := s.deviceQueue.Get()
+			s.deviceQueue.Done(key)
+		}
+		return n
+	}
+
+	pods := []*statesinformer.PodMeta{newPod("pod-1", corev1.PodRunning, true), newPod("pod-2", corev1.PodRunning, false)}
+	s.checkGPUAllocatedPodsUpdate(pods)
+	assert.Equal(t, 1, drain())
+
+	// the pods without gpus and the resync of the unchanged pods do not trigger the reporting
+	s.checkGPUAllocatedPodsUpdate(append(pods, newPod("pod-3", corev1.PodRunning, false)))
+	assert.Equal(t, 0, drain())
+
+	// a pod allocated with gpus is added
+	pods = append(pods, newPod("pod-4", corev1.PodPending, true))
+	s.checkGPUAllocatedPodsUpdate(pods)
+	assert.Equal(t, 1, drain())
+
+	// a pod allocated with gpus terminates
+	pods[0] = newPod("pod-1", corev1.PodSucceeded, true)
+	s.checkGPUAllocatedPodsUpdate(pods)
+	assert.Equal(t, 1, drain())
+}
+
+func Test_buildGPUMonitoringCondition(t *testing.T) {
+	gpuDevices := []schedulingv1alpha1.DeviceInfo{
+		{UUID: "1", Type: schedulingv1alpha1.GPU, Health: true},
+		{UUID: "2", Type: schedulingv1alpha1.GPU, Health: false},
+	}
+	tests := []struct {
+		name         string
+		gpuAvailable bool
+		gpuDevices   []schedulingv1alpha1.DeviceInfo
+		buildErr     error
+		want         *metav1.Condition
+	}{
+		{
+			name: "node without gpu",
+		},
+		{
+			name:         "gpus monitored",
+			gpuAvailable: true,
+			gpuDevices:   gpuDevices,
+			want: &metav1.Condition{
+				Type:    schedulingv1alpha1.DeviceConditionGPUMonitoringReady,
+				Status:  metav1.ConditionTrue,
+				Reason:  gpuMonitoringReasonReady,
+				Message: "1 healthy and 1 unhealthy gpus",
+			},
+		},
+		{
+			name:       "nvml not initialized",
+			gpuDevices: gpuDevices,
+			want: &metav1.Condition{
+				Type:    schedulingv1alpha1.DeviceConditionGPUMonitoringReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  gpuMonitoringReasonNVMLNotInitialized,
+				Message: "NVML is not initialized, the health of 2 gpus is not monitored",
+			},
+		},
+		{
+			name:         "gpus failed to build",
+			gpuAvailable: true,
+			buildErr:     fmt.Errorf("GPU subsystem degraded"),
+			want: &metav1.Condition{
+				Type:    schedulingv1alpha1.DeviceConditionGPUMonitoringReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  gpuMonitoringReasonGPUDevicesUnavailable,
+				Message: "GPU subsystem degraded",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &statesInformer{gpuAvailable: tt.gpuAvailable}
+			assert.Equal(t, tt.want, s.buildGPUMonitoringCondition(tt.gpuDevices, tt.buildErr))
+		})
+	}
+}
+
+func Test_limitReportedDevices(t *testing.T) {
+	newDevice := func() *schedulingv1alpha1.Device {
+		return &schedulingv1alpha1.Device{
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			Spec: schedulingv1alpha1.DeviceSpec{
+				Devices: []schedulingv1alpha1.DeviceInfo{
+					{UUID: "1", Type: schedulingv1alpha1.GPU},
+					{UUID: "2", Type: schedulingv1alpha1.GPU},
+					{UUID: "3", Type: schedulingv1alpha1.RDMA},
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name   string
+		config *Config
+		want   []string
+	}{
+		{
+			name:   "no limit",
+			config: &Config{},
+			want:   []string{"1", "2", "3"},
+		},
+		{
+			name:   "within the limit",
+			config: &Config{MaxReportedDevices: 3, CapReportedDevices: true},
+			want:   []string{"1", "2", "3"},
+		},
+		{
+			name:   "over the limit without capping",
+			config: &Config{MaxReportedDevices: 2},
+			want:   []string{"1", "2", "3"},
+		},
+		{
+			name:   "over the limit with capping",
+			config: &Config{MaxReportedDevices: 2, CapReportedDevices: true},
+			want:   []string{"1", "2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &statesInformer{config: tt.config}
+			device := newDevice()
+			s.limitReportedDevices(device)
+			var got []string
+			for _, d := range device.Spec.Devices {
+				got = append(got, d.UUID)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_buildGPUCoolingCondition(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     *Config
+		gpuDevices []schedulingv1alpha1.DeviceInfo
+		want       *metav1.Condition
+	}{
+		{
+			name:   "condition not enabled",
+			config: &Config{},
+			gpuDevices: []schedulingv1alpha1.DeviceInfo{
+				{UUID: "1", Type: schedulingv1alpha1.GPU, Labels: map[string]string{extension.LabelGPUCoolingDegraded: "true"}},
+			},
+		},
+		{
+			name:   "node without gpu",
+			config: &Config{EnableGPUCoolingCondition: true},
+		},
+		{
+			name:   "cooling normal",
+			config: &Config{EnableGPUCoolingCondition: true},
+			gpuDevices: []schedulingv1alpha1.DeviceInfo{
+				{UUID: "1", Type: schedulingv1alpha1.GPU},
+				{UUID: "2", Type: schedulingv1alpha1.GPU, Labels: map[string]string{extension.LabelGPUProductName: "A100"}},
+			},
+			want: &metav1.Condition{
+				Type:    schedulingv1alpha1.DeviceConditionGPUCoolingDegraded,
+				Status:  metav1.ConditionFalse,
+				Reason:  gpuCoolingReasonNormal,
+				Message: "no gpu is found with the fan stopped while thermally loaded",
+			},
+		},
+		{
+			name:   "cooling degraded",
+			config: &Config{EnableGPUCoolingCondition: true},
+			gpuDevices: []schedulingv1alpha1.DeviceInfo{
+				{UUID: "3", Type: schedulingv1alpha1.GPU, Labels: map[string]string{extension.LabelGPUCoolingDegraded: "true"}},
+				{UUID: "2", Type: schedulingv1alpha1.GPU},
+				{UUID: "1", Type: schedulingv1alpha1.GPU, Labels: map[string]string{extension.LabelGPUCoolingDegraded: "true"}},
+			},
+			want: &metav1.Condition{
+				Type:    schedulingv1alpha1.DeviceConditionGPUCoolingDegraded,
+				Status:  metav1.ConditionTrue,
+				Reason:  gpuCoolingReasonFanStopped,
+				Message: "the fan stops while thermally loaded on gpus 1,3",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &statesInformer{config: tt.config}
+			assert.Equal(t, tt.want, s.buildGPUCoolingCondition(tt.gpuDevices))
+		})
+	}
+}
+
+func Test_isGPUMemoryDegraded(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+		gpu    koordletutil.GPUDeviceInfo
+		want   bool
+	}{
+		{
+			name:   "condition not enabled",
+			config: &Config{GPURetiredPagesThreshold: 60},
+			gpu:    koordletutil.GPUDeviceInfo{UUID: "1", RetiredPages: 64, RetiredPagesPending: true},
+			want:   false,
+		},
+		{
+			name:   "pages retired under the threshold",
+			config: &Config{EnableGPUMemoryCondition: true, GPURetiredPagesThreshold: 60},
+			gpu:    koordletutil.GPUDeviceInfo{UUID: "1", RetiredPages: 3},
+			want:   false,
+		},
+		{
+			name:   "pages retired over the threshold",
+			config: &Config{EnableGPUMemoryCondition: true, GPURetiredPagesThreshold: 60},
+			gpu:    koordletutil.GPUDeviceInfo{UUID: "1", RetiredPages: 60},
+			want:   true,
+		},
+		{
+			name:   "pages pending retirement",
+			config: &Config{EnableGPUMemoryCondition: true, GPURetiredPagesThreshold: 60},
+			gpu:    koordletutil.GPUDeviceInfo{UUID: "1", RetiredPages: 1, RetiredPagesPending: true},
+			want:   true,
+		},
+		{
+			name:   "threshold disabled",
+			config: &Config{EnableGPUMemoryCondition: true},
+			gpu:    koordletutil.GPUDeviceInfo{UUID: "1", RetiredPages: 100},
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &statesInformer{config: tt.config}
+			assert.Equal(t, tt.want, s.isGPUMemoryDegraded(&tt.gpu))
+		})
+	}
+}
+
+func Test_getGPUHealthState(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+		gpu    koordletutil.GPUDeviceInfo
+		health bool
+		want   schedulingv1alpha1.DeviceHealthState
+	}{
+		{
+			name:   "healthy",
+			config: &Config{DrainGPUOnCoolingDegraded: true, DrainGPUOnMemoryDegraded: true, GPURetiredPagesThreshold: 60},
+			gpu:    koordletutil.GPUDeviceInfo{UUID: "1", RetiredPages: 3},
+			health: true,
+			want:   schedulingv1alpha1.DeviceHealthStateHealthy,
+		},
+		{
+			name:   "unhealthy gpu is not draining",
+			config: &Config{DrainGPUOnCoolingDegraded: true},
+			gpu:    koordletutil.GPUDeviceInfo{UUID: "1", CoolingDegraded: true},
+			health: false,
+			want:   schedulingv1alpha1.DeviceHealthStateUnhealthy,
+		},
+		{
+			name:   "draining on cooling degraded",
+			config: &Config{DrainGPUOnCoolingDegraded: true},
+			gpu:    koordletutil.GPUDeviceInfo{UUID: "1", CoolingDegraded: true},
+			health: true,
+			want:   schedulingv1alpha1.DeviceHealthStateDraining,
+		},
+		{
+			name:   "cooling degraded without the trigger",
+			config: &Config{DrainGPUOnMemoryDegraded: true},
+			gpu:    koordletutil.GPUDeviceInfo{UUID: "1", CoolingDegraded: true},
+			health: true,
+			want:   schedulingv1alpha1.DeviceHealthStateHealthy,
+		},
+		{
+			name:   "draining on pages pending retirement",
+			config: &Config{DrainGPUOnMemoryDegraded: true},
+			gpu:    koordletutil.GPUDeviceInfo{UUID: "1", RetiredPagesPending: true},
+			health: true,
+			want:   schedulingv1alpha1.DeviceHealthStateDraining,
+		},
+		{
+			name:   "draining on pages retired over the threshold",
+			config: &Config{DrainGPUOnMemoryDegraded: true, GPURetiredPagesThreshold: 60},
+			gpu:    koordletutil.GPUDeviceInfo{UUID: "1", RetiredPages: 64},
+			health: true,
+			want:   schedulingv1alpha1.DeviceHealthStateDraining,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &statesInformer{config: tt.config}
+			assert.Equal(t, tt.want, s.getGPUHealthState(&tt.gpu, tt.health))
+		})
+	}
+}
+
+func Test_buildGPUMemoryCondition(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     *Config
+		gpuDevices []schedulingv1alpha1.DeviceInfo
+		want       *metav1.Condition
+	}{
+		{
+			name:   "condition not enabled",
+			config: &Config{},
+			gpuDevices: []schedulingv1alpha1.DeviceInfo{
+				{UUID: "1", Type: schedulingv1alpha1.GPU, Labels: map[string]string{extension.LabelGPUMemoryDegraded: "true"}},
+			},
+		},
+		{
+			name:   "memory normal",
+			config: &Config{EnableGPUMemoryCondition: true},
+			gpuDevices: []schedulingv1alpha1.DeviceInfo{
+				{UUID: "1", Type: schedulingv1alpha1.GPU},
+			},
+			want: &metav1.Condition{
+				Type:    schedulingv1alpha1.DeviceConditionGPUMemoryDegraded,
+				Status:  metav1.ConditionFalse,
+				Reason:  gpuMemoryReasonNormal,
+				Message: "no gpu is found with the memory pages retired over the threshold or pending retirement",
+			},
+		},
+		{
+			name:   "memory degraded",
+			config: &Config{EnableGPUMemoryCondition: true},
+			gpuDevices: []schedulingv1alpha1.DeviceInfo{
+				{UUID: "2", Type: schedulingv1alpha1.GPU, Labels: map[string]string{extension.LabelGPUMemoryDegraded: "true"}},
+				{UUID: "1", Type: schedulingv1alpha1.GPU},
+			},
+			want: &metav1.Condition{
+				Type:    schedulingv1alpha1.DeviceConditionGPUMemoryDegraded,
+				Status:  metav1.ConditionTrue,
+				Reason:  gpuMemoryReasonPagesRetired,
+				Message: "the memory pages are retired over the threshold or pending retirement on gpus 2",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &statesInformer{config: tt.config}
+			assert.Equal(t, tt.want, s.buildGPUMemoryCondition(tt.gpuDevices))
+		})
+	}
+}
+
+func Test_mergeDeviceConditions(t *testing.T) {
+	lastTransitionTime := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	latest := &schedulingv1alpha1.Device{
+		Status: schedulingv1alpha1.DeviceStatus{
+			Conditions: []metav1.Condition{
+				{Type: "External", Status: metav1.ConditionTrue, Reason: "External", LastTransitionTime: lastTransitionTime},
+				{
+					Type:               schedulingv1alpha1.DeviceConditionGPUMonitoringReady,
+					Status:             metav1.ConditionTrue,
+					Reason:             gpuMonitoringReasonReady,
+					Message:            "2 healthy and 0 unhealthy gpus",
+					LastTransitionTime: lastTransitionTime,
+				},
+			},
+		},
+	}
+
+	// the transition time is kept if the status is unchanged
+	desired := &schedulingv1alpha1.Device{}
+	meta.SetStatusCondition(&desired.Status.Conditions, metav1.Condition{
+		Type:    schedulingv1alpha1.DeviceConditionGPUMonitoringReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  gpuMonitoringReasonReady,
+		Message: "1 healthy and 1 unhealthy gpus",
+	})
+	merged := latest.DeepCopy()
+	mergeDeviceConditions(merged, desired)
+	assert.Equal(t, 2, len(merged.Status.Conditions))
+	condition := meta.FindStatusCondition(merged.Status.Conditions, schedulingv1alpha1.DeviceConditionGPUMonitoringReady)
+	assert.Equal(t, "1 healthy and 1 unhealthy gpus", condition.Message)
+	assert.Equal(t, lastTransitionTime, condition.LastTransitionTime)
+
+	// the transition time is updated if the status changes
+	desired = &schedulingv1alpha1.Device{}
+	meta.SetStatusCondition(&desired.Status.Conditions, metav1.Condition{
+		Type:   schedulingv1alpha1.DeviceConditionGPUMonitoringReady,
+		Status: metav1.ConditionFalse,
+		Reason: gpuMonitoringReasonGPUDevicesUnavailable,
+	})
+	merged = latest.DeepCopy()
+	mergeDeviceConditions(merged, desired)
+	condition = meta.FindStatusCondition(merged.Status.Conditions, schedulingv1alpha1.DeviceConditionGPUMonitoringReady)
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+	assert.True(t, condition.LastTransitionTime.After(lastTransitionTime.Time))
+
+	// the condition is removed if the node has no gpu anymore, the external conditions are kept
+	merged = latest.DeepCopy()
+	mergeDeviceConditions(merged, &schedulingv1alpha1.Device{})
+	assert.Equal(t, []metav1.Condition{latest.Status.Conditions[0]}, merged.Status.Conditions)
+}
+
+func Test_reportDeviceForceResync(t *testing.T) {
+	testNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	fakeClientSet := schedulingfake.NewSimpleClientset()
+	fakeClient := fakeClientSet.SchedulingV1alpha1().Devices()
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	gpuDeviceInfo := koordletutil.GPUDevices{
+		{UUID: "1", Minor: 1, MemoryTotal: 8000},
+	}
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(gpuDeviceInfo, true).AnyTimes()
+	mockMetricCache.EXPECT().Get(koordletutil.RDMADeviceType).Return(nil, false).AnyTimes()
+	mockMetricCache.EXPECT().Get(koordletutil.FPGADeviceType).Return(nil, false).AnyTimes()
+	r := &statesInformer{
+		deviceClient: fakeClient,
+		metricsCache: mockMetricCache,
+		states: &PluginState{
+			informerPlugins: map[PluginName]informerPlugin{
+				nodeInformerName: &nodeInformer{
+					node: testNode,
+				},
+			},
+		},
+		getGPUDriverAndModelFunc: func() (string, string) {
+			return "A100", "470"
+		},
+	}
+	countPatches := func() int {
+		count := 0
+		for _, action := range fakeClientSet.Actions() {
+			if action.GetVerb() == "patch" {
+				count++
+			}
+		}
+		return count
+	}
+
+	r.reportDevice()
+	r.reportDevice()
+	assert.Equal(t, 0, countPatches(), "unchanged device should not be patched")
+
+	testNode.Annotations = map[string]string{extension.AnnotationDeviceResync: "token-1"}
+	r.reportDevice()
+	assert.Equal(t, 1, countPatches(), "resync token should force a patch")
+	assert.Equal(t, "token-1", r.deviceResyncToken)
+
+	r.reportDevice()
+	assert.Equal(t, 1, countPatches(), "handled resync token should not force a patch again")
+}
+
+func Test_reportDevicePaused(t *testing.T) {
+	testNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test",
+			Annotations: map[string]string{extension.AnnotationDeviceReportPaused: "true"},
+		},
+	}
+	existingDevice := &schedulingv1alpha1.Device{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+		Spec: schedulingv1alpha1.DeviceSpec{
+			Devices: []schedulingv1alpha1.DeviceInfo{
+				{
+					UUID:   "1",
+					Minor:  pointer.Int32(1),
+					Type:   schedulingv1alpha1.GPU,
+					Health: true,
+				},
+				{
+					UUID:   "2",
+					Minor:  pointer.Int32(2),
+					Type:   schedulingv1alpha1.GPU,
+					Health: true,
+				},
+			},
+		},
+	}
+	fakeClient := schedulingfake.NewSimpleClientset(existingDevice).SchedulingV1alpha1().Devices()
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	// a gpu disappears during the driver maintenance
+	gpuDeviceInfo := koordletutil.GPUDevices{
+		{UUID: "1", Minor: 1, MemoryTotal: 8000},
+	}
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(gpuDeviceInfo, true).AnyTimes()
+	mockMetricCache.EXPECT().Get(koordletutil.RDMADeviceType).Return(nil, false).AnyTimes()
+	mockMetricCache.EXPECT().Get(koordletutil.FPGADeviceType).Return(nil, false).AnyTimes()
+	r := &statesInformer{
+		deviceClient: fakeClient,
+		metricsCache: mockMetricCache,
+		states: &PluginState{
+			informerPlugins: map[PluginName]informerPlugin{
+				nodeInformerName: &nodeInformer{
+					node: testNode,
+				},
+			},
+		},
+		getGPUDriverAndModelFunc: func() (string, string) {
+			return "A100", "470"
+		},
+	}
+
+	assert.NoError(t, r.reportDevice())
+	device, err := fakeClient.Get(context.TODO(), "test", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, existingDevice.Spec.Devices, device.Spec.Devices, "paused device should be kept")
+
+	testNode.Annotations = nil
+	assert.NoError(t, r.reportDevice())
+	device, err = fakeClient.Get(context.TODO(), "test", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(device.Spec.Devices), "resumed device should be reported")
+	assert.Equal(t, "1", device.Spec.Devices[0].UUID)
+}
+
+func Test_reportDeviceRefreshReportTime(t *testing.T) {
+	testNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	fakeClientSet := schedulingfake.NewSimpleClientset()
+	fakeClient := fakeClientSet.SchedulingV1alpha1().Devices()
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	gpuDeviceInfo := koordletutil.GPUDevices{
+		{UUID: "1", Minor: 1, MemoryTotal: 8000},
+	}
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(gpuDeviceInfo, true).AnyTimes()
+	mockMetricCache.EXPECT().Get(koordletutil.RDMADeviceType).Return(nil, false).AnyTimes()
+	mockMetricCache.EXPECT().Get(koordletutil.FPGADeviceType).Return(nil, false).AnyTimes()
+	cfg := NewDefaultConfig()
+	cfg.DeviceReportTimeRefreshInterval = time.Minute
+	r := &statesInformer{
+		config:       cfg,
+		deviceClient: fakeClient,
+		metricsCache: mockMetricCache,
+		states: &PluginState{
+			informerPlugins: map[PluginName]informerPlugin{
+				nodeInformerName: &nodeInformer{
+					node: testNode,
+				},
+			},
+		},
+		getGPUDriverAndModelFunc: func() (string, string) {
+			return "A100", "470"
+		},
+	}
+	countPatches := func() int {
+		count := 0
+		for _, action := range fakeClientSet.Actions() {
+			if action.GetVerb() == "patch" {
+				count++
+			}
+		}
+		return count
+	}
+
+	assert.NoError(t, r.reportDevice())
+	device, err := fakeClient.Get(context.TODO(), "test", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.NotNil(t, device.Spec.Devices[0].LastReportTime)
+
+	assert.NoError(t, r.reportDevice())
+	assert.Equal(t, 0, countPatches(), "the report time should not be refreshed within the interval")
+
+	staleTime := metav1.NewTime(time.Now().Add(-2 * time.Minute))
+	device.Spec.Devices[0].LastReportTime = &staleTime
+	_, err = fakeClient.Update(context.TODO(), device, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	assert.NoError(t, r.reportDevice())
+	assert.Equal(t, 1, countPatches(), "the expired report time should be refreshed")
+	device, err = fakeClient.Get(context.TODO(), "test", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.True(t, device.Spec.Devices[0].LastReportTime.After(staleTime.Time))
+
+	cfg.DeviceReportTimeRefreshInterval = 0
+	assert.NoError(t, r.reportDevice())
+	assert.Equal(t, 2, countPatches(), "the report time should be refreshed in each report if the interval is 0")
+}
+
+func Test_reportDeviceHeterogeneousGPUMemory(t *testing.T) {
+	testNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	fakeClientSet := schedulingfake.NewSimpleClientset()
+	fakeClient := fakeClientSet.SchedulingV1alpha1().Devices()
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	// A100-40GB and A100-80GB are mixed in the node, and collected in an arbitrary order
+	gpuDeviceInfo := koordletutil.GPUDevices{
+		{UUID: "GPU-80G-1", Minor: 1, MemoryTotal: 85899345920, ProductName: "A100-SXM4-80GB"},
+		{UUID: "GPU-40G-0", Minor: 0, MemoryTotal: 42949672960, ProductName: "A100-SXM4-40GB"},
+		{UUID: "GPU-80G-2", Minor: 2, MemoryTotal: 85899345920, ProductName: "A100-SXM4-80GB"},
+	}
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).DoAndReturn(func(key interface{}) (interface{}, bool) {
+		return gpuDeviceInfo, true
+	}).AnyTimes()
+	mockMetricCache.EXPECT().Get(koordletutil.RDMADeviceType).Return(nil, false).AnyTimes()
+	mockMetricCache.EXPECT().Get(koordletutil.FPGADeviceType).Return(nil, false).AnyTimes()
+	r := &statesInformer{
+		config:       NewDefaultConfig(),
+		deviceClient: fakeClient,
+		metricsCache: mockMetricCache,
+		states: &PluginState{
+			informerPlugins: map[PluginName]informerPlugin{
+				nodeInformerName: &nodeInformer{
+					node: testNode,
+				},
+			},
+		},
+		getGPUDriverAndModelFunc: func() (string, string) {
+			return "A100-SXM4-40GB", "470"
+		},
+	}
+	countPatches := func() int {
+		count := 0
+		for _, action := range fakeClientSet.Actions() {
+			if action.GetVerb() == "patch" {
+				count++
+			}
+		}
+		return count
+	}
+
+	assert.NoError(t, r.reportDevice())
+	device, err := fakeClient.Get(context.TODO(), "test", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(device.Spec.Devices))
+	// each gpu reports its own memory instead of an aggregated or averaged one
+	expectedMemory := map[string]resource.Quantity{
+		"GPU-40G-0": resource.MustParse("40Gi"),
+		"GPU-80G-1": resource.MustParse("80Gi"),
+		"GPU-80G-2": resource.MustParse("80Gi"),
+	}
+	expectedProductName := map[string]string{
+		"GPU-40G-0": "A100-SXM4-40GB",
+		"GPU-80G-1": "A100-SXM4-80GB",
+		"GPU-80G-2": "A100-SXM4-80GB",
+	}
+	for _, d := range device.Spec.Devices {
+		memory := d.Resources[extension.ResourceGPUMemory]
+		expected := expectedMemory[d.UUID]
+		assert.Equal(t, 0, expected.Cmp(memory), "gpu %s, memory %s", d.UUID, memory.String())
+		assert.Equal(t, expectedProductName[d.UUID], d.Labels[extension.LabelGPUProductName])
+		memoryRatio := d.Resources[extension.ResourceGPUMemoryRatio]
+		assert.Equal(t, int64(100), memoryRatio.Value())
+	}
+
+	// the devices are not updated when the gpus are collected in another order
+	gpuDeviceInfo = koordletutil.GPUDevices{gpuDeviceInfo[2], gpuDeviceInfo[1], gpuDeviceInfo[0]}
+	assert.NoError(t, r.reportDevice())
+	assert.Equal(t, 0, countPatches())
+}
+
+func Test_sortDeviceInfos(t *testing.T) {
+	devices := []schedulingv1alpha1.DeviceInfo{
+		{Type: schedulingv1alpha1.RDMA, UUID: "rdma-1", Minor: pointer.Int32(1)},
+		{Type: schedulingv1alpha1.GPU, UUID: "b", Minor: pointer.Int32(0)},
+		{Type: schedulingv1alpha1.GPU, UUID: "c", Minor: pointer.Int32(1)},
+		{Type: schedulingv1alpha1.GPU, UUID: "a", Minor: pointer.Int32(0)},
+		{Type: schedulingv1alpha1.FPGA, UUID: "fpga"},
+	}
+	sortDeviceInfos(devices)
+	var got []string
+	for _, d := range devices {
+		got = append(got, d.UUID)
+	}
+	assert.Equal(t, []string{"fpga", "a", "b", "c", "rdma-1"}, got)
+}
+
+func Test_sortDeviceInfosSharedMinor(t *testing.T) {
+	replica := func(uuid string, minor int32, index string) schedulingv1alpha1.DeviceInfo {
+		d := schedulingv1alpha1.DeviceInfo{Type: schedulingv1alpha1.GPU, UUID: uuid, Minor: pointer.Int32(minor)}
+		if index != "" {
+			d.Labels = map[string]string{extension.LabelGPUReplicaIndex: index}
+		}
+		return d
+	}
+	devices := []schedulingv1alpha1.DeviceInfo{
+		replica("gpu-1", 0, "2"),
+		replica("gpu-1", 0, "10"),
+		replica("gpu-0", 0, "1"),
+		replica("gpu-1", 0, ""),
+		replica("gpu-0", 0, "0"),
+	}
+	// the result is the same in whichever order the devices are given
+	for i := 0; i < len(devices); i++ {
+		shuffled := append(append([]schedulingv1alpha1.DeviceInfo{}, devices[i:]...), devices[:i]...)
+		sortDeviceInfos(shuffled)
+		var got []string
+		for _, d := range shuffled {
+			got = append(got, d.UUID+"/"+d.Labels[extension.LabelGPUReplicaIndex])
+		}
+		assert.Equal(t, []string{"gpu-0/0", "gpu-0/1", "gpu-1/", "gpu-1/2", "gpu-1/10"}, got)
+	}
+}
+
+func Test_reportDeviceIdempotentWithReplicas(t *testing.T) {
+	testNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	fakeClientSet := schedulingfake.NewSimpleClientset()
+	fakeClient := fakeClientSet.SchedulingV1alpha1().Devices()
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	gpuDeviceInfo := koordletutil.GPUDevices{
+		{UUID: "GPU-1", Minor: 1, MemoryTotal: 16106127360, ProductName: "Tesla-T4"},
+		{UUID: "GPU-0", Minor: 0, MemoryTotal: 16106127360, ProductName: "Tesla-T4"},
+	}
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).DoAndReturn(func(key interface{}) (interface{}, bool) {
+		return gpuDeviceInfo, true
+	}).AnyTimes()
+	mockMetricCache.EXPECT().Get(koordletutil.RDMADeviceType).Return(nil, false).AnyTimes()
+	mockMetricCache.EXPECT().Get(koordletutil.FPGADeviceType).Return(nil, false).AnyTimes()
+	cfg := NewDefaultConfig()
+	cfg.GPUTimeSlicingReplicas = map[string]string{"Tesla-T4": "4"}
+	r := &statesInformer{
+		config:       cfg,
+		deviceClient: fakeClient,
+		metricsCache: mockMetricCache,
+		states: &PluginState{
+			informerPlugins: map[PluginName]informerPlugin{
+				nodeInformerName: &nodeInformer{
+					node: testNode,
+				},
+			},
+		},
+		getGPUDriverAndModelFunc: func() (string, string) {
+			return "Tesla-T4", "470"
+		},
+	}
+	countPatches := func() int {
+		count := 0
+		for _, action := range fakeClientSet.Actions() {
+			if action.GetVerb() == "patch" {
+				count++
+			}
+		}
+		return count
+	}
+
+	assert.NoError(t, r.reportDevice())
+	device, err := fakeClient.Get(context.TODO(), "test", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 8, len(device.Spec.Devices))
+
+	// repeated reports of the identical gpus produce no update
+	for i := 0; i < 5; i++ {
+		gpuDeviceInfo = koordletutil.GPUDevices{gpuDeviceInfo[1], gpuDeviceInfo[0]}
+		assert.NoError(t, r.reportDevice())
+	}
+	assert.Equal(t, 0, countPatches())
+}
+
+func Test_generateDevicePatch(t *testing.T) {
+	latest := &schedulingv1alpha1.Device{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "test",
+			ResourceVersion: "10",
+			Annotations: map[string]string{
+				"example.com/maintenance": "gpu-1",
+			},
+			Labels: map[string]string{
+				"example.com/owner":     "ops",
+				extension.LabelGPUModel: "V100",
+			},
+		},
+		Spec: schedulingv1alpha1.DeviceSpec{
+			Devices: []schedulingv1alpha1.DeviceInfo{
+				{UUID: "0", Minor: pointer.Int32(0), Type: schedulingv1alpha1.GPU, Health: true},
+			},
+		},
+	}
+	merged := latest.DeepCopy()
+	delete(merged.Labels, extension.LabelGPUModel)
+	merged.Spec.Devices[0].Health = false
+
+	patchBytes, err := generateDevicePatch(latest, merged)
+	assert.NoError(t, err)
+	patch := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(patchBytes, &patch))
+	assert.Equal(t, map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"resourceVersion": "10",
+			"labels": map[string]interface{}{
+				extension.LabelGPUModel: nil,
+			},
+		},
+		"spec": map[string]interface{}{
+			"devices": []interface{}{
+				map[string]interface{}{
+					"id":     "0",
+					"minor":  float64(0),
+					"type":   "gpu",
+					"health": false,
+				},
+			},
+		},
+	}, patch)
+	assert.Equal(t, "10", latest.ResourceVersion, "the latest Device should not be modified")
+}
+
+// getOptionsRecordingDeviceClient records the resource versions of the Device reads.
+type getOptionsRecordingDeviceClient struct {
+	schedv1alpha1.DeviceInterface
+	resourceVersions []string
+}
+
+func (c *getOptionsRecordingDeviceClient) Get(ctx context.Context, name string, options metav1.GetOptions) (*schedulingv1alpha1.Device, error) {
+	c.resourceVersions = append(c.resourceVersions, options.ResourceVersion)
+	return c.DeviceInterface.Get(ctx, name, options)
+}
+
+func Test_updateDeviceReadsLatestAfterConflict(t *testing.T) {
+	existingDevice := &schedulingv1alpha1.Device{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Spec: schedulingv1alpha1.DeviceSpec{
+			Devices: []schedulingv1alpha1.DeviceInfo{
+				{UUID: "1", Minor: pointer.Int32(1), Type: schedulingv1alpha1.GPU, Health: true},
+			},
+		},
+	}
+	fakeClientSet := schedulingfake.NewSimpleClientset(existingDevice)
+	conflicts := 2
+	fakeClientSet.PrependReactor("patch", "devices", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if conflicts > 0 {
+			conflicts--
+			return true, nil, errors.NewConflict(schema.GroupResource{Resource: "devices"}, "test", fmt.Errorf("stale"))
+		}
+		return false, nil, nil
+	})
+	deviceClient := &getOptionsRecordingDeviceClient{DeviceInterface: fakeClientSet.SchedulingV1alpha1().Devices()}
+	r := &statesInformer{
+		config:       NewDefaultConfig(),
+		deviceClient: deviceClient,
+	}
+
+	desired := existingDevice.DeepCopy()
+	desired.Spec.Devices[0].Health = false
+	assert.NoError(t, r.updateDevice(desired, false, false))
+	assert.Equal(t, []string{"0", "", ""}, deviceClient.resourceVersions,
+		"the Device should be read from the cache until the first conflict")
+	device, err := fakeClientSet.SchedulingV1alpha1().Devices().Get(context.TODO(), "test", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.False(t, device.Spec.Devices[0].Health)
+}
+
+func Test_reportDevicePreserveExternalChanges(t *testing.T) {
+	testNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	existingDevice := &schedulingv1alpha1.Device{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+			Annotations: map[string]string{
+				"example.com/maintenance": "gpu-1",
+			},
+			Labels: map[string]string{
+				"example.com/owner":             "ops",
+				extension.LabelGPUModel:         "V100",
+				extension.LabelGPUDriverVersion: "450",
+			},
+		},
+		Spec: schedulingv1alpha1.DeviceSpec{
+			Devices: []schedulingv1alpha1.DeviceInfo{
+				{
+					UUID:   "0",
+					Minor:  pointer.Int32(0),
+					Type:   schedulingv1alpha1.GPU,
+					Health: true,
+				},
+				{
+					UUID:   "npu-0",
+					Minor:  pointer.Int32(0),
+					Type:   schedulingv1alpha1.DeviceType("npu"),
+					Health: true,
+				},
+			},
+		},
+	}
+	fakeClient := schedulingfake.NewSimpleClientset(existingDevice).SchedulingV1alpha1().Devices()
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	gpuDeviceInfo := koordletutil.GPUDevices{
+		{UUID: "1", Minor: 1, MemoryTotal: 8000},
+	}
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(gpuDeviceInfo, true)
+	mockMetricCache.EXPECT().Get(koordletutil.RDMADeviceType).Return(nil, false)
+	mockMetricCache.EXPECT().Get(koordletutil.FPGADeviceType).Return(nil, false)
+	r := &statesInformer{
+		deviceClient: fakeClient,
+		metricsCache: mockMetricCache,
+		states: &PluginState{
+			informerPlugins: map[PluginName]informerPlugin{
+				nodeInformerName: &nodeInformer{
+					node: testNode,
+				},
+			},
+		},
+		getGPUDriverAndModelFunc: func() (string, string) {
+			return "A100", "470"
+		},
+	}
+	r.reportDevice()
+
+	device, err := fakeClient.Get(context.TODO(), "test", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "gpu-1", device.Annotations["example.com/maintenance"])
+	assert.Equal(t, map[string]string{
+		"example.com/owner":               "ops",
+		extension.LabelGPUModel:           "A100",
+		extension.LabelGPUDriverVersion:   "470",
+		extension.LabelGPUCoreGranularity: "100",
+	}, device.Labels)
+	expectedDevices := []schedulingv1alpha1.DeviceInfo{
+		{
+			UUID:        "1",
+			Minor:       pointer.Int32(1),
+			Type:        schedulingv1alpha1.GPU,
+			Health:      true,
+			HealthState: schedulingv1alpha1.DeviceHealthStateHealthy,
+			Resources: map[corev1.ResourceName]resource.Quantity{
+				extension.ResourceGPUCore:        *resource.NewQuantity(100, resource.DecimalSI),
+				extension.ResourceGPUMemory:      *resource.NewQuantity(8000, resource.BinarySI),
+				extension.ResourceGPUMemoryRatio: *resource.NewQuantity(100, resource.DecimalSI),
+			},
+		},
+		{
+			UUID:   "npu-0",
+			Minor:  pointer.Int32(0),
+			Type:   schedulingv1alpha1.DeviceType("npu"),
+			Health: true,
+		},
+	}
+	assertDevicesEqual(t, expectedDevices, device.Spec.Devices)
+}
+
+func Test_reportDeviceWithGPUCoreGranularity(t *testing.T) {
+	testNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	fakeClient := schedulingfake.NewSimpleClientset().SchedulingV1alpha1().Devices()
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	gpuDeviceInfo := koordletutil.GPUDevices{
+		{UUID: "1", Minor: 1, MemoryTotal: 8000},
+	}
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(gpuDeviceInfo, true)
+	mockMetricCache.EXPECT().Get(koordletutil.RDMADeviceType).Return(nil, false)
+	mockMetricCache.EXPECT().Get(koordletutil.FPGADeviceType).Return(nil, false)
+	cfg := NewDefaultConfig()
+	cfg.GPUCoreGranularity = 1000
+	r := &statesInformer{
+		config:       cfg,
+		deviceClient: fakeClient,
+		metricsCache: mockMetricCache,
+		states: &PluginState{
+			informerPlugins: map[PluginName]informerPlugin{
+				nodeInformerName: &nodeInformer{
+					node: testNode,
+				},
+			},
+		},
+		getGPUDriverAndModelFunc: func() (string, string) {
+			return "A100", "470"
+		},
+	}
+	r.reportDevice()
+
+	device, err := fakeClient.Get(context.TODO(), "test", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "1000", device.Labels[extension.LabelGPUCoreGranularity])
+	assert.Equal(t, 1, len(device.Spec.Devices))
+	gpuCore := device.Spec.Devices[0].Resources[extension.ResourceGPUCore]
+	assert.Equal(t, int64(1000), gpuCore.Value())
+	assert.Equal(t, extension.GetGPUCoreGranularity(device), gpuCore.Value())
+}
+
+func Test_reportDeviceWithGPUMemoryGranularity(t *testing.T) {
+	testNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	fakeClient := schedulingfake.NewSimpleClientset().SchedulingV1alpha1().Devices()
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	gpuDeviceInfo := koordletutil.GPUDevices{
+		{UUID: "1", Minor: 1, MemoryTotal: 85899345920 - 100<<20},
+	}
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(gpuDeviceInfo, true)
+	mockMetricCache.EXPECT().Get(koordletutil.RDMADeviceType).Return(nil, false)
+	mockMetricCache.EXPECT().Get(koordletutil.FPGADeviceType).Return(nil, false)
+	cfg := NewDefaultConfig()
+	cfg.GPUMemoryGranularity = 1 << 30
+	r := &statesInformer{
+		config:       cfg,
+		deviceClient: fakeClient,
+		metricsCache: mockMetricCache,
+		states: &PluginState{
+			informerPlugins: map[PluginName]informerPlugin{
+				nodeInformerName: &nodeInformer{
+					node: testNode,
+				},
+			},
+		},
+		getGPUDriverAndModelFunc: func() (string, string) {
+			return "A100", "470"
+		},
+	}
+	r.reportDevice()
+
+	device, err := fakeClient.Get(context.TODO(), "test", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "1073741824", device.Labels[extension.LabelGPUMemoryGranularity])
+	assert.Equal(t, 1, len(device.Spec.Devices))
+	gpuMemory := device.Spec.Devices[0].Resources[extension.ResourceGPUMemory]
+	expectedGPUMemory := resource.MustParse("79Gi")
+	assert.Equal(t, expectedGPUMemory.Value(), gpuMemory.Value())
+	assert.Equal(t, int64(1<<30), extension.GetGPUMemoryGranularity(device))
+}
+
+func Test_reportFPGADevice(t *testing.T) {
+	testNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	fakeClient := schedulingfake.NewSimpleClientset().SchedulingV1alpha1().Devices()
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	gpuDeviceInfo := koordletutil.GPUDevices{
+		{UUID: "1", Minor: 1, MemoryTotal: 8000},
+	}
+	fpgaDeviceInfo := koordletutil.FPGADevices{
+		{ID: "0000:3b:00.1", Minor: 0, Driver: "xocl", NodeID: 0, PCIE: "pci0000:3a", BusID: "0000:3b:00.1"},
+		{ID: "0000:d8:00.1", Minor: 1, NodeID: 1, PCIE: "pci0000:d7", BusID: "0000:d8:00.1", Unhealthy: true},
+	}
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(gpuDeviceInfo, true)
+	mockMetricCache.EXPECT().Get(koordletutil.RDMADeviceType).Return(nil, false)
+	mockMetricCache.EXPECT().Get(koordletutil.FPGADeviceType).Return(fpgaDeviceInfo, true)
+	r := &statesInformer{
+		deviceClient: fakeClient,
+		metricsCache: mockMetricCache,
+		states: &PluginState{
+			informerPlugins: map[PluginName]informerPlugin{
+				nodeInformerName: &nodeInformer{
+					node: testNode,
+				},
+			},
+		},
+		getGPUDriverAndModelFunc: func() (string, string) {
+			return "A100", "470"
+		},
+	}
+	assert.NoError(t, r.reportDevice())
+
+	device, err := fakeClient.Get(context.TODO(), "test", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(device.Spec.Devices))
+	expectedFPGADevices := []schedulingv1alpha1.DeviceInfo{
+		{
+			UUID:   "0000:3b:00.1",
+			Minor:  pointer.Int32(0),
+			Type:   schedulingv1alpha1.FPGA,
+			Health: true,
+			Resources: map[corev1.ResourceName]resource.Quantity{
+				extension.ResourceFPGA: *resource.NewQuantity(100, resource.DecimalSI),
+			},
+			Topology: &schedulingv1alpha1.DeviceTopology{
+				SocketID: -1,
+				NodeID:   0,
+				PCIEID:   "pci0000:3a",
+				BusID:    "0000:3b:00.1",
+			},
+		},
+		{
+			UUID:   "0000:d8:00.1",
+			Minor:  pointer.Int32(1),
+			Type:   schedulingv1alpha1.FPGA,
+			Health: false,
+			Resources: map[corev1.ResourceName]resource.Quantity{
+				extension.ResourceFPGA: *resource.NewQuantity(100, resource.DecimalSI),
+			},
+			Topology: &schedulingv1alpha1.DeviceTopology{
+				SocketID: -1,
+				NodeID:   1,
+				PCIEID:   "pci0000:d7",
+				BusID:    "0000:d8:00.1",
+			},
+		},
+	}
+	// the devices are sorted by the type, and fpga is before gpu
+	assert.Equal(t, expectedFPGADevices, clearDeviceReportTime(device.Spec.Devices[:2]))
+	assert.Equal(t, schedulingv1alpha1.GPU, device.Spec.Devices[2].Type)
+}
+
+func Test_getGPUDriverAndModel(t *testing.T) {
+	tests := []struct {
+		name         string
+		gpuAvailable bool
+		devices      []*fakeNVMLDevice
+		wantModel    string
+		wantDriver   string
+	}{
+		{
+			name:         "nvml is not available",
+			gpuAvailable: false,
+			devices:      []*fakeNVMLDevice{{uuid: "1", name: "NVIDIA A100 SXM4 80GB"}},
+		},
+		{
+			name:         "no gpu device",
+			gpuAvailable: true,
+		},
+		{
+			name:         "gpus of the same model",
+			gpuAvailable: true,
+			devices: []*fakeNVMLDevice{
+				{uuid: "1", name: "NVIDIA A100 SXM4 80GB"},
+				{uuid: "2", name: "NVIDIA A100 SXM4 80GB"},
+			},
+			wantModel:  "A100-SXM4-80GB",
+			wantDriver: "470.82.01",
+		},
+		{
+			name:         "gpus of different models",
+			gpuAvailable: true,
+			devices: []*fakeNVMLDevice{
+				{uuid: "1", name: "NVIDIA A100 SXM4 80GB"},
+				{uuid: "2", name: "Tesla T4"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &statesInformer{
+				nvml:         newFakeNVML("470.82.01", tt.devices...),
+				gpuAvailable: tt.gpuAvailable,
+			}
+			gotModel, gotDriver := s.getGPUDriverAndModel()
+			assert.Equal(t, tt.wantModel, gotModel)
+			assert.Equal(t, tt.wantDriver, gotDriver)
+		})
+	}
+}
+
+func Test_getCUDADriverVersion(t *testing.T) {
+	fakeNVML := newFakeNVML("470.82.01", &fakeNVMLDevice{uuid: "1"})
+	fakeNVML.cudaDriverVersion = 11040
+	s := &statesInformer{
+		nvml:         fakeNVML,
+		gpuAvailable: true,
+	}
+	assert.Equal(t, "", s.getCUDADriverVersion(""))
+	assert.Equal(t, "11.4", s.getCUDADriverVersion("470.82.01"))
+	// the cached version is returned if the driver does not change
+	fakeNVML.cudaDriverVersion = 12020
+	assert.Equal(t, "11.4", s.getCUDADriverVersion("470.82.01"))
+	assert.Equal(t, int32(1), fakeNVML.cudaDriverVersionCalls.Load())
+	// re-query after the driver changes
+	assert.Equal(t, "12.2", s.getCUDADriverVersion("535.104.05"))
+	assert.Equal(t, int32(2), fakeNVML.cudaDriverVersionCalls.Load())
+	// not cached if the query fails
+	fakeNVML.cudaDriverVersion = 0
+	assert.Equal(t, "", s.getCUDADriverVersion("470.82.01"))
+	assert.Equal(t, "", s.getCUDADriverVersion("470.82.01"))
+	assert.Equal(t, int32(4), fakeNVML.cudaDriverVersionCalls.Load())
+
+	s.gpuAvailable = false
+	assert.Equal(t, "", s.getCUDADriverVersion("470.82.01"))
+}
+
+func Test_gpuHealCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		devices []*fakeNVMLDevice
+		xids    map[string]uint64
+		// events are injected as is before the xids
+		events []nvmlEventData
+		policy XidHealthPolicy
+		// keepUnmonitoredHealthy keeps the gpus not supporting health check healthy
+		keepUnmonitoredHealthy bool
+		// paused pauses the device reporting by the node annotation
+		paused        bool
+		wantUnhealthy map[string]struct{}
+	}{
+		{
+			name: "application xid does not mark gpu unhealthy",
+			devices: []*fakeNVMLDevice{
+				{uuid: "1"},
+				{uuid: "2"},
+			},
+			xids:          map[string]uint64{"1": 13},
+			wantUnhealthy: map[string]struct{}{},
+		},
+		{
+			name: "critical xid marks gpu unhealthy",
+			devices: []*fakeNVMLDevice{
+				{uuid: "1"},
+				{uuid: "2"},
+			},
+			xids:          map[string]uint64{"1": 79},
+			wantUnhealthy: map[string]struct{}{"1": {}},
+		},
+		{
+			name: "critical xid does not mark gpu unhealthy when device reporting is paused",
+			devices: []*fakeNVMLDevice{
+				{uuid: "1"},
+				{uuid: "2"},
+			},
+			xids:          map[string]uint64{"1": 79},
+			paused:        true,
+			wantUnhealthy: map[string]struct{}{},
+		},
+		{
+			name: "critical xid without device does not mark reachable gpus unhealthy",
+			devices: []*fakeNVMLDevice{
+				{uuid: "1"},
+				{uuid: "2"},
+			},
+			xids:          map[string]uint64{"": 48},
+			wantUnhealthy: map[string]struct{}{},
+		},
+		{
+			name: "critical xid without device marks unreachable gpus unhealthy",
+			devices: []*fakeNVMLDevice{
+				{uuid: "1"},
+				{uuid: "2", lost: true},
+			},
+			xids:          map[string]uint64{"": 79},
+			wantUnhealthy: map[string]struct{}{"2": {}},
+		},
+		{
+			name: "gpu not supporting health check is unhealthy",
+			devices: []*fakeNVMLDevice{
+				{uuid: "1"},
+				{uuid: "2", registerRet: nvml.ERROR_NOT_SUPPORTED},
+			},
+			wantUnhealthy: map[string]struct{}{"2": {}},
+		},
+		{
+			name: "gpu not supporting health check is kept healthy if configured",
+			devices: []*fakeNVMLDevice{
+				{uuid: "1"},
+				{uuid: "2", registerRet: nvml.ERROR_NOT_SUPPORTED},
+			},
+			xids:                   map[string]uint64{"1": 79},
+			keepUnmonitoredHealthy: true,
+			wantUnhealthy:          map[string]struct{}{"1": {}},
+		},
+		{
+			name: "custom policy fails the node on an application xid",
+			devices: []*fakeNVMLDevice{
+				{uuid: "1"},
+				{uuid: "2"},
+			},
+			xids: map[string]uint64{"1": 13},
+			policy: func(event XidEvent) GPUHealthDecision {
+				if event.Xid == 13 && event.DeviceUUID == "1" && !event.Timestamp.IsZero() {
+					return GPUNodeFailed
+				}
+				return GPUHealthy
+			},
+			wantUnhealthy: map[string]struct{}{"1": {}, "2": {}},
+		},
+		{
+			name: "custom policy degrades a reachable gpu",
+			devices: []*fakeNVMLDevice{
+				{uuid: "1"},
+				{uuid: "2"},
+			},
+			xids: map[string]uint64{"1": 79},
+			policy: func(event XidEvent) GPUHealthDecision {
+				return GPUDegraded
+			},
+			wantUnhealthy: map[string]struct{}{},
+		},
+		{
+			name: "custom policy fails all gpus on a xid without device",
+			devices: []*fakeNVMLDevice{
+				{uuid: "1"},
+				{uuid: "2"},
+			},
+			xids: map[string]uint64{"": 48},
+			policy: func(event XidEvent) GPUHealthDecision {
+				return GPUFailed
+			},
+			wantUnhealthy: map[string]struct{}{"1": {}, "2": {}},
+		},
+		{
+			name: "events other than xid critical errors are ignored",
+			devices: []*fakeNVMLDevice{
+				{uuid: "1"},
+				{uuid: "2"},
+			},
+			events: []nvmlEventData{
+				{Device: &fakeNVMLDevice{uuid: "1"}, EventType: nvml.EventTypeSingleBitEccError, EventData: 79},
+				{EventType: nvml.EventTypePState},
+			},
+			xids:          map[string]uint64{"2": 79},
+			wantUnhealthy: map[string]struct{}{"2": {}},
+		},
+		{
+			name: "xid critical error without device handle marks unreachable gpus unhealthy",
+			devices: []*fakeNVMLDevice{
+				{uuid: "1"},
+				{uuid: "2", lost: true},
+			},
+			events: []nvmlEventData{
+				newNVMLEventData(nvml.EventData{EventType: nvml.EventTypeXidCriticalError, EventData: 79}),
+			},
+			wantUnhealthy: map[string]struct{}{"2": {}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeNVML := newFakeNVML("470.82.01", tt.devices...)
+			for _, e := range tt.events {
+				fakeNVML.sendEvent(e)
+			}
+			for uuid, xid := range tt.xids {
+				fakeNVML.sendXid(uuid, xid)
+			}
+			cfg := NewDefaultConfig()
+			cfg.GPUHealthCheckWaitTimeout = 10 * time.Millisecond
+			cfg.MarkUnmonitoredGPUUnhealthy = !tt.keepUnmonitoredHealthy
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+			if tt.paused {
+				node.Annotations = map[string]string{extension.AnnotationDeviceReportPaused: "true"}
+			}
+			s := &statesInformer{
+				config:          cfg,
+				nvml:            fakeNVML,
+				xidHealthPolicy: tt.policy,
+				unhealthyGPU:    map[string]struct{}{},
+				states: &PluginState{
+					informerPlugins: map[PluginName]informerPlugin{
+						nodeInformerName: &nodeInformer{
+							node: node,
+						},
+					},
+				},
+			}
+
+			stopCh := make(chan struct{})
+			done := make(chan struct{})
+			go func() {
+				s.gpuHealCheck(stopCh)
+				close(done)
+			}()
+			// all injected events are consumed after the event channel is drained and a wait times out
+			assert.Eventually(t, func() bool {
+				return len(fakeNVML.events) == 0
+			}, time.Second, 5*time.Millisecond)
+			time.Sleep(5 * cfg.GPUHealthCheckWaitTimeout)
+			close(stopCh)
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("gpu health check is not stopped")
+			}
+
+			s.gpuMutex.RLock()
+			defer s.gpuMutex.RUnlock()
+			assert.Equal(t, tt.wantUnhealthy, s.unhealthyGPU)
+		})
+	}
+}
+
+func Test_newNVMLEventData(t *testing.T) {
+	e := newNVMLEventData(nvml.EventData{EventType: nvml.EventTypeXidCriticalError, EventData: 79, GpuInstanceId: 1, ComputeInstanceId: 2})
+	assert.Nil(t, e.Device, "the event without device handle should have no device")
+	assert.Equal(t, nvmlEventData{EventType: nvml.EventTypeXidCriticalError, EventData: 79, GpuInstanceId: 1, ComputeInstanceId: 2}, e)
+}
+
+func Test_IsGPUHealthy(t *testing.T) {
+	s := &statesInformer{
+		unhealthyGPU: map[string]struct{}{"3": {}, "1": {}},
+	}
+	assert.False(t, s.IsGPUHealthy("1"))
+	assert.True(t, s.IsGPUHealthy("2"))
+	assert.Equal(t, []string{"1", "3"}, s.ListUnhealthyGPUs())
+
+	s.unhealthyGPU = map[string]struct{}{}
+	assert.True(t, s.IsGPUHealthy("1"))
+	assert.Equal(t, []string{}, s.ListUnhealthyGPUs())
+}
+
+func Test_gpuHealthObserver(t *testing.T) {
+	fakeNVML := newFakeNVML("470.82.01", &fakeNVMLDevice{uuid: "1"}, &fakeNVMLDevice{uuid: "2"},
+		&fakeNVMLDevice{uuid: "3", registerRet: nvml.ERROR_NOT_SUPPORTED})
+	fakeNVML.sendXid("1", 79)
+	fakeNVML.sendXid("1", 79)
+	cfg := NewDefaultConfig()
+	cfg.GPUHealthCheckWaitTimeout = 10 * time.Millisecond
+	s := &statesInformer{
+		config:       cfg,
+		nvml:         fakeNVML,
+		unhealthyGPU: map[string]struct{}{},
+		gpuAvailable: true,
+		states: &PluginState{
+			informerPlugins: map[PluginName]informerPlugin{
+				nodeInformerName: &nodeInformer{
+					node: &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+				},
+			},
+		},
+	}
+	var lock sync.Mutex
+	var transitions []statesinformer.GPUHealthTransition
+	s.RegisterGPUHealthObserver("test", func(transition statesinformer.GPUHealthTransition) {
+		lock.Lock()
+		defer lock.Unlock()
+		transitions = append(transitions, transition)
+	})
+
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		s.gpuHealCheck(stopCh)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool {
+		return len(fakeNVML.events) == 0
+	}, time.Second, 5*time.Millisecond)
+	time.Sleep(5 * cfg.GPUHealthCheckWaitTimeout)
+	close(stopCh)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("gpu health check is not stopped")
+	}
+
+	// the gpu failing continuously is notified only once
+	lock.Lock()
+	assert.ElementsMatch(t, []statesinformer.GPUHealthTransition{
+		{DeviceUUID: "1", OldHealthy: true, NewHealthy: false, Reason: statesinformer.GPUHealthReasonXidError, Xid: 79},
+		{DeviceUUID: "3", OldHealthy: true, NewHealthy: false, Reason: statesinformer.GPUHealthReasonUnmonitored},
+	}, transitions)
+	transitions = nil
+	lock.Unlock()
+
+	// the unhealthy gpu no longer discovered is recovered
+	s.pruneUnhealthyGPUs(koordletutil.GPUDevices{{UUID: "1"}, {UUID: "2"}})
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, []statesinformer.GPUHealthTransition{
+		{DeviceUUID: "3", OldHealthy: false, NewHealthy: true, Reason: statesinformer.GPUHealthReasonNotDiscovered},
+	}, transitions)
+}
+
+func Test_gpuHealCheckRestart(t *testing.T) {
+	tests := []struct {
+		name     string
+		waitHook func(stuckCh <-chan struct{}) func(eventSetIndex int32)
+	}{
+		{
+			name: "restart the health check after it panics",
+			waitHook: func(stuckCh <-chan struct{}) func(eventSetIndex int32) {
+				return func(eventSetIndex int32) {
+					if eventSetIndex == 1 {
+						panic("test panic")
+					}
+				}
+			},
+		},
+		{
+			name: "restart the health check after its event loop is stale",
+			waitHook: func(stuckCh <-chan struct{}) func(eventSetIndex int32) {
+				return func(eventSetIndex int32) {
+					if eventSetIndex == 1 {
+						<-stuckCh
+					}
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldBackoff := gpuHealthCheckRestartBackoff
+			defer func() {
+				gpuHealthCheckRestartBackoff = oldBackoff
+			}()
+			gpuHealthCheckRestartBackoff = 10 * time.Millisecond
+
+			stuckCh := make(chan struct{})
+			defer close(stuckCh)
+			fakeNVML := newFakeNVML("470.82.01", &fakeNVMLDevice{uuid: "1"}, &fakeNVMLDevice{uuid: "2"})
+			fakeNVML.waitHook = tt.waitHook(stuckCh)
+			cfg := NewDefaultConfig()
+			cfg.GPUHealthCheckWaitTimeout = 10 * time.Millisecond
+			cfg.GPUHealthCheckStaleThreshold = 50 * time.Millisecond
+			s := &statesInformer{
+				config:       cfg,
+				nvml:         fakeNVML,
+				unhealthyGPU: map[string]struct{}{},
+				states: &PluginState{
+					informerPlugins: map[PluginName]informerPlugin{
+						nodeInformerName: &nodeInformer{
+							node: &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+						},
+					},
+				},
+			}
+
+			stopCh := make(chan struct{})
+			done := make(chan struct{})
+			go func() {
+				s.gpuHealCheck(stopCh)
+				close(done)
+			}()
+			assert.Eventually(t, func() bool {
+				return fakeNVML.eventSetCreated.Load() >= 2
+			}, time.Second, 5*time.Millisecond)
+			// the restarted health check still handles the events
+			fakeNVML.sendXid("1", 79)
+			assert.Eventually(t, func() bool {
+				s.gpuMutex.RLock()
+				defer s.gpuMutex.RUnlock()
+				_, ok := s.unhealthyGPU["1"]
+				return ok
+			}, time.Second, 5*time.Millisecond)
+			close(stopCh)
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("gpu health check is not stopped")
+			}
+		})
+	}
+}
+
+func Test_shutdownGPU(t *testing.T) {
+	oldTimeout := gpuShutdownTimeout
+	defer func() {
+		gpuShutdownTimeout = oldTimeout
+	}()
+	gpuShutdownTimeout = 100 * time.Millisecond
+
+	fakeNVML := newFakeNVML("470.82.01", &fakeNVMLDevice{uuid: "1"}, &fakeNVMLDevice{uuid: "2"})
+	cfg := NewDefaultConfig()
+	cfg.GPUHealthCheckWaitTimeout = 10 * time.Millisecond
+	s := &statesInformer{
+		config:       cfg,
+		nvml:         fakeNVML,
+		gpuAvailable: true,
+		unhealthyGPU: map[string]struct{}{},
+		states: &PluginState{
+			informerPlugins: map[PluginName]informerPlugin{
+				nodeInformerName: &nodeInformer{
+					node: &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+				},
+			},
+		},
+	}
+
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.gpuHealCheck(stopCh)
+	}()
+	assert.Eventually(t, func() bool {
+		return fakeNVML.eventSetCreated.Load() >= 1
+	}, time.Second, 5*time.Millisecond)
+	close(stopCh)
+	s.shutdownGPU(done)
+	// nvml is shut down after the event sets are freed
+	assert.Equal(t, fakeNVML.eventSetCreated.Load(), fakeNVML.eventSetFreed.Load())
+	assert.Equal(t, int32(1), fakeNVML.shutdownCalls.Load())
+
+	// nvml is not shut down if the health check does not exit in time
+	fakeNVML = newFakeNVML("470.82.01")
+	s.nvml = fakeNVML
+	s.shutdownGPU(make(chan struct{}))
+	assert.Equal(t, int32(0), fakeNVML.shutdownCalls.Load())
+
+	// nvml is not shut down if it is not initialized
+	s.gpuAvailable = false
+	done = make(chan struct{})
+	close(done)
+	s.shutdownGPU(done)
+	assert.Equal(t, int32(0), fakeNVML.shutdownCalls.Load())
+}
+
+func Test_discoverGPUDevices(t *testing.T) {
+	devices := make([]*fakeNVMLDevice, 16)
+	for i := range devices {
+		devices[i] = &fakeNVMLDevice{uuid: fmt.Sprintf("GPU-%d", i), minor: i}
+	}
+	want := make([]string, 0, len(devices))
+	for _, d := range devices {
+		want = append(want, d.uuid)
+	}
+	lib := newFakeNVML("470.82.01", devices...)
+	for _, parallelism := range []int{1, 4, 32} {
+		assert.Equal(t, want, discoverGPUDevices(lib, "test-node", len(devices), parallelism), "parallelism %d", parallelism)
+	}
+	// the devices which cannot be queried are skipped
+	assert.Equal(t, want, discoverGPUDevices(lib, "test-node", len(devices)+2, 4))
+}
+
+func Test_registerGPUEvents(t *testing.T) {
+	lib := newFakeNVML("470.82.01",
+		&fakeNVMLDevice{uuid: "GPU-0", minor: 0},
+		&fakeNVMLDevice{uuid: "GPU-1", minor: 1, registerRet: nvml.ERROR_NOT_SUPPORTED},
+		&fakeNVMLDevice{uuid: "GPU-2", minor: 2, lost: true},
+		&fakeNVMLDevice{uuid: "GPU-3", minor: 3, registerRet: nvml.ERROR_NOT_SUPPORTED},
+		&fakeNVMLDevice{uuid: "GPU-4", minor: 4, registerRet: nvml.ERROR_UNKNOWN},
+	)
+	eventSet, ret := lib.EventSetCreate()
+	assert.Equal(t, nvml.SUCCESS, ret)
+	devs := []string{"GPU-0", "GPU-1", "GPU-2", "GPU-3", "GPU-4"}
+	for _, parallelism := range []int{1, 8} {
+		assert.Equal(t, []string{"GPU-1", "GPU-3"}, registerGPUEvents(lib, eventSet, "test-node", devs, parallelism), "parallelism %d", parallelism)
+	}
+}
+
+func Benchmark_discoverGPUDevices(b *testing.B) {
+	devices := make([]*fakeNVMLDevice, 16)
+	for i := range devices {
+		devices[i] = &fakeNVMLDevice{uuid: fmt.Sprintf("GPU-%d", i), minor: i}
+	}
+	lib := newFakeNVML("470.82.01", devices...)
+	lib.handleLatency = 5 * time.Millisecond
+	for _, parallelism := range []int{1, gpuDiscoveryParallelism} {
+		b.Run(fmt.Sprintf("parallelism-%d", parallelism), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				devs := discoverGPUDevices(lib, "test-node", len(devices), parallelism)
+				eventSet, _ := lib.EventSetCreate()
+				registerGPUEvents(lib, eventSet, "test-node", devs, parallelism)
+			}
+		})
+	}
+}
+
+func Test_reportDeviceCreateOrUpdate(t *testing.T) {
+	testNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	fakeClientSet := schedulingfake.NewSimpleClientset()
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	gpuDeviceInfo := koordletutil.GPUDevices{
+		{UUID: "1", Minor: 0, MemoryTotal: 8000},
+		{UUID: "2", Minor: 1, MemoryTotal: 8000},
+	}
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(gpuDeviceInfo, true).AnyTimes()
+	mockMetricCache.EXPECT().Get(koordletutil.RDMADeviceType).Return(nil, false).AnyTimes()
+	mockMetricCache.EXPECT().Get(koordletutil.FPGADeviceType).Return(nil, false).AnyTimes()
+	s := &statesInformer{
+		nvml: newFakeNVML("470.82.01",
+			&fakeNVMLDevice{uuid: "1", name: "NVIDIA A100 SXM4 80GB"},
+			&fakeNVMLDevice{uuid: "2", name: "NVIDIA A100 SXM4 80GB"},
+		),
+		gpuAvailable: true,
+		deviceClient: fakeClientSet.SchedulingV1alpha1().Devices(),
+		metricsCache: mockMetricCache,
+		unhealthyGPU: map[string]struct{}{},
+		states: &PluginState{
+			informerPlugins: map[PluginName]informerPlugin{
+				nodeInformerName: &nodeInformer{
+					node: testNode,
+				},
+			},
+		},
+	}
+	s.getGPUDriverAndModelFunc = s.getGPUDriverAndModel
+	countActions := func(verb string) int {
+		count := 0
+		for _, action := range fakeClientSet.Actions() {
+			if action.GetVerb() == verb && action.GetResource().Resource == "devices" {
+				count++
+			}
+		}
+		return count
+	}
+
+	// the Device is created at the first time
+	s.reportDevice()
+	assert.Equal(t, 1, countActions("create"))
+	assert.Equal(t, 0, countActions("patch"))
+	device, err := fakeClientSet.SchedulingV1alpha1().Devices().Get(context.TODO(), "test", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "A100-SXM4-80GB", device.Labels[extension.LabelGPUModel])
+	assert.Equal(t, "470.82.01", device.Labels[extension.LabelGPUDriverVersion])
+	for _, d := range device.Spec.Devices {
+		assert.True(t, d.Health)
+	}
+
+	// the Device is updated when a gpu turns unhealthy
+	s.gpuMutex.Lock()
+	s.unhealthyGPU["2"] = struct{}{}
+	s.gpuMutex.Unlock()
+	s.reportDevice()
+	assert.Equal(t, 1, countActions("create"))
+	assert.Equal(t, 1, countActions("patch"))
+	device, err = fakeClientSet.SchedulingV1alpha1().Devices().Get(context.TODO(), "test", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(device.Spec.Devices))
+	assert.True(t, device.Spec.Devices[0].Health)
+	assert.False(t, device.Spec.Devices[1].Health)
+}
+
+func Test_buildGPUDeviceWithCodecResources(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	gpuDeviceInfo := koordletutil.GPUDevices{
+		{UUID: "1", Minor: 0, MemoryTotal: 8000, EncoderSupported: true, DecoderSupported: true},
+		{UUID: "2", Minor: 1, MemoryTotal: 8000},
+	}
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(gpuDeviceInfo, true).AnyTimes()
+	cfg := NewDefaultConfig()
+	s := &statesInformer{
+		config:       cfg,
+		metricsCache: mockMetricCache,
+	}
+
+	devices, err := s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(devices))
+	_, ok := devices[0].Resources[extension.ResourceGPUEncoder]
+	assert.False(t, ok, "codec resources should not be reported if disabled")
+
+	cfg.EnableGPUCodecResources = true
+	devices, err = s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(devices))
+	encoder := devices[0].Resources[extension.ResourceGPUEncoder]
+	assert.Equal(t, int64(100), encoder.Value())
+	decoder := devices[0].Resources[extension.ResourceGPUDecoder]
+	assert.Equal(t, int64(100), decoder.Value())
+	_, ok = devices[1].Resources[extension.ResourceGPUEncoder]
+	assert.False(t, ok, "codec resources should not be reported for the gpu without video engines")
+}
+
+func Test_buildGPUDeviceWithBAR1MemoryResource(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	gpuDeviceInfo := koordletutil.GPUDevices{
+		{UUID: "1", Minor: 0, MemoryTotal: 8000, BAR1MemoryTotal: 256 << 20},
+		{UUID: "2", Minor: 1, MemoryTotal: 8000},
+	}
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(gpuDeviceInfo, true).AnyTimes()
+	cfg := NewDefaultConfig()
+	s := &statesInformer{
+		config:       cfg,
+		metricsCache: mockMetricCache,
+	}
+
+	devices, err := s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(devices))
+	_, ok := devices[0].Resources[extension.ResourceGPUBAR1Memory]
+	assert.False(t, ok, "bar1 memory should not be reported if disabled")
+
+	cfg.EnableGPUBAR1MemoryResource = true
+	cfg.GPUMemoryGranularity = 1 << 30
+	devices, err = s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(devices))
+	bar1Memory := devices[0].Resources[extension.ResourceGPUBAR1Memory]
+	assert.Equal(t, int64(256<<20), bar1Memory.Value(), "bar1 memory should not be rounded by the gpu memory granularity")
+	_, ok = devices[1].Resources[extension.ResourceGPUBAR1Memory]
+	assert.False(t, ok, "bar1 memory should not be reported for the gpu not supporting it")
+}
+
+func Test_buildGPUDeviceWithNodeProblemConditions(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	gpuDeviceInfo := koordletutil.GPUDevices{
+		{UUID: "GPU-1", Minor: 0, MemoryTotal: 8000},
+		{UUID: "GPU-2", Minor: 1, MemoryTotal: 8000},
+	}
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(gpuDeviceInfo, true).AnyTimes()
+	cfg := NewDefaultConfig()
+	s := &statesInformer{
+		config:       cfg,
+		metricsCache: mockMetricCache,
+	}
+	node := &corev1.Node{
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{
+				{Type: "GPUProblem", Status: corev1.ConditionTrue, Reason: "XidError", Message: "Xid 79 on GPU-2: fallen off the bus"},
+				{Type: "DriverProblem", Status: corev1.ConditionFalse, Reason: "DriverOK"},
+			},
+		},
+	}
+	getHealth := func(devices []schedulingv1alpha1.DeviceInfo) []bool {
+		var health []bool
+		for _, d := range devices {
+			health = append(health, d.Health)
+		}
+		return health
+	}
+
+	// the node conditions are ignored if not configured
+	devices, err := s.buildGPUDevice(node)
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, true}, getHealth(devices))
+
+	// the gpu named in the message of the true condition is unhealthy
+	cfg.GPUHealthNodeConditions = "GPUProblem, DriverProblem"
+	devices, err = s.buildGPUDevice(node)
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, false}, getHealth(devices))
+	assert.Equal(t, schedulingv1alpha1.DeviceHealthStateUnhealthy, devices[1].HealthState)
+
+	// all gpus are unhealthy if the true condition names none of them
+	node.Status.Conditions[1].Status = corev1.ConditionTrue
+	node.Status.Conditions[1].Message = "nvidia driver failed to load"
+	devices, err = s.buildGPUDevice(node)
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false, false}, getHealth(devices))
+
+	// the gpus recover once the conditions are cleared
+	node.Status.Conditions[0].Status = corev1.ConditionFalse
+	node.Status.Conditions[1].Status = corev1.ConditionFalse
+	devices, err = s.buildGPUDevice(node)
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, true}, getHealth(devices))
+}
+
+func Test_buildGPUDeviceWithTimeSlicingReplicas(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	gpuDeviceInfo := koordletutil.GPUDevices{
+		{UUID: "1", Minor: 0, MemoryTotal: 8000, ProductName: "Tesla-T4"},
+		{UUID: "2", Minor: 1, MemoryTotal: 8000, ProductName: "Tesla-T4"},
+		{UUID: "3", Minor: 2, MemoryTotal: 8000, ProductName: "A100-SXM4-80GB"},
+	}
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(gpuDeviceInfo, true).AnyTimes()
+	cfg := NewDefaultConfig()
+	cfg.GPUTimeSlicingReplicas = map[string]string{
+		"Tesla-T4":       "2",
+		"A100-SXM4-80GB": "invalid",
+	}
+	s := &statesInformer{
+		config:       cfg,
+		metricsCache: mockMetricCache,
+		unhealthyGPU: map[string]struct{}{"2": {}},
+	}
+
+	devices, err := s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, len(devices))
+
+	var uuids []string
+	var minors []int32
+	for _, d := range devices {
+		uuids = append(uuids, d.UUID)
+		minors = append(minors, *d.Minor)
+	}
+	assert.Equal(t, []string{"1::0", "1::1", "2::0", "2::1", "3"}, uuids)
+	assert.Equal(t, []int32{0, 1, 2, 3, 4}, minors)
+
+	assert.Equal(t, "2", devices[3].Labels[extension.LabelGPUPhysicalUUID])
+	assert.Equal(t, "1", devices[3].Labels[extension.LabelGPUPhysicalMinor])
+	assert.Equal(t, "1", devices[3].Labels[extension.LabelGPUReplicaIndex])
+	assert.False(t, devices[2].Health, "replicas of an unhealthy gpu should be unhealthy")
+	assert.True(t, devices[0].Health)
+	memory := devices[1].Resources[extension.ResourceGPUMemory]
+	assert.Equal(t, int64(8000), memory.Value(), "each replica reports the whole gpu memory")
+	assert.Equal(t, "2", devices[4].Labels[extension.LabelGPUPhysicalMinor], "gpu with invalid replicas should be reported as one device")
+	assert.Equal(t, "0", devices[4].Labels[extension.LabelGPUReplicaIndex])
+
+	cfg.GPUTimeSlicingReplicas = map[string]string{}
+	devices, err = s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(devices))
+	assert.Equal(t, "3", devices[2].UUID)
+	assert.Equal(t, int32(2), *devices[2].Minor)
+	_, ok := devices[2].Labels[extension.LabelGPUPhysicalUUID]
+	assert.False(t, ok, "labels of time-slicing should not be reported if disabled")
+}
+
+func Test_buildGPUDeviceWithVGPUs(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(nil, false).AnyTimes()
+	cfg := NewDefaultConfig()
+	cfg.EnableVGPUReport = true
+	s := &statesInformer{
+		config:       cfg,
+		metricsCache: mockMetricCache,
+		gpuAvailable: true,
+		unhealthyGPU: map[string]struct{}{"2": {}},
+		nvml: newFakeNVML("470.82.01",
+			&fakeNVMLDevice{uuid: "1", name: "NVIDIA A100-SXM4-80GB", minor: 0, memoryTotal: 80 << 30, vgpus: []*fakeNVMLVgpuInstance{
+				{uuid: "vgpu-b", typeName: "GRID A100-40C", framebufferSize: 40 << 30},
+				{uuid: "vgpu-a", typeName: "GRID A100-40C", framebufferSize: 40 << 30},
+			}},
+			&fakeNVMLDevice{uuid: "2", name: "NVIDIA A100-SXM4-80GB", minor: 1, memoryTotal: 80 << 30, vgpus: []*fakeNVMLVgpuInstance{
+				{uuid: "vgpu-c", typeName: "GRID A100-20C", framebufferSize: 20 << 30},
+			}},
+			// not virtualized
+			&fakeNVMLDevice{uuid: "3", name: "NVIDIA A100-SXM4-80GB", minor: 2, memoryTotal: 80 << 30},
+		),
+	}
+
+	devices, err := s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	var uuids []string
+	var minors []int32
+	for _, d := range devices {
+		uuids = append(uuids, d.UUID)
+		minors = append(minors, *d.Minor)
+	}
+	assert.Equal(t, []string{"vgpu-a", "vgpu-b", "vgpu-c", "3"}, uuids)
+	assert.Equal(t, []int32{0, 1, 2, 4}, minors)
+
+	assert.Equal(t, "GRID A100-40C", devices[1].Labels[extension.LabelGPUVGPUProfile])
+	assert.Equal(t, "1", devices[1].Labels[extension.LabelGPUPhysicalUUID])
+	assert.Equal(t, "0", devices[1].Labels[extension.LabelGPUPhysicalMinor])
+	memory := devices[1].Resources[extension.ResourceGPUMemory]
+	assert.Equal(t, int64(40<<30), memory.Value(), "vGPU reports the framebuffer of its profile")
+	core := devices[1].Resources[extension.ResourceGPUCore]
+	assert.Equal(t, s.getGPUCoreGranularity(), core.Value())
+	assert.True(t, devices[1].Health)
+
+	assert.Equal(t, "GRID A100-20C", devices[2].Labels[extension.LabelGPUVGPUProfile])
+	assert.False(t, devices[2].Health, "vGPUs of an unhealthy gpu should be unhealthy")
+
+	_, ok := devices[3].Labels[extension.LabelGPUVGPUProfile]
+	assert.False(t, ok, "gpu without vGPUs should be reported as is")
+	memory = devices[3].Resources[extension.ResourceGPUMemory]
+	assert.Equal(t, int64(80<<30), memory.Value())
+
+	cfg.EnableVGPUReport = false
+	devices, err = s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(devices))
+	assert.Equal(t, "1", devices[0].UUID)
+	assert.Equal(t, int32(0), *devices[0].Minor)
+}
+
+func Test_buildGPUDeviceWithMigMode(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	gpuDeviceInfo := koordletutil.GPUDevices{
+		{UUID: "1", Minor: 0, MemoryTotal: 8000, ProductName: "Tesla T4"},
+		{UUID: "2", Minor: 1, MemoryTotal: 8000, ProductName: "A100-SXM4-80GB", MigCapable: true},
+		{UUID: "3", Minor: 2, MemoryTotal: 8000, ProductName: "A100-SXM4-80GB", MigCapable: true, MigPendingEnabled: true},
+	}
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(gpuDeviceInfo, true).AnyTimes()
+	s := &statesInformer{
+		config:       NewDefaultConfig(),
+		metricsCache: mockMetricCache,
+	}
+
+	devices, err := s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(devices))
+	assert.Equal(t, map[string]string{
+		extension.LabelGPUProductName: "Tesla T4",
+	}, devices[0].Labels)
+	assert.Equal(t, map[string]string{
+		extension.LabelGPUProductName:       "A100-SXM4-80GB",
+		extension.LabelGPUMigCapable:        "true",
+		extension.LabelGPUMigEnabled:        "false",
+		extension.LabelGPUMigPendingEnabled: "false",
+	}, devices[1].Labels)
+	assert.Equal(t, map[string]string{
+		extension.LabelGPUProductName:       "A100-SXM4-80GB",
+		extension.LabelGPUMigCapable:        "true",
+		extension.LabelGPUMigEnabled:        "false",
+		extension.LabelGPUMigPendingEnabled: "true",
+	}, devices[2].Labels)
+}
+
+func Test_buildGPUDeviceWithPersistenceMode(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	gpuDeviceInfo := koordletutil.GPUDevices{
+		{UUID: "1", Minor: 0, MemoryTotal: 8000},
+		{UUID: "2", Minor: 1, MemoryTotal: 8000, PersistenceModeEnabled: pointer.Bool(true)},
+		{UUID: "3", Minor: 2, MemoryTotal: 8000, PersistenceModeEnabled: pointer.Bool(false)},
+	}
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(gpuDeviceInfo, true).AnyTimes()
+	s := &statesInformer{
+		config:       NewDefaultConfig(),
+		metricsCache: mockMetricCache,
+	}
+
+	devices, err := s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(devices))
+	assert.Nil(t, devices[0].Labels)
+	assert.Equal(t, map[string]string{
+		extension.LabelGPUPersistenceModeEnabled: "true",
+	}, devices[1].Labels)
+	assert.Equal(t, map[string]string{
+		extension.LabelGPUPersistenceModeEnabled: "false",
+	}, devices[2].Labels)
+}
+
+func Test_buildGPUDeviceFallbackToNVML(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(nil, false).AnyTimes()
+	s := &statesInformer{
+		config:       NewDefaultConfig(),
+		metricsCache: mockMetricCache,
+		gpuAvailable: true,
+		nvml: newFakeNVML("470.82.01",
+			&fakeNVMLDevice{uuid: "1", name: "NVIDIA A100-SXM4-80GB", minor: 0, memoryTotal: 8000},
+			&fakeNVMLDevice{uuid: "2", name: "NVIDIA A100-SXM4-80GB", minor: 1, memoryTotal: 8000},
+		),
+	}
+
+	devices, err := s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(devices))
+	for i, d := range devices {
+		assert.Equal(t, fmt.Sprint(i+1), d.UUID)
+		assert.Equal(t, int32(i), *d.Minor)
+		assert.True(t, d.Health)
+		assert.Nil(t, d.Topology)
+		assert.Equal(t, "A100-SXM4-80GB", d.Labels[extension.LabelGPUProductName])
+		memory := d.Resources[extension.ResourceGPUMemory]
+		assert.Equal(t, int64(8000), memory.Value())
+	}
+
+	s.nvml = newFakeNVML("470.82.01")
+	devices, err = s.buildGPUDevice(nil)
+	assert.Error(t, err, "should fail if no gpu is found by nvml")
+	assert.Nil(t, devices)
+}
+
+func Test_buildGPUDevicePruneUnhealthyGPUs(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(nil, false).AnyTimes()
+	s := &statesInformer{
+		config:       NewDefaultConfig(),
+		metricsCache: mockMetricCache,
+		gpuAvailable: true,
+		// the uuid of gpu 2 is changed by the MIG reconfiguration
+		unhealthyGPU: map[string]struct{}{"1": {}, "2-old": {}},
+		nvml: newFakeNVML("470.82.01",
+			&fakeNVMLDevice{uuid: "1", name: "NVIDIA A100-SXM4-80GB", minor: 0, memoryTotal: 8000},
+			&fakeNVMLDevice{uuid: "2", name: "NVIDIA A100-SXM4-80GB", minor: 1, memoryTotal: 8000},
+		),
+	}
+
+	devices, err := s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(devices))
+	assert.False(t, devices[0].Health)
+	assert.True(t, devices[1].Health)
+	assert.Equal(t, []string{"1"}, s.ListUnhealthyGPUs())
+
+	// the gpus collected in the metric cache without nvml do not prune the unhealthy gpus
+	s.gpuAvailable = false
+	s.unhealthyGPU["2-old"] = struct{}{}
+	mockMetricCache = mock_metriccache.NewMockMetricCache(ctl)
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(koordletutil.GPUDevices{
+		{UUID: "1", Minor: 0, MemoryTotal: 8000},
+	}, true).AnyTimes()
+	s.metricsCache = mockMetricCache
+	_, err = s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1", "2-old"}, s.ListUnhealthyGPUs())
+}
+
+func Test_buildGPUDeviceWithPartiallyCollected(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	gpuDeviceInfo := koordletutil.GPUDevices{
+		{UUID: "1", Minor: 0, MemoryTotal: 8000, NodeID: 0, PCIE: "pci0000:00", BusID: "0000:00:07.0", ProductName: "A100-SXM4-80GB"},
+		// the stale gpu not found by nvml is not reported
+		{UUID: "4", Minor: 3, MemoryTotal: 8000, NodeID: 0, PCIE: "pci0000:00", BusID: "0000:00:0a.0", ProductName: "A100-SXM4-80GB"},
+	}
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(gpuDeviceInfo, true).AnyTimes()
+	mockMetricCache.EXPECT().Get(metriccache.NodeCPUInfoKey).Return(nil, false).AnyTimes()
+	s := &statesInformer{
+		config:       NewDefaultConfig(),
+		metricsCache: mockMetricCache,
+		gpuAvailable: true,
+		nvml: newFakeNVML("470.82.01",
+			&fakeNVMLDevice{uuid: "1", name: "NVIDIA A100-SXM4-80GB", minor: 0, memoryTotal: 8000},
+			&fakeNVMLDevice{uuid: "2", name: "NVIDIA A100-SXM4-80GB", minor: 1, memoryTotal: 8000},
+			&fakeNVMLDevice{uuid: "3", name: "NVIDIA A100-SXM4-80GB", minor: 2, memoryTotal: 8000},
+		),
+	}
+
+	devices, err := s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(devices))
+	for i, d := range devices {
+		assert.Equal(t, fmt.Sprint(i+1), d.UUID)
+		assert.Equal(t, int32(i), *d.Minor)
+		assert.True(t, d.Health)
+		assert.Equal(t, "A100-SXM4-80GB", d.Labels[extension.LabelGPUProductName])
+	}
+	// the topology is layered from the metric cache
+	assert.Equal(t, &schedulingv1alpha1.DeviceTopology{SocketID: -1, NodeID: 0, PCIEID: "pci0000:00", BusID: "0000:00:07.0"}, devices[0].Topology)
+	assert.Nil(t, devices[1].Topology)
+	assert.Nil(t, devices[2].Topology)
+
+	// the gpus are not reported if nvml fails, even if they are collected
+	s.nvml = newFakeNVML("470.82.01")
+	devices, err = s.buildGPUDevice(nil)
+	assert.Error(t, err)
+	assert.Nil(t, devices)
+}
+
+func Test_buildGPUDeviceWithNVMLCircuitBreaker(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(nil, false).AnyTimes()
+	fakeNVML := newFakeNVML("470.82.01",
+		&fakeNVMLDevice{uuid: "1", name: "NVIDIA A100-SXM4-80GB", minor: 0, memoryTotal: 8000},
+	)
+	fakeNVML.countRet = nvml.ERROR_GPU_IS_LOST
+	cooldown := 50 * time.Millisecond
+	s := &statesInformer{
+		config:       NewDefaultConfig(),
+		metricsCache: mockMetricCache,
+		gpuAvailable: true,
+		nvml:         fakeNVML,
+		nvmlBreaker:  koordletutil.NewCircuitBreaker("GPU", 2, cooldown, nil),
+	}
+
+	// the breaker opens after the consecutive failures
+	for i := 0; i < 2; i++ {
+		_, err := s.buildGPUDevice(nil)
+		assert.Error(t, err)
+	}
+	assert.True(t, s.nvmlBreaker.IsOpen())
+	assert.Equal(t, int32(2), fakeNVML.countCalls.Load())
+
+	// nvml is not called during the cooldown
+	_, err := s.buildGPUDevice(nil)
+	assert.Error(t, err)
+	model, driver := s.getGPUDriverAndModel()
+	assert.Equal(t, "", model)
+	assert.Equal(t, "", driver)
+	assert.Equal(t, int32(2), fakeNVML.countCalls.Load())
+
+	// the breaker closes after a successful probe
+	fakeNVML.countRet = nvml.SUCCESS
+	time.Sleep(cooldown)
+	model, driver = s.getGPUDriverAndModel()
+	assert.Equal(t, "A100-SXM4-80GB", model)
+	assert.Equal(t, "470.82.01", driver)
+	assert.False(t, s.nvmlBreaker.IsOpen())
+	devices, err := s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(devices))
+}
+
+func Test_queryGPUsWithNVMLCircuitBreaker(t *testing.T) {
+	fakeNVML := newFakeNVML("470.82.01",
+		&fakeNVMLDevice{uuid: "1", name: "NVIDIA A100-SXM4-80GB", minor: 0, memoryTotal: 8000, lost: true},
+		&fakeNVMLDevice{uuid: "2", name: "NVIDIA A100-SXM4-80GB", minor: 1, memoryTotal: 8000, lost: true},
+	)
+	config := NewDefaultConfig()
+	config.EnableVGPUReport = true
+	s := &statesInformer{
+		config:       config,
+		gpuAvailable: true,
+		nvml:         fakeNVML,
+		nvmlBreaker:  koordletutil.NewCircuitBreaker("GPU", 2, time.Minute, nil),
+	}
+	gpus := koordletutil.GPUDevices{{UUID: "1"}, {UUID: "2"}}
+
+	// the failures of the queries open the breaker
+	assert.Equal(t, gpuPCIeLink{}, s.getGPUPCIeLink("1"))
+	assert.Equal(t, gpuIdentity{}, s.getGPUIdentity("1", true))
+	assert.True(t, s.nvmlBreaker.IsOpen())
+	assert.Equal(t, int32(2), fakeNVML.handleByUUIDCalls.Load())
+
+	// nvml is not called during the cooldown
+	assert.Equal(t, gpuPCIeLink{}, s.getGPUPCIeLink("1"))
+	assert.Equal(t, gpuIdentity{}, s.getGPUIdentity("1", true))
+	assert.Nil(t, s.getGPUP2PGroups(gpus))
+	vgpus, err := s.getActiveVGPUs(gpus)
+	assert.Error(t, err)
+	assert.Nil(t, vgpus)
+	assert.Equal(t, int32(2), fakeNVML.handleByUUIDCalls.Load())
+}
+
+func Test_deviceReporter(t *testing.T) {
+	testNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	fakeClientSet := schedulingfake.NewSimpleClientset()
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	gpuDeviceInfo := koordletutil.GPUDevices{
+		{UUID: "1", Minor: 0, MemoryTotal: 8000},
+	}
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(gpuDeviceInfo, true).AnyTimes()
+	mockMetricCache.EXPECT().Get(koordletutil.RDMADeviceType).Return(nil, false).AnyTimes()
+	mockMetricCache.EXPECT().Get(koordletutil.FPGADeviceType).Return(nil, false).AnyTimes()
+	nodeInformer := &nodeInformer{}
+	s := &statesInformer{
+		deviceClient: fakeClientSet.SchedulingV1alpha1().Devices(),
+		metricsCache: mockMetricCache,
+		unhealthyGPU: map[string]struct{}{},
+		deviceQueue: workqueue.NewRateLimitingQueue(
+			workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, 10*time.Millisecond)),
+		states: &PluginState{
+			informerPlugins: map[PluginName]informerPlugin{
+				nodeInformerName: nodeInformer,
+			},
+		},
+		getGPUDriverAndModelFunc: func() (string, string) {
+			return "A100", "470"
+		},
+	}
+	defer s.deviceQueue.ShutDown()
+
+	// the gpu updates in the metric cache are enqueued once
+	s.checkGPUDevicesUpdate()
+	assert.Equal(t, 1, s.deviceQueue.Len())
+	s.checkGPUDevicesUpdate()
+	assert.Equal(t, 1, s.deviceQueue.Len())
+
+	// the reporting is retried with the rate limit if failed, e.g. the node is not synced
+	assert.True(t, s.processNextDevice())
+	assert.Equal(t, 1, s.deviceQueue.NumRequeues(deviceReportKey))
+	_, err := fakeClientSet.SchedulingV1alpha1().Devices().Get(context.TODO(), "test", metav1.GetOptions{})
+	assert.Error(t, err)
+
+	nodeInformer.node = testNode
+	assert.True(t, s.processNextDevice())
+	assert.Equal(t, 0, s.deviceQueue.NumRequeues(deviceReportKey))
+	device, err := fakeClientSet.SchedulingV1alpha1().Devices().Get(context.TODO(), "test", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(device.Spec.Devices))
+
+	// the reporting of the multiple triggers are merged
+	s.enqueueDevice()
+	s.enqueueDevice()
+	assert.Equal(t, 1, s.deviceQueue.Len())
+}
+
+func Test_deviceReporterWithoutDeviceCRD(t *testing.T) {
+	testNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	fakeClientSet := schedulingfake.NewSimpleClientset()
+	fakeKubeClient := fakeclientset.NewSimpleClientset()
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	mockMetricCache.EXPECT().Get(gomock.Any()).Return(nil, false).AnyTimes()
+	s := &statesInformer{
+		deviceClient: fakeClientSet.SchedulingV1alpha1().Devices(),
+		metricsCache: mockMetricCache,
+		unhealthyGPU: map[string]struct{}{},
+		deviceQueue:  workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		option:       &PluginOption{KubeClient: fakeKubeClient},
+		states: &PluginState{
+			informerPlugins: map[PluginName]informerPlugin{
+				nodeInformerName: &nodeInformer{node: testNode},
+			},
+		},
+		getGPUDriverAndModelFunc: func() (string, string) {
+			return "", ""
+		},
+	}
+	defer s.deviceQueue.ShutDown()
+	countDiscoveries := func() int {
+		count := 0
+		for _, action := range fakeKubeClient.Actions() {
+			if action.GetResource().Resource == "resource" {
+				count++
+			}
+		}
+		return count
+	}
+
+	// the reporting is skipped without retries if the CRD is not installed
+	s.enqueueDevice()
+	assert.True(t, s.processNextDevice())
+	assert.Equal(t, 0, s.deviceQueue.Len())
+	assert.Equal(t, 0, s.deviceQueue.NumRequeues(deviceReportKey))
+	assert.Equal(t, 0, len(fakeClientSet.Actions()))
+	assert.True(t, s.deviceCRDMissing)
+
+	// the CRD is not re-checked within the interval
+	s.enqueueDevice()
+	assert.True(t, s.processNextDevice())
+	assert.Equal(t, 1, countDiscoveries())
+	assert.Equal(t, 0, len(fakeClientSet.Actions()))
+
+	// the reporting resumes once the CRD is installed
+	fakeKubeClient.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: schedulingv1alpha1.SchemeGroupVersion.String(),
+			APIResources: []metav1.APIResource{{Name: "devices", Kind: "Device"}},
+		},
+	}
+	s.deviceCRDCheckTime = time.Now().Add(-deviceCRDRecheckInterval)
+	s.enqueueDevice()
+	assert.True(t, s.processNextDevice())
+	assert.Equal(t, 2, countDiscoveries())
+	assert.False(t, s.deviceCRDMissing)
+	_, err := fakeClientSet.SchedulingV1alpha1().Devices().Get(context.TODO(), "test", metav1.GetOptions{})
+	assert.NoError(t, err)
+}
+
+func Test_deviceReporterMinInterval(t *testing.T) {
+	testNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	fakeClientSet := schedulingfake.NewSimpleClientset()
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	gpuDeviceInfo := koordletutil.GPUDevices{
+		{UUID: "1", Minor: 0, MemoryTotal: 8000},
+	}
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(gpuDeviceInfo, true).AnyTimes()
+	mockMetricCache.EXPECT().Get(koordletutil.RDMADeviceType).Return(nil, false).AnyTimes()
+	mockMetricCache.EXPECT().Get(koordletutil.FPGADeviceType).Return(nil, false).AnyTimes()
+	cfg := NewDefaultConfig()
+	cfg.DeviceReportMinInterval = 100 * time.Millisecond
+	s := &statesInformer{
+		config:       cfg,
+		deviceClient: fakeClientSet.SchedulingV1alpha1().Devices(),
+		metricsCache: mockMetricCache,
+		unhealthyGPU: map[string]struct{}{},
+		deviceQueue:  workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		states: &PluginState{
+			informerPlugins: map[PluginName]informerPlugin{
+				nodeInformerName: &nodeInformer{node: testNode},
+			},
+		},
+		getGPUDriverAndModelFunc: func() (string, string) {
+			return "A100", "470"
+		},
+	}
+	defer s.deviceQueue.ShutDown()
+
+	s.enqueueDevice()
+	assert.True(t, s.processNextDevice())
+	reported := len(fakeClientSet.Actions())
+	assert.NotZero(t, reported)
+
+	// the triggers within the min interval are delayed and merged
+	s.unhealthyGPU["1"] = struct{}{}
+	s.enqueueDevice()
+	assert.True(t, s.processNextDevice())
+	assert.Equal(t, 0, s.deviceQueue.Len())
+	s.enqueueDevice()
+	s.enqueueDevice()
+	assert.Equal(t, 1, s.deviceQueue.Len())
+	assert.True(t, s.processNextDevice())
+	assert.Equal(t, reported, len(fakeClientSet.Actions()))
+
+	// the latest state is reported after the min interval
+	assert.Eventually(t, func() bool {
+		return s.deviceQueue.Len() == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.True(t, s.processNextDevice())
+	assert.Equal(t, 0, s.deviceQueue.Len())
+	device, err := fakeClientSet.SchedulingV1alpha1().Devices().Get(context.TODO(), "test", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.False(t, device.Spec.Devices[0].Health)
+}
+
+func Test_buildGPUDeviceWithResourceNameMapping(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	gpuDeviceInfo := koordletutil.GPUDevices{
+		{UUID: "1", Minor: 0, MemoryTotal: 8000},
+	}
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(gpuDeviceInfo, true).AnyTimes()
+	cfg := NewDefaultConfig()
+	s := &statesInformer{
+		config:       cfg,
+		metricsCache: mockMetricCache,
+	}
+
+	devices, err := s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(devices))
+	assert.Equal(t, corev1.ResourceList{
+		extension.ResourceGPUCore:        *resource.NewQuantity(100, resource.DecimalSI),
+		extension.ResourceGPUMemory:      *resource.NewQuantity(8000, resource.BinarySI),
+		extension.ResourceGPUMemoryRatio: *resource.NewQuantity(100, resource.DecimalSI),
+	}, corev1.ResourceList(devices[0].Resources))
+
+	cfg.GPUResourceNameMapping = map[string]string{
+		string(extension.ResourceGPUCore):   "example.com/gpu-core",
+		string(extension.ResourceGPUMemory): "example.com/gpu-memory",
+	}
+	devices, err = s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(devices))
+	assert.Equal(t, corev1.ResourceList{
+		"example.com/gpu-core":           *resource.NewQuantity(100, resource.DecimalSI),
+		"example.com/gpu-memory":         *resource.NewQuantity(8000, resource.BinarySI),
+		extension.ResourceGPUMemoryRatio: *resource.NewQuantity(100, resource.DecimalSI),
+	}, corev1.ResourceList(devices[0].Resources))
+}
+
+func Test_buildGPUDeviceWithReservedGPUs(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	gpuDeviceInfo := koordletutil.GPUDevices{
+		{UUID: "GPU-0", Minor: 0, MemoryTotal: 8000},
+		{UUID: "GPU-1", Minor: 1, MemoryTotal: 8000},
+		{UUID: "GPU-2", Minor: 2, MemoryTotal: 8000},
+	}
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(gpuDeviceInfo, true).AnyTimes()
+	s := &statesInformer{
+		config:       NewDefaultConfig(),
+		metricsCache: mockMetricCache,
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+			Annotations: map[string]string{
+				extension.AnnotationReservedGPUs: "GPU-0, 2",
+			},
+		},
+	}
+
+	devices, err := s.buildGPUDevice(node)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(devices))
+	assert.False(t, devices[0].Health)
+	assert.Equal(t, "true", devices[0].Labels[extension.LabelGPUReserved])
+	assert.True(t, devices[1].Health)
+	assert.Nil(t, devices[1].Labels)
+	assert.False(t, devices[2].Health)
+	assert.Equal(t, "true", devices[2].Labels[extension.LabelGPUReserved])
+
+	// removing the annotation restores the gpus
+	delete(node.Annotations, extension.AnnotationReservedGPUs)
+	devices, err = s.buildGPUDevice(node)
+	assert.NoError(t, err)
+	for _, d := range devices {
+		assert.True(t, d.Health)
+		assert.Nil(t, d.Labels)
+	}
+}
+
+func Test_reportDeviceDeferCreationUntilGPUStable(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	gpuDeviceInfo := koordletutil.GPUDevices{
+		{UUID: "1", Minor: 0, MemoryTotal: 8000},
+	}
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(gpuDeviceInfo, true).AnyTimes()
+	mockMetricCache.EXPECT().Get(koordletutil.RDMADeviceType).Return(nil, false).AnyTimes()
+	mockMetricCache.EXPECT().Get(koordletutil.FPGADeviceType).Return(nil, false).AnyTimes()
+	newStatesInformer := func(node *corev1.Node) (*statesInformer, *schedulingfake.Clientset) {
+		fakeClientSet := schedulingfake.NewSimpleClientset()
+		cfg := NewDefaultConfig()
+		cfg.ExpectedGPUCount = 2
+		cfg.GPUDeviceStabilizationPeriod = time.Minute
+		return &statesInformer{
+			config:       cfg,
+			gpuAvailable: true,
+			nvml:         newFakeNVML("470.82.01", &fakeNVMLDevice{uuid: "1", memoryTotal: 8000}),
+			deviceClient: fakeClientSet.SchedulingV1alpha1().Devices(),
+			metricsCache: mockMetricCache,
+			unhealthyGPU: map[string]struct{}{},
+			states: &PluginState{
+				informerPlugins: map[PluginName]informerPlugin{
+					nodeInformerName: &nodeInformer{
+						node: node,
+					},
+				},
+			},
+			getGPUDriverAndModelFunc: func() (string, string) {
+				return "A100", "470"
+			},
+		}, fakeClientSet
+	}
+
+	// the Device is not created until the gpu count is stable for the period
+	s, fakeClientSet := newStatesInformer(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test"}})
+	assert.Error(t, s.reportDevice())
+	_, err := fakeClientSet.SchedulingV1alpha1().Devices().Get(context.TODO(), "test", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	s.discoveredGPUCountSince = time.Now().Add(-2 * time.Minute)
+	assert.NoError(t, s.reportDevice())
+	device, err := fakeClientSet.SchedulingV1alpha1().Devices().Get(context.TODO(), "test", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(device.Spec.Devices))
+
+	// the expected count in the node label takes precedence
+	s, fakeClientSet = newStatesInformer(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+			Labels: map[string]string{
+				extension.LabelGPUExpectedCount: "1",
+			},
+		},
+	})
+	assert.NoError(t, s.reportDevice())
+	_, err = fakeClientSet.SchedulingV1alpha1().Devices().Get(context.TODO(), "test", metav1.GetOptions{})
+	assert.NoError(t, err)
+}
+
+func Test_buildGPUDeviceWithNUMASocket(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	gpuDeviceInfo := koordletutil.GPUDevices{
+		{UUID: "1", Minor: 0, MemoryTotal: 8000, NodeID: 0, PCIE: "pci0000:00", BusID: "0000:00:07.0"},
+		{UUID: "2", Minor: 1, MemoryTotal: 8000, NodeID: 1, PCIE: "pci0000:80", BusID: "0000:80:07.0"},
+		// the numa node without cpus has no socket
+		{UUID: "3", Minor: 2, MemoryTotal: 8000, NodeID: 2, PCIE: "pci0000:c0", BusID: "0000:c0:07.0"},
+	}
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(gpuDeviceInfo, true).AnyTimes()
+	mockMetricCache.EXPECT().Get(metriccache.NodeCPUInfoKey).Return(&metriccache.NodeCPUInfo{
+		ProcessorInfos: []koordletutil.ProcessorInfo{
+			{CPUID: 0, CoreID: 0, SocketID: 0, NodeID: 0},
+			{CPUID: 1, CoreID: 1, SocketID: 0, NodeID: 0},
+			{CPUID: 2, CoreID: 2, SocketID: 1, NodeID: 1},
+			{CPUID: 3, CoreID: 3, SocketID: 1, NodeID: 1},
+		},
+	}, true).AnyTimes()
+	s := &statesInformer{
+		config:       NewDefaultConfig(),
+		metricsCache: mockMetricCache,
+	}
+
+	devices, err := s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(devices))
+	assert.Equal(t, &schedulingv1alpha1.DeviceTopology{SocketID: 0, NodeID: 0, PCIEID: "pci0000:00", BusID: "0000:00:07.0"}, devices[0].Topology)
+	assert.Equal(t, &schedulingv1alpha1.DeviceTopology{SocketID: 1, NodeID: 1, PCIEID: "pci0000:80", BusID: "0000:80:07.0"}, devices[1].Topology)
+	assert.Equal(t, &schedulingv1alpha1.DeviceTopology{SocketID: -1, NodeID: 2, PCIEID: "pci0000:c0", BusID: "0000:c0:07.0"}, devices[2].Topology)
+}
+
+func Test_buildGPUDeviceWithIdentity(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(nil, false).AnyTimes()
+	gpu0 := &fakeNVMLDevice{uuid: "1", name: "NVIDIA A100-SXM4-80GB", minor: 0, memoryTotal: 8000, serial: "1320221000001", inforomVersion: "G001.0000.03.03"}
+	gpu1 := &fakeNVMLDevice{uuid: "2", name: "NVIDIA A100-SXM4-80GB", minor: 1, memoryTotal: 8000}
+	s := &statesInformer{
+		config:       NewDefaultConfig(),
+		metricsCache: mockMetricCache,
+		gpuAvailable: true,
+		nvml:         newFakeNVML("470.82.01", gpu0, gpu1),
+		unhealthyGPU: map[string]struct{}{},
+	}
+
+	devices, err := s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(devices))
+	assert.Equal(t, "1320221000001", devices[0].Labels[extension.LabelGPUSerialNumber])
+	assert.Equal(t, "G001.0000.03.03", devices[0].Labels[extension.LabelGPUInforomVersion])
+	// the gpu not supporting the identity has no labels of it
+	_, ok := devices[1].Labels[extension.LabelGPUSerialNumber]
+	assert.False(t, ok)
+	_, ok = devices[1].Labels[extension.LabelGPUInforomVersion]
+	assert.False(t, ok)
+
+	// the identity is not re-queried while the health is unchanged
+	gpu0.serial = "1320221000002"
+	devices, err = s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "1320221000001", devices[0].Labels[extension.LabelGPUSerialNumber])
+
+	// the identity is refreshed when the gpu becomes unhealthy
+	s.unhealthyGPU["1"] = struct{}{}
+	devices, err = s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.False(t, devices[0].Health)
+	assert.Equal(t, "1320221000002", devices[0].Labels[extension.LabelGPUSerialNumber])
+}
+
+func Test_buildGPUDeviceWithP2PGroups(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(nil, false).AnyTimes()
+	// gpus 1, 2 and 3 are connected through gpu 2, and gpu 4 has no peer
+	gpu0 := &fakeNVMLDevice{uuid: "1", minor: 0, memoryTotal: 8000, p2pPeers: []string{"2"}}
+	gpu1 := &fakeNVMLDevice{uuid: "2", minor: 1, memoryTotal: 8000, p2pPeers: []string{"1", "3"}}
+	gpu2 := &fakeNVMLDevice{uuid: "3", minor: 2, memoryTotal: 8000, p2pPeers: []string{"2"}}
+	gpu3 := &fakeNVMLDevice{uuid: "4", minor: 3, memoryTotal: 8000}
+	fakeNVML := newFakeNVML("470.82.01", gpu0, gpu1, gpu2, gpu3)
+	s := &statesInformer{
+		config:       NewDefaultConfig(),
+		metricsCache: mockMetricCache,
+		gpuAvailable: true,
+		nvml:         fakeNVML,
+		unhealthyGPU: map[string]struct{}{},
+	}
+	p2pGroups := func(devices []schedulingv1alpha1.DeviceInfo) map[string]string {
+		groups := map[string]string{}
+		for _, d := range devices {
+			if group, ok := d.Labels[extension.LabelGPUP2PGroup]; ok {
+				groups[d.UUID] = group
+			}
+		}
+		return groups
+	}
+
+	devices, err := s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"1": "0", "2": "0", "3": "0"}, p2pGroups(devices))
+	calls := gpu0.p2pCalls.Load()
+	assert.Equal(t, int32(3), calls)
+
+	// the groups are not recomputed while the gpus are unchanged
+	devices, err = s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"1": "0", "2": "0", "3": "0"}, p2pGroups(devices))
+	assert.Equal(t, calls, gpu0.p2pCalls.Load())
+
+	// the groups are recomputed when the gpus change
+	gpu3.p2pPeers = []string{"5"}
+	gpu4 := &fakeNVMLDevice{uuid: "5", minor: 4, memoryTotal: 8000}
+	fakeNVML.devices = append(fakeNVML.devices, gpu4)
+	devices, err = s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"1": "0", "2": "0", "3": "0", "4": "1", "5": "1"}, p2pGroups(devices))
+}
+
+func Test_buildGPUDeviceWithPCIeLink(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(nil, false).AnyTimes()
+	// gpu 1 is idle at a lower generation, and gpu 2 does not support the pcie link query
+	gpu0 := &fakeNVMLDevice{uuid: "1", minor: 0, memoryTotal: 8000,
+		pcieLink: gpuPCIeLink{Generation: 1, Width: 16, MaxGeneration: 4, MaxWidth: 16}}
+	gpu1 := &fakeNVMLDevice{uuid: "2", minor: 1, memoryTotal: 8000}
+	s := &statesInformer{
+		config:       NewDefaultConfig(),
+		metricsCache: mockMetricCache,
+		gpuAvailable: true,
+		nvml:         newFakeNVML("470.82.01", gpu0, gpu1),
+		unhealthyGPU: map[string]struct{}{},
+	}
+
+	devices, err := s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.Len(t, devices, 2)
+	assert.Equal(t, map[string]string{
+		extension.LabelGPUPCIeLinkGeneration:    "1",
+		extension.LabelGPUPCIeLinkWidth:         "16",
+		extension.LabelGPUPCIeLinkMaxGeneration: "4",
+		extension.LabelGPUPCIeLinkMaxWidth:      "16",
+	}, devices[0].Labels)
+	assert.Nil(t, devices[1].Labels)
+
+	// the narrower link width is degraded
+	gpu0.pcieLink.Width = 8
+	devices, err = s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "8", devices[0].Labels[extension.LabelGPUPCIeLinkWidth])
+	assert.Equal(t, "true", devices[0].Labels[extension.LabelGPUPCIeLinkDegraded])
+	assert.True(t, s.gpuPCIeLinkDegraded["1"])
+
+	// the degradation is cleared when the link recovers
+	gpu0.pcieLink.Width = 16
+	devices, err = s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.NotContains(t, devices[0].Labels, extension.LabelGPUPCIeLinkDegraded)
+	assert.False(t, s.gpuPCIeLinkDegraded["1"])
+}
+
+func Test_fillGPUDeviceWithFabricPartitions(t *testing.T) {
+	s := &statesInformer{config: NewDefaultConfig()}
+	device := &schedulingv1alpha1.Device{}
+	s.fillGPUDevice(device, []schedulingv1alpha1.DeviceInfo{
+		{UUID: "1", Labels: map[string]string{extension.LabelGPUFabricPartition: "0"}},
+		{UUID: "2", Labels: map[string]string{extension.LabelGPUFabricPartition: "0"}},
+		{UUID: "3", Labels: map[string]string{extension.LabelGPUFabricPartition: "1"}},
+	}, "", "", "")
+	assert.Equal(t, "2", device.Labels[extension.LabelGPUFabricPartitionCount])
+	// the node is not a domain of the fabric partition with multiple partitions
+	assert.NotContains(t, device.Labels, extension.LabelGPUFabricPartition)
+
+	device = &schedulingv1alpha1.Device{}
+	s.fillGPUDevice(device, []schedulingv1alpha1.DeviceInfo{
+		{UUID: "1", Labels: map[string]string{extension.LabelGPUFabricPartition: "3"}},
+		{UUID: "2", Labels: map[string]string{extension.LabelGPUFabricPartition: "3"}},
+	}, "", "", "")
+	assert.Equal(t, "1", device.Labels[extension.LabelGPUFabricPartitionCount])
+	assert.Equal(t, "3", device.Labels[extension.LabelGPUFabricPartition])
+
+	// the gpus without fabric partitions are not counted
+	device = &schedulingv1alpha1.Device{}
+	s.fillGPUDevice(device, []schedulingv1alpha1.DeviceInfo{{UUID: "1"}}, "", "", "")
+	assert.NotContains(t, device.Labels, extension.LabelGPUFabricPartitionCount)
+	assert.NotContains(t, device.Labels, extension.LabelGPUFabricPartition)
+}
+
+func Test_buildGPUDiscoveryCondition(t *testing.T) {
+	expectedNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "test",
+			Labels: map[string]string{extension.LabelGPUExpectedCount: "2"},
+		},
+	}
+	gpuDevices := []schedulingv1alpha1.DeviceInfo{{UUID: "1"}, {UUID: "2"}}
+	tests := []struct {
+		name         string
+		node         *corev1.Node
+		gpuAvailable bool
+		gpuInitErr   error
+		gpuDevices   []schedulingv1alpha1.DeviceInfo
+		buildErr     error
+		wantStatus   corev1.ConditionStatus
+		wantReason   string
+	}{
+		{
+			name:       "node without gpu driver",
+			node:       &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+			gpuInitErr: errNVMLLibraryNotFound,
+		},
+		{
+			name:       "gpu driver not found on the node expecting gpus",
+			node:       expectedNode,
+			gpuInitErr: errNVMLLibraryNotFound,
+			wantStatus: corev1.ConditionFalse,
+			wantReason: gpuDiscoveryReasonNVMLInitFailed,
+		},
+		{
+			name:       "nvml init failed",
+			node:       &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+			gpuInitErr: fmt.Errorf("nvml init failed: Driver Not Loaded"),
+			wantStatus: corev1.ConditionFalse,
+			wantReason: gpuDiscoveryReasonNVMLInitFailed,
+		},
+		{
+			name:         "device count failed",
+			node:         expectedNode,
+			gpuAvailable: true,
+			buildErr:     fmt.Errorf("nvml is available but failed to query gpus: Unknown Error"),
+			wantStatus:   corev1.ConditionFalse,
+			wantReason:   gpuDiscoveryReasonDiscoveryFailed,
+		},
+		{
+			name:         "gpus missing",
+			node:         expectedNode,
+			gpuAvailable: true,
+			gpuDevices:   gpuDevices[:1],
+			wantStatus:   corev1.ConditionFalse,
+			wantReason:   gpuDiscoveryReasonGPUsMissing,
+		},
+		{
+			name:         "gpus discovered",
+			node:         expectedNode,
+			gpuAvailable: true,
+			gpuDevices:   gpuDevices,
+			wantStatus:   corev1.ConditionTrue,
+			wantReason:   gpuDiscoveryReasonDiscovered,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &statesInformer{
+				config:       NewDefaultConfig(),
+				gpuAvailable: tt.gpuAvailable,
+				gpuInitErr:   tt.gpuInitErr,
+			}
+			condition := s.buildGPUDiscoveryCondition(tt.node, tt.gpuDevices, tt.buildErr)
+			if tt.wantReason == "" {
+				assert.Nil(t, condition)
+				return
+			}
+			assert.NotNil(t, condition)
+			assert.Equal(t, extension.NodeConditionGPUDiscoveryReady, condition.Type)
+			assert.Equal(t, tt.wantStatus, condition.Status)
+			assert.Equal(t, tt.wantReason, condition.Reason)
+		})
+	}
+}
+
+func Test_updateGPUDiscoveryCondition(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+	kubeClient := fakeclientset.NewSimpleClientset(node)
+	s := &statesInformer{option: &PluginOption{KubeClient: kubeClient}}
+
+	failed := &corev1.NodeCondition{
+		Type:    extension.NodeConditionGPUDiscoveryReady,
+		Status:  corev1.ConditionFalse,
+		Reason:  gpuDiscoveryReasonNVMLInitFailed,
+		Message: "nvml init failed: Driver Not Loaded",
+	}
+	assert.NoError(t, s.updateGPUDiscoveryCondition(node, failed))
+	node, err := kubeClient.CoreV1().Nodes().Get(context.TODO(), "test", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, node.Status.Conditions, 1)
+	assert.Equal(t, corev1.ConditionFalse, node.Status.Conditions[0].Status)
+
+	// the unchanged condition is not patched again
+	actions := len(kubeClient.Actions())
+	assert.NoError(t, s.updateGPUDiscoveryCondition(node, failed.DeepCopy()))
+	assert.Equal(t, actions, len(kubeClient.Actions()))
+
+	// the condition is cleared when the discovery recovers
+	assert.NoError(t, s.updateGPUDiscoveryCondition(node, &corev1.NodeCondition{
+		Type:    extension.NodeConditionGPUDiscoveryReady,
+		Status:  corev1.ConditionTrue,
+		Reason:  gpuDiscoveryReasonDiscovered,
+		Message: "discovered 2 gpus",
+	}))
+	node, err = kubeClient.CoreV1().Nodes().Get(context.TODO(), "test", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, node.Status.Conditions, 1)
+	assert.Equal(t, corev1.ConditionTrue, node.Status.Conditions[0].Status)
+	assert.Equal(t, gpuDiscoveryReasonDiscovered, node.Status.Conditions[0].Reason)
+}
+
+func Test_buildGPUDeviceWithPartialNVMLFailures(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockMetricCache := mock_metriccache.NewMockMetricCache(ctl)
+	collected := koordletutil.GPUDevices{
+		{UUID: "1", Minor: 0, MemoryTotal: 8000, NodeID: -1},
+		{UUID: "2", Minor: 1, MemoryTotal: 8000, NodeID: -1},
+		{UUID: "3", Minor: 2, MemoryTotal: 8000, NodeID: -1},
+	}
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(collected, true).AnyTimes()
+	gpu0 := &fakeNVMLDevice{uuid: "1", minor: 0, memoryTotal: 8000}
+	gpu1 := &fakeNVMLDevice{uuid: "2", minor: 1, memoryTotal: 8000}
+	gpu2 := &fakeNVMLDevice{uuid: "3", minor: 2, memoryTotal: 8000}
+	s := &statesInformer{
+		config:       NewDefaultConfig(),
+		metricsCache: mockMetricCache,
+		gpuAvailable: true,
+		nvml:         newFakeNVML("470.82.01", gpu0, gpu1, gpu2),
+		unhealthyGPU: map[string]struct{}{},
+	}
+	healthByUUID := func(devices []schedulingv1alpha1.DeviceInfo) map[string]bool {
+		health := map[string]bool{}
+		for _, d := range devices {
+			health[d.UUID] = d.Health
+		}
+		return health
+	}
+
+	// the gpu failing to be queried is reported unhealthy with the collected minor
+	gpu1.minorRet = nvml.ERROR_UNKNOWN
+	devices, err := s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"1": true, "2": false, "3": true}, healthByUUID(devices))
+
+	// the collected gpu is kept unhealthy if a gpu is unidentified by nvml
+	gpu1.minorRet = nvml.SUCCESS
+	gpu2.handleRet = nvml.ERROR_UNKNOWN
+	devices, err = s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"1": true, "2": true, "3": false}, healthByUUID(devices))
+
+	// the gpu whose minor is unknown is not reported if not collected
+	mockMetricCache = mock_metriccache.NewMockMetricCache(ctl)
+	mockMetricCache.EXPECT().Get(koordletutil.GPUDeviceType).Return(nil, false).AnyTimes()
+	s.metricsCache = mockMetricCache
+	gpu2.handleRet = nvml.SUCCESS
+	gpu1.minorRet = nvml.ERROR_UNKNOWN
+	devices, err = s.buildGPUDevice(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"1": true, "3": true}, healthByUUID(devices))
+
+	// it fails only if no gpu is identified
+	gpu0.handleRet = nvml.ERROR_UNKNOWN
+	gpu1.handleRet = nvml.ERROR_UNKNOWN
+	gpu2.handleRet = nvml.ERROR_UNKNOWN
+	_, err = s.buildGPUDevice(nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impl
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	"k8s.io/klog/v2"
+
+	schedulingv1alpha1 "github.com/koordinator-sh/koordinator/apis/scheduling/v1alpha1"
+	"github.com/koordinator-sh/koordinator/pkg/util"
+)
+
+// getDeviceSummaryNamespace returns the namespace of the tenant which the node belongs to, and empty if the
+// DeviceSummary is disabled or the node belongs to no tenant.
+func (s *statesInformer) getDeviceSummaryNamespace(node *corev1.Node) string {
+	if s.config == nil || s.config.DeviceSummaryTenantLabel == "" {
+		return ""
+	}
+	return node.Labels[s.config.DeviceSummaryTenantLabel]
+}
+
+// reportDeviceSummary creates or updates the DeviceSummary of the node in the namespace of its tenant, and deletes
+// the one reported in the namespace of the previous tenant once the node moves to another tenant or out of them.
+func (s *statesInformer) reportDeviceSummary(node *corev1.Node, device *schedulingv1alpha1.Device) error {
+	namespace := s.getDeviceSummaryNamespace(node)
+	if s.deviceSummaryNamespace != "" && s.deviceSummaryNamespace != namespace {
+		err := s.deviceSummaryClient.DeviceSummaries(s.deviceSummaryNamespace).Delete(context.TODO(), node.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		klog.V(4).InfoS("Successfully deleted DeviceSummary of the previous tenant", "node", node.Name,
+			"namespace", s.deviceSummaryNamespace)
+		s.deviceSummaryNamespace = ""
+	}
+	if namespace == "" {
+		return nil
+	}
+
+	summary := buildDeviceSummary(device, namespace)
+	err := util.RetryOnConflictOrTooManyRequests(func() error {
+		latest, err := s.deviceSummaryClient.DeviceSummaries(namespace).Get(context.TODO(), node.Name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			_, err = s.deviceSummaryClient.DeviceSummaries(namespace).Create(context.TODO(), summary, metav1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if apiequality.Semantic.DeepEqual(latest.Spec, summary.Spec) &&
+			apiequality.Semantic.DeepEqual(latest.Status.Devices, summary.Status.Devices) &&
+			!s.isDeviceSummaryUpdateTimeExpired(latest) {
+			return nil
+		}
+		updated := latest.DeepCopy()
+		updated.Spec = summary.Spec
+		updated.Status = summary.Status
+		_, err = s.deviceSummaryClient.DeviceSummaries(namespace).Update(context.TODO(), updated, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	klog.V(4).InfoS("Successfully reported DeviceSummary", "node", node.Name, "namespace", namespace)
+	s.deviceSummaryNamespace = namespace
+	return nil
+}
+
+// isDeviceSummaryUpdateTimeExpired returns whether the last update time of the DeviceSummary needs to be refreshed,
+// which follows the refresh interval of the last report time of the Device.
+func (s *statesInformer) isDeviceSummaryUpdateTimeExpired(summary *schedulingv1alpha1.DeviceSummary) bool {
+	interval := s.getDeviceReportTimeRefreshInterval()
+	return interval <= 0 || summary.Status.LastUpdateTime == nil || time.Since(summary.Status.LastUpdateTime.Time) >= interval
+}
+
+// buildDeviceSummary returns the DeviceSummary of the Device in the namespace, which sums up the devices by the type.
+func buildDeviceSummary(device *schedulingv1alpha1.Device, namespace string) *schedulingv1alpha1.DeviceSummary {
+	summaries := map[schedulingv1alpha1.DeviceType]*schedulingv1alpha1.DeviceTypeSummary{}
+	getSummary := func(deviceType schedulingv1alpha1.DeviceType) *schedulingv1alpha1.DeviceTypeSummary {
+		summary, ok := summaries[deviceType]
+		if !ok {
+			summary = &schedulingv1alpha1.DeviceTypeSummary{Type: deviceType}
+			summaries[deviceType] = summary
+		}
+		return summary
+	}
+	for i := range device.Spec.Devices {
+		d := &device.Spec.Devices[i]
+		summary := getSummary(d.Type)
+		summary.Count++
+		if d.Health {
+			summary.HealthyCount++
+		}
+		summary.Resources = quotav1.Add(summary.Resources, d.Resources)
+	}
+	for i := range device.Status.Resources {
+		r := &device.Status.Resources[i]
+		if summary, ok := summaries[r.Type]; ok {
+			summary.Allocated = quotav1.Add(summary.Allocated, r.Allocated)
+		}
+	}
+
+	devices := make([]schedulingv1alpha1.DeviceTypeSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		devices = append(devices, *summary)
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Type < devices[j].Type
+	})
+	now := metav1.Now()
+	return &schedulingv1alpha1.DeviceSummary{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      device.Name,
+		},
+		Spec: schedulingv1alpha1.DeviceSummarySpec{
+			NodeName: device.Name,
+		},
+		Status: schedulingv1alpha1.DeviceSummaryStatus{
+			Devices:        devices,
+			LastUpdateTime: &now,
+		},
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	schedulingv1alpha1 "github.com/koordinator-sh/koordinator/apis/scheduling/v1alpha1"
+	schedulingfake "github.com/koordinator-sh/koordinator/pkg/client/clientset/versioned/fake"
+)
+
+func newTestSummaryDevice() *schedulingv1alpha1.Device {
+	gpuResources := corev1.ResourceList{
+		extension.ResourceGPUCore:   resource.MustParse("100"),
+		extension.ResourceGPUMemory: resource.MustParse("16Gi"),
+	}
+	return &schedulingv1alpha1.Device{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-node"},
+		Spec: schedulingv1alpha1.DeviceSpec{
+			Devices: []schedulingv1alpha1.DeviceInfo{
+				{UUID: "gpu-0", Type: schedulingv1alpha1.GPU, Minor: pointer.Int32(0), Health: true, Resources: gpuResources},
+				{UUID: "gpu-1", Type: schedulingv1alpha1.GPU, Minor: pointer.Int32(1), Health: false, Resources: gpuResources},
+				{UUID: "rdma-0", Type: schedulingv1alpha1.RDMA, Minor: pointer.Int32(0), Health: true},
+			},
+		},
+		Status: schedulingv1alpha1.DeviceStatus{
+			Resources: []schedulingv1alpha1.DeviceResourceStatus{
+				{
+					Type:      schedulingv1alpha1.GPU,
+					UUID:      "gpu-0",
+					Minor:     pointer.Int32(0),
+					Allocated: corev1.ResourceList{extension.ResourceGPUCore: resource.MustParse("30")},
+				},
+				{
+					Type:      schedulingv1alpha1.GPU,
+					UUID:      "gpu-1",
+					Minor:     pointer.Int32(1),
+					Allocated: corev1.ResourceList{extension.ResourceGPUCore: resource.MustParse("50")},
+				},
+			},
+		},
+	}
+}
+
+func Test_buildDeviceSummary(t *testing.T) {
+	summary := buildDeviceSummary(newTestSummaryDevice(), "tenant-a")
+	assert.Equal(t, "tenant-a", summary.Namespace)
+	assert.Equal(t, "test-node", summary.Name)
+	assert.Equal(t, "test-node", summary.Spec.NodeName)
+	assert.NotNil(t, summary.Status.LastUpdateTime)
+	want := []schedulingv1alpha1.DeviceTypeSummary{
+		{
+			Type:         schedulingv1alpha1.GPU,
+			Count:        2,
+			HealthyCount: 1,
+			Resources: corev1.ResourceList{
+				extension.ResourceGPUCore:   resource.MustParse("200"),
+				extension.ResourceGPUMemory: resource.MustParse("32Gi"),
+			},
+			Allocated: corev1.ResourceList{extension.ResourceGPUCore: resource.MustParse("80")},
+		},
+		{
+			Type:         schedulingv1alpha1.RDMA,
+			Count:        1,
+			HealthyCount: 1,
+			Resources:    corev1.ResourceList{},
+		},
+	}
+	assert.True(t, apiequality.Semantic.DeepEqual(want, summary.Status.Devices), "got %+v", summary.Status.Devices)
+}
+
+func Test_reportDeviceSummary(t *testing.T) {
+	fakeClientSet := schedulingfake.NewSimpleClientset()
+	r := &statesInformer{
+		config:              &Config{DeviceSummaryTenantLabel: "example.com/tenant", DeviceReportTimeRefreshInterval: time.Hour},
+		deviceSummaryClient: fakeClientSet.SchedulingV1alpha1(),
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "test-node",
+			Labels: map[string]string{"example.com/tenant": "tenant-a"},
+		},
+	}
+	device := newTestSummaryDevice()
+	countUpdates := func() int {
+		count := 0
+		for _, action := range fakeClientSet.Actions() {
+			if action.GetVerb() == "update" {
+				count++
+			}
+		}
+		return count
+	}
+
+	// the summary is created in the namespace of the tenant
+	assert.NoError(t, r.reportDeviceSummary(node, device))
+	summary, err := fakeClientSet.SchedulingV1alpha1().DeviceSummaries("tenant-a").Get(context.TODO(), "test-node", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(summary.Status.Devices))
+
+	// the unchanged summary is not updated
+	assert.NoError(t, r.reportDeviceSummary(node, device))
+	assert.Equal(t, 0, countUpdates())
+
+	// the changed summary is updated
+	device.Spec.Devices[1].Health = true
+	assert.NoError(t, r.reportDeviceSummary(node, device))
+	assert.Equal(t, 1, countUpdates())
+	summary, err = fakeClientSet.SchedulingV1alpha1().DeviceSummaries("tenant-a").Get(context.TODO(), "test-node", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), summary.Status.Devices[0].HealthyCount)
+
+	// the summary moves with the node to another tenant
+	node.Labels["example.com/tenant"] = "tenant-b"
+	assert.NoError(t, r.reportDeviceSummary(node, device))
+	_, err = fakeClientSet.SchedulingV1alpha1().DeviceSummaries("tenant-a").Get(context.TODO(), "test-node", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	_, err = fakeClientSet.SchedulingV1alpha1().DeviceSummaries("tenant-b").Get(context.TODO(), "test-node", metav1.GetOptions{})
+	assert.NoError(t, err)
+
+	// the summary is deleted once the node belongs to no tenant
+	delete(node.Labels, "example.com/tenant")
+	assert.NoError(t, r.reportDeviceSummary(node, device))
+	_, err = fakeClientSet.SchedulingV1alpha1().DeviceSummaries("tenant-b").Get(context.TODO(), "test-node", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	assert.Equal(t, "", r.deviceSummaryNamespace)
+
+	// the summary is not reported if disabled
+	r.config.DeviceSummaryTenantLabel = ""
+	node.Labels["example.com/tenant"] = "tenant-a"
+	assert.NoError(t, r.reportDeviceSummary(node, device))
+	_, err = fakeClientSet.SchedulingV1alpha1().DeviceSummaries("tenant-a").Get(context.TODO(), "test-node", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+}
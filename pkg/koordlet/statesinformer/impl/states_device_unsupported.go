@@ -19,14 +19,24 @@ limitations under the License.
 
 package impl
 
-func (s *statesInformer) reportDevice() {
-	return
+type nvmlInterface interface{}
+
+func newNVMLInterface() nvmlInterface {
+	return nil
+}
+
+func (s *statesInformer) reportDevice() error {
+	return nil
 }
 
 func (s *statesInformer) initGPU() bool {
 	return false
 }
 
+func (s *statesInformer) shutdownGPU(healthCheckDone <-chan struct{}) {
+	return
+}
+
 func (s *statesInformer) gpuHealCheck(stopCh <-chan struct{}) {
 	return
 }
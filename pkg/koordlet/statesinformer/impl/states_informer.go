@@ -19,6 +19,7 @@ package impl
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	topov1alpha1 "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha1"
 	topologyclientset "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/generated/clientset/versioned"
@@ -26,9 +27,9 @@ import (
 	"go.uber.org/atomic"
 	corev1 "k8s.io/api/core/v1"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
-	"k8s.io/apimachinery/pkg/util/wait"
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 
 	slov1alpha1 "github.com/koordinator-sh/koordinator/apis/slo/v1alpha1"
@@ -38,6 +39,7 @@ import (
 	"github.com/koordinator-sh/koordinator/pkg/koordlet/metriccache"
 	"github.com/koordinator-sh/koordinator/pkg/koordlet/prediction"
 	"github.com/koordinator-sh/koordinator/pkg/koordlet/statesinformer"
+	koordletutil "github.com/koordinator-sh/koordinator/pkg/koordlet/util"
 )
 
 const (
@@ -69,8 +71,56 @@ type statesInformer struct {
 	config       *Config
 	metricsCache metriccache.MetricCache
 	deviceClient schedv1alpha1.DeviceInterface
-	unhealthyGPU map[string]struct{}
-	gpuMutex     sync.RWMutex
+	// deviceSummaryClient reports the DeviceSummary in the namespace of the tenant the node belongs to
+	deviceSummaryClient schedv1alpha1.DeviceSummariesGetter
+	unhealthyGPU        map[string]struct{}
+	gpuMutex            sync.RWMutex
+	// gpuHealthObservers are notified of the gpu health transitions, which is guarded by the gpuMutex
+	gpuHealthObservers []gpuHealthObserver
+	// nvml is the NVML library to report the gpus, which is replaceable for testing
+	nvml nvmlInterface
+	// nvmlBreaker suspends the nvml calls after repeated failures, e.g. the driver crashes
+	nvmlBreaker *koordletutil.CircuitBreaker
+	// xidHealthPolicy decides the health of the gpus on the xid errors
+	xidHealthPolicy XidHealthPolicy
+	// gpuAvailable indicates whether nvml is initialized successfully, which means the node is expected to have gpus
+	gpuAvailable bool
+	// gpuInitErr is the error of initializing nvml, which is nil if nvml is initialized or the accelerators are disabled
+	gpuInitErr error
+	// cudaDriverVersion is the CUDA driver version queried with the gpu driver version cudaDriverVersionOf,
+	// which is re-queried only if the gpu driver changes
+	cudaDriverVersion   string
+	cudaDriverVersionOf string
+	// gpuIdentities is the board identity of each gpu by the uuid, which is re-queried only if the gpu health changes
+	gpuIdentities map[string]gpuIdentity
+	// gpuP2PGroupsKey is the sorted uuids of the gpus whose peer-access groups are cached in gpuP2PGroups, which are
+	// recomputed only if the gpus change
+	gpuP2PGroupsKey string
+	gpuP2PGroups    map[string]string
+	// gpuPCIeLinkDegraded is the uuids of the gpus whose pcie links are degraded, which is only accessed by the
+	// reporter to warn once on the degradation
+	gpuPCIeLinkDegraded map[string]bool
+	// deviceResyncToken is the last handled value of the node annotation AnnotationDeviceResync
+	deviceResyncToken string
+	// deviceSummaryNamespace is the namespace of the last reported DeviceSummary, which is only accessed by the reporter
+	deviceSummaryNamespace string
+	// deviceQueue queues the Device reporting on the gpu health changes, the gpu updates and the resyncs
+	deviceQueue workqueue.RateLimitingInterface
+	// deviceCRDMissing is whether the Device CRD is found not installed at the deviceCRDCheckTime, which are only
+	// accessed by the reporter
+	deviceCRDMissing   bool
+	deviceCRDCheckTime time.Time
+	// lastDeviceReportTime is the start time of the last Device reporting, which is only accessed by the reporter
+	lastDeviceReportTime time.Time
+	// lastGPUDevices is the gpus in the metric cache when the Device reporting is enqueued last time
+	lastGPUDevices interface{}
+	// lastGPUAllocatedPodsKey is the key of the pods allocated with gpus when the Device reporting is enqueued last
+	// time, which is only accessed by the pods callback
+	lastGPUAllocatedPodsKey string
+	// discoveredGPUCount is the count of the physical gpus discovered before the Device is created, and
+	// discoveredGPUCountSince is the time since when the count keeps unchanged
+	discoveredGPUCount      int
+	discoveredGPUCountSince time.Time
 
 	option  *PluginOption
 	states  *PluginState
@@ -104,10 +154,15 @@ func NewStatesInformer(config *Config, kubeClient clientset.Interface, crdClient
 		predictorFactory: predictorFactory,
 	}
 	s := &statesInformer{
-		config:       config,
-		metricsCache: metricsCache,
-		deviceClient: schedulingClient.Devices(),
-		unhealthyGPU: make(map[string]struct{}),
+		config:              config,
+		metricsCache:        metricsCache,
+		deviceClient:        schedulingClient.Devices(),
+		deviceSummaryClient: schedulingClient,
+		unhealthyGPU:        make(map[string]struct{}),
+		nvml:                newNVMLInterface(),
+		nvmlBreaker:         koordletutil.NVMLCircuitBreaker,
+		xidHealthPolicy:     newXidHealthPolicy(config),
+		deviceQueue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "device"),
 
 		option:  opt,
 		states:  stat,
@@ -147,12 +202,18 @@ func (s *statesInformer) Run(stopCh <-chan struct{}) error {
 		return fmt.Errorf("timed out waiting for states informer caches to sync")
 	}
 
+	gpuHealthCheckDone := make(chan struct{})
 	if features.DefaultKoordletFeatureGate.Enabled(features.Accelerators) {
-		go wait.Until(s.reportDevice, s.config.NodeTopologySyncInterval, stopCh)
 		// check is nvml is available
-		if s.initGPU() {
-			go s.gpuHealCheck(stopCh)
+		s.gpuAvailable = s.initGPU()
+		if s.gpuAvailable {
+			go func() {
+				defer close(gpuHealthCheckDone)
+				s.gpuHealCheck(stopCh)
+			}()
 		}
+		s.registerDeviceCallbacks()
+		go s.runDeviceReporter(stopCh)
 	}
 
 	// start callback runner after informers synced
@@ -164,6 +225,8 @@ func (s *statesInformer) Run(stopCh <-chan struct{}) error {
 	s.started.Store(true)
 	<-stopCh
 	klog.Infof("shutting down states informer daemon")
+	// nvml is initialized in the Run, so it is shut down here rather than by each user
+	s.shutdownGPU(gpuHealthCheckDone)
 	return nil
 }
 
@@ -102,6 +102,10 @@ type nodeMetricInformer struct {
 	metricCache      metriccache.MetricCache
 	predictorFactory prediction.PredictorFactory
 
+	gpuUtilizationWindowEnabled bool
+	// gpuAggregationType is the aggregation of the gpu usage reported in the node usage
+	gpuAggregationType metriccache.AggregationType
+
 	rwMutex    sync.RWMutex
 	nodeMetric *slov1alpha1.NodeMetric
 }
@@ -124,6 +128,9 @@ func (r *nodeMetricInformer) HasSynced() bool {
 
 func (r *nodeMetricInformer) Setup(ctx *PluginOption, state *PluginState) {
 	r.reportEnabled = ctx.config.EnableNodeMetricReport
+	r.gpuUtilizationWindowEnabled = ctx.config.EnableGPUUtilizationWindow
+	// the aggregation type has been validated when the koordlet starts
+	r.gpuAggregationType, _ = metriccache.ParseAggregationType(ctx.config.GPUMetricAggregationType)
 	r.nodeName = ctx.NodeName
 	r.nodeMetricInformer = newNodeMetricInformer(ctx.KoordClient, ctx.NodeName)
 	r.nodeMetricLister = listerv1alpha1.NewNodeMetricLister(r.nodeMetricInformer.GetIndexer())
@@ -345,7 +352,7 @@ func (r *nodeMetricInformer) collectMetric() (*slov1alpha1.NodeMetricInfo, []*sl
 	startTime := endTime.Add(-time.Duration(*spec.CollectPolicy.AggregateDurationSeconds) * time.Second)
 
 	nodeMetricInfo := &slov1alpha1.NodeMetricInfo{
-		NodeUsage:              r.queryNodeMetric(startTime, endTime, metriccache.AggregationTypeAVG, false),
+		NodeUsage:              r.queryNodeMetric(startTime, endTime, metriccache.AggregationTypeAVG, r.getGPUAggregationType(), false),
 		AggregatedNodeUsages:   r.collectNodeAggregateMetric(endTime, spec.CollectPolicy.NodeAggregatePolicy),
 		SystemUsage:            r.querySystemMetric(startTime, endTime, metriccache.AggregationTypeAVG, false),
 		AggregatedSystemUsages: r.collectSystemAggregateMetric(endTime, spec.CollectPolicy.NodeAggregatePolicy),
@@ -359,6 +366,9 @@ func (r *nodeMetricInformer) collectMetric() (*slov1alpha1.NodeMetricInfo, []*sl
 			klog.Errorf("value type error, expect: %T, got %T", koordletutil.GPUDevices{}, value)
 		}
 	}
+	if r.gpuUtilizationWindowEnabled {
+		r.recordGPUUtilizationWindow(startTime, endTime, gpus)
+	}
 
 	podsMeta := r.podsInformer.GetAllPods()
 	podsMetricInfo := make([]*slov1alpha1.PodMetricInfo, 0, len(podsMeta))
@@ -415,7 +425,9 @@ func (r *nodeMetricInformer) collectMetric() (*slov1alpha1.NodeMetricInfo, []*sl
 	return nodeMetricInfo, podsMetricInfo, hostAppMetricInfo, prodReclaimable
 }
 
-func (r *nodeMetricInformer) queryNodeMetric(start time.Time, end time.Time, aggregateType metriccache.AggregationType,
+// queryNodeMetric queries the node usage aggregated by the aggregateType, where the gpu usage is aggregated by the
+// gpuAggregateType separately, since the scheduling policies may care about the peak usage of the gpus.
+func (r *nodeMetricInformer) queryNodeMetric(start time.Time, end time.Time, aggregateType, gpuAggregateType metriccache.AggregationType,
 	coldStartFilter bool) slov1alpha1.ResourceMap {
 	rm := slov1alpha1.ResourceMap{}
 
@@ -448,6 +460,7 @@ func (r *nodeMetricInformer) queryNodeMetric(start time.Time, end time.Time, agg
 		klog.Errorf("value type error, expect: %T, got %T", koordletutil.GPUDevices{}, value)
 		return rm
 	}
+	queryParam.Aggregate = gpuAggregateType
 	devices, err := r.collectNodeGPUMetric(queryParam, gpus)
 	if err != nil {
 		klog.Errorf("query node gpu metric failed, error: %v", err)
@@ -558,7 +571,7 @@ func (r *nodeMetricInformer) collectNodeGPUMetric(queryparam metriccache.QueryPa
 			// TODO: how to check the health status of GPU
 			Resources: map[corev1.ResourceName]resource.Quantity{
 				apiext.ResourceGPUCore:        *resource.NewQuantity(int64(coreUsage), resource.DecimalSI),
-				apiext.ResourceGPUMemory:      *resource.NewQuantity(int64(memUsage), resource.BinarySI),
+				apiext.ResourceGPUMemory:      koordletutil.GPUMemoryQuantity(uint64(memUsage), koordletutil.MemoryUnitByte),
 				apiext.ResourceGPUMemoryRatio: *resource.NewQuantity(int64(memoryRatioRaw), resource.DecimalSI),
 			},
 		})
@@ -567,6 +580,54 @@ func (r *nodeMetricInformer) collectNodeGPUMetric(queryparam metriccache.QueryPa
 	return result, nil
 }
 
+// getGPUAggregationType returns the aggregation of the gpu usage reported in the node usage, avg by default.
+func (r *nodeMetricInformer) getGPUAggregationType() metriccache.AggregationType {
+	if r.gpuAggregationType == "" {
+		return metriccache.AggregationTypeAVG
+	}
+	return r.gpuAggregationType
+}
+
+// recordGPUUtilizationWindow records the avg and max utilization of each gpu over the window as the metrics, since the
+// avg alone cannot tell a gpu busy in bursts from a gpu steadily half used.
+func (r *nodeMetricInformer) recordGPUUtilizationWindow(start, end time.Time, gpus koordletutil.GPUDevices) {
+	metrics.ResetNodeGPUUtilization()
+	if len(gpus) == 0 {
+		return
+	}
+	querier, err := r.metricCache.Querier(start, end)
+	if err != nil {
+		klog.V(5).Infof("get node gpu utilization querier failed, error %v", err)
+		return
+	}
+	defer querier.Close()
+	for _, gpu := range gpus {
+		properties := metriccache.MetricPropertiesFunc.GPU(fmt.Sprintf("%d", gpu.Minor), gpu.UUID)
+		coreUsageResult, err := doQuery(querier, metriccache.NodeGPUCoreUsageMetric, properties)
+		if err != nil {
+			klog.V(4).Infof("query gpu %s core usage failed, error %v", gpu.UUID, err)
+			continue
+		}
+		memUsedResult, err := doQuery(querier, metriccache.NodeGPUMemUsageMetric, properties)
+		if err != nil {
+			klog.V(4).Infof("query gpu %s memory usage failed, error %v", gpu.UUID, err)
+			continue
+		}
+		for _, aggregationType := range []metriccache.AggregationType{metriccache.AggregationTypeAVG, metriccache.AggregationTypeMax} {
+			if coreUsageResult.Count() > 0 {
+				if coreUsage, err := coreUsageResult.Value(aggregationType); err == nil {
+					metrics.RecordNodeGPUUtilization(gpu.Minor, gpu.UUID, string(apiext.ResourceGPUCore), string(aggregationType), coreUsage)
+				}
+			}
+			if memUsedResult.Count() > 0 && gpu.MemoryTotal > 0 {
+				if memUsage, err := memUsedResult.Value(aggregationType); err == nil {
+					metrics.RecordNodeGPUUtilization(gpu.Minor, gpu.UUID, string(apiext.ResourceGPUMemoryRatio), string(aggregationType), 100*memUsage/float64(gpu.MemoryTotal))
+				}
+			}
+		}
+	}
+}
+
 func (r *nodeMetricInformer) collectNodeAggregateMetric(endTime time.Time, aggregatePolicy *slov1alpha1.AggregatePolicy) []slov1alpha1.AggregatedUsage {
 	var aggregateUsages []slov1alpha1.AggregatedUsage
 	if aggregatePolicy == nil {
@@ -576,10 +637,10 @@ func (r *nodeMetricInformer) collectNodeAggregateMetric(endTime time.Time, aggre
 		start := endTime.Add(-d.Duration)
 		aggregateUsage := slov1alpha1.AggregatedUsage{
 			Usage: map[apiext.AggregationType]slov1alpha1.ResourceMap{
-				apiext.P50: r.queryNodeMetric(start, endTime, metriccache.AggregationTypeP50, true),
-				apiext.P90: r.queryNodeMetric(start, endTime, metriccache.AggregationTypeP90, true),
-				apiext.P95: r.queryNodeMetric(start, endTime, metriccache.AggregationTypeP95, true),
-				apiext.P99: r.queryNodeMetric(start, endTime, metriccache.AggregationTypeP99, true),
+				apiext.P50: r.queryNodeMetric(start, endTime, metriccache.AggregationTypeP50, metriccache.AggregationTypeP50, true),
+				apiext.P90: r.queryNodeMetric(start, endTime, metriccache.AggregationTypeP90, metriccache.AggregationTypeP90, true),
+				apiext.P95: r.queryNodeMetric(start, endTime, metriccache.AggregationTypeP95, metriccache.AggregationTypeP95, true),
+				apiext.P99: r.queryNodeMetric(start, endTime, metriccache.AggregationTypeP99, metriccache.AggregationTypeP99, true),
 			},
 			Duration: d,
 		}
@@ -824,7 +885,7 @@ func (r *nodeMetricInformer) collectPodGPUMetric(queryparam metriccache.QueryPar
 			// TODO: how to check the health status of GPU
 			Resources: map[corev1.ResourceName]resource.Quantity{
 				apiext.ResourceGPUCore:        *resource.NewQuantity(int64(coreUsage), resource.DecimalSI),
-				apiext.ResourceGPUMemory:      *resource.NewQuantity(int64(memUsage), resource.BinarySI),
+				apiext.ResourceGPUMemory:      koordletutil.GPUMemoryQuantity(uint64(memUsage), koordletutil.MemoryUnitByte),
 				apiext.ResourceGPUMemoryRatio: *resource.NewQuantity(int64(memoryRatioRaw), resource.DecimalSI),
 			},
 		})
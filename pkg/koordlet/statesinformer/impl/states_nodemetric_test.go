@@ -25,6 +25,8 @@ import (
 
 	"github.com/golang/mock/gomock"
 	faketopologyclientset "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/generated/clientset/versioned/fake"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -44,6 +46,7 @@ import (
 	listerv1alpha1 "github.com/koordinator-sh/koordinator/pkg/client/listers/slo/v1alpha1"
 	"github.com/koordinator-sh/koordinator/pkg/koordlet/metriccache"
 	mockmetriccache "github.com/koordinator-sh/koordinator/pkg/koordlet/metriccache/mockmetriccache"
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/metrics"
 	"github.com/koordinator-sh/koordinator/pkg/koordlet/prediction"
 	"github.com/koordinator-sh/koordinator/pkg/koordlet/statesinformer"
 	"github.com/koordinator-sh/koordinator/pkg/koordlet/util"
@@ -867,6 +870,72 @@ func Test_nodeMetricInformer_collectNodeGPUMetric(t *testing.T) {
 	}
 }
 
+func Test_nodeMetricInformer_recordGPUUtilizationWindow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	metrics.Register(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test-node"}})
+	defer metrics.Register(nil)
+
+	end := time.Now()
+	start := end.Add(-time.Second * 120)
+	gpus := util.GPUDevices{
+		{Minor: 0, UUID: "1", MemoryTotal: 8000},
+		{Minor: 1, UUID: "2", MemoryTotal: 10000},
+	}
+
+	mockMetricCache := mockmetriccache.NewMockMetricCache(ctrl)
+	mockResultFactory := mockmetriccache.NewMockAggregateResultFactory(ctrl)
+	metriccache.DefaultAggregateResultFactory = mockResultFactory
+	mockQuerier := mockmetriccache.NewMockQuerier(ctrl)
+	mockMetricCache.EXPECT().Querier(start, end).Return(mockQuerier, nil)
+	mockQuerier.EXPECT().Close()
+	buildResult := func(resource metriccache.MetricResource, gpu util.GPUDeviceInfo, avg, max float64, count int) {
+		queryMeta, err := resource.BuildQueryMeta(metriccache.MetricPropertiesFunc.GPU(fmt.Sprintf("%d", gpu.Minor), gpu.UUID))
+		assert.NoError(t, err)
+		result := mockmetriccache.NewMockAggregateResult(ctrl)
+		result.EXPECT().Value(metriccache.AggregationTypeAVG).Return(avg, nil).AnyTimes()
+		result.EXPECT().Value(metriccache.AggregationTypeMax).Return(max, nil).AnyTimes()
+		result.EXPECT().Count().Return(count).AnyTimes()
+		mockResultFactory.EXPECT().New(queryMeta).Return(result)
+		mockQuerier.EXPECT().Query(queryMeta, gomock.Any(), result).Return(nil)
+	}
+	buildResult(metriccache.NodeGPUCoreUsageMetric, gpus[0], 20, 95, 10)
+	buildResult(metriccache.NodeGPUMemUsageMetric, gpus[0], 800, 4000, 10)
+	// the samples of gpu 2 are not collected yet
+	buildResult(metriccache.NodeGPUCoreUsageMetric, gpus[1], 0, 0, 0)
+	buildResult(metriccache.NodeGPUMemUsageMetric, gpus[1], 0, 0, 0)
+
+	// the metrics of the removed gpus are cleaned
+	metrics.RecordNodeGPUUtilization(7, "removed", string(apiext.ResourceGPUCore), string(metriccache.AggregationTypeMax), 100)
+
+	r := &nodeMetricInformer{
+		metricCache: mockMetricCache,
+	}
+	r.recordGPUUtilizationWindow(start, end, gpus)
+
+	got := map[string]float64{}
+	ch := make(chan prometheus.Metric, 10)
+	metrics.NodeGPUUtilization.Collect(ch)
+	close(ch)
+	for m := range ch {
+		metric := dto.Metric{}
+		assert.NoError(t, m.Write(&metric))
+		labels := map[string]string{}
+		for _, label := range metric.GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+		key := fmt.Sprintf("%s/%s/%s", labels[metrics.GPUUUIDKey], labels[metrics.ResourceKey], labels[metrics.AggregationTypeKey])
+		got[key] = metric.GetGauge().GetValue()
+	}
+	want := map[string]float64{
+		"1/koordinator.sh/gpu-core/avg":         20,
+		"1/koordinator.sh/gpu-core/max":         95,
+		"1/koordinator.sh/gpu-memory-ratio/avg": 10,
+		"1/koordinator.sh/gpu-memory-ratio/max": 50,
+	}
+	assert.Equal(t, want, got)
+}
+
 func Test_nodeMetricInformer_collectNodeMetric(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
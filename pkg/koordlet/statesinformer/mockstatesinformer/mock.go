@@ -153,6 +153,34 @@ func (mr *MockStatesInformerMockRecorder) HasSynced() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasSynced", reflect.TypeOf((*MockStatesInformer)(nil).HasSynced))
 }
 
+// IsGPUHealthy mocks base method.
+func (m *MockStatesInformer) IsGPUHealthy(uuid string) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsGPUHealthy", uuid)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsGPUHealthy indicates an expected call of IsGPUHealthy.
+func (mr *MockStatesInformerMockRecorder) IsGPUHealthy(uuid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsGPUHealthy", reflect.TypeOf((*MockStatesInformer)(nil).IsGPUHealthy), uuid)
+}
+
+// ListUnhealthyGPUs mocks base method.
+func (m *MockStatesInformer) ListUnhealthyGPUs() []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUnhealthyGPUs")
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// ListUnhealthyGPUs indicates an expected call of ListUnhealthyGPUs.
+func (mr *MockStatesInformerMockRecorder) ListUnhealthyGPUs() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUnhealthyGPUs", reflect.TypeOf((*MockStatesInformer)(nil).ListUnhealthyGPUs))
+}
+
 // RegisterCallbacks mocks base method.
 func (m *MockStatesInformer) RegisterCallbacks(objType statesinformer.RegisterType, name, description string, callbackFn statesinformer.UpdateCbFn) {
 	m.ctrl.T.Helper()
@@ -165,6 +193,18 @@ func (mr *MockStatesInformerMockRecorder) RegisterCallbacks(objType, name, descr
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterCallbacks", reflect.TypeOf((*MockStatesInformer)(nil).RegisterCallbacks), objType, name, description, callbackFn)
 }
 
+// RegisterGPUHealthObserver mocks base method.
+func (m *MockStatesInformer) RegisterGPUHealthObserver(name string, observerFn statesinformer.GPUHealthObserverFn) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RegisterGPUHealthObserver", name, observerFn)
+}
+
+// RegisterGPUHealthObserver indicates an expected call of RegisterGPUHealthObserver.
+func (mr *MockStatesInformerMockRecorder) RegisterGPUHealthObserver(name, observerFn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterGPUHealthObserver", reflect.TypeOf((*MockStatesInformer)(nil).RegisterGPUHealthObserver), name, observerFn)
+}
+
 // Run mocks base method.
 func (m *MockStatesInformer) Run(stopCh <-chan struct{}) error {
 	m.ctrl.T.Helper()
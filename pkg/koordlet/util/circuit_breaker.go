@@ -0,0 +1,175 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/koordinator-sh/koordinator/pkg/koordlet/metrics"
+)
+
+const (
+	// DefaultNVMLFailureThreshold is the number of consecutive nvml failures to open the NVMLCircuitBreaker.
+	DefaultNVMLFailureThreshold = 5
+	// DefaultNVMLCooldown is the duration to suspend the nvml calls once the NVMLCircuitBreaker opens.
+	DefaultNVMLCooldown = time.Minute
+)
+
+// NVMLCircuitBreaker is shared by all the nvml callers of koordlet, i.e. the device reporting, the gpu health check
+// and the gpu metric collection, so the failures of any of them suspend the others once the driver is broken.
+var NVMLCircuitBreaker = NewCircuitBreaker("GPU", DefaultNVMLFailureThreshold, DefaultNVMLCooldown, metrics.RecordGPUSubsystemDegraded)
+
+// CircuitBreaker stops the calls to a failing dependency for a cooldown after consecutive failures.
+// When the cooldown passes, one call is allowed as a probe: the breaker closes if the probe succeeds,
+// otherwise it opens for another cooldown. A nil CircuitBreaker allows all the calls.
+type CircuitBreaker struct {
+	lock      sync.Mutex
+	name      string
+	threshold int
+	cooldown  time.Duration
+	// onStateChange is called with true when the breaker opens and false when it closes.
+	onStateChange func(open bool)
+
+	failures  int
+	open      bool
+	openUntil time.Time
+	now       func() time.Time
+}
+
+func NewCircuitBreaker(name string, threshold int, cooldown time.Duration, onStateChange func(open bool)) *CircuitBreaker {
+	if threshold <= 0 {
+		threshold = 1
+	}
+	return &CircuitBreaker{
+		name:          name,
+		threshold:     threshold,
+		cooldown:      cooldown,
+		onStateChange: onStateChange,
+		now:           time.Now,
+	}
+}
+
+// Allow returns whether a call is allowed. It returns false during the cooldown, and true only for the first
+// caller after the cooldown to probe the dependency. The cooldown restarts once the probe is allowed, so an
+// unrecorded probe does not block the next one.
+func (c *CircuitBreaker) Allow() bool {
+	if c == nil {
+		return true
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.open {
+		return true
+	}
+	now := c.now()
+	if now.Before(c.openUntil) {
+		return false
+	}
+	c.openUntil = now.Add(c.cooldown)
+	klog.V(4).InfoS("Probe the degraded subsystem", "subsystem", c.name)
+	return true
+}
+
+// Err returns a non-nil error if a call is not allowed.
+func (c *CircuitBreaker) Err() error {
+	if c.Allow() {
+		return nil
+	}
+	return fmt.Errorf("%s subsystem degraded, calls are suspended until %v", c.name, c.OpenUntil())
+}
+
+// RecordSuccess resets the consecutive failures and closes the breaker.
+func (c *CircuitBreaker) RecordSuccess() {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.failures = 0
+	if c.open {
+		c.open = false
+		klog.InfoS("Subsystem recovered, resume the calls", "subsystem", c.name)
+		c.notify(false)
+	}
+}
+
+// RecordFailure counts a failure and opens the breaker once the consecutive failures reach the threshold,
+// or immediately if the failure is of a probe.
+func (c *CircuitBreaker) RecordFailure(err error) {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.failures++
+	if c.open {
+		// the probe fails, wait for another cooldown
+		c.openUntil = c.now().Add(c.cooldown)
+		klog.V(4).InfoS("Probe of the degraded subsystem failed", "subsystem", c.name, "err", err, "retryAfter", c.openUntil)
+		return
+	}
+	if c.failures < c.threshold {
+		return
+	}
+	c.open = true
+	c.openUntil = c.now().Add(c.cooldown)
+	klog.ErrorS(err, "Subsystem degraded, suspend the calls after consecutive failures",
+		"subsystem", c.name, "failures", c.failures, "cooldown", c.cooldown)
+	c.notify(true)
+}
+
+// Record records the result of a call, which fails if the err is non-nil.
+func (c *CircuitBreaker) Record(err error) {
+	if err != nil {
+		c.RecordFailure(err)
+	} else {
+		c.RecordSuccess()
+	}
+}
+
+// IsOpen returns whether the breaker is open, i.e. the subsystem is degraded.
+func (c *CircuitBreaker) IsOpen() bool {
+	if c == nil {
+		return false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.open
+}
+
+// OpenUntil returns the end of the current cooldown, or zero if the breaker is closed.
+func (c *CircuitBreaker) OpenUntil() time.Time {
+	if c == nil {
+		return time.Time{}
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.open {
+		return time.Time{}
+	}
+	return c.openUntil
+}
+
+func (c *CircuitBreaker) notify(open bool) {
+	if c.onStateChange != nil {
+		c.onStateChange(open)
+	}
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	var states []bool
+	c := NewCircuitBreaker("GPU", 3, time.Minute, func(open bool) {
+		states = append(states, open)
+	})
+	c.now = func() time.Time { return now }
+	testErr := fmt.Errorf("gpu is lost")
+
+	// the consecutive failures are reset by a success
+	c.RecordFailure(testErr)
+	c.RecordFailure(testErr)
+	c.RecordSuccess()
+	c.RecordFailure(testErr)
+	c.RecordFailure(testErr)
+	assert.True(t, c.Allow())
+	assert.False(t, c.IsOpen())
+	assert.NoError(t, c.Err())
+
+	// open after the consecutive failures reach the threshold
+	c.Record(testErr)
+	assert.True(t, c.IsOpen())
+	assert.False(t, c.Allow())
+	assert.Error(t, c.Err())
+	assert.Equal(t, now.Add(time.Minute), c.OpenUntil())
+	assert.Equal(t, []bool{true}, states)
+
+	// only one probe is allowed after the cooldown
+	now = now.Add(time.Minute)
+	assert.True(t, c.Allow())
+	assert.False(t, c.Allow())
+
+	// the failed probe restarts the cooldown
+	c.RecordFailure(testErr)
+	assert.True(t, c.IsOpen())
+	now = now.Add(30 * time.Second)
+	assert.False(t, c.Allow())
+
+	// the unrecorded probe does not block the next one
+	now = now.Add(30 * time.Second)
+	assert.True(t, c.Allow())
+	now = now.Add(time.Minute)
+	assert.True(t, c.Allow())
+
+	// close after the probe succeeds
+	c.Record(nil)
+	assert.False(t, c.IsOpen())
+	assert.True(t, c.Allow())
+	assert.Equal(t, time.Time{}, c.OpenUntil())
+	assert.Equal(t, []bool{true, false}, states)
+}
@@ -16,11 +16,18 @@ limitations under the License.
 
 package util
 
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
 type DeviceType string
 
 const (
 	GPUDeviceType  DeviceType = "GPU"
 	RDMADeviceType DeviceType = "RDMA"
+	FPGADeviceType DeviceType = "FPGA"
 )
 
 type Devices interface {
@@ -37,11 +44,84 @@ type GPUDeviceInfo struct {
 	// UUID represents the UUID of device
 	UUID string `json:"id,omitempty"`
 	// Minor represents the Minor number of Devices, starting from 0
-	Minor       int32  `json:"minor,omitempty"`
+	Minor int32 `json:"minor,omitempty"`
+	// MemoryTotal represents the total memory of device in bytes
 	MemoryTotal uint64 `json:"memory-total,omitempty"`
-	NodeID      int32  `json:"nodeID"`
-	PCIE        string `json:"pcie,omitempty"`
-	BusID       string `json:"busID,omitempty"`
+	// BAR1MemoryTotal represents the total BAR1 memory of device in bytes, 0 if not supported
+	BAR1MemoryTotal uint64 `json:"bar1-memory-total,omitempty"`
+	NodeID          int32  `json:"nodeID"`
+	PCIE            string `json:"pcie,omitempty"`
+	BusID           string `json:"busID,omitempty"`
+	// ComputeCapability represents the CUDA compute capability in the form of "major.minor", e.g. "8.0"
+	ComputeCapability string `json:"computeCapability,omitempty"`
+	// ProductName represents the product name of device, e.g. "A100-SXM4-80GB"
+	ProductName string `json:"productName,omitempty"`
+	// Unhealthy indicates the device is detected unhealthy by the collector
+	Unhealthy bool `json:"unhealthy,omitempty"`
+	// EncoderSupported indicates the device has the video encoder engines, e.g. NVENC
+	EncoderSupported bool `json:"encoderSupported,omitempty"`
+	// DecoderSupported indicates the device has the video decoder engines, e.g. NVDEC
+	DecoderSupported bool `json:"decoderSupported,omitempty"`
+	// MigCapable indicates the device supports the Multi-Instance GPU (MIG), e.g. A100
+	MigCapable bool `json:"migCapable,omitempty"`
+	// MigEnabled indicates the MIG mode of the MIG-capable device is currently enabled
+	MigEnabled bool `json:"migEnabled,omitempty"`
+	// MigPendingEnabled indicates the MIG mode of the MIG-capable device is enabled after the next gpu reset
+	MigPendingEnabled bool `json:"migPendingEnabled,omitempty"`
+	// FabricPartitionID represents the partition of the gpus connected by the same NVSwitches, empty if the device
+	// is not connected to any NVSwitch
+	FabricPartitionID string `json:"fabricPartitionID,omitempty"`
+	// PersistenceModeEnabled indicates whether the persistence mode of the device is enabled, nil if not supported
+	PersistenceModeEnabled *bool `json:"persistenceModeEnabled,omitempty"`
+	// CoolingDegraded indicates the fan of the device stops while the device is thermally loaded, e.g. a fan failure
+	CoolingDegraded bool `json:"coolingDegraded,omitempty"`
+	// RetiredPages is the count of the memory pages retired by the ECC errors of the device
+	RetiredPages uint32 `json:"retiredPages,omitempty"`
+	// RetiredPagesPending indicates some memory pages are pending retirement until the device is reset
+	RetiredPagesPending bool `json:"retiredPagesPending,omitempty"`
+}
+
+// MemoryUnit represents the unit of the memory value reported by the device library.
+type MemoryUnit string
+
+const (
+	MemoryUnitByte MemoryUnit = "B"
+	MemoryUnitKiB  MemoryUnit = "KiB"
+	MemoryUnitMiB  MemoryUnit = "MiB"
+	MemoryUnitGiB  MemoryUnit = "GiB"
+)
+
+var memoryUnitToBytes = map[MemoryUnit]uint64{
+	MemoryUnitByte: 1,
+	MemoryUnitKiB:  1 << 10,
+	MemoryUnitMiB:  1 << 20,
+	MemoryUnitGiB:  1 << 30,
+}
+
+// ConvertMemoryToBytes converts the memory value in the given unit to bytes.
+// An unknown unit is regarded as byte.
+func ConvertMemoryToBytes(value uint64, unit MemoryUnit) uint64 {
+	if factor, ok := memoryUnitToBytes[unit]; ok {
+		return value * factor
+	}
+	return value
+}
+
+// GPUMemoryQuantity returns the quantity of koordinator.sh/gpu-memory, which is always reported in bytes
+// no matter what unit the device library returns, e.g. 16Gi.
+func GPUMemoryQuantity(value uint64, unit MemoryUnit) resource.Quantity {
+	return *resource.NewQuantity(int64(ConvertMemoryToBytes(value, unit)), resource.BinarySI)
+}
+
+var productNameTrimmer = strings.NewReplacer("(R)", "", "(TM)", "")
+
+// FormatGPUProductName formats the product name reported by the device library as a valid label value,
+// e.g. "NVIDIA A100-SXM4-80GB" -> "A100-SXM4-80GB", "Tesla T4" -> "Tesla-T4",
+// "Intel(R) Data Center GPU Max 1550" -> "Intel-Data-Center-GPU-Max-1550".
+func FormatGPUProductName(name string) string {
+	name = strings.TrimPrefix(name, "NVIDIA ")
+	name = productNameTrimmer.Replace(name)
+	return strings.Join(strings.Fields(name), "-")
 }
 
 type RDMADevices []RDMADeviceInfo
@@ -66,6 +146,28 @@ type RDMADeviceInfo struct {
 	BusID         string                      `json:"busID,omitempty"`
 }
 
+type FPGADevices []FPGADeviceInfo
+
+func (f FPGADevices) Type() DeviceType {
+	return FPGADeviceType
+}
+
+type FPGADeviceInfo struct {
+	// ID represents the PCI address of the device
+	ID string `json:"id,omitempty"`
+	// Minor represents the Minor number of Devices, starting from 0
+	Minor      int32  `json:"minor"`
+	VendorCode string `json:"vendorCode,omitempty"`
+	DeviceCode string `json:"deviceCode,omitempty"`
+	// Driver represents the kernel driver bound to the device, e.g. "xocl"
+	Driver string `json:"driver,omitempty"`
+	NodeID int32  `json:"nodeID,omitempty"`
+	PCIE   string `json:"pcie,omitempty"`
+	BusID  string `json:"busID,omitempty"`
+	// Unhealthy indicates the device is detected unhealthy by the collector
+	Unhealthy bool `json:"unhealthy,omitempty"`
+}
+
 type VirtualFunction struct {
 	ID         string            `json:"id,omitempty"`
 	Labels     map[string]string `json:"labels,omitempty"`
@@ -0,0 +1,99 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestGPUMemoryQuantity(t *testing.T) {
+	tests := []struct {
+		name  string
+		value uint64
+		unit  MemoryUnit
+		want  resource.Quantity
+	}{
+		{
+			name:  "bytes",
+			value: 16 * 1024 * 1024 * 1024,
+			unit:  MemoryUnitByte,
+			want:  resource.MustParse("16Gi"),
+		},
+		{
+			name:  "MiB",
+			value: 16 * 1024,
+			unit:  MemoryUnitMiB,
+			want:  resource.MustParse("16Gi"),
+		},
+		{
+			name:  "GiB",
+			value: 80,
+			unit:  MemoryUnitGiB,
+			want:  resource.MustParse("80Gi"),
+		},
+		{
+			name:  "unknown unit is regarded as byte",
+			value: 8000,
+			unit:  MemoryUnit("unknown"),
+			want:  *resource.NewQuantity(8000, resource.BinarySI),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GPUMemoryQuantity(tt.value, tt.unit)
+			assert.Equal(t, tt.want.Value(), got.Value())
+			assert.Equal(t, tt.want.String(), got.String())
+		})
+	}
+}
+
+func TestFormatGPUProductName(t *testing.T) {
+	tests := []struct {
+		name string
+		arg  string
+		want string
+	}{
+		{
+			name: "trim nvidia prefix",
+			arg:  "NVIDIA A100-SXM4-80GB",
+			want: "A100-SXM4-80GB",
+		},
+		{
+			name: "replace spaces",
+			arg:  "Tesla T4",
+			want: "Tesla-T4",
+		},
+		{
+			name: "trim trademarks",
+			arg:  "Intel(R) Data Center GPU Max 1550",
+			want: "Intel-Data-Center-GPU-Max-1550",
+		},
+		{
+			name: "empty name",
+			arg:  "",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, FormatGPUProductName(tt.arg))
+		})
+	}
+}
@@ -37,6 +37,7 @@ const (
 	ErrUnsupportedGPURequests               = "node(s) Unsupported number of GPU requests"
 	ErrUnsupportedMultiSharedGPU            = "node(s) Unsupported Multi-Shared GPU"
 	ErrNodeMissingGPUDeviceTopologyTree     = "node(s) missing GPU Device Topology Tree"
+	ErrNodeGPUResourceNotSynced             = "node(s) GPU resources not synced with Device"
 )
 
 func init() {
@@ -545,6 +545,10 @@ func buildDeviceResources(device *schedulingv1alpha1.Device) map[schedulingv1alp
 		if !deviceInfo.Health {
 			resources = make(corev1.ResourceList)
 			klog.Errorf("Find device unhealthy, nodeName:%v, deviceType:%v, minor:%v", device.Name, deviceInfo.Type, deviceInfo.Minor)
+		} else if !apiext.IsDeviceSchedulable(&deviceInfo) {
+			// the draining device accepts no new pods, while the allocated ones keep running
+			resources = make(corev1.ResourceList)
+			klog.V(4).Infof("Find device draining, nodeName:%v, deviceType:%v, minor:%v", device.Name, deviceInfo.Type, deviceInfo.Minor)
 		} else {
 			resources = deviceInfo.Resources
 			klog.V(5).Infof("Find device resource update, nodeName:%v, deviceType:%v, minor:%v, res:%v", device.Name, deviceInfo.Type, deviceInfo.Minor, resources)
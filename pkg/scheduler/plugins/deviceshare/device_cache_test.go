@@ -142,3 +142,31 @@ func Test_gcNodeDevices(t *testing.T) {
 	nodeNames := sets.StringKeySet(cache.nodeDeviceInfos)
 	assert.Equal(t, expectedNodeNames, nodeNames)
 }
+
+func TestBuildDeviceResourcesWithDrainingDevice(t *testing.T) {
+	resources := corev1.ResourceList{
+		apiext.ResourceGPUCore:        resource.MustParse("100"),
+		apiext.ResourceGPUMemory:      resource.MustParse("8Gi"),
+		apiext.ResourceGPUMemoryRatio: resource.MustParse("100"),
+	}
+	device := &schedulingv1alpha1.Device{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-node"},
+		Spec: schedulingv1alpha1.DeviceSpec{
+			Devices: []schedulingv1alpha1.DeviceInfo{
+				{Type: schedulingv1alpha1.GPU, Minor: pointer.Int32(0), Health: true, Resources: resources},
+				{Type: schedulingv1alpha1.GPU, Minor: pointer.Int32(1), Health: true, HealthState: schedulingv1alpha1.DeviceHealthStateHealthy, Resources: resources},
+				{Type: schedulingv1alpha1.GPU, Minor: pointer.Int32(2), Health: true, HealthState: schedulingv1alpha1.DeviceHealthStateDraining, Resources: resources},
+				{Type: schedulingv1alpha1.GPU, Minor: pointer.Int32(3), Health: false, HealthState: schedulingv1alpha1.DeviceHealthStateUnhealthy, Resources: resources},
+			},
+		},
+	}
+	expected := map[schedulingv1alpha1.DeviceType]deviceResources{
+		schedulingv1alpha1.GPU: {
+			0: resources,
+			1: resources,
+			2: corev1.ResourceList{},
+			3: corev1.ResourceList{},
+		},
+	}
+	assert.Equal(t, expected, buildDeviceResources(device))
+}
@@ -333,6 +333,12 @@ func (p *Plugin) Filter(ctx context.Context, cycleState *framework.CycleState, p
 
 	nodeDeviceInfo.lock.RLock()
 	defer nodeDeviceInfo.lock.RUnlock()
+	// the gpus of the Device are allocated only after the node gpu resources are synced from them, so that the
+	// scheduler does not place the pods on the Device updated ahead of the node
+	if k8sfeature.DefaultFeatureGate.Enabled(features.EnableStrictGPUResourceSync) && state.podRequests[schedulingv1alpha1.GPU] != nil &&
+		!isNodeGPUResourceSynced(node, nodeDeviceInfo.deviceInfos[schedulingv1alpha1.GPU]) {
+		return framework.NewStatus(framework.Unschedulable, ErrNodeGPUResourceNotSynced)
+	}
 	allocateResult, status := p.tryAllocateFromReservation(allocator, state, restoreState, restoreState.matched, pod, node, preemptible, state.hasReservationAffinity)
 	if !status.IsSuccess() {
 		return status
@@ -2195,6 +2195,92 @@ func Test_Plugin_Filter(t *testing.T) {
 	}
 }
 
+func Test_Plugin_FilterWithStrictGPUResourceSync(t *testing.T) {
+	defer utilfeature.SetFeatureGateDuringTest(t, k8sfeature.DefaultMutableFeatureGate, koordfeatures.EnableStrictGPUResourceSync, true)()
+
+	device := &schedulingv1alpha1.Device{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-node",
+		},
+		Spec: schedulingv1alpha1.DeviceSpec{
+			Devices: []schedulingv1alpha1.DeviceInfo{
+				{
+					Type:   schedulingv1alpha1.GPU,
+					Minor:  pointer.Int32(0),
+					Health: true,
+					Resources: corev1.ResourceList{
+						apiext.ResourceGPUCore:        resource.MustParse("100"),
+						apiext.ResourceGPUMemoryRatio: resource.MustParse("100"),
+						apiext.ResourceGPUMemory:      resource.MustParse("16Gi"),
+					},
+				},
+				{
+					Type:   schedulingv1alpha1.GPU,
+					Minor:  pointer.Int32(1),
+					Health: false,
+					Resources: corev1.ResourceList{
+						apiext.ResourceGPUCore:        resource.MustParse("100"),
+						apiext.ResourceGPUMemoryRatio: resource.MustParse("100"),
+						apiext.ResourceGPUMemory:      resource.MustParse("16Gi"),
+					},
+				},
+			},
+		},
+	}
+	cache := newNodeDeviceCache()
+	cache.updateNodeDevice("test-node", device)
+	p := &Plugin{nodeDeviceCache: cache}
+
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							apiext.ResourceGPUCore:        resource.MustParse("50"),
+							apiext.ResourceGPUMemoryRatio: resource.MustParse("50"),
+						},
+					},
+				},
+			},
+		},
+	}
+	state, status := preparePod(pod)
+	assert.True(t, status.IsSuccess())
+	cycleState := framework.NewCycleState()
+	cycleState.Write(stateKey, state)
+
+	// the node resources are not synced from the healthy gpus yet
+	testNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-node",
+		},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				apiext.ResourceGPUCore:        resource.MustParse("200"),
+				apiext.ResourceGPUMemoryRatio: resource.MustParse("200"),
+				apiext.ResourceGPUMemory:      resource.MustParse("32Gi"),
+			},
+		},
+	}
+	nodeInfo := framework.NewNodeInfo()
+	nodeInfo.SetNode(testNode)
+	status = p.Filter(context.TODO(), cycleState, pod, nodeInfo)
+	assert.Equal(t, framework.NewStatus(framework.Unschedulable, ErrNodeGPUResourceNotSynced), status)
+
+	// the node resources are synced
+	testNode = testNode.DeepCopy()
+	testNode.Status.Allocatable = corev1.ResourceList{
+		apiext.ResourceGPUCore:        resource.MustParse("100"),
+		apiext.ResourceGPUMemoryRatio: resource.MustParse("100"),
+		apiext.ResourceGPUMemory:      resource.MustParse("16Gi"),
+	}
+	nodeInfo = framework.NewNodeInfo()
+	nodeInfo.SetNode(testNode)
+	status = p.Filter(context.TODO(), cycleState, pod, nodeInfo)
+	assert.True(t, status.IsSuccess())
+}
+
 func Test_Plugin_FilterNominateReservation(t *testing.T) {
 	node := &corev1.Node{
 		ObjectMeta: metav1.ObjectMeta{
@@ -443,3 +443,21 @@ func parseGPURequirements(pod *corev1.Pod, podRequests map[schedulingv1alpha1.De
 	}
 	return gpuRequirements, nil
 }
+
+// isNodeGPUResourceSynced returns whether the gpu resources of the node equal to the total resources of the healthy
+// gpus in the Device, i.e. the node resources are synced from the Device by koord-manager.
+func isNodeGPUResourceSynced(node *corev1.Node, gpus []*schedulingv1alpha1.DeviceInfo) bool {
+	total := corev1.ResourceList{}
+	for _, gpu := range gpus {
+		if gpu.Health {
+			util.AddResourceList(total, gpu.Resources)
+		}
+	}
+	for _, resourceName := range []corev1.ResourceName{apiext.ResourceGPUCore, apiext.ResourceGPUMemory, apiext.ResourceGPUMemoryRatio} {
+		expected, actual := total[resourceName], node.Status.Allocatable[resourceName]
+		if expected.Cmp(actual) != 0 {
+			return false
+		}
+	}
+	return true
+}
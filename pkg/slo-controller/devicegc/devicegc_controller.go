@@ -0,0 +1,126 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devicegc
+
+import (
+	"context"
+	"flag"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	schedulingv1alpha1 "github.com/koordinator-sh/koordinator/apis/scheduling/v1alpha1"
+)
+
+const Name = "devicegc"
+
+var (
+	// DeviceGCTTL is the duration which a Device is kept after its Node is missing.
+	DeviceGCTTL = 10 * time.Minute
+	// DeviceGCResyncInterval is the interval to check whether the Node of a Device still exists.
+	DeviceGCResyncInterval = 30 * time.Minute
+)
+
+func InitFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&DeviceGCTTL, "device-gc-ttl", DeviceGCTTL, "The duration which a Device is kept after its Node is missing, and then it is deleted.")
+	fs.DurationVar(&DeviceGCResyncInterval, "device-gc-resync-interval", DeviceGCResyncInterval, "The interval to check whether the Node of a Device still exists.")
+}
+
+// DeviceGCReconciler deletes the Device whose Node no longer exists, as a safety net of the owner reference GC,
+// e.g. when the Node is force-deleted without the cascading deletion.
+type DeviceGCReconciler struct {
+	client.Client
+	Clock clock.Clock
+
+	// orphanedSince records the first time the Node of a Device is found missing.
+	orphanedSince sync.Map
+}
+
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=scheduling.koordinator.sh,resources=devices,verbs=get;list;watch;delete
+
+func (r *DeviceGCReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	_ = log.FromContext(ctx, "device-gc-reconciler", req.NamespacedName)
+
+	device := &schedulingv1alpha1.Device{}
+	if err := r.Client.Get(ctx, req.NamespacedName, device); err != nil {
+		if !errors.IsNotFound(err) {
+			klog.Errorf("failed to get Device %v, error: %v", req.Name, err)
+			return ctrl.Result{Requeue: true}, err
+		}
+		r.orphanedSince.Delete(req.Name)
+		return ctrl.Result{}, nil
+	}
+
+	node := &corev1.Node{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: getNodeName(device)}, node); err == nil {
+		r.orphanedSince.Delete(req.Name)
+		return ctrl.Result{RequeueAfter: DeviceGCResyncInterval}, nil
+	} else if !errors.IsNotFound(err) {
+		klog.Errorf("failed to get Node of Device %v, error: %v", req.Name, err)
+		return ctrl.Result{Requeue: true}, err
+	}
+
+	now := r.Clock.Now()
+	value, _ := r.orphanedSince.LoadOrStore(req.Name, now)
+	if remaining := DeviceGCTTL - now.Sub(value.(time.Time)); remaining > 0 {
+		klog.V(4).Infof("Node of Device %v is missing, delete the Device after %v", req.Name, remaining)
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	if err := r.Client.Delete(ctx, device); err != nil && !errors.IsNotFound(err) {
+		klog.Errorf("failed to delete orphaned Device %v, error: %v", req.Name, err)
+		return ctrl.Result{Requeue: true}, err
+	}
+	r.orphanedSince.Delete(req.Name)
+	klog.Infof("delete orphaned Device %v since its Node has been missing for %v", req.Name, DeviceGCTTL)
+	return ctrl.Result{}, nil
+}
+
+// getNodeName returns the name of the owner Node of the Device, which is the same as the Device name by default.
+func getNodeName(device *schedulingv1alpha1.Device) string {
+	for _, owner := range device.OwnerReferences {
+		if owner.Kind == "Node" {
+			return owner.Name
+		}
+	}
+	return device.Name
+}
+
+func Add(mgr ctrl.Manager) error {
+	reconciler := &DeviceGCReconciler{
+		Client: mgr.GetClient(),
+		Clock:  clock.RealClock{},
+	}
+	return reconciler.SetupWithManager(mgr)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DeviceGCReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&schedulingv1alpha1.Device{}).
+		Named(Name).
+		Complete(r)
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devicegc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clocktesting "k8s.io/utils/clock/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	schedulingv1alpha1 "github.com/koordinator-sh/koordinator/apis/scheduling/v1alpha1"
+)
+
+func TestDeviceGCReconciler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = schedulingv1alpha1.AddToScheme(scheme)
+
+	newDevice := func(name string) *schedulingv1alpha1.Device {
+		return &schedulingv1alpha1.Device{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+				OwnerReferences: []metav1.OwnerReference{
+					{APIVersion: "v1", Kind: "Node", Name: name},
+				},
+			},
+		}
+	}
+	existingNode := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	r := &DeviceGCReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(existingNode, newDevice("node-1"), newDevice("node-2")).Build(),
+		Clock: fakeClock,
+	}
+	reconcile := func(name string) ctrl.Result {
+		result, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Name: name}})
+		assert.NoError(t, err)
+		return result
+	}
+	deviceExists := func(name string) bool {
+		err := r.Client.Get(context.TODO(), types.NamespacedName{Name: name}, &schedulingv1alpha1.Device{})
+		if errors.IsNotFound(err) {
+			return false
+		}
+		assert.NoError(t, err)
+		return true
+	}
+
+	// the Device of an existing Node is kept and checked periodically
+	assert.Equal(t, ctrl.Result{RequeueAfter: DeviceGCResyncInterval}, reconcile("node-1"))
+	assert.True(t, deviceExists("node-1"))
+
+	// the Device of a missing Node is kept until the TTL expires
+	assert.Equal(t, ctrl.Result{RequeueAfter: DeviceGCTTL}, reconcile("node-2"))
+	assert.True(t, deviceExists("node-2"))
+	fakeClock.Step(DeviceGCTTL / 2)
+	assert.Equal(t, ctrl.Result{RequeueAfter: DeviceGCTTL / 2}, reconcile("node-2"))
+	assert.True(t, deviceExists("node-2"))
+
+	// the Device is deleted after the TTL expires
+	fakeClock.Step(DeviceGCTTL / 2)
+	assert.Equal(t, ctrl.Result{}, reconcile("node-2"))
+	assert.False(t, deviceExists("node-2"))
+	_, ok := r.orphanedSince.Load("node-2")
+	assert.False(t, ok)
+
+	// the deleted Device is ignored
+	assert.Equal(t, ctrl.Result{}, reconcile("node-2"))
+}
@@ -73,11 +73,16 @@ func (p *Plugin) Setup(opt *framework.Option) error {
 }
 
 func (p *Plugin) NeedSync(strategy *configuration.ColocationStrategy, oldNode, newNode *corev1.Node) (bool, string) {
+	diffThreshold := *strategy.ResourceDiffThreshold
+	// the node gpu resources are derived from the Device, so publish them once the Device changes instead of
+	// letting the scheduler see the node resources inconsistent with the Device
+	if utilfeature.DefaultFeatureGate.Enabled(features.EnableStrictGPUResourceSync) {
+		diffThreshold = 0
+	}
 	for _, resourceName := range ResourceNames {
-		if util.IsResourceDiff(oldNode.Status.Allocatable, newNode.Status.Allocatable, resourceName,
-			*strategy.ResourceDiffThreshold) {
+		if util.IsResourceDiff(oldNode.Status.Allocatable, newNode.Status.Allocatable, resourceName, diffThreshold) {
 			klog.V(4).InfoS("need sync node since resource diff bigger than threshold", "node", newNode.Name,
-				"resource", resourceName, "threshold", *strategy.ResourceDiffThreshold)
+				"resource", resourceName, "threshold", diffThreshold)
 			return true, NeedSyncForResourceDiffMsg
 		}
 	}
@@ -119,11 +124,10 @@ func (p *Plugin) Prepare(_ *configuration.ColocationStrategy, node *corev1.Node,
 	// prepare node labels
 	// TBD: shall we reset labels if not exist in the NR
 	if nr.Labels != nil {
-		if _, ok := nr.Labels[extension.LabelGPUModel]; ok {
-			node.Labels[extension.LabelGPUModel] = nr.Labels[extension.LabelGPUModel]
-		}
-		if _, ok := nr.Labels[extension.LabelGPUDriverVersion]; ok {
-			node.Labels[extension.LabelGPUDriverVersion] = nr.Labels[extension.LabelGPUDriverVersion]
+		for _, label := range Labels {
+			if value, ok := nr.Labels[label]; ok {
+				node.Labels[label] = value
+			}
 		}
 	}
 
@@ -205,11 +209,10 @@ func (p *Plugin) calculate(node *corev1.Node, device *schedulingv1alpha1.Device)
 
 	// calculate labels about gpu driver and model
 	updatedLabels := map[string]string{}
-	if gpuModel, ok := device.Labels[extension.LabelGPUModel]; ok {
-		updatedLabels[extension.LabelGPUModel] = gpuModel
-	}
-	if gpuDriverVersion, ok := device.Labels[extension.LabelGPUDriverVersion]; ok {
-		updatedLabels[extension.LabelGPUDriverVersion] = gpuDriverVersion
+	for _, label := range Labels {
+		if value, ok := device.Labels[label]; ok {
+			updatedLabels[label] = value
+		}
 	}
 	if len(updatedLabels) != 0 {
 		items = append(items, framework.ResourceItem{
@@ -35,7 +35,9 @@ import (
 	"github.com/koordinator-sh/koordinator/apis/configuration"
 	"github.com/koordinator-sh/koordinator/apis/extension"
 	schedulingv1alpha1 "github.com/koordinator-sh/koordinator/apis/scheduling/v1alpha1"
+	"github.com/koordinator-sh/koordinator/pkg/features"
 	"github.com/koordinator-sh/koordinator/pkg/slo-controller/noderesource/framework"
+	utilfeature "github.com/koordinator-sh/koordinator/pkg/util/feature"
 	"github.com/koordinator-sh/koordinator/pkg/util/testutil"
 )
 
@@ -198,6 +200,28 @@ func TestPluginNeedSync(t *testing.T) {
 		got, got1 = p.NeedSync(testStrategy, testNodeWithDevice, testNodeWithoutDevice)
 		assert.True(t, got)
 		assert.Equal(t, NeedSyncForResourceDiffMsg, got1)
+
+		// a small resource update is ignored under the diff threshold
+		testNodeWithDeviceSmallUpdate := testNodeWithDevice.DeepCopy()
+		testNodeWithDeviceSmallUpdate.Status.Allocatable[extension.ResourceGPUMemory] = *resource.NewQuantity(16500, resource.DecimalSI)
+		got, got1 = p.NeedSync(testStrategy, testNodeWithDevice, testNodeWithDeviceSmallUpdate)
+		assert.False(t, got)
+		assert.Equal(t, "", got1)
+	})
+	t.Run("strict sync", func(t *testing.T) {
+		defer utilfeature.SetFeatureGateDuringTest(t, utilfeature.DefaultMutableFeatureGate, features.EnableStrictGPUResourceSync, true)()
+		p := &Plugin{}
+
+		got, got1 := p.NeedSync(testStrategy, testNodeWithDevice, testNodeWithDevice)
+		assert.False(t, got)
+		assert.Equal(t, "", got1)
+
+		// any resource update is synced regardless of the diff threshold
+		testNodeWithDeviceSmallUpdate := testNodeWithDevice.DeepCopy()
+		testNodeWithDeviceSmallUpdate.Status.Allocatable[extension.ResourceGPUMemory] = *resource.NewQuantity(16500, resource.DecimalSI)
+		got, got1 = p.NeedSync(testStrategy, testNodeWithDevice, testNodeWithDeviceSmallUpdate)
+		assert.True(t, got)
+		assert.Equal(t, NeedSyncForResourceDiffMsg, got1)
 	})
 }
 
@@ -328,6 +352,12 @@ func TestPluginNeedSyncMeta(t *testing.T) {
 		got, got1 = p.NeedSyncMeta(nil, testNodeWithDevice, testNodeWithDeviceDriverUpdate)
 		assert.True(t, got)
 		assert.Equal(t, fmt.Sprintf(NeedSyncForGPUModelMsgFmt, extension.LabelGPUDriverVersion), got1)
+		// cuda driver version update
+		testNodeWithCUDADriverUpdate := testNodeWithDevice.DeepCopy()
+		testNodeWithCUDADriverUpdate.Labels[extension.LabelGPUCUDADriverVersion] = "12.2"
+		got, got1 = p.NeedSyncMeta(nil, testNodeWithDevice, testNodeWithCUDADriverUpdate)
+		assert.True(t, got)
+		assert.Equal(t, fmt.Sprintf(NeedSyncForGPUModelMsgFmt, extension.LabelGPUCUDADriverVersion), got1)
 
 		// remove labels
 		got, got1 = p.NeedSyncMeta(nil, testNodeWithDevice, testNodeWithoutDevice)
@@ -359,9 +389,10 @@ func TestPluginPrepare(t *testing.T) {
 		ObjectMeta: metav1.ObjectMeta{
 			Name: "test-node",
 			Labels: map[string]string{
-				"test-label":                    "test-value",
-				extension.LabelGPUModel:         "A100",
-				extension.LabelGPUDriverVersion: "480",
+				"test-label":                        "test-value",
+				extension.LabelGPUModel:             "A100",
+				extension.LabelGPUDriverVersion:     "480",
+				extension.LabelGPUCUDADriverVersion: "11.4",
 			},
 		},
 		Status: corev1.NodeStatus{
@@ -387,9 +418,10 @@ func TestPluginPrepare(t *testing.T) {
 		ObjectMeta: metav1.ObjectMeta{
 			Name: "test-node",
 			Labels: map[string]string{
-				"test-label":                    "test-value",
-				extension.LabelGPUModel:         "A100",
-				extension.LabelGPUDriverVersion: "480",
+				"test-label":                        "test-value",
+				extension.LabelGPUModel:             "A100",
+				extension.LabelGPUDriverVersion:     "480",
+				extension.LabelGPUCUDADriverVersion: "11.4",
 			},
 		},
 		Status: corev1.NodeStatus{
@@ -435,8 +467,9 @@ func TestPluginPrepare(t *testing.T) {
 					},
 					ZoneResources: map[string]corev1.ResourceList{},
 					Labels: map[string]string{
-						extension.LabelGPUModel:         "A100",
-						extension.LabelGPUDriverVersion: "480",
+						extension.LabelGPUModel:             "A100",
+						extension.LabelGPUDriverVersion:     "480",
+						extension.LabelGPUCUDADriverVersion: "11.4",
 					},
 					Annotations: map[string]string{
 						"ignored-annotation": "ignored-value",
@@ -505,8 +538,9 @@ func TestPluginCalculate(t *testing.T) {
 		ObjectMeta: metav1.ObjectMeta{
 			Name: testNode.Name,
 			Labels: map[string]string{
-				extension.LabelGPUModel:         "A100",
-				extension.LabelGPUDriverVersion: "480",
+				extension.LabelGPUModel:             "A100",
+				extension.LabelGPUDriverVersion:     "480",
+				extension.LabelGPUCUDADriverVersion: "11.4",
 			},
 		},
 		Spec: schedulingv1alpha1.DeviceSpec{
@@ -682,8 +716,9 @@ func TestPluginCalculate(t *testing.T) {
 				{
 					Name: PluginName,
 					Labels: map[string]string{
-						extension.LabelGPUModel:         "A100",
-						extension.LabelGPUDriverVersion: "480",
+						extension.LabelGPUModel:             "A100",
+						extension.LabelGPUDriverVersion:     "480",
+						extension.LabelGPUCUDADriverVersion: "11.4",
 					},
 					Message: UpdateLabelsMsg,
 				},
@@ -727,8 +762,9 @@ func TestPluginCalculate(t *testing.T) {
 				{
 					Name: PluginName,
 					Labels: map[string]string{
-						extension.LabelGPUModel:         "A100",
-						extension.LabelGPUDriverVersion: "480",
+						extension.LabelGPUModel:             "A100",
+						extension.LabelGPUDriverVersion:     "480",
+						extension.LabelGPUCUDADriverVersion: "11.4",
 					},
 					Message: UpdateLabelsMsg,
 				},
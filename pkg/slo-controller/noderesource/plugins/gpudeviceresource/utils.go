@@ -36,5 +36,8 @@ var (
 	Labels = []string{
 		extension.LabelGPUModel,
 		extension.LabelGPUDriverVersion,
+		extension.LabelGPUCUDADriverVersion,
+		extension.LabelGPUFabricPartitionCount,
+		extension.LabelGPUFabricPartition,
 	}
 )
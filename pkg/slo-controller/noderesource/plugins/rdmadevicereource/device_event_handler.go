@@ -18,7 +18,6 @@ package rdmadeviceresource
 
 import (
 	"context"
-	"reflect"
 
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/util/workqueue"
@@ -27,6 +26,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	schedulingv1alpha1 "github.com/koordinator-sh/koordinator/apis/scheduling/v1alpha1"
+	"github.com/koordinator-sh/koordinator/pkg/util"
 )
 
 var _ handler.EventHandler = &DeviceHandler{}
@@ -45,7 +45,7 @@ func (d *DeviceHandler) Create(ctx context.Context, e event.CreateEvent, q workq
 func (d *DeviceHandler) Update(ctx context.Context, e event.UpdateEvent, q workqueue.RateLimitingInterface) {
 	newDevice := e.ObjectNew.(*schedulingv1alpha1.Device)
 	oldDevice := e.ObjectOld.(*schedulingv1alpha1.Device)
-	if reflect.DeepEqual(newDevice.Spec, oldDevice.Spec) {
+	if util.IsDeviceSpecEqual(&newDevice.Spec, &oldDevice.Spec) {
 		return
 	}
 	q.Add(reconcile.Request{
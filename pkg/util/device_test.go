@@ -0,0 +1,61 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	schedulingv1alpha1 "github.com/koordinator-sh/koordinator/apis/scheduling/v1alpha1"
+)
+
+func TestIsDeviceSpecEqual(t *testing.T) {
+	now := metav1.Now()
+	later := metav1.NewTime(now.Add(time.Minute))
+	spec := &schedulingv1alpha1.DeviceSpec{
+		Devices: []schedulingv1alpha1.DeviceInfo{
+			{
+				Type:           schedulingv1alpha1.GPU,
+				UUID:           "GPU-1",
+				Minor:          pointer.Int32(0),
+				Health:         true,
+				LastReportTime: &now,
+			},
+		},
+	}
+
+	refreshed := spec.DeepCopy()
+	refreshed.Devices[0].LastReportTime = &later
+	assert.True(t, IsDeviceSpecEqual(spec, refreshed))
+
+	unset := spec.DeepCopy()
+	unset.Devices[0].LastReportTime = nil
+	assert.True(t, IsDeviceSpecEqual(spec, unset))
+
+	unhealthy := refreshed.DeepCopy()
+	unhealthy.Devices[0].Health = false
+	assert.False(t, IsDeviceSpecEqual(spec, unhealthy))
+
+	assert.False(t, IsDeviceSpecEqual(spec, &schedulingv1alpha1.DeviceSpec{}))
+	assert.True(t, IsDeviceSpecEqual(&schedulingv1alpha1.DeviceSpec{}, &schedulingv1alpha1.DeviceSpec{}))
+	// the loop copies must not modify the input
+	assert.NotNil(t, spec.Devices[0].LastReportTime)
+}
@@ -19,6 +19,7 @@ package elasticquota
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	v1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -41,22 +42,33 @@ type QuotaMetaChecker struct {
 }
 
 var (
-	quotaMetaCheck = &QuotaMetaChecker{
-		QuotaTopo: nil,
-	}
+	// sharedQuotaTopo is the quota topology shared by all the checkers, which is built with the client of the first
+	// checker, since it is updated by the only quota informer.
+	sharedQuotaTopo     *quotaTopology
+	sharedQuotaTopoOnce sync.Once
+	// sharedQuotaInformer is the informer updating the sharedQuotaTopo, which is injected at most once.
+	sharedQuotaInformer     cache.Informer
+	sharedQuotaInformerLock sync.RWMutex
 )
 
 func (c *QuotaMetaChecker) Name() string {
 	return "QuotaMetaChecker"
 }
 
+// NewPlugin returns a checker with the decoder and client of the caller, which only shares the quota topology and
+// the quota informer with the other checkers, so that the concurrent requests do not race on the client and decoder.
 func NewPlugin(decoder *admission.Decoder, client client.Client) *QuotaMetaChecker {
-	quotaMetaCheck.Client = client
-	quotaMetaCheck.Decoder = decoder
-	if quotaMetaCheck.QuotaTopo == nil {
-		quotaMetaCheck.QuotaTopo = NewQuotaTopology(client)
+	sharedQuotaTopoOnce.Do(func() {
+		sharedQuotaTopo = NewQuotaTopology(client)
+	})
+	sharedQuotaInformerLock.RLock()
+	defer sharedQuotaInformerLock.RUnlock()
+	return &QuotaMetaChecker{
+		Client:        client,
+		Decoder:       decoder,
+		QuotaTopo:     sharedQuotaTopo,
+		QuotaInformer: sharedQuotaInformer,
 	}
-	return quotaMetaCheck
 }
 
 func (c *QuotaMetaChecker) AdmitQuota(ctx context.Context, req admission.Request, obj runtime.Object) error {
@@ -121,7 +133,16 @@ func (c *QuotaMetaChecker) GetQuotaTopologyInfo() *QuotaTopologySummary {
 	if c.QuotaTopo == nil {
 		return nil
 	}
-	return quotaMetaCheck.QuotaTopo.getQuotaTopologyInfo()
+	return c.QuotaTopo.getQuotaTopologyInfo()
+}
+
+// QuotaTopologyGeneration returns the generation of the quota topology, which is changed whenever the topology
+// changes, or 0 if the topology is not initialized.
+func (c *QuotaMetaChecker) QuotaTopologyGeneration() uint64 {
+	if c.QuotaTopo == nil {
+		return 0
+	}
+	return c.QuotaTopo.Generation()
 }
 
 func (c *QuotaMetaChecker) GetQuotaInfo(name, namespace string) *QuotaInfo {
@@ -133,6 +154,9 @@ func (c *QuotaMetaChecker) GetQuotaInfo(name, namespace string) *QuotaInfo {
 }
 
 func (c *QuotaMetaChecker) InjectInformer(elasticQuotaInformer cache.Informer) {
+	sharedQuotaInformerLock.Lock()
+	defer sharedQuotaInformerLock.Unlock()
+	sharedQuotaInformer = elasticQuotaInformer
 	c.QuotaInformer = elasticQuotaInformer
 }
 
@@ -18,10 +18,12 @@ package elasticquota
 
 import (
 	"context"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -31,6 +33,7 @@ import (
 	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
 
 	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/pkg/util"
 )
 
 func TestQuotaMetaChecker(t *testing.T) {
@@ -69,3 +72,96 @@ func TestQuotaMetaChecker(t *testing.T) {
 	assert.Equal(t, parentQuota.Name, quotaInfo.Name)
 	assert.Equal(t, extension.RootQuotaName, quotaInfo.ParentName)
 }
+
+// TestQuotaMetaCheckerConcurrentAccess validates the pods while the informer updates the quota topology, run it
+// with -race to detect the data races.
+func TestQuotaMetaCheckerConcurrentAccess(t *testing.T) {
+	client := fake.NewClientBuilder().Build()
+	sche := client.Scheme()
+	sche.AddKnownTypes(schema.GroupVersion{
+		Group:   "scheduling.sigs.k8s.io",
+		Version: "v1alpha1",
+	}, &v1alpha1.ElasticQuota{}, &v1alpha1.ElasticQuotaList{})
+	decoder := admission.NewDecoder(sche)
+	checker := &QuotaMetaChecker{
+		Client:    client,
+		Decoder:   decoder,
+		QuotaTopo: NewQuotaTopology(client),
+	}
+
+	parentQuota := MakeQuota("parent-quota").Namespace("kube-system").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).
+		Min(MakeResourceList().CPU(120).Mem(1048576).Obj()).IsParent(true).Obj()
+	childQuota := MakeQuota("child-quota").Namespace("kube-system").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).
+		Min(MakeResourceList().CPU(60).Mem(1048576).Obj()).ParentName("parent-quota").Obj()
+	checker.QuotaTopo.OnQuotaAdd(parentQuota)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "kube-system",
+			Name:      "test-pod",
+			Labels: map[string]string{
+				extension.LabelQuotaName: "child-quota",
+			},
+		},
+	}
+	request := admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Object: runtime.RawExtension{
+				Raw: []byte(util.DumpJSON(pod)),
+			},
+		},
+	}
+
+	const rounds = 100
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			checker.QuotaTopo.OnQuotaAdd(childQuota)
+			updatedQuota := childQuota.DeepCopy()
+			updatedQuota.Spec.Min = MakeResourceList().CPU(int64(i)).Mem(1048576).Obj()
+			checker.QuotaTopo.OnQuotaUpdate(childQuota, updatedQuota)
+			checker.QuotaTopo.OnQuotaDelete(updatedQuota)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			assert.NoError(t, checker.ValidatePod(context.TODO(), request))
+			checker.GetQuotaInfo("child-quota", "kube-system")
+			assert.NotNil(t, checker.GetQuotaTopologyInfo())
+		}
+	}()
+	wg.Wait()
+}
+
+func TestNewPluginSharesQuotaTopology(t *testing.T) {
+	client1 := fake.NewClientBuilder().Build()
+	client2 := fake.NewClientBuilder().Build()
+	decoder := admission.NewDecoder(client1.Scheme())
+
+	var wg sync.WaitGroup
+	checkers := make([]*QuotaMetaChecker, 10)
+	for i := range checkers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				checkers[i] = NewPlugin(decoder, client1)
+			} else {
+				checkers[i] = NewPlugin(decoder, client2)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for i, checker := range checkers {
+		if i%2 == 0 {
+			assert.Same(t, client1, checker.Client)
+		} else {
+			assert.Same(t, client2, checker.Client)
+		}
+		assert.Same(t, checkers[0].QuotaTopo, checker.QuotaTopo)
+	}
+}
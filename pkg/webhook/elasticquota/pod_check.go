@@ -31,6 +31,7 @@ import (
 
 	"github.com/koordinator-sh/koordinator/apis/extension"
 	"github.com/koordinator-sh/koordinator/pkg/features"
+	"github.com/koordinator-sh/koordinator/pkg/util"
 	utilclient "github.com/koordinator-sh/koordinator/pkg/util/client"
 	utilfeature "github.com/koordinator-sh/koordinator/pkg/util/feature"
 )
@@ -39,8 +40,8 @@ import (
 // Supporting the parentQuotaGroup to submit pods is the future work.
 
 func (qt *quotaTopology) ValidateAddPod(pod *corev1.Pod) error {
-	qt.lock.Lock()
-	defer qt.lock.Unlock()
+	qt.lock.RLock()
+	defer qt.lock.RUnlock()
 
 	featureGate := utilfeature.DefaultFeatureGate
 	if featureGate.Enabled(features.SupportParentQuotaSubmitPod) {
@@ -105,38 +106,38 @@ func GetQuotaName(pod *corev1.Pod, kubeClient client.Client) string {
 	return extension.DefaultQuotaName
 }
 
-// hasQuotaBoundedPods returns true if the quota has bounded pods.
-func hasQuotaBoundedPods(kubeClient client.Client, quotaName string, namespaces []string) (bool, error) {
+// getQuotaActivePod returns the key of a non-terminated pod charged to the quota, or "" if there is none.
+// The pods are charged to the quota either by the quota label, or by running in the namespace bound to the quota
+// without the quota label when the default quota is enabled.
+func getQuotaActivePod(kubeClient client.Client, quotaName string, namespaces []string) (string, error) {
 	podList := &corev1.PodList{}
 	if err := kubeClient.List(context.TODO(), podList, &client.ListOptions{
 		FieldSelector: fields.OneTermEqualSelector("label.quotaName", quotaName),
 	}, utilclient.DisableDeepCopy); err != nil {
-		return false, err
+		return "", err
 	}
-	if len(podList.Items) > 0 {
-		return true, nil
+	for i := range podList.Items {
+		if pod := &podList.Items[i]; !util.IsPodTerminated(pod) {
+			return pod.Namespace + "/" + pod.Name, nil
+		}
 	}
 
-	if err := kubeClient.List(context.TODO(), podList, &client.ListOptions{
-		Namespace: quotaName,
-	}, utilclient.DisableDeepCopy); err != nil {
-		return false, err
-	}
-	if len(podList.Items) > 0 {
-		return true, nil
+	if utilfeature.DefaultFeatureGate.Enabled(features.DisableDefaultQuota) {
+		return "", nil
 	}
-
-	for _, namespace := range namespaces {
+	for _, namespace := range append([]string{quotaName}, namespaces...) {
+		podList := &corev1.PodList{}
 		if err := kubeClient.List(context.TODO(), podList, &client.ListOptions{
 			Namespace: namespace,
 		}, utilclient.DisableDeepCopy); err != nil {
-			return false, err
+			return "", err
 		}
-
-		if len(podList.Items) > 0 {
-			return true, nil
+		for i := range podList.Items {
+			pod := &podList.Items[i]
+			if extension.GetQuotaName(pod) == "" && !util.IsPodTerminated(pod) {
+				return pod.Namespace + "/" + pod.Name, nil
+			}
 		}
 	}
-
-	return false, nil
+	return "", nil
 }
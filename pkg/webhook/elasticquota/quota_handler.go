@@ -52,6 +52,7 @@ func (qt *quotaTopology) OnQuotaAdd(obj interface{}) {
 		qt.namespaceToQuotaMap[ns] = quota.Name
 	}
 
+	qt.generation++
 	klog.V(5).Infof("OnQuotaAdd success: %v.%v", quota.Namespace, quota.Name)
 }
 
@@ -91,6 +92,7 @@ func (qt *quotaTopology) OnQuotaUpdate(oldObj, newObj interface{}) {
 		}
 	}
 
+	qt.generation++
 	klog.V(5).Infof("OnQuotaUpdate success: %v.%v", newQuota.Namespace, newQuota.Name)
 }
 
@@ -114,5 +116,6 @@ func (qt *quotaTopology) OnQuotaDelete(obj interface{}) {
 	for _, ns := range namespaces {
 		delete(qt.namespaceToQuotaMap, ns)
 	}
+	qt.generation++
 	klog.V(5).Infof("OnQuotaDelete success: %v.%v", quota.Namespace, quota.Name)
 }
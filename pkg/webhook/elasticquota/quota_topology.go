@@ -17,32 +17,32 @@ limitations under the License.
 package elasticquota
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"reflect"
 	"sync"
 
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
 
 	"github.com/koordinator-sh/koordinator/apis/extension"
-	utilclient "github.com/koordinator-sh/koordinator/pkg/util/client"
 	"github.com/koordinator-sh/koordinator/pkg/webhook/metrics"
 )
 
 type quotaTopology struct {
-	lock sync.Mutex
+	// lock protects the topology, which is read by the admission requests and updated by the informer concurrently
+	lock sync.RWMutex
 	// quotaInfoMap stores all quota information
 	quotaInfoMap map[string]*QuotaInfo
 	// namespaceMap key: annotationNamespace, val: quotaName
 	namespaceToQuotaMap map[string]string
 	// quotaHierarchyInfo stores the quota's all children
 	quotaHierarchyInfo map[string]map[string]struct{}
+	// generation is increased on each change of the topology, which invalidates the decisions derived from it
+	generation uint64
 
 	client client.Client
 }
@@ -58,6 +58,13 @@ func NewQuotaTopology(client client.Client) *quotaTopology {
 	return topology
 }
 
+// Generation returns the generation of the topology, which is changed whenever the topology changes.
+func (qt *quotaTopology) Generation() uint64 {
+	qt.lock.RLock()
+	defer qt.lock.RUnlock()
+	return qt.generation
+}
+
 func (qt *quotaTopology) ValidAddQuota(quota *v1alpha1.ElasticQuota) error {
 	if quota == nil {
 		return fmt.Errorf("AddQuota param is nil")
@@ -93,6 +100,7 @@ func (qt *quotaTopology) ValidAddQuota(quota *v1alpha1.ElasticQuota) error {
 	for _, namespace := range annotationNamespaces {
 		qt.namespaceToQuotaMap[namespace] = quota.Name
 	}
+	qt.generation++
 	return nil
 }
 
@@ -149,9 +157,14 @@ func (qt *quotaTopology) ValidUpdateQuota(oldQuota, newQuota *v1alpha1.ElasticQu
 	for _, namespace := range annotationNamespaces {
 		qt.namespaceToQuotaMap[namespace] = quotaName
 	}
+	qt.generation++
 	return nil
 }
 
+// ValidDeleteQuota rejects deleting the quota which still has child quotas or active pods charged to it, since the
+// usage of these pods would be orphaned in the topology once the quota is removed by OnQuotaDelete.
+// The quota should be cleaned up like a finalizer: delete the child quotas and drain the pods (or move them to
+// another quota) first, and the deletion is admitted once the remaining pods are all terminated.
 func (qt *quotaTopology) ValidDeleteQuota(quota *v1alpha1.ElasticQuota) error {
 	qt.lock.Lock()
 	defer qt.lock.Unlock()
@@ -174,25 +187,22 @@ func (qt *quotaTopology) ValidDeleteQuota(quota *v1alpha1.ElasticQuota) error {
 		return fmt.Errorf("BUG quotaMap and quotaTree information out of sync, losed :%v", quotaName)
 	}
 
-	podList := &corev1.PodList{}
-	opts := &client.ListOptions{
-		FieldSelector: fields.OneTermEqualSelector("label.quotaName", quota.Name),
-	}
-	err := qt.client.List(context.TODO(), podList, opts, utilclient.DisableDeepCopy)
+	annotationNamespaces := extension.GetAnnotationQuotaNamespaces(quota)
+	activePod, err := getQuotaActivePod(qt.client, quotaName, annotationNamespaces)
 	if err != nil {
 		return fmt.Errorf("failed list pods for quota %v, err: %v", quota.Name, err)
 	}
-	if len(podList.Items) > 0 {
-		return fmt.Errorf("delete quota failed, quota %v has child pods", quotaName)
+	if activePod != "" {
+		return fmt.Errorf("delete quota failed, quota %v has active pod %v, delete or move the pods to another quota first", quotaName, activePod)
 	}
 
 	delete(qt.quotaHierarchyInfo[quotaInfo.ParentName], quotaName)
 	delete(qt.quotaHierarchyInfo, quotaName)
 	delete(qt.quotaInfoMap, quotaName)
-	annotationNamespaces := extension.GetAnnotationQuotaNamespaces(quota)
 	for _, namespace := range annotationNamespaces {
 		delete(qt.namespaceToQuotaMap, namespace)
 	}
+	qt.generation++
 	return nil
 }
 
@@ -202,8 +212,8 @@ func (qt *quotaTopology) fillQuotaDefaultInformation(quota *v1alpha1.ElasticQuot
 		return nil
 	}
 
-	qt.lock.Lock()
-	defer qt.lock.Unlock()
+	qt.lock.RLock()
+	defer qt.lock.RUnlock()
 
 	if quota.Labels == nil {
 		quota.Labels = make(map[string]string)
@@ -269,8 +279,8 @@ func NewQuotaTopologySummary() *QuotaTopologySummary {
 func (qt *quotaTopology) getQuotaTopologyInfo() *QuotaTopologySummary {
 	result := NewQuotaTopologySummary()
 
-	qt.lock.Lock()
-	defer qt.lock.Unlock()
+	qt.lock.RLock()
+	defer qt.lock.RUnlock()
 
 	for key, value := range qt.quotaInfoMap {
 		result.QuotaInfoMap[key] = value.GetQuotaSummary()
@@ -287,8 +297,8 @@ func (qt *quotaTopology) getQuotaTopologyInfo() *QuotaTopologySummary {
 }
 
 func (qt *quotaTopology) getQuotaInfo(name, namespace string) *QuotaInfo {
-	qt.lock.Lock()
-	defer qt.lock.Unlock()
+	qt.lock.RLock()
+	defer qt.lock.RUnlock()
 
 	info, ok := qt.quotaInfoMap[name]
 	if ok {
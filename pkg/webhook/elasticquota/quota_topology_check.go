@@ -101,6 +101,10 @@ func (qt *quotaTopology) validateQuotaTopology(oldQuotaInfo, newQuotaInfo *Quota
 		return fmt.Errorf("failed to check sub and parent group quotaKey, err: %w", err)
 	}
 
+	if err := qt.checkMinNotExceedParentMax(newQuotaInfo); err != nil {
+		return err
+	}
+
 	if err := qt.checkMinQuotaValidate(newQuotaInfo); err != nil {
 		return err
 	}
@@ -157,11 +161,11 @@ func (qt *quotaTopology) checkIsParentChange(oldQuotaInfo, quotaInfo *QuotaInfo,
 	}
 
 	if quotaInfo.IsParent {
-		hasPods, err := hasQuotaBoundedPods(qt.client, oldQuotaInfo.Name, oldNamespaces)
+		activePod, err := getQuotaActivePod(qt.client, oldQuotaInfo.Name, oldNamespaces)
 		if err != nil {
 			return err
 		}
-		if hasPods {
+		if activePod != "" {
 			return fmt.Errorf("quota has bound pods, isParent is forbidden to modify as true, quotaName: %v", oldQuotaInfo.Name)
 		}
 	}
@@ -245,6 +249,45 @@ func (qt *quotaTopology) checkSubAndParentGroupQuotaKey(quotaInfo *QuotaInfo, en
 	return nil
 }
 
+// checkMinNotExceedParentMax will do two checks:
+//  1. the quota's min should less than or equal to its parent's max in each dimension.
+//  2. each child's min should less than or equal to the quota's max in each dimension.
+func (qt *quotaTopology) checkMinNotExceedParentMax(quotaInfo *QuotaInfo) error {
+	if quotaInfo.ParentName != extension.RootQuotaName {
+		parentInfo, exist := qt.quotaInfoMap[quotaInfo.ParentName]
+		if !exist {
+			return fmt.Errorf("%v has parentName %v but not find parentInfo in quotaInfoMap", quotaInfo.Name, quotaInfo.ParentName)
+		}
+		if key, exceeded := minExceedMax(quotaInfo.CalculateInfo.Min, parentInfo.CalculateInfo.Max); exceeded {
+			return fmt.Errorf("resourceKey %v of quota %v min :%v > parent %v max :%v",
+				key, quotaInfo.Name, quotaInfo.CalculateInfo.Min, parentInfo.Name, parentInfo.CalculateInfo.Max)
+		}
+	}
+
+	for name := range qt.quotaHierarchyInfo[quotaInfo.Name] {
+		child, exist := qt.quotaInfoMap[name]
+		if !exist {
+			return fmt.Errorf("BUG quotaInfoMap and quotaTree information out of sync, losed :%v", name)
+		}
+		if key, exceeded := minExceedMax(child.CalculateInfo.Min, quotaInfo.CalculateInfo.Max); exceeded {
+			return fmt.Errorf("resourceKey %v of child quota %v min :%v > quota %v max :%v",
+				key, child.Name, child.CalculateInfo.Min, quotaInfo.Name, quotaInfo.CalculateInfo.Max)
+		}
+	}
+	return nil
+}
+
+// minExceedMax returns the first resource key whose quantity in min is larger than that in max.
+// The keys not included in max are ignored, since they are checked by checkSubAndParentGroupQuotaKey.
+func minExceedMax(min, max v1.ResourceList) (v1.ResourceName, bool) {
+	for key, minVal := range min {
+		if maxVal, exist := max[key]; exist && maxVal.Cmp(minVal) < 0 {
+			return key, true
+		}
+	}
+	return "", false
+}
+
 // checkMinQuotaValidate will do two checks:
 //  1. the sum of brothers' minquota should less than or equal to parentMinQuota.
 //  2. the sum of children's minquota should less than or equal to newQuotaMin.
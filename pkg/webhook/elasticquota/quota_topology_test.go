@@ -514,6 +514,52 @@ func TestQuotaTopology_checkMinQuotaSum(t *testing.T) {
 	}
 }
 
+func TestQuotaTopology_checkMinNotExceedParentMax(t *testing.T) {
+	tests := []struct {
+		name        string
+		parentQuota *v1alpha1.ElasticQuota
+		quota       *v1alpha1.ElasticQuota
+		subQuota    *v1alpha1.ElasticQuota
+		wantErr     bool
+	}{
+		{
+			name: "satisfy",
+			parentQuota: MakeQuota("temp").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).
+				Min(MakeResourceList().CPU(64).Mem(51200).Obj()).IsParent(true).Obj(),
+			quota: MakeQuota("sub-1").ParentName("temp").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).
+				Min(MakeResourceList().CPU(16).Mem(12800).Obj()).IsParent(true).Obj(),
+			subQuota: MakeQuota("sub-sub-1").ParentName("sub-1").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).
+				Min(MakeResourceList().CPU(16).Mem(12800).Obj()).IsParent(false).Obj(),
+		},
+		{
+			name: "min exceeds parent's max",
+			parentQuota: MakeQuota("temp").Max(MakeResourceList().CPU(10).Mem(1048576).Obj()).
+				Min(MakeResourceList().CPU(10).Mem(51200).Obj()).IsParent(true).Obj(),
+			quota: MakeQuota("sub-1").ParentName("temp").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).
+				Min(MakeResourceList().CPU(16).Mem(12800).Obj()).IsParent(false).Obj(),
+			wantErr: true,
+		},
+		{
+			name: "child's min exceeds max",
+			quota: MakeQuota("temp").Max(MakeResourceList().CPU(10).Mem(1048576).Obj()).
+				Min(MakeResourceList().CPU(10).Mem(51200).Obj()).IsParent(true).Obj(),
+			subQuota: MakeQuota("sub-1").ParentName("temp").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).
+				Min(MakeResourceList().CPU(16).Mem(12800).Obj()).IsParent(false).Obj(),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qt := newFakeQuotaTopology()
+			qt.OnQuotaAdd(tt.parentQuota)
+			qt.OnQuotaAdd(tt.quota)
+			qt.OnQuotaAdd(tt.subQuota)
+			err := qt.checkMinNotExceedParentMax(NewQuotaInfoFromQuota(tt.quota))
+			assert.Equal(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
 func TestQuotaTopology_ValidAddQuota(t *testing.T) {
 	qt := newFakeQuotaTopology()
 	quota := MakeQuota("temp").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).
@@ -674,6 +720,15 @@ func TestQuotaTopology_ValidUpdateQuota(t *testing.T) {
 	newSub1.Labels[extension.LabelQuotaTreeID] = "tree-1"
 	err = qt.ValidUpdateQuota(sub1, newSub1)
 	assert.Equal(t, fmt.Sprint("sub-1 tree id changed [] vs [tree-1]"), err.Error())
+
+	// the terminated pods are not bound
+	qt.client.Delete(context.TODO(), pod3)
+	pod4 := MakePod("sub-2", "pod4").Label(extension.LabelQuotaName, "sub-1").Obj()
+	pod4.Status.Phase = v1.PodSucceeded
+	qt.client.Create(context.TODO(), pod4)
+	activePod, err := getQuotaActivePod(qt.client, "sub-1", []string{"namespace1", "namespace2"})
+	assert.NoError(t, err)
+	assert.Equal(t, "", activePod)
 }
 
 func TestQuotaTopology_ListQuotaPods(t *testing.T) {
@@ -804,11 +859,31 @@ func TestQuotaTopology_ValidDeleteQuota(t *testing.T) {
 	err = qt.ValidDeleteQuota(sub1)
 	assert.Error(t, err)
 
-	// delete pod
+	// terminate pod
 	err = qt.client.Delete(context.TODO(), pod)
 	assert.Nil(t, err)
+	pod = MakePod("sub-1", "pod1").Label(extension.LabelQuotaName, "sub-1").Obj()
+	pod.Status.Phase = v1.PodSucceeded
+	err = qt.client.Create(context.TODO(), pod)
+	assert.Nil(t, err)
+	sub1Copy := sub1.DeepCopy()
+	sub1Copy.Annotations[extension.AnnotationQuotaNamespaces] = "[\"test1\"]"
+	qt.lock.Lock()
+	qt.namespaceToQuotaMap["test1"] = sub1.Name
+	qt.lock.Unlock()
 
-	err = qt.ValidDeleteQuota(sub1)
+	// forbidden delete quota with unlabeled pods in the bound namespace
+	pod2 := MakePod("test1", "pod2").Obj()
+	err = qt.client.Create(context.TODO(), pod2)
+	assert.Nil(t, err)
+	err = qt.ValidDeleteQuota(sub1Copy)
+	assert.Error(t, err)
+
+	// delete pod, the terminated pod does not block the deletion
+	err = qt.client.Delete(context.TODO(), pod2)
+	assert.Nil(t, err)
+
+	err = qt.ValidDeleteQuota(sub1Copy)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(qt.quotaInfoMap))
 	assert.Equal(t, 2, len(qt.quotaHierarchyInfo))
@@ -33,9 +33,55 @@ func (h *PodMutatingHandler) deviceResourceSpecMutatingPod(ctx context.Context,
 		return nil
 	}
 
+	fillGPUResourceRequirements(pod)
 	return h.mutateByDeviceResources(pod)
 }
 
+// gpuResourceNames are the GPU resources whose requests and limits must be equal like the other extended resources.
+var gpuResourceNames = []corev1.ResourceName{
+	extension.ResourceNvidiaGPU,
+	extension.ResourceHygonDCU,
+	extension.ResourceGPU,
+	extension.ResourceGPUCore,
+	extension.ResourceGPUMemory,
+	extension.ResourceGPUMemoryRatio,
+	extension.ResourceGPUShared,
+}
+
+// fillGPUResourceRequirements fills the missing requests or limits of the GPU resources of the containers with the
+// other ones, since the GPU resources must be specified equally in both like the other extended resources, e.g. the
+// batch jobs only setting the requests. The specified ones are never overwritten, so it is idempotent.
+func fillGPUResourceRequirements(pod *corev1.Pod) {
+	fill := func(container *corev1.Container) {
+		for _, name := range gpuResourceNames {
+			request, hasRequest := container.Resources.Requests[name]
+			limit, hasLimit := container.Resources.Limits[name]
+			if hasRequest == hasLimit {
+				continue
+			}
+			if hasRequest {
+				if container.Resources.Limits == nil {
+					container.Resources.Limits = corev1.ResourceList{}
+				}
+				container.Resources.Limits[name] = request.DeepCopy()
+			} else {
+				if container.Resources.Requests == nil {
+					container.Resources.Requests = corev1.ResourceList{}
+				}
+				container.Resources.Requests[name] = limit.DeepCopy()
+			}
+			klog.V(4).Infof("fill the %s of container %s in Pod %s/%s with the specified one",
+				name, container.Name, pod.Namespace, pod.Name)
+		}
+	}
+	for i := range pod.Spec.InitContainers {
+		fill(&pod.Spec.InitContainers[i])
+	}
+	for i := range pod.Spec.Containers {
+		fill(&pod.Spec.Containers[i])
+	}
+}
+
 func (h *PodMutatingHandler) mutateByDeviceResources(pod *corev1.Pod) error {
 	// device resource request euqal limit, not overcommit
 	for i := range pod.Spec.Containers {
@@ -54,6 +100,13 @@ func (h *PodMutatingHandler) mutateByDeviceResources(pod *corev1.Pod) error {
 		// use gpu api first
 		_, ok := r.Requests[extension.ResourceGPU]
 		if ok {
+			// keep the ambiguous requests mixing the whole and fractional GPUs to be rejected by the validating webhook
+			// instead of overwriting the fractional requests
+			if requestsFractionalGPU(container) {
+				klog.V(4).Infof("skip mutating the GPU resources of container %s in Pod %s/%s, which requests both whole and fractional GPUs",
+					container.Name, pod.Namespace, pod.Name)
+				continue
+			}
 			injectGPU(container)
 		}
 
@@ -67,6 +120,15 @@ func (h *PodMutatingHandler) mutateByDeviceResources(pod *corev1.Pod) error {
 	return nil
 }
 
+func requestsFractionalGPU(c *corev1.Container) bool {
+	for _, name := range []corev1.ResourceName{extension.ResourceGPUCore, extension.ResourceGPUMemory, extension.ResourceGPUMemoryRatio} {
+		if _, ok := c.Resources.Requests[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 func injectResourceContainerSpec(c *corev1.Container, s *extension.ExtendedResourceContainerSpec) {
 	for resource := range s.Requests {
 		c.Resources.Requests[resource] = s.Requests[resource]
@@ -161,6 +161,70 @@ func TestDeviceResourceSpecMutatingPod(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "not mutating gpu mixed with gpu memory ratio",
+			resourceRequirements: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					extension.ResourceGPU:            *resource.NewQuantity(100, resource.DecimalSI),
+					extension.ResourceGPUMemoryRatio: *resource.NewQuantity(50, resource.DecimalSI),
+				},
+				Limits: corev1.ResourceList{
+					extension.ResourceGPU:            *resource.NewQuantity(100, resource.DecimalSI),
+					extension.ResourceGPUMemoryRatio: *resource.NewQuantity(50, resource.DecimalSI),
+				},
+			},
+			expectedResourceRequirements: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					extension.ResourceGPU:            *resource.NewQuantity(100, resource.DecimalSI),
+					extension.ResourceGPUMemoryRatio: *resource.NewQuantity(50, resource.DecimalSI),
+				},
+				Limits: corev1.ResourceList{
+					extension.ResourceGPU:            *resource.NewQuantity(100, resource.DecimalSI),
+					extension.ResourceGPUMemoryRatio: *resource.NewQuantity(50, resource.DecimalSI),
+				},
+			},
+		},
+		{
+			name: "fill gpu limits with requests",
+			resourceRequirements: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					extension.ResourceGPUCore:        *resource.NewQuantity(50, resource.DecimalSI),
+					extension.ResourceGPUMemoryRatio: *resource.NewQuantity(50, resource.DecimalSI),
+				},
+			},
+			expectedResourceRequirements: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					extension.ResourceGPUCore:        *resource.NewQuantity(50, resource.DecimalSI),
+					extension.ResourceGPUMemoryRatio: *resource.NewQuantity(50, resource.DecimalSI),
+					extension.ResourceGPUShared:      *resource.NewQuantity(1, resource.DecimalSI),
+				},
+				Limits: corev1.ResourceList{
+					extension.ResourceGPUCore:        *resource.NewQuantity(50, resource.DecimalSI),
+					extension.ResourceGPUMemoryRatio: *resource.NewQuantity(50, resource.DecimalSI),
+					extension.ResourceGPUShared:      *resource.NewQuantity(1, resource.DecimalSI),
+				},
+			},
+		},
+		{
+			name: "fill gpu requests with limits",
+			resourceRequirements: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU: resource.MustParse("1"),
+				},
+				Limits: corev1.ResourceList{
+					extension.ResourceNvidiaGPU: *resource.NewQuantity(2, resource.DecimalSI),
+				},
+			},
+			expectedResourceRequirements: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:          resource.MustParse("1"),
+					extension.ResourceNvidiaGPU: *resource.NewQuantity(2, resource.DecimalSI),
+				},
+				Limits: corev1.ResourceList{
+					extension.ResourceNvidiaGPU: *resource.NewQuantity(2, resource.DecimalSI),
+				},
+			},
+		},
 	}
 
 	req := newAdmission(admissionv1.Create, runtime.RawExtension{}, runtime.RawExtension{}, "")
@@ -172,3 +236,65 @@ func TestDeviceResourceSpecMutatingPod(t *testing.T) {
 		assert.Equal(pod.Spec.Containers[0].Resources, testCases[i].expectedResourceRequirements)
 	}
 }
+
+func TestFillGPUResourceRequirements(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test-pod"},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{
+				{
+					Name: "init",
+					Resources: corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{
+							extension.ResourceGPUMemoryRatio: *resource.NewQuantity(50, resource.DecimalSI),
+						},
+					},
+				},
+			},
+			Containers: []corev1.Container{
+				{
+					Name: "main",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:          resource.MustParse("1"),
+							extension.ResourceNvidiaGPU: *resource.NewQuantity(1, resource.DecimalSI),
+							// the unequal requests and limits are kept to be rejected by the api server
+							extension.ResourceGPUCore: *resource.NewQuantity(50, resource.DecimalSI),
+						},
+						Limits: corev1.ResourceList{
+							extension.ResourceGPUCore: *resource.NewQuantity(100, resource.DecimalSI),
+						},
+					},
+				},
+			},
+		},
+	}
+	expected := []corev1.ResourceRequirements{
+		{
+			Requests: corev1.ResourceList{
+				extension.ResourceGPUMemoryRatio: *resource.NewQuantity(50, resource.DecimalSI),
+			},
+			Limits: corev1.ResourceList{
+				extension.ResourceGPUMemoryRatio: *resource.NewQuantity(50, resource.DecimalSI),
+			},
+		},
+		{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:          resource.MustParse("1"),
+				extension.ResourceNvidiaGPU: *resource.NewQuantity(1, resource.DecimalSI),
+				extension.ResourceGPUCore:   *resource.NewQuantity(50, resource.DecimalSI),
+			},
+			Limits: corev1.ResourceList{
+				extension.ResourceNvidiaGPU: *resource.NewQuantity(1, resource.DecimalSI),
+				extension.ResourceGPUCore:   *resource.NewQuantity(100, resource.DecimalSI),
+			},
+		},
+	}
+
+	// filling again is a no-op
+	for i := 0; i < 2; i++ {
+		fillGPUResourceRequirements(pod)
+		assert.Equal(t, expected[0], pod.Spec.InitContainers[0].Resources)
+		assert.Equal(t, expected[1], pod.Spec.Containers[0].Resources)
+	}
+}
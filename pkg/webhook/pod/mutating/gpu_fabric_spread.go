@@ -0,0 +1,200 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutating
+
+import (
+	"context"
+	"fmt"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	schedulingv1alpha1 "github.com/koordinator-sh/koordinator/apis/scheduling/v1alpha1"
+	"github.com/koordinator-sh/koordinator/pkg/features"
+	utilfeature "github.com/koordinator-sh/koordinator/pkg/util/feature"
+)
+
+var (
+	// GPUFabricSpreadTopologyKey is the node label key of the domains which the pods are spread across. The pods are
+	// spread across the GPU fabric partitions reported in the Devices and synced to the nodes by default.
+	GPUFabricSpreadTopologyKey = extension.LabelGPUFabricPartition
+	// GPUFabricSpreadMaxSkew is the max skew of the pods of a group between the domains.
+	GPUFabricSpreadMaxSkew = 1
+	// GPUFabricSpreadWhenUnsatisfiable is the policy when the pods cannot be spread, either DoNotSchedule or
+	// ScheduleAnyway. The pods are placed on the nodes with GPU fabric partitions only if DoNotSchedule, and
+	// preferred to them otherwise.
+	GPUFabricSpreadWhenUnsatisfiable = string(corev1.ScheduleAnyway)
+)
+
+// gpuFabricSpreadMutatingPod spreads the created multi-GPU pods annotated with AnnotationGPUFabricSpread across the
+// nodes with GPU fabric partitions, so that a failing fabric does not take down all pods of a training job.
+func (h *PodMutatingHandler) gpuFabricSpreadMutatingPod(ctx context.Context, req admission.Request, pod *corev1.Pod) error {
+	if req.Operation != admissionv1.Create ||
+		!utilfeature.DefaultFeatureGate.Enabled(features.EnableGPUFabricSpreadInjection) {
+		return nil
+	}
+	if pod.Annotations[extension.AnnotationGPUFabricSpread] == "" {
+		return nil
+	}
+	// the gpu-core of a whole GPU is known only if the pod is assigned to a node
+	gpuCoreGranularity := extension.DefaultGPUCoreGranularity
+	if pod.Spec.NodeName != "" {
+		device := &schedulingv1alpha1.Device{}
+		err := h.Client.Get(ctx, types.NamespacedName{Name: pod.Spec.NodeName}, device)
+		if err == nil {
+			gpuCoreGranularity = extension.GetGPUCoreGranularity(device)
+		} else if !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	injectGPUFabricSpread(pod, GPUFabricSpreadTopologyKey, int32(GPUFabricSpreadMaxSkew),
+		corev1.UnsatisfiableConstraintAction(GPUFabricSpreadWhenUnsatisfiable), gpuCoreGranularity)
+	return nil
+}
+
+// validateGPUFabricSpreadFlags returns an error if the gpu fabric spread policy is invalid.
+func validateGPUFabricSpreadFlags() error {
+	if GPUFabricSpreadTopologyKey == "" {
+		return fmt.Errorf("gpu fabric spread topology key must not be empty")
+	}
+	if GPUFabricSpreadMaxSkew < 1 {
+		return fmt.Errorf("invalid gpu fabric spread max skew %d, which must be at least 1", GPUFabricSpreadMaxSkew)
+	}
+	switch corev1.UnsatisfiableConstraintAction(GPUFabricSpreadWhenUnsatisfiable) {
+	case corev1.DoNotSchedule, corev1.ScheduleAnyway:
+		return nil
+	default:
+		return fmt.Errorf("invalid gpu fabric spread policy %q, which must be %s or %s",
+			GPUFabricSpreadWhenUnsatisfiable, corev1.DoNotSchedule, corev1.ScheduleAnyway)
+	}
+}
+
+// injectGPUFabricSpread injects the topology spread constraint selecting the pods of the same group, and the node
+// affinity to the nodes with GPU fabric partitions reported in the Devices. It is idempotent, the constraint and the
+// affinity already injected are not duplicated.
+func injectGPUFabricSpread(pod *corev1.Pod, topologyKey string, maxSkew int32, whenUnsatisfiable corev1.UnsatisfiableConstraintAction,
+	gpuCoreGranularity int64) {
+	groupKey := pod.Annotations[extension.AnnotationGPUFabricSpread]
+	if groupKey == "" || countRequestedGPUs(pod, gpuCoreGranularity) < 2 {
+		return
+	}
+	groupValue, ok := pod.Labels[groupKey]
+	if !ok {
+		klog.V(4).Infof("skip spreading Pod %s/%s across the gpu fabric partitions, which has no group label %s",
+			pod.Namespace, pod.Name, groupKey)
+		return
+	}
+
+	requirement := corev1.NodeSelectorRequirement{
+		Key:      extension.LabelGPUFabricPartitionCount,
+		Operator: corev1.NodeSelectorOpExists,
+	}
+	if whenUnsatisfiable == corev1.DoNotSchedule {
+		addRequiredNodeSelectorRequirement(pod, requirement)
+	} else {
+		addPreferredNodeSelectorRequirement(pod, requirement)
+	}
+
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{groupKey: groupValue}}
+	for _, constraint := range pod.Spec.TopologySpreadConstraints {
+		if constraint.TopologyKey == topologyKey && constraint.LabelSelector != nil &&
+			len(constraint.LabelSelector.MatchLabels) == 1 && constraint.LabelSelector.MatchLabels[groupKey] == groupValue {
+			return
+		}
+	}
+	pod.Spec.TopologySpreadConstraints = append(pod.Spec.TopologySpreadConstraints, corev1.TopologySpreadConstraint{
+		MaxSkew:           maxSkew,
+		TopologyKey:       topologyKey,
+		WhenUnsatisfiable: whenUnsatisfiable,
+		LabelSelector:     selector,
+	})
+	klog.V(4).Infof("mutate Pod %s/%s to spread across the gpu fabric partitions by %s=%s", pod.Namespace, pod.Name, groupKey, groupValue)
+}
+
+// countRequestedGPUs returns the whole GPUs requested by the containers of the pod, where the koordinator.sh/gpu is in
+// percentage of a GPU, and a whole GPU is the gpuCoreGranularity of gpu-core.
+func countRequestedGPUs(pod *corev1.Pod, gpuCoreGranularity int64) int64 {
+	var count int64
+	for i := range pod.Spec.Containers {
+		requests := pod.Spec.Containers[i].Resources.Requests
+		if quantity, ok := requests[extension.ResourceNvidiaGPU]; ok {
+			count += quantity.Value()
+		} else if quantity, ok := requests[extension.ResourceGPU]; ok {
+			count += quantity.Value() / 100
+		} else if quantity, ok := requests[extension.ResourceGPUCore]; ok {
+			count += quantity.Value() / gpuCoreGranularity
+		}
+	}
+	return count
+}
+
+func addRequiredNodeSelectorRequirement(pod *corev1.Pod, requirement corev1.NodeSelectorRequirement) {
+	nodeAffinity := getOrCreateNodeAffinity(pod)
+	required := nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if required == nil {
+		required = &corev1.NodeSelector{}
+		nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = required
+	}
+	if len(required.NodeSelectorTerms) == 0 {
+		required.NodeSelectorTerms = []corev1.NodeSelectorTerm{{}}
+	}
+	for i := range required.NodeSelectorTerms {
+		term := &required.NodeSelectorTerms[i]
+		if !hasNodeSelectorRequirement(term.MatchExpressions, requirement) {
+			term.MatchExpressions = append(term.MatchExpressions, requirement)
+		}
+	}
+}
+
+func addPreferredNodeSelectorRequirement(pod *corev1.Pod, requirement corev1.NodeSelectorRequirement) {
+	nodeAffinity := getOrCreateNodeAffinity(pod)
+	for _, term := range nodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+		if hasNodeSelectorRequirement(term.Preference.MatchExpressions, requirement) {
+			return
+		}
+	}
+	nodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(nodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution,
+		corev1.PreferredSchedulingTerm{
+			Weight:     100,
+			Preference: corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{requirement}},
+		})
+}
+
+func getOrCreateNodeAffinity(pod *corev1.Pod) *corev1.NodeAffinity {
+	if pod.Spec.Affinity == nil {
+		pod.Spec.Affinity = &corev1.Affinity{}
+	}
+	if pod.Spec.Affinity.NodeAffinity == nil {
+		pod.Spec.Affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	return pod.Spec.Affinity.NodeAffinity
+}
+
+func hasNodeSelectorRequirement(requirements []corev1.NodeSelectorRequirement, requirement corev1.NodeSelectorRequirement) bool {
+	for _, r := range requirements {
+		if r.Key == requirement.Key && r.Operator == requirement.Operator {
+			return true
+		}
+	}
+	return false
+}
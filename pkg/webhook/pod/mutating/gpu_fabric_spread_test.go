@@ -0,0 +1,237 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutating
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestInjectGPUFabricSpread(t *testing.T) {
+	makePod := func(annotations, labels map[string]string, gpuCore int64) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "default",
+				Name:        "test-pod",
+				Annotations: annotations,
+				Labels:      labels,
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name: "main",
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{
+								extension.ResourceGPUCore: *resource.NewQuantity(gpuCore, resource.DecimalSI),
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	spreadAnnotations := map[string]string{extension.AnnotationGPUFabricSpread: "job-name"}
+	jobLabels := map[string]string{"job-name": "train"}
+	requirement := corev1.NodeSelectorRequirement{
+		Key:      extension.LabelGPUFabricPartitionCount,
+		Operator: corev1.NodeSelectorOpExists,
+	}
+	expectedConstraint := corev1.TopologySpreadConstraint{
+		MaxSkew:           1,
+		TopologyKey:       extension.LabelGPUFabricPartition,
+		WhenUnsatisfiable: corev1.DoNotSchedule,
+		LabelSelector:     &metav1.LabelSelector{MatchLabels: jobLabels},
+	}
+
+	tests := []struct {
+		name              string
+		pod               *corev1.Pod
+		whenUnsatisfiable corev1.UnsatisfiableConstraintAction
+		wantInjected      bool
+	}{
+		{
+			name:              "pod without annotation",
+			pod:               makePod(nil, jobLabels, 800),
+			whenUnsatisfiable: corev1.DoNotSchedule,
+		},
+		{
+			name:              "pod requesting one gpu",
+			pod:               makePod(spreadAnnotations, jobLabels, 100),
+			whenUnsatisfiable: corev1.DoNotSchedule,
+		},
+		{
+			name:              "pod without group label",
+			pod:               makePod(spreadAnnotations, nil, 800),
+			whenUnsatisfiable: corev1.DoNotSchedule,
+		},
+		{
+			name:              "multi-gpu pod",
+			pod:               makePod(spreadAnnotations, jobLabels, 800),
+			whenUnsatisfiable: corev1.DoNotSchedule,
+			wantInjected:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			injectGPUFabricSpread(tt.pod, extension.LabelGPUFabricPartition, 1, tt.whenUnsatisfiable, extension.DefaultGPUCoreGranularity)
+			if !tt.wantInjected {
+				assert.Nil(t, tt.pod.Spec.TopologySpreadConstraints)
+				assert.Nil(t, tt.pod.Spec.Affinity)
+				return
+			}
+			// the re-admission does not duplicate the constraint and the affinity
+			injectGPUFabricSpread(tt.pod, extension.LabelGPUFabricPartition, 1, tt.whenUnsatisfiable, extension.DefaultGPUCoreGranularity)
+			assert.Equal(t, []corev1.TopologySpreadConstraint{expectedConstraint}, tt.pod.Spec.TopologySpreadConstraints)
+			assert.Equal(t, []corev1.NodeSelectorTerm{{MatchExpressions: []corev1.NodeSelectorRequirement{requirement}}},
+				tt.pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms)
+		})
+	}
+
+	// the nodes with gpu fabric partitions are preferred if the pods can be scheduled anyway
+	pod := makePod(spreadAnnotations, jobLabels, 800)
+	injectGPUFabricSpread(pod, extension.LabelGPUFabricPartition, 1, corev1.ScheduleAnyway, extension.DefaultGPUCoreGranularity)
+	injectGPUFabricSpread(pod, extension.LabelGPUFabricPartition, 1, corev1.ScheduleAnyway, extension.DefaultGPUCoreGranularity)
+	assert.Len(t, pod.Spec.TopologySpreadConstraints, 1)
+	assert.Equal(t, corev1.ScheduleAnyway, pod.Spec.TopologySpreadConstraints[0].WhenUnsatisfiable)
+	assert.Nil(t, pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution)
+	assert.Equal(t, []corev1.PreferredSchedulingTerm{
+		{Weight: 100, Preference: corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{requirement}}},
+	}, pod.Spec.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution)
+}
+
+func TestCountRequestedGPUs(t *testing.T) {
+	makePod := func(requests ...corev1.ResourceList) *corev1.Pod {
+		pod := &corev1.Pod{}
+		for _, r := range requests {
+			pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{
+				Resources: corev1.ResourceRequirements{Requests: r},
+			})
+		}
+		return pod
+	}
+	tests := []struct {
+		name               string
+		pod                *corev1.Pod
+		gpuCoreGranularity int64
+		want               int64
+	}{
+		{
+			name: "nvidia gpu",
+			pod: makePod(corev1.ResourceList{
+				extension.ResourceNvidiaGPU: resource.MustParse("2"),
+			}),
+			gpuCoreGranularity: extension.DefaultGPUCoreGranularity,
+			want:               2,
+		},
+		{
+			name: "koordinator gpu in percentage",
+			pod: makePod(corev1.ResourceList{
+				extension.ResourceGPU: resource.MustParse("200"),
+			}, corev1.ResourceList{
+				extension.ResourceGPU: resource.MustParse("50"),
+			}),
+			gpuCoreGranularity: 1000,
+			want:               2,
+		},
+		{
+			name: "gpu-core of the node granularity",
+			pod: makePod(corev1.ResourceList{
+				extension.ResourceGPUCore:        resource.MustParse("2000"),
+				extension.ResourceGPUMemoryRatio: resource.MustParse("200"),
+			}),
+			gpuCoreGranularity: 1000,
+			want:               2,
+		},
+		{
+			name: "gpu-core of the default granularity",
+			pod: makePod(corev1.ResourceList{
+				extension.ResourceGPUCore:        resource.MustParse("200"),
+				extension.ResourceGPUMemoryRatio: resource.MustParse("200"),
+			}),
+			gpuCoreGranularity: extension.DefaultGPUCoreGranularity,
+			want:               2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, countRequestedGPUs(tt.pod, tt.gpuCoreGranularity))
+		})
+	}
+}
+
+func TestValidateGPUFabricSpreadFlags(t *testing.T) {
+	defer func(topologyKey string, maxSkew int, whenUnsatisfiable string) {
+		GPUFabricSpreadTopologyKey = topologyKey
+		GPUFabricSpreadMaxSkew = maxSkew
+		GPUFabricSpreadWhenUnsatisfiable = whenUnsatisfiable
+	}(GPUFabricSpreadTopologyKey, GPUFabricSpreadMaxSkew, GPUFabricSpreadWhenUnsatisfiable)
+
+	tests := []struct {
+		name              string
+		topologyKey       string
+		maxSkew           int
+		whenUnsatisfiable string
+		wantErr           bool
+	}{
+		{
+			name:              "default",
+			topologyKey:       extension.LabelGPUFabricPartition,
+			maxSkew:           1,
+			whenUnsatisfiable: string(corev1.ScheduleAnyway),
+		},
+		{
+			name:              "do not schedule",
+			topologyKey:       corev1.LabelHostname,
+			maxSkew:           2,
+			whenUnsatisfiable: string(corev1.DoNotSchedule),
+		},
+		{
+			name:              "invalid policy",
+			topologyKey:       extension.LabelGPUFabricPartition,
+			maxSkew:           1,
+			whenUnsatisfiable: "Unknown",
+			wantErr:           true,
+		},
+		{
+			name:              "invalid max skew",
+			topologyKey:       extension.LabelGPUFabricPartition,
+			maxSkew:           0,
+			whenUnsatisfiable: string(corev1.ScheduleAnyway),
+			wantErr:           true,
+		},
+		{
+			name:              "empty topology key",
+			maxSkew:           1,
+			whenUnsatisfiable: string(corev1.ScheduleAnyway),
+			wantErr:           true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			GPUFabricSpreadTopologyKey = tt.topologyKey
+			GPUFabricSpreadMaxSkew = tt.maxSkew
+			GPUFabricSpreadWhenUnsatisfiable = tt.whenUnsatisfiable
+			assert.Equal(t, tt.wantErr, ValidateFlags() != nil)
+		})
+	}
+}
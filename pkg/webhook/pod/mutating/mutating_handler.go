@@ -37,6 +37,7 @@ const (
 	ExtendedResourceSpec     = "ExtendedResourceSpec"
 	MultiQuotaTree           = "MultiQuotaTree"
 	DeviceResourceSpec       = "DeviceResourceSpec"
+	GPUFabricSpread          = "GPUFabricSpread"
 )
 
 // PodMutatingHandler handles Pod
@@ -151,6 +152,17 @@ func (h *PodMutatingHandler) handleCreate(ctx context.Context, req admission.Req
 	metrics.RecordWebhookDurationMilliseconds(metrics.MutatingWebhook,
 		metrics.Pod, string(req.Operation), nil, DeviceResourceSpec, time.Since(start).Seconds())
 
+	// the gpus are counted after the device resources are mutated
+	start = time.Now()
+	if err := h.gpuFabricSpreadMutatingPod(ctx, req, obj); err != nil {
+		klog.Errorf("Failed to mutating Pod %s/%s by GPUFabricSpread, err: %v", obj.Namespace, obj.Name, err)
+		metrics.RecordWebhookDurationMilliseconds(metrics.MutatingWebhook,
+			metrics.Pod, string(req.Operation), err, GPUFabricSpread, time.Since(start).Seconds())
+		return err
+	}
+	metrics.RecordWebhookDurationMilliseconds(metrics.MutatingWebhook,
+		metrics.Pod, string(req.Operation), nil, GPUFabricSpread, time.Since(start).Seconds())
+
 	return nil
 }
 
@@ -17,6 +17,8 @@ limitations under the License.
 package mutating
 
 import (
+	"flag"
+
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
@@ -51,3 +53,18 @@ func (b *podMutateBuilder) Build() admission.Handler {
 		Decoder: admission.NewDecoder(b.mgr.GetScheme()),
 	}
 }
+
+// InitFlags registers the flags of the pod mutating webhook.
+func InitFlags(fs *flag.FlagSet) {
+	fs.StringVar(&GPUFabricSpreadTopologyKey, "gpu-fabric-spread-topology-key", GPUFabricSpreadTopologyKey,
+		"The node label key of the domains which the multi-GPU pods annotated with the gpu fabric spread are spread across.")
+	fs.IntVar(&GPUFabricSpreadMaxSkew, "gpu-fabric-spread-max-skew", GPUFabricSpreadMaxSkew,
+		"The max skew of the multi-GPU pods of a group between the gpu fabric spread domains.")
+	fs.StringVar(&GPUFabricSpreadWhenUnsatisfiable, "gpu-fabric-spread-when-unsatisfiable", GPUFabricSpreadWhenUnsatisfiable,
+		"The policy when the multi-GPU pods cannot be spread, DoNotSchedule or ScheduleAnyway.")
+}
+
+// ValidateFlags returns an error if the flags of the pod mutating webhook are invalid.
+func ValidateFlags() error {
+	return validateGPUFabricSpreadFlags()
+}
@@ -111,7 +111,7 @@ func TestEvalucateQuota(t *testing.T) {
 				corev1.ResourceMemory: resource.MustParse("2Gi"),
 			}).Obj(),
 			wantAllowed: false,
-			wantReason:  "exceeded quota: kube-system/quota1, requested: cpu=2, used: cpu=4, limited: cpu=4",
+			wantReason:  "exceeded quota: kube-system/quota1, requested: cpu=2, used: cpu=4, available: cpu=0, limited: cpu=4, the quota has no guaranteed min and its burstable usage up to the limit is used up, please retry later after the used resources are released or raise the quota",
 			wantErr:     true,
 			wantUsed:    corev1.ResourceList{},
 		},
@@ -131,7 +131,7 @@ func TestEvalucateQuota(t *testing.T) {
 				corev1.ResourceMemory: resource.MustParse("8Gi"),
 			}).Obj(),
 			wantAllowed: false,
-			wantReason:  "exceeded quota: kube-system/quota1, requested: cpu=2,memory=4Gi, used: cpu=4,memory=8Gi, limited: cpu=4,memory=8Gi",
+			wantReason:  "exceeded quota: kube-system/quota1, requested: cpu=2,memory=4Gi, used: cpu=4,memory=8Gi, available: cpu=0,memory=0, limited: cpu=4,memory=8Gi, the quota has no guaranteed min and its burstable usage up to the limit is used up, please retry later after the used resources are released or raise the quota",
 			wantErr:     true,
 			wantUsed:    corev1.ResourceList{},
 		},
@@ -246,7 +246,7 @@ func TestEvalucateQuota(t *testing.T) {
 				extension.BatchMemory: resource.MustParse("8Gi"),
 			}).Obj(),
 			wantAllowed: false,
-			wantReason:  "exceeded quota: kube-system/quota1, requested: kubernetes.io/batch-cpu=1,kubernetes.io/batch-memory=2Gi, used: kubernetes.io/batch-cpu=4,kubernetes.io/batch-memory=8Gi, limited: kubernetes.io/batch-cpu=4,kubernetes.io/batch-memory=8Gi",
+			wantReason:  "exceeded quota: kube-system/quota1, requested: kubernetes.io/batch-cpu=1,kubernetes.io/batch-memory=2Gi, used: kubernetes.io/batch-cpu=4,kubernetes.io/batch-memory=8Gi, available: kubernetes.io/batch-cpu=0,kubernetes.io/batch-memory=0, limited: kubernetes.io/batch-cpu=4,kubernetes.io/batch-memory=8Gi, the quota has no guaranteed min and its burstable usage up to the limit is used up, please retry later after the used resources are released or raise the quota",
 			wantErr:     true,
 			wantUsed:    corev1.ResourceList{},
 		},
@@ -263,7 +263,7 @@ func TestEvalucateQuota(t *testing.T) {
 				corev1.ResourceMemory: resource.MustParse("2Gi"),
 			}).Obj(),
 			wantAllowed: false,
-			wantReason:  "exceeded quota: kube-system/quota1, requested: cpu=2,memory=4Gi, used: , limited: cpu=1,memory=2Gi",
+			wantReason:  "exceeded quota: kube-system/quota1, requested: cpu=2,memory=4Gi, used: , available: cpu=1,memory=2Gi, limited: cpu=1,memory=2Gi, the request is larger than the whole limit of the quota and cannot be admitted by retrying, please raise the quota",
 			wantErr:     true,
 			wantUsed:    corev1.ResourceList{},
 		},
@@ -280,7 +280,7 @@ func TestEvalucateQuota(t *testing.T) {
 				corev1.ResourceMemory: resource.MustParse("2Gi"),
 			}).Admission(corev1.ResourceList{}).Obj(),
 			wantAllowed: false,
-			wantReason:  "exceeded quota: kube-system/quota1, requested: cpu=2,memory=4Gi, used: , limited: cpu=1,memory=2Gi",
+			wantReason:  "exceeded quota: kube-system/quota1, requested: cpu=2,memory=4Gi, used: , available: cpu=1,memory=2Gi, limited: cpu=1,memory=2Gi, the request is larger than the whole limit of the quota and cannot be admitted by retrying, please raise the quota",
 			wantErr:     true,
 			wantUsed:    corev1.ResourceList{},
 		},
@@ -300,7 +300,7 @@ func TestEvalucateQuota(t *testing.T) {
 				corev1.ResourceMemory: resource.MustParse("0Gi"),
 			}).Obj(),
 			wantAllowed: false,
-			wantReason:  "exceeded quota: kube-system/quota1, requested: cpu=2,memory=4Gi, used: , limited: cpu=0,memory=0",
+			wantReason:  "exceeded quota: kube-system/quota1, requested: cpu=2,memory=4Gi, used: , available: cpu=0,memory=0, limited: cpu=0,memory=0, the request is larger than the whole limit of the quota and cannot be admitted by retrying, please raise the quota",
 			wantErr:     true,
 			wantUsed:    corev1.ResourceList{},
 		},
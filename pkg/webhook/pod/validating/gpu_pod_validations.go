@@ -0,0 +1,260 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	schedulingv1alpha1 "github.com/koordinator-sh/koordinator/apis/scheduling/v1alpha1"
+	"github.com/koordinator-sh/koordinator/pkg/features"
+	utilfeature "github.com/koordinator-sh/koordinator/pkg/util/feature"
+)
+
+// The names of the GPU pod validations, which are enabled by GPUPodValidations.
+const (
+	GPUNodeDeviceValidation          = "NodeDevice"
+	GPUMemoryRatioCapacityValidation = "MemoryRatioCapacity"
+	GPUAllocationAlignmentValidation = "AllocationAlignment"
+	GPUCoreMemoryRatioValidation     = "CoreMemoryRatio"
+	GPUExclusiveValidation           = "Exclusive"
+	GPUNUMAPolicyValidation          = "NUMAPolicy"
+	GPUMemoryLimitValidation         = "MemoryLimit"
+	GPUInitContainerValidation       = "InitContainer"
+	GPUPartitioningValidation        = "Partitioning"
+	GPUResourceLimitsValidation      = "ResourceLimits"
+	GPUCompanionResourcesValidation  = "CompanionResources"
+	GPUCardCapacityValidation        = "CardCapacity"
+	GPUCountValidation               = "Count"
+	GPUCapacityWarning               = "CapacityWarning"
+	GPURuntimeClassValidation        = "RuntimeClass"
+	GPURequiredLabelsValidation      = "RequiredLabels"
+	GPUNamespaceQuotaValidation      = "NamespaceQuota"
+	GPUNodeSelectorValidation        = "NodeSelector"
+	GPUAnnotationsValidation         = "Annotations"
+)
+
+// GPUPodValidations are the GPU pod validations enabled when the feature gate EnableGPUPodValidation is enabled.
+// "*" enables all validations, and "-Name" disables the validation Name.
+var GPUPodValidations = []string{"*"}
+
+// AllGPUPodValidations are the names of all the GPU pod validations.
+var AllGPUPodValidations = []string{
+	GPUNodeDeviceValidation,
+	GPUMemoryRatioCapacityValidation,
+	GPUAllocationAlignmentValidation,
+	GPUCoreMemoryRatioValidation,
+	GPUExclusiveValidation,
+	GPUNUMAPolicyValidation,
+	GPUMemoryLimitValidation,
+	GPUInitContainerValidation,
+	GPUPartitioningValidation,
+	GPUResourceLimitsValidation,
+	GPUCompanionResourcesValidation,
+	GPUCardCapacityValidation,
+	GPUCountValidation,
+	GPUCapacityWarning,
+	GPURuntimeClassValidation,
+	GPURequiredLabelsValidation,
+	GPUNamespaceQuotaValidation,
+	GPUNodeSelectorValidation,
+	GPUAnnotationsValidation,
+}
+
+// isGPUPodValidationEnabled returns whether the GPU pod validation is enabled.
+func isGPUPodValidationEnabled(name string) bool {
+	if !utilfeature.DefaultFeatureGate.Enabled(features.EnableGPUPodValidation) {
+		return false
+	}
+	hasStar := false
+	for _, v := range GPUPodValidations {
+		if v == name {
+			return true
+		}
+		if v == "-"+name {
+			return false
+		}
+		if v == "*" {
+			hasStar = true
+		}
+	}
+	return hasStar
+}
+
+// deviceLister lists all the Devices at most once per admission request, which are shared by the validations and
+// the warnings of the request.
+type deviceLister struct {
+	client  client.Client
+	listed  bool
+	devices []schedulingv1alpha1.Device
+}
+
+func newDeviceLister(c client.Client) *deviceLister {
+	return &deviceLister{client: c}
+}
+
+func (l *deviceLister) list(ctx context.Context) ([]schedulingv1alpha1.Device, error) {
+	if l.listed {
+		return l.devices, nil
+	}
+	deviceList := &schedulingv1alpha1.DeviceList{}
+	if err := l.client.List(ctx, deviceList); err != nil {
+		return nil, err
+	}
+	l.listed = true
+	l.devices = deviceList.Items
+	return l.devices, nil
+}
+
+// gpuPodValidationContext holds the Devices shared by the GPU pod validations of an admission request.
+type gpuPodValidationContext struct {
+	// device is the Device of the node which the pod is assigned to, nil if not assigned or not reported
+	device  *schedulingv1alpha1.Device
+	devices *deviceLister
+}
+
+// gpuPodValidation validates the created pods, which is run only if its name is enabled in GPUPodValidations.
+type gpuPodValidation struct {
+	name     string
+	validate func(ctx context.Context, pod *corev1.Pod, c *gpuPodValidationContext) (field.ErrorList, error)
+}
+
+// gpuPodValidations are run in order until a validation fails.
+var gpuPodValidations = []gpuPodValidation{
+	{
+		name: GPUAnnotationsValidation,
+		validate: func(ctx context.Context, pod *corev1.Pod, c *gpuPodValidationContext) (field.ErrorList, error) {
+			return validateGPUAnnotations(pod), nil
+		},
+	},
+	{
+		name: GPUNodeDeviceValidation,
+		validate: func(ctx context.Context, pod *corev1.Pod, c *gpuPodValidationContext) (field.ErrorList, error) {
+			return validateNodeDevice(pod, c.device), nil
+		},
+	},
+	{
+		name: GPUMemoryRatioCapacityValidation,
+		validate: func(ctx context.Context, pod *corev1.Pod, c *gpuPodValidationContext) (field.ErrorList, error) {
+			return validateGPUMemoryRatioCapacity(pod, c.device, GPUMemoryRatioOversubscriptionFactor), nil
+		},
+	},
+	{
+		name: GPUAllocationAlignmentValidation,
+		validate: func(ctx context.Context, pod *corev1.Pod, c *gpuPodValidationContext) (field.ErrorList, error) {
+			return validateGPUAllocationAlignment(pod, GPUAllocationGranularity), nil
+		},
+	},
+	{
+		name: GPUCoreMemoryRatioValidation,
+		validate: func(ctx context.Context, pod *corev1.Pod, c *gpuPodValidationContext) (field.ErrorList, error) {
+			return validateGPUCoreMemoryRatio(pod, GPUMemoryRatioMaxCoreFactor), nil
+		},
+	},
+	{
+		name: GPUExclusiveValidation,
+		validate: func(ctx context.Context, pod *corev1.Pod, c *gpuPodValidationContext) (field.ErrorList, error) {
+			return validateGPUExclusive(pod, extension.GetGPUCoreGranularity(c.device)), nil
+		},
+	},
+	{
+		name: GPUNUMAPolicyValidation,
+		validate: func(ctx context.Context, pod *corev1.Pod, c *gpuPodValidationContext) (field.ErrorList, error) {
+			return validateGPUNUMAPolicy(pod, c.device), nil
+		},
+	},
+	{
+		name: GPUMemoryLimitValidation,
+		validate: func(ctx context.Context, pod *corev1.Pod, c *gpuPodValidationContext) (field.ErrorList, error) {
+			return validateGPUMemoryLimits(pod), nil
+		},
+	},
+	{
+		name: GPUInitContainerValidation,
+		validate: func(ctx context.Context, pod *corev1.Pod, c *gpuPodValidationContext) (field.ErrorList, error) {
+			return validateGPUInitContainers(pod), nil
+		},
+	},
+	{
+		name: GPUPartitioningValidation,
+		validate: func(ctx context.Context, pod *corev1.Pod, c *gpuPodValidationContext) (field.ErrorList, error) {
+			return validateGPUPartitioning(pod), nil
+		},
+	},
+	{
+		name: GPUResourceLimitsValidation,
+		validate: func(ctx context.Context, pod *corev1.Pod, c *gpuPodValidationContext) (field.ErrorList, error) {
+			return validateGPUResourceLimits(pod), nil
+		},
+	},
+	{
+		name: GPUCompanionResourcesValidation,
+		validate: func(ctx context.Context, pod *corev1.Pod, c *gpuPodValidationContext) (field.ErrorList, error) {
+			return validateGPUCompanionResources(pod, GPUPodMinCPURequest.Quantity, GPUPodMinMemoryRequest.Quantity), nil
+		},
+	},
+	{
+		name: GPUCardCapacityValidation,
+		validate: func(ctx context.Context, pod *corev1.Pod, c *gpuPodValidationContext) (field.ErrorList, error) {
+			if !requestsGPU(pod) {
+				return nil, nil
+			}
+			devices, err := c.devices.list(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return validateGPUCardCapacity(pod, devices), nil
+		},
+	},
+	{
+		name: GPUCountValidation,
+		validate: func(ctx context.Context, pod *corev1.Pod, c *gpuPodValidationContext) (field.ErrorList, error) {
+			return validateNodeGPUCount(pod, c.device), nil
+		},
+	},
+	{
+		name: GPUNodeSelectorValidation,
+		validate: func(ctx context.Context, pod *corev1.Pod, c *gpuPodValidationContext) (field.ErrorList, error) {
+			if !GPUNodeSelectorDenial || pod.Spec.NodeName != "" || !requestsGPU(pod) {
+				return nil, nil
+			}
+			devices, err := c.devices.list(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return validateGPUNodeSelector(ctx, c.devices.client, pod, devices)
+		},
+	},
+}
+
+// validateGPUPod runs the enabled GPU pod validations in order, and returns the errors of the first failed one.
+func validateGPUPod(ctx context.Context, pod *corev1.Pod, c *gpuPodValidationContext) (field.ErrorList, error) {
+	for _, v := range gpuPodValidations {
+		if !isGPUPodValidationEnabled(v.name) {
+			continue
+		}
+		allErrs, err := v.validate(ctx, pod, c)
+		if err != nil || len(allErrs) > 0 {
+			return allErrs, err
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"context"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/pkg/features"
+	utilfeature "github.com/koordinator-sh/koordinator/pkg/util/feature"
+)
+
+// setGPUPodValidationsDuringTest enables the feature gate EnableGPUPodValidation and only the given validations,
+// and returns the function restoring them.
+func setGPUPodValidationsDuringTest(t *testing.T, validations ...string) func() {
+	restoreGate := utilfeature.SetFeatureGateDuringTest(t, utilfeature.DefaultMutableFeatureGate, features.EnableGPUPodValidation, true)
+	old := GPUPodValidations
+	GPUPodValidations = validations
+	return func() {
+		GPUPodValidations = old
+		restoreGate()
+	}
+}
+
+func TestIsGPUPodValidationEnabled(t *testing.T) {
+	tests := []struct {
+		name        string
+		gate        bool
+		validations []string
+		want        map[string]bool
+	}{
+		{
+			name:        "feature gate disabled",
+			gate:        false,
+			validations: []string{"*"},
+			want:        map[string]bool{GPUNodeDeviceValidation: false, GPUMemoryLimitValidation: false},
+		},
+		{
+			name:        "all enabled",
+			gate:        true,
+			validations: []string{"*"},
+			want:        map[string]bool{GPUNodeDeviceValidation: true, GPUMemoryLimitValidation: true},
+		},
+		{
+			name:        "all enabled except one",
+			gate:        true,
+			validations: []string{"*", "-" + GPUMemoryLimitValidation},
+			want:        map[string]bool{GPUNodeDeviceValidation: true, GPUMemoryLimitValidation: false},
+		},
+		{
+			name:        "only one enabled",
+			gate:        true,
+			validations: []string{GPUNodeDeviceValidation},
+			want:        map[string]bool{GPUNodeDeviceValidation: true, GPUMemoryLimitValidation: false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer utilfeature.SetFeatureGateDuringTest(t, utilfeature.DefaultMutableFeatureGate, features.EnableGPUPodValidation, tt.gate)()
+			old := GPUPodValidations
+			defer func() {
+				GPUPodValidations = old
+			}()
+			GPUPodValidations = tt.validations
+			for name, want := range tt.want {
+				assert.Equal(t, want, isGPUPodValidationEnabled(name), name)
+			}
+		})
+	}
+}
+
+func TestGPUPodValidationsFlag(t *testing.T) {
+	old := GPUPodValidations
+	defer func() { GPUPodValidations = old }()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	InitFlags(fs)
+	assert.Equal(t, "*", fs.Lookup("gpu-pod-validations").DefValue)
+	assert.NoError(t, fs.Parse([]string{"--gpu-pod-validations=*, -MemoryLimit"}))
+	assert.Equal(t, []string{"*", "-MemoryLimit"}, GPUPodValidations)
+}
+
+func TestValidateGPUPod(t *testing.T) {
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{
+				{
+					Name: "init",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{extension.ResourceGPUCore: resource.MustParse("50")},
+					},
+				},
+			},
+			Containers: []corev1.Container{
+				{
+					Name: "test",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{extension.ResourceGPUCore: resource.MustParse("50")},
+					},
+				},
+			},
+		},
+	}
+	t.Run("failed validations disabled", func(t *testing.T) {
+		defer setGPUPodValidationsDuringTest(t, GPUPartitioningValidation, "-"+GPUInitContainerValidation)()
+		allErrs, err := validateGPUPod(context.TODO(), pod, &gpuPodValidationContext{devices: newDeviceLister(fake.NewClientBuilder().Build())})
+		assert.NoError(t, err)
+		assert.Empty(t, allErrs)
+	})
+	t.Run("first failed validation returned", func(t *testing.T) {
+		defer setGPUPodValidationsDuringTest(t, GPUInitContainerValidation, GPUResourceLimitsValidation)()
+		allErrs, err := validateGPUPod(context.TODO(), pod, &gpuPodValidationContext{devices: newDeviceLister(fake.NewClientBuilder().Build())})
+		assert.NoError(t, err)
+		assert.Equal(t, validateGPUInitContainers(pod), allErrs)
+	})
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var (
+	// PodDecisionCacheTTL is how long the allowed decision of the colocation and quota meta checks is reused for the
+	// identical pods.
+	PodDecisionCacheTTL = 5 * time.Second
+	// podDecisionCacheMaxEntries bounds the memory of the cache, the entries are dropped once it is full
+	podDecisionCacheMaxEntries = 4096
+)
+
+var defaultPodDecisionCache = newPodDecisionCache()
+
+// podDecisionCache caches the allowed decisions of the checks which only depend on the pod fields and the quota
+// topology, so that the identical pods created in a burst are not walked through them repeatedly. The decisions are
+// invalidated once the quota topology changes. The rejections are not cached.
+type podDecisionCache struct {
+	lock    sync.Mutex
+	entries map[string]podDecision
+}
+
+type podDecision struct {
+	// quotaGeneration is the generation of the quota topology which the decision is made with
+	quotaGeneration uint64
+	expireAt        time.Time
+}
+
+func newPodDecisionCache() *podDecisionCache {
+	return &podDecisionCache{entries: map[string]podDecision{}}
+}
+
+// allowed returns whether the pod of the key is allowed with the same quota topology and not expired.
+func (c *podDecisionCache) allowed(key string, quotaGeneration uint64, now time.Time) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	decision, ok := c.entries[key]
+	if !ok {
+		return false
+	}
+	if decision.quotaGeneration != quotaGeneration || !now.Before(decision.expireAt) {
+		delete(c.entries, key)
+		return false
+	}
+	return true
+}
+
+// recordAllowed records the pod of the key is allowed with the quota topology of the generation.
+func (c *podDecisionCache) recordAllowed(key string, quotaGeneration uint64, now time.Time, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(c.entries) >= podDecisionCacheMaxEntries {
+		for k, decision := range c.entries {
+			if !now.Before(decision.expireAt) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= podDecisionCacheMaxEntries {
+			c.entries = map[string]podDecision{}
+		}
+	}
+	c.entries[key] = podDecision{quotaGeneration: quotaGeneration, expireAt: now.Add(ttl)}
+}
+
+// podDecisionFields are the pod fields validated by the cached checks.
+type podDecisionFields struct {
+	Namespace         string                        `json:"namespace"`
+	Owner             types.UID                     `json:"owner,omitempty"`
+	Labels            map[string]string             `json:"labels,omitempty"`
+	PriorityClassName string                        `json:"priorityClassName,omitempty"`
+	Priority          *int32                        `json:"priority,omitempty"`
+	InitContainers    []corev1.ResourceRequirements `json:"initContainers,omitempty"`
+	Containers        []corev1.ResourceRequirements `json:"containers,omitempty"`
+	Overhead          corev1.ResourceList           `json:"overhead,omitempty"`
+}
+
+// podDecisionKey returns the key of the pod in the podDecisionCache, the pods with the same key get the same
+// decision from the cached checks.
+func podDecisionKey(pod *corev1.Pod) (string, error) {
+	fields := podDecisionFields{
+		Namespace:         pod.Namespace,
+		Labels:            pod.Labels,
+		PriorityClassName: pod.Spec.PriorityClassName,
+		Priority:          pod.Spec.Priority,
+		Overhead:          pod.Spec.Overhead,
+	}
+	if owner := metav1.GetControllerOf(pod); owner != nil {
+		fields.Owner = owner.UID
+	}
+	for i := range pod.Spec.InitContainers {
+		fields.InitContainers = append(fields.InitContainers, pod.Spec.InitContainers[i].Resources)
+	}
+	for i := range pod.Spec.Containers {
+		fields.Containers = append(fields.Containers, pod.Spec.Containers[i].Resources)
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestPodDecisionCache(t *testing.T) {
+	c := newPodDecisionCache()
+	now := time.Now()
+	assert.False(t, c.allowed("a", 1, now))
+
+	c.recordAllowed("a", 1, now, time.Second)
+	assert.True(t, c.allowed("a", 1, now.Add(500*time.Millisecond)))
+	// expired
+	assert.False(t, c.allowed("a", 1, now.Add(time.Second)))
+
+	// invalidated by the quota topology changes
+	c.recordAllowed("a", 1, now, time.Second)
+	assert.False(t, c.allowed("a", 2, now))
+	assert.False(t, c.allowed("a", 1, now))
+
+	// disabled
+	c.recordAllowed("b", 1, now, 0)
+	assert.False(t, c.allowed("b", 1, now))
+}
+
+func TestPodDecisionCacheFull(t *testing.T) {
+	oldMaxEntries := podDecisionCacheMaxEntries
+	defer func() {
+		podDecisionCacheMaxEntries = oldMaxEntries
+	}()
+	podDecisionCacheMaxEntries = 2
+
+	c := newPodDecisionCache()
+	now := time.Now()
+	c.recordAllowed("expired", 1, now.Add(-time.Minute), time.Second)
+	c.recordAllowed("a", 1, now, time.Second)
+	// the expired entries are dropped first
+	c.recordAllowed("b", 1, now, time.Second)
+	assert.True(t, c.allowed("a", 1, now))
+	assert.True(t, c.allowed("b", 1, now))
+	// all entries are dropped if none is expired
+	c.recordAllowed("c", 1, now, time.Second)
+	assert.False(t, c.allowed("a", 1, now))
+	assert.True(t, c.allowed("c", 1, now))
+	assert.Equal(t, 1, len(c.entries))
+}
+
+func TestPodDecisionKey(t *testing.T) {
+	newPod := func(name string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      name,
+				Labels: map[string]string{
+					extension.LabelPodQoS: string(extension.QoSLSR),
+					"pod-template-hash":   "5d8f9c",
+				},
+				OwnerReferences: []metav1.OwnerReference{
+					{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "test", UID: "rs-uid", Controller: pointer.Bool(true)},
+				},
+			},
+			Spec: corev1.PodSpec{
+				Priority: pointer.Int32(9000),
+				Containers: []corev1.Container{
+					{
+						Name: "main",
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+						},
+					},
+				},
+			},
+		}
+	}
+	key, err := podDecisionKey(newPod("test-1"))
+	assert.NoError(t, err)
+	tests := []struct {
+		name     string
+		mutate   func(pod *corev1.Pod)
+		wantSame bool
+	}{
+		{
+			name:     "replica of the same owner",
+			mutate:   func(pod *corev1.Pod) {},
+			wantSame: true,
+		},
+		{
+			name: "different annotations",
+			mutate: func(pod *corev1.Pod) {
+				pod.Annotations = map[string]string{"foo": "bar"}
+			},
+			wantSame: true,
+		},
+		{
+			name: "different namespace",
+			mutate: func(pod *corev1.Pod) {
+				pod.Namespace = "other"
+			},
+		},
+		{
+			name: "different owner",
+			mutate: func(pod *corev1.Pod) {
+				pod.OwnerReferences[0].UID = "other-rs-uid"
+			},
+		},
+		{
+			name: "different labels",
+			mutate: func(pod *corev1.Pod) {
+				pod.Labels[extension.LabelQuotaName] = "quota-a"
+			},
+		},
+		{
+			name: "different priority",
+			mutate: func(pod *corev1.Pod) {
+				pod.Spec.Priority = pointer.Int32(5000)
+			},
+		},
+		{
+			name: "different requests",
+			mutate: func(pod *corev1.Pod) {
+				pod.Spec.Containers[0].Resources.Requests[corev1.ResourceCPU] = resource.MustParse("1500m")
+			},
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := newPod(fmt.Sprintf("test-%d", i+2))
+			tt.mutate(pod)
+			got, err := podDecisionKey(pod)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantSame, got == key)
+		})
+	}
+}
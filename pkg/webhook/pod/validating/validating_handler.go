@@ -27,6 +27,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	"github.com/koordinator-sh/koordinator/pkg/features"
+	utilfeature "github.com/koordinator-sh/koordinator/pkg/util/feature"
 	"github.com/koordinator-sh/koordinator/pkg/webhook/elasticquota"
 	"github.com/koordinator-sh/koordinator/pkg/webhook/metrics"
 	"github.com/koordinator-sh/koordinator/pkg/webhook/quotaevaluate"
@@ -37,6 +39,10 @@ const (
 	ClusterColocationProfile = "ClusterColocationProfile"
 	EvaluateQuota            = "EvaluateQuota"
 	DeviceResource           = "DeviceResource"
+	ValidationRules          = "ValidationRules"
+	GPURuntimeClass          = "GPURuntimeClass"
+	GPURequiredLabels        = "GPURequiredLabels"
+	NamespaceGPUQuota        = "NamespaceGPUQuota"
 )
 
 // PodValidatingHandler handles Pod
@@ -48,6 +54,9 @@ type PodValidatingHandler struct {
 
 	// QuotaEvaluator evaluate pod quota usage
 	QuotaEvaluator quotaevaluate.Evaluator
+
+	// ValidationRules holds the additional rules loaded from the ConfigMap, nil if disabled
+	ValidationRules *PodValidationRuleStore
 }
 
 var _ admission.Handler = &PodValidatingHandler{}
@@ -61,7 +70,7 @@ func shouldIgnoreIfNotPod(req admission.Request) bool {
 	return false
 }
 
-func (h *PodValidatingHandler) validatingPodFn(ctx context.Context, req admission.Request) (allowed bool, reason string, err error) {
+func (h *PodValidatingHandler) validatingPodFn(ctx context.Context, req admission.Request, devices *deviceLister) (allowed bool, reason string, err error) {
 	allowed = true
 	if shouldIgnoreIfNotPod(req) {
 		return
@@ -83,37 +92,85 @@ func (h *PodValidatingHandler) validatingPodFn(ctx context.Context, req admissio
 		return false, reason, err
 	}
 
+	// the colocation and quota meta checks are skipped for the identical pods allowed recently
+	plugin := elasticquota.NewPlugin(h.Decoder, h.Client)
+	var decisionKey string
+	quotaGeneration := plugin.QuotaTopologyGeneration()
+	if req.Operation == admissionv1.Create && utilfeature.DefaultFeatureGate.Enabled(features.EnablePodValidationDecisionCache) {
+		key, keyErr := podDecisionKey(pod)
+		if keyErr != nil {
+			klog.V(4).Infof("failed to get the decision key of pod %s/%s, err: %v", pod.Namespace, pod.Name, keyErr)
+		} else {
+			decisionKey = key
+		}
+	}
+	if decisionKey == "" || !defaultPodDecisionCache.allowed(decisionKey, quotaGeneration, time.Now()) {
+		start = time.Now()
+		_, reason, err = h.clusterColocationProfileValidatingPod(ctx, req)
+		metrics.RecordWebhookDurationMilliseconds(metrics.ValidatingWebhook,
+			metrics.Pod, string(req.Operation), err, ClusterColocationProfile, time.Since(start).Seconds())
+		if err != nil {
+			return false, reason, err
+		}
+
+		start = time.Now()
+		if err = plugin.ValidatePod(ctx, req); err != nil {
+			metrics.RecordWebhookDurationMilliseconds(metrics.ValidatingWebhook,
+				metrics.Pod, string(req.Operation), err, plugin.Name(), time.Since(start).Seconds())
+			return false, "", err
+		}
+		metrics.RecordWebhookDurationMilliseconds(metrics.ValidatingWebhook,
+			metrics.Pod, string(req.Operation), nil, plugin.Name(), time.Since(start).Seconds())
+		if decisionKey != "" {
+			defaultPodDecisionCache.recordAllowed(decisionKey, quotaGeneration, time.Now(), PodDecisionCacheTTL)
+		}
+	}
+
 	start = time.Now()
-	_, reason, err = h.clusterColocationProfileValidatingPod(ctx, req)
+	_, reason, err = h.evaluateQuota(ctx, req)
 	metrics.RecordWebhookDurationMilliseconds(metrics.ValidatingWebhook,
-		metrics.Pod, string(req.Operation), err, ClusterColocationProfile, time.Since(start).Seconds())
+		metrics.Pod, string(req.Operation), err, EvaluateQuota, time.Since(start).Seconds())
+
 	if err != nil {
 		return false, reason, err
 	}
 
 	start = time.Now()
-	plugin := elasticquota.NewPlugin(h.Decoder, h.Client)
-	if err = plugin.ValidatePod(ctx, req); err != nil {
-		metrics.RecordWebhookDurationMilliseconds(metrics.ValidatingWebhook,
-			metrics.Pod, string(req.Operation), err, plugin.Name(), time.Since(start).Seconds())
-		return false, "", err
+	allowed, reason, err = h.deviceResourceValidatingPod(ctx, req, devices)
+	metrics.RecordWebhookDurationMilliseconds(metrics.ValidatingWebhook,
+		metrics.Pod, string(req.Operation), err, DeviceResource, time.Since(start).Seconds())
+	if err != nil {
+		return false, reason, err
 	}
+
+	start = time.Now()
+	allowed, reason, err = h.validationRulesValidatingPod(ctx, req)
 	metrics.RecordWebhookDurationMilliseconds(metrics.ValidatingWebhook,
-		metrics.Pod, string(req.Operation), nil, plugin.Name(), time.Since(start).Seconds())
+		metrics.Pod, string(req.Operation), err, ValidationRules, time.Since(start).Seconds())
+	if err != nil {
+		return false, reason, err
+	}
 
 	start = time.Now()
-	_, reason, err = h.evaluateQuota(ctx, req)
+	allowed, reason, err = h.gpuRuntimeClassValidatingPod(ctx, req)
 	metrics.RecordWebhookDurationMilliseconds(metrics.ValidatingWebhook,
-		metrics.Pod, string(req.Operation), err, EvaluateQuota, time.Since(start).Seconds())
+		metrics.Pod, string(req.Operation), err, GPURuntimeClass, time.Since(start).Seconds())
+	if err != nil {
+		return false, reason, err
+	}
 
+	start = time.Now()
+	allowed, reason, err = h.gpuRequiredLabelsValidatingPod(ctx, req)
+	metrics.RecordWebhookDurationMilliseconds(metrics.ValidatingWebhook,
+		metrics.Pod, string(req.Operation), err, GPURequiredLabels, time.Since(start).Seconds())
 	if err != nil {
 		return false, reason, err
 	}
 
 	start = time.Now()
-	allowed, reason, err = h.deviceResourceValidatingPod(ctx, req)
+	allowed, reason, err = h.namespaceGPUQuotaValidatingPod(ctx, req)
 	metrics.RecordWebhookDurationMilliseconds(metrics.ValidatingWebhook,
-		metrics.Pod, string(req.Operation), err, DeviceResource, time.Since(start).Seconds())
+		metrics.Pod, string(req.Operation), err, NamespaceGPUQuota, time.Since(start).Seconds())
 	if err != nil {
 		return false, reason, err
 	}
@@ -125,11 +182,19 @@ var _ admission.Handler = &PodValidatingHandler{}
 
 // Handle handles admission requests.
 func (h *PodValidatingHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
-	allowed, reason, err := h.validatingPodFn(ctx, req)
+	// the Devices are listed at most once for the validations and the warnings
+	devices := newDeviceLister(h.Client)
+	allowed, reason, err := h.validatingPodFn(ctx, req, devices)
 	if err != nil {
 		return admission.Errored(http.StatusBadRequest, err)
 	}
-	return admission.ValidationResponse(allowed, reason)
+	resp := admission.ValidationResponse(allowed, reason)
+	if allowed {
+		if warnings := h.deviceResourceWarnings(ctx, req, devices); len(warnings) > 0 {
+			resp = resp.WithWarnings(warnings...)
+		}
+	}
+	return resp
 }
 
 // var _ inject.Client = &PodValidatingHandler{}
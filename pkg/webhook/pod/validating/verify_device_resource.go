@@ -18,17 +18,27 @@ package validating
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/validation/field"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	"k8s.io/klog/v2"
+	apiresource "k8s.io/kubernetes/pkg/api/v1/resource"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	"github.com/koordinator-sh/koordinator/apis/extension"
+	schedulingv1alpha1 "github.com/koordinator-sh/koordinator/apis/scheduling/v1alpha1"
 )
 
-func (h *PodValidatingHandler) deviceResourceValidatingPod(ctx context.Context, req admission.Request) (bool, string, error) {
+// +kubebuilder:rbac:groups=scheduling.koordinator.sh,resources=devices,verbs=get;list;watch
+
+func (h *PodValidatingHandler) deviceResourceValidatingPod(ctx context.Context, req admission.Request, devices *deviceLister) (bool, string, error) {
 	newPod := &corev1.Pod{}
 	var allErrs field.ErrorList
 	switch req.Operation {
@@ -47,7 +57,24 @@ func (h *PodValidatingHandler) deviceResourceValidatingPod(ctx context.Context,
 
 	}
 
-	allErrs = append(allErrs, validateDeviceResource(newPod)...)
+	// the pod assigned to a node is validated with the gpu-core granularity of the node
+	var device *schedulingv1alpha1.Device
+	if newPod.Spec.NodeName != "" && requestsGPU(newPod) {
+		var err error
+		device, err = h.getNodeDevice(ctx, newPod.Spec.NodeName)
+		if err != nil {
+			return false, "", err
+		}
+	}
+
+	allErrs = append(allErrs, validateDeviceResource(newPod, extension.GetGPUCoreGranularity(device))...)
+	if req.Operation == admissionv1.Create && len(allErrs) == 0 {
+		errs, err := validateGPUPod(ctx, newPod, &gpuPodValidationContext{device: device, devices: devices})
+		if err != nil {
+			return false, "", err
+		}
+		allErrs = append(allErrs, errs...)
+	}
 	err := allErrs.ToAggregate()
 	allowed := true
 	reason := ""
@@ -59,7 +86,9 @@ func (h *PodValidatingHandler) deviceResourceValidatingPod(ctx context.Context,
 	return allowed, reason, err
 }
 
-func validateDeviceResource(pod *corev1.Pod) field.ErrorList {
+// validateDeviceResource validates the device resources requested by the pod, where gpuCoreGranularity is
+// the gpu-core quantity of a whole GPU.
+func validateDeviceResource(pod *corev1.Pod, gpuCoreGranularity int64) field.ErrorList {
 	allErrs := field.ErrorList{}
 
 	for i := range pod.Spec.Containers {
@@ -68,6 +97,14 @@ func validateDeviceResource(pod *corev1.Pod) field.ErrorList {
 		// use gpu api first
 		_, gpuExist := container.Resources.Requests[extension.ResourceGPU]
 		_, gpuShareExist := container.Resources.Requests[extension.ResourceGPUShared]
+		if gpuExist {
+			if fractional := fractionalGPURequests(container); len(fractional) > 0 {
+				allErrs = append(allErrs, field.Forbidden(field.NewPath("pod.spec.containers[*].resources.requests"),
+					fmt.Sprintf("container %s requests whole GPUs by %s and fractional GPUs by %s at same time, request either of them",
+						container.Name, extension.ResourceGPU, strings.Join(fractional, ", "))))
+				continue
+			}
+		}
 		if gpuExist && gpuShareExist {
 			allErrs = append(allErrs, field.Forbidden(field.NewPath("pod.spec.containers[*].resources.requests"), "Forbidden declare GPU and GPU share at same time"))
 			continue
@@ -75,7 +112,7 @@ func validateDeviceResource(pod *corev1.Pod) field.ErrorList {
 
 		// gpu resource will no longer exist
 		if gpuExist {
-			allErrs = append(allErrs, validateGPU(container)...)
+			allErrs = append(allErrs, validateGPU(container, gpuCoreGranularity)...)
 		}
 
 		if gpuShareExist {
@@ -86,8 +123,186 @@ func validateDeviceResource(pod *corev1.Pod) field.ErrorList {
 	return allErrs
 }
 
-func validatePercentageResource(q resource.Quantity) bool {
-	if q.Value() > 100 && q.Value()%100 != 0 {
+// fractionalGPUResourceNames are the resources requesting a fraction of GPUs, which are ambiguous to be requested
+// with the whole GPUs by koordinator.sh/gpu.
+var fractionalGPUResourceNames = []corev1.ResourceName{
+	extension.ResourceGPUCore,
+	extension.ResourceGPUMemory,
+	extension.ResourceGPUMemoryRatio,
+}
+
+// fractionalGPURequests returns the names of the fractional GPU resources requested by the container.
+func fractionalGPURequests(c *corev1.Container) []string {
+	var names []string
+	for _, name := range fractionalGPUResourceNames {
+		if _, ok := c.Resources.Requests[name]; ok {
+			names = append(names, string(name))
+		}
+	}
+	return names
+}
+
+// getNodeDevice returns the Device of the node, or nil if the Device is not found.
+func (h *PodValidatingHandler) getNodeDevice(ctx context.Context, nodeName string) (*schedulingv1alpha1.Device, error) {
+	device := &schedulingv1alpha1.Device{}
+	if err := h.Client.Get(ctx, types.NamespacedName{Name: nodeName}, device); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return device, nil
+}
+
+// validateNodeDevice rejects the GPU pod which is directly assigned to a node without healthy GPUs reported,
+// e.g. the koordlet has not reported the Device yet. The scheduled pods are filtered by the scheduler.
+func validateNodeDevice(pod *corev1.Pod, device *schedulingv1alpha1.Device) field.ErrorList {
+	if pod.Spec.NodeName == "" || !requestsGPU(pod) {
+		return nil
+	}
+
+	allErrs := field.ErrorList{}
+	fldPath := field.NewPath("pod.spec.nodeName")
+	if device == nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath, fmt.Sprintf("the Device of node %s is not reported yet", pod.Spec.NodeName)))
+		return allErrs
+	}
+
+	for _, d := range device.Spec.Devices {
+		if d.Type == schedulingv1alpha1.GPU && extension.IsDeviceSchedulable(&d) {
+			return allErrs
+		}
+	}
+	allErrs = append(allErrs, field.Forbidden(fldPath, fmt.Sprintf("node %s has no healthy GPU", pod.Spec.NodeName)))
+	return allErrs
+}
+
+// deviceResourceWarnings returns the admission warnings if the GPU requests of the pod cannot be satisfied by the
+// healthy GPUs of any Device, the GPUs requested exceed the GPUs of any Device, or the node selector of the pod
+// matches no node with healthy GPUs. It is best-effort and never denies the pod, since the GPUs may be available
+// later. The Devices listed by the validations are reused.
+func (h *PodValidatingHandler) deviceResourceWarnings(ctx context.Context, req admission.Request, devices *deviceLister) []string {
+	capacityWarning := isGPUPodValidationEnabled(GPUCapacityWarning)
+	countWarning := isGPUPodValidationEnabled(GPUCountValidation)
+	nodeSelectorWarning := isGPUPodValidationEnabled(GPUNodeSelectorValidation) && !GPUNodeSelectorDenial
+	if req.Operation != admissionv1.Create || shouldIgnoreIfNotPod(req) || (!capacityWarning && !countWarning && !nodeSelectorWarning) {
+		return nil
+	}
+	pod := &corev1.Pod{}
+	if err := h.Decoder.DecodeRaw(req.Object, pod); err != nil {
+		return nil
+	}
+	// the pod assigned to a node is validated with the Device of the node
+	if pod.Spec.NodeName != "" || !requestsGPU(pod) {
+		return nil
+	}
+
+	deviceItems, err := devices.list(ctx)
+	if err != nil {
+		klog.V(4).Infof("failed to list Devices to check the GPU capacity for pod %s/%s, err: %v", pod.Namespace, pod.Name, err)
+		return nil
+	}
+	var warnings []string
+	if countWarning {
+		warnings = append(warnings, gpuCountWarnings(pod, deviceItems)...)
+	}
+	if capacityWarning {
+		requests := podGPURequests(pod)
+		fits := false
+		for i := range deviceItems {
+			if fitsHealthyGPUs(requests, &deviceItems[i]) {
+				fits = true
+				break
+			}
+		}
+		if !fits {
+			warnings = append(warnings, fmt.Sprintf("no node has enough healthy GPUs for the requests %s, the pod may stay pending", printGPURequests(requests)))
+		}
+	}
+	if nodeSelectorWarning {
+		nodeSelectorWarnings, err := gpuNodeSelectorWarnings(ctx, h.Client, pod, deviceItems)
+		if err != nil {
+			klog.V(4).Infof("failed to check the node selector for pod %s/%s, err: %v", pod.Namespace, pod.Name, err)
+		}
+		warnings = append(warnings, nodeSelectorWarnings...)
+	}
+	return warnings
+}
+
+var gpuCapacityResourceNames = []corev1.ResourceName{
+	extension.ResourceGPUCore,
+	extension.ResourceGPUMemory,
+	extension.ResourceGPUMemoryRatio,
+}
+
+// podGPURequests returns the GPU resources requested by the pod, the koordinator.sh/gpu is requested as both the
+// gpu-core and the gpu-memory-ratio like the mutating webhook does.
+func podGPURequests(pod *corev1.Pod) corev1.ResourceList {
+	podRequests := apiresource.PodRequests(pod, apiresource.PodResourcesOptions{})
+	requests := corev1.ResourceList{}
+	for _, name := range gpuCapacityResourceNames {
+		if q, ok := podRequests[name]; ok && !q.IsZero() {
+			requests[name] = q.DeepCopy()
+		}
+	}
+	if q, ok := podRequests[extension.ResourceGPU]; ok && !q.IsZero() {
+		for _, name := range []corev1.ResourceName{extension.ResourceGPUCore, extension.ResourceGPUMemoryRatio} {
+			total := requests[name]
+			total.Add(q)
+			requests[name] = total
+		}
+	}
+	return requests
+}
+
+// fitsHealthyGPUs returns whether the total healthy GPU resources of the Device is not less than the requests.
+func fitsHealthyGPUs(requests corev1.ResourceList, device *schedulingv1alpha1.Device) bool {
+	total := corev1.ResourceList{}
+	for _, d := range device.Spec.Devices {
+		if d.Type != schedulingv1alpha1.GPU || !extension.IsDeviceSchedulable(&d) {
+			continue
+		}
+		total = quotav1.Add(total, d.Resources)
+	}
+	for name, q := range requests {
+		if capacity, ok := total[name]; !ok || capacity.Cmp(q) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func printGPURequests(requests corev1.ResourceList) string {
+	var items []string
+	for _, name := range gpuCapacityResourceNames {
+		if q, ok := requests[name]; ok {
+			items = append(items, fmt.Sprintf("%s=%s", name, q.String()))
+		}
+	}
+	return strings.Join(items, ",")
+}
+
+func requestsGPU(pod *corev1.Pod) bool {
+	gpuResourceNames := []corev1.ResourceName{
+		extension.ResourceGPU,
+		extension.ResourceGPUShared,
+		extension.ResourceGPUCore,
+		extension.ResourceGPUMemory,
+		extension.ResourceGPUMemoryRatio,
+	}
+	containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+	for i := range containers {
+		for _, name := range gpuResourceNames {
+			if q, ok := containers[i].Resources.Requests[name]; ok && !q.IsZero() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func validatePercentageResource(q resource.Quantity, granularity int64) bool {
+	if q.Value() > granularity && q.Value()%granularity != 0 {
 		return false
 	}
 
@@ -118,7 +333,7 @@ func validateLess100(q resource.Quantity) bool {
 	return true
 }
 
-func validateGPU(c *corev1.Container) field.ErrorList {
+func validateGPU(c *corev1.Container, granularity int64) field.ErrorList {
 	allErrs := field.ErrorList{}
 	gpuQuantity := c.Resources.Requests[extension.ResourceGPU]
 
@@ -126,8 +341,8 @@ func validateGPU(c *corev1.Container) field.ErrorList {
 		allErrs = append(allErrs, field.Invalid(field.NewPath("pod.spec.containers[*].resources.requests"), gpuQuantity.String(), "the requested GPU must be greater than zero"))
 	}
 
-	if !validatePercentageResource(gpuQuantity) {
-		allErrs = append(allErrs, field.Invalid(field.NewPath("pod.spec.containers[*].resources.requests"), gpuQuantity.String(), "the requested GPU must be percentage of 100"))
+	if !validatePercentageResource(gpuQuantity, granularity) {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("pod.spec.containers[*].resources.requests"), gpuQuantity.String(), fmt.Sprintf("the requested GPU must be percentage of %d", granularity)))
 	}
 
 	return allErrs
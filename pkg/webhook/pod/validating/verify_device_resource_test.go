@@ -18,23 +18,30 @@ package validating
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	configv1alpha1 "github.com/koordinator-sh/koordinator/apis/config/v1alpha1"
 	"github.com/koordinator-sh/koordinator/apis/extension"
+	schedulingv1alpha1 "github.com/koordinator-sh/koordinator/apis/scheduling/v1alpha1"
 	"github.com/koordinator-sh/koordinator/pkg/util"
 )
 
 func init() {
 	_ = configv1alpha1.AddToScheme(scheme.Scheme)
+	_ = schedulingv1alpha1.AddToScheme(scheme.Scheme)
 }
 
 func TestDeviceResourceValidatingPod(t *testing.T) {
@@ -211,6 +218,34 @@ func TestDeviceResourceValidatingPod(t *testing.T) {
 			wantAllowed: false,
 			wantReason:  "pod.spec.containers[*].resources.requests: Invalid value: \"101\": the requested gpuMemoryRatio must multiple of shared",
 		},
+		{
+			name:      "validate gpu and gpu memory ratio at same time",
+			operation: admissionv1.Create,
+			newPod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "test-container-a",
+							Resources: corev1.ResourceRequirements{
+								Limits: corev1.ResourceList{
+									extension.ResourceGPU:            *resource.NewQuantity(100, resource.DecimalSI),
+									extension.ResourceGPUMemoryRatio: *resource.NewQuantity(50, resource.DecimalSI),
+								},
+								Requests: corev1.ResourceList{
+									extension.ResourceGPU:            *resource.NewQuantity(100, resource.DecimalSI),
+									extension.ResourceGPUMemoryRatio: *resource.NewQuantity(50, resource.DecimalSI),
+								},
+							},
+						},
+					},
+					SchedulerName:     "koordinator-scheduler",
+					PriorityClassName: "koordinator-batch",
+				},
+			},
+			wantErr:     true,
+			wantAllowed: false,
+			wantReason:  "pod.spec.containers[*].resources.requests: Forbidden: container test-container-a requests whole GPUs by koordinator.sh/gpu and fractional GPUs by koordinator.sh/gpu-memory-ratio at same time, request either of them",
+		},
 	}
 
 	for _, tt := range tests {
@@ -235,7 +270,7 @@ func TestDeviceResourceValidatingPod(t *testing.T) {
 			}
 
 			req := newAdmissionRequest(tt.operation, objRawExt, oldObjRawExt, "pods")
-			gotAllowed, gotReason, err := h.deviceResourceValidatingPod(context.TODO(), admission.Request{AdmissionRequest: req})
+			gotAllowed, gotReason, err := h.deviceResourceValidatingPod(context.TODO(), admission.Request{AdmissionRequest: req}, newDeviceLister(h.Client))
 			if (err != nil) != tt.wantErr {
 				t.Errorf("clusterReservationValidatingPod() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -252,3 +287,282 @@ func TestDeviceResourceValidatingPod(t *testing.T) {
 		})
 	}
 }
+
+func TestDeviceResourceValidatingPodOnNode(t *testing.T) {
+	defer setGPUPodValidationsDuringTest(t, GPUNodeDeviceValidation)()
+
+	gpuPodWithQuantity := func(nodeName string, quantity int64) *corev1.Pod {
+		return &corev1.Pod{
+			Spec: corev1.PodSpec{
+				NodeName: nodeName,
+				Containers: []corev1.Container{
+					{
+						Name: "test-container-a",
+						Resources: corev1.ResourceRequirements{
+							Limits: corev1.ResourceList{
+								extension.ResourceGPU: *resource.NewQuantity(quantity, resource.DecimalSI),
+							},
+							Requests: corev1.ResourceList{
+								extension.ResourceGPU: *resource.NewQuantity(quantity, resource.DecimalSI),
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	gpuPod := func(nodeName string) *corev1.Pod {
+		return gpuPodWithQuantity(nodeName, 100)
+	}
+	gpuDevice := func(nodeName string, health bool) *schedulingv1alpha1.Device {
+		return &schedulingv1alpha1.Device{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+			Spec: schedulingv1alpha1.DeviceSpec{
+				Devices: []schedulingv1alpha1.DeviceInfo{
+					{UUID: "1", Type: schedulingv1alpha1.GPU, Health: health},
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name        string
+		pod         *corev1.Pod
+		device      *schedulingv1alpha1.Device
+		wantAllowed bool
+		wantReason  string
+	}{
+		{
+			name:        "pod not assigned to node",
+			pod:         gpuPod(""),
+			wantAllowed: true,
+		},
+		{
+			name:        "node has healthy gpu",
+			pod:         gpuPod("test-node"),
+			device:      gpuDevice("test-node", true),
+			wantAllowed: true,
+		},
+		{
+			name:        "node has no Device",
+			pod:         gpuPod("test-node"),
+			wantAllowed: false,
+			wantReason:  "pod.spec.nodeName: Forbidden: the Device of node test-node is not reported yet",
+		},
+		{
+			name:        "node has no healthy gpu",
+			pod:         gpuPod("test-node"),
+			device:      gpuDevice("test-node", false),
+			wantAllowed: false,
+			wantReason:  "pod.spec.nodeName: Forbidden: node test-node has no healthy GPU",
+		},
+		{
+			name: "pod requests whole gpus of the node granularity",
+			pod:  gpuPodWithQuantity("test-node", 2000),
+			device: func() *schedulingv1alpha1.Device {
+				device := gpuDevice("test-node", true)
+				device.Labels = map[string]string{extension.LabelGPUCoreGranularity: "1000"}
+				return device
+			}(),
+			wantAllowed: true,
+		},
+		{
+			name: "pod requests partial gpus of the node granularity",
+			pod:  gpuPodWithQuantity("test-node", 1500),
+			device: func() *schedulingv1alpha1.Device {
+				device := gpuDevice("test-node", true)
+				device.Labels = map[string]string{extension.LabelGPUCoreGranularity: "1000"}
+				return device
+			}(),
+			wantAllowed: false,
+			wantReason:  "pod.spec.containers[*].resources.requests: Invalid value: \"1500\": the requested GPU must be percentage of 1000",
+		},
+		{
+			name: "pod requests no gpu",
+			pod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					NodeName:   "test-node",
+					Containers: []corev1.Container{{Name: "test-container-a"}},
+				},
+			},
+			wantAllowed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := fake.NewClientBuilder()
+			if tt.device != nil {
+				builder = builder.WithObjects(tt.device)
+			}
+			h := &PodValidatingHandler{
+				Client:  builder.Build(),
+				Decoder: admission.NewDecoder(scheme.Scheme),
+			}
+			objRawExt := runtime.RawExtension{
+				Raw: []byte(util.DumpJSON(tt.pod)),
+			}
+			req := newAdmissionRequest(admissionv1.Create, objRawExt, runtime.RawExtension{}, "pods")
+			gotAllowed, gotReason, _ := h.deviceResourceValidatingPod(context.TODO(), admission.Request{AdmissionRequest: req}, newDeviceLister(h.Client))
+			assert.Equal(t, tt.wantAllowed, gotAllowed)
+			assert.Equal(t, tt.wantReason, gotReason)
+		})
+	}
+}
+
+func TestDeviceResourceWarnings(t *testing.T) {
+	defer setGPUPodValidationsDuringTest(t, GPUCapacityWarning)()
+
+	gpuPod := func(nodeName string, gpuCore, gpuMemoryRatio int64) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test-pod"},
+			Spec: corev1.PodSpec{
+				NodeName: nodeName,
+				Containers: []corev1.Container{
+					{
+						Name: "test-container-a",
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{
+								extension.ResourceGPUCore:        *resource.NewQuantity(gpuCore, resource.DecimalSI),
+								extension.ResourceGPUMemoryRatio: *resource.NewQuantity(gpuMemoryRatio, resource.DecimalSI),
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	gpuDevice := func(nodeName string, health ...bool) *schedulingv1alpha1.Device {
+		device := &schedulingv1alpha1.Device{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+		}
+		for i, h := range health {
+			device.Spec.Devices = append(device.Spec.Devices, schedulingv1alpha1.DeviceInfo{
+				UUID:   fmt.Sprintf("%s-%d", nodeName, i),
+				Minor:  pointer.Int32(int32(i)),
+				Type:   schedulingv1alpha1.GPU,
+				Health: h,
+				Resources: corev1.ResourceList{
+					extension.ResourceGPUCore:        *resource.NewQuantity(100, resource.DecimalSI),
+					extension.ResourceGPUMemoryRatio: *resource.NewQuantity(100, resource.DecimalSI),
+					extension.ResourceGPUMemory:      resource.MustParse("16Gi"),
+				},
+			})
+		}
+		return device
+	}
+	wantWarning := "no node has enough healthy GPUs for the requests koordinator.sh/gpu-core=200,koordinator.sh/gpu-memory-ratio=200, the pod may stay pending"
+	tests := []struct {
+		name    string
+		pod     *corev1.Pod
+		devices []*schedulingv1alpha1.Device
+		want    []string
+	}{
+		{
+			name:    "a node has enough healthy gpus",
+			pod:     gpuPod("", 200, 200),
+			devices: []*schedulingv1alpha1.Device{gpuDevice("node-1", true), gpuDevice("node-2", true, true)},
+		},
+		{
+			name:    "no node has enough healthy gpus",
+			pod:     gpuPod("", 200, 200),
+			devices: []*schedulingv1alpha1.Device{gpuDevice("node-1", true), gpuDevice("node-2", true, false)},
+			want:    []string{wantWarning},
+		},
+		{
+			name: "no Device reported",
+			pod:  gpuPod("", 200, 200),
+			want: []string{wantWarning},
+		},
+		{
+			name: "pod assigned to node is not warned",
+			pod:  gpuPod("node-1", 200, 200),
+		},
+		{
+			name: "pod requests no gpu",
+			pod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "test-container-a"}},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := fake.NewClientBuilder()
+			for _, device := range tt.devices {
+				builder = builder.WithObjects(device)
+			}
+			h := &PodValidatingHandler{
+				Client:  builder.Build(),
+				Decoder: admission.NewDecoder(scheme.Scheme),
+			}
+			objRawExt := runtime.RawExtension{
+				Raw: []byte(util.DumpJSON(tt.pod)),
+			}
+			req := newAdmissionRequest(admissionv1.Create, objRawExt, runtime.RawExtension{}, "")
+			got := h.deviceResourceWarnings(context.TODO(), admission.Request{AdmissionRequest: req}, newDeviceLister(h.Client))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+type countingListClient struct {
+	client.Client
+	lists int
+}
+
+func (c *countingListClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	c.lists++
+	return c.Client.List(ctx, list, opts...)
+}
+
+func TestDeviceResourceListDevicesOnce(t *testing.T) {
+	defer setGPUPodValidationsDuringTest(t, GPUCardCapacityValidation, GPUCapacityWarning)()
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test-pod"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name: "test-container-a",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							extension.ResourceGPUCore:        resource.MustParse("100"),
+							extension.ResourceGPUMemoryRatio: resource.MustParse("100"),
+						},
+					},
+				},
+			},
+		},
+	}
+	device := &schedulingv1alpha1.Device{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Spec: schedulingv1alpha1.DeviceSpec{
+			Devices: []schedulingv1alpha1.DeviceInfo{
+				{
+					UUID:   "node-1-0",
+					Minor:  pointer.Int32(0),
+					Type:   schedulingv1alpha1.GPU,
+					Health: true,
+					Resources: corev1.ResourceList{
+						extension.ResourceGPUCore:        resource.MustParse("100"),
+						extension.ResourceGPUMemoryRatio: resource.MustParse("100"),
+						extension.ResourceGPUMemory:      resource.MustParse("16Gi"),
+					},
+				},
+			},
+		},
+	}
+	c := &countingListClient{Client: fake.NewClientBuilder().WithObjects(device).Build()}
+	h := &PodValidatingHandler{
+		Client:  c,
+		Decoder: admission.NewDecoder(scheme.Scheme),
+	}
+	req := admission.Request{AdmissionRequest: newAdmissionRequest(admissionv1.Create,
+		runtime.RawExtension{Raw: []byte(util.DumpJSON(pod))}, runtime.RawExtension{}, "")}
+	devices := newDeviceLister(h.Client)
+	allowed, _, err := h.deviceResourceValidatingPod(context.TODO(), req, devices)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Empty(t, h.deviceResourceWarnings(context.TODO(), req, devices))
+	assert.Equal(t, 1, c.lists)
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+// GPUAllocationGranularity is the granularity of the gpu-core and gpu-memory-ratio allocated by the device plugin on
+// a GPU, e.g. 25 if the GPUs are allocated by quarters. The validation is disabled if it is not greater than 1.
+var GPUAllocationGranularity int64 = 0
+
+// gpuAlignedResourceNames are the GPU resources allocated in the granularity.
+var gpuAlignedResourceNames = []corev1.ResourceName{
+	extension.ResourceGPU,
+	extension.ResourceGPUCore,
+	extension.ResourceGPUMemoryRatio,
+}
+
+// validateGPUAllocationAlignment rejects the containers requesting the GPU resources not aligned to the granularity,
+// which can never be satisfied by the device plugin. The requests declared by koordinator.sh/gpu-shared are validated
+// per GPU.
+func validateGPUAllocationAlignment(pod *corev1.Pod, granularity int64) field.ErrorList {
+	if granularity <= 1 {
+		return nil
+	}
+	allErrs := field.ErrorList{}
+	for i := range pod.Spec.Containers {
+		container := &pod.Spec.Containers[i]
+		shared := int64(1)
+		if q, ok := container.Resources.Requests[extension.ResourceGPUShared]; ok && q.Value() > 1 {
+			shared = q.Value()
+		}
+		for _, name := range gpuAlignedResourceNames {
+			q, ok := container.Resources.Requests[name]
+			if !ok || q.Value() <= 0 {
+				continue
+			}
+			// the requests not divisible by the shared GPUs are rejected by validateGPUShare
+			perGPU := q.Value()
+			if perGPU%shared == 0 {
+				perGPU /= shared
+			}
+			if perGPU%granularity == 0 {
+				continue
+			}
+			fldPath := field.NewPath("pod.spec.containers").Index(i).Child("resources", "requests").Key(string(name))
+			allErrs = append(allErrs, field.Invalid(fldPath, q.String(),
+				fmt.Sprintf("container %s requests %s %d per GPU, which is not aligned to the allocation granularity %d, the nearest valid request is %d",
+					container.Name, name, perGPU, granularity, nearestAlignedValue(perGPU, granularity)*shared)))
+		}
+	}
+	return allErrs
+}
+
+// nearestAlignedValue returns the multiple of the granularity nearest to the value, which is at least the granularity.
+func nearestAlignedValue(value, granularity int64) int64 {
+	aligned := (value + granularity/2) / granularity * granularity
+	if aligned < granularity {
+		return granularity
+	}
+	return aligned
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestValidateGPUAllocationAlignment(t *testing.T) {
+	tests := []struct {
+		name        string
+		requests    corev1.ResourceList
+		granularity int64
+		wantReasons []string
+	}{
+		{
+			name: "validation disabled",
+			requests: corev1.ResourceList{
+				extension.ResourceGPUCore:        resource.MustParse("30"),
+				extension.ResourceGPUMemoryRatio: resource.MustParse("30"),
+			},
+			granularity: 1,
+		},
+		{
+			name: "aligned requests",
+			requests: corev1.ResourceList{
+				extension.ResourceGPUCore:        resource.MustParse("25"),
+				extension.ResourceGPUMemoryRatio: resource.MustParse("75"),
+			},
+			granularity: 25,
+		},
+		{
+			name: "aligned whole GPUs",
+			requests: corev1.ResourceList{
+				extension.ResourceGPU: resource.MustParse("200"),
+			},
+			granularity: 25,
+		},
+		{
+			name: "unaligned requests",
+			requests: corev1.ResourceList{
+				extension.ResourceGPUCore:        resource.MustParse("30"),
+				extension.ResourceGPUMemoryRatio: resource.MustParse("40"),
+			},
+			granularity: 25,
+			wantReasons: []string{
+				"container test requests koordinator.sh/gpu-core 30 per GPU, which is not aligned to the allocation granularity 25, the nearest valid request is 25",
+				"container test requests koordinator.sh/gpu-memory-ratio 40 per GPU, which is not aligned to the allocation granularity 25, the nearest valid request is 50",
+			},
+		},
+		{
+			name: "unaligned request less than the granularity",
+			requests: corev1.ResourceList{
+				extension.ResourceGPU: resource.MustParse("10"),
+			},
+			granularity: 25,
+			wantReasons: []string{
+				"container test requests koordinator.sh/gpu 10 per GPU, which is not aligned to the allocation granularity 25, the nearest valid request is 25",
+			},
+		},
+		{
+			name: "aligned shared GPUs",
+			requests: corev1.ResourceList{
+				extension.ResourceGPUShared:      resource.MustParse("2"),
+				extension.ResourceGPUCore:        resource.MustParse("100"),
+				extension.ResourceGPUMemoryRatio: resource.MustParse("50"),
+			},
+			granularity: 25,
+		},
+		{
+			name: "unaligned shared GPUs",
+			requests: corev1.ResourceList{
+				extension.ResourceGPUShared:      resource.MustParse("2"),
+				extension.ResourceGPUMemoryRatio: resource.MustParse("60"),
+			},
+			granularity: 25,
+			wantReasons: []string{
+				"container test requests koordinator.sh/gpu-memory-ratio 30 per GPU, which is not aligned to the allocation granularity 25, the nearest valid request is 50",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:      "test",
+							Resources: corev1.ResourceRequirements{Requests: tt.requests},
+						},
+					},
+				},
+			}
+			errs := validateGPUAllocationAlignment(pod, tt.granularity)
+			var reasons []string
+			for _, err := range errs {
+				reasons = append(reasons, err.Detail)
+			}
+			assert.Equal(t, tt.wantReasons, reasons)
+		})
+	}
+}
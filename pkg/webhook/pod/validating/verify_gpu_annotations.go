@@ -0,0 +1,167 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	schedulingv1alpha1 "github.com/koordinator-sh/koordinator/apis/scheduling/v1alpha1"
+)
+
+// gpuAnnotationSchemas validate the values of the pod annotations used by the GPU scheduling, which returns why the
+// value is malformed.
+var gpuAnnotationSchemas = map[string]func(value string) string{
+	extension.AnnotationDeviceAllocated:     validateDeviceAllocatedAnnotation,
+	extension.AnnotationDeviceAllocateHint:  validateDeviceAllocateHintAnnotation,
+	extension.AnnotationDeviceJointAllocate: validateDeviceJointAllocateAnnotation,
+	extension.AnnotationGPUPartitionSpec:    validateGPUPartitionSpecAnnotation,
+	extension.AnnotationGPUExclusive: func(value string) string {
+		if _, err := strconv.ParseBool(value); err != nil {
+			return "must be true or false"
+		}
+		return ""
+	},
+	extension.AnnotationGPUFabricSpread: func(value string) string {
+		if errs := validation.IsQualifiedName(value); len(errs) > 0 {
+			return fmt.Sprintf("must be a label key, %s", strings.Join(errs, "; "))
+		}
+		return ""
+	},
+}
+
+// validateGPUAnnotations rejects the pod with the malformed GPU annotations, or the unknown annotations looking like
+// the GPU annotations of koordinator, i.e. under scheduling.koordinator.sh and naming a gpu or device, which are
+// mostly typos silently ignored by the scheduler.
+func validateGPUAnnotations(pod *corev1.Pod) field.ErrorList {
+	allErrs := field.ErrorList{}
+	keys := make([]string, 0, len(pod.Annotations))
+	for key := range pod.Annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fldPath := field.NewPath("metadata", "annotations").Key(key)
+		schema, ok := gpuAnnotationSchemas[key]
+		if !ok {
+			if isGPUAnnotationKey(key) {
+				allErrs = append(allErrs, field.NotSupported(fldPath, key, sets.List(sets.KeySet(gpuAnnotationSchemas))))
+			}
+			continue
+		}
+		if reason := schema(pod.Annotations[key]); reason != "" {
+			allErrs = append(allErrs, field.Invalid(fldPath, pod.Annotations[key], reason))
+		}
+	}
+	return allErrs
+}
+
+// isGPUAnnotationKey returns whether the annotation key is in the domain of the GPU annotations of the pod.
+func isGPUAnnotationKey(key string) bool {
+	name := strings.TrimPrefix(key, extension.SchedulingDomainPrefix+"/")
+	if name == key {
+		return false
+	}
+	return strings.Contains(name, "gpu") || strings.Contains(name, "device")
+}
+
+// strictUnmarshal decodes the json value into obj, and rejects the unknown fields which are mostly typos.
+func strictUnmarshal(value string, obj interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(value)))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(obj)
+}
+
+func validateDeviceAllocatedAnnotation(value string) string {
+	allocations := extension.DeviceAllocations{}
+	if err := strictUnmarshal(value, &allocations); err != nil {
+		return fmt.Sprintf("must be the json of the device allocations, %v", err)
+	}
+	return ""
+}
+
+func validateDeviceAllocateHintAnnotation(value string) string {
+	hints := extension.DeviceAllocateHints{}
+	if err := strictUnmarshal(value, &hints); err != nil {
+		return fmt.Sprintf("must be the json of the device allocate hints, %v", err)
+	}
+	deviceTypes := make([]schedulingv1alpha1.DeviceType, 0, len(hints))
+	for deviceType := range hints {
+		deviceTypes = append(deviceTypes, deviceType)
+	}
+	sort.Slice(deviceTypes, func(i, j int) bool { return deviceTypes[i] < deviceTypes[j] })
+	for _, deviceType := range deviceTypes {
+		hint := hints[deviceType]
+		if hint == nil {
+			continue
+		}
+		if reason := validateEnum("allocateStrategy", string(hint.AllocateStrategy),
+			string(extension.ApplyForAllDeviceAllocateStrategy), string(extension.RequestsAsCountAllocateStrategy)); reason != "" {
+			return fmt.Sprintf("the hint of %s: %s", deviceType, reason)
+		}
+		if hint.RequiredTopologyScope != "" {
+			if _, ok := extension.DeviceTopologyScopeLevel[hint.RequiredTopologyScope]; !ok {
+				return fmt.Sprintf("the hint of %s: unsupported requiredTopologyScope %q", deviceType, hint.RequiredTopologyScope)
+			}
+		}
+		if reason := validateEnum("exclusivePolicy", string(hint.ExclusivePolicy),
+			string(extension.DeviceLevelDeviceExclusivePolicy), string(extension.PCIExpressLevelDeviceExclusivePolicy)); reason != "" {
+			return fmt.Sprintf("the hint of %s: %s", deviceType, reason)
+		}
+	}
+	return ""
+}
+
+func validateDeviceJointAllocateAnnotation(value string) string {
+	jointAllocate := extension.DeviceJointAllocate{}
+	if err := strictUnmarshal(value, &jointAllocate); err != nil {
+		return fmt.Sprintf("must be the json of the device joint allocate, %v", err)
+	}
+	return validateEnum("requiredScope", string(jointAllocate.RequiredScope), string(extension.SamePCIeDeviceJointAllocateScope))
+}
+
+func validateGPUPartitionSpecAnnotation(value string) string {
+	spec := extension.GPUPartitionSpec{}
+	if err := strictUnmarshal(value, &spec); err != nil {
+		return fmt.Sprintf("must be the json of the gpu partition spec, %v", err)
+	}
+	return validateEnum("allocatePolicy", string(spec.AllocatePolicy),
+		string(extension.GPUPartitionAllocatePolicyRestricted), string(extension.GPUPartitionAllocatePolicyBestEffort))
+}
+
+// validateEnum returns why the optional field is not one of the supported values, or empty if it is.
+func validateEnum(name, value string, supported ...string) string {
+	if value == "" {
+		return ""
+	}
+	for _, v := range supported {
+		if v == value {
+			return ""
+		}
+	}
+	return fmt.Sprintf("unsupported %s %q, supported values: %s", name, value, strings.Join(supported, ", "))
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestValidateGPUAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantReasons []string
+	}{
+		{
+			name: "no annotations",
+		},
+		{
+			name: "valid annotations",
+			annotations: map[string]string{
+				extension.AnnotationDeviceAllocateHint:  `{"gpu":{"allocateStrategy":"ApplyForAll","requiredTopologyScope":"PCIe","exclusivePolicy":"DeviceLevel"}}`,
+				extension.AnnotationDeviceJointAllocate: `{"deviceTypes":["gpu","rdma"],"requiredScope":"SamePCIe"}`,
+				extension.AnnotationGPUPartitionSpec:    `{"allocatePolicy":"Restricted"}`,
+				extension.AnnotationGPUExclusive:        "true",
+				extension.AnnotationGPUFabricSpread:     "job-name",
+				extension.AnnotationDeviceAllocated:     `{"gpu":[{"minor":0,"resources":{"koordinator.sh/gpu-core":"100"}}]}`,
+				"scheduling.koordinator.sh/other":       "ignored",
+				"example.com/gpu-type":                  "ignored",
+			},
+		},
+		{
+			name:        "unknown gpu annotation",
+			annotations: map[string]string{"scheduling.koordinator.sh/gpu-exlusive": "true"},
+			wantReasons: []string{`Unsupported value: "scheduling.koordinator.sh/gpu-exlusive"`},
+		},
+		{
+			name:        "unknown device annotation",
+			annotations: map[string]string{"scheduling.koordinator.sh/device-allocate-hints": "{}"},
+			wantReasons: []string{`Unsupported value: "scheduling.koordinator.sh/device-allocate-hints"`},
+		},
+		{
+			name:        "malformed json",
+			annotations: map[string]string{extension.AnnotationGPUPartitionSpec: `{"allocatePolicy":`},
+			wantReasons: []string{"must be the json of the gpu partition spec"},
+		},
+		{
+			name:        "unknown field",
+			annotations: map[string]string{extension.AnnotationDeviceJointAllocate: `{"deviceType":["gpu"]}`},
+			wantReasons: []string{`unknown field "deviceType"`},
+		},
+		{
+			name:        "unsupported enum",
+			annotations: map[string]string{extension.AnnotationDeviceAllocateHint: `{"gpu":{"requiredTopologyScope":"NUMA"}}`},
+			wantReasons: []string{`the hint of gpu: unsupported requiredTopologyScope "NUMA"`},
+		},
+		{
+			name:        "unsupported allocate policy",
+			annotations: map[string]string{extension.AnnotationGPUPartitionSpec: `{"allocatePolicy":"Strict"}`},
+			wantReasons: []string{`unsupported allocatePolicy "Strict", supported values: Restricted, BestEffort`},
+		},
+		{
+			name: "invalid scalar values",
+			annotations: map[string]string{
+				extension.AnnotationGPUExclusive:    "yes",
+				extension.AnnotationGPUFabricSpread: "job name",
+			},
+			wantReasons: []string{"must be true or false", "must be a label key"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}}
+			errs := validateGPUAnnotations(pod)
+			assert.Equal(t, len(tt.wantReasons), len(errs), "%v", errs)
+			for i, reason := range tt.wantReasons {
+				if i < len(errs) {
+					assert.Contains(t, errs[i].Error(), reason)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	apiresource "k8s.io/kubernetes/pkg/api/v1/resource"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	schedulingv1alpha1 "github.com/koordinator-sh/koordinator/apis/scheduling/v1alpha1"
+)
+
+// validateGPUCardCapacity rejects the pod whose GPU requests cannot fit any node of the cluster as the scheduler
+// splits them into GPUs, i.e. the GPU memory requested on each GPU exceeds the largest GPU rounded down to the
+// gpu-memory granularity of its Device, or the whole GPUs requested exceed the most GPUs of a node. The pod is not
+// validated if no Device is reported, e.g. the cluster is being set up.
+func validateGPUCardCapacity(pod *corev1.Pod, devices []schedulingv1alpha1.Device) field.ErrorList {
+	var maxGPUMemory resource.Quantity
+	maxGPUCount := 0
+	for i := range devices {
+		// the GPU memory is validated in the granularity allocatable by the device plugin
+		granularity := extension.GetGPUMemoryGranularity(&devices[i])
+		for _, d := range devices[i].Spec.Devices {
+			if d.Type != schedulingv1alpha1.GPU {
+				continue
+			}
+			memory, ok := d.Resources[extension.ResourceGPUMemory]
+			if !ok {
+				continue
+			}
+			if memory = extension.RoundDownGPUMemory(memory, granularity); memory.Cmp(maxGPUMemory) > 0 {
+				maxGPUMemory = memory
+			}
+		}
+		if count := countGPUs(&devices[i]); count > maxGPUCount {
+			maxGPUCount = count
+		}
+	}
+	if maxGPUCount == 0 {
+		return nil
+	}
+
+	requests := podGPURequests(pod)
+	count := podGPUCount(pod)
+
+	allErrs := field.ErrorList{}
+	fldPath := field.NewPath("pod.spec.containers[*].resources.requests")
+	if count > int64(maxGPUCount) {
+		allErrs = append(allErrs, field.Forbidden(fldPath,
+			fmt.Sprintf("the pod requests %d GPUs but the node with the most GPUs has %d, the pod can never be scheduled", count, maxGPUCount)))
+		return allErrs
+	}
+	if memory, ok := requests[extension.ResourceGPUMemory]; ok && !maxGPUMemory.IsZero() {
+		perGPU := resource.NewQuantity(memory.Value()/count, resource.BinarySI)
+		if perGPU.Cmp(maxGPUMemory) > 0 {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Key(string(extension.ResourceGPUMemory)),
+				fmt.Sprintf("the pod requests %s GPU memory on each of %d GPUs but the largest GPU has %s, the pod can never be scheduled",
+					perGPU.String(), count, maxGPUMemory.String())))
+		}
+	}
+	return allErrs
+}
+
+// podGPUCount returns the number of GPUs requested by the pod, which is calculated like the scheduler, i.e. the GPU
+// resources are requested on one GPU unless shared by GPUs or requested as the whole GPUs.
+func podGPUCount(pod *corev1.Pod) int64 {
+	ratio := podGPURequests(pod)[extension.ResourceGPUMemoryRatio]
+	if share := apiresource.PodRequests(pod, apiresource.PodResourcesOptions{})[extension.ResourceGPUShared]; share.Value() > 0 {
+		return share.Value()
+	} else if ratio.Value() > 100 && ratio.Value()%100 == 0 {
+		return ratio.Value() / 100
+	}
+	return 1
+}
+
+// countGPUs returns the number of GPUs in the Device, including the unhealthy ones.
+func countGPUs(device *schedulingv1alpha1.Device) int {
+	count := 0
+	for _, d := range device.Spec.Devices {
+		if d.Type == schedulingv1alpha1.GPU {
+			count++
+		}
+	}
+	return count
+}
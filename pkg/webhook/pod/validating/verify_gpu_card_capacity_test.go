@@ -0,0 +1,124 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	schedulingv1alpha1 "github.com/koordinator-sh/koordinator/apis/scheduling/v1alpha1"
+)
+
+func TestValidateGPUCardCapacity(t *testing.T) {
+	newDevice := func(name string, count int, memory string) schedulingv1alpha1.Device {
+		device := schedulingv1alpha1.Device{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		for i := 0; i < count; i++ {
+			device.Spec.Devices = append(device.Spec.Devices, schedulingv1alpha1.DeviceInfo{
+				Type:   schedulingv1alpha1.GPU,
+				Health: true,
+				Resources: corev1.ResourceList{
+					extension.ResourceGPUCore:        resource.MustParse("100"),
+					extension.ResourceGPUMemory:      resource.MustParse(memory),
+					extension.ResourceGPUMemoryRatio: resource.MustParse("100"),
+				},
+			})
+		}
+		return device
+	}
+	devices := []schedulingv1alpha1.Device{
+		newDevice("node-1", 8, "40Gi"),
+		newDevice("node-2", 4, "80Gi"),
+	}
+	tests := []struct {
+		name     string
+		requests corev1.ResourceList
+		devices  []schedulingv1alpha1.Device
+		wantErrs []string
+	}{
+		{
+			name:     "fits the largest gpu",
+			requests: corev1.ResourceList{extension.ResourceGPUMemory: resource.MustParse("80Gi")},
+			devices:  devices,
+		},
+		{
+			name:     "exceeds the largest gpu",
+			requests: corev1.ResourceList{extension.ResourceGPUMemory: resource.MustParse("96Gi")},
+			devices:  devices,
+			wantErrs: []string{
+				"pod.spec.containers[*].resources.requests[koordinator.sh/gpu-memory]: Forbidden: the pod requests 96Gi GPU memory on each of 1 GPUs but the largest GPU has 80Gi, the pod can never be scheduled",
+			},
+		},
+		{
+			name: "gpu memory shared by gpus",
+			requests: corev1.ResourceList{
+				extension.ResourceGPUShared: resource.MustParse("2"),
+				extension.ResourceGPUMemory: resource.MustParse("96Gi"),
+			},
+			devices: devices,
+		},
+		{
+			name:     "whole gpus fit the node with the most gpus",
+			requests: corev1.ResourceList{extension.ResourceGPU: resource.MustParse("800")},
+			devices:  devices,
+		},
+		{
+			name:     "whole gpus exceed the node with the most gpus",
+			requests: corev1.ResourceList{extension.ResourceGPU: resource.MustParse("1600")},
+			devices:  devices,
+			wantErrs: []string{
+				"pod.spec.containers[*].resources.requests: Forbidden: the pod requests 16 GPUs but the node with the most GPUs has 8, the pod can never be scheduled",
+			},
+		},
+		{
+			name:     "exceeds the largest gpu rounded down to the gpu-memory granularity",
+			requests: corev1.ResourceList{extension.ResourceGPUMemory: resource.MustParse("40Gi")},
+			devices: func() []schedulingv1alpha1.Device {
+				device := newDevice("node-3", 1, "40900Mi")
+				device.Labels = map[string]string{extension.LabelGPUMemoryGranularity: "1073741824"}
+				return []schedulingv1alpha1.Device{device}
+			}(),
+			wantErrs: []string{
+				"pod.spec.containers[*].resources.requests[koordinator.sh/gpu-memory]: Forbidden: the pod requests 40Gi GPU memory on each of 1 GPUs but the largest GPU has 39Gi, the pod can never be scheduled",
+			},
+		},
+		{
+			name:     "no device reported",
+			requests: corev1.ResourceList{extension.ResourceGPUMemory: resource.MustParse("96Gi")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "main", Resources: corev1.ResourceRequirements{Requests: tt.requests}},
+					},
+				},
+			}
+			var gotErrs []string
+			for _, err := range validateGPUCardCapacity(pod, tt.devices) {
+				gotErrs = append(gotErrs, err.Error())
+			}
+			assert.Equal(t, tt.wantErrs, gotErrs)
+		})
+	}
+}
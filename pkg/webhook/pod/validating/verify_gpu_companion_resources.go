@@ -0,0 +1,66 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	apiresource "k8s.io/kubernetes/pkg/api/v1/resource"
+)
+
+var (
+	// GPUPodMinCPURequest is the minimum cpu request of the GPU pods. The validation of cpu is disabled if it is zero.
+	GPUPodMinCPURequest = resource.QuantityValue{Quantity: resource.MustParse("0")}
+	// GPUPodMinMemoryRequest is the minimum memory request of the GPU pods. The validation of memory is disabled if
+	// it is zero.
+	GPUPodMinMemoryRequest = resource.QuantityValue{Quantity: resource.MustParse("0")}
+)
+
+// validateGPUCompanionResources rejects the GPU pods whose cpu or memory requests are below the floors, which may
+// starve on the node and leave the allocated GPUs unusable. The requests are the effective pod requests counting the
+// init containers and the pod overhead.
+func validateGPUCompanionResources(pod *corev1.Pod, minCPU, minMemory resource.Quantity) field.ErrorList {
+	if !requestsGPU(pod) || (minCPU.IsZero() && minMemory.IsZero()) {
+		return nil
+	}
+	allErrs := field.ErrorList{}
+	podRequests := apiresource.PodRequests(pod, apiresource.PodResourcesOptions{})
+	floors := []struct {
+		name corev1.ResourceName
+		min  resource.Quantity
+	}{
+		{name: corev1.ResourceCPU, min: minCPU},
+		{name: corev1.ResourceMemory, min: minMemory},
+	}
+	for _, f := range floors {
+		if f.min.IsZero() {
+			continue
+		}
+		request := podRequests[f.name]
+		if request.Cmp(f.min) >= 0 {
+			continue
+		}
+		fldPath := field.NewPath("pod.spec.containers[*].resources.requests").Key(string(f.name))
+		allErrs = append(allErrs, field.Forbidden(fldPath,
+			fmt.Sprintf("the pod requesting GPUs requests %s %s, which is less than the minimum %s",
+				f.name, request.String(), f.min.String())))
+	}
+	return allErrs
+}
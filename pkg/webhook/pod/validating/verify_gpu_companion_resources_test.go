@@ -0,0 +1,105 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestValidateGPUCompanionResources(t *testing.T) {
+	tests := []struct {
+		name        string
+		requests    corev1.ResourceList
+		minCPU      string
+		minMemory   string
+		wantReasons []string
+	}{
+		{
+			name: "validation disabled",
+			requests: corev1.ResourceList{
+				extension.ResourceGPU: resource.MustParse("100"),
+			},
+			minCPU:    "0",
+			minMemory: "0",
+		},
+		{
+			name: "non-GPU pod ignored",
+			requests: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("100m"),
+			},
+			minCPU:    "1",
+			minMemory: "1Gi",
+		},
+		{
+			name: "requests satisfy the floors",
+			requests: corev1.ResourceList{
+				extension.ResourceGPU: resource.MustParse("100"),
+				corev1.ResourceCPU:    resource.MustParse("2"),
+				corev1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+			minCPU:    "1",
+			minMemory: "1Gi",
+		},
+		{
+			name: "requests below the floors",
+			requests: corev1.ResourceList{
+				extension.ResourceGPU: resource.MustParse("100"),
+				corev1.ResourceCPU:    resource.MustParse("500m"),
+			},
+			minCPU:    "1",
+			minMemory: "1Gi",
+			wantReasons: []string{
+				"the pod requesting GPUs requests cpu 500m, which is less than the minimum 1",
+				"the pod requesting GPUs requests memory 0, which is less than the minimum 1Gi",
+			},
+		},
+		{
+			name: "only the cpu floor configured",
+			requests: corev1.ResourceList{
+				extension.ResourceGPUShared: resource.MustParse("1"),
+				corev1.ResourceCPU:          resource.MustParse("1"),
+			},
+			minCPU:    "1",
+			minMemory: "0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:      "test",
+							Resources: corev1.ResourceRequirements{Requests: tt.requests},
+						},
+					},
+				},
+			}
+			var reasons []string
+			for _, err := range validateGPUCompanionResources(pod, resource.MustParse(tt.minCPU), resource.MustParse(tt.minMemory)) {
+				reasons = append(reasons, err.Detail)
+			}
+			assert.Equal(t, tt.wantReasons, reasons)
+		})
+	}
+}
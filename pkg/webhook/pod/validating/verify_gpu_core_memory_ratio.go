@@ -0,0 +1,60 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"fmt"
+	"math"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+// GPUMemoryRatioMaxCoreFactor is the max factor of the gpu-memory-ratio to the gpu-core requested by a container,
+// e.g. 4 if a container requesting 10 gpu-core can request at most 40 gpu-memory-ratio. The validation is disabled if
+// it is not greater than 0.
+var GPUMemoryRatioMaxCoreFactor = 0.0
+
+// validateGPUCoreMemoryRatio rejects the containers requesting a gpu-memory-ratio disproportionate to the gpu-core,
+// which monopolize the memory of a shared GPU while leaving its cores underused. The containers not requesting
+// gpu-core are not validated.
+func validateGPUCoreMemoryRatio(pod *corev1.Pod, maxFactor float64) field.ErrorList {
+	if maxFactor <= 0 {
+		return nil
+	}
+	allErrs := field.ErrorList{}
+	for i := range pod.Spec.Containers {
+		container := &pod.Spec.Containers[i]
+		core, ok := container.Resources.Requests[extension.ResourceGPUCore]
+		if !ok || core.Value() <= 0 {
+			continue
+		}
+		memoryRatio, ok := container.Resources.Requests[extension.ResourceGPUMemoryRatio]
+		if !ok || float64(memoryRatio.Value()) <= maxFactor*float64(core.Value()) {
+			continue
+		}
+		fldPath := field.NewPath("pod.spec.containers").Index(i).Child("resources", "requests").Key(string(extension.ResourceGPUMemoryRatio))
+		allErrs = append(allErrs, field.Invalid(fldPath, memoryRatio.String(),
+			fmt.Sprintf("container %s requests %s %d, which exceeds %v times of the %s %d and leaves the GPU cores underused, request at least %d %s or at most %d %s",
+				container.Name, extension.ResourceGPUMemoryRatio, memoryRatio.Value(), maxFactor, extension.ResourceGPUCore, core.Value(),
+				int64(math.Ceil(float64(memoryRatio.Value())/maxFactor)), extension.ResourceGPUCore,
+				int64(math.Floor(maxFactor*float64(core.Value()))), extension.ResourceGPUMemoryRatio)))
+	}
+	return allErrs
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestValidateGPUCoreMemoryRatio(t *testing.T) {
+	tests := []struct {
+		name        string
+		requests    corev1.ResourceList
+		maxFactor   float64
+		wantReasons []string
+	}{
+		{
+			name: "validation disabled",
+			requests: corev1.ResourceList{
+				extension.ResourceGPUCore:        resource.MustParse("5"),
+				extension.ResourceGPUMemoryRatio: resource.MustParse("100"),
+			},
+			maxFactor: 0,
+		},
+		{
+			name: "proportional requests",
+			requests: corev1.ResourceList{
+				extension.ResourceGPUCore:        resource.MustParse("10"),
+				extension.ResourceGPUMemoryRatio: resource.MustParse("40"),
+			},
+			maxFactor: 4,
+		},
+		{
+			name: "more cores than memory",
+			requests: corev1.ResourceList{
+				extension.ResourceGPUCore:        resource.MustParse("100"),
+				extension.ResourceGPUMemoryRatio: resource.MustParse("10"),
+			},
+			maxFactor: 4,
+		},
+		{
+			name: "memory ratio without gpu core",
+			requests: corev1.ResourceList{
+				extension.ResourceGPUMemoryRatio: resource.MustParse("100"),
+			},
+			maxFactor: 4,
+		},
+		{
+			name: "disproportionate requests",
+			requests: corev1.ResourceList{
+				extension.ResourceGPUCore:        resource.MustParse("5"),
+				extension.ResourceGPUMemoryRatio: resource.MustParse("90"),
+			},
+			maxFactor: 4,
+			wantReasons: []string{
+				"container test requests koordinator.sh/gpu-memory-ratio 90, which exceeds 4 times of the koordinator.sh/gpu-core 5 and leaves the GPU cores underused, request at least 23 koordinator.sh/gpu-core or at most 20 koordinator.sh/gpu-memory-ratio",
+			},
+		},
+		{
+			name: "disproportionate requests with a fractional factor",
+			requests: corev1.ResourceList{
+				extension.ResourceGPUCore:        resource.MustParse("10"),
+				extension.ResourceGPUMemoryRatio: resource.MustParse("30"),
+			},
+			maxFactor: 2.5,
+			wantReasons: []string{
+				"container test requests koordinator.sh/gpu-memory-ratio 30, which exceeds 2.5 times of the koordinator.sh/gpu-core 10 and leaves the GPU cores underused, request at least 12 koordinator.sh/gpu-core or at most 25 koordinator.sh/gpu-memory-ratio",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:      "test",
+							Resources: corev1.ResourceRequirements{Requests: tt.requests},
+						},
+					},
+				},
+			}
+			errs := validateGPUCoreMemoryRatio(pod, tt.maxFactor)
+			var reasons []string
+			for _, err := range errs {
+				reasons = append(reasons, err.Detail)
+			}
+			assert.Equal(t, tt.wantReasons, reasons)
+		})
+	}
+}
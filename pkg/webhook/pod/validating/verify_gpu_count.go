@@ -0,0 +1,60 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	schedulingv1alpha1 "github.com/koordinator-sh/koordinator/apis/scheduling/v1alpha1"
+)
+
+// validateNodeGPUCount rejects the GPU pod assigned to a node which requests more GPUs than the Device of the node
+// has, since the pod can never run on the node. The pod is not validated if the Device is not reported yet.
+func validateNodeGPUCount(pod *corev1.Pod, device *schedulingv1alpha1.Device) field.ErrorList {
+	if pod.Spec.NodeName == "" || device == nil || !requestsGPU(pod) {
+		return nil
+	}
+
+	allErrs := field.ErrorList{}
+	count, nodeCount := podGPUCount(pod), countGPUs(device)
+	if count > int64(nodeCount) {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("pod.spec.nodeName"),
+			fmt.Sprintf("the pod requests %d GPUs but node %s has %d, the pod can never run on the node", count, pod.Spec.NodeName, nodeCount)))
+	}
+	return allErrs
+}
+
+// gpuCountWarnings returns the admission warnings if the unassigned pod requests more GPUs than any Device has. The
+// pod is not warned if no Device is reported, e.g. the cluster is being set up.
+func gpuCountWarnings(pod *corev1.Pod, devices []schedulingv1alpha1.Device) []string {
+	maxGPUCount := 0
+	for i := range devices {
+		if count := countGPUs(&devices[i]); count > maxGPUCount {
+			maxGPUCount = count
+		}
+	}
+	if maxGPUCount == 0 {
+		return nil
+	}
+	if count := podGPUCount(pod); count > int64(maxGPUCount) {
+		return []string{fmt.Sprintf("the pod requests %d GPUs but the node with the most GPUs has %d, the pod may stay pending", count, maxGPUCount)}
+	}
+	return nil
+}
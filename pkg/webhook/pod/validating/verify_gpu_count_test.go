@@ -0,0 +1,140 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	schedulingv1alpha1 "github.com/koordinator-sh/koordinator/apis/scheduling/v1alpha1"
+)
+
+func newGPUCountDevice(name string, count int) *schedulingv1alpha1.Device {
+	device := &schedulingv1alpha1.Device{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	for i := 0; i < count; i++ {
+		device.Spec.Devices = append(device.Spec.Devices, schedulingv1alpha1.DeviceInfo{
+			Type:   schedulingv1alpha1.GPU,
+			Health: i > 0,
+		})
+	}
+	device.Spec.Devices = append(device.Spec.Devices, schedulingv1alpha1.DeviceInfo{Type: schedulingv1alpha1.RDMA, Health: true})
+	return device
+}
+
+func newGPUCountPod(nodeName string, requests corev1.ResourceList) *corev1.Pod {
+	return &corev1.Pod{
+		Spec: corev1.PodSpec{
+			NodeName: nodeName,
+			Containers: []corev1.Container{
+				{Name: "main", Resources: corev1.ResourceRequirements{Requests: requests}},
+			},
+		},
+	}
+}
+
+func TestValidateNodeGPUCount(t *testing.T) {
+	tests := []struct {
+		name     string
+		pod      *corev1.Pod
+		device   *schedulingv1alpha1.Device
+		wantErrs []string
+	}{
+		{
+			name:   "whole gpus fit the node",
+			pod:    newGPUCountPod("node-1", corev1.ResourceList{extension.ResourceGPU: resource.MustParse("800")}),
+			device: newGPUCountDevice("node-1", 8),
+		},
+		{
+			name:   "whole gpus exceed the node",
+			pod:    newGPUCountPod("node-1", corev1.ResourceList{extension.ResourceGPU: resource.MustParse("900")}),
+			device: newGPUCountDevice("node-1", 8),
+			wantErrs: []string{
+				"pod.spec.nodeName: Forbidden: the pod requests 9 GPUs but node node-1 has 8, the pod can never run on the node",
+			},
+		},
+		{
+			name: "shared gpus exceed the node",
+			pod: newGPUCountPod("node-1", corev1.ResourceList{
+				extension.ResourceGPUShared: resource.MustParse("4"),
+				extension.ResourceGPUMemory: resource.MustParse("16Gi"),
+			}),
+			device: newGPUCountDevice("node-1", 2),
+			wantErrs: []string{
+				"pod.spec.nodeName: Forbidden: the pod requests 4 GPUs but node node-1 has 2, the pod can never run on the node",
+			},
+		},
+		{
+			name: "device not reported",
+			pod:  newGPUCountPod("node-1", corev1.ResourceList{extension.ResourceGPU: resource.MustParse("900")}),
+		},
+		{
+			name:   "pod not assigned",
+			pod:    newGPUCountPod("", corev1.ResourceList{extension.ResourceGPU: resource.MustParse("900")}),
+			device: newGPUCountDevice("node-1", 8),
+		},
+		{
+			name:   "pod requests no gpu",
+			pod:    newGPUCountPod("node-1", nil),
+			device: newGPUCountDevice("node-1", 0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotErrs []string
+			for _, err := range validateNodeGPUCount(tt.pod, tt.device) {
+				gotErrs = append(gotErrs, err.Error())
+			}
+			assert.Equal(t, tt.wantErrs, gotErrs)
+		})
+	}
+}
+
+func TestGPUCountWarnings(t *testing.T) {
+	devices := []schedulingv1alpha1.Device{*newGPUCountDevice("node-1", 8), *newGPUCountDevice("node-2", 4)}
+	tests := []struct {
+		name    string
+		pod     *corev1.Pod
+		devices []schedulingv1alpha1.Device
+		want    []string
+	}{
+		{
+			name:    "whole gpus fit the node with the most gpus",
+			pod:     newGPUCountPod("", corev1.ResourceList{extension.ResourceGPU: resource.MustParse("800")}),
+			devices: devices,
+		},
+		{
+			name:    "whole gpus exceed the node with the most gpus",
+			pod:     newGPUCountPod("", corev1.ResourceList{extension.ResourceGPU: resource.MustParse("900")}),
+			devices: devices,
+			want:    []string{"the pod requests 9 GPUs but the node with the most GPUs has 8, the pod may stay pending"},
+		},
+		{
+			name: "no device reported",
+			pod:  newGPUCountPod("", corev1.ResourceList{extension.ResourceGPU: resource.MustParse("900")}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, gpuCountWarnings(tt.pod, tt.devices))
+		})
+	}
+}
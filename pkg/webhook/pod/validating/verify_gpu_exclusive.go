@@ -0,0 +1,93 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+// validateGPUExclusive rejects the pod requiring exclusive GPUs by the annotation but requesting fractional GPUs,
+// which would be allocated in the sharing mode. A container requests whole GPUs by koordinator.sh/gpu in multiples
+// of 100, or by both gpu-core and gpu-memory-ratio of the same whole GPUs, where gpuCoreGranularity is the gpu-core
+// of a whole GPU.
+func validateGPUExclusive(pod *corev1.Pod, gpuCoreGranularity int64) field.ErrorList {
+	value, ok := pod.Annotations[extension.AnnotationGPUExclusive]
+	if !ok {
+		return nil
+	}
+	allErrs := field.ErrorList{}
+	exclusive, err := strconv.ParseBool(value)
+	if err != nil {
+		fldPath := field.NewPath("metadata", "annotations").Key(extension.AnnotationGPUExclusive)
+		return append(allErrs, field.Invalid(fldPath, value, "must be true or false"))
+	}
+	if !exclusive {
+		return nil
+	}
+
+	for i := range pod.Spec.Containers {
+		container := &pod.Spec.Containers[i]
+		if reason := fractionalGPUReason(container.Resources.Requests, gpuCoreGranularity); reason != "" {
+			fldPath := field.NewPath("pod.spec.containers").Index(i).Child("resources", "requests")
+			allErrs = append(allErrs, field.Forbidden(fldPath,
+				fmt.Sprintf("container %s requests fractional GPUs while the pod requires exclusive GPUs by %s, %s",
+					container.Name, extension.AnnotationGPUExclusive, reason)))
+		}
+	}
+	return allErrs
+}
+
+// fractionalGPUReason returns why the requests are not whole GPUs, or empty if they are whole GPUs or no GPU.
+func fractionalGPUReason(requests corev1.ResourceList, gpuCoreGranularity int64) string {
+	if q, ok := requests[extension.ResourceGPU]; ok && !q.IsZero() {
+		if q.Value()%100 != 0 {
+			return fmt.Sprintf("%s %s is not a multiple of 100", extension.ResourceGPU, q.String())
+		}
+		return ""
+	}
+	core, coreOK := requests[extension.ResourceGPUCore]
+	ratio, ratioOK := requests[extension.ResourceGPUMemoryRatio]
+	if q, ok := requests[extension.ResourceGPUMemory]; ok && !q.IsZero() {
+		return fmt.Sprintf("%s cannot express whole GPUs, request %s instead", extension.ResourceGPUMemory, extension.ResourceGPUMemoryRatio)
+	}
+	if !coreOK && !ratioOK {
+		return ""
+	}
+
+	// the number of the whole GPUs is declared by gpu-shared, or implied by the gpu-memory-ratio
+	var gpus int64
+	if q, ok := requests[extension.ResourceGPUShared]; ok && q.Value() > 0 {
+		gpus = q.Value()
+	} else if ratio.Value() > 0 && ratio.Value()%100 == 0 {
+		gpus = ratio.Value() / 100
+	} else {
+		return fmt.Sprintf("%s %s is not a multiple of 100", extension.ResourceGPUMemoryRatio, ratio.String())
+	}
+	if ratio.Value() != gpus*100 {
+		return fmt.Sprintf("%s %s does not match the %d whole GPUs, which is %d", extension.ResourceGPUMemoryRatio, ratio.String(), gpus, gpus*100)
+	}
+	if core.Value() != gpus*gpuCoreGranularity {
+		return fmt.Sprintf("%s %s does not match the %d whole GPUs, which is %d", extension.ResourceGPUCore, core.String(), gpus, gpus*gpuCoreGranularity)
+	}
+	return ""
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestValidateGPUExclusive(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		requests    corev1.ResourceList
+		granularity int64
+		wantReasons []string
+	}{
+		{
+			name: "not exclusive",
+			requests: corev1.ResourceList{
+				extension.ResourceGPUCore:        resource.MustParse("50"),
+				extension.ResourceGPUMemoryRatio: resource.MustParse("50"),
+			},
+			granularity: 100,
+		},
+		{
+			name:        "exclusive disabled",
+			annotations: map[string]string{extension.AnnotationGPUExclusive: "false"},
+			requests: corev1.ResourceList{
+				extension.ResourceGPUCore:        resource.MustParse("50"),
+				extension.ResourceGPUMemoryRatio: resource.MustParse("50"),
+			},
+			granularity: 100,
+		},
+		{
+			name:        "invalid annotation",
+			annotations: map[string]string{extension.AnnotationGPUExclusive: "yes"},
+			granularity: 100,
+			wantReasons: []string{"must be true or false"},
+		},
+		{
+			name:        "whole GPUs",
+			annotations: map[string]string{extension.AnnotationGPUExclusive: "true"},
+			requests: corev1.ResourceList{
+				extension.ResourceGPU: resource.MustParse("200"),
+			},
+			granularity: 100,
+		},
+		{
+			name:        "fractional GPU",
+			annotations: map[string]string{extension.AnnotationGPUExclusive: "true"},
+			requests: corev1.ResourceList{
+				extension.ResourceGPU: resource.MustParse("50"),
+			},
+			granularity: 100,
+			wantReasons: []string{
+				"container test requests fractional GPUs while the pod requires exclusive GPUs by scheduling.koordinator.sh/gpu-exclusive, koordinator.sh/gpu 50 is not a multiple of 100",
+			},
+		},
+		{
+			name:        "whole GPU by gpu-core and gpu-memory-ratio",
+			annotations: map[string]string{extension.AnnotationGPUExclusive: "true"},
+			requests: corev1.ResourceList{
+				extension.ResourceGPUCore:        resource.MustParse("1000"),
+				extension.ResourceGPUMemoryRatio: resource.MustParse("100"),
+			},
+			granularity: 1000,
+		},
+		{
+			name:        "fractional gpu-core",
+			annotations: map[string]string{extension.AnnotationGPUExclusive: "true"},
+			requests: corev1.ResourceList{
+				extension.ResourceGPUCore:        resource.MustParse("50"),
+				extension.ResourceGPUMemoryRatio: resource.MustParse("100"),
+			},
+			granularity: 100,
+			wantReasons: []string{
+				"container test requests fractional GPUs while the pod requires exclusive GPUs by scheduling.koordinator.sh/gpu-exclusive, koordinator.sh/gpu-core 50 does not match the 1 whole GPUs, which is 100",
+			},
+		},
+		{
+			name:        "fractional gpu-memory-ratio",
+			annotations: map[string]string{extension.AnnotationGPUExclusive: "true"},
+			requests: corev1.ResourceList{
+				extension.ResourceGPUCore:        resource.MustParse("100"),
+				extension.ResourceGPUMemoryRatio: resource.MustParse("50"),
+			},
+			granularity: 100,
+			wantReasons: []string{
+				"container test requests fractional GPUs while the pod requires exclusive GPUs by scheduling.koordinator.sh/gpu-exclusive, koordinator.sh/gpu-memory-ratio 50 is not a multiple of 100",
+			},
+		},
+		{
+			name:        "whole GPUs by gpu-shared",
+			annotations: map[string]string{extension.AnnotationGPUExclusive: "true"},
+			requests: corev1.ResourceList{
+				extension.ResourceGPUShared:      resource.MustParse("2"),
+				extension.ResourceGPUCore:        resource.MustParse("200"),
+				extension.ResourceGPUMemoryRatio: resource.MustParse("200"),
+			},
+			granularity: 100,
+		},
+		{
+			name:        "fractional GPUs by gpu-shared",
+			annotations: map[string]string{extension.AnnotationGPUExclusive: "true"},
+			requests: corev1.ResourceList{
+				extension.ResourceGPUShared:      resource.MustParse("2"),
+				extension.ResourceGPUCore:        resource.MustParse("100"),
+				extension.ResourceGPUMemoryRatio: resource.MustParse("100"),
+			},
+			granularity: 100,
+			wantReasons: []string{
+				"container test requests fractional GPUs while the pod requires exclusive GPUs by scheduling.koordinator.sh/gpu-exclusive, koordinator.sh/gpu-memory-ratio 100 does not match the 2 whole GPUs, which is 200",
+			},
+		},
+		{
+			name:        "gpu-memory",
+			annotations: map[string]string{extension.AnnotationGPUExclusive: "true"},
+			requests: corev1.ResourceList{
+				extension.ResourceGPUCore:   resource.MustParse("100"),
+				extension.ResourceGPUMemory: resource.MustParse("16Gi"),
+			},
+			granularity: 100,
+			wantReasons: []string{
+				"container test requests fractional GPUs while the pod requires exclusive GPUs by scheduling.koordinator.sh/gpu-exclusive, koordinator.sh/gpu-memory cannot express whole GPUs, request koordinator.sh/gpu-memory-ratio instead",
+			},
+		},
+		{
+			name:        "no GPU",
+			annotations: map[string]string{extension.AnnotationGPUExclusive: "true"},
+			requests: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("1"),
+			},
+			granularity: 100,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:      "test",
+							Resources: corev1.ResourceRequirements{Requests: tt.requests},
+						},
+					},
+				},
+			}
+			errs := validateGPUExclusive(pod, tt.granularity)
+			var reasons []string
+			for _, err := range errs {
+				reasons = append(reasons, err.Detail)
+			}
+			assert.Equal(t, tt.wantReasons, reasons)
+		})
+	}
+}
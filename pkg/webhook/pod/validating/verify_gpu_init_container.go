@@ -0,0 +1,76 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+// initContainerGPUResourceNames are the GPU resources allocated by the device plugin to each container requesting them.
+var initContainerGPUResourceNames = []corev1.ResourceName{
+	extension.ResourceNvidiaGPU,
+	extension.ResourceGPU,
+	extension.ResourceGPUShared,
+	extension.ResourceGPUCore,
+	extension.ResourceGPUMemory,
+	extension.ResourceGPUMemoryRatio,
+}
+
+// validateGPUInitContainers rejects the init containers requesting the GPU resources also requested by the containers.
+// The device plugin allocates the GPUs to the init containers and the containers separately, so the GPUs are counted
+// twice although the init containers have exited before the containers start.
+func validateGPUInitContainers(pod *corev1.Pod) field.ErrorList {
+	requestedBy := map[corev1.ResourceName]string{}
+	for i := range pod.Spec.Containers {
+		container := &pod.Spec.Containers[i]
+		for _, name := range initContainerGPUResourceNames {
+			if _, ok := requestedBy[name]; ok {
+				continue
+			}
+			if q, ok := container.Resources.Requests[name]; ok && !q.IsZero() {
+				requestedBy[name] = container.Name
+			}
+		}
+	}
+	if len(requestedBy) == 0 {
+		return nil
+	}
+
+	allErrs := field.ErrorList{}
+	for i := range pod.Spec.InitContainers {
+		initContainer := &pod.Spec.InitContainers[i]
+		for _, name := range initContainerGPUResourceNames {
+			containerName, ok := requestedBy[name]
+			if !ok {
+				continue
+			}
+			if q, ok := initContainer.Resources.Requests[name]; !ok || q.IsZero() {
+				continue
+			}
+			fldPath := field.NewPath("pod.spec.initContainers").Index(i).Child("resources", "requests").Key(string(name))
+			allErrs = append(allErrs, field.Forbidden(fldPath,
+				fmt.Sprintf("init container %s requests %s which is also requested by container %s, the device plugin allocates the GPUs to both of them; request the GPUs only in the containers running the GPU workloads",
+					initContainer.Name, name, containerName)))
+		}
+	}
+	return allErrs
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestValidateGPUInitContainers(t *testing.T) {
+	tests := []struct {
+		name         string
+		initRequests corev1.ResourceList
+		mainRequests corev1.ResourceList
+		wantErrs     []string
+	}{
+		{
+			name:         "gpu in containers only",
+			initRequests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+			mainRequests: corev1.ResourceList{extension.ResourceNvidiaGPU: resource.MustParse("1")},
+		},
+		{
+			name:         "gpu in init containers only",
+			initRequests: corev1.ResourceList{extension.ResourceGPUCore: resource.MustParse("50")},
+			mainRequests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+		},
+		{
+			name:         "different gpu resources",
+			initRequests: corev1.ResourceList{extension.ResourceNvidiaGPU: resource.MustParse("1")},
+			mainRequests: corev1.ResourceList{extension.ResourceGPUCore: resource.MustParse("50")},
+		},
+		{
+			name:         "zero gpu in init containers",
+			initRequests: corev1.ResourceList{extension.ResourceNvidiaGPU: resource.MustParse("0")},
+			mainRequests: corev1.ResourceList{extension.ResourceNvidiaGPU: resource.MustParse("1")},
+		},
+		{
+			name:         "same gpu resources",
+			initRequests: corev1.ResourceList{extension.ResourceNvidiaGPU: resource.MustParse("1")},
+			mainRequests: corev1.ResourceList{extension.ResourceNvidiaGPU: resource.MustParse("2")},
+			wantErrs: []string{
+				"pod.spec.initContainers[0].resources.requests[nvidia.com/gpu]: Forbidden: init container init requests nvidia.com/gpu which is also requested by container main, the device plugin allocates the GPUs to both of them; request the GPUs only in the containers running the GPU workloads",
+			},
+		},
+		{
+			name: "same fractional gpu resources",
+			initRequests: corev1.ResourceList{
+				extension.ResourceGPUCore:        resource.MustParse("50"),
+				extension.ResourceGPUMemoryRatio: resource.MustParse("50"),
+			},
+			mainRequests: corev1.ResourceList{
+				extension.ResourceGPUCore:        resource.MustParse("100"),
+				extension.ResourceGPUMemoryRatio: resource.MustParse("100"),
+			},
+			wantErrs: []string{
+				"pod.spec.initContainers[0].resources.requests[koordinator.sh/gpu-core]: Forbidden: init container init requests koordinator.sh/gpu-core which is also requested by container main, the device plugin allocates the GPUs to both of them; request the GPUs only in the containers running the GPU workloads",
+				"pod.spec.initContainers[0].resources.requests[koordinator.sh/gpu-memory-ratio]: Forbidden: init container init requests koordinator.sh/gpu-memory-ratio which is also requested by container main, the device plugin allocates the GPUs to both of them; request the GPUs only in the containers running the GPU workloads",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{
+						{Name: "init", Resources: corev1.ResourceRequirements{Requests: tt.initRequests}},
+					},
+					Containers: []corev1.Container{
+						{Name: "sidecar"},
+						{Name: "main", Resources: corev1.ResourceRequirements{Requests: tt.mainRequests}},
+					},
+				},
+			}
+			var gotErrs []string
+			for _, err := range validateGPUInitContainers(pod) {
+				gotErrs = append(gotErrs, err.Error())
+			}
+			assert.Equal(t, tt.wantErrs, gotErrs)
+		})
+	}
+}
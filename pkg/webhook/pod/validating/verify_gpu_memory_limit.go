@@ -0,0 +1,73 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+// gpuMemoryResourceNames are the GPU memory resources isolated by the limits.
+var gpuMemoryResourceNames = []corev1.ResourceName{
+	extension.ResourceGPUMemory,
+	extension.ResourceGPUMemoryRatio,
+}
+
+// validateGPUMemoryLimits rejects the containers whose GPU memory limits are not equal to the requests, since the
+// GPU memory is isolated by the limits and the bursting above the requests is not accounted by the scheduler.
+func validateGPUMemoryLimits(pod *corev1.Pod) field.ErrorList {
+	allErrs := field.ErrorList{}
+	containers := []struct {
+		fldPath    *field.Path
+		containers []corev1.Container
+	}{
+		{fldPath: field.NewPath("pod.spec.initContainers"), containers: pod.Spec.InitContainers},
+		{fldPath: field.NewPath("pod.spec.containers"), containers: pod.Spec.Containers},
+	}
+	for _, c := range containers {
+		for i := range c.containers {
+			container := &c.containers[i]
+			for _, name := range gpuMemoryResourceNames {
+				request, requestOK := container.Resources.Requests[name]
+				limit, limitOK := container.Resources.Limits[name]
+				if !requestOK && !limitOK {
+					continue
+				}
+				if request.Cmp(limit) == 0 {
+					continue
+				}
+				fldPath := c.fldPath.Index(i).Child("resources", "limits").Key(string(name))
+				allErrs = append(allErrs, field.Forbidden(fldPath,
+					fmt.Sprintf("container %s requests %s %s but limits %s, the limit must be equal to the request for the GPU memory isolation",
+						container.Name, name, printQuantity(request, requestOK), printQuantity(limit, limitOK))))
+			}
+		}
+	}
+	return allErrs
+}
+
+func printQuantity(q resource.Quantity, ok bool) string {
+	if !ok {
+		return "none"
+	}
+	return q.String()
+}
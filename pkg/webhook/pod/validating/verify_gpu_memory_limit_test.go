@@ -0,0 +1,115 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestValidateGPUMemoryLimits(t *testing.T) {
+	tests := []struct {
+		name          string
+		initContainer *corev1.Container
+		resources     corev1.ResourceRequirements
+		wantErrs      []string
+	}{
+		{
+			name: "no gpu memory",
+			resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{extension.ResourceGPUCore: resource.MustParse("50")},
+				Limits:   corev1.ResourceList{extension.ResourceGPUCore: resource.MustParse("100")},
+			},
+		},
+		{
+			name: "limits equal to requests",
+			resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					extension.ResourceGPUCore:        resource.MustParse("50"),
+					extension.ResourceGPUMemoryRatio: resource.MustParse("50"),
+				},
+				Limits: corev1.ResourceList{
+					extension.ResourceGPUCore:        resource.MustParse("50"),
+					extension.ResourceGPUMemoryRatio: resource.MustParse("50"),
+				},
+			},
+		},
+		{
+			name: "equal quantities in different formats",
+			resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{extension.ResourceGPUMemory: resource.MustParse("1Gi")},
+				Limits:   corev1.ResourceList{extension.ResourceGPUMemory: resource.MustParse("1073741824")},
+			},
+		},
+		{
+			name: "gpu-memory-ratio bursting",
+			resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{extension.ResourceGPUMemoryRatio: resource.MustParse("50")},
+				Limits:   corev1.ResourceList{extension.ResourceGPUMemoryRatio: resource.MustParse("100")},
+			},
+			wantErrs: []string{
+				"pod.spec.containers[0].resources.limits[koordinator.sh/gpu-memory-ratio]: Forbidden: container main requests koordinator.sh/gpu-memory-ratio 50 but limits 100, the limit must be equal to the request for the GPU memory isolation",
+			},
+		},
+		{
+			name: "gpu-memory without limit",
+			resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{extension.ResourceGPUMemory: resource.MustParse("8Gi")},
+			},
+			wantErrs: []string{
+				"pod.spec.containers[0].resources.limits[koordinator.sh/gpu-memory]: Forbidden: container main requests koordinator.sh/gpu-memory 8Gi but limits none, the limit must be equal to the request for the GPU memory isolation",
+			},
+		},
+		{
+			name: "init container bursting",
+			initContainer: &corev1.Container{
+				Name: "init",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{extension.ResourceGPUMemory: resource.MustParse("4Gi")},
+					Limits:   corev1.ResourceList{extension.ResourceGPUMemory: resource.MustParse("8Gi")},
+				},
+			},
+			wantErrs: []string{
+				"pod.spec.initContainers[0].resources.limits[koordinator.sh/gpu-memory]: Forbidden: container init requests koordinator.sh/gpu-memory 4Gi but limits 8Gi, the limit must be equal to the request for the GPU memory isolation",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "main", Resources: tt.resources},
+					},
+				},
+			}
+			if tt.initContainer != nil {
+				pod.Spec.InitContainers = []corev1.Container{*tt.initContainer}
+			}
+			var errs []string
+			for _, err := range validateGPUMemoryLimits(pod) {
+				errs = append(errs, err.Error())
+			}
+			assert.Equal(t, tt.wantErrs, errs)
+		})
+	}
+}
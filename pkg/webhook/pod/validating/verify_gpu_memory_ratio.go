@@ -0,0 +1,96 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	schedulingv1alpha1 "github.com/koordinator-sh/koordinator/apis/scheduling/v1alpha1"
+)
+
+const defaultGPUMemoryRatioCapacity int64 = 100
+
+// GPUMemoryRatioOversubscriptionFactor is the factor of the gpu-memory-ratio capacity of a GPU which can be
+// requested, e.g. 2 allows the pods to request gpu-memory-ratio up to 200 on a GPU. The oversubscription is
+// disabled if it is not greater than 1.
+var GPUMemoryRatioOversubscriptionFactor = 1.0
+
+// validateGPUMemoryRatioCapacity rejects the pod whose gpu-memory-ratio requested on a GPU exceeds the capacity of a
+// GPU, i.e. the gpu-memory-ratio reported by the Device of the node, or 100 if the node is unknown. The requests of
+// the containers are summed since they are allocated on the same GPUs. The requests of the whole GPUs, i.e. the
+// multiples of the capacity, are allowed, unless the GPUs are declared by koordinator.sh/gpu-shared.
+func validateGPUMemoryRatioCapacity(pod *corev1.Pod, device *schedulingv1alpha1.Device, factor float64) field.ErrorList {
+	var total, shared int64
+	for i := range pod.Spec.Containers {
+		requests := pod.Spec.Containers[i].Resources.Requests
+		if q, ok := requests[extension.ResourceGPUMemoryRatio]; ok {
+			total += q.Value()
+		}
+		if q, ok := requests[extension.ResourceGPUShared]; ok {
+			shared += q.Value()
+		}
+	}
+	if total <= 0 {
+		return nil
+	}
+
+	capacity := getGPUMemoryRatioCapacity(device)
+	perGPU := total
+	if shared > 0 {
+		perGPU = (total + shared - 1) / shared
+	} else if total%capacity == 0 {
+		return nil
+	}
+	if factor < 1 {
+		factor = 1
+	}
+	limit := int64(float64(capacity) * factor)
+	if perGPU <= limit {
+		return nil
+	}
+
+	allErrs := field.ErrorList{}
+	fldPath := field.NewPath("pod.spec.containers[*].resources.requests").Key(string(extension.ResourceGPUMemoryRatio))
+	allErrs = append(allErrs, field.Forbidden(fldPath,
+		fmt.Sprintf("the pod requests %s %d on a GPU, which exceeds the limit %d of a GPU (capacity %d, oversubscription factor %g)",
+			extension.ResourceGPUMemoryRatio, perGPU, limit, capacity, factor)))
+	return allErrs
+}
+
+// getGPUMemoryRatioCapacity returns the max gpu-memory-ratio of the GPUs reported by the Device, or 100 if unknown.
+func getGPUMemoryRatioCapacity(device *schedulingv1alpha1.Device) int64 {
+	if device == nil {
+		return defaultGPUMemoryRatioCapacity
+	}
+	var capacity int64
+	for _, d := range device.Spec.Devices {
+		if d.Type != schedulingv1alpha1.GPU {
+			continue
+		}
+		if q, ok := d.Resources[extension.ResourceGPUMemoryRatio]; ok && q.Value() > capacity {
+			capacity = q.Value()
+		}
+	}
+	if capacity <= 0 {
+		return defaultGPUMemoryRatioCapacity
+	}
+	return capacity
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	schedulingv1alpha1 "github.com/koordinator-sh/koordinator/apis/scheduling/v1alpha1"
+)
+
+func TestValidateGPUMemoryRatioCapacity(t *testing.T) {
+	container := func(requests map[corev1.ResourceName]string) corev1.Container {
+		c := corev1.Container{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{}}}
+		for name, value := range requests {
+			c.Resources.Requests[name] = resource.MustParse(value)
+		}
+		return c
+	}
+	gpuDevice := func(ratio string) *schedulingv1alpha1.Device {
+		return &schedulingv1alpha1.Device{
+			Spec: schedulingv1alpha1.DeviceSpec{
+				Devices: []schedulingv1alpha1.DeviceInfo{
+					{
+						Type:   schedulingv1alpha1.GPU,
+						Health: true,
+						Resources: corev1.ResourceList{
+							extension.ResourceGPUMemoryRatio: resource.MustParse(ratio),
+						},
+					},
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name       string
+		containers []corev1.Container
+		device     *schedulingv1alpha1.Device
+		factor     float64
+		wantErr    bool
+		wantReason string
+	}{
+		{
+			name:       "no gpu-memory-ratio requested",
+			containers: []corev1.Container{container(map[corev1.ResourceName]string{extension.ResourceGPUCore: "50"})},
+			factor:     1,
+		},
+		{
+			name: "containers fit a GPU",
+			containers: []corev1.Container{
+				container(map[corev1.ResourceName]string{extension.ResourceGPUMemoryRatio: "40"}),
+				container(map[corev1.ResourceName]string{extension.ResourceGPUMemoryRatio: "60"}),
+			},
+			factor: 1,
+		},
+		{
+			name: "containers exceed a GPU",
+			containers: []corev1.Container{
+				container(map[corev1.ResourceName]string{extension.ResourceGPUMemoryRatio: "60"}),
+				container(map[corev1.ResourceName]string{extension.ResourceGPUMemoryRatio: "60"}),
+			},
+			factor:     1,
+			wantErr:    true,
+			wantReason: "the pod requests koordinator.sh/gpu-memory-ratio 120 on a GPU, which exceeds the limit 100 of a GPU (capacity 100, oversubscription factor 1)",
+		},
+		{
+			name: "containers fit an oversubscribed GPU",
+			containers: []corev1.Container{
+				container(map[corev1.ResourceName]string{extension.ResourceGPUMemoryRatio: "60"}),
+				container(map[corev1.ResourceName]string{extension.ResourceGPUMemoryRatio: "60"}),
+			},
+			factor: 1.5,
+		},
+		{
+			name: "containers exceed an oversubscribed GPU",
+			containers: []corev1.Container{
+				container(map[corev1.ResourceName]string{extension.ResourceGPUMemoryRatio: "90"}),
+				container(map[corev1.ResourceName]string{extension.ResourceGPUMemoryRatio: "90"}),
+			},
+			factor:     1.5,
+			wantErr:    true,
+			wantReason: "the pod requests koordinator.sh/gpu-memory-ratio 180 on a GPU, which exceeds the limit 150 of a GPU (capacity 100, oversubscription factor 1.5)",
+		},
+		{
+			name:       "the factor less than 1 disables the oversubscription",
+			containers: []corev1.Container{container(map[corev1.ResourceName]string{extension.ResourceGPUMemoryRatio: "150"})},
+			factor:     0,
+			wantErr:    true,
+			wantReason: "the pod requests koordinator.sh/gpu-memory-ratio 150 on a GPU, which exceeds the limit 100 of a GPU (capacity 100, oversubscription factor 1)",
+		},
+		{
+			name: "whole GPUs are allowed",
+			containers: []corev1.Container{
+				container(map[corev1.ResourceName]string{extension.ResourceGPUMemoryRatio: "100"}),
+				container(map[corev1.ResourceName]string{extension.ResourceGPUMemoryRatio: "100"}),
+			},
+			factor: 1,
+		},
+		{
+			name: "shared GPUs fit",
+			containers: []corev1.Container{
+				container(map[corev1.ResourceName]string{extension.ResourceGPUShared: "2", extension.ResourceGPUMemoryRatio: "160"}),
+			},
+			factor: 1,
+		},
+		{
+			name: "shared GPUs exceed",
+			containers: []corev1.Container{
+				container(map[corev1.ResourceName]string{extension.ResourceGPUShared: "1", extension.ResourceGPUMemoryRatio: "100"}),
+				container(map[corev1.ResourceName]string{extension.ResourceGPUShared: "1", extension.ResourceGPUMemoryRatio: "200"}),
+			},
+			factor:     1,
+			wantErr:    true,
+			wantReason: "the pod requests koordinator.sh/gpu-memory-ratio 150 on a GPU, which exceeds the limit 100 of a GPU (capacity 100, oversubscription factor 1)",
+		},
+		{
+			name:       "capacity reported by the Device",
+			containers: []corev1.Container{container(map[corev1.ResourceName]string{extension.ResourceGPUMemoryRatio: "150"})},
+			device:     gpuDevice("200"),
+			factor:     1,
+		},
+		{
+			name:       "exceed the capacity reported by the Device",
+			containers: []corev1.Container{container(map[corev1.ResourceName]string{extension.ResourceGPUMemoryRatio: "60"})},
+			device:     gpuDevice("50"),
+			factor:     1,
+			wantErr:    true,
+			wantReason: "the pod requests koordinator.sh/gpu-memory-ratio 60 on a GPU, which exceeds the limit 50 of a GPU (capacity 50, oversubscription factor 1)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: tt.containers}}
+			errs := validateGPUMemoryRatioCapacity(pod, tt.device, tt.factor)
+			if !tt.wantErr {
+				assert.Empty(t, errs)
+				return
+			}
+			assert.Len(t, errs, 1)
+			assert.Equal(t, tt.wantReason, errs[0].Detail)
+		})
+	}
+}
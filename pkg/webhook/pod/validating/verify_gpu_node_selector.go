@@ -0,0 +1,106 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/component-helpers/scheduling/corev1/nodeaffinity"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	schedulingv1alpha1 "github.com/koordinator-sh/koordinator/apis/scheduling/v1alpha1"
+)
+
+// GPUNodeSelectorDenial rejects the GPU pods whose node selector and affinity match no node with healthy GPUs,
+// otherwise the pods are only warned.
+var GPUNodeSelectorDenial = false
+
+const gpuNodeSelectorMismatchMessage = "the node selector and affinity of the pod match no node with healthy GPUs"
+
+// validateGPUNodeSelector rejects the unassigned GPU pod whose node selector and affinity match no node with healthy
+// GPUs if GPUNodeSelectorDenial is enabled.
+func validateGPUNodeSelector(ctx context.Context, c client.Client, pod *corev1.Pod, devices []schedulingv1alpha1.Device) (field.ErrorList, error) {
+	if !GPUNodeSelectorDenial {
+		return nil, nil
+	}
+	matched, err := matchesGPUNodes(ctx, c, pod, devices)
+	if err != nil || matched {
+		return nil, err
+	}
+	return field.ErrorList{field.Forbidden(field.NewPath("pod.spec.nodeSelector"), gpuNodeSelectorMismatchMessage)}, nil
+}
+
+// gpuNodeSelectorWarnings returns the admission warnings if the node selector and affinity of the unassigned GPU pod
+// match no node with healthy GPUs, and GPUNodeSelectorDenial is disabled.
+func gpuNodeSelectorWarnings(ctx context.Context, c client.Client, pod *corev1.Pod, devices []schedulingv1alpha1.Device) ([]string, error) {
+	if GPUNodeSelectorDenial {
+		return nil, nil
+	}
+	matched, err := matchesGPUNodes(ctx, c, pod, devices)
+	if err != nil || matched {
+		return nil, err
+	}
+	return []string{gpuNodeSelectorMismatchMessage + ", the pod may stay pending"}, nil
+}
+
+// matchesGPUNodes returns whether the node selector and the required node affinity of the pod match any node of
+// the Devices with healthy GPUs. The pod is regarded as matched if it is assigned, it has no node selector or
+// affinity, or no Device has healthy GPUs, e.g. the cluster is being set up.
+func matchesGPUNodes(ctx context.Context, c client.Client, pod *corev1.Pod, devices []schedulingv1alpha1.Device) (bool, error) {
+	if pod.Spec.NodeName != "" || !requestsGPU(pod) {
+		return true, nil
+	}
+	if len(pod.Spec.NodeSelector) == 0 && (pod.Spec.Affinity == nil || pod.Spec.Affinity.NodeAffinity == nil) {
+		return true, nil
+	}
+
+	affinity := nodeaffinity.GetRequiredNodeAffinity(pod)
+	hasGPUNodes := false
+	for i := range devices {
+		if !hasHealthyGPUs(&devices[i]) {
+			continue
+		}
+		node := &corev1.Node{}
+		if err := c.Get(ctx, types.NamespacedName{Name: devices[i].Name}, node); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return false, err
+		}
+		hasGPUNodes = true
+		// the invalid selectors are rejected by the apiserver
+		if matched, err := affinity.Match(node); err != nil || matched {
+			return true, nil
+		}
+	}
+	return !hasGPUNodes, nil
+}
+
+func hasHealthyGPUs(device *schedulingv1alpha1.Device) bool {
+	for i := range device.Spec.Devices {
+		d := &device.Spec.Devices[i]
+		if d.Type == schedulingv1alpha1.GPU && extension.IsDeviceSchedulable(d) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/pkg/util"
+)
+
+func newNodeSelectorGPUPod(nodeSelector map[string]string, affinity *corev1.Affinity) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test-pod"},
+		Spec: corev1.PodSpec{
+			NodeSelector: nodeSelector,
+			Affinity:     affinity,
+			Containers: []corev1.Container{
+				{
+					Name: "main",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{extension.ResourceGPU: resource.MustParse("100")},
+					},
+				},
+			},
+		},
+	}
+}
+
+func newNodeSelectorObjects() []client.Object {
+	return []client.Object{
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "gpu-node", Labels: map[string]string{"pool": "gpu"}}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "unhealthy-gpu-node", Labels: map[string]string{"pool": "unhealthy"}}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "cpu-node", Labels: map[string]string{"pool": "cpu"}}},
+		newGPUCountDevice("gpu-node", 2),
+		newGPUCountDevice("unhealthy-gpu-node", 1),
+	}
+}
+
+func TestMatchesGPUNodes(t *testing.T) {
+	affinityIn := func(values ...string) *corev1.Affinity {
+		return &corev1.Affinity{
+			NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{
+						{
+							MatchExpressions: []corev1.NodeSelectorRequirement{
+								{Key: "pool", Operator: corev1.NodeSelectorOpIn, Values: values},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name    string
+		pod     *corev1.Pod
+		objects []client.Object
+		want    bool
+	}{
+		{
+			name:    "no node selector",
+			pod:     newNodeSelectorGPUPod(nil, nil),
+			objects: newNodeSelectorObjects(),
+			want:    true,
+		},
+		{
+			name:    "node selector matches gpu node",
+			pod:     newNodeSelectorGPUPod(map[string]string{"pool": "gpu"}, nil),
+			objects: newNodeSelectorObjects(),
+			want:    true,
+		},
+		{
+			name:    "node selector matches cpu node only",
+			pod:     newNodeSelectorGPUPod(map[string]string{"pool": "cpu"}, nil),
+			objects: newNodeSelectorObjects(),
+			want:    false,
+		},
+		{
+			name:    "node selector matches node without healthy gpus",
+			pod:     newNodeSelectorGPUPod(map[string]string{"pool": "unhealthy"}, nil),
+			objects: newNodeSelectorObjects(),
+			want:    false,
+		},
+		{
+			name:    "node affinity matches gpu node",
+			pod:     newNodeSelectorGPUPod(nil, affinityIn("cpu", "gpu")),
+			objects: newNodeSelectorObjects(),
+			want:    true,
+		},
+		{
+			name:    "node selector and affinity match different nodes",
+			pod:     newNodeSelectorGPUPod(map[string]string{"pool": "gpu"}, affinityIn("cpu")),
+			objects: newNodeSelectorObjects(),
+			want:    false,
+		},
+		{
+			name: "no Device has healthy gpus",
+			pod:  newNodeSelectorGPUPod(map[string]string{"pool": "cpu"}, nil),
+			objects: []client.Object{
+				&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "cpu-node", Labels: map[string]string{"pool": "cpu"}}},
+			},
+			want: true,
+		},
+		{
+			name: "pod assigned to node",
+			pod: func() *corev1.Pod {
+				pod := newNodeSelectorGPUPod(map[string]string{"pool": "cpu"}, nil)
+				pod.Spec.NodeName = "cpu-node"
+				return pod
+			}(),
+			objects: newNodeSelectorObjects(),
+			want:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithObjects(tt.objects...).Build()
+			devices, err := newDeviceLister(c).list(context.TODO())
+			assert.NoError(t, err)
+			got, err := matchesGPUNodes(context.TODO(), c, tt.pod, devices)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGPUNodeSelectorValidatingPod(t *testing.T) {
+	defer setGPUPodValidationsDuringTest(t, GPUNodeSelectorValidation)()
+
+	pod := newNodeSelectorGPUPod(map[string]string{"pool": "cpu"}, nil)
+	c := fake.NewClientBuilder().WithObjects(newNodeSelectorObjects()...).Build()
+	h := &PodValidatingHandler{
+		Client:  c,
+		Decoder: admission.NewDecoder(scheme.Scheme),
+	}
+	req := admission.Request{AdmissionRequest: newAdmissionRequest(admissionv1.Create,
+		runtime.RawExtension{Raw: []byte(util.DumpJSON(pod))}, runtime.RawExtension{}, "")}
+
+	// the pod is warned by default
+	devices := newDeviceLister(h.Client)
+	allowed, _, err := h.deviceResourceValidatingPod(context.TODO(), req, devices)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, []string{"the node selector and affinity of the pod match no node with healthy GPUs, the pod may stay pending"},
+		h.deviceResourceWarnings(context.TODO(), req, devices))
+
+	// the pod is rejected if the denial is enabled
+	GPUNodeSelectorDenial = true
+	defer func() {
+		GPUNodeSelectorDenial = false
+	}()
+	devices = newDeviceLister(h.Client)
+	allowed, reason, err := h.deviceResourceValidatingPod(context.TODO(), req, devices)
+	assert.Error(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, "pod.spec.nodeSelector: Forbidden: the node selector and affinity of the pod match no node with healthy GPUs", reason)
+	assert.Empty(t, h.deviceResourceWarnings(context.TODO(), req, devices))
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	schedulingv1alpha1 "github.com/koordinator-sh/koordinator/apis/scheduling/v1alpha1"
+)
+
+// validateGPUNUMAPolicy rejects the GPU pod assigned to a node with the SingleNUMANode policy if no NUMA node of the
+// node has enough healthy GPUs for the pod, since its cpus and GPUs cannot be aligned on one NUMA node. The pod is
+// skipped if the NUMA nodes of the GPUs are not reported in the Device.
+func validateGPUNUMAPolicy(pod *corev1.Pod, device *schedulingv1alpha1.Device) field.ErrorList {
+	if pod.Spec.NodeName == "" || device == nil || !requestsGPU(pod) {
+		return nil
+	}
+	fldPath := field.NewPath("metadata", "annotations").Key(extension.AnnotationNUMATopologySpec)
+	numaSpec, err := extension.GetNUMATopologySpec(pod.Annotations)
+	if err != nil {
+		return field.ErrorList{field.Invalid(fldPath, pod.Annotations[extension.AnnotationNUMATopologySpec], err.Error())}
+	}
+	if numaSpec.NUMATopologyPolicy != extension.NUMATopologyPolicySingleNUMANode {
+		return nil
+	}
+
+	numaDevices := map[int32]*schedulingv1alpha1.Device{}
+	for _, d := range device.Spec.Devices {
+		if d.Type != schedulingv1alpha1.GPU {
+			continue
+		}
+		if d.Topology == nil || d.Topology.NodeID < 0 {
+			return nil
+		}
+		numaDevice := numaDevices[d.Topology.NodeID]
+		if numaDevice == nil {
+			numaDevice = &schedulingv1alpha1.Device{}
+			numaDevices[d.Topology.NodeID] = numaDevice
+		}
+		numaDevice.Spec.Devices = append(numaDevice.Spec.Devices, d)
+	}
+	requests := podGPURequests(pod)
+	for _, numaDevice := range numaDevices {
+		if fitsHealthyGPUs(requests, numaDevice) {
+			return nil
+		}
+	}
+	return field.ErrorList{field.Forbidden(fldPath,
+		fmt.Sprintf("the pod requires %s policy, but no NUMA node of node %s has enough healthy GPUs for the requests %s",
+			extension.NUMATopologyPolicySingleNUMANode, pod.Spec.NodeName, printGPURequests(requests)))}
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	schedulingv1alpha1 "github.com/koordinator-sh/koordinator/apis/scheduling/v1alpha1"
+)
+
+func TestValidateGPUNUMAPolicy(t *testing.T) {
+	gpu := func(minor int32, nodeID int32, health bool) schedulingv1alpha1.DeviceInfo {
+		return schedulingv1alpha1.DeviceInfo{
+			Minor:  pointer.Int32(minor),
+			Type:   schedulingv1alpha1.GPU,
+			Health: health,
+			Resources: corev1.ResourceList{
+				extension.ResourceGPUCore:        resource.MustParse("100"),
+				extension.ResourceGPUMemoryRatio: resource.MustParse("100"),
+			},
+			Topology: &schedulingv1alpha1.DeviceTopology{SocketID: nodeID, NodeID: nodeID},
+		}
+	}
+	device := func(gpus ...schedulingv1alpha1.DeviceInfo) *schedulingv1alpha1.Device {
+		return &schedulingv1alpha1.Device{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-node"},
+			Spec:       schedulingv1alpha1.DeviceSpec{Devices: gpus},
+		}
+	}
+	singleNUMANode := map[string]string{extension.AnnotationNUMATopologySpec: `{"numaTopologyPolicy":"SingleNUMANode"}`}
+	tests := []struct {
+		name        string
+		nodeName    string
+		annotations map[string]string
+		requests    corev1.ResourceList
+		device      *schedulingv1alpha1.Device
+		wantReasons []string
+	}{
+		{
+			name:        "not assigned",
+			annotations: singleNUMANode,
+			requests:    corev1.ResourceList{extension.ResourceGPU: resource.MustParse("200")},
+			device:      device(gpu(0, 0, true), gpu(1, 1, true)),
+		},
+		{
+			name:     "no numa policy",
+			nodeName: "test-node",
+			requests: corev1.ResourceList{extension.ResourceGPU: resource.MustParse("200")},
+			device:   device(gpu(0, 0, true), gpu(1, 1, true)),
+		},
+		{
+			name:        "invalid numa spec",
+			nodeName:    "test-node",
+			annotations: map[string]string{extension.AnnotationNUMATopologySpec: "SingleNUMANode"},
+			requests:    corev1.ResourceList{extension.ResourceGPU: resource.MustParse("100")},
+			device:      device(gpu(0, 0, true)),
+			wantReasons: []string{"invalid character 'S' looking for beginning of value"},
+		},
+		{
+			name:        "fits one numa node",
+			nodeName:    "test-node",
+			annotations: singleNUMANode,
+			requests:    corev1.ResourceList{extension.ResourceGPU: resource.MustParse("200")},
+			device:      device(gpu(0, 0, true), gpu(1, 1, true), gpu(2, 1, true)),
+		},
+		{
+			name:        "spans numa nodes",
+			nodeName:    "test-node",
+			annotations: singleNUMANode,
+			requests:    corev1.ResourceList{extension.ResourceGPU: resource.MustParse("200")},
+			device:      device(gpu(0, 0, true), gpu(1, 1, true), gpu(2, 1, false)),
+			wantReasons: []string{
+				"the pod requires SingleNUMANode policy, but no NUMA node of node test-node has enough healthy GPUs for the requests koordinator.sh/gpu-core=200,koordinator.sh/gpu-memory-ratio=200",
+			},
+		},
+		{
+			name:        "numa node of gpus unknown",
+			nodeName:    "test-node",
+			annotations: singleNUMANode,
+			requests:    corev1.ResourceList{extension.ResourceGPU: resource.MustParse("200")},
+			device: device(gpu(0, 0, true), schedulingv1alpha1.DeviceInfo{
+				Minor:  pointer.Int32(1),
+				Type:   schedulingv1alpha1.GPU,
+				Health: true,
+				Resources: corev1.ResourceList{
+					extension.ResourceGPUCore:        resource.MustParse("100"),
+					extension.ResourceGPUMemoryRatio: resource.MustParse("100"),
+				},
+			}),
+		},
+		{
+			name:        "device not reported",
+			nodeName:    "test-node",
+			annotations: singleNUMANode,
+			requests:    corev1.ResourceList{extension.ResourceGPU: resource.MustParse("100")},
+		},
+		{
+			name:        "no GPU",
+			nodeName:    "test-node",
+			annotations: singleNUMANode,
+			requests:    corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4")},
+			device:      device(gpu(0, 0, false)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations},
+				Spec: corev1.PodSpec{
+					NodeName: tt.nodeName,
+					Containers: []corev1.Container{
+						{
+							Name:      "test",
+							Resources: corev1.ResourceRequirements{Requests: tt.requests},
+						},
+					},
+				},
+			}
+			errs := validateGPUNUMAPolicy(pod, tt.device)
+			var reasons []string
+			for _, err := range errs {
+				reasons = append(reasons, err.Detail)
+			}
+			assert.Equal(t, tt.wantReasons, reasons)
+		})
+	}
+}
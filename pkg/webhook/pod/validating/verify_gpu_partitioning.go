@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+const (
+	// resourceNvidiaMIGPrefix is the prefix of the MIG profiles exposed by the NVIDIA device plugin with the mixed
+	// strategy, e.g. nvidia.com/mig-1g.10gb.
+	resourceNvidiaMIGPrefix = "nvidia.com/mig-"
+	// resourceNvidiaGPUShared is the time-sliced replicas exposed by the NVIDIA device plugin with renameByDefault.
+	resourceNvidiaGPUShared corev1.ResourceName = "nvidia.com/gpu.shared"
+)
+
+// gpuSharingResourceNames are the GPU resources shared and partitioned by koordinator.
+var gpuSharingResourceNames = []corev1.ResourceName{
+	extension.ResourceGPU,
+	extension.ResourceGPUShared,
+	extension.ResourceGPUCore,
+	extension.ResourceGPUMemory,
+	extension.ResourceGPUMemoryRatio,
+}
+
+// validateGPUPartitioning rejects the pod requesting the GPUs in more than one partitioning model, i.e. the MIG
+// profiles, the time-sliced replicas of the device plugin, and the GPUs shared or partitioned by koordinator, since
+// a GPU is partitioned in only one model and the pod can never get all of them as requested.
+func validateGPUPartitioning(pod *corev1.Pod) field.ErrorList {
+	mig, timeSlicing, koordinator := map[string]struct{}{}, map[string]struct{}{}, map[string]struct{}{}
+	containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+	for i := range containers {
+		for name, q := range containers[i].Resources.Requests {
+			if q.IsZero() {
+				continue
+			}
+			if strings.HasPrefix(string(name), resourceNvidiaMIGPrefix) {
+				mig[string(name)] = struct{}{}
+			} else if name == resourceNvidiaGPUShared {
+				timeSlicing[string(name)] = struct{}{}
+			}
+		}
+		for _, name := range gpuSharingResourceNames {
+			if q, ok := containers[i].Resources.Requests[name]; ok && !q.IsZero() {
+				koordinator[string(name)] = struct{}{}
+			}
+		}
+	}
+	if _, ok := pod.Annotations[extension.AnnotationGPUPartitionSpec]; ok {
+		koordinator[fmt.Sprintf("annotation %s", extension.AnnotationGPUPartitionSpec)] = struct{}{}
+	}
+
+	var intents []string
+	for _, model := range []struct {
+		name     string
+		requests map[string]struct{}
+	}{
+		{name: "MIG", requests: mig},
+		{name: "time-slicing", requests: timeSlicing},
+		{name: "koordinator GPU sharing", requests: koordinator},
+	} {
+		if len(model.requests) == 0 {
+			continue
+		}
+		requests := make([]string, 0, len(model.requests))
+		for r := range model.requests {
+			requests = append(requests, r)
+		}
+		sort.Strings(requests)
+		intents = append(intents, fmt.Sprintf("%s by %s", model.name, strings.Join(requests, ", ")))
+	}
+	if len(intents) <= 1 {
+		return nil
+	}
+
+	allErrs := field.ErrorList{}
+	allErrs = append(allErrs, field.Forbidden(field.NewPath("pod.spec.containers[*].resources.requests"),
+		fmt.Sprintf("the pod requests GPUs in multiple partitioning models: %s; request only one of them", strings.Join(intents, "; "))))
+	return allErrs
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestValidateGPUPartitioning(t *testing.T) {
+	tests := []struct {
+		name         string
+		annotations  map[string]string
+		initRequests corev1.ResourceList
+		mainRequests corev1.ResourceList
+		wantErrs     []string
+	}{
+		{
+			name:         "mig profile only",
+			mainRequests: corev1.ResourceList{"nvidia.com/mig-1g.10gb": resource.MustParse("1")},
+		},
+		{
+			name:         "time-sliced replicas only",
+			mainRequests: corev1.ResourceList{resourceNvidiaGPUShared: resource.MustParse("1")},
+		},
+		{
+			name:         "koordinator gpu sharing with partition spec",
+			annotations:  map[string]string{extension.AnnotationGPUPartitionSpec: `{"allocatePolicy":"Restricted"}`},
+			mainRequests: corev1.ResourceList{extension.ResourceGPU: resource.MustParse("200")},
+		},
+		{
+			name:         "mig profile with zero time-sliced replicas",
+			mainRequests: corev1.ResourceList{"nvidia.com/mig-1g.10gb": resource.MustParse("1"), resourceNvidiaGPUShared: resource.MustParse("0")},
+		},
+		{
+			name: "mig profile with time-sliced replicas",
+			mainRequests: corev1.ResourceList{
+				"nvidia.com/mig-1g.10gb": resource.MustParse("1"),
+				"nvidia.com/mig-2g.20gb": resource.MustParse("1"),
+				resourceNvidiaGPUShared:  resource.MustParse("1"),
+			},
+			wantErrs: []string{
+				"pod.spec.containers[*].resources.requests: Forbidden: the pod requests GPUs in multiple partitioning models: MIG by nvidia.com/mig-1g.10gb, nvidia.com/mig-2g.20gb; time-slicing by nvidia.com/gpu.shared; request only one of them",
+			},
+		},
+		{
+			name:         "mig profile in init container with koordinator gpu partition spec",
+			annotations:  map[string]string{extension.AnnotationGPUPartitionSpec: `{"allocatePolicy":"Restricted"}`},
+			initRequests: corev1.ResourceList{"nvidia.com/mig-1g.10gb": resource.MustParse("1")},
+			wantErrs: []string{
+				"pod.spec.containers[*].resources.requests: Forbidden: the pod requests GPUs in multiple partitioning models: MIG by nvidia.com/mig-1g.10gb; koordinator GPU sharing by annotation scheduling.koordinator.sh/gpu-partition-spec; request only one of them",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations},
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{
+						{Name: "init", Resources: corev1.ResourceRequirements{Requests: tt.initRequests}},
+					},
+					Containers: []corev1.Container{
+						{Name: "main", Resources: corev1.ResourceRequirements{Requests: tt.mainRequests}},
+					},
+				},
+			}
+			var gotErrs []string
+			for _, err := range validateGPUPartitioning(pod) {
+				gotErrs = append(gotErrs, err.Error())
+			}
+			assert.Equal(t, tt.wantErrs, gotErrs)
+		})
+	}
+}
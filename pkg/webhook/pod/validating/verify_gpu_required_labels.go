@@ -0,0 +1,125 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var (
+	// GPUPodRequiredLabels are the labels required by the GPU pods, separated by comma. Each of them is a key, or
+	// a key with the valid values separated by "|", e.g. "example.com/numa-policy=single|restricted".
+	GPUPodRequiredLabels = ""
+	// GPUPodRequiredAnnotations are the annotations required by the GPU pods in the same format as the labels.
+	GPUPodRequiredAnnotations = ""
+)
+
+// requiredKey is a required label or annotation key, and its valid values if any.
+type requiredKey struct {
+	key    string
+	values []string
+}
+
+// gpuRequiredLabelsValidatingPod rejects the created GPU pods without the required labels or annotations,
+// e.g. the topology hints of the scheduling extenders.
+func (h *PodValidatingHandler) gpuRequiredLabelsValidatingPod(ctx context.Context, req admission.Request) (bool, string, error) {
+	if req.Operation != admissionv1.Create ||
+		!isGPUPodValidationEnabled(GPURequiredLabelsValidation) {
+		return true, "", nil
+	}
+
+	pod := &corev1.Pod{}
+	if err := h.Decoder.DecodeRaw(req.Object, pod); err != nil {
+		return false, "", err
+	}
+
+	err := validateGPURequiredLabels(pod, parseRequiredKeys(GPUPodRequiredLabels), parseRequiredKeys(GPUPodRequiredAnnotations)).ToAggregate()
+	allowed := true
+	reason := ""
+	if err != nil {
+		allowed = false
+		reason = err.Error()
+	}
+	return allowed, reason, err
+}
+
+func validateGPURequiredLabels(pod *corev1.Pod, requiredLabels, requiredAnnotations []requiredKey) field.ErrorList {
+	if len(requiredLabels) == 0 && len(requiredAnnotations) == 0 || !requestsGPU(pod) {
+		return nil
+	}
+	allErrs := field.ErrorList{}
+	allErrs = append(allErrs, validateRequiredKeys(pod.Labels, requiredLabels, field.NewPath("pod.metadata.labels"))...)
+	allErrs = append(allErrs, validateRequiredKeys(pod.Annotations, requiredAnnotations, field.NewPath("pod.metadata.annotations"))...)
+	return allErrs
+}
+
+// validateRequiredKeys returns one error listing all the missing keys, and one error for each invalid value.
+func validateRequiredKeys(m map[string]string, requiredKeys []requiredKey, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	var missingKeys []string
+	for _, required := range requiredKeys {
+		value, ok := m[required.key]
+		if !ok {
+			missingKeys = append(missingKeys, required.key)
+			continue
+		}
+		if len(required.values) == 0 {
+			continue
+		}
+		valid := false
+		for _, v := range required.values {
+			if value == v {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Key(required.key), value, required.values))
+		}
+	}
+	if len(missingKeys) > 0 {
+		allErrs = append(allErrs, field.Required(fldPath,
+			fmt.Sprintf("the pod requesting GPU resources must have the keys [%s]", strings.Join(missingKeys, ", "))))
+	}
+	return allErrs
+}
+
+func parseRequiredKeys(value string) []requiredKey {
+	var requiredKeys []requiredKey
+	for _, item := range splitFlagValues(value) {
+		key, values, _ := strings.Cut(item, "=")
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		required := requiredKey{key: key}
+		for _, v := range strings.Split(values, "|") {
+			if v = strings.TrimSpace(v); v != "" {
+				required.values = append(required.values, v)
+			}
+		}
+		requiredKeys = append(requiredKeys, required)
+	}
+	return requiredKeys
+}
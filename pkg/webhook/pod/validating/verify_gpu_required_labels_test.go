@@ -0,0 +1,151 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestGPURequiredLabelsValidatingPod(t *testing.T) {
+	defer setGPUPodValidationsDuringTest(t, GPURequiredLabelsValidation)()
+
+	gpuPod := func(labels, annotations map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels:      labels,
+				Annotations: annotations,
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{
+								extension.ResourceGPU: resource.MustParse("100"),
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name                  string
+		operation             admissionv1.Operation
+		pod                   *corev1.Pod
+		requiredLabels        string
+		requiredAnnotations   string
+		wantAllowed           bool
+		wantErr               bool
+		wantReasonContainsAll []string
+	}{
+		{
+			name:      "non-gpu pod",
+			operation: admissionv1.Create,
+			pod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{}},
+				},
+			},
+			requiredLabels: "example.com/numa-policy",
+			wantAllowed:    true,
+		},
+		{
+			name:                "gpu pod with the required labels and annotations",
+			operation:           admissionv1.Create,
+			pod:                 gpuPod(map[string]string{"example.com/numa-policy": "single"}, map[string]string{"example.com/topology": "ring"}),
+			requiredLabels:      "example.com/numa-policy=single|restricted",
+			requiredAnnotations: "example.com/topology",
+			wantAllowed:         true,
+		},
+		{
+			name:                  "gpu pod missing the required keys",
+			operation:             admissionv1.Create,
+			pod:                   gpuPod(nil, nil),
+			requiredLabels:        "example.com/numa-policy, example.com/job",
+			requiredAnnotations:   "example.com/topology",
+			wantAllowed:           false,
+			wantErr:               true,
+			wantReasonContainsAll: []string{"pod.metadata.labels", "example.com/numa-policy, example.com/job", "pod.metadata.annotations", "example.com/topology"},
+		},
+		{
+			name:                  "gpu pod with an invalid value",
+			operation:             admissionv1.Create,
+			pod:                   gpuPod(map[string]string{"example.com/numa-policy": "none"}, nil),
+			requiredLabels:        "example.com/numa-policy=single|restricted",
+			wantAllowed:           false,
+			wantErr:               true,
+			wantReasonContainsAll: []string{"pod.metadata.labels[example.com/numa-policy]", "none", "single", "restricted"},
+		},
+		{
+			name:        "nothing configured",
+			operation:   admissionv1.Create,
+			pod:         gpuPod(nil, nil),
+			wantAllowed: true,
+		},
+		{
+			name:           "gpu pod missing the required keys on update",
+			operation:      admissionv1.Update,
+			pod:            gpuPod(nil, nil),
+			requiredLabels: "example.com/numa-policy",
+			wantAllowed:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldLabels, oldAnnotations := GPUPodRequiredLabels, GPUPodRequiredAnnotations
+			defer func() {
+				GPUPodRequiredLabels, GPUPodRequiredAnnotations = oldLabels, oldAnnotations
+			}()
+			GPUPodRequiredLabels, GPUPodRequiredAnnotations = tt.requiredLabels, tt.requiredAnnotations
+
+			h := &PodValidatingHandler{
+				Decoder: admission.NewDecoder(scheme.Scheme),
+			}
+			podRaw, err := json.Marshal(tt.pod)
+			assert.NoError(t, err)
+			object := runtime.RawExtension{Raw: podRaw}
+			req := newAdmissionRequest(tt.operation, object, object, "")
+			gotAllowed, gotReason, err := h.gpuRequiredLabelsValidatingPod(context.TODO(), admission.Request{AdmissionRequest: req})
+			assert.Equal(t, tt.wantAllowed, gotAllowed, gotReason)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			for _, s := range tt.wantReasonContainsAll {
+				assert.Contains(t, gotReason, s)
+			}
+		})
+	}
+}
+
+func Test_parseRequiredKeys(t *testing.T) {
+	got := parseRequiredKeys(" example.com/a , example.com/b=x| y ,=z,")
+	assert.Equal(t, []requiredKey{
+		{key: "example.com/a"},
+		{key: "example.com/b", values: []string{"x", "y"}},
+	}, got)
+}
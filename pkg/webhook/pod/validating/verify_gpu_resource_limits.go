@@ -0,0 +1,63 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// validateGPUResourceLimits rejects the containers whose requests and limits of the GPU resources diverge. As the
+// extended resources cannot be overcommitted, the limit must be set if the resource is requested, and the request
+// must be equal to the limit, where the request defaults to the limit if not set.
+func validateGPUResourceLimits(pod *corev1.Pod) field.ErrorList {
+	allErrs := field.ErrorList{}
+	containers := []struct {
+		fldPath    *field.Path
+		containers []corev1.Container
+	}{
+		{fldPath: field.NewPath("pod.spec.initContainers"), containers: pod.Spec.InitContainers},
+		{fldPath: field.NewPath("pod.spec.containers"), containers: pod.Spec.Containers},
+	}
+	for _, c := range containers {
+		for i := range c.containers {
+			container := &c.containers[i]
+			for _, name := range gpuSharingResourceNames {
+				request, requestOK := container.Resources.Requests[name]
+				limit, limitOK := container.Resources.Limits[name]
+				if !requestOK {
+					continue
+				}
+				fldPath := c.fldPath.Index(i).Child("resources", "limits").Key(string(name))
+				if !limitOK {
+					allErrs = append(allErrs, field.Forbidden(fldPath,
+						fmt.Sprintf("container %s requests %s %s without the limit, the limit must be set for the extended resource",
+							container.Name, name, request.String())))
+					continue
+				}
+				if request.Cmp(limit) != 0 {
+					allErrs = append(allErrs, field.Forbidden(fldPath,
+						fmt.Sprintf("container %s requests %s %s but limits %s, the request must be equal to the limit for the extended resource",
+							container.Name, name, request.String(), limit.String())))
+				}
+			}
+		}
+	}
+	return allErrs
+}
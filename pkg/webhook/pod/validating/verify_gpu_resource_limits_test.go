@@ -0,0 +1,120 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestValidateGPUResourceLimits(t *testing.T) {
+	tests := []struct {
+		name          string
+		initContainer *corev1.Container
+		resources     corev1.ResourceRequirements
+		wantErrs      []string
+	}{
+		{
+			name: "no gpu resources",
+			resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+				Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+			},
+		},
+		{
+			name: "limits equal to requests",
+			resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					extension.ResourceGPUCore:        resource.MustParse("50"),
+					extension.ResourceGPUMemoryRatio: resource.MustParse("50"),
+				},
+				Limits: corev1.ResourceList{
+					extension.ResourceGPUCore:        resource.MustParse("50"),
+					extension.ResourceGPUMemoryRatio: resource.MustParse("50"),
+				},
+			},
+		},
+		{
+			name: "limits only",
+			resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{extension.ResourceGPU: resource.MustParse("100")},
+			},
+		},
+		{
+			name: "equal quantities in different formats",
+			resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{extension.ResourceGPUMemory: resource.MustParse("1Gi")},
+				Limits:   corev1.ResourceList{extension.ResourceGPUMemory: resource.MustParse("1073741824")},
+			},
+		},
+		{
+			name: "gpu-core without limit",
+			resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{extension.ResourceGPUCore: resource.MustParse("50")},
+			},
+			wantErrs: []string{
+				"pod.spec.containers[0].resources.limits[koordinator.sh/gpu-core]: Forbidden: container main requests koordinator.sh/gpu-core 50 without the limit, the limit must be set for the extended resource",
+			},
+		},
+		{
+			name: "gpu-core limits diverge from requests",
+			resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{extension.ResourceGPUCore: resource.MustParse("50")},
+				Limits:   corev1.ResourceList{extension.ResourceGPUCore: resource.MustParse("100")},
+			},
+			wantErrs: []string{
+				"pod.spec.containers[0].resources.limits[koordinator.sh/gpu-core]: Forbidden: container main requests koordinator.sh/gpu-core 50 but limits 100, the request must be equal to the limit for the extended resource",
+			},
+		},
+		{
+			name: "init container without limit",
+			initContainer: &corev1.Container{
+				Name: "init",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{extension.ResourceGPUShared: resource.MustParse("1")},
+				},
+			},
+			wantErrs: []string{
+				"pod.spec.initContainers[0].resources.limits[koordinator.sh/gpu.shared]: Forbidden: container init requests koordinator.sh/gpu.shared 1 without the limit, the limit must be set for the extended resource",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "main", Resources: tt.resources},
+					},
+				},
+			}
+			if tt.initContainer != nil {
+				pod.Spec.InitContainers = []corev1.Container{*tt.initContainer}
+			}
+			var errs []string
+			for _, err := range validateGPUResourceLimits(pod) {
+				errs = append(errs, err.Error())
+			}
+			assert.Equal(t, tt.wantErrs, errs)
+		})
+	}
+}
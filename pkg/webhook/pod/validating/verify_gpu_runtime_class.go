@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var (
+	// GPURuntimeClassNames are the runtime classes which can access the GPU devices, separated by comma, e.g.
+	// "nvidia". The validation is disabled if neither the runtime classes nor the annotations are configured.
+	GPURuntimeClassNames = ""
+	// GPURuntimeAnnotations are the keys of the annotations which make the GPU devices accessible without
+	// the runtime class, e.g. the pod is handled by the runtime hooks, separated by comma.
+	GPURuntimeAnnotations = ""
+)
+
+// gpuRuntimeClassValidatingPod rejects the created GPU pods which cannot access the GPU devices at runtime.
+func (h *PodValidatingHandler) gpuRuntimeClassValidatingPod(ctx context.Context, req admission.Request) (bool, string, error) {
+	if req.Operation != admissionv1.Create ||
+		!isGPUPodValidationEnabled(GPURuntimeClassValidation) {
+		return true, "", nil
+	}
+
+	pod := &corev1.Pod{}
+	if err := h.Decoder.DecodeRaw(req.Object, pod); err != nil {
+		return false, "", err
+	}
+
+	err := validateGPURuntimeClass(pod, splitFlagValues(GPURuntimeClassNames), splitFlagValues(GPURuntimeAnnotations)).ToAggregate()
+	allowed := true
+	reason := ""
+	if err != nil {
+		allowed = false
+		reason = err.Error()
+	}
+	return allowed, reason, err
+}
+
+func validateGPURuntimeClass(pod *corev1.Pod, runtimeClassNames, annotations []string) field.ErrorList {
+	if !requestsGPU(pod) || len(runtimeClassNames) == 0 && len(annotations) == 0 {
+		return nil
+	}
+	if pod.Spec.RuntimeClassName != nil {
+		for _, name := range runtimeClassNames {
+			if *pod.Spec.RuntimeClassName == name {
+				return nil
+			}
+		}
+	}
+	for _, key := range annotations {
+		if _, ok := pod.Annotations[key]; ok {
+			return nil
+		}
+	}
+
+	allErrs := field.ErrorList{}
+	fldPath := field.NewPath("pod.spec.runtimeClassName")
+	detail := fmt.Sprintf("the pod requesting GPU resources cannot access the GPU devices, use one of the runtime classes [%s]",
+		strings.Join(runtimeClassNames, ", "))
+	if len(annotations) > 0 {
+		detail = fmt.Sprintf("%s or set one of the annotations [%s]", detail, strings.Join(annotations, ", "))
+	}
+	if pod.Spec.RuntimeClassName == nil {
+		allErrs = append(allErrs, field.Required(fldPath, detail))
+	} else {
+		allErrs = append(allErrs, field.Invalid(fldPath, *pod.Spec.RuntimeClassName, detail))
+	}
+	return allErrs
+}
+
+func splitFlagValues(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestGPURuntimeClassValidatingPod(t *testing.T) {
+	defer setGPUPodValidationsDuringTest(t, GPURuntimeClassValidation)()
+
+	gpuPod := func(runtimeClassName *string, annotations map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: annotations,
+			},
+			Spec: corev1.PodSpec{
+				RuntimeClassName: runtimeClassName,
+				Containers: []corev1.Container{
+					{
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{
+								extension.ResourceGPU: resource.MustParse("100"),
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name                  string
+		operation             admissionv1.Operation
+		pod                   *corev1.Pod
+		runtimeClassNames     string
+		runtimeAnnotations    string
+		wantAllowed           bool
+		wantErr               bool
+		wantReasonContainsAll []string
+	}{
+		{
+			name:      "non-gpu pod",
+			operation: admissionv1.Create,
+			pod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Resources: corev1.ResourceRequirements{
+								Requests: corev1.ResourceList{
+									corev1.ResourceCPU: resource.MustParse("1"),
+								},
+							},
+						},
+					},
+				},
+			},
+			runtimeClassNames: "nvidia",
+			wantAllowed:       true,
+		},
+		{
+			name:              "gpu pod with the runtime class",
+			operation:         admissionv1.Create,
+			pod:               gpuPod(pointer.String("nvidia"), nil),
+			runtimeClassNames: "nvidia",
+			wantAllowed:       true,
+		},
+		{
+			name:              "gpu pod with one of the runtime classes",
+			operation:         admissionv1.Create,
+			pod:               gpuPod(pointer.String("nvidia-cdi"), nil),
+			runtimeClassNames: "nvidia, nvidia-cdi",
+			wantAllowed:       true,
+		},
+		{
+			name:                  "gpu pod without runtime class",
+			operation:             admissionv1.Create,
+			pod:                   gpuPod(nil, nil),
+			runtimeClassNames:     "nvidia",
+			wantAllowed:           false,
+			wantErr:               true,
+			wantReasonContainsAll: []string{"pod.spec.runtimeClassName", "nvidia"},
+		},
+		{
+			name:                  "gpu pod with incompatible runtime class",
+			operation:             admissionv1.Create,
+			pod:                   gpuPod(pointer.String("runc"), nil),
+			runtimeClassNames:     "nvidia",
+			runtimeAnnotations:    "example.com/gpu-runtime",
+			wantAllowed:           false,
+			wantErr:               true,
+			wantReasonContainsAll: []string{"runc", "nvidia", "example.com/gpu-runtime"},
+		},
+		{
+			name:               "gpu pod with the annotation",
+			operation:          admissionv1.Create,
+			pod:                gpuPod(nil, map[string]string{"example.com/gpu-runtime": "true"}),
+			runtimeClassNames:  "nvidia",
+			runtimeAnnotations: "example.com/gpu-runtime",
+			wantAllowed:        true,
+		},
+		{
+			name:        "nothing configured",
+			operation:   admissionv1.Create,
+			pod:         gpuPod(nil, nil),
+			wantAllowed: true,
+		},
+		{
+			name:              "gpu pod without runtime class on update",
+			operation:         admissionv1.Update,
+			pod:               gpuPod(nil, nil),
+			runtimeClassNames: "nvidia",
+			wantAllowed:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldNames, oldAnnotations := GPURuntimeClassNames, GPURuntimeAnnotations
+			defer func() {
+				GPURuntimeClassNames, GPURuntimeAnnotations = oldNames, oldAnnotations
+			}()
+			GPURuntimeClassNames, GPURuntimeAnnotations = tt.runtimeClassNames, tt.runtimeAnnotations
+
+			h := &PodValidatingHandler{
+				Decoder: admission.NewDecoder(scheme.Scheme),
+			}
+			podRaw, err := json.Marshal(tt.pod)
+			assert.NoError(t, err)
+			object := runtime.RawExtension{Raw: podRaw}
+			req := newAdmissionRequest(tt.operation, object, object, "")
+			gotAllowed, gotReason, err := h.gpuRuntimeClassValidatingPod(context.TODO(), admission.Request{AdmissionRequest: req})
+			assert.Equal(t, tt.wantAllowed, gotAllowed, gotReason)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			for _, s := range tt.wantReasonContainsAll {
+				assert.Contains(t, gotReason, s)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"context"
+	"fmt"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/klog/v2"
+	apiresource "k8s.io/kubernetes/pkg/api/v1/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/pkg/util"
+)
+
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+
+// namespaceGPUQuotaValidatingPod rejects the created GPU pods which make the gpu-core requested by the running and
+// pending pods in the namespace exceed the limit annotated on the namespace.
+func (h *PodValidatingHandler) namespaceGPUQuotaValidatingPod(ctx context.Context, req admission.Request) (bool, string, error) {
+	if req.Operation != admissionv1.Create ||
+		!isGPUPodValidationEnabled(GPUNamespaceQuotaValidation) {
+		return true, "", nil
+	}
+
+	pod := &corev1.Pod{}
+	if err := h.Decoder.DecodeRaw(req.Object, pod); err != nil {
+		return false, "", err
+	}
+	if pod.Namespace == "" {
+		pod.Namespace = req.Namespace
+	}
+	requested := podNamespaceGPUCore(pod)
+	if requested.IsZero() {
+		return true, "", nil
+	}
+
+	namespace := &corev1.Namespace{}
+	if err := h.Client.Get(ctx, types.NamespacedName{Name: pod.Namespace}, namespace); err != nil {
+		if errors.IsNotFound(err) {
+			return true, "", nil
+		}
+		return false, "", err
+	}
+	limit, ok, err := getNamespaceGPUCoreLimit(namespace)
+	if err != nil {
+		// a malformed limit should not block all the GPU pods of the namespace
+		klog.Warningf("Skip to validate the GPU quota of namespace %s, %v", namespace.Name, err)
+		return true, "", nil
+	}
+	if !ok {
+		return true, "", nil
+	}
+
+	podList := &corev1.PodList{}
+	if err := h.Client.List(ctx, podList, client.InNamespace(pod.Namespace)); err != nil {
+		return false, "", err
+	}
+	used := namespaceGPUCoreUsed(podList.Items, pod)
+
+	err = validateNamespaceGPUQuota(pod.Namespace, requested, used, limit).ToAggregate()
+	allowed := true
+	reason := ""
+	if err != nil {
+		allowed = false
+		reason = err.Error()
+	}
+	return allowed, reason, err
+}
+
+// getNamespaceGPUCoreLimit returns the gpu-core limit annotated on the namespace, and whether it is annotated.
+func getNamespaceGPUCoreLimit(namespace *corev1.Namespace) (resource.Quantity, bool, error) {
+	value, ok := namespace.Annotations[extension.AnnotationNamespaceGPUCoreLimit]
+	if !ok {
+		return resource.Quantity{}, false, nil
+	}
+	limit, err := resource.ParseQuantity(value)
+	if err != nil {
+		return resource.Quantity{}, false, fmt.Errorf("invalid annotation %s %q, %v", extension.AnnotationNamespaceGPUCoreLimit, value, err)
+	}
+	if limit.Sign() < 0 {
+		return resource.Quantity{}, false, fmt.Errorf("invalid annotation %s %q, must not be negative", extension.AnnotationNamespaceGPUCoreLimit, value)
+	}
+	return limit, true, nil
+}
+
+// namespaceGPUCoreUsed returns the gpu-core requested by the running and pending pods except the validated one.
+func namespaceGPUCoreUsed(pods []corev1.Pod, validated *corev1.Pod) resource.Quantity {
+	used := resource.Quantity{}
+	for i := range pods {
+		p := &pods[i]
+		if util.IsPodTerminated(p) {
+			continue
+		}
+		if validated.Name != "" && p.Name == validated.Name {
+			continue
+		}
+		used.Add(podNamespaceGPUCore(p))
+	}
+	return used
+}
+
+// podNamespaceGPUCore returns the gpu-core charged to the namespace for the pod, where each nvidia.com/gpu is charged
+// as a whole GPU of the default gpu-core granularity, so that the pods requesting the GPUs by the device plugin
+// resource cannot bypass the limit.
+func podNamespaceGPUCore(pod *corev1.Pod) resource.Quantity {
+	gpuCore := podGPURequests(pod)[extension.ResourceGPUCore]
+	gpuCore = gpuCore.DeepCopy()
+	podRequests := apiresource.PodRequests(pod, apiresource.PodResourcesOptions{})
+	if q, ok := podRequests[extension.ResourceNvidiaGPU]; ok && !q.IsZero() {
+		gpuCore.Add(*resource.NewQuantity(q.Value()*extension.DefaultGPUCoreGranularity, resource.DecimalSI))
+	}
+	return gpuCore
+}
+
+func validateNamespaceGPUQuota(namespace string, requested, used, limit resource.Quantity) field.ErrorList {
+	total := used.DeepCopy()
+	total.Add(requested)
+	if total.Cmp(limit) <= 0 {
+		return nil
+	}
+
+	allErrs := field.ErrorList{}
+	fldPath := field.NewPath("pod.spec.containers[*].resources.requests").Key(string(extension.ResourceGPUCore))
+	allErrs = append(allErrs, field.Forbidden(fldPath,
+		fmt.Sprintf("the pod requests %s %s, which exceeds the limit %s of namespace %s (used %s)",
+			extension.ResourceGPUCore, requested.String(), limit.String(), namespace, used.String())))
+	return allErrs
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/pkg/util"
+)
+
+func TestNamespaceGPUQuotaValidatingPod(t *testing.T) {
+	defer setGPUPodValidationsDuringTest(t, GPUNamespaceQuotaValidation)()
+
+	gpuPod := func(name string, requests corev1.ResourceList, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "team-a",
+				Name:      name,
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name:      "main",
+						Resources: corev1.ResourceRequirements{Requests: requests},
+					},
+				},
+			},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+	}
+	namespace := func(annotations map[string]string) *corev1.Namespace {
+		return &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "team-a",
+				Annotations: annotations,
+			},
+		}
+	}
+	limited := namespace(map[string]string{extension.AnnotationNamespaceGPUCoreLimit: "200"})
+	tests := []struct {
+		name        string
+		operation   admissionv1.Operation
+		pod         *corev1.Pod
+		objects     []client.Object
+		wantAllowed bool
+		wantErr     bool
+		wantReason  string
+	}{
+		{
+			name:        "non-gpu pod",
+			operation:   admissionv1.Create,
+			pod:         gpuPod("test", corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}, ""),
+			objects:     []client.Object{namespace(map[string]string{extension.AnnotationNamespaceGPUCoreLimit: "0"})},
+			wantAllowed: true,
+		},
+		{
+			name:        "namespace without limit",
+			operation:   admissionv1.Create,
+			pod:         gpuPod("test", corev1.ResourceList{extension.ResourceGPU: resource.MustParse("100")}, ""),
+			objects:     []client.Object{namespace(nil)},
+			wantAllowed: true,
+		},
+		{
+			name:        "invalid limit is ignored",
+			operation:   admissionv1.Create,
+			pod:         gpuPod("test", corev1.ResourceList{extension.ResourceGPU: resource.MustParse("100")}, ""),
+			objects:     []client.Object{namespace(map[string]string{extension.AnnotationNamespaceGPUCoreLimit: "two"})},
+			wantAllowed: true,
+		},
+		{
+			name:      "fits the limit",
+			operation: admissionv1.Create,
+			pod:       gpuPod("test", corev1.ResourceList{extension.ResourceGPUCore: resource.MustParse("50")}, ""),
+			objects: []client.Object{
+				limited,
+				gpuPod("running", corev1.ResourceList{extension.ResourceGPU: resource.MustParse("100")}, corev1.PodRunning),
+				gpuPod("pending", corev1.ResourceList{extension.ResourceGPUCore: resource.MustParse("50")}, corev1.PodPending),
+			},
+			wantAllowed: true,
+		},
+		{
+			name:      "terminated pods are not counted",
+			operation: admissionv1.Create,
+			pod:       gpuPod("test", corev1.ResourceList{extension.ResourceGPU: resource.MustParse("100")}, ""),
+			objects: []client.Object{
+				limited,
+				gpuPod("running", corev1.ResourceList{extension.ResourceGPU: resource.MustParse("100")}, corev1.PodRunning),
+				gpuPod("succeeded", corev1.ResourceList{extension.ResourceGPU: resource.MustParse("100")}, corev1.PodSucceeded),
+				gpuPod("failed", corev1.ResourceList{extension.ResourceGPU: resource.MustParse("100")}, corev1.PodFailed),
+			},
+			wantAllowed: true,
+		},
+		{
+			name:      "exceeds the limit",
+			operation: admissionv1.Create,
+			pod:       gpuPod("test", corev1.ResourceList{extension.ResourceGPUCore: resource.MustParse("60")}, ""),
+			objects: []client.Object{
+				limited,
+				gpuPod("running", corev1.ResourceList{extension.ResourceGPU: resource.MustParse("100")}, corev1.PodRunning),
+				gpuPod("pending", corev1.ResourceList{extension.ResourceGPUCore: resource.MustParse("50")}, corev1.PodPending),
+			},
+			wantAllowed: false,
+			wantErr:     true,
+			wantReason:  "pod.spec.containers[*].resources.requests[koordinator.sh/gpu-core]: Forbidden: the pod requests koordinator.sh/gpu-core 60, which exceeds the limit 200 of namespace team-a (used 150)",
+		},
+		{
+			name:      "nvidia.com/gpu requested by the pod is counted",
+			operation: admissionv1.Create,
+			pod:       gpuPod("test", corev1.ResourceList{extension.ResourceNvidiaGPU: resource.MustParse("2")}, ""),
+			objects: []client.Object{
+				limited,
+				gpuPod("running", corev1.ResourceList{extension.ResourceGPUCore: resource.MustParse("50")}, corev1.PodRunning),
+			},
+			wantAllowed: false,
+			wantErr:     true,
+			wantReason:  "pod.spec.containers[*].resources.requests[koordinator.sh/gpu-core]: Forbidden: the pod requests koordinator.sh/gpu-core 200, which exceeds the limit 200 of namespace team-a (used 50)",
+		},
+		{
+			name:      "nvidia.com/gpu requested by the running pods is counted",
+			operation: admissionv1.Create,
+			pod:       gpuPod("test", corev1.ResourceList{extension.ResourceGPUCore: resource.MustParse("50")}, ""),
+			objects: []client.Object{
+				limited,
+				gpuPod("running", corev1.ResourceList{extension.ResourceNvidiaGPU: resource.MustParse("2")}, corev1.PodRunning),
+			},
+			wantAllowed: false,
+			wantErr:     true,
+			wantReason:  "pod.spec.containers[*].resources.requests[koordinator.sh/gpu-core]: Forbidden: the pod requests koordinator.sh/gpu-core 50, which exceeds the limit 200 of namespace team-a (used 200)",
+		},
+		{
+			name:      "pods in other namespaces are not counted",
+			operation: admissionv1.Create,
+			pod:       gpuPod("test", corev1.ResourceList{extension.ResourceGPU: resource.MustParse("200")}, ""),
+			objects: []client.Object{
+				limited,
+				&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "team-b", Name: "running"},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name: "main",
+								Resources: corev1.ResourceRequirements{
+									Requests: corev1.ResourceList{extension.ResourceGPU: resource.MustParse("100")},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantAllowed: true,
+		},
+		{
+			name:        "not validated on update",
+			operation:   admissionv1.Update,
+			pod:         gpuPod("test", corev1.ResourceList{extension.ResourceGPU: resource.MustParse("300")}, ""),
+			objects:     []client.Object{limited},
+			wantAllowed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &PodValidatingHandler{
+				Client:  fake.NewClientBuilder().WithObjects(tt.objects...).Build(),
+				Decoder: admission.NewDecoder(scheme.Scheme),
+			}
+			object := runtime.RawExtension{Raw: []byte(util.DumpJSON(tt.pod))}
+			req := newAdmissionRequest(tt.operation, object, object, "")
+			gotAllowed, gotReason, err := h.namespaceGPUQuotaValidatingPod(context.TODO(), admission.Request{AdmissionRequest: req})
+			assert.Equal(t, tt.wantAllowed, gotAllowed, gotReason)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.wantReason, gotReason)
+		})
+	}
+}
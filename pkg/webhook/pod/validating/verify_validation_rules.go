@@ -0,0 +1,243 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	clientcache "k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	apiresource "k8s.io/kubernetes/pkg/api/v1/resource"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/koordinator-sh/koordinator/pkg/util/sloconfig"
+)
+
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+
+const (
+	// PodValidationRulesKey is the key of the rules in the data of the ConfigMap, whose value is the JSON
+	// of PodValidationRules.
+	PodValidationRulesKey = "pod-validation-rules"
+)
+
+var (
+	// PodValidationRulesConfigMap is the name of the ConfigMap which contains the additional pod validation rules.
+	// It is in the namespace of the koordinator ConfigMaps.
+	PodValidationRulesConfigMap = "pod-validation-rules"
+)
+
+// PodValidationRules are the additional rules to validate the pods, which can be tuned without redeploying the webhook.
+type PodValidationRules struct {
+	// RequiredLabels requires the selected pods to have the labels.
+	RequiredLabels []RequiredLabelsRule `json:"requiredLabels,omitempty"`
+	// ForbiddenResourceCombinations forbids the pods to request the resources together.
+	ForbiddenResourceCombinations []ForbiddenResourceCombinationRule `json:"forbiddenResourceCombinations,omitempty"`
+}
+
+type RequiredLabelsRule struct {
+	Name string `json:"name,omitempty"`
+	// Resources selects the pods requesting any of the resources, e.g. the GPU pods. All pods are selected if empty.
+	Resources []corev1.ResourceName `json:"resources,omitempty"`
+	// Labels are the keys of the labels which the selected pods must have.
+	Labels []string `json:"labels,omitempty"`
+}
+
+type ForbiddenResourceCombinationRule struct {
+	Name string `json:"name,omitempty"`
+	// Resources are forbidden to be requested all together by a pod.
+	Resources []corev1.ResourceName `json:"resources,omitempty"`
+}
+
+// PodValidationRuleStore holds the rules loaded from the ConfigMap. The rules are replaced as a whole on changes,
+// so that a request is always validated with a consistent rule set.
+type PodValidationRuleStore struct {
+	rules atomic.Pointer[PodValidationRules]
+}
+
+func NewPodValidationRuleStore() *PodValidationRuleStore {
+	return &PodValidationRuleStore{}
+}
+
+// Get returns the current rules, which is nil if no rule is loaded. The returned rules must not be modified.
+func (s *PodValidationRuleStore) Get() *PodValidationRules {
+	return s.rules.Load()
+}
+
+func (s *PodValidationRuleStore) OnConfigMapAdd(obj interface{}) {
+	configMap, ok := obj.(*corev1.ConfigMap)
+	if !ok || !isPodValidationRulesConfigMap(configMap) {
+		return
+	}
+	s.load(configMap)
+}
+
+func (s *PodValidationRuleStore) OnConfigMapUpdate(oldObj, newObj interface{}) {
+	s.OnConfigMapAdd(newObj)
+}
+
+func (s *PodValidationRuleStore) OnConfigMapDelete(obj interface{}) {
+	var configMap *corev1.ConfigMap
+	switch t := obj.(type) {
+	case *corev1.ConfigMap:
+		configMap = t
+	case clientcache.DeletedFinalStateUnknown:
+		configMap, _ = t.Obj.(*corev1.ConfigMap)
+	}
+	if configMap == nil || !isPodValidationRulesConfigMap(configMap) {
+		return
+	}
+	s.rules.Store(nil)
+	klog.Infof("pod validation rules ConfigMap %s/%s is deleted, clean the rules", configMap.Namespace, configMap.Name)
+}
+
+// load replaces the rules with the ones in the ConfigMap. The current rules are kept if the ConfigMap is invalid.
+func (s *PodValidationRuleStore) load(configMap *corev1.ConfigMap) {
+	rules, err := parsePodValidationRules(configMap)
+	if err != nil {
+		klog.Errorf("failed to parse pod validation rules ConfigMap %s/%s, keep the current rules, err: %v",
+			configMap.Namespace, configMap.Name, err)
+		return
+	}
+	s.rules.Store(rules)
+	klog.V(4).Infof("pod validation rules are loaded from ConfigMap %s/%s, resourceVersion %s",
+		configMap.Namespace, configMap.Name, configMap.ResourceVersion)
+}
+
+func isPodValidationRulesConfigMap(configMap *corev1.ConfigMap) bool {
+	return configMap.Namespace == sloconfig.ConfigNameSpace && configMap.Name == PodValidationRulesConfigMap
+}
+
+func parsePodValidationRules(configMap *corev1.ConfigMap) (*PodValidationRules, error) {
+	rules := &PodValidationRules{}
+	data, ok := configMap.Data[PodValidationRulesKey]
+	if !ok {
+		return rules, nil
+	}
+	if err := json.Unmarshal([]byte(data), rules); err != nil {
+		return nil, err
+	}
+	for i, rule := range rules.RequiredLabels {
+		if len(rule.Labels) == 0 {
+			return nil, fmt.Errorf("requiredLabels[%d] %s has no label", i, rule.Name)
+		}
+	}
+	for i, rule := range rules.ForbiddenResourceCombinations {
+		if len(rule.Resources) < 2 {
+			return nil, fmt.Errorf("forbiddenResourceCombinations[%d] %s has less than 2 resources", i, rule.Name)
+		}
+	}
+	return rules, nil
+}
+
+// InjectValidationRules watches the ConfigMap of the pod validation rules and reloads the rules on changes.
+func (h *PodValidatingHandler) InjectValidationRules(cache cache.Cache) error {
+	if h.ValidationRules == nil {
+		h.ValidationRules = NewPodValidationRuleStore()
+	}
+	informer, err := cache.GetInformer(context.TODO(), &corev1.ConfigMap{})
+	if err != nil {
+		return err
+	}
+	_, err = informer.AddEventHandler(clientcache.ResourceEventHandlerFuncs{
+		AddFunc:    h.ValidationRules.OnConfigMapAdd,
+		UpdateFunc: h.ValidationRules.OnConfigMapUpdate,
+		DeleteFunc: h.ValidationRules.OnConfigMapDelete,
+	})
+	return err
+}
+
+// validationRulesValidatingPod validates the created pods with the rules loaded from the ConfigMap.
+// The updated pods are not validated, so that tuning the rules does not block the existing pods.
+func (h *PodValidatingHandler) validationRulesValidatingPod(ctx context.Context, req admission.Request) (bool, string, error) {
+	if h.ValidationRules == nil || req.Operation != admissionv1.Create {
+		return true, "", nil
+	}
+	rules := h.ValidationRules.Get()
+	if rules == nil {
+		return true, "", nil
+	}
+
+	pod := &corev1.Pod{}
+	if err := h.Decoder.DecodeRaw(req.Object, pod); err != nil {
+		return false, "", err
+	}
+
+	err := validatePodWithRules(pod, rules).ToAggregate()
+	allowed := true
+	reason := ""
+	if err != nil {
+		allowed = false
+		reason = err.Error()
+	}
+	return allowed, reason, err
+}
+
+func validatePodWithRules(pod *corev1.Pod, rules *PodValidationRules) field.ErrorList {
+	allErrs := field.ErrorList{}
+	requests := apiresource.PodRequests(pod, apiresource.PodResourcesOptions{})
+	requested := func(name corev1.ResourceName) bool {
+		q, ok := requests[name]
+		return ok && !q.IsZero()
+	}
+
+	for _, rule := range rules.RequiredLabels {
+		selected := len(rule.Resources) == 0
+		for _, name := range rule.Resources {
+			if requested(name) {
+				selected = true
+				break
+			}
+		}
+		if !selected {
+			continue
+		}
+		for _, key := range rule.Labels {
+			if _, ok := pod.Labels[key]; !ok {
+				allErrs = append(allErrs, field.Required(field.NewPath("metadata", "labels").Key(key),
+					fmt.Sprintf("required by the pod validation rule %s", rule.Name)))
+			}
+		}
+	}
+
+	for _, rule := range rules.ForbiddenResourceCombinations {
+		forbidden := true
+		for _, name := range rule.Resources {
+			if !requested(name) {
+				forbidden = false
+				break
+			}
+		}
+		if forbidden {
+			var names []string
+			for _, name := range rule.Resources {
+				names = append(names, string(name))
+			}
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("pod.spec.containers[*].resources.requests"),
+				fmt.Sprintf("requesting %s together is forbidden by the pod validation rule %s", strings.Join(names, ", "), rule.Name)))
+		}
+	}
+	return allErrs
+}
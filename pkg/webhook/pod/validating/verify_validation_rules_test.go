@@ -0,0 +1,246 @@
+/*
+Copyright 2023 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	clientcache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/pkg/util/sloconfig"
+)
+
+func newValidationRulesConfigMap(rules string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: sloconfig.ConfigNameSpace,
+			Name:      PodValidationRulesConfigMap,
+		},
+		Data: map[string]string{
+			PodValidationRulesKey: rules,
+		},
+	}
+}
+
+func TestPodValidationRuleStore(t *testing.T) {
+	store := NewPodValidationRuleStore()
+	assert.Nil(t, store.Get())
+
+	otherConfigMap := newValidationRulesConfigMap(`{"requiredLabels":[{"name":"other","labels":["app"]}]}`)
+	otherConfigMap.Name = "other"
+	store.OnConfigMapAdd(otherConfigMap)
+	assert.Nil(t, store.Get(), "other ConfigMaps should be ignored")
+
+	configMap := newValidationRulesConfigMap(`{"requiredLabels":[{"name":"gpu-owner","resources":["koordinator.sh/gpu"],"labels":["owner"]}]}`)
+	store.OnConfigMapAdd(configMap)
+	rules := store.Get()
+	assert.NotNil(t, rules)
+	assert.Equal(t, 1, len(rules.RequiredLabels))
+	assert.Equal(t, "gpu-owner", rules.RequiredLabels[0].Name)
+
+	invalidConfigMap := newValidationRulesConfigMap(`{"forbiddenResourceCombinations":[{"name":"invalid","resources":["cpu"]}]}`)
+	store.OnConfigMapUpdate(configMap, invalidConfigMap)
+	assert.Same(t, rules, store.Get(), "the current rules should be kept if the ConfigMap is invalid")
+
+	updatedConfigMap := newValidationRulesConfigMap(`{"forbiddenResourceCombinations":[{"name":"batch-gpu","resources":["kubernetes.io/batch-cpu","koordinator.sh/gpu"]}]}`)
+	store.OnConfigMapUpdate(configMap, updatedConfigMap)
+	updatedRules := store.Get()
+	assert.Equal(t, 0, len(updatedRules.RequiredLabels))
+	assert.Equal(t, 1, len(updatedRules.ForbiddenResourceCombinations))
+	assert.Equal(t, 1, len(rules.RequiredLabels), "the previous rules should not be modified")
+
+	store.OnConfigMapDelete(clientcache.DeletedFinalStateUnknown{Obj: updatedConfigMap})
+	assert.Nil(t, store.Get())
+}
+
+func TestValidationRulesValidatingPod(t *testing.T) {
+	rules := `{
+  "requiredLabels": [
+    {"name": "gpu-owner", "resources": ["koordinator.sh/gpu"], "labels": ["owner"]}
+  ],
+  "forbiddenResourceCombinations": [
+    {"name": "batch-gpu", "resources": ["kubernetes.io/batch-cpu", "koordinator.sh/gpu"]}
+  ]
+}`
+	tests := []struct {
+		name        string
+		operation   admissionv1.Operation
+		pod         *corev1.Pod
+		noRules     bool
+		wantAllowed bool
+		wantErr     bool
+	}{
+		{
+			name:      "no rules",
+			operation: admissionv1.Create,
+			pod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Resources: corev1.ResourceRequirements{
+								Requests: corev1.ResourceList{
+									extension.ResourceGPU: resource.MustParse("100"),
+								},
+							},
+						},
+					},
+				},
+			},
+			noRules:     true,
+			wantAllowed: true,
+		},
+		{
+			name:      "non-gpu pod without required labels",
+			operation: admissionv1.Create,
+			pod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Resources: corev1.ResourceRequirements{
+								Requests: corev1.ResourceList{
+									corev1.ResourceCPU: resource.MustParse("1"),
+								},
+							},
+						},
+					},
+				},
+			},
+			wantAllowed: true,
+		},
+		{
+			name:      "gpu pod with required labels",
+			operation: admissionv1.Create,
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"owner": "test",
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Resources: corev1.ResourceRequirements{
+								Requests: corev1.ResourceList{
+									extension.ResourceGPU: resource.MustParse("100"),
+								},
+							},
+						},
+					},
+				},
+			},
+			wantAllowed: true,
+		},
+		{
+			name:      "gpu pod without required labels",
+			operation: admissionv1.Create,
+			pod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Resources: corev1.ResourceRequirements{
+								Requests: corev1.ResourceList{
+									extension.ResourceGPU: resource.MustParse("100"),
+								},
+							},
+						},
+					},
+				},
+			},
+			wantAllowed: false,
+			wantErr:     true,
+		},
+		{
+			name:      "gpu pod without required labels on update",
+			operation: admissionv1.Update,
+			pod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Resources: corev1.ResourceRequirements{
+								Requests: corev1.ResourceList{
+									extension.ResourceGPU: resource.MustParse("100"),
+								},
+							},
+						},
+					},
+				},
+			},
+			wantAllowed: true,
+		},
+		{
+			name:      "pod requests forbidden resource combination",
+			operation: admissionv1.Create,
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"owner": "test",
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Resources: corev1.ResourceRequirements{
+								Requests: corev1.ResourceList{
+									extension.BatchCPU: resource.MustParse("1000"),
+								},
+							},
+						},
+						{
+							Resources: corev1.ResourceRequirements{
+								Requests: corev1.ResourceList{
+									extension.ResourceGPU: resource.MustParse("100"),
+								},
+							},
+						},
+					},
+				},
+			},
+			wantAllowed: false,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &PodValidatingHandler{
+				Decoder:         admission.NewDecoder(scheme.Scheme),
+				ValidationRules: NewPodValidationRuleStore(),
+			}
+			if !tt.noRules {
+				h.ValidationRules.OnConfigMapAdd(newValidationRulesConfigMap(rules))
+			}
+
+			podRaw, err := json.Marshal(tt.pod)
+			assert.NoError(t, err)
+			object := runtime.RawExtension{Raw: podRaw}
+			req := newAdmissionRequest(tt.operation, object, object, "")
+			gotAllowed, gotReason, err := h.validationRulesValidatingPod(context.TODO(), admission.Request{AdmissionRequest: req})
+			assert.Equal(t, tt.wantAllowed, gotAllowed, gotReason)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
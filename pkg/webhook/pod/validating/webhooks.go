@@ -17,10 +17,17 @@ limitations under the License.
 package validating
 
 import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	"github.com/koordinator-sh/koordinator/pkg/features"
+	utilfeature "github.com/koordinator-sh/koordinator/pkg/util/feature"
 	"github.com/koordinator-sh/koordinator/pkg/webhook/quotaevaluate"
 	"github.com/koordinator-sh/koordinator/pkg/webhook/util/framework"
 )
@@ -53,5 +60,66 @@ func (b *podValidateBuilder) Build() admission.Handler {
 	quotaAccessor := quotaevaluate.NewQuotaAccessor(h.Client)
 	h.QuotaEvaluator = quotaevaluate.NewQuotaEvaluator(quotaAccessor, 16, make(chan struct{}))
 
+	if utilfeature.DefaultFeatureGate.Enabled(features.EnablePodValidationRules) {
+		if err := h.InjectValidationRules(b.mgr.GetCache()); err != nil {
+			klog.Fatalf("failed to inject cache for podValidateBuilder: %v", err)
+		}
+	}
+
 	return h
 }
+
+// InitFlags registers the flags of the pod validating webhook.
+func InitFlags(fs *flag.FlagSet) {
+	fs.Var(&stringListValue{value: &GPUPodValidations}, "gpu-pod-validations", fmt.Sprintf("A list of GPU pod validations to enable "+
+		"when the feature gate EnableGPUPodValidation is enabled. "+
+		"'-gpu-pod-validations=*' enables all validations. "+
+		"'-gpu-pod-validations=NodeDevice' means only the 'NodeDevice' validation is enabled. "+
+		"'-gpu-pod-validations=*,-MemoryLimit' means all validations except the 'MemoryLimit' validation are enabled.\n"+
+		"All validations: %s", strings.Join(AllGPUPodValidations, ", ")))
+	fs.DurationVar(&PodDecisionCacheTTL, "pod-validation-decision-cache-ttl", PodDecisionCacheTTL,
+		"How long the allowed decision of the colocation and quota meta checks is reused for the identical pods created, e.g. the replicas of a ReplicaSet. It takes effect when the feature gate EnablePodValidationDecisionCache is enabled.")
+	fs.StringVar(&GPURuntimeClassNames, "gpu-runtime-class-names", GPURuntimeClassNames,
+		"The runtime classes compatible with the GPU devices, separated by comma. The GPU pods must use one of them or have one of the gpu-runtime-annotations.")
+	fs.StringVar(&GPURuntimeAnnotations, "gpu-runtime-annotations", GPURuntimeAnnotations,
+		"The annotation keys which make the GPU pods compatible with the GPU devices without the runtime class, separated by comma.")
+	fs.StringVar(&GPUPodRequiredLabels, "gpu-pod-required-labels", GPUPodRequiredLabels,
+		"The labels required by the GPU pods, separated by comma. Each of them is a key, or a key with the valid values separated by '|', e.g. 'example.com/numa-policy=single|restricted'.")
+	fs.StringVar(&GPUPodRequiredAnnotations, "gpu-pod-required-annotations", GPUPodRequiredAnnotations,
+		"The annotations required by the GPU pods in the same format as the gpu-pod-required-labels.")
+	fs.Float64Var(&GPUMemoryRatioOversubscriptionFactor, "gpu-memory-ratio-oversubscription-factor", GPUMemoryRatioOversubscriptionFactor,
+		"The factor of the gpu-memory-ratio capacity of a GPU which can be requested, the oversubscription is disabled if it is not greater than 1.")
+	fs.Int64Var(&GPUAllocationGranularity, "gpu-allocation-granularity", GPUAllocationGranularity,
+		"The granularity of the gpu-core and gpu-memory-ratio allocated by the device plugin on a GPU, e.g. 25 if the GPUs are allocated by quarters. The validation is disabled if it is not greater than 1.")
+	fs.Float64Var(&GPUMemoryRatioMaxCoreFactor, "gpu-memory-ratio-max-core-factor", GPUMemoryRatioMaxCoreFactor,
+		"The max factor of the gpu-memory-ratio to the gpu-core requested by a container, e.g. 4 if a container requesting 10 gpu-core can request at most 40 gpu-memory-ratio. The validation is disabled if it is not greater than 0.")
+	fs.Var(&GPUPodMinCPURequest, "gpu-pod-min-cpu-request",
+		"The minimum cpu request of the pods requesting GPUs, e.g. 500m. The validation of cpu is disabled if it is zero.")
+	fs.BoolVar(&GPUNodeSelectorDenial, "gpu-node-selector-denial", GPUNodeSelectorDenial,
+		"Whether to reject the GPU pods whose node selector and affinity match no node with healthy GPUs by the 'NodeSelector' validation, otherwise the pods are only warned.")
+	fs.Var(&GPUPodMinMemoryRequest, "gpu-pod-min-memory-request",
+		"The minimum memory request of the pods requesting GPUs, e.g. 1Gi. The validation of memory is disabled if it is zero.")
+}
+
+// stringListValue is a flag.Value of the comma-separated strings.
+type stringListValue struct {
+	value *[]string
+}
+
+func (v *stringListValue) String() string {
+	if v.value == nil {
+		return ""
+	}
+	return strings.Join(*v.value, ",")
+}
+
+func (v *stringListValue) Set(s string) error {
+	var values []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	*v.value = values
+	return nil
+}
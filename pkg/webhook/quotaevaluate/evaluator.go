@@ -345,11 +345,14 @@ func (e *quotaEvaluator) checkRequest(quota *v1alpha1.ElasticQuota, a *Attribute
 		failedRequestedUsage := quotav1.Mask(requestedUsage, exceeded)
 		failedUsed := quotav1.Mask(used, exceeded)
 		failedHard := quotav1.Mask(admission, exceeded)
-		return quota, fmt.Errorf("exceeded quota: %s/%s, requested: %s, used: %s, limited: %s",
+		failedAvailable := quotav1.SubtractWithNonNegativeResult(failedHard, failedUsed)
+		return quota, fmt.Errorf("exceeded quota: %s/%s, requested: %s, used: %s, available: %s, limited: %s, %s",
 			quota.Namespace, quota.Name,
 			prettyPrint(failedRequestedUsage),
 			prettyPrint(failedUsed),
-			prettyPrint(failedHard))
+			prettyPrint(failedAvailable),
+			prettyPrint(failedHard),
+			exceededQuotaHint(failedRequestedUsage, quotav1.Mask(newUsage, exceeded), failedHard, quotav1.Mask(quota.Spec.Min, exceeded)))
 	}
 
 	data, err := json.Marshal(newUsage)
@@ -426,6 +429,23 @@ func (e *quotaEvaluator) getWork() (string, []*admissionWaiter, bool) {
 	return key, work, false
 }
 
+// exceededQuotaHint tells the user whether to retry later or to raise the quota when the request is denied,
+// i.e. whether the exceeded usage is still within the guaranteed min or bursting up to the limit.
+func exceededQuotaHint(requested, newUsage, limited, min corev1.ResourceList) string {
+	if fits, _ := quotav1.LessThanOrEqual(requested, limited); !fits {
+		return "the request is larger than the whole limit of the quota and cannot be admitted by retrying, please raise the quota"
+	}
+	if len(min) == 0 {
+		return "the quota has no guaranteed min and its burstable usage up to the limit is used up, please retry later after the used resources are released or raise the quota"
+	}
+	if withinMin, _ := quotav1.LessThanOrEqual(newUsage, min); withinMin {
+		return fmt.Sprintf("the usage is within the guaranteed min (min: %s) but exceeds the admission of the quota, please raise the admission",
+			prettyPrint(min))
+	}
+	return fmt.Sprintf("the usage beyond the guaranteed min (min: %s) is burstable up to the limit which is used up, please retry later after the used resources are released or raise the quota",
+		prettyPrint(min))
+}
+
 // prettyPrint formats a resource list for usage in errors
 // it outputs resources sorted in increasing order
 func prettyPrint(item corev1.ResourceList) string {
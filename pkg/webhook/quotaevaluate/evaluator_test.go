@@ -89,12 +89,88 @@ func TestEvaluate(t *testing.T) {
 					}).Obj(),
 			},
 			expectError: true,
-			errMessage:  "exceeded quota: ns1/test1, requested: cpu=2, used: cpu=19, limited: cpu=20",
+			errMessage:  "exceeded quota: ns1/test1, requested: cpu=2, used: cpu=19, available: cpu=1, limited: cpu=20, the quota has no guaranteed min and its burstable usage up to the limit is used up, please retry later after the used resources are released or raise the quota",
 			expectUsed: corev1.ResourceList{
 				corev1.ResourceCPU:    resource.MustParse("2"),
 				corev1.ResourceMemory: resource.MustParse("4Gi"),
 			},
 		},
+		{
+			name: "cpu exceed beyond min",
+			quota: elasticquota.MakeQuota("test1").Namespace("ns1").Min(
+				corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("10"),
+					corev1.ResourceMemory: resource.MustParse("30Gi"),
+				}).Max(
+				corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("20"),
+					corev1.ResourceMemory: resource.MustParse("60Gi"),
+				}).ChildRequest(
+				corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("19"),
+					corev1.ResourceMemory: resource.MustParse("50Gi"),
+				}).Obj(),
+			attribute: &Attributes{
+				QuotaNamespace: "ns1",
+				QuotaName:      "test1",
+				Operation:      admissionv1.Create,
+				Pod: elasticquota.MakePod("ns1", "pod1").Container(
+					corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("2"),
+						corev1.ResourceMemory: resource.MustParse("4Gi"),
+					}).Obj(),
+			},
+			expectError: true,
+			errMessage: "exceeded quota: ns1/test1, requested: cpu=2, used: cpu=19, available: cpu=1, limited: cpu=20, " +
+				"the usage beyond the guaranteed min (min: cpu=10) is burstable up to the limit which is used up, please retry later after the used resources are released or raise the quota",
+		},
+		{
+			name: "cpu exceed admission within min",
+			quota: elasticquota.MakeQuota("test1").Namespace("ns1").Min(
+				corev1.ResourceList{
+					corev1.ResourceCPU: resource.MustParse("10"),
+				}).Max(
+				corev1.ResourceList{
+					corev1.ResourceCPU: resource.MustParse("20"),
+				}).ChildRequest(
+				corev1.ResourceList{
+					corev1.ResourceCPU: resource.MustParse("4"),
+				}).Admission(
+				corev1.ResourceList{
+					corev1.ResourceCPU: resource.MustParse("5"),
+				}).Obj(),
+			attribute: &Attributes{
+				QuotaNamespace: "ns1",
+				QuotaName:      "test1",
+				Operation:      admissionv1.Create,
+				Pod: elasticquota.MakePod("ns1", "pod1").Container(
+					corev1.ResourceList{
+						corev1.ResourceCPU: resource.MustParse("2"),
+					}).Obj(),
+			},
+			expectError: true,
+			errMessage: "exceeded quota: ns1/test1, requested: cpu=2, used: cpu=4, available: cpu=1, limited: cpu=5, " +
+				"the usage is within the guaranteed min (min: cpu=10) but exceeds the admission of the quota, please raise the admission",
+		},
+		{
+			name: "request larger than limit",
+			quota: elasticquota.MakeQuota("test1").Namespace("ns1").Max(
+				corev1.ResourceList{
+					corev1.ResourceCPU: resource.MustParse("1"),
+				}).Obj(),
+			attribute: &Attributes{
+				QuotaNamespace: "ns1",
+				QuotaName:      "test1",
+				Operation:      admissionv1.Create,
+				Pod: elasticquota.MakePod("ns1", "pod1").Container(
+					corev1.ResourceList{
+						corev1.ResourceCPU: resource.MustParse("2"),
+					}).Obj(),
+			},
+			expectError: true,
+			errMessage: "exceeded quota: ns1/test1, requested: cpu=2, used: , available: cpu=1, limited: cpu=1, " +
+				"the request is larger than the whole limit of the quota and cannot be admitted by retrying, please raise the quota",
+		},
 		{
 			name: "admission allow",
 			quota: elasticquota.MakeQuota("test1").Namespace("ns1").Max(
@@ -169,7 +245,7 @@ func TestEvaluate(t *testing.T) {
 					}).Obj(),
 			},
 			expectError: true,
-			errMessage:  "exceeded quota: ns1/test1, requested: nvidia.com/gpu=2, used: nvidia.com/gpu=2, limited: nvidia.com/gpu=2",
+			errMessage:  "exceeded quota: ns1/test1, requested: nvidia.com/gpu=2, used: nvidia.com/gpu=2, available: nvidia.com/gpu=0, limited: nvidia.com/gpu=2, the quota has no guaranteed min and its burstable usage up to the limit is used up, please retry later after the used resources are released or raise the quota",
 			expectUsed: corev1.ResourceList{
 				extension.ResourceNvidiaGPU: resource.MustParse("2"),
 			},
@@ -18,6 +18,7 @@ package webhook
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"net/http"
 	"time"
@@ -30,6 +31,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
 
+	"github.com/koordinator-sh/koordinator/pkg/webhook/pod/mutating"
+	"github.com/koordinator-sh/koordinator/pkg/webhook/pod/validating"
 	webhookutil "github.com/koordinator-sh/koordinator/pkg/webhook/util"
 	webhookcontroller "github.com/koordinator-sh/koordinator/pkg/webhook/util/controller"
 	"github.com/koordinator-sh/koordinator/pkg/webhook/util/framework"
@@ -65,6 +68,17 @@ func addHandlersWithGate(m map[string]framework.HandlerBuilder, fn GateFunc) {
 	}
 }
 
+// InitFlags registers the flags of the webhooks.
+func InitFlags(fs *flag.FlagSet) {
+	mutating.InitFlags(fs)
+	validating.InitFlags(fs)
+}
+
+// ValidateFlags returns an error if the flags of the webhooks are invalid.
+func ValidateFlags() error {
+	return mutating.ValidateFlags()
+}
+
 func filterActiveHandlers() {
 	disablePaths := sets.NewString()
 	for path := range HandlerBuilderMap {